@@ -1,19 +1,18 @@
 package autotrader
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"math"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
-	"strings"
-	"text/tabwriter"
 	"time"
 
-	"github.com/go-echarts/go-echarts/v2/charts"
-	"github.com/go-echarts/go-echarts/v2/components"
-	"github.com/go-echarts/go-echarts/v2/opts"
 	"golang.org/x/exp/rand"
 	"golang.org/x/exp/slices"
 )
@@ -23,200 +22,109 @@ var (
 	ErrNoData         = errors.New("no data")
 	ErrPositionClosed = errors.New("position already closed")
 	ErrInvalidUnits   = errors.New("the units provided failed to meet the criteria")
+	ErrNotTestBroker  = errors.New("backtesting is only supported with a TestBroker")
+	ErrUnknownOutput  = errors.New("unknown backtest output format")
 )
 
 var _ Broker = (*TestBroker)(nil) // Compile-time interface check.
 
+// Backtest runs the trader on a TestBroker until it runs out of data and then generates the default report. See BacktestWithConfig to choose the outputs and BacktestWithReport to customize the report.
 func Backtest(trader *Trader) {
-	switch broker := trader.Broker.(type) {
-	case *TestBroker:
-		rand.Seed(uint64(time.Now().UnixNano()))
-		trader.Init() // Initialize the trader and strategy.
-		start := time.Now()
-		for !trader.EOF {
-			trader.Tick()    // Allow the trader to process the current candlesticks.
-			broker.Advance() // Give the trader access to the next candlestick.
-		}
-		trader.CloseOrdersAndPositions() // Close any outstanding trades now.
-
-		log.Printf("Backtest completed on %d candles. Opening report...\n", trader.Stats().Dated.Len())
-		stats := trader.Stats()
-		// log.Println(trader.Stats().Dated.String())
-
-		var totalTraded float64
-		stats.Dated.Series("Trades").ForEach(func(i int, val any) {
-			if val == nil {
-				return
-			}
-			switch typ := val.(type) {
-			case []TradeStat:
-				for _, trade := range typ {
-					if trade.Exit { // Only count entry trades.
-						continue
-					}
-					totalTraded += trade.Price * math.Abs(trade.Units)
-				}
-			default:
-				panic("unknown type when calculating totalTraded")
-			}
-		})
-		// Divide net profit by maximum drawdown to get the profit factor.
-		var maxDrawdown float64
-		stats.Dated.Series("Drawdown").ForEach(func(i int, val any) {
-			f := val.(float64)
-			if f > maxDrawdown {
-				maxDrawdown = f
-			}
-		})
-		profit := stats.Dated.Float("Profit", -1)
-		profitFactor := profit / maxDrawdown
-		maxDrawdownPct := 100 * maxDrawdown / stats.Dated.Float("Equity", 0)
-
-		// Print a summary of the statistics to the console.
-		{
-			w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
-			fmt.Fprintln(w)
-			fmt.Fprintf(w, "Timespan:\t%s\t\n", stats.Dated.Date(-1).Sub(stats.Dated.Date(0)).Round(time.Second))
-			fmt.Fprintf(w, "Total Traded:\t$%.2f\t\n", totalTraded)
-			fmt.Fprintf(w, "Net Profit:\t$%.2f (%.2f%%)\t\n", profit, 100*profit/stats.Dated.Float("Equity", 0))
-			fmt.Fprintf(w, "Profit Factor:\t%.2f\t\n", profitFactor)
-			fmt.Fprintf(w, "Max Drawdown:\t$%.2f (%.2f%%)\t\n", maxDrawdown, maxDrawdownPct)
-			fmt.Fprintf(w, "Spread collected:\t$%.2f\t\n", broker.spreadCollectedUSD)
-			fmt.Fprintln(w)
-			w.Flush()
-		}
-
-		// Pick a datetime layout based on the frequency.
-		dateLayout := time.DateTime
-		if strings.Contains(trader.Frequency, "S") { // Seconds
-			dateLayout = "15:04:05"
-		} else if strings.Contains(trader.Frequency, "H") { // Hours
-			dateLayout = "2006-01-02 15:04"
-		} else if strings.Contains(trader.Frequency, "D") || trader.Frequency == "W" { // Days or Weeks
-			dateLayout = time.DateOnly
-		} else if trader.Frequency == "M" { // Months
-			dateLayout = "2006-01"
-		} else if strings.Contains(trader.Frequency, "M") { // Minutes
-			dateLayout = "01-02 15:04"
-		}
-
-		page := components.NewPage()
-
-		// Create a new line balChart based on account equity and add it to the page.
-		balChart := charts.NewLine()
-		balChart.SetGlobalOptions(
-			charts.WithTitleOpts(opts.Title{
-				Title:    "Balance",
-				Subtitle: fmt.Sprintf("%s %s %T  %s (took %.2f seconds)", trader.Symbol, trader.Frequency, trader.Strategy, time.Now().Format(time.DateTime), time.Since(start).Seconds()),
-			}),
-			charts.WithTooltipOpts(opts.Tooltip{
-				Show:      true,
-				Trigger:   "axis",
-				TriggerOn: "mousemove|click",
-			}),
-			charts.WithYAxisOpts(opts.YAxis{
-				AxisLabel: &opts.AxisLabel{
-					Show:      true,
-					Formatter: "${value}",
-				},
-			}),
-			charts.WithLegendOpts(opts.Legend{
-				Show:     true,
-				Selected: map[string]bool{"Equity": false, "Profit": true},
-			}))
-		balChart.SetXAxis(seriesStringArray(stats.Dated.Dates(), dateLayout)).
-			AddSeries("Equity", lineDataFromSeries(stats.Dated.Series("Equity"))).
-			SetSeriesOptions(
-				charts.WithMarkPointNameTypeItemOpts(
-					opts.MarkPointNameTypeItem{Name: "Peak", Type: "max", ItemStyle: &opts.ItemStyle{
-						Color: balChart.Colors[1],
-					}},
-					opts.MarkPointNameTypeItem{Name: "Drawdown", Type: "min", ItemStyle: &opts.ItemStyle{
-						Color: balChart.Colors[3],
-					}},
-				),
-			)
-		balChart.AddSeries("Profit", lineDataFromSeries(stats.Dated.Series("Profit")))
-
-		// Create a new kline chart based on the candlesticks and add it to the page.
-		kline := newKline(trader.data, stats.Dated.Series("Trades"), dateLayout)
-
-		// Sort Returns by value.
-		// Plot returns as a bar chart.
-		returnsSeries := stats.Dated.Series("Returns")
-		returns := make([]float64, 0, returnsSeries.Len())
-		// returns := stats.Dated.Series("Returns").Values()
-		// Remove nil values.
-		for i := 0; i < returnsSeries.Len(); i++ {
-			r := returnsSeries.Value(i)
-			if r != nil {
-				returns = append(returns, r.(float64))
-			}
-		}
-		// Sort the returns.
-		slices.Sort(returns)
-		// Create the X axis labels for the returns chart based on length of the returns slice.
-		returnsLabels := make([]int, len(returns))
-		for i := range returns {
-			returnsLabels[i] = i + 1
-		}
-		returnsBars := make([]opts.BarData, len(returns))
-		for i, r := range returns {
-			returnsBars[i] = opts.BarData{Value: r}
-		}
-		var avg float64
-		for _, r := range returns {
-			avg += r
-		}
-		avg /= float64(len(returns))
-		returnsAverage := make([]opts.LineData, len(returns))
-		for i := range returnsAverage {
-			returnsAverage[i] = opts.LineData{Value: avg}
-		}
-
-		returnsChart := charts.NewBar()
-		returnsChart.SetGlobalOptions(
-			charts.WithTitleOpts(opts.Title{
-				Title:    "Returns",
-				Subtitle: fmt.Sprintf("Average: $%.2f", avg),
-			}),
-			charts.WithYAxisOpts(opts.YAxis{
-				AxisLabel: &opts.AxisLabel{
-					Show:      true,
-					Formatter: "${value}",
-				},
-			}))
-		returnsChart.SetXAxis(returnsLabels).
-			AddSeries("Returns", returnsBars)
-
-		returnsChartAvg := charts.NewLine()
-		returnsChartAvg.SetGlobalOptions(charts.WithTitleOpts(opts.Title{
-			Title: "Average Returns",
-		}))
-		returnsChartAvg.SetXAxis(returnsLabels).
-			AddSeries("Average", returnsAverage, func(s *charts.SingleSeries) {
-				s.LineStyle = &opts.LineStyle{
-					Width: 2,
-				}
-			})
-		returnsChart.Overlap(returnsChartAvg)
+	BacktestWithReport(trader, NewReport())
+}
 
-		// TODO: Use Radar to display performance metrics.
+// BacktestHeadless runs the trader on a TestBroker until it runs out of data, prints the summary, and writes it as JSON to summary.json without generating any charts.
+func BacktestHeadless(trader *Trader) {
+	BacktestWithReport(trader, NewHeadlessReport("summary.json"))
+}
+
+// BacktestWithConfig runs the trader on a TestBroker until it runs out of data and then writes the outputs chosen by config. It panics if config has an unknown format.
+func BacktestWithConfig(trader *Trader, config BacktestConfig) {
+	report, err := config.Report()
+	if err != nil {
+		panic(err)
+	}
+	BacktestWithReport(trader, report)
+}
+
+// OutputFormat is a kind of file a backtest can write. See BacktestConfig.
+type OutputFormat string
+
+const (
+	OutputHTML OutputFormat = "html" // OutputHTML is the page of charts of the default report.
+	OutputJSON OutputFormat = "json" // OutputJSON is the BacktestSummary as JSON.
+	OutputCSV  OutputFormat = "csv"  // OutputCSV is the equity curve as CSV. See EquityCSVSection.
+)
 
-		// Add all the charts in the desired order.
-		page.PageTitle = "Backtest Report"
-		page.AddCharts(balChart, kline, returnsChart)
+// BacktestConfig chooses where the output of a backtest is written, in which formats, and whether the browser is opened, like for CI runs that only need the summary as JSON. The summary is always printed, and the run manifest and trades are always written to result.json and trades.csv.
+type BacktestConfig struct {
+	Dir       string         // Dir is the directory the files are written to. If empty, every run gets a new directory in the "runs" archive.
+	Name      string         // Name is the name of the files of Formats without their extension, like "backtest" for backtest.html, backtest.json, and backtest.csv. If empty, it is "backtest".
+	Formats   []OutputFormat // Formats are the formats to write. If empty, only OutputHTML is written.
+	NoBrowser bool           // NoBrowser does not open the HTML page once it has been written.
+	Out       io.Writer      // Out receives the printed summary. It is os.Stdout if nil.
+}
 
-		// Draw the page to a file.
-		f, err := os.Create("backtest.html")
+// Report returns the Report that writes the outputs of the config. An error wrapping ErrUnknownOutput is returned for an unknown format.
+func (c BacktestConfig) Report() (*Report, error) {
+	name := c.Name
+	if name == "" {
+		name = "backtest"
+	}
+	formats := c.Formats
+	if len(formats) == 0 {
+		formats = []OutputFormat{OutputHTML}
+	}
+	report := &Report{
+		Title:    "Backtest Report",
+		Out:      c.Out,
+		Dir:      c.Dir,
+		Sections: []ReportSection{SummarySection, ManifestSection("result.json"), TradesCSVSection("trades.csv")},
+	}
+	if c.Dir == "" {
+		report.Runs = &RunArchive{}
+	}
+	for _, format := range formats {
+		switch format {
+		case OutputHTML:
+			report.Filename = name + ".html"
+			report.Open = !c.NoBrowser
+			report.Add(EquitySection, CostsSection, DrawdownsSection, CurrencySection, KlineSection, RecordedSection, ReturnsSection)
+		case OutputJSON:
+			report.Add(SummaryJSONSection(name + ".json"))
+		case OutputCSV:
+			report.Add(EquityCSVSection(name + ".csv"))
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrUnknownOutput, format)
+		}
+	}
+	return report, nil
+}
+
+// BacktestWithReport runs the trader on a TestBroker until it runs out of data and then generates the given report. If the report is written to a directory, like a new run of its archive, the log of the trader is also written to backtest.log in that directory.
+func BacktestWithReport(trader *Trader, report *Report) {
+	switch broker := trader.Broker.(type) {
+	case *TestBroker:
+		dir, err := report.runDir(trader)
 		if err != nil {
 			panic(err)
 		}
-		page.Render(f)
-		f.Close()
-
-		// Open the chart in the default browser.
-		if err := Open("backtest.html"); err != nil {
+		if dir != "" && trader.Log != nil {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				panic(err)
+			}
+			logFile, err := os.Create(filepath.Join(dir, "backtest.log"))
+			if err != nil {
+				panic(err)
+			}
+			defer logFile.Close()
+			out := trader.Log.Writer()
+			trader.Log.SetOutput(io.MultiWriter(out, logFile))
+			defer trader.Log.SetOutput(out)
+		}
+		elapsed := runBacktest(trader, broker)
+		log.Printf("Backtest completed on %d candles. Generating report in %s...\n", trader.Stats().Dated.Len(), reportDir(dir))
+		if err := report.generate(trader, broker, elapsed, dir); err != nil {
 			panic(err)
 		}
 	default:
@@ -224,115 +132,71 @@ func Backtest(trader *Trader) {
 	}
 }
 
-func newKline(dohlcv *IndexedFrame[UnixTime], trades *Series, dateLayout string) *charts.Kline {
-	kline := charts.NewKLine()
-
-	x := make([]string, dohlcv.Len())
-	y := make([]opts.KlineData, dohlcv.Len())
-	for i := 0; i < dohlcv.Len(); i++ {
-		x[i] = dohlcv.Date(i).Time().Format(dateLayout)
-		y[i] = opts.KlineData{Value: [4]float64{
-			dohlcv.Open(i),
-			dohlcv.Close(i),
-			dohlcv.Low(i),
-			dohlcv.High(i),
-		}}
-	}
-
-	marks := make([]opts.MarkPointNameCoordItem, 0)
-	for i := 0; i < trades.Len(); i++ {
-		if slice := trades.Value(i); slice != nil {
-			for _, trade := range slice.([]TradeStat) {
-				color := "green"
-				rotation := float32(0)
-				if trade.Units < 0 {
-					color = "red"
-					rotation = 180
-				}
-				if trade.Exit {
-					color = "black"
-				}
-				marks = append(marks, opts.MarkPointNameCoordItem{
-					Name:       "Trade",
-					Value:      fmt.Sprintf("%v units", trade.Units),
-					Coordinate: []interface{}{x[i], y[i].Value.([4]float64)[1]},
-					Label: &opts.Label{
-						Show:     true,
-						Position: "inside",
-					},
-					ItemStyle: &opts.ItemStyle{
-						Color: color,
-					},
-					Symbol:       "arrow",
-					SymbolRotate: rotation,
-					SymbolSize:   25,
-				})
-			}
-		}
+// RunBacktest runs the trader on a TestBroker until it runs out of data, without generating a report, and returns the summary of its performance. ErrNotTestBroker is returned if the broker of the trader is not a *TestBroker.
+func RunBacktest(trader *Trader) (BacktestSummary, error) {
+	broker, ok := trader.Broker.(*TestBroker)
+	if !ok {
+		return BacktestSummary{}, fmt.Errorf("%w: got %T", ErrNotTestBroker, trader.Broker)
 	}
+	runBacktest(trader, broker)
+	return Summarize(trader.Stats(), broker), nil
+}
 
-	kline.SetGlobalOptions(
-		charts.WithTitleOpts(opts.Title{
-			Title:    "Trades",
-			Subtitle: fmt.Sprintf("Showing %d candles", dohlcv.Len()),
-		}),
-		charts.WithXAxisOpts(opts.XAxis{
-			SplitNumber: 20,
-		}),
-		charts.WithYAxisOpts(opts.YAxis{
-			Scale: true,
-		}),
-		charts.WithTooltipOpts(opts.Tooltip{ // Enable seeing details on hover.
-			Show:      true,
-			Trigger:   "axis",
-			TriggerOn: "mousemove|click",
-		}),
-		charts.WithDataZoomOpts(opts.DataZoom{ // Support zooming with scroll wheel.
-			Type:       "inside",
-			Start:      0,
-			End:        100,
-			XAxisIndex: []int{0},
-		}),
-		charts.WithDataZoomOpts(opts.DataZoom{ // Support zooming with bottom slider.
-			Type:       "slider",
-			Start:      0,
-			End:        100,
-			XAxisIndex: []int{0},
-		}),
-	)
-	kline.SetXAxis(x).AddSeries("Price Action", y, charts.WithMarkPointNameCoordItemOpts(marks...))
-	return kline
-}
-
-func lineDataFromSeries(s *Series) []opts.LineData {
-	if s == nil || s.Len() == 0 {
-		return []opts.LineData{}
-	}
-	data := make([]opts.LineData, s.Len())
-	for i := 0; i < s.Len(); i++ {
-		data[i] = opts.LineData{Value: Round(s.Value(i).(float64), 2)}
-	}
-	return data
-}
-
-func seriesStringArray(s *Series, dateLayout string) []string {
-	if s == nil || s.Len() == 0 {
-		return []string{}
-	}
-	data := make([]string, s.Len())
-	for i := 0; i < s.Len(); i++ {
-		switch val := s.Value(i).(type) {
-		case time.Time:
-			data[i] = val.Format(dateLayout)
-		case string:
-			data[i] = fmt.Sprintf("%q", val)
-		default:
-			data[i] = fmt.Sprintf("%v", val)
+// reportDir returns a printable name of the directory of a report.
+func reportDir(dir string) string {
+	if dir == "" {
+		return "the current directory"
+	}
+	return dir
+}
+
+func runBacktest(trader *Trader, broker *TestBroker) time.Duration {
+	if broker.Seed == 0 {
+		broker.Seed = uint64(time.Now().UnixNano())
+	}
+	broker.rand = rand.New(rand.NewSource(broker.Seed))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	trader.startProfiler(ctx)
+	trader.Init() // Initialize the trader and strategy.
+	start := time.Now()
+	// When the data is finer than the frequency of the trader, like M1 data for an H1 strategy, the trader only ticks when one of its candles closes, and the finer candles are used to sample the equity.
+	coarser := broker.coarserFrequency(trader.Frequency)
+	for !trader.EOF {
+		trader.sample()
+		if !coarser || broker.closesCandle(trader.Frequency) {
+			trader.Tick() // Allow the trader to process the current candlesticks.
 		}
+		broker.Advance() // Give the trader access to the next candlestick.
 	}
-	return data
+	trader.CloseOrdersAndPositions() // Close any outstanding trades now.
+	return time.Since(start)
 }
 
+// PositionMode is how a TestBroker holds the positions of orders in the same symbol, to match the semantics of different brokers.
+type PositionMode string
+
+const (
+	// PositionHedging opens a separate position for every order, so long and short positions in the same symbol can be held at once. It is the default.
+	PositionHedging PositionMode = ""
+	// PositionNetting holds at most one position per symbol. An order in its direction adds to it at the average entry price, and an opposite order reduces or closes it, opening a position in the other direction with any units left over.
+	PositionNetting PositionMode = "netting"
+	// PositionFIFO opens a separate position for every order in the direction of the open positions, but an opposite order reduces or closes them from the oldest first, like the FIFO rule of US forex brokers.
+	PositionFIFO PositionMode = "fifo"
+)
+
+// IntrabarPath is what a TestBroker assumes about the path of the price within a candle, whose open, high, low, and close do not tell whether the high or the low came first. It decides whether the stop loss or the take profit of a position was reached first when both are within the range of a candle, and the order in which pending orders are filled.
+type IntrabarPath string
+
+const (
+	// IntrabarWorstCase assumes the worst for the strategy: a stop loss is hit before a take profit within the same candle, and the take profit of a position filled on the candle is not reached until the next candle, since the price may have reached it before the fill. Pending orders are filled in the order they were placed. It is the default.
+	IntrabarWorstCase IntrabarPath = ""
+	// IntrabarOHLC assumes the price moved from the open to the nearer of the high and the low, then to the other, then to the close, and fills orders and closes positions in the order their prices are reached along that path. Exits of a position filled on the candle are only reached after the fill.
+	IntrabarOHLC IntrabarPath = "ohlc"
+	// IntrabarSubCandles is IntrabarOHLC along the path of the SubCandles within each candle, one sub-candle after another. Candles without sub-candles, and the candles of SymbolData, use the path of IntrabarOHLC.
+	IntrabarSubCandles IntrabarPath = "sub"
+)
+
 // TestBroker is a broker that can be used for testing. It implements the Broker interface and fulfills orders
 //
 // Signals:
@@ -340,21 +204,74 @@ func seriesStringArray(s *Series, dateLayout string) []string {
 //   - OrderPlaced(Order) - Called when an order is placed.
 //   - OrderFilled(Order) - Called when an order is filled.
 //   - OrderCanceled(Order) - Called when an order is canceled.
+//   - OrderExpired(Order) - Called when an order expires because of its time in force, like a FillOrKill order that could not be filled when it was placed.
 //   - PositionClosed(Position) - Called when a position is closed.
 //   - PositionModified(Position) - Called when a position changes.
+//   - CandleOpened(CandleEvent) - Called by Advance before the orders and positions are updated on the next candle.
+//   - CandleClosed(CandleEvent) - Called by Advance after the orders and positions are updated on the next candle.
+//   - MarginCall(MarginEvent) - Called when the margin level falls below MarginCallLevel or MarginCloseoutLevel.
 type TestBroker struct {
 	SignalManager
 	DataBroker Broker
 	Data       *IndexedFrame[UnixTime]
+	// SymbolData are the candles of other symbols than the one of Data, at the same frequency, for backtesting a basket of symbols. Data drives the clock of the broker, and the current candle of each other symbol is its latest candle at or before the current candle of Data, so their dates should be aligned with Data. Symbols without SymbolData are priced from Data.
+	SymbolData map[string]*IndexedFrame[UnixTime]
 	Cash       float64
 	Leverage   float64
-	Spread     float64 // Number of pips to add to the price when buying and subtract when selling. (Forex)
-	Slippage   float64 // A percentage of the price to add when buying and subtract when selling.
-
-	candleCount        int // The number of candles anyone outside this broker has seen. Also equal to the number of times Candles has been called.
+	Spread     float64                // Spread is the absolute difference between the ask and bid prices, which depends on the precision of the instrument. See SpreadPips.
+	Slippage   float64                // A percentage of the price to add when buying and subtract when selling.
+	Conversion ConversionRateProvider // Conversion converts values in the quote currency of a symbol into the account currency. If nil, every symbol is assumed to be quoted in the account currency.
+	Seed       uint64                 // Seed is the seed of the random number generator of the broker, used for slippage and IDs. If zero, Backtest picks one from the current time. Either way it is recorded in the run manifest so the run can be reproduced. Every broker has its own generator, so backtests run in parallel are reproducible too.
+	Commission float64                // Commission is the fee charged on every fill as a fraction of the traded value. For example, 0.001 charges 0.1% when opening and again when closing a position.
+	// MarketImpact moves the fill price of every order against it by this fraction of the price for each fraction of the volume of the candle it trades. For example, 0.1 fills an order for 1% of the volume of the candle 0.1% worse. The impact is counted as slippage, and is reduced by splitting a large order into smaller child orders with WithTWAP or WithIceberg. Candles without volume have no impact.
+	MarketImpact float64
+	// Frequency is the frequency of Data, like "M15". When it is set, Candles of any other frequency are resampled from the visible candles of Data and only include candles that have closed, so strategies can use several frequencies from one base dataset.
+	Frequency string
+	// Stream is an optional source of candles that are read a chunk at a time as the broker advances, so the entire dataset never has to be loaded into memory. Candles read from Stream are appended to Data.
+	Stream      CandleChunkReader
+	StreamChunk int      // StreamChunk is the number of candles to read from Stream at a time. The default is 1000.
+	StreamKeep  int      // StreamKeep is the number of past candles to keep in Data while streaming. Older candles are discarded. Zero keeps every candle, and should otherwise be at least the number of candles the trader requests.
+	Symbols     []string // Symbols are the symbols that can be traded. Orders for any other symbol fail with ErrSymbolNotFound. If empty, every symbol can be traded.
+	MinUnits    float64  // MinUnits is the minimum absolute number of units of an order for instruments that do not set their own. Smaller orders fail with ErrUnitsBelowMinimum.
+	// Calendar tells when the market is open, like ForexHours. Orders placed on a candle while the market is closed fail with ErrMarketClosed, unless QueueClosedOrders is set. When the market closed between two candles, the open of the second candle gaps past stop losses, take profits, and pending orders, which are filled at the open instead of their price. If nil, the market is always open.
+	Calendar          MarketCalendar
+	SpreadPips        float64 // SpreadPips is a spread in pips that is converted to a price with the pip size of the symbol and added to Spread, so the same setting works for instruments of any precision.
+	PipSize           float64 // PipSize is the size of a pip of the traded instrument. If zero, the PipSize of the symbol is used.
+	SplitSpread       bool    // SplitSpread puts half of the spread on each side of the close, which is how spreads apply to midpoint data. Otherwise the bid is the close and the ask is the close plus the spread.
+	QueueClosedOrders bool    // QueueClosedOrders makes orders placed while the market is closed wait for the open. Queued market orders are filled at the open of the first candle the market is open.
+	// ShareCandles makes Candles return a view of Data with IndexedFrame.View instead of a copy, so a large CandlesToKeep does not copy every kept candle on every tick. The strategy must then treat the candles as read-only, for example by copying a series before changing it with Map.
+	ShareCandles bool
+	// Funding are the funding rates of perpetual futures. At every funding time that passes when the broker advances, each open position of a symbol with rates pays its rate times the value of the position at the close of the candle, or is credited if the payment is negative. The payments are taken from Cash and reported as the Financing cost of the position when it is closed.
+	Funding FundingRates
+	// Instruments describe the contracts of symbols that are not spot instruments, like futures and options. Their multipliers scale the values and costs of positions, order prices are rounded to their tick sizes, and open positions are settled with CloseExpiry on the first candle at or after their expiry: futures at the price of the contract and options at their intrinsic value at the price of their Underlying, which is priced from its SymbolData. Orders for expired contracts fail with ErrContractExpired, and orders for options without an Underlying fail with ErrNoUnderlying.
+	Instruments map[string]Instrument
+	// PositionMode is how the positions of orders in the same symbol are held. The default is PositionHedging. With the other modes, the units of an order that reduce positions are closed like the market order of a strategy, at the fill price of the order and with the close costs of the positions, so only units left over open a position.
+	PositionMode PositionMode
+	// MarginCallLevel is the margin level, the NAV divided by the margin used, below which the broker emits MarginCall, like 1 when the losses of the positions start eating into their margin. Zero disables margin calls.
+	MarginCallLevel float64
+	// MarginCloseoutLevel is the margin level below which the broker closes the open positions at the close of every candle, from the largest loss, until the level is restored, like the 0.5 of many forex brokers. Positions closed this way have the CloseMarginCall close type. Zero disables closeouts, so the account can go negative.
+	MarginCloseoutLevel float64
+	// CorporateActions are the dividends of stocks, which are credited to long positions and charged to short positions that are open on their ex-date at the amount per share times the units of the position. The payments are added to Cash and reported as negative Financing costs of the positions. Splits are ignored, so the data should be adjusted for splits but not for dividends with AdjustCandles.
+	CorporateActions []CorporateAction
+	// Intrabar is the path the price is assumed to take within a candle when filling orders and closing positions. The default is IntrabarWorstCase.
+	Intrabar IntrabarPath
+	// SubCandles are candles of the symbol of Data at a finer frequency, like M1 candles for H1 Data, which are the path of the price within the candles of Data when Intrabar is IntrabarSubCandles.
+	SubCandles *IndexedFrame[UnixTime]
+
+	rand               *rand.Rand // rand is the random number generator seeded with Seed. It is created by random if it is nil.
+	candleCount        int        // The number of candles anyone outside this broker has seen. Also equal to the number of times Candles has been called.
+	advances           int        // The number of candles the broker has advanced, which unlike candleCount is not reduced when streamed candles are discarded.
+	streamErr          error
 	orders             []Order
 	positions          []Position
+	ordersByID         map[string]*TestOrder
+	positionsByID      map[string]*TestPosition
 	spreadCollectedUSD float64 // Total amount of spread collected from trades.
+	spreadPips         float64 // Total spread collected from trades in pips.
+	commissionPaid     float64 // Total amount of commission charged on trades.
+	fundingPaid        float64 // Total amount of funding paid on positions, which is negative if more was received.
+	dividends          float64 // Total amount of dividends received on positions, which is negative if more was paid on short positions.
+	marginCalled       bool    // marginCalled is true while the margin level is below a margin call or closeout level, so MarginCall is only emitted when it first falls below.
 }
 
 func NewTestBroker(dataBroker Broker, data *IndexedFrame[UnixTime], cash, leverage, spread float64, startCandles int) *TestBroker {
@@ -369,11 +286,129 @@ func NewTestBroker(dataBroker Broker, data *IndexedFrame[UnixTime], cash, levera
 	}
 }
 
+// random returns the random number generator of the broker, seeding it with Seed, or the current time if Seed is zero, the first time it is used outside of a backtest.
+func (b *TestBroker) random() *rand.Rand {
+	if b.rand == nil {
+		if b.Seed == 0 {
+			b.Seed = uint64(time.Now().UnixNano())
+		}
+		b.rand = rand.New(rand.NewSource(b.Seed))
+	}
+	return b.rand
+}
+
 // SpreadCollected returns the total amount of spread collected from trades, in USD.
 func (b *TestBroker) SpreadCollected() float64 {
 	return b.spreadCollectedUSD
 }
 
+// SpreadCollectedPips returns the total spread collected from trades in pips, which is the sum of the spread of every round trip no matter the size of the trade.
+func (b *TestBroker) SpreadCollectedPips() float64 {
+	return b.spreadPips
+}
+
+// CommissionPaid returns the total amount of commission charged on trades, in USD.
+func (b *TestBroker) CommissionPaid() float64 {
+	return b.commissionPaid
+}
+
+// FundingPaid returns the total amount of funding paid on positions, in USD. It is negative if more funding was received than paid.
+func (b *TestBroker) FundingPaid() float64 {
+	return b.fundingPaid
+}
+
+// DividendsReceived returns the total amount of dividends received on long positions minus the dividends paid on short positions, in USD.
+func (b *TestBroker) DividendsReceived() float64 {
+	return b.dividends
+}
+
+// fillCosts returns the costs of filling units at price in the account currency, where requested is the price before slippage was applied and rate is the conversion rate of the symbol. Market fills pay half of the spread, since the spread is paid once over the round trip of a position.
+func (b *TestBroker) fillCosts(symbol string, units, price, requested, rate float64, market bool) TradeCosts {
+	rate *= b.Instruments[symbol].ContractMultiplier() // Costs are per unit of the underlying.
+	costs := TradeCosts{
+		Commission: b.Commission * math.Abs(units*price) * rate,
+		Slippage:   (price - requested) * units * rate,
+	}
+	if market {
+		spread := b.spread(symbol)
+		costs.Spread = spread / 2 * math.Abs(units) * rate
+		costs.SpreadPips = spread / 2 / b.pipSize(symbol)
+	}
+	return costs
+}
+
+// instrument returns the instrument of symbol, which is a spot instrument if it is not in Instruments.
+func (b *TestBroker) instrument(symbol string) Instrument {
+	if instrument, ok := b.Instruments[symbol]; ok {
+		return instrument
+	}
+	return Instrument{Symbol: symbol}
+}
+
+// spread returns the difference between the ask and bid prices of symbol.
+func (b *TestBroker) spread(symbol string) float64 {
+	return b.Spread + b.SpreadPips*b.pipSize(symbol)
+}
+
+// pipSize returns PipSize if it is set, otherwise the pip size of symbol.
+func (b *TestBroker) pipSize(symbol string) float64 {
+	if b.PipSize > 0 {
+		return b.PipSize
+	}
+	return PipSize(symbol)
+}
+
+// conversionRate returns the rate that converts values in the quote currency of symbol into the account currency.
+func (b *TestBroker) conversionRate(symbol string) (float64, error) {
+	if b.Conversion == nil {
+		return 1, nil
+	}
+	return b.Conversion.ConversionRate(symbol)
+}
+
+// marketImpact returns how much an order for units of symbol moves the price against it on the current candle.
+func (b *TestBroker) marketImpact(symbol string, units, price float64) float64 {
+	if b.MarketImpact <= 0 || b.Data == nil || b.Data.Len() == 0 {
+		return 0
+	}
+	data, row := b.symbolRow(symbol)
+	if row < 0 {
+		return 0
+	}
+	volume := candleVolume(data, row)
+	if volume <= 0 {
+		return 0
+	}
+	return b.MarketImpact * price * units / float64(volume)
+}
+
+// Now returns the simulated time of the backtest, which is the date of the current candle. Orders and positions are stamped with it. It is zero when there is no data.
+func (b *TestBroker) Now() time.Time {
+	if b.Data == nil || b.Data.Len() == 0 {
+		return time.Time{}
+	}
+	return b.Data.Date(Min(b.CandleIndex(), b.Data.Len()-1)).Time()
+}
+
+// symbolRow returns the candles of symbol and the row of its current candle, which is the latest candle at or before the current candle of Data. The row is -1 if the symbol has no candle yet.
+func (b *TestBroker) symbolRow(symbol string) (*IndexedFrame[UnixTime], int) {
+	data, ok := b.SymbolData[symbol]
+	if !ok {
+		return b.Data, Min(b.CandleIndex(), b.Data.Len()-1)
+	}
+	now := UnixTime(b.Now().Unix())
+	return data, sort.Search(data.Len(), func(i int) bool { return *data.Date(i) > now }) - 1
+}
+
+// symbolCandle returns the open, high, and low of the current candle of symbol. It returns false if the symbol has no candle on the current date, so its orders and positions are left alone until it does.
+func (b *TestBroker) symbolCandle(symbol string) (open, high, low float64, ok bool) {
+	data, row := b.symbolRow(symbol)
+	if row < 0 || data != b.Data && data.Date(row).Time() != b.Now() {
+		return 0, 0, 0, false
+	}
+	return data.Open(row), data.High(row), data.Low(row), true
+}
+
 // CandleIndex returns the index of the current candle.
 func (b *TestBroker) CandleIndex() int {
 	return Max(b.candleCount-1, 0)
@@ -382,24 +417,152 @@ func (b *TestBroker) CandleIndex() int {
 // Advance advances the test broker to the next candle in the input data. This should be done at the end of the
 // strategy loop. This will also call Tick() to update orders and positions.
 func (b *TestBroker) Advance() {
-	if b.candleCount < b.Data.Len() {
-		b.candleCount++
+	if err := b.readStream(); err != nil {
+		b.streamErr = err // Reported by the next call to Candles.
+	}
+	if b.candleCount >= b.Data.Len() {
+		b.Tick()
+		return
 	}
+	prev := b.Now()
+	b.candleCount++
+	b.advances++
+	candle := b.candle(b.CandleIndex())
+	opened := candle
+	opened.High, opened.Low, opened.Close, opened.Volume = candle.Open, candle.Open, candle.Open, 0
+	b.SignalEmit(CandleOpened, CandleEvent{Symbol: b.symbol(), Frequency: b.Frequency, Candle: opened})
+	b.fund(prev, candle.Date)
+	b.payDividends(prev, candle.Date)
 	b.Tick()
+	b.SignalEmit(CandleClosed, CandleEvent{Symbol: b.symbol(), Frequency: b.Frequency, Candle: candle})
+}
+
+// fund charges the open positions the funding of the funding times after start up to and including end.
+func (b *TestBroker) fund(start, end time.Time) {
+	if len(b.Funding) == 0 || start.IsZero() {
+		return
+	}
+	for _, any_p := range b.positions {
+		if any_p.Closed() {
+			continue
+		}
+		p := any_p.(*TestPosition)
+		if rate := b.Funding.Between(p.symbol, start, end); rate != 0 {
+			payment := rate * p.Value() // Longs have a positive value and pay positive rates, shorts have a negative value and receive them.
+			p.financing += payment
+			b.Cash -= payment
+			b.fundingPaid += payment
+		}
+	}
+}
+
+// payDividends credits the open positions with the dividends that went ex after start up to and including end.
+func (b *TestBroker) payDividends(start, end time.Time) {
+	if start.IsZero() {
+		return
+	}
+	for _, action := range b.CorporateActions {
+		if action.Type != Dividend || !action.Date.After(start) || action.Date.After(end) {
+			continue
+		}
+		for _, any_p := range b.positions {
+			p := any_p.(*TestPosition)
+			if p.closed || action.Symbol != "" && action.Symbol != p.symbol {
+				continue
+			}
+			p.updateRate()
+			dividend := action.Value * p.units * p.rate * p.multiplier // Short positions pay the dividend.
+			p.financing -= dividend
+			b.Cash += dividend
+			b.dividends += dividend
+		}
+	}
+}
+
+// candle returns the candle at row i of Data.
+func (b *TestBroker) candle(i int) Candle {
+	return frameCandle(b.Data, i)
+}
+
+// symbol returns the symbol of the candles of Data, which is only known if exactly one symbol can be traded.
+func (b *TestBroker) symbol() string {
+	if len(b.Symbols) == 1 {
+		return b.Symbols[0]
+	}
+	return ""
+}
+
+// readStream reads the next chunk of candles from Stream once every candle in Data has been seen, and discards the candles that are older than StreamKeep.
+func (b *TestBroker) readStream() error {
+	if b.Stream == nil || (b.Data != nil && b.candleCount < b.Data.Len()) {
+		return nil
+	}
+	chunkSize := b.StreamChunk
+	if chunkSize <= 0 {
+		chunkSize = 1000
+	}
+	chunk, err := b.Stream.ReadChunk(chunkSize)
+	if err == io.EOF {
+		b.Stream = nil // Every candle has been read.
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if b.Data == nil {
+		b.Data = chunk
+	} else {
+		chunk.ForEachSeries(func(s *IndexedSeries[UnixTime]) {
+			dst := b.Data.Series(s.Name())
+			if dst == nil {
+				return
+			}
+			for i := 0; i < s.Len(); i++ {
+				dst.Insert(*s.Index(i), s.Value(i))
+			}
+		})
+	}
+
+	if b.StreamKeep > 0 {
+		if excess := b.candleCount - b.StreamKeep; excess > 0 {
+			b.Data.ForEachSeries(func(s *IndexedSeries[UnixTime]) {
+				s.RemoveRange(0, excess)
+			})
+			b.candleCount -= excess
+		}
+	}
+	return nil
 }
 
 func (b *TestBroker) Tick() {
-	// Check if the current candle's high and lows contain any take profits or stop losses.
-	high, low := b.Data.High(b.CandleIndex()), b.Data.Low(b.CandleIndex())
+	b.expireOrders()
+	if !b.marketOpen() {
+		return // Nothing is filled while the market is closed.
+	}
+	gap := b.gapped()
+
+	b.settleExpired()
 
 	// Update orders.
-	for _, any_o := range b.orders {
-		if any_o.Fulfilled() {
+	filled := make(map[*TestPosition]float64) // filled are the positions opened on this candle by where along the intrabar path they were filled.
+	for _, o := range b.pendingOrders() {
+		if o.Fulfilled() || o.cancelled { // An earlier fill may have cancelled the order, like the other order of an OCO group.
 			continue
 		}
-		o := any_o.(*TestOrder)
-
-		if o.orderType == Limit {
+		open, high, low, ok := b.symbolCandle(o.symbol)
+		if !ok {
+			continue
+		}
+		positions := len(b.positions)
+
+		if o.orderType == Market { // Market orders are only pending when they were queued while the market was closed.
+			o.gapped = true
+			o.fulfill(b.openPrice(o.symbol, open, o.units > 0))
+		} else if gap && (o.orderType == Limit && (o.units > 0 && open <= o.price || o.units < 0 && open >= o.price) ||
+			o.orderType == Stop && (o.units > 0 && open >= o.price || o.units < 0 && open <= o.price)) {
+			o.gapped = true
+			o.fulfillAt(open, o.price)
+		} else if o.orderType == Limit {
 			if o.price >= low && o.price <= high {
 				o.fulfill(o.price)
 			}
@@ -410,6 +573,9 @@ func (b *TestBroker) Tick() {
 		} else {
 			panic("the order type is either unknown or otherwise should not be market because those are fulfilled immediately")
 		}
+		if len(b.positions) > positions && o.position == b.positions[len(b.positions)-1] && !o.gapped {
+			filled[o.position] = b.orderReached(o)
+		}
 	}
 
 	// Update positions.
@@ -418,30 +584,201 @@ func (b *TestBroker) Tick() {
 			continue
 		}
 		p := any_p.(*TestPosition)
-		price := b.Price("", p.units < 0) // We want to buy if we are short, and vice versa.
-
-		if p.trailingSLDist > 0 {
-			p.trailingSL = Max(p.trailingSL, price-p.trailingSLDist)
+		// Check if the current candle's high and lows contain any take profits or stop losses.
+		open, high, low, ok := b.symbolCandle(p.symbol)
+		if !ok {
+			continue
 		}
+		price := b.Price(p.symbol, p.units < 0) // We want to buy if we are short, and vice versa.
 
-		// Check if the position should be closed.
-		if p.takeProfit > 0 {
-			if (p.units > 0 && p.takeProfit <= high) || (p.units < 0 && p.takeProfit >= low) {
-				p.close(p.takeProfit, CloseTakeProfit)
+		if gap {
+			if stop, stopType := p.stop(); stop > 0 && (p.units > 0 && open <= stop || p.units < 0 && open >= stop) {
+				p.gapped = true
+				p.closeAt(open, stop, stopType)
+				continue
+			} else if p.takeProfit > 0 && (p.units > 0 && open >= p.takeProfit || p.units < 0 && open <= p.takeProfit) {
+				p.gapped = true
+				p.closeAt(open, p.takeProfit, CloseTakeProfit)
 				continue
 			}
 		}
-		// stopLoss won't be set if trailingSL is set, and vice versa.
-		if p.stopLoss > 0 {
-			if (p.units > 0 && p.stopLoss >= low) || (p.units < 0 && p.stopLoss <= high) {
-				p.close(p.stopLoss, CloseStopLoss)
+
+		if p.trailing.Value > 0 {
+			// The stop only moves in favor of the position: up for a long and down for a short.
+			distance := b.trailingDistance(p.symbol, p.trailing, price)
+			if trailingSL := price - distance; p.units > 0 && trailingSL > p.trailingSL {
+				p.trailingSL = trailingSL
+				b.SignalEmit(PositionModified, p)
+			} else if trailingSL := price + distance; p.units < 0 && (p.trailingSL == 0 || trailingSL < p.trailingSL) {
+				p.trailingSL = trailingSL
+				b.SignalEmit(PositionModified, p)
 			}
-		} else if p.trailingSL > 0 {
-			if (p.units > 0 && p.trailingSL >= low) || (p.units < 0 && p.trailingSL <= high) {
-				p.close(p.trailingSL, CloseTrailingStop)
+		} else if stop, ok := p.breakEven.Stop(p.units, p.entryPrice, p.stopLoss, price); ok {
+			p.stopLoss = stop
+			b.SignalEmit(PositionModified, p)
+		}
+
+		// Check if the position should be closed.
+		stop, stopType := p.stop()
+		hitStop := stop > 0 && (p.units > 0 && stop >= low || p.units < 0 && stop <= high)
+		hitTP := p.takeProfit > 0 && (p.units > 0 && p.takeProfit <= high || p.units < 0 && p.takeProfit >= low)
+		if hitStop || hitTP {
+			entry, entered := filled[p]
+			hitStop, hitTP = b.firstExit(p, stop, hitStop, hitTP, entry, entered)
+		}
+		if hitStop {
+			p.close(stop, stopType)
+		} else if hitTP {
+			p.close(p.takeProfit, CloseTakeProfit)
+			continue
+		}
+		// A position that outlived its maximum holding period is closed at the close, unless a stop or take profit got it first.
+		if !p.closed && p.maxHolding.Expired(b.advances-p.openedAt, b.Now().Sub(p.time)) {
+			p.close(price, CloseTimeExit)
+		}
+	}
+	b.checkMargin()
+}
+
+// pendingOrders returns the open orders in the order they are filled on the current candle, which is the order their prices are reached along the intrabar path, or the order they were placed in with IntrabarWorstCase.
+func (b *TestBroker) pendingOrders() []*TestOrder {
+	var pending []*TestOrder
+	for _, order := range b.orders {
+		if o := order.(*TestOrder); !o.Fulfilled() && !o.cancelled {
+			pending = append(pending, o)
+		}
+	}
+	if b.Intrabar != IntrabarWorstCase && len(pending) > 1 {
+		reached := make(map[*TestOrder]float64, len(pending))
+		for _, o := range pending {
+			reached[o] = b.orderReached(o)
+		}
+		slices.SortStableFunc(pending, func(a, b *TestOrder) bool { return reached[a] < reached[b] })
+	}
+	return pending
+}
+
+// orderReached returns where along the intrabar path of the current candle the price of o was first reached, which is zero for market orders and with IntrabarWorstCase, and +Inf if it was not reached.
+func (b *TestBroker) orderReached(o *TestOrder) float64 {
+	if o.orderType == Market || b.Intrabar == IntrabarWorstCase {
+		return 0
+	}
+	if _, _, _, ok := b.symbolCandle(o.symbol); !ok {
+		return math.Inf(1)
+	}
+	// Buy limits and sell stops are reached from above, and sell limits and buy stops from below.
+	return pathTouch(b.intrabarPath(o.symbol), o.price, (o.orderType == Limit) == (o.units > 0), 0)
+}
+
+// firstExit returns which of the stop and the take profit of p, whose prices were within the range of the current candle as given by hitStop and hitTP, closes the position. If entered is true, p was filled on the candle at entry along its intrabar path.
+func (b *TestBroker) firstExit(p *TestPosition, stop float64, hitStop, hitTP bool, entry float64, entered bool) (bool, bool) {
+	if b.Intrabar == IntrabarWorstCase {
+		return hitStop, hitTP && !hitStop && !entered
+	}
+	path := b.intrabarPath(p.symbol)
+	atStop, atTP := math.Inf(1), math.Inf(1)
+	if hitStop {
+		atStop = pathTouch(path, stop, p.units > 0, entry)
+	}
+	if hitTP {
+		atTP = pathTouch(path, p.takeProfit, p.units < 0, entry)
+	}
+	if math.IsInf(atStop, 1) && math.IsInf(atTP, 1) {
+		return false, false
+	}
+	return atStop <= atTP, atTP < atStop
+}
+
+// intrabarPath returns the prices the current candle of symbol is assumed to have moved through, from its open to its close. The candle must exist.
+func (b *TestBroker) intrabarPath(symbol string) []float64 {
+	data, row := b.symbolRow(symbol)
+	if b.Intrabar == IntrabarSubCandles && data == b.Data && b.SubCandles != nil {
+		sub, start := b.SubCandles, *data.Date(row)
+		var end *UnixTime // end is the date of the next candle, if there is one.
+		if row+1 < data.Len() {
+			end = data.Date(row + 1)
+		}
+		var path []float64
+		for i := sort.Search(sub.Len(), func(i int) bool { return *sub.Date(i) >= start }); i < sub.Len(); i++ {
+			if end != nil && *sub.Date(i) >= *end {
+				break
 			}
+			path = append(path, ohlcPath(sub.Open(i), sub.High(i), sub.Low(i), sub.Close(i))...)
+		}
+		if len(path) > 0 {
+			return path
+		}
+	}
+	return ohlcPath(data.Open(row), data.High(row), data.Low(row), data.Close(row))
+}
+
+// ohlcPath returns the path of a candle that moved from its open to the nearer of its high and low, then to the other, then to its close.
+func ohlcPath(open, high, low, close float64) []float64 {
+	if high-open < open-low {
+		return []float64{open, high, low, close}
+	}
+	return []float64{open, low, high, close}
+}
+
+// pathTouch returns where along path, from 0 at its first price to len(path)-1 at its last, the price first reached level at or after from: at or below level if below is true, and at or above it otherwise. It returns +Inf if the price never reached level.
+func pathTouch(path []float64, level float64, below bool, from float64) float64 {
+	reached := func(price float64) bool {
+		if below {
+			return price <= level
+		}
+		return price >= level
+	}
+	for i := int(from); i < len(path); i++ {
+		start, price := float64(i), path[i]
+		if start < from { // Start within the segment, at the price interpolated at from.
+			start, price = from, path[i]+(from-start)*(path[i+1]-path[i])
+		}
+		if reached(price) {
+			return start
+		} else if i+1 < len(path) && reached(path[i+1]) {
+			return float64(i) + (level-path[i])/(path[i+1]-path[i])
 		}
 	}
+	return math.Inf(1)
+}
+
+// expireOrders expires the open GoodTilDate orders whose expiry is before the current candle, even while the market is closed, so they cannot be filled on it.
+func (b *TestBroker) expireOrders() {
+	now := b.Now()
+	for _, order := range b.orders {
+		if o := order.(*TestOrder); !o.Fulfilled() && !o.cancelled && o.timeInForce == GoodTilDate && now.After(o.expiry) {
+			o.expire()
+		}
+	}
+}
+
+// settleExpired closes the positions of contracts that have expired, futures at their price and options at their intrinsic value.
+func (b *TestBroker) settleExpired() {
+	if len(b.Instruments) == 0 {
+		return
+	}
+	now := b.Now()
+	for _, any_p := range b.positions {
+		if any_p.Closed() {
+			continue
+		}
+		p := any_p.(*TestPosition)
+		instrument, ok := b.Instruments[p.symbol]
+		if !ok || !instrument.Expired(now) {
+			continue
+		}
+		price := b.Price(p.symbol, p.units < 0)
+		if instrument.Type == Option && instrument.Underlying != "" { // Orders for options without an underlying fail, but positions loaded from a portfolio are settled at their own price.
+			price = instrument.IntrinsicValue(b.Price(instrument.Underlying, false))
+		}
+		p.close(price, CloseExpiry)
+	}
+}
+
+// trailingDistance returns the distance of a trailing stop from price on the current candle of symbol.
+func (b *TestBroker) trailingDistance(symbol string, trailing TrailingStop, price float64) float64 {
+	data, row := b.symbolRow(symbol)
+	return trailing.Distance(price, data, Max(row, 0))
 }
 
 // Price returns the ask price if wantToBuy is true and the bid price if wantToBuy is false.
@@ -452,7 +789,15 @@ func (b *TestBroker) Price(symbol string, wantToBuy bool) float64 {
 	return b.Bid(symbol)
 }
 
-func (b *TestBroker) lastClose() float64 {
+// lastClose returns the close of the current candle of symbol, or zero if the symbol has no candle yet.
+func (b *TestBroker) lastClose(symbol string) float64 {
+	if _, ok := b.SymbolData[symbol]; ok {
+		data, row := b.symbolRow(symbol)
+		if row < 0 {
+			return 0
+		}
+		return data.Close(row)
+	}
 	if b.CandleIndex() < b.Data.Len() {
 		return b.Data.Close(b.CandleIndex())
 	} else {
@@ -461,26 +806,43 @@ func (b *TestBroker) lastClose() float64 {
 }
 
 // Bid returns the price a seller receives for the current candle.
-func (b *TestBroker) Bid(_ string) float64 {
-	return b.lastClose()
+func (b *TestBroker) Bid(symbol string) float64 {
+	if b.SplitSpread {
+		return b.lastClose(symbol) - b.spread(symbol)/2
+	}
+	return b.lastClose(symbol)
 }
 
 // Ask returns the price a buyer pays for the current candle.
-func (b *TestBroker) Ask(_ string) float64 {
-	return b.lastClose() + b.Spread
+func (b *TestBroker) Ask(symbol string) float64 {
+	if b.SplitSpread {
+		return b.lastClose(symbol) + b.spread(symbol)/2
+	}
+	return b.lastClose(symbol) + b.spread(symbol)
 }
 
 // Candles returns the last count candles for the given symbol and frequency. If count is greater than the number of candles, then a dataframe with zero rows is returned.
 //
 // If the TestBroker has a data broker set, then it will use that to get candles. Otherwise, it will return the candles from the data that was set. The first call to Candles will fetch candles from the data broker if it is set, so it is recommended to set the data broker before the first call to Candles and to call Candles the first time with the number of candles you want to fetch.
-func (b *TestBroker) Candles(symbol string, frequency string, count int) (*IndexedFrame[UnixTime], error) {
+func (b *TestBroker) Candles(ctx context.Context, symbol string, frequency string, count int) (*IndexedFrame[UnixTime], error) {
+	if b.Frequency != "" && frequency != "" && frequency != b.Frequency {
+		return b.resampledCandles(ctx, symbol, frequency, count)
+	}
+	if b.streamErr != nil {
+		return nil, b.streamErr
+	} else if err := b.readStream(); err != nil {
+		return nil, err
+	}
+	if data, ok := b.SymbolData[symbol]; ok && b.Data != nil {
+		return b.symbolCandles(data, symbol, count)
+	}
 	start := Max(Max(b.candleCount, 1)-count, 0)
 	adjCount := b.candleCount - start
 
 	if b.Data != nil && b.candleCount >= b.Data.Len() { // We have data and we are at the end of it.
-		return b.Data.CopyRange(-count, -1), ErrEOF // Return the last count candles.
+		return b.window(-count, -1), ErrEOF // Return the last count candles.
 	} else if b.DataBroker != nil && b.Data == nil { // We have a data broker but no data.
-		candles, err := b.DataBroker.Candles(symbol, frequency, count)
+		candles, err := b.DataBroker.Candles(ctx, symbol, frequency, count)
 		if err != nil {
 			return nil, err
 		}
@@ -488,53 +850,144 @@ func (b *TestBroker) Candles(symbol string, frequency string, count int) (*Index
 	} else if b.Data == nil { // Both b.DataBroker and b.Data are nil.
 		return nil, ErrNoData
 	}
-	return b.Data.CopyRange(start, adjCount), nil
+	return b.window(start, adjCount), nil
+}
+
+// symbolCandles returns the last count candles of symbol in data up to its current candle.
+func (b *TestBroker) symbolCandles(data *IndexedFrame[UnixTime], symbol string, count int) (*IndexedFrame[UnixTime], error) {
+	_, row := b.symbolRow(symbol)
+	end := row + 1
+	start := Max(end-count, 0)
+	var candles *IndexedFrame[UnixTime]
+	if b.ShareCandles {
+		candles = data.View(start, end-start)
+	} else {
+		candles = data.CopyRange(start, end-start)
+	}
+	if b.candleCount >= b.Data.Len() {
+		return candles, ErrEOF
+	}
+	return candles, nil
+}
+
+// window returns the rows of Data in the given range as a copy, or as a view if ShareCandles is set.
+func (b *TestBroker) window(start, count int) *IndexedFrame[UnixTime] {
+	if b.ShareCandles {
+		return b.Data.View(start, count)
+	}
+	return b.Data.CopyRange(start, count)
 }
 
-func (b *TestBroker) Order(orderType OrderType, symbol string, units, price, stopLoss, takeProfit float64) (Order, error) {
-	if units == 0 {
-		return nil, ErrInvalidUnits
+// resampledCandles returns the last count closed candles of frequency resampled from the visible candles of Data.
+func (b *TestBroker) resampledCandles(ctx context.Context, symbol, frequency string, count int) (*IndexedFrame[UnixTime], error) {
+	baseDuration, err := FrequencyDuration(b.Frequency)
+	if err != nil {
+		return nil, err
+	}
+	duration, err := FrequencyDuration(frequency)
+	if err != nil {
+		return nil, err
 	}
+	// Fetch enough base candles to fill count candles of frequency, plus the one that may still be open.
+	perCandle := int((duration + baseDuration - 1) / baseDuration)
+	if frequency == "M" {
+		perCandle = perCandle * 31 / 30 // Months can be longer than the approximate duration.
+	}
+	base, err := b.Candles(ctx, symbol, b.Frequency, (count+1)*perCandle)
+	if err != nil && err != ErrEOF {
+		return nil, err
+	}
+	candles, resampleErr := ResampleClosed(base, b.Frequency, frequency)
+	if resampleErr != nil {
+		return nil, resampleErr
+	}
+	if candles.Len() > count {
+		// The first candle may be missing base candles, so it is only kept when it is needed.
+		candles = candles.CopyRange(-count, -1)
+	}
+	return candles, err
+}
+
+func (b *TestBroker) Order(ctx context.Context, orderType OrderType, symbol string, units, price, stopLoss, takeProfit float64, options ...OrderOption) (Order, error) {
 	if b.Data == nil { // The DataBroker could have data but nobody has fetched it, yet.
-		if b.DataBroker == nil {
+		if b.DataBroker == nil && b.Stream == nil {
 			return nil, ErrNoData
 		}
-		_, err := b.Candles("", "", 1) // Fetch data from the DataBroker.
+		_, err := b.Candles(ctx, "", "", 1) // Fetch data from the DataBroker.
 		if err != nil {
 			return nil, err
 		}
 	}
+	if _, ok := b.SymbolData[symbol]; ok {
+		if _, row := b.symbolRow(symbol); row < 0 {
+			return nil, ErrNoData // The symbol has no price before its first candle.
+		}
+	}
 
-	var trailingSL float64
-	if stopLoss < 0 {
-		trailingSL = -stopLoss
+	orderOptions := NewOrderOptions(options...)
+	trailing := orderOptions.TrailingStop
+	if trailing.Value > 0 {
+		stopLoss = 0 // The trailing stop replaces the stop loss.
+	} else if stopLoss < 0 {
+		trailing = TrailingStop{Mode: TrailingDistance, Value: -stopLoss}
 	}
 
-	marketPrice := b.Price("", units > 0)
+	rate, err := b.conversionRate(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	marketPrice := b.Price(symbol, units > 0)
 	if orderType == Market {
 		price = marketPrice
+	} else if instrument := b.instrument(symbol); instrument.TickSize > 0 {
+		price = instrument.RoundPrice(price)
+		stopLoss, takeProfit = instrument.RoundPrice(stopLoss), instrument.RoundPrice(takeProfit)
+	}
+	if err := b.validateOrder(orderType, symbol, units, price, stopLoss, takeProfit, marketPrice, rate); err != nil {
+		return nil, err
+	}
+	if orderOptions.TimeInForce == GoodTilDate && orderOptions.Expiry.IsZero() {
+		return nil, &OrderError{Err: ErrInvalidExpiry, OrderType: orderType, Symbol: symbol, Units: units, Price: price, Reason: "good til date without an expiry"}
 	}
 
 	order := &TestOrder{
 		broker:     b,
-		id:         strconv.Itoa(rand.Int()),
+		id:         strconv.Itoa(b.random().Int()),
 		leverage:   b.Leverage,
 		position:   nil,
 		price:      price,
 		symbol:     symbol,
 		takeProfit: takeProfit,
-		time:       time.Now(),
+		time:       b.Now(),
 		orderType:  orderType,
 		units:      units,
+		rate:       rate,
+		tags:       orderOptions.Tags,
+		trailing:   trailing,
+		breakEven:  orderOptions.BreakEven,
+		maxHolding: orderOptions.MaxHolding,
+
+		timeInForce: orderOptions.TimeInForce,
+		expiry:      orderOptions.Expiry,
 	}
-	if trailingSL > 0 {
-		order.trailingSL = trailingSL
+	if trailing.Value > 0 {
+		order.trailingSL = b.trailingDistance(symbol, trailing, price)
 	} else {
 		order.stopLoss = stopLoss
 	}
 
+	b.orders = append(b.orders, order)
+	if b.ordersByID == nil {
+		b.ordersByID = make(map[string]*TestOrder)
+	}
+	b.ordersByID[order.id] = order
+	b.SignalEmit(OrderPlaced, order)
+
 	// TODO: only instantly fulfill market orders or sometimes limit orders when requirements are met.
-	if orderType == Market {
+	if !b.marketOpen() {
+		// The order is queued until the market opens.
+	} else if orderType == Market {
 		order.fulfill(price)
 	} else if orderType == Limit {
 		if units > 0 && marketPrice <= order.price {
@@ -543,13 +996,177 @@ func (b *TestBroker) Order(orderType OrderType, symbol string, units, price, sto
 			order.fulfill(price)
 		}
 	}
-
-	b.orders = append(b.orders, order)
-	b.SignalEmit(OrderPlaced, order)
+	// Orders are always filled entirely, so the unfilled orders of both are killed.
+	if !order.Fulfilled() && (order.timeInForce == FillOrKill || order.timeInForce == ImmediateOrCancel) {
+		order.expire()
+	}
 
 	return order, nil
 }
 
+// offset closes the units of an order for units of symbol filled at atPrice that are opposite to the open positions of symbol, from the oldest, unless the PositionMode is PositionHedging. It returns the units left over to open or add to a position and the last position it closed units of.
+func (b *TestBroker) offset(symbol string, units, atPrice, requested float64) (float64, *TestPosition) {
+	var closed *TestPosition
+	if b.PositionMode == PositionHedging {
+		return units, closed
+	}
+	for _, position := range b.positions {
+		p := position.(*TestPosition)
+		if units == 0 || p.closed || p.symbol != symbol || (p.units > 0) == (units > 0) {
+			continue
+		}
+		if math.Abs(units) >= math.Abs(p.units) {
+			units += p.units
+			p.closeAt(atPrice, requested, CloseMarket)
+			closed = p
+		} else {
+			closed = p.reduce(-units, atPrice, requested, CloseMarket)
+			units = 0
+		}
+	}
+	return units, closed
+}
+
+// oppositeUnits returns the absolute units of the open positions of symbol that an order for units would close before opening anything, which is zero if the PositionMode is PositionHedging.
+func (b *TestBroker) oppositeUnits(symbol string, units float64) float64 {
+	if b.PositionMode == PositionHedging {
+		return 0
+	}
+	var opposite float64
+	for _, position := range b.positions {
+		if p := position.(*TestPosition); !p.closed && p.symbol == symbol && (p.units > 0) != (units > 0) {
+			opposite += math.Abs(p.units)
+		}
+	}
+	return opposite
+}
+
+// nettingPosition returns the open position of symbol to add the units of an order to if the PositionMode is PositionNetting, or nil.
+func (b *TestBroker) nettingPosition(symbol string) *TestPosition {
+	if b.PositionMode != PositionNetting {
+		return nil
+	}
+	for _, position := range b.positions {
+		if p := position.(*TestPosition); !p.closed && p.symbol == symbol {
+			return p
+		}
+	}
+	return nil
+}
+
+// marketOpen returns true if the market is open on the current candle.
+func (b *TestBroker) marketOpen() bool {
+	return b.Calendar == nil || b.Data == nil || b.Data.Len() == 0 || b.Calendar.IsOpen(b.Now())
+}
+
+// gapped returns true if the market closed between the previous candle and the current candle, so the current candle may open with a gap.
+func (b *TestBroker) gapped() bool {
+	i := b.CandleIndex()
+	if b.Calendar == nil || i < 1 || i >= b.Data.Len() {
+		return false
+	}
+	prev := b.Data.Date(i - 1).Time()
+	return !b.Calendar.IsOpen(prev) || b.Calendar.NextClose(prev).Before(b.Data.Date(i).Time())
+}
+
+// openPrice returns the price of buying or selling at the open of the current candle.
+func (b *TestBroker) openPrice(symbol string, open float64, wantToBuy bool) float64 {
+	spread := b.spread(symbol)
+	if b.SplitSpread {
+		open -= spread / 2
+	}
+	if wantToBuy {
+		return open + spread
+	}
+	return open
+}
+
+// validateOrder returns an OrderError if the order would be rejected by a real broker. The price is the market price for market orders.
+func (b *TestBroker) validateOrder(orderType OrderType, symbol string, units, price, stopLoss, takeProfit, marketPrice, rate float64) error {
+	reject := func(err error, reason string) error {
+		return &OrderError{Err: err, OrderType: orderType, Symbol: symbol, Units: units, Price: price, Reason: reason}
+	}
+	if len(b.Symbols) > 0 && !slices.Contains(b.Symbols, symbol) {
+		return reject(ErrSymbolNotFound, "")
+	}
+	if !b.QueueClosedOrders && !b.marketOpen() {
+		return reject(ErrMarketClosed, "")
+	}
+	instrument := b.instrument(symbol)
+	if instrument.Expired(b.Now()) {
+		return reject(ErrContractExpired, "expired "+instrument.Expiry.Format(time.DateOnly))
+	}
+	if instrument.Type == Option && instrument.Underlying == "" {
+		return reject(ErrNoUnderlying, "")
+	}
+	if instrument.MinUnits == 0 {
+		instrument.MinUnits = b.MinUnits
+	}
+	if err := ValidateOrder(instrument, orderType, units, price, stopLoss, takeProfit, marketPrice); err != nil {
+		return err
+	}
+	// Only the units left after closing opposite positions need margin, so a fully margined account can still reduce or flip its position.
+	opening := Max(math.Abs(units)-b.oppositeUnits(symbol, units), 0)
+	if required, available := opening*math.Abs(price*rate*b.instrument(symbol).ContractMultiplier())/Max(b.Leverage, 1), b.MarginAvailable(); required > 0 && required > available {
+		return reject(ErrInsufficientMargin, fmt.Sprintf("requires %.2f of margin but %.2f is available", required, available))
+	}
+	return nil
+}
+
+// MarginUsed returns the margin held by the open positions, which is their value divided by their leverage.
+func (b *TestBroker) MarginUsed() float64 {
+	var margin float64
+	for _, position := range b.positions {
+		if !position.Closed() {
+			margin += math.Abs(position.Value()) / Max(position.Leverage(), 1)
+		}
+	}
+	return margin
+}
+
+// MarginAvailable returns the NAV that is not held as margin by the open positions, which new orders can use. It is negative when the losses of the positions have eaten into their margin.
+func (b *TestBroker) MarginAvailable() float64 {
+	return b.NAV() - b.MarginUsed()
+}
+
+// MarginLevel returns the NAV divided by the margin used, like 2 for an account with twice the margin its positions use, or +Inf without open positions.
+func (b *TestBroker) MarginLevel() float64 {
+	used := b.MarginUsed()
+	if used == 0 {
+		return math.Inf(1)
+	}
+	return b.NAV() / used
+}
+
+// checkMargin emits MarginCall when the margin level first falls below MarginCallLevel, and closes the open positions from the largest loss at the current price, with CloseMarginCall, while it is below MarginCloseoutLevel.
+func (b *TestBroker) checkMargin() {
+	if b.MarginCallLevel <= 0 && b.MarginCloseoutLevel <= 0 {
+		return
+	}
+	level := b.MarginLevel()
+	if level >= b.MarginCallLevel && level >= b.MarginCloseoutLevel {
+		b.marginCalled = false
+		return
+	}
+	if !b.marginCalled {
+		b.marginCalled = true
+		b.SignalEmit(MarginCall, MarginEvent{NAV: b.NAV(), MarginUsed: b.MarginUsed(), Level: level})
+	}
+	for level < b.MarginCloseoutLevel {
+		var worst *TestPosition
+		for _, position := range b.positions {
+			if p := position.(*TestPosition); !p.closed && (worst == nil || p.PL() < worst.PL()) {
+				worst = p
+			}
+		}
+		if worst == nil {
+			return
+		}
+		worst.close(b.Price(worst.symbol, worst.units < 0), CloseMarginCall)
+		level = b.MarginLevel()
+	}
+}
+
 func (b *TestBroker) NAV() float64 {
 	nav := b.Cash
 	// Add the value of open positions to our NAV.
@@ -572,7 +1189,7 @@ func (b *TestBroker) PL() float64 {
 func (b *TestBroker) OpenOrders() []Order {
 	orders := make([]Order, 0, len(b.orders))
 	for _, order := range b.orders {
-		if !order.Fulfilled() {
+		if !order.Fulfilled() && !order.(*TestOrder).cancelled {
 			orders = append(orders, order)
 		}
 	}
@@ -597,21 +1214,80 @@ func (b *TestBroker) Positions() []Position {
 	return b.positions
 }
 
+func (b *TestBroker) OrderByID(ctx context.Context, id string) (Order, error) {
+	if order, ok := b.ordersByID[id]; ok {
+		return order, nil
+	}
+	return nil, ErrOrderNotFound
+}
+
+func (b *TestBroker) PositionByID(ctx context.Context, id string) (Position, error) {
+	if position, ok := b.positionsByID[id]; ok {
+		return position, nil
+	}
+	return nil, ErrPositionNotFound
+}
+
+// Capabilities returns the capabilities of the TestBroker, which supports every order type and trailing stop mode, holds any number of positions in a symbol, and emits CandleClosed as it advances. Its MinFrequency is its Frequency.
+func (b *TestBroker) Capabilities() Capabilities {
+	return Capabilities{
+		OrderTypes:    []OrderType{Market, Limit, Stop},
+		StopLoss:      true,
+		TakeProfit:    true,
+		TrailingStops: []TrailingStopMode{TrailingDistance, TrailingPercent, TrailingATR},
+		Hedging:       b.PositionMode == PositionHedging,
+		Streaming:     true,
+		MinFrequency:  b.Frequency,
+	}
+}
+
 type TestPosition struct {
-	broker         *TestBroker
-	closed         bool
-	entryPrice     float64
-	closePrice     float64        // If zero, then position has not been closed.
-	closeType      OrderCloseType // SL, TS, TP
-	id             string
-	leverage       float64
-	symbol         string
-	trailingSL     float64 // The price of the trailing stop loss as assigned by broker Tick().
-	trailingSLDist float64 // Serves to calculate the trailing stop loss at the broker.
-	stopLoss       float64
-	takeProfit     float64
-	time           time.Time
-	units          float64 // Is negative if this is a short position or positive for long.
+	broker     *TestBroker
+	closed     bool
+	entryPrice float64
+	closePrice float64        // If zero, then position has not been closed.
+	closeType  OrderCloseType // SL, TS, TP
+	closeCosts TradeCosts
+	financing  float64 // The funding and dividends paid while the position was open, which is negative if more was received.
+	tags       Tags
+	entryRate  float64 // The conversion rate into the account currency when the position was opened.
+	rate       float64 // The latest conversion rate into the account currency.
+	multiplier float64 // The contract multiplier of the instrument.
+	id         string
+	leverage   float64
+	symbol     string
+	trailingSL float64      // The price of the trailing stop loss as assigned by broker Tick().
+	trailing   TrailingStop // Serves to calculate the trailing stop loss at the broker.
+	breakEven  BreakEven
+	maxHolding MaxHolding
+	openedAt   int // The number of candles the broker had advanced when the position was opened.
+	stopLoss   float64
+	takeProfit float64
+	time       time.Time
+	units      float64 // Is negative if this is a short position or positive for long.
+	gapped     bool    // The position was closed at an open that gapped past its stop loss or take profit.
+}
+
+// SetStopLoss replaces the stop loss of the position with price, which must be on the losing side of the current price. A trailing stop is removed.
+func (p *TestPosition) SetStopLoss(price float64) error {
+	if p.closed {
+		return ErrPositionClosed
+	}
+	if current := p.broker.Price(p.symbol, p.units < 0); price <= 0 || p.units > 0 && price >= current || p.units < 0 && price <= current {
+		return ErrInvalidStopLoss
+	}
+	p.stopLoss = price
+	p.trailing, p.trailingSL = TrailingStop{}, 0
+	p.broker.SignalEmit(PositionModified, p)
+	return nil
+}
+
+// stop returns the price of the stop that closes the position first and whether it is the stop loss or the trailing stop, which is the higher of the two for a long and the lower for a short. The price is zero if the position has neither.
+func (p *TestPosition) stop() (float64, OrderCloseType) {
+	if p.trailingSL == 0 || p.stopLoss > 0 && (p.units > 0 && p.stopLoss >= p.trailingSL || p.units < 0 && p.stopLoss <= p.trailingSL) {
+		return p.stopLoss, CloseStopLoss
+	}
+	return p.trailingSL, CloseTrailingStop
 }
 
 func (p *TestPosition) Close() error {
@@ -620,17 +1296,34 @@ func (p *TestPosition) Close() error {
 }
 
 func (p *TestPosition) close(atPrice float64, closeType OrderCloseType) {
+	p.closeAt(atPrice, atPrice, closeType)
+}
+
+// closeAt closes the position at atPrice when the requested price was different, so the difference is counted as slippage.
+func (p *TestPosition) closeAt(atPrice, requested float64, closeType OrderCloseType) {
 	if p.closed {
 		return
 	}
 	p.closed = true
 	p.closePrice = atPrice
 	p.closeType = closeType
+	p.updateRate()
+	// Closing a position sells long units and buys back short units.
+	p.closeCosts = p.broker.fillCosts(p.symbol, -p.units, atPrice, requested, p.rate, closeType == CloseMarket)
+	p.closeCosts.Financing = p.financing
 	p.broker.Cash += p.Value() // Return the value of the position to the broker.
-	p.broker.spreadCollectedUSD += p.broker.Spread * math.Abs(p.units) * p.closePrice
+	p.broker.Cash -= p.closeCosts.Commission
+	p.broker.spreadCollectedUSD += p.closeCosts.Spread
+	p.broker.spreadPips += p.closeCosts.SpreadPips
+	p.broker.commissionPaid += p.closeCosts.Commission
 	p.broker.SignalEmit(PositionClosed, p)
 }
 
+// Gapped returns true if the position was closed at the open of a candle that gapped past its stop loss or take profit after the market was closed.
+func (p *TestPosition) Gapped() bool {
+	return p.gapped
+}
+
 func (p *TestPosition) Closed() bool {
 	return p.closed
 }
@@ -643,12 +1336,16 @@ func (p *TestPosition) EntryPrice() float64 {
 	return p.entryPrice
 }
 
+func (p *TestPosition) CloseCosts() TradeCosts {
+	return p.closeCosts
+}
+
 func (p *TestPosition) ClosePrice() float64 {
 	return p.closePrice
 }
 
 func (p *TestPosition) EntryValue() float64 {
-	return p.entryPrice * p.units
+	return p.entryPrice * p.units * p.entryRate * p.multiplier
 }
 
 func (p *TestPosition) Id() string {
@@ -675,6 +1372,10 @@ func (p *TestPosition) StopLoss() float64 {
 	return p.stopLoss
 }
 
+func (p *TestPosition) Tags() Tags {
+	return p.tags
+}
+
 func (p *TestPosition) TakeProfit() float64 {
 	return p.takeProfit
 }
@@ -689,57 +1390,173 @@ func (p *TestPosition) Units() float64 {
 
 func (p *TestPosition) Value() float64 {
 	if p.closed {
-		return p.closePrice * p.units
+		return p.closePrice * p.units * p.rate * p.multiplier
+	}
+	p.updateRate()
+	return p.broker.Price(p.symbol, p.units > 0) * p.units * p.rate * p.multiplier
+}
+
+// add adds units opened at atPrice with the conversion rate rate to the position, whose entry price becomes the average of both, and returns the entry value of the added units.
+func (p *TestPosition) add(units, atPrice, rate float64) float64 {
+	value := atPrice * units * rate * p.multiplier
+	entryValue := p.EntryValue() + value
+	p.units += units
+	p.entryPrice = entryValue / (p.units * p.entryRate * p.multiplier)
+	return value
+}
+
+// reduce closes units of the position, which have its sign and are fewer than its units, at atPrice like closeAt. The closed units are split off into a closed position with the same ID, which is returned, so PositionClosed reports their exit while the rest of the position stays open.
+func (p *TestPosition) reduce(units, atPrice, requested float64, closeType OrderCloseType) *TestPosition {
+	part := *p
+	part.units = units
+	part.financing = p.financing * units / p.units
+	p.financing -= part.financing
+	p.units -= units
+	p.broker.positions = append(p.broker.positions, &part)
+	part.closeAt(atPrice, requested, closeType)
+	return &part
+}
+
+// updateRate refreshes the conversion rate of the position. The last known rate is kept if a new rate is not available.
+func (p *TestPosition) updateRate() {
+	if rate, err := p.broker.conversionRate(p.symbol); err == nil {
+		p.rate = rate
 	}
-	return p.broker.Price("", p.units > 0) * p.units
 }
 
 type TestOrder struct {
 	broker     *TestBroker
+	costs      TradeCosts
+	rate       float64 // The conversion rate into the account currency when the order was placed.
+	tags       Tags
 	id         string
 	leverage   float64
 	position   *TestPosition
 	price      float64
 	symbol     string
-	trailingSL float64
+	trailingSL float64 // The distance of the trailing stop loss when the order was placed.
+	trailing   TrailingStop
+	breakEven  BreakEven
+	maxHolding MaxHolding
 	stopLoss   float64
 	takeProfit float64
 	time       time.Time
 	orderType  OrderType
 	units      float64
+	gapped     bool // The order was filled at the open of a candle after the market was closed.
+	cancelled  bool
+	// timeInForce and expiry are how long the order stays open. An expired order is also cancelled.
+	timeInForce TimeInForce
+	expiry      time.Time
+	expired     bool
 }
 
+// Cancel cancels the order if it has not been filled, which emits OrderCancelled. ErrCancelFailed is returned if it was already filled, cancelled, or expired.
 func (o *TestOrder) Cancel() error {
-	return ErrCancelFailed
+	if o.position != nil || o.cancelled {
+		return ErrCancelFailed
+	}
+	o.cancelled = true
+	o.broker.SignalEmit(OrderCancelled, o)
+	return nil
+}
+
+// Expired returns true if the order expired because of its time in force before it was filled.
+func (o *TestOrder) Expired() bool {
+	return o.expired
+}
+
+// expire cancels the order because of its time in force, which emits OrderExpired.
+func (o *TestOrder) expire() {
+	o.cancelled, o.expired = true, true
+	o.broker.SignalEmit(OrderExpired, o)
 }
 
 func (o *TestOrder) fulfill(atPrice float64) {
-	slippage := rand.Float64() * o.broker.Slippage * atPrice
+	o.fulfillAt(atPrice, atPrice)
+}
+
+// fulfillAt fills the order at atPrice plus slippage when the requested price was different, so the difference is counted as slippage.
+func (o *TestOrder) fulfillAt(atPrice, requested float64) {
+	slippage := o.broker.random().Float64() * o.broker.Slippage * atPrice
 	atPrice += slippage / 2 // Adjust price as +/- 50% of the slippage.
+	atPrice += o.broker.marketImpact(o.symbol, o.units, atPrice)
+	if rate, err := o.broker.conversionRate(o.symbol); err == nil {
+		o.rate = rate
+	}
+	units, closed := o.broker.offset(o.symbol, o.units, atPrice, requested)
+	if units == 0 { // The order only closed positions, which emitted PositionClosed for its units.
+		o.position = closed
+		o.broker.SignalEmit(OrderFulfilled, o)
+		return
+	}
+	o.costs = o.broker.fillCosts(o.symbol, units, atPrice, requested, o.rate, o.orderType == Market)
+	if position := o.broker.nettingPosition(o.symbol); position != nil {
+		o.position = position
+		o.broker.Cash -= position.add(units, atPrice, o.rate)
+		if o.trailing.Value > 0 {
+			position.trailing, position.trailingSL, position.stopLoss = o.trailing, 0, 0
+		} else if o.stopLoss != 0 {
+			position.stopLoss, position.trailing, position.trailingSL = o.stopLoss, TrailingStop{}, 0
+		}
+		if o.takeProfit != 0 {
+			position.takeProfit = o.takeProfit
+		}
+		o.broker.Cash -= o.costs.Commission
+		o.broker.spreadCollectedUSD += o.costs.Spread
+		o.broker.spreadPips += o.costs.SpreadPips
+		o.broker.commissionPaid += o.costs.Commission
+		o.broker.SignalEmit(OrderFulfilled, o)
+		return
+	}
 
 	o.position = &TestPosition{
 		broker:     o.broker,
 		closed:     false,
 		entryPrice: atPrice,
-		id:         strconv.Itoa(rand.Int()),
+		entryRate:  o.rate,
+		tags:       o.tags,
+		rate:       o.rate,
+		multiplier: o.broker.instrument(o.symbol).ContractMultiplier(),
+		id:         strconv.Itoa(o.broker.random().Int()),
 		leverage:   o.leverage,
 		symbol:     o.symbol,
 		takeProfit: o.takeProfit,
-		time:       time.Now(),
-		units:      o.units,
+		time:       o.broker.Now(),
+		units:      units,
+		breakEven:  o.breakEven,
+		maxHolding: o.maxHolding,
+		openedAt:   o.broker.advances,
 	}
-	if o.trailingSL > 0 {
-		o.position.trailingSLDist = o.trailingSL
+	if o.trailing.Value > 0 {
+		o.position.trailing = o.trailing
 	} else {
 		o.position.stopLoss = o.stopLoss
 	}
 	// TODO: cash should be a function because position values change over time and you will pay for losses in realtime
 	o.broker.Cash -= o.position.EntryValue()
+	o.broker.Cash -= o.costs.Commission
+	o.broker.spreadCollectedUSD += o.costs.Spread
+	o.broker.spreadPips += o.costs.SpreadPips
+	o.broker.commissionPaid += o.costs.Commission
 
 	o.broker.positions = append(o.broker.positions, o.position)
+	if o.broker.positionsByID == nil {
+		o.broker.positionsByID = make(map[string]*TestPosition)
+	}
+	o.broker.positionsByID[o.position.id] = o.position
 	o.broker.SignalEmit(OrderFulfilled, o)
 }
 
+func (o *TestOrder) Costs() TradeCosts {
+	return o.costs
+}
+
+// Gapped returns true if the order was filled at the open of a candle after the market was closed, instead of at its price.
+func (o *TestOrder) Gapped() bool {
+	return o.gapped
+}
+
 func (o *TestOrder) Fulfilled() bool {
 	return o.position != nil
 }
@@ -772,6 +1589,10 @@ func (o *TestOrder) StopLoss() float64 {
 	return o.stopLoss
 }
 
+func (o *TestOrder) Tags() Tags {
+	return o.tags
+}
+
 func (o *TestOrder) TakeProfit() float64 {
 	return o.takeProfit
 }
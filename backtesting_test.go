@@ -1,6 +1,10 @@
 package autotrader
 
 import (
+	"context"
+	"errors"
+	"io"
+	"math"
 	"testing"
 	"time"
 )
@@ -45,7 +49,7 @@ var testData = func() *IndexedFrame[UnixTime] {
 func TestBacktestingBrokerCandles(t *testing.T) {
 	broker := NewTestBroker(nil, testData, 0, 0, 0, 0)
 
-	candles, err := broker.Candles("EUR_USD", "D", 3)
+	candles, err := broker.Candles(context.Background(), "EUR_USD", "D", 3)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -58,7 +62,7 @@ func TestBacktestingBrokerCandles(t *testing.T) {
 	}
 
 	broker.Advance()
-	candles, err = broker.Candles("EUR_USD", "D", 3)
+	candles, err = broker.Candles(context.Background(), "EUR_USD", "D", 3)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -72,7 +76,7 @@ func TestBacktestingBrokerCandles(t *testing.T) {
 
 	for i := 0; i < 7; i++ { // 6 because we want to call broker.Advance() 9 times total
 		broker.Advance()
-		candles, err = broker.Candles("EUR_USD", "D", 5)
+		candles, err = broker.Candles(context.Background(), "EUR_USD", "D", 5)
 		if err != nil && err != ErrEOF && i != 6 { // Allow ErrEOF on last iteration.
 			t.Fatalf("Got an error on iteration %d: %v (called Advance() %d times)", i, err, broker.CandleIndex()+1)
 		}
@@ -92,8 +96,8 @@ func TestBacktestingBrokerMarketOrders(t *testing.T) {
 	broker := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
 	broker.Slippage = 0
 
-	timeBeforeOrder := time.Now()
-	order, err := broker.Order(Market, "EUR_USD", 50_000, 0, 0, 0) // Buy 50,000 USD for 1000 EUR with no stop loss or take profit
+	candleTime := testData.Date(0).Time()
+	order, err := broker.Order(context.Background(), Market, "EUR_USD", 50_000, 0, 0, 0) // Buy 50,000 USD for 1000 EUR with no stop loss or take profit
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -113,8 +117,8 @@ func TestBacktestingBrokerMarketOrders(t *testing.T) {
 	if order.Fulfilled() != true {
 		t.Error("Expected order to be fulfilled")
 	}
-	if order.Time().Before(timeBeforeOrder) {
-		t.Error("Expected order time to be after timeBeforeOrder")
+	if !order.Time().Equal(candleTime) {
+		t.Errorf("Expected order time to be the time of the first candle %v, got %v", candleTime, order.Time())
 	}
 	if order.Leverage() != 50 {
 		t.Errorf("Expected leverage to be 50, got %f", order.Leverage())
@@ -142,8 +146,8 @@ func TestBacktestingBrokerMarketOrders(t *testing.T) {
 	if position.EntryPrice() != 1.15 {
 		t.Errorf("Expected entry price to be 1.15 (first close), got %f", position.EntryPrice())
 	}
-	if position.Time().Before(timeBeforeOrder) {
-		t.Error("Expected position time to be after timeBeforeOrder")
+	if !position.Time().Equal(candleTime) {
+		t.Errorf("Expected position time to be the time of the first candle %v, got %v", candleTime, position.Time())
 	}
 	if position.Leverage() != 50 {
 		t.Errorf("Expected leverage to be 50, got %f", position.Leverage())
@@ -185,7 +189,7 @@ func TestBacktestingBrokerLimitOrders(t *testing.T) {
 	broker := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
 	broker.Slippage = 0
 
-	order, err := broker.Order(Limit, "EUR_USD", -50_000, 1.3, 1.35, 1.1) // Sell limit 50,000 USD for 1000 EUR
+	order, err := broker.Order(context.Background(), Limit, "EUR_USD", -50_000, 1.3, 1.35, 1.1) // Sell limit 50,000 USD for 1000 EUR
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -234,7 +238,7 @@ func TestBacktestingBrokerStopOrders(t *testing.T) {
 	broker := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
 	broker.Slippage = 0
 
-	order, err := broker.Order(Stop, "EUR_USD", 50_000, 1.2, 1, 1.3) // Buy stop 50,000 EUR for 1000 USD
+	order, err := broker.Order(context.Background(), Stop, "EUR_USD", 50_000, 1.2, 1, 1.3) // Buy stop 50,000 EUR for 1000 USD
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -282,7 +286,7 @@ func TestBacktestingBrokerStopLossTakeProfit(t *testing.T) {
 	broker := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
 	broker.Slippage = 0
 
-	order, err := broker.Order(Market, "", 10_000, 0, 1.05, 1.25)
+	order, err := broker.Order(context.Background(), Market, "", 10_000, 0, 1.05, 1.25)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -322,7 +326,7 @@ func TestBacktestingBrokerStopLossTakeProfit(t *testing.T) {
 
 	broker.Advance() // 4th candle
 
-	order, err = broker.Order(Market, "", 10_000, 0, -0.2, 1.4) // Long position with trailing stop loss of 0.2.
+	order, err = broker.Order(context.Background(), Market, "", 10_000, 0, -0.2, 1.4) // Long position with trailing stop loss of 0.2.
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -371,3 +375,948 @@ func TestBacktestingBrokerStopLossTakeProfit(t *testing.T) {
 		t.Errorf("Expected close type to be %q, got %q", CloseTrailingStop, position.CloseType())
 	}
 }
+
+func TestBacktestingBrokerTradeCosts(t *testing.T) {
+	broker := NewTestBroker(nil, testData, 100_000, 1, 0.01, 0)
+	broker.Slippage = 0
+	broker.Commission = 0.001
+
+	order, err := broker.Order(context.Background(), Market, "EUR_USD", 1000, 0, 0, 0) // Filled at the ask price of 1.16.
+	if err != nil {
+		t.Fatal(err)
+	}
+	costs := order.Costs()
+	if !EqualApprox(costs.Spread, 5) { // Half of the 0.01 spread on 1000 units.
+		t.Errorf("Expected entry spread cost to be 5, got %f", costs.Spread)
+	}
+	if !EqualApprox(costs.Commission, 1.16) { // 0.1% of $1160
+		t.Errorf("Expected entry commission to be 1.16, got %f", costs.Commission)
+	}
+	if costs.Slippage != 0 {
+		t.Errorf("Expected entry slippage to be 0, got %f", costs.Slippage)
+	}
+	if !EqualApprox(broker.Cash, 100_000-1160-1.16) {
+		t.Errorf("Expected cash to be %f, got %f", 100_000-1160-1.16, broker.Cash)
+	}
+
+	position := order.Position()
+	if err := position.Close(); err != nil { // Closed at the bid price of 1.15.
+		t.Fatal(err)
+	}
+	closeCosts := position.CloseCosts()
+	if !EqualApprox(closeCosts.Spread, 5) {
+		t.Errorf("Expected exit spread cost to be 5, got %f", closeCosts.Spread)
+	}
+	if !EqualApprox(closeCosts.Commission, 1.15) {
+		t.Errorf("Expected exit commission to be 1.15, got %f", closeCosts.Commission)
+	}
+	if !EqualApprox(broker.SpreadCollected(), 10) {
+		t.Errorf("Expected spread collected to be 10, got %f", broker.SpreadCollected())
+	}
+	if !EqualApprox(broker.CommissionPaid(), 2.31) {
+		t.Errorf("Expected commission paid to be 2.31, got %f", broker.CommissionPaid())
+	}
+	if !EqualApprox(broker.NAV(), 100_000-10-2.31) {
+		t.Errorf("Expected NAV to be %f, got %f", 100_000-10-2.31, broker.NAV())
+	}
+
+	// Slippage is attributed to the trade as the difference from the requested price.
+	broker.Slippage = 0.01
+	order, err = broker.Order(context.Background(), Market, "EUR_USD", -1000, 0, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slipped := (order.Position().EntryPrice() - order.Price()) * order.Units()
+	if !EqualApprox(order.Costs().Slippage, slipped) {
+		t.Errorf("Expected entry slippage to be %f, got %f", slipped, order.Costs().Slippage)
+	}
+}
+
+func TestBacktestingBrokerSpreadPips(t *testing.T) {
+	broker := NewTestBroker(nil, testData, 100_000, 50, 0, 0) // The close is 1.15.
+	broker.Slippage = 0
+	broker.SpreadPips = 2
+
+	if ask := broker.Ask("EUR_USD"); !EqualApprox(ask, 1.1502) {
+		t.Errorf("Expected a 2 pip spread on EUR_USD to give an ask of 1.1502, got %v", ask)
+	}
+	if ask := broker.Ask("USD_JPY"); !EqualApprox(ask, 1.17) {
+		t.Errorf("Expected a 2 pip spread on USD_JPY to give an ask of 1.17, got %v", ask)
+	}
+
+	order, err := broker.Order(context.Background(), Market, "EUR_USD", 10_000, 0, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if costs := order.Costs(); !EqualApprox(costs.SpreadPips, 1) || !EqualApprox(costs.Spread, 1) {
+		t.Errorf("Expected half of the spread to be paid on entry, 1 pip and $1, got %v pips and $%v", costs.SpreadPips, costs.Spread)
+	}
+	if err := order.Position().Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !EqualApprox(broker.SpreadCollectedPips(), 2) || !EqualApprox(broker.SpreadCollected(), 2) {
+		t.Errorf("Expected 2 pips and $2 of spread to be collected, got %v pips and $%v", broker.SpreadCollectedPips(), broker.SpreadCollected())
+	}
+
+	broker.SplitSpread = true
+	if bid, ask := broker.Bid("EUR_USD"), broker.Ask("EUR_USD"); !EqualApprox(bid, 1.1499) || !EqualApprox(ask, 1.1501) {
+		t.Errorf("Expected the split spread to give a bid of 1.1499 and an ask of 1.1501, got %v and %v", bid, ask)
+	}
+	broker.PipSize = 0.01
+	if ask := broker.Ask("EUR_USD"); !EqualApprox(ask, 1.16) {
+		t.Errorf("Expected the pip size of the broker to override the symbol, got an ask of %v", ask)
+	}
+}
+
+func TestBacktestingBrokerLookupByID(t *testing.T) {
+	broker := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	order, err := broker.Order(context.Background(), Market, "EUR_USD", 1000, 0, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if found, err := broker.OrderByID(context.Background(), order.Id()); err != nil || found != order {
+		t.Errorf("Expected to find order %s, got %v, %v", order.Id(), found, err)
+	}
+	if found, err := broker.PositionByID(context.Background(), order.Position().Id()); err != nil || found != order.Position() {
+		t.Errorf("Expected to find position %s, got %v, %v", order.Position().Id(), found, err)
+	}
+	if _, err := broker.OrderByID(context.Background(), "missing"); err != ErrOrderNotFound {
+		t.Errorf("Expected ErrOrderNotFound, got %v", err)
+	}
+	if _, err := broker.PositionByID(context.Background(), "missing"); err != ErrPositionNotFound {
+		t.Errorf("Expected ErrPositionNotFound, got %v", err)
+	}
+}
+
+func TestBacktestingBrokerOrderErrors(t *testing.T) {
+	broker := NewTestBroker(nil, testData, 100_000, 10, 0, 0) // The price is 1.15.
+	broker.Symbols = []string{"EUR_USD"}
+	broker.MinUnits = 1000
+	broker.Calendar = &MarketHours{OpenDay: time.Monday, CloseDay: time.Sunday} // Closed on Sundays.
+
+	tests := []struct {
+		name       string
+		symbol     string
+		units      float64
+		stopLoss   float64
+		takeProfit float64
+		want       error
+	}{
+		{"unknown symbol", "GBP_USD", 1000, 0, 0, ErrSymbolNotFound},
+		{"units below minimum", "EUR_USD", -500, 0, 0, ErrUnitsBelowMinimum},
+		{"long stop loss above price", "EUR_USD", 1000, 1.2, 0, ErrInvalidStopLoss},
+		{"short stop loss below price", "EUR_USD", -1000, 1.1, 0, ErrInvalidStopLoss},
+		{"long take profit below price", "EUR_USD", 1000, 0, 1.1, ErrInvalidTakeProfit},
+		{"short take profit above price", "EUR_USD", -1000, 0, 1.2, ErrInvalidTakeProfit},
+		{"insufficient margin", "EUR_USD", 1_000_000, 0, 0, ErrInsufficientMargin}, // Requires 115,000 of margin.
+	}
+	for _, test := range tests {
+		_, err := broker.Order(context.Background(), Market, test.symbol, test.units, 0, test.stopLoss, test.takeProfit)
+		if !errors.Is(err, test.want) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.want, err)
+		}
+		var orderErr *OrderError
+		if !errors.As(err, &orderErr) || orderErr.Symbol != test.symbol || orderErr.Units != test.units {
+			t.Errorf("%s: expected an OrderError for %v units of %s, got %v", test.name, test.units, test.symbol, err)
+		}
+	}
+	if len(broker.Orders()) != 0 {
+		t.Errorf("Expected rejected orders to not be placed, got %d orders", len(broker.Orders()))
+	}
+
+	if _, err := broker.Order(context.Background(), Market, "EUR_USD", 800_000, 0, 1.1, 1.2); err != nil { // Uses 92,000 of margin.
+		t.Fatalf("Expected the order to be placed, got %v", err)
+	}
+	if _, err := broker.Order(context.Background(), Market, "EUR_USD", 100_000, 0, 0, 0); !errors.Is(err, ErrInsufficientMargin) {
+		t.Errorf("Expected the margin of the open position to be used, got %v", err)
+	}
+
+	broker.Advance() // Sunday
+	if _, err := broker.Order(context.Background(), Market, "EUR_USD", 1000, 0, 0, 0); !errors.Is(err, ErrMarketClosed) {
+		t.Errorf("Expected ErrMarketClosed, got %v", err)
+	}
+}
+
+func TestBacktestingBrokerWeekendGaps(t *testing.T) {
+	data := NewDOHLCVIndexedFrame[UnixTime]()
+	for _, c := range []struct {
+		date                   time.Time
+		open, high, low, close float64
+	}{
+		{time.Date(2022, 1, 7, 20, 0, 0, 0, time.UTC), 1.20, 1.21, 1.19, 1.20},
+		{time.Date(2022, 1, 7, 21, 0, 0, 0, time.UTC), 1.20, 1.21, 1.19, 1.20}, // The last candle before the close.
+		{time.Date(2022, 1, 8, 12, 0, 0, 0, time.UTC), 1.20, 1.20, 1.20, 1.20}, // A flat candle while the market is closed.
+		{time.Date(2022, 1, 9, 22, 0, 0, 0, time.UTC), 1.10, 1.12, 1.09, 1.11}, // Gaps down at the open.
+		{time.Date(2022, 1, 9, 23, 0, 0, 0, time.UTC), 1.11, 1.12, 1.10, 1.11},
+	} {
+		data.PushCandle(UnixTime(c.date.Unix()), c.open, c.high, c.low, c.close, 0)
+	}
+	broker := NewTestBroker(nil, data, 100_000, 50, 0, 2)
+	broker.Slippage = 0
+	broker.Calendar = ForexHours
+	broker.QueueClosedOrders = true
+
+	long, err := broker.Order(context.Background(), Market, "EUR_USD", 1000, 0, 1.15, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limit, err := broker.Order(context.Background(), Limit, "EUR_USD", 1000, 1.15, 0, 0) // The gap jumps below the limit price.
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	broker.Advance() // Saturday
+	queued, err := broker.Order(context.Background(), Market, "EUR_USD", -1000, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("Expected the order to be queued while the market is closed, got %v", err)
+	}
+	if queued.Fulfilled() || long.Position().Closed() {
+		t.Fatal("Expected nothing to be filled while the market is closed")
+	}
+
+	broker.Advance() // Sunday open
+	position := long.Position().(*TestPosition)
+	if !position.Closed() || position.ClosePrice() != 1.10 || position.CloseType() != CloseStopLoss || !position.Gapped() {
+		t.Errorf("Expected the stop loss to be gapped and filled at the open of 1.10, got closed: %v at %v (%v), gapped: %v", position.Closed(), position.ClosePrice(), position.CloseType(), position.Gapped())
+	}
+	if !EqualApprox(position.CloseCosts().Slippage, 50) { // 1000 units filled 0.05 below the stop loss.
+		t.Errorf("Expected the gap to be counted as 50 of slippage, got %v", position.CloseCosts().Slippage)
+	}
+	if !limit.Fulfilled() || limit.Position().EntryPrice() != 1.10 || !limit.(*TestOrder).Gapped() {
+		t.Errorf("Expected the limit order to be filled at the open of 1.10, got %v", limit.Position())
+	}
+	if !queued.Fulfilled() || queued.Position().EntryPrice() != 1.10 || !queued.(*TestOrder).Gapped() {
+		t.Errorf("Expected the queued order to be filled at the open of 1.10, got %v", queued.Position())
+	}
+
+	broker = NewTestBroker(nil, data, 100_000, 50, 0, 3) // Saturday
+	broker.Calendar = ForexHours
+	if _, err := broker.Order(context.Background(), Market, "EUR_USD", 1000, 0, 0, 0); !errors.Is(err, ErrMarketClosed) {
+		t.Errorf("Expected ErrMarketClosed, got %v", err)
+	}
+}
+
+func TestBacktestingBrokerGapStop(t *testing.T) {
+	data := NewDOHLCVIndexedFrame[UnixTime]()
+	for _, c := range []struct {
+		date                   time.Time
+		open, high, low, close float64
+	}{
+		{time.Date(2022, 1, 7, 21, 0, 0, 0, time.UTC), 1.20, 1.21, 1.19, 1.20},
+		{time.Date(2022, 1, 9, 22, 0, 0, 0, time.UTC), 1.24, 1.25, 1.23, 1.24}, // Gaps up past the trailing stop but not the stop loss.
+	} {
+		data.PushCandle(UnixTime(c.date.Unix()), c.open, c.high, c.low, c.close, 0)
+	}
+	broker := NewTestBroker(nil, data, 100_000, 50, 0, 0)
+	broker.Slippage = 0
+	broker.Calendar = ForexHours
+
+	short, err := broker.Order(context.Background(), Market, "EUR_USD", -1000, 0, 1.30, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	position := short.Position().(*TestPosition)
+	position.trailingSL = 1.22 // The trailing stop is the tighter stop of the short.
+
+	broker.Advance()
+	if !position.Closed() || position.ClosePrice() != 1.24 || position.CloseType() != CloseTrailingStop || !position.Gapped() {
+		t.Errorf("Expected the trailing stop to be gapped and filled at the open of 1.24, got closed: %v at %v (%v), gapped: %v", position.Closed(), position.ClosePrice(), position.CloseType(), position.Gapped())
+	}
+}
+
+type endingStrategy struct {
+	nexts, ends int
+}
+
+func (s *endingStrategy) Init(_ *Trader) {}
+
+func (s *endingStrategy) Next(t *Trader) {
+	s.nexts++
+	if !t.IsLong() {
+		t.Buy(1000, 0, 0)
+	}
+}
+
+func (s *endingStrategy) End(t *Trader) {
+	s.ends++
+	t.CloseOrdersAndPositions()
+}
+
+func TestStrategyEnd(t *testing.T) {
+	strategy := &endingStrategy{}
+	broker := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	trader := NewTrader(TraderConfig{
+		Broker:        broker,
+		Strategy:      strategy,
+		Symbol:        "EUR_USD",
+		Frequency:     "D",
+		CandlesToKeep: 5,
+	})
+	trader.Log.SetOutput(io.Discard)
+	trader.Init()
+	for !trader.EOF {
+		trader.Tick()
+		broker.Advance()
+	}
+
+	if strategy.ends != 1 {
+		t.Errorf("Expected End to be called once, got %d", strategy.ends)
+	}
+	if strategy.nexts != testData.Len() {
+		t.Errorf("Expected Next to be called %d times, got %d", testData.Len(), strategy.nexts)
+	}
+	if len(broker.OpenPositions()) != 0 {
+		t.Errorf("Expected End to close all positions, got %d open", len(broker.OpenPositions()))
+	}
+}
+
+type roundTripStrategy struct {
+	candle int
+}
+
+func (s *roundTripStrategy) Init(_ *Trader) {}
+
+func (s *roundTripStrategy) Next(t *Trader) {
+	s.candle++
+	switch s.candle {
+	case 2:
+		t.Buy(1000, 0, 0)
+	case 4:
+		t.CloseOrdersAndPositions()
+	}
+}
+
+func TestTradeStatLinks(t *testing.T) {
+	broker := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	trader := NewTrader(TraderConfig{
+		Broker:        broker,
+		Strategy:      &roundTripStrategy{},
+		Symbol:        "EUR_USD",
+		Frequency:     "D",
+		CandlesToKeep: 5,
+	})
+	trader.Log.SetOutput(io.Discard)
+	trader.Init()
+	for !trader.EOF {
+		trader.Tick()
+		broker.Advance()
+	}
+
+	trades := trader.Stats().Trades()
+	if len(trades) != 2 {
+		t.Fatalf("Expected 2 trades, got %d", len(trades))
+	}
+	entry, exit := trades[0], trades[1]
+	if entry.Exit || !exit.Exit {
+		t.Fatalf("Expected an entry followed by an exit, got %+v and %+v", entry, exit)
+	}
+	if entry.PositionID == "" || entry.PositionID != exit.PositionID {
+		t.Errorf("Expected both trades to share a position ID, got %q and %q", entry.PositionID, exit.PositionID)
+	}
+	openTime := time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC)
+	closeTime := time.Date(2022, 1, 4, 0, 0, 0, 0, time.UTC)
+	if !entry.OpenTime.Equal(openTime) || !entry.CloseTime.IsZero() {
+		t.Errorf("Expected entry to open at %s and not close, got %s and %s", openTime, entry.OpenTime, entry.CloseTime)
+	}
+	if !exit.OpenTime.Equal(openTime) || !exit.CloseTime.Equal(closeTime) {
+		t.Errorf("Expected exit to open at %s and close at %s, got %s and %s", openTime, closeTime, exit.OpenTime, exit.CloseTime)
+	}
+	if exit.Entry == nil || exit.Entry.Price != entry.Price {
+		t.Errorf("Expected exit to link to its entry, got %+v", exit.Entry)
+	}
+	if exit.Duration() != 48*time.Hour {
+		t.Errorf("Expected duration to be 48h, got %s", exit.Duration())
+	}
+	if entry.Duration() != 0 {
+		t.Errorf("Expected entry duration to be 0, got %s", entry.Duration())
+	}
+}
+
+type taggedStrategy struct {
+	roundTripStrategy
+}
+
+func (s *taggedStrategy) Next(t *Trader) {
+	s.candle++
+	switch s.candle {
+	case 2:
+		t.Buy(1000, 0, 0, WithTags(Tags{"setup": "breakout"}), WithTags(Tags{"strategy": "override"}))
+	case 4:
+		t.CloseOrdersAndPositions()
+	}
+}
+
+func TestOrderTags(t *testing.T) {
+	broker := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	trader := NewTrader(TraderConfig{
+		Broker:        broker,
+		Strategy:      &taggedStrategy{},
+		Symbol:        "EUR_USD",
+		Frequency:     "D",
+		CandlesToKeep: 5,
+		Tags:          Tags{"strategy": "tagged", "account": "shared"},
+	})
+	trader.Log.SetOutput(io.Discard)
+	trader.Init()
+	for !trader.EOF {
+		trader.Tick()
+		broker.Advance()
+	}
+
+	expected := "account=shared setup=breakout strategy=override"
+	order := broker.Orders()[0]
+	if order.Tags().String() != expected || order.Position().Tags().String() != expected {
+		t.Errorf("Expected the order and position tags to be %q, got %q and %q", expected, order.Tags(), order.Position().Tags())
+	}
+	for _, trade := range trader.Stats().Trades() {
+		if trade.Tags.String() != expected {
+			t.Errorf("Expected trade tags to be %q, got %q", expected, trade.Tags)
+		}
+	}
+}
+
+// contextBroker is a TestBroker that records the contexts of its requests and fails them once their context is done.
+type contextBroker struct {
+	*TestBroker
+	deadlines int
+}
+
+func (b *contextBroker) Candles(ctx context.Context, symbol, frequency string, count int) (*IndexedFrame[UnixTime], error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, ok := ctx.Deadline(); ok {
+		b.deadlines++
+	}
+	return b.TestBroker.Candles(ctx, symbol, frequency, count)
+}
+
+func (b *contextBroker) Order(ctx context.Context, orderType OrderType, symbol string, units, price, stopLoss, takeProfit float64, options ...OrderOption) (Order, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, ok := ctx.Deadline(); ok {
+		b.deadlines++
+	}
+	return b.TestBroker.Order(ctx, orderType, symbol, units, price, stopLoss, takeProfit, options...)
+}
+
+func TestTraderContext(t *testing.T) {
+	strategy := &endingStrategy{}
+	broker := &contextBroker{TestBroker: NewTestBroker(nil, testData, 100_000, 50, 0, 0)}
+	trader := NewTrader(TraderConfig{
+		Broker:        broker,
+		Strategy:      strategy,
+		Symbol:        "EUR_USD",
+		Frequency:     "D",
+		CandlesToKeep: 5,
+		Timeout:       time.Minute,
+	})
+	trader.Log.SetOutput(io.Discard)
+	trader.Init()
+	trader.Tick()
+	if broker.deadlines != 2 {
+		t.Errorf("Expected the candles and order requests to have a deadline, got %d requests with one", broker.deadlines)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	trader.ctx = ctx
+	cancel()
+	broker.Advance()
+	trader.Tick()
+	if strategy.nexts != 1 {
+		t.Errorf("Expected Next to not be called after the context is cancelled, got %d calls", strategy.nexts)
+	}
+	if trader.Data().Len() != 1 {
+		t.Errorf("Expected the data to be left unchanged, got %d candles", trader.Data().Len())
+	}
+}
+
+// slowBroker is a TestBroker whose requests for candles take until their context is done while slow is set.
+type slowBroker struct {
+	*TestBroker
+	slow bool
+}
+
+func (b *slowBroker) Candles(ctx context.Context, symbol, frequency string, count int) (*IndexedFrame[UnixTime], error) {
+	if b.slow {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return b.TestBroker.Candles(ctx, symbol, frequency, count)
+}
+
+func TestTraderTimeout(t *testing.T) {
+	strategy := &endingStrategy{}
+	broker := &slowBroker{TestBroker: NewTestBroker(nil, testData, 100_000, 50, 0, 0)}
+	trader := NewTrader(TraderConfig{
+		Broker:        broker,
+		Strategy:      strategy,
+		Symbol:        "EUR_USD",
+		Frequency:     "D",
+		CandlesToKeep: 5,
+		Timeout:       time.Millisecond,
+	})
+	trader.Log.SetOutput(io.Discard)
+	trader.ctx = context.Background()
+	trader.Init()
+	trader.Tick()
+
+	broker.slow = true
+	broker.Advance()
+	trader.Tick() // Must skip the tick instead of panicking.
+	if strategy.nexts != 1 || trader.Data().Len() != 1 {
+		t.Errorf("Expected the tick to be skipped when the request times out, got %d calls of Next and %d candles", strategy.nexts, trader.Data().Len())
+	}
+	broker.slow = false
+	trader.Tick()
+	if strategy.nexts != 2 || trader.Data().Len() != 2 {
+		t.Errorf("Expected the next tick to run once the broker answers, got %d calls of Next and %d candles", strategy.nexts, trader.Data().Len())
+	}
+}
+
+func TestTraderRunContext(t *testing.T) {
+	strategy := &endingStrategy{}
+	trader := NewTrader(TraderConfig{
+		Broker:        NewTestBroker(nil, testData, 100_000, 50, 0, 0),
+		Strategy:      strategy,
+		Symbol:        "EUR_USD",
+		Frequency:     "MS10",
+		CandlesToKeep: 5,
+	})
+	trader.Log.SetOutput(io.Discard)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		trader.RunContext(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected RunContext to return when the context is done")
+	}
+	if trader.Context() != ctx {
+		t.Errorf("Expected the context of the Trader to be the one given to RunContext")
+	}
+}
+
+func TestBacktestingBrokerTrailingStopModes(t *testing.T) {
+	ctx := context.Background()
+	broker := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	broker.Slippage = 0
+
+	// 5% of the bid of 1.2 on the next candle puts the stop at 1.14, which the low of 1.1 hits.
+	order, err := broker.Order(ctx, Market, "EUR_USD", 1000, 0, 0, 0, WithTrailingStop(TrailingPercent, 0.05))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !EqualApprox(order.TrailingStop(), 0.0575) {
+		t.Errorf("Expected a trailing distance of 0.0575 when placed, got %f", order.TrailingStop())
+	}
+	short, err := broker.Order(ctx, Market, "EUR_USD", -1000, 0, -0.2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	broker.Advance()
+	long := order.Position()
+	if !long.Closed() || long.CloseType() != CloseTrailingStop || !EqualApprox(long.ClosePrice(), 1.14) {
+		t.Errorf("Expected the long to be closed by the trailing stop at 1.14, got %v at %f", long.CloseType(), long.ClosePrice())
+	}
+	if !EqualApprox(short.Position().TrailingStop(), 1.4) {
+		t.Errorf("Expected the trailing stop of the short to be 1.4, got %f", short.Position().TrailingStop())
+	}
+	broker.Advance() // The price rises to 1.25, which must not move the stop of the short up.
+	if !EqualApprox(short.Position().TrailingStop(), 1.4) {
+		t.Errorf("Expected the trailing stop of the short to stay at 1.4, got %f", short.Position().TrailingStop())
+	}
+
+	// The true ranges of the second and third candles are 0.1 and 0.15.
+	if distance := broker.trailingDistance("EUR_USD", TrailingStop{Mode: TrailingATR, Value: 2, ATRPeriod: 2}, 1.25); !EqualApprox(distance, 0.25) {
+		t.Errorf("Expected a distance of 2 ATRs to be 0.25, got %f", distance)
+	}
+
+	broker.Advance() // The price falls to 1.1, moving the stop of the short down to 1.3, which the high touches.
+	if p := short.Position(); !p.Closed() || !EqualApprox(p.ClosePrice(), 1.3) {
+		t.Errorf("Expected the short to be closed by the trailing stop at 1.3, got %f", p.ClosePrice())
+	}
+}
+
+func TestBacktestingBrokerBreakEven(t *testing.T) {
+	broker := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	broker.Slippage = 0
+
+	// The close of 1.2 on the next candle is 0.05 in profit, so the stop moves to 1.16 and the low of 1.1 hits it.
+	order, err := broker.Order(context.Background(), Market, "EUR_USD", 1000, 0, 0, 0, WithBreakEven(0.04, 0.01))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var modified int
+	broker.SignalConnect(PositionModified, t, func(...any) { modified++ })
+	broker.Advance()
+	position := order.Position()
+	if modified != 1 || !EqualApprox(position.StopLoss(), 1.16) {
+		t.Errorf("Expected the stop loss to be moved once to 1.16, got %d times to %f", modified, position.StopLoss())
+	}
+	if !position.Closed() || position.CloseType() != CloseStopLoss || !EqualApprox(position.ClosePrice(), 1.16) {
+		t.Errorf("Expected the position to be stopped out at break-even plus the offset, got %v at %f", position.CloseType(), position.ClosePrice())
+	}
+
+	if stop, ok := (BreakEven{Trigger: 0.1}).Stop(-1000, 1.2, 0, 1.15); ok {
+		t.Errorf("Expected a short that has not reached the trigger to keep its stop, got %f", stop)
+	}
+	if err := position.(StopLossModifier).SetStopLoss(1.1); err != ErrPositionClosed {
+		t.Errorf("Expected ErrPositionClosed, got %v", err)
+	}
+}
+
+func TestBacktestingBrokerMaxHolding(t *testing.T) {
+	broker := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	broker.Slippage = 0
+
+	order, err := broker.Order(context.Background(), Market, "EUR_USD", 1000, 0, 0, 0, WithMaxHolding(2, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker.Advance()
+	position := order.Position()
+	if position.Closed() {
+		t.Fatal("Expected the position to be open after one candle")
+	}
+	broker.Advance()
+	if !position.Closed() || position.CloseType() != CloseTimeExit || !EqualApprox(position.ClosePrice(), 1.25) {
+		t.Errorf("Expected the position to be closed at the close of 1.25 after two candles, got %v at %f", position.CloseType(), position.ClosePrice())
+	}
+
+	order, err = broker.Order(context.Background(), Market, "EUR_USD", -1000, 0, 0, 0, WithMaxHolding(0, 24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker.Advance()
+	if position := order.Position(); !position.Closed() || position.CloseType() != CloseTimeExit {
+		t.Errorf("Expected the position to be closed after a day, got %v", position.CloseType())
+	}
+}
+
+func TestBacktestingBrokerShareCandles(t *testing.T) {
+	copied := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	shared := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	shared.ShareCandles = true
+	for i := 0; i < testData.Len(); i++ {
+		a, errA := copied.Candles(context.Background(), "EUR_USD", "D", 5)
+		b, errB := shared.Candles(context.Background(), "EUR_USD", "D", 5)
+		if errA != errB || a.Len() != b.Len() || *a.Date(0) != *b.Date(0) || a.Close(-1) != b.Close(-1) {
+			t.Fatalf("Expected the shared candles to match the copied candles on candle %d, got:\n%v\n%v", i, a, b)
+		}
+		copied.Advance()
+		shared.Advance()
+	}
+}
+
+func TestBacktestingBrokerCandleSignals(t *testing.T) {
+	broker := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	broker.Symbols = []string{"EUR_USD"}
+	broker.Frequency = "D"
+	var events []string
+	var opened, closed CandleEvent
+	broker.SignalConnect(CandleOpened, t, func(args ...any) {
+		opened = args[0].(CandleEvent)
+		events = append(events, CandleOpened)
+	})
+	broker.SignalConnect(CandleClosed, t, func(args ...any) {
+		closed = args[0].(CandleEvent)
+		events = append(events, CandleClosed)
+	})
+	broker.Advance()
+
+	if len(events) != 2 || events[0] != CandleOpened || events[1] != CandleClosed {
+		t.Fatalf("Expected CandleOpened then CandleClosed, got %v", events)
+	}
+	if opened.Symbol != "EUR_USD" || opened.Frequency != "D" || opened.Candle.Open != 1.15 || opened.Candle.Close != 1.15 {
+		t.Errorf("Expected the opened candle to only have the open of 1.15, got %+v", opened)
+	}
+	if !closed.Candle.Date.Equal(testData.Date(1).Time()) || closed.Candle.High != 1.2 || closed.Candle.Close != 1.2 {
+		t.Errorf("Expected the closed candle to be the second candle, got %+v", closed.Candle)
+	}
+
+	for i := 0; i < testData.Len(); i++ {
+		broker.Advance()
+	}
+	if len(events) != 2*(testData.Len()-1) {
+		t.Errorf("Expected no signals after the last candle, got %d signals", len(events))
+	}
+}
+
+func TestBacktestingBrokerPositionModes(t *testing.T) {
+	order := func(broker *TestBroker, units float64) {
+		t.Helper()
+		if _, err := broker.Order(context.Background(), Market, "EUR_USD", units, 0, 0, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	openUnits := func(broker *TestBroker) []float64 {
+		var units []float64
+		for _, position := range broker.OpenPositions() {
+			units = append(units, position.Units())
+		}
+		return units
+	}
+
+	netting := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	netting.Slippage = 0
+	netting.PositionMode = PositionNetting
+	order(netting, 1000) // At 1.15.
+	netting.Advance()
+	order(netting, 1000) // At 1.2.
+	if units := openUnits(netting); len(units) != 1 || units[0] != 2000 || !EqualApprox(netting.OpenPositions()[0].EntryPrice(), 1.175) {
+		t.Fatalf("Expected one position of 2000 units at the average price of 1.175, got %v", units)
+	}
+	order(netting, -500)
+	if units := openUnits(netting); len(units) != 1 || units[0] != 1500 {
+		t.Fatalf("Expected the position to be reduced to 1500 units, got %v", units)
+	}
+	netting.Advance()
+	order(netting, -2500) // At 1.25, which closes the position and opens a short with the rest.
+	if units := openUnits(netting); len(units) != 1 || units[0] != -1000 || netting.OpenPositions()[0].EntryPrice() != 1.25 {
+		t.Fatalf("Expected a short position of 1000 units at 1.25, got %v", units)
+	}
+	if nav := netting.NAV(); !EqualApprox(nav, 100_125) { // 500 units closed 0.025 up and 1500 units 0.075 up.
+		t.Errorf("Expected a NAV of 100125, got %v", nav)
+	}
+	if netting.Capabilities().Hedging {
+		t.Error("Expected a netting broker not to support hedging")
+	}
+
+	fifo := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	fifo.Slippage = 0
+	fifo.PositionMode = PositionFIFO
+	order(fifo, 1000)
+	order(fifo, 500)
+	order(fifo, -1200)
+	if units := openUnits(fifo); len(units) != 1 || !EqualApprox(units[0], 300) {
+		t.Errorf("Expected the oldest position to close first and 300 units of the second to remain, got %v", units)
+	}
+
+	hedging := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	order(hedging, 1000)
+	order(hedging, -1000)
+	if units := openUnits(hedging); len(units) != 2 || !hedging.Capabilities().Hedging {
+		t.Errorf("Expected a long and a short position, got %v", units)
+	}
+}
+
+func TestBacktestingBrokerNettingMargin(t *testing.T) {
+	for _, mode := range []PositionMode{PositionNetting, PositionFIFO, PositionHedging} {
+		broker := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+		broker.Slippage = 0
+		broker.PositionMode = mode
+		if _, err := broker.Order(context.Background(), Market, "EUR_USD", 100_000, 0, 0, 0); err != nil {
+			t.Fatal(err)
+		}
+		broker.Cash -= broker.MarginAvailable() + 1 // Use up the margin.
+		if available := broker.MarginAvailable(); available > 0 {
+			t.Fatalf("Expected no margin available, got %v", available)
+		}
+		_, err := broker.Order(context.Background(), Market, "EUR_USD", -50_000, 0, 0, 0)
+		if mode == PositionHedging {
+			if !errors.Is(err, ErrInsufficientMargin) {
+				t.Errorf("Expected an opposite position to need margin when hedging, got %v", err)
+			}
+		} else if err != nil {
+			t.Errorf("Expected an order that reduces the position to need no margin with %v, got %v", mode, err)
+		}
+		if _, err := broker.Order(context.Background(), Market, "EUR_USD", -200_000, 0, 0, 0); !errors.Is(err, ErrInsufficientMargin) {
+			t.Errorf("Expected the units that flip the position to need margin with %v, got %v", mode, err)
+		}
+	}
+}
+
+func TestTraderNettingTrades(t *testing.T) {
+	trade := func(units float64) func(t *Trader) {
+		return func(t *Trader) {
+			if units > 0 {
+				t.Buy(units, 0, 0)
+			} else {
+				t.Sell(-units, 0, 0)
+			}
+		}
+	}
+	strategy := &scriptedStrategy{actions: map[int]func(t *Trader){1: trade(1000), 2: trade(1000), 3: trade(-500), 4: trade(-2500)}}
+	broker := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	broker.PositionMode = PositionNetting
+	trader := NewTrader(TraderConfig{Broker: broker, Strategy: strategy, Symbol: "EUR_USD", Frequency: "D", CandlesToKeep: 5})
+	trader.Log.SetOutput(io.Discard)
+	trader.Init()
+	for i := 0; i < 5; i++ {
+		trader.Tick()
+		broker.Advance()
+	}
+
+	var entries, exits []TradeStat
+	for _, trade := range trader.Stats().Trades() {
+		if trade.Exit {
+			exits = append(exits, trade)
+		} else {
+			entries = append(entries, trade)
+		}
+	}
+	if len(entries) != 3 || entries[2].Units != -1000 {
+		t.Fatalf("Expected 2 long entries and a short entry of the rest, got %+v", entries)
+	}
+	if len(exits) != 2 || exits[0].Units != 500 || exits[1].Units != 1500 {
+		t.Fatalf("Expected exits of 500 and 1500 units, got %+v", exits)
+	}
+	for _, exit := range exits {
+		if exit.Entry == nil || exit.Entry.PositionID != entries[0].PositionID {
+			t.Errorf("Expected the exit of %v units to link to the first entry, got %+v", exit.Units, exit.Entry)
+		}
+	}
+	if !trader.IsShort() || trader.IsLong() {
+		t.Error("Expected the Trader to be short")
+	}
+}
+
+func TestBacktestingBrokerMarginCall(t *testing.T) {
+	broker := NewTestBroker(nil, testData, 3000, 50, 0, 0)
+	broker.Slippage = 0
+	broker.MarginCallLevel = 1
+	broker.MarginCloseoutLevel = 0.25
+	var calls []MarginEvent
+	var closed []Position
+	broker.SignalConnect(MarginCall, t, func(args ...any) { calls = append(calls, args[0].(MarginEvent)) })
+	broker.SignalConnect(PositionClosed, t, func(args ...any) { closed = append(closed, args[0].(Position)) })
+
+	for _, units := range []float64{-50_000, 10_000} { // At 1.15.
+		if _, err := broker.Order(context.Background(), Market, "EUR_USD", units, 0, 0, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if level := broker.MarginLevel(); !EqualApprox(level, 3000/1380.0) || !EqualApprox(broker.MarginAvailable(), 1620) {
+		t.Fatalf("Expected a margin level of 3000/1380, got %v", level)
+	}
+	if _, err := broker.Order(context.Background(), Market, "EUR_USD", 100_000, 0, 0, 0); !errors.Is(err, ErrInsufficientMargin) {
+		t.Errorf("Expected an order over the available margin to fail, got %v", err)
+	}
+
+	broker.Advance() // At 1.2 the NAV of $1000 is under the $1440 of margin used.
+	if len(calls) != 1 || !EqualApprox(calls[0].NAV, 1000) || !EqualApprox(calls[0].MarginUsed, 1440) || len(closed) != 0 {
+		t.Fatalf("Expected a margin call without a closeout, got %+v and %d closed positions", calls, len(closed))
+	}
+	broker.Advance() // At 1.25 the NAV is negative.
+	if len(calls) != 1 {
+		t.Errorf("Expected only one margin call while the level stays low, got %d", len(calls))
+	}
+	if len(closed) != 2 || closed[0].Units() != -50_000 || closed[0].CloseType() != CloseMarginCall || closed[1].CloseType() != CloseMarginCall {
+		t.Fatalf("Expected the short with the largest loss to be closed out first, got %v", closed)
+	}
+	if !math.IsInf(broker.MarginLevel(), 1) || !EqualApprox(broker.NAV(), -1000) {
+		t.Errorf("Expected no margin used and a NAV of -1000, got %v and %v", broker.MarginLevel(), broker.NAV())
+	}
+}
+
+func TestBacktestingBrokerTimeInForce(t *testing.T) {
+	broker := NewTestBroker(nil, testData, 100_000, 50, 0, 1)
+	broker.Slippage = 0
+	var expired []Order
+	broker.SignalConnect(OrderExpired, t, func(args ...any) { expired = append(expired, args[0].(Order)) })
+	order := func(orderType OrderType, price float64, options ...OrderOption) *TestOrder {
+		t.Helper()
+		o, err := broker.Order(context.Background(), orderType, "EUR_USD", 1000, price, 0, 0, options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return o.(*TestOrder)
+	}
+
+	// The market is at 1.15.
+	gtd := order(Limit, 1.0, WithExpiry(time.Date(2022, 1, 2, 12, 0, 0, 0, time.UTC)))
+	gtc := order(Limit, 1.0)
+	fok := order(Limit, 1.0, WithTimeInForce(FillOrKill))
+	ioc := order(Limit, 1.2, WithTimeInForce(ImmediateOrCancel))
+	market := order(Market, 0, WithTimeInForce(FillOrKill))
+	if !fok.Expired() || fok.Fulfilled() || len(expired) != 1 || expired[0] != fok {
+		t.Errorf("Expected the fill or kill limit below the market to expire when placed, got %+v", expired)
+	}
+	if !ioc.Fulfilled() || ioc.Expired() || !market.Fulfilled() {
+		t.Error("Expected the marketable immediate or cancel and fill or kill orders to be filled")
+	}
+	if err := fok.Cancel(); !errors.Is(err, ErrCancelFailed) {
+		t.Errorf("Expected an expired order not to be cancelled, got %v", err)
+	}
+
+	broker.Advance() // The low of 1.1 on 2022-01-02 fills neither limit.
+	if gtd.Expired() || len(broker.OpenOrders()) != 2 {
+		t.Fatalf("Expected the good til date order to be open until its expiry, got %d open orders", len(broker.OpenOrders()))
+	}
+	broker.Advance()
+	if !gtd.Expired() || len(expired) != 2 || expired[1] != gtd {
+		t.Errorf("Expected the good til date order to expire after 2022-01-02 12:00, got %+v", expired)
+	}
+	if open := broker.OpenOrders(); len(open) != 1 || open[0] != gtc {
+		t.Errorf("Expected only the good til cancelled order to be open, got %+v", open)
+	}
+
+	_, err := broker.Order(context.Background(), Limit, "EUR_USD", 1000, 1.0, 0, 0, WithTimeInForce(GoodTilDate))
+	if !errors.Is(err, ErrInvalidExpiry) {
+		t.Errorf("Expected ErrInvalidExpiry for a good til date order without an expiry, got %v", err)
+	}
+}
+
+func TestBacktestingBrokerIntrabar(t *testing.T) {
+	// The candle of 2022-01-04 opens at 1.25 and reaches both the take profit at 1.28 and the stop loss at 1.05 of a long position.
+	closeType := func(intrabar IntrabarPath, sub *IndexedFrame[UnixTime]) OrderCloseType {
+		t.Helper()
+		broker := NewTestBroker(nil, testData, 100_000, 50, 0, 3)
+		broker.Slippage, broker.Intrabar, broker.SubCandles = 0, intrabar, sub
+		order, err := broker.Order(context.Background(), Market, "EUR_USD", 1000, 0, 1.05, 1.28)
+		if err != nil {
+			t.Fatal(err)
+		}
+		broker.Advance()
+		position := order.Position()
+		if !position.Closed() {
+			t.Fatalf("Expected the position to be closed with %q intrabar", intrabar)
+		}
+		return position.(*TestPosition).closeType
+	}
+	if got := closeType(IntrabarWorstCase, nil); got != CloseStopLoss {
+		t.Errorf("Expected the worst case to hit the stop loss, got %s", got)
+	}
+	if got := closeType(IntrabarOHLC, nil); got != CloseTakeProfit {
+		t.Errorf("Expected the path through the nearer high to hit the take profit, got %s", got)
+	}
+	sub := NewDOHLCVIndexedFrame[UnixTime]()
+	sub.PushCandle(UnixTime(time.Date(2022, 1, 4, 0, 0, 0, 0, time.UTC).Unix()), 1.25, 1.25, 1.0, 1.05, 60)
+	sub.PushCandle(UnixTime(time.Date(2022, 1, 4, 12, 0, 0, 0, time.UTC).Unix()), 1.05, 1.3, 1.05, 1.1, 70)
+	if got := closeType(IntrabarSubCandles, sub); got != CloseStopLoss {
+		t.Errorf("Expected the sub-candles to fall to the stop loss first, got %s", got)
+	}
+	if got := closeType(IntrabarSubCandles, NewDOHLCVIndexedFrame[UnixTime]()); got != CloseTakeProfit {
+		t.Errorf("Expected a candle without sub-candles to take the path of its open, high, low, and close, got %s", got)
+	}
+
+	// A limit buy at 1.2 is filled on the way down from the high, after the price passed its take profit.
+	for _, intrabar := range []IntrabarPath{IntrabarWorstCase, IntrabarOHLC} {
+		broker := NewTestBroker(nil, testData, 100_000, 50, 0, 3)
+		broker.Slippage, broker.Intrabar = 0, intrabar
+		order, err := broker.Order(context.Background(), Limit, "EUR_USD", 1000, 1.2, 0, 1.28)
+		if err != nil {
+			t.Fatal(err)
+		}
+		broker.Advance()
+		if !order.Fulfilled() || order.Position().Closed() {
+			t.Errorf("Expected the take profit not to be reached after the fill with %q intrabar", intrabar)
+		}
+	}
+
+	if at := pathTouch([]float64{1.25, 1.3, 1.0, 1.1}, 1.2, true, 0); !EqualApprox(at, 1+1.0/3) {
+		t.Errorf("Expected 1.2 to be reached a third of the way from the high to the low, got %f", at)
+	}
+	if at := pathTouch([]float64{1.25, 1.3, 1.0, 1.1}, 1.28, false, 1.5); !math.IsInf(at, 1) {
+		t.Errorf("Expected 1.28 not to be reached after the fall from the high, got %f", at)
+	}
+}
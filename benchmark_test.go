@@ -0,0 +1,193 @@
+package autotrader
+
+import (
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/rand"
+)
+
+// benchCandles returns n hourly candles of a seeded random walk, so benchmarks always run on the same data. The series are built directly instead of with PushCandle, so building large datasets does not dominate the benchmarks.
+func benchCandles(n int) *IndexedFrame[UnixTime] {
+	r := rand.New(rand.NewSource(1))
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	indexes := make([]UnixTime, n)
+	opens, highs, lows, closes, volumes := make([]any, n), make([]any, n), make([]any, n), make([]any, n), make([]any, n)
+	price := 1.0
+	for i := 0; i < n; i++ {
+		open := price
+		price = math.Max(price*(1+r.NormFloat64()*0.01), 0.01)
+		indexes[i] = UnixTime(start.Add(time.Duration(i) * time.Hour).Unix())
+		opens[i], closes[i] = open, price
+		highs[i] = math.Max(open, price) * (1 + r.Float64()*0.005)
+		lows[i] = math.Min(open, price) * (1 - r.Float64()*0.005)
+		volumes[i] = int64(r.Intn(1000))
+	}
+	series := func(name string, vals []any) *IndexedSeries[UnixTime] {
+		return &IndexedSeries[UnixTime]{&SignalManager{}, NewSeries(name, vals...), append([]UnixTime(nil), indexes...)}
+	}
+	return NewIndexedFrame(series("Open", opens), series("High", highs), series("Low", lows), series("Close", closes), series("Volume", volumes))
+}
+
+func BenchmarkSeriesPush(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		s := NewSeries("bench")
+		for j := 0; j < 10_000; j++ {
+			s.Push(float64(j))
+		}
+	}
+}
+
+func BenchmarkSeriesArithmetic(b *testing.B) {
+	closes := benchCandles(10_000).Closes().series
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		closes.Copy().Sub(closes).Add(closes).Mul(closes).Div(closes)
+	}
+}
+
+func BenchmarkSeriesDescribe(b *testing.B) {
+	closes := benchCandles(10_000).Closes().series
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		closes.Describe()
+	}
+}
+
+func BenchmarkIndexedFramePushCandle(b *testing.B) {
+	candles := benchCandles(5_000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		frame := NewDOHLCVIndexedFrame[UnixTime]()
+		for j := 0; j < candles.Len(); j++ {
+			frame.PushCandle(*candles.Date(j), candles.Open(j), candles.High(j), candles.Low(j), candles.Close(j), candleVolume(candles, j))
+		}
+	}
+}
+
+func BenchmarkIndexedFrameCopyRange(b *testing.B) {
+	candles := benchCandles(100_000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		candles.CopyRange(-2500, -1)
+	}
+}
+
+func BenchmarkIndexedFrameTrim(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		candles := benchCandles(50_000)
+		b.StartTimer()
+		for candles.Len() > 0 { // Trim the oldest candle like a live buffer.
+			candles.RemoveRange(0, 1)
+		}
+	}
+}
+
+func BenchmarkIndexedFrameView(b *testing.B) {
+	candles := benchCandles(100_000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		candles.View(-2500, -1)
+	}
+}
+
+func BenchmarkRollingMean(b *testing.B) {
+	closes := benchCandles(10_000).Closes()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		closes.Copy().Rolling(20).Mean()
+	}
+}
+
+func BenchmarkRollingStdDev(b *testing.B) {
+	closes := benchCandles(10_000).Closes()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		closes.Copy().Rolling(20).StdDev()
+	}
+}
+
+func BenchmarkRollingEMA(b *testing.B) {
+	closes := benchCandles(10_000).Closes()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		closes.Copy().Rolling(20).EMA()
+	}
+}
+
+func BenchmarkRSI(b *testing.B) {
+	closes := &FloatSeries{benchCandles(10_000).Closes().series}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		RSI(closes.Copy(), 14)
+	}
+}
+
+func BenchmarkATR(b *testing.B) {
+	candles := benchCandles(10_000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ATR(candles, 14)
+	}
+}
+
+// benchStrategy is an SMA crossover, which does the work of a typical strategy on every candle.
+type benchStrategy struct{}
+
+func (s *benchStrategy) Init(_ *Trader) {}
+
+func (s *benchStrategy) Next(t *Trader) {
+	closes := t.Data().Closes()
+	if closes.Len() < 20 {
+		return
+	}
+	fast := closes.Copy().Rolling(5).Mean()
+	slow := closes.Copy().Rolling(20).Mean()
+	if CrossoverIndex(*t.Data().Date(-1), fast, slow) {
+		t.CloseOrdersAndPositions()
+		t.Buy(1000, 0, 0)
+	} else if CrossoverIndex(*t.Data().Date(-1), slow, fast) {
+		t.CloseOrdersAndPositions()
+		t.Sell(1000, 0, 0)
+	}
+}
+
+// BenchmarkBacktest runs the standard backtest of an SMA crossover on 100k candles.
+func BenchmarkBacktest(b *testing.B) {
+	candles := benchCandles(100_000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		broker := NewTestBroker(nil, candles, 100_000, 50, 0.0002, 0)
+		broker.Seed = 1
+		broker.ShareCandles = true
+		trader := NewTrader(TraderConfig{
+			Broker:        broker,
+			Strategy:      &benchStrategy{},
+			Symbol:        "EUR_USD",
+			Frequency:     "H1",
+			CandlesToKeep: 100,
+		})
+		trader.Log.SetOutput(io.Discard)
+		if _, err := RunBacktest(trader); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestProfileHandler(t *testing.T) {
+	server := httptest.NewServer(ProfileHandler())
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/debug/pprof/heap?debug=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+}
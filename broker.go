@@ -1,8 +1,14 @@
 package autotrader
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 )
 
 type OrderCloseType string
@@ -12,12 +18,54 @@ const (
 	CloseStopLoss     OrderCloseType = "SL"
 	CloseTrailingStop OrderCloseType = "TS"
 	CloseTakeProfit   OrderCloseType = "TP"
+	CloseTimeExit     OrderCloseType = "TIME" // CloseTimeExit is a close at market because the position was held for its maximum holding period.
+	CloseExpiry       OrderCloseType = "EXP"  // CloseExpiry is the settlement of a futures or options position when its contract expired.
+	CloseMarginCall   OrderCloseType = "MC"   // CloseMarginCall is a close at market by the broker because the account fell below its margin closeout level.
 
 	OrderPlaced    = "OrderPlaced"
 	OrderCancelled = "OrderCancelled"
 	OrderFulfilled = "OrderFulfilled"
+	OrderExpired   = "OrderExpired"
+
+	PositionClosed   = "PositionClosed"
+	PositionModified = "PositionModified"
+
+	CandleOpened  = "CandleOpened"
+	CandleClosed  = "CandleClosed"
+	CandleRevised = "CandleRevised"
 
-	PositionClosed = "PositionClosed"
+	MarginCall = "MarginCall"
+)
+
+// MarginEvent is the payload of the MarginCall signal.
+type MarginEvent struct {
+	NAV        float64 // NAV is the net asset value of the account.
+	MarginUsed float64 // MarginUsed is the margin held by the open positions.
+	Level      float64 // Level is the NAV divided by the margin used, like 0.8 for an account with 80% of the margin it uses.
+}
+
+// CandleEvent is the payload of the CandleOpened and CandleClosed signals.
+type CandleEvent struct {
+	Symbol    string // Symbol is the symbol of the candle, or empty if the broker does not know it, like a TestBroker with more than one symbol.
+	Frequency string // Frequency is the frequency of the candle, or empty if the broker does not know it.
+	Candle    Candle // Candle is the candle. When it was just opened, only the open is known and the high, low, and close equal it.
+}
+
+// CandleRevision is the payload of the CandleRevised signal.
+type CandleRevision struct {
+	Row      int    // Row is the row of the revised candle in the frame.
+	Previous Candle // Previous is the candle before it was revised.
+	Candle   Candle // Candle is the candle as revised by the broker.
+}
+
+// TimeInForce is how long an order stays open when it cannot be filled.
+type TimeInForce string
+
+const (
+	GoodTilCancelled  TimeInForce = ""    // GoodTilCancelled orders stay open until they are filled or cancelled. It is the default.
+	GoodTilDate       TimeInForce = "GTD" // GoodTilDate orders expire at the Expiry of their options if they have not been filled.
+	FillOrKill        TimeInForce = "FOK" // FillOrKill orders are filled entirely as soon as they are placed or expire.
+	ImmediateOrCancel TimeInForce = "IOC" // ImmediateOrCancel orders are filled as much as possible as soon as they are placed and the rest expires.
 )
 
 type OrderType string
@@ -29,14 +77,219 @@ const (
 )
 
 var (
-	ErrCancelFailed      = errors.New("cancel failed")
-	ErrSymbolNotFound    = errors.New("symbol not found")
-	ErrInvalidStopLoss   = errors.New("invalid stop loss")
-	ErrInvalidTakeProfit = errors.New("invalid take profit")
+	ErrCancelFailed       = errors.New("cancel failed")
+	ErrSymbolNotFound     = errors.New("symbol not found")
+	ErrInvalidStopLoss    = errors.New("invalid stop loss")
+	ErrInvalidTakeProfit  = errors.New("invalid take profit")
+	ErrOrderNotFound      = errors.New("order not found")
+	ErrPositionNotFound   = errors.New("position not found")
+	ErrInsufficientMargin = errors.New("insufficient margin")
+	ErrMarketClosed       = errors.New("market closed")
+	ErrUnitsBelowMinimum  = fmt.Errorf("%w: below the minimum trade size", ErrInvalidUnits)
+	ErrUnitsAboveMaximum  = fmt.Errorf("%w: above the maximum trade size", ErrInvalidUnits)
+	ErrPriceTooFar        = errors.New("price too far from the market")
+	ErrUnsupportedOrder   = errors.New("unsupported order")
+	ErrContractExpired    = errors.New("contract expired")
+	ErrInvalidExpiry      = errors.New("invalid expiry")
+	ErrNoUnderlying       = errors.New("option has no underlying")
 )
 
+// GapFill is implemented by orders and positions that can tell whether they were filled at a price that gapped past their requested price while the market was closed, like a stop loss jumped over by the open after a weekend. An order reports on its fill and a position reports on its close.
+type GapFill interface {
+	Gapped() bool
+}
+
+// OrderError is returned by a broker when it rejects an order. It wraps the kind of error, like ErrInsufficientMargin, ErrInvalidUnits, ErrInvalidStopLoss, ErrInvalidTakeProfit, ErrPriceTooFar, ErrMarketClosed, ErrUnitsBelowMinimum, or ErrSymbolNotFound, so strategies can branch on it with errors.Is and get the details of the order with errors.As.
+type OrderError struct {
+	Err       error // Err is the kind of error.
+	OrderType OrderType
+	Symbol    string
+	Units     float64
+	Price     float64 // Price is the price of the order, or the market price of a market order.
+	Reason    string  // Reason is an optional explanation, like the reject reason given by a live broker.
+}
+
+func (e *OrderError) Error() string {
+	msg := fmt.Sprintf("%v order of %v units of %s @ $%.5f rejected: %v", e.OrderType, e.Units, e.Symbol, e.Price, e.Err)
+	if e.Reason != "" {
+		msg += " (" + e.Reason + ")"
+	}
+	return msg
+}
+
+func (e *OrderError) Unwrap() error {
+	return e.Err
+}
+
+// Tags are labels attached to an order by the client, like the name of the strategy or setup that placed it. Tags are carried from an order to its position and recorded in the stats of the Trader, which is essential when several strategies share one account.
+type Tags map[string]string
+
+// String returns the tags as "key=value" pairs sorted by key.
+func (t Tags) String() string {
+	keys := maps.Keys(t)
+	slices.Sort(keys)
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + t[key]
+	}
+	return strings.Join(pairs, " ")
+}
+
+// TrailingStopMode is how the distance of a trailing stop loss from the price is measured.
+type TrailingStopMode int
+
+const (
+	TrailingDistance TrailingStopMode = iota // TrailingDistance trails the price by a fixed number of price points, which is the same as a negative stop loss.
+	TrailingPercent                          // TrailingPercent trails the price by a fraction of the price, like 0.02 for 2%.
+	TrailingATR                              // TrailingATR trails the price by a multiple of the average true range, which is recalculated on every candle.
+)
+
+// TrailingStop is a trailing stop loss that follows the price as it moves in favor of a position and closes the position when the price moves back by the distance.
+type TrailingStop struct {
+	Mode      TrailingStopMode
+	Value     float64 // Value is the distance in price points, the fraction of the price, or the multiple of the ATR, depending on the Mode.
+	ATRPeriod int     // ATRPeriod is the number of candles of the ATR of the TrailingATR mode. The default is 14.
+}
+
+// Distance returns the distance of the trailing stop from price on candle i of candles, which are only needed by TrailingATR.
+func (s TrailingStop) Distance(price float64, candles *IndexedFrame[UnixTime], i int) float64 {
+	switch s.Mode {
+	case TrailingPercent:
+		return price * s.Value
+	case TrailingATR:
+		period := s.ATRPeriod
+		if period <= 0 {
+			period = 14
+		}
+		start := Max(i-period, 0) // One extra candle gives the first true range its previous close.
+		return s.Value * ATR(candles.CopyRange(start, i-start+1), period).Value(-1)
+	default:
+		return s.Value
+	}
+}
+
+// BreakEven moves the stop loss of a position to its entry price plus Offset once the price has moved Trigger price points in its favor, so a winning trade cannot turn into a loss.
+type BreakEven struct {
+	Trigger float64 // Trigger is the profit in price points at which the stop loss is moved. Zero disables the break-even stop.
+	Offset  float64 // Offset is how far past the entry price in the direction of the position the stop loss is placed, like a pip to cover costs.
+}
+
+// Stop returns the break-even stop loss of a position of units opened at entry with stopLoss when the price is at price. False is returned if the price has not reached the trigger or the stop loss is already at or past break-even.
+func (b BreakEven) Stop(units, entry, stopLoss, price float64) (float64, bool) {
+	if b.Trigger <= 0 {
+		return 0, false
+	}
+	if units > 0 {
+		stop := entry + b.Offset
+		return stop, price-entry >= b.Trigger && stopLoss < stop
+	}
+	stop := entry - b.Offset
+	return stop, entry-price >= b.Trigger && (stopLoss == 0 || stopLoss > stop)
+}
+
+// MaxHolding is the longest a position may be held before it is closed at market. A position is closed when either limit is reached.
+type MaxHolding struct {
+	Candles  int           // Candles is the number of candles after the one the position was opened on. Zero disables the limit.
+	Duration time.Duration // Duration is how long after the position was opened. Zero disables the limit.
+}
+
+// Expired returns true if a position that has been held for candles candles and for age has reached a limit.
+func (m MaxHolding) Expired(candles int, age time.Duration) bool {
+	return m.Candles > 0 && candles >= m.Candles || m.Duration > 0 && age >= m.Duration
+}
+
+// StopLossModifier is implemented by positions whose stop loss can be changed after they were opened, which is needed to manage their exits, like moving the stop loss to break-even.
+type StopLossModifier interface {
+	SetStopLoss(price float64) error // SetStopLoss replaces the stop loss of the position with price.
+}
+
+// OrderOptions holds the optional settings of an order. Brokers build it from the OrderOption arguments passed to Order with NewOrderOptions.
+type OrderOptions struct {
+	Tags         Tags
+	TrailingStop TrailingStop // TrailingStop replaces the stop loss of the order with a trailing stop loss if its Value is positive.
+	BreakEven    BreakEven    // BreakEven moves the stop loss of the position to break-even once it is in profit. It has no effect with a trailing stop.
+	MaxHolding   MaxHolding   // MaxHolding closes the position with CloseTimeExit once it has been held for too long.
+	Execution    Execution    // Execution splits the order into child orders, which is done by the Trader rather than the broker.
+	TimeInForce  TimeInForce  // TimeInForce is how long the order stays open when it cannot be filled.
+	Expiry       time.Time    // Expiry is when a GoodTilDate order expires.
+}
+
+// OrderOption sets an optional setting of an order.
+type OrderOption func(*OrderOptions)
+
+// WithTags attaches tags to an order. Tags from multiple WithTags options are merged, with later tags taking precedence.
+func WithTags(tags Tags) OrderOption {
+	return func(o *OrderOptions) {
+		if o.Tags == nil {
+			o.Tags = make(Tags, len(tags))
+		}
+		for k, v := range tags {
+			o.Tags[k] = v
+		}
+	}
+}
+
+// WithTrailingStop gives an order a trailing stop loss measured by mode, replacing the stopLoss argument of Order. For example, WithTrailingStop(TrailingATR, 2) trails the price by two ATRs. Brokers that do not support a mode may reject the order.
+func WithTrailingStop(mode TrailingStopMode, value float64) OrderOption {
+	return func(o *OrderOptions) {
+		o.TrailingStop = TrailingStop{Mode: mode, Value: value, ATRPeriod: o.TrailingStop.ATRPeriod}
+	}
+}
+
+// WithBreakEven moves the stop loss of the position of an order to the entry price plus offset once it is trigger price points in profit.
+func WithBreakEven(trigger, offset float64) OrderOption {
+	return func(o *OrderOptions) {
+		o.BreakEven = BreakEven{Trigger: trigger, Offset: offset}
+	}
+}
+
+// WithMaxHolding closes the position of an order at market after it has been held for candles candles or for duration, whichever comes first. Either may be zero to disable it.
+func WithMaxHolding(candles int, duration time.Duration) OrderOption {
+	return func(o *OrderOptions) {
+		o.MaxHolding = MaxHolding{Candles: candles, Duration: duration}
+	}
+}
+
+// WithTimeInForce sets how long an order stays open when it cannot be filled, like FillOrKill. Use WithExpiry for GoodTilDate orders.
+func WithTimeInForce(timeInForce TimeInForce) OrderOption {
+	return func(o *OrderOptions) {
+		o.TimeInForce = timeInForce
+	}
+}
+
+// WithExpiry makes an order GoodTilDate, so it expires at expiry if it has not been filled.
+func WithExpiry(expiry time.Time) OrderOption {
+	return func(o *OrderOptions) {
+		o.TimeInForce, o.Expiry = GoodTilDate, expiry
+	}
+}
+
+// NewOrderOptions applies each option to a new OrderOptions.
+func NewOrderOptions(options ...OrderOption) OrderOptions {
+	var o OrderOptions
+	for _, option := range options {
+		option(&o)
+	}
+	return o
+}
+
+// TradeCosts is the cost of executing a single fill, in the account currency. Each cost is positive when it was paid by the trader and may be negative when it worked in the trader's favor, such as slippage to a better price.
+type TradeCosts struct {
+	Spread     float64 // Spread is the cost of crossing the bid/ask spread.
+	Commission float64 // Commission is the fee charged by the broker.
+	Slippage   float64 // Slippage is the difference between the requested price and the price the trade was filled at, multiplied by the units.
+	Financing  float64 // Financing is the swap or funding paid for holding the position, which is reported when it is closed.
+	SpreadPips float64 // SpreadPips is the spread paid in pips. It is not in the account currency, so it is not part of the Total.
+}
+
+// Total returns the sum of all costs.
+func (c TradeCosts) Total() float64 {
+	return c.Spread + c.Commission + c.Slippage + c.Financing
+}
+
 type Order interface {
 	Cancel() error         // Cancel attempts to cancel the order and returns an error if it fails. If the error is nil, the order was canceled.
+	Costs() TradeCosts     // Costs returns the costs paid to fill the order. The costs are zero until the order has been filled.
 	Fulfilled() bool       // Fulfilled returns true if the order has been filled with the broker and a position is active.
 	Id() string            // Id returns the unique identifier of the order by the broker.
 	Leverage() float64     // Leverage returns the leverage of the order.
@@ -45,6 +298,7 @@ type Order interface {
 	Symbol() string        // Symbol returns the symbol name of the order.
 	TrailingStop() float64 // TrailingStop returns the trailing stop loss distance of the order.
 	StopLoss() float64     // StopLoss returns the stop loss price of the order.
+	Tags() Tags            // Tags returns the tags the order was placed with, which may be nil.
 	TakeProfit() float64   // TakeProfit returns the take profit price of the order.
 	Time() time.Time       // Time returns the time the order was placed.
 	Type() OrderType       // Type returns the type of order.
@@ -55,6 +309,7 @@ type Position interface {
 	Close() error              // Close attempts to close the position and returns an error if it fails. If the error is nil, the position was closed.
 	Closed() bool              // Closed returns true if the position has been closed with the broker.
 	CloseType() OrderCloseType // CloseType returns the type of order used to close the position.
+	CloseCosts() TradeCosts    // CloseCosts returns the costs paid to close the position. The costs are zero until the position has been closed.
 	ClosePrice() float64       // ClosePrice returns the price of the symbol at the time the position was closed. May be zero if the position is still open.
 	EntryPrice() float64       // EntryPrice returns the price of the symbol at the time the position was opened.
 	EntryValue() float64       // EntryValue returns the value of the position at the time it was opened.
@@ -64,6 +319,7 @@ type Position interface {
 	Symbol() string            // Symbol returns the symbol name of the position.
 	TrailingStop() float64     // TrailingStop returns the trailing stop loss price of the position.
 	StopLoss() float64         // StopLoss returns the stop loss price of the position.
+	Tags() Tags                // Tags returns the tags of the order that opened the position, which may be nil.
 	TakeProfit() float64       // TakeProfit returns the take profit price of the position.
 	Time() time.Time           // Time returns the time the position was opened.
 	Units() float64            // Units returns the number of units purchased or sold by the position.
@@ -73,15 +329,21 @@ type Position interface {
 // Broker is an interface that defines the methods that a broker must implement to report symbol data and place orders, etc. All Broker implementations must also implement the Signaler interface and emit the following functions when necessary:
 //
 //   - PositionClosed(Position) - Emitted after a position is closed either manually or automatically.
+//   - PositionModified(Position) - Emitted after the stop loss, trailing stop, or take profit of a position changes.
+//
+// Brokers that follow candles as they form may also emit these signals:
+//
+//   - CandleOpened(CandleEvent) - Emitted when a new candle starts.
+//   - CandleClosed(CandleEvent) - Emitted when a candle is complete.
 type Broker interface {
 	Signaler
 	Price(symbol string, wantToBuy bool) float64 // Price returns the ask price if wantToBuy is true and the bid price if wantToBuy is false.
 	Bid(symbol string) float64                   // Bid returns the sell price of the symbol.
 	Ask(symbol string) float64                   // Ask returns the buy price of the symbol, which is typically higher than the sell price.
-	// Candles returns a dataframe of candles for the given symbol, frequency, and count by querying the broker.
-	Candles(symbol, frequency string, count int) (*IndexedFrame[UnixTime], error)
-	// Order places an order with orderType for the given symbol and returns an error if it fails. A short position has negative units. If the orderType is Market, the price argument will be ignored and the order will be fulfilled at current price. Otherwise, price is used to set the target price for Stop and Limit orders. If stopLoss or takeProfit are zero, they will not be set. If the stopLoss is greater than the current price for a long position or less than the current price for a short position, the order will fail. Likewise for takeProfit. If the stopLoss is a negative number, it is used as a trailing stop loss to represent how many price points away the stop loss should be from the current price.
-	Order(orderType OrderType, symbol string, units, price, stopLoss, takeProfit float64) (Order, error)
+	// Candles returns a dataframe of candles for the given symbol, frequency, and count by querying the broker. The request is abandoned with the error of ctx if ctx is done first.
+	Candles(ctx context.Context, symbol, frequency string, count int) (*IndexedFrame[UnixTime], error)
+	// Order places an order with orderType for the given symbol and returns an error if it fails. A short position has negative units. If the orderType is Market, the price argument will be ignored and the order will be fulfilled at current price. Otherwise, price is used to set the target price for Stop and Limit orders. If stopLoss or takeProfit are zero, they will not be set. If the stopLoss is greater than the current price for a long position or less than the current price for a short position, the order will fail. Likewise for takeProfit. If the stopLoss is a negative number, it is used as a trailing stop loss to represent how many price points away the stop loss should be from the current price. Optional settings like tags are given as options. A rejected order returns an *OrderError. The request is abandoned with the error of ctx if ctx is done first.
+	Order(ctx context.Context, orderType OrderType, symbol string, units, price, stopLoss, takeProfit float64, options ...OrderOption) (Order, error)
 	NAV() float64 // NAV returns the net asset value of the account.
 	PL() float64  // PL returns the profit or loss of the account.
 	OpenOrders() []Order
@@ -92,4 +354,58 @@ type Broker interface {
 	// Positions returns a slice of positions that are currently open with the broker. If a position has been
 	// closed, it will not be returned.
 	Positions() []Position
+	// OrderByID returns the order with the given id, or ErrOrderNotFound if the broker has no such order.
+	OrderByID(ctx context.Context, id string) (Order, error)
+	// PositionByID returns the position with the given id, or ErrPositionNotFound if the broker has no such position.
+	PositionByID(ctx context.Context, id string) (Position, error)
+	// Capabilities returns what the broker supports, so the Trader and strategies can adapt to it.
+	Capabilities() Capabilities
+}
+
+// Capabilities describe what a broker supports, so the Trader and strategies can adapt to it, like emulating trailing stops client-side when the broker lacks them or polling for candles when it does not stream them.
+type Capabilities struct {
+	OrderTypes    []OrderType        // OrderTypes are the types of orders the broker accepts.
+	StopLoss      bool               // StopLoss is true if a stop loss can be attached to an order.
+	TakeProfit    bool               // TakeProfit is true if a take profit can be attached to an order.
+	TrailingStops []TrailingStopMode // TrailingStops are the modes of trailing stop losses the broker supports, which is empty if it has none.
+	Hedging       bool               // Hedging is true if the broker can hold long and short positions in the same symbol at once. Otherwise, an opposite order reduces or closes the position.
+	Streaming     bool               // Streaming is true if the broker emits CandleClosed signals, so a Trader can be CandleDriven.
+	MinFrequency  string             // MinFrequency is the finest frequency of candles the broker provides, like "S5" or "M1". It is empty if it is unknown.
+}
+
+// SupportsOrderType returns true if the broker accepts orders of orderType.
+func (c Capabilities) SupportsOrderType(orderType OrderType) bool {
+	return slices.Contains(c.OrderTypes, orderType)
+}
+
+// SupportsTrailingStop returns true if the broker supports trailing stop losses measured by mode.
+func (c Capabilities) SupportsTrailingStop(mode TrailingStopMode) bool {
+	return slices.Contains(c.TrailingStops, mode)
+}
+
+// Intersect returns the capabilities that both c and other have, which are what a combination of brokers can be relied upon to support. The MinFrequency is the coarser of the two.
+func (c Capabilities) Intersect(other Capabilities) Capabilities {
+	both := Capabilities{
+		StopLoss:     c.StopLoss && other.StopLoss,
+		TakeProfit:   c.TakeProfit && other.TakeProfit,
+		Hedging:      c.Hedging && other.Hedging,
+		Streaming:    c.Streaming && other.Streaming,
+		MinFrequency: c.MinFrequency,
+	}
+	for _, orderType := range c.OrderTypes {
+		if other.SupportsOrderType(orderType) {
+			both.OrderTypes = append(both.OrderTypes, orderType)
+		}
+	}
+	for _, mode := range c.TrailingStops {
+		if other.SupportsTrailingStop(mode) {
+			both.TrailingStops = append(both.TrailingStops, mode)
+		}
+	}
+	mine, err := FrequencyDuration(c.MinFrequency)
+	theirs, otherErr := FrequencyDuration(other.MinFrequency)
+	if err != nil || otherErr == nil && theirs > mine {
+		both.MinFrequency = other.MinFrequency
+	}
+	return both
 }
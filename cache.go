@@ -0,0 +1,110 @@
+package autotrader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CachedBroker wraps a Broker and keeps the candles it returns in CSV files in Dir, one file per symbol and frequency. When a cached dataset exists, Candles only requests the candles that are newer than the last cached candle from the wrapped broker and appends them to the cache, rather than downloading the entire window again after every backtest or restart.
+//
+// All other methods are passed through to the wrapped Broker.
+type CachedBroker struct {
+	Broker
+	Dir string // Dir is the directory the cache files are stored in. It is created if it does not exist.
+
+	frames map[string]*IndexedFrame[UnixTime] // Cached candles by file path.
+	now    func() time.Time
+}
+
+// NewCachedBroker returns a CachedBroker that caches the candles of broker in dir.
+func NewCachedBroker(broker Broker, dir string) *CachedBroker {
+	return &CachedBroker{
+		Broker: broker,
+		Dir:    dir,
+		frames: make(map[string]*IndexedFrame[UnixTime]),
+		now:    time.Now,
+	}
+}
+
+// CachePath returns the path of the file that caches the candles of symbol at frequency.
+func (b *CachedBroker) CachePath(symbol, frequency string) string {
+	return filepath.Join(b.Dir, fmt.Sprintf("%s_%s.csv", strings.ReplaceAll(symbol, string(filepath.Separator), "_"), frequency))
+}
+
+// Candles returns the last count candles of symbol at frequency. Only the candles since the last cached candle are requested from the wrapped broker, including the last cached candle itself in case it was incomplete. If the cache holds fewer than count candles, the entire window is requested.
+func (b *CachedBroker) Candles(ctx context.Context, symbol, frequency string, count int) (*IndexedFrame[UnixTime], error) {
+	path := b.CachePath(symbol, frequency)
+	cached, err := b.load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fetch := count
+	if cached != nil && cached.Len() >= count {
+		if freq, err := FrequencyDuration(frequency); err == nil {
+			last := cached.Date(-1).Time()
+			fetch = Min(int(b.now().Sub(last)/freq)+1, count)
+		}
+	}
+	if fetch > 0 {
+		candles, err := b.Broker.Candles(ctx, symbol, frequency, fetch)
+		if candles == nil {
+			return nil, err
+		}
+		if cached == nil {
+			cached = candles
+		} else {
+			candles.ForEachSeries(func(s *IndexedSeries[UnixTime]) {
+				dst := cached.Series(s.Name())
+				if dst == nil {
+					return
+				}
+				for i := 0; i < s.Len(); i++ {
+					dst.Insert(*s.Index(i), s.Value(i)) // Overwrites the candles we already had.
+				}
+			})
+		}
+		b.frames[path] = cached
+		if saveErr := b.save(path, cached); saveErr != nil {
+			return nil, saveErr
+		}
+		if err != nil { // Some brokers return candles along with an error, like ErrEOF.
+			return cached.CopyRange(-count, -1), err
+		}
+	}
+	return cached.CopyRange(-count, -1), nil
+}
+
+// load returns the cached candles at path, reading them from disk if they are not in memory. A nil frame is returned if there is no cache.
+func (b *CachedBroker) load(path string) (*IndexedFrame[UnixTime], error) {
+	if frame, ok := b.frames[path]; ok {
+		return frame, nil
+	}
+	frame, err := IndexedFrameFromCSV(path, CandlesCSVLayout)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading candle cache: %w", err)
+	}
+	b.frames[path] = frame
+	return frame, nil
+}
+
+func (b *CachedBroker) save(path string, frame *IndexedFrame[UnixTime]) error {
+	if err := os.MkdirAll(b.Dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteCandlesCSV(f, frame); err != nil {
+		f.Close()
+		return fmt.Errorf("error writing candle cache: %w", err)
+	}
+	return f.Close()
+}
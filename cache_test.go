@@ -0,0 +1,60 @@
+package autotrader
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// countingBroker returns candles from a TestBroker and records how many candles were requested.
+type countingBroker struct {
+	*TestBroker
+	requested []int
+}
+
+func (b *countingBroker) Candles(ctx context.Context, symbol, frequency string, count int) (*IndexedFrame[UnixTime], error) {
+	b.requested = append(b.requested, count)
+	return b.TestBroker.Candles(ctx, symbol, frequency, count)
+}
+
+func TestCachedBroker(t *testing.T) {
+	dir := t.TempDir()
+	source := &countingBroker{TestBroker: NewTestBroker(nil, testData, 0, 0, 0, 5)}
+	cache := NewCachedBroker(source, dir)
+	cache.now = func() time.Time { return source.Data.Date(source.CandleIndex()).Time() }
+
+	candles, err := cache.Candles(context.Background(), "EUR_USD", "D", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if candles.Len() != 5 || source.requested[0] != 5 {
+		t.Fatalf("Expected the first request to fetch all 5 candles, got %d and requested %v", candles.Len(), source.requested)
+	}
+
+	source.Advance()
+	candles, err = cache.Candles(context.Background(), "EUR_USD", "D", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source.requested[1] != 2 {
+		t.Errorf("Expected to only request the last cached candle and the new candle, requested %d", source.requested[1])
+	}
+	if candles.Len() != 5 || candles.Close(-1) != testData.Close(5) {
+		t.Errorf("Expected 5 candles ending with close %f, got %v", testData.Close(5), candles)
+	}
+
+	// A new CachedBroker should pick up where the last one left off by reading the cache from disk.
+	source.Advance()
+	cache = NewCachedBroker(source, dir)
+	cache.now = func() time.Time { return source.Data.Date(source.CandleIndex()).Time() }
+	candles, err = cache.Candles(context.Background(), "EUR_USD", "D", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source.requested[2] != 2 {
+		t.Errorf("Expected the cache on disk to be used, requested %d candles", source.requested[2])
+	}
+	if candles.Len() != 5 || candles.Close(-1) != testData.Close(6) || !candles.Date(0).Time().Equal(testData.Date(2).Time()) {
+		t.Errorf("Expected 5 candles from %v to close %f, got %v", testData.Date(2), testData.Close(6), candles)
+	}
+}
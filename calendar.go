@@ -0,0 +1,87 @@
+package autotrader
+
+import (
+	"time"
+)
+
+const weekLength = 7 * 24 * time.Hour
+
+// MarketCalendar tells when a market is open for trading.
+type MarketCalendar interface {
+	IsOpen(t time.Time) bool         // IsOpen returns true if the market is open at t.
+	NextClose(t time.Time) time.Time // NextClose returns the first time after t that the market closes.
+}
+
+// MarketHours is a MarketCalendar of a market that trades continuously from a weekly open to a weekly close, like forex, except on holidays. Daylight saving time is not accounted for.
+type MarketHours struct {
+	OpenDay   time.Weekday  // OpenDay is the day of the week the market opens, like time.Sunday for forex.
+	OpenTime  time.Duration // OpenTime is the time of day the market opens on OpenDay in UTC.
+	CloseDay  time.Weekday  // CloseDay is the day of the week the market closes, like time.Friday for forex.
+	CloseTime time.Duration // CloseTime is the time of day the market closes on CloseDay in UTC.
+	Holidays  []time.Time   // Holidays are the days in UTC that the market is closed for the entire day.
+}
+
+// ForexHours are the hours of the forex market, which opens Sunday at 22:00 UTC and closes Friday at 22:00 UTC.
+var ForexHours = &MarketHours{OpenDay: time.Sunday, OpenTime: 22 * time.Hour, CloseDay: time.Friday, CloseTime: 22 * time.Hour}
+
+func (h *MarketHours) IsOpen(t time.Time) bool {
+	if h.holiday(t) {
+		return false
+	}
+	offset := weekOffset(t)
+	open, close := h.openOffset(), h.closeOffset()
+	if open < close {
+		return offset >= open && offset < close
+	}
+	return offset >= open || offset < close // The trading week wraps around Sunday at midnight.
+}
+
+func (h *MarketHours) NextClose(t time.Time) time.Time {
+	t = t.UTC()
+	next := weekStart(t).Add(h.closeOffset())
+	if !next.After(t) {
+		next = next.Add(weekLength)
+	}
+	// A holiday closes the market at the start of the day if it would otherwise be open.
+	for _, holiday := range h.Holidays {
+		start := dayStart(holiday.UTC())
+		if start.After(t) && start.Before(next) && h.IsOpen(start.Add(-time.Nanosecond)) {
+			next = start
+		}
+	}
+	return next
+}
+
+func (h *MarketHours) openOffset() time.Duration {
+	return time.Duration(h.OpenDay)*24*time.Hour + h.OpenTime
+}
+
+func (h *MarketHours) closeOffset() time.Duration {
+	return time.Duration(h.CloseDay)*24*time.Hour + h.CloseTime
+}
+
+func (h *MarketHours) holiday(t time.Time) bool {
+	day := dayStart(t.UTC())
+	for _, holiday := range h.Holidays {
+		if dayStart(holiday.UTC()).Equal(day) {
+			return true
+		}
+	}
+	return false
+}
+
+// dayStart returns midnight of the day of t.
+func dayStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// weekStart returns midnight of the Sunday of the week of t.
+func weekStart(t time.Time) time.Time {
+	return dayStart(t).AddDate(0, 0, -int(t.Weekday()))
+}
+
+// weekOffset returns the time since the start of the week of t in UTC.
+func weekOffset(t time.Time) time.Duration {
+	t = t.UTC()
+	return t.Sub(weekStart(t))
+}
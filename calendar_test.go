@@ -0,0 +1,46 @@
+package autotrader
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMarketHours(t *testing.T) {
+	hours := &MarketHours{
+		OpenDay:   ForexHours.OpenDay,
+		OpenTime:  ForexHours.OpenTime,
+		CloseDay:  ForexHours.CloseDay,
+		CloseTime: ForexHours.CloseTime,
+		Holidays:  []time.Time{time.Date(2023, 12, 25, 0, 0, 0, 0, time.UTC)}, // Monday
+	}
+	open := []struct {
+		time time.Time
+		open bool
+	}{
+		{time.Date(2023, 12, 13, 12, 0, 0, 0, time.UTC), true},  // Wednesday
+		{time.Date(2023, 12, 15, 21, 59, 0, 0, time.UTC), true}, // Friday before the close
+		{time.Date(2023, 12, 15, 22, 0, 0, 0, time.UTC), false}, // Friday at the close
+		{time.Date(2023, 12, 16, 12, 0, 0, 0, time.UTC), false}, // Saturday
+		{time.Date(2023, 12, 17, 21, 59, 0, 0, time.UTC), false},
+		{time.Date(2023, 12, 17, 22, 0, 0, 0, time.UTC), true},  // Sunday at the open
+		{time.Date(2023, 12, 25, 12, 0, 0, 0, time.UTC), false}, // Holiday
+	}
+	for _, test := range open {
+		if got := hours.IsOpen(test.time); got != test.open {
+			t.Errorf("Expected IsOpen(%v) to be %v, got %v", test.time, test.open, got)
+		}
+	}
+
+	closes := []struct {
+		time, next time.Time
+	}{
+		{time.Date(2023, 12, 13, 12, 0, 0, 0, time.UTC), time.Date(2023, 12, 15, 22, 0, 0, 0, time.UTC)},
+		{time.Date(2023, 12, 15, 23, 0, 0, 0, time.UTC), time.Date(2023, 12, 22, 22, 0, 0, 0, time.UTC)},
+		{time.Date(2023, 12, 24, 23, 0, 0, 0, time.UTC), time.Date(2023, 12, 25, 0, 0, 0, 0, time.UTC)}, // Closed early for the holiday.
+	}
+	for _, test := range closes {
+		if got := hours.NextClose(test.time); !got.Equal(test.next) {
+			t.Errorf("Expected NextClose(%v) to be %v, got %v", test.time, test.next, got)
+		}
+	}
+}
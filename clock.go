@@ -0,0 +1,57 @@
+package autotrader
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the current time. A live Trader uses the wall clock, while a backtest uses the simulated time of its TestBroker, which is the date of the current candle, so orders, positions, and time-based strategy logic follow the historical data rather than the time the backtest was run.
+type Clock interface {
+	Now() time.Time
+}
+
+// RealClock is a Clock of the wall clock time.
+type RealClock struct{}
+
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+// SimulatedClock is a Clock that only moves when it is set or advanced. It is safe for concurrent use.
+type SimulatedClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewSimulatedClock returns a SimulatedClock set to now.
+func NewSimulatedClock(now time.Time) *SimulatedClock {
+	return &SimulatedClock{now: now}
+}
+
+func (c *SimulatedClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set moves the clock to now.
+func (c *SimulatedClock) Set(now time.Time) {
+	c.mu.Lock()
+	c.now = now
+	c.mu.Unlock()
+}
+
+// Advance moves the clock forward by d.
+func (c *SimulatedClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// brokerNow returns the time of broker if it is a Clock, like a TestBroker, and the wall clock time otherwise.
+func brokerNow(broker Broker) time.Time {
+	if clock, ok := broker.(Clock); ok {
+		return clock.Now()
+	}
+	return time.Now()
+}
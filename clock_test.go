@@ -0,0 +1,52 @@
+package autotrader
+
+import (
+	"testing"
+	"time"
+)
+
+// clockStrategy records the time of the Trader on every candle.
+type clockStrategy struct {
+	times []time.Time
+}
+
+func (s *clockStrategy) Init(_ *Trader) {}
+
+func (s *clockStrategy) Next(t *Trader) {
+	s.times = append(s.times, t.Now())
+	if len(s.times) == 3 {
+		t.Buy(1000, 0, 0)
+	}
+}
+
+func TestTraderClock(t *testing.T) {
+	strategy := &clockStrategy{}
+	trader, broker := runTestBacktest(t, strategy)
+	if len(strategy.times) != testData.Len() {
+		t.Fatalf("Expected %d times, got %d", testData.Len(), len(strategy.times))
+	}
+	for i, now := range strategy.times {
+		if !now.Equal(testData.Date(i).Time()) {
+			t.Errorf("Expected time of candle %d to be %v, got %v", i, testData.Date(i).Time(), now)
+		}
+	}
+	position := broker.Positions()[0]
+	if !position.Time().Equal(testData.Date(2).Time()) {
+		t.Errorf("Expected position to be opened at %v, got %v", testData.Date(2).Time(), position.Time())
+	}
+
+	clock := NewSimulatedClock(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	trader.Clock = clock
+	clock.Advance(time.Hour)
+	if expected := time.Date(2030, 1, 1, 1, 0, 0, 0, time.UTC); !trader.Now().Equal(expected) {
+		t.Errorf("Expected the clock of the Trader to override the broker, got %v", trader.Now())
+	}
+}
+
+func TestTraderRealClock(t *testing.T) {
+	trader := NewTrader(TraderConfig{Broker: NewSpreadBroker(nil, nil)})
+	before := time.Now()
+	if now := trader.Now(); now.Before(before) || now.After(time.Now()) {
+		t.Errorf("Expected the wall clock for a broker that is not a Clock, got %v", now)
+	}
+}
@@ -0,0 +1,44 @@
+// Command autotrader is a helper for developing autotrader strategies.
+//
+// Usage:
+//
+//	autotrader new strategy [-o dir] [-symbol symbol] [-frequency frequency] Name
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+const usage = `Usage:
+  autotrader new strategy [-o dir] [-symbol symbol] [-frequency frequency] Name
+
+Commands:
+  new strategy  Generate a strategy file with Init and Next stubs, parameter declarations, and a backtest main.
+`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// run executes the command given by args and writes its output to w.
+func run(args []string, w io.Writer) error {
+	if len(args) < 2 || args[0] != "new" {
+		fmt.Fprint(w, usage)
+		if len(args) == 0 || args[0] == "help" || args[0] == "-h" {
+			return nil
+		}
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	switch args[1] {
+	case "strategy":
+		return newStrategy(args[2:], w)
+	default:
+		fmt.Fprint(w, usage)
+		return fmt.Errorf("cannot generate a %q, only a strategy", args[1])
+	}
+}
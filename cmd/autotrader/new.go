@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+var ErrInvalidName = errors.New("strategy name must be an exported Go identifier")
+
+// strategyTemplate is the Go source of a new strategy. The generated file is a standalone main package, so it can be run with "go run" right away.
+var strategyTemplate = template.Must(template.New("strategy").Parse(`package main
+
+import (
+	"fmt"
+	"os"
+
+	auto "github.com/fivemoreminix/autotrader"
+)
+
+// {{.Name}} is a trading strategy.
+type {{.Name}} struct {
+	// Parameters of the strategy. Declare them as fields so they can be set by New{{.Name}} and searched by an auto.Optimizer.
+	Period int     // Period is the number of candles the strategy looks back on.
+	Units  float64 // Units is the number of units to trade.
+}
+
+// New{{.Name}} returns a {{.Name}} with the parameters in params, using defaults for the ones that are missing.
+func New{{.Name}}(params auto.Parameters) *{{.Name}} {
+	s := &{{.Name}}{Period: 20, Units: 1000}
+	if period, ok := params["Period"].(int); ok {
+		s.Period = period
+	}
+	if units, ok := params["Units"].(float64); ok {
+		s.Units = units
+	}
+	return s
+}
+
+// Init is called once before the first candle. Connect to broker signals or prepare state here.
+func (s *{{.Name}}) Init(t *auto.Trader) {
+}
+
+// Next is called on every new candle. Read t.Data() and place orders with t.Buy, t.Sell, and t.CloseOrdersAndPositions.
+func (s *{{.Name}}) Next(t *auto.Trader) {
+	if t.Data().Len() < s.Period {
+		return // Not enough candles yet.
+	}
+	// TODO: implement the strategy.
+}
+
+func main() {
+	// Backtest on the candles in a CSV file. Replace auto.EURUSD with auto.IndexedFrameFromCSV(path, layout) to use other data.
+	data, err := auto.EURUSD()
+	if err != nil {
+		fmt.Println("error:", err)
+		os.Exit(1)
+	}
+	auto.Backtest(auto.NewTrader(auto.TraderConfig{
+		Broker:        auto.NewTestBroker(nil, data, 10000, 50, 0.0002, 0),
+		Strategy:      New{{.Name}}(nil),
+		Symbol:        {{printf "%q" .Symbol}},
+		Frequency:     {{printf "%q" .Frequency}},
+		CandlesToKeep: 1000,
+	}))
+}
+`))
+
+// strategyFile holds the values of strategyTemplate.
+type strategyFile struct {
+	Name      string
+	Symbol    string
+	Frequency string
+}
+
+// newStrategy generates a strategy file named after the snake case of the strategy name, refusing to overwrite an existing file.
+func newStrategy(args []string, w io.Writer) error {
+	flags := flag.NewFlagSet("new strategy", flag.ContinueOnError)
+	flags.SetOutput(w)
+	dir := flags.String("o", ".", "directory to write the strategy file to")
+	symbol := flags.String("symbol", "EUR_USD", "symbol to backtest the strategy on")
+	frequency := flags.String("frequency", "D", "frequency of the candles to backtest the strategy on")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected one strategy name, got %d", flags.NArg())
+	}
+	name := flags.Arg(0)
+	if !token.IsIdentifier(name) || !token.IsExported(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+
+	src, err := generateStrategy(strategyFile{Name: name, Symbol: *symbol, Frequency: *frequency})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		return err
+	}
+	path := filepath.Join(*dir, snakeCase(name)+".go")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(src); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Created %s. Backtest it with: go run %s\n", path, path)
+	return nil
+}
+
+// generateStrategy returns the formatted source of a new strategy.
+func generateStrategy(file strategyFile) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := strategyTemplate.Execute(&buf, file); err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+// snakeCase converts a name like "MyRSIStrategy" to "my_rsi_strategy".
+func snakeCase(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// Start a new word at a lowercase to uppercase change and before the last capital of an acronym.
+			if i > 0 && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1])) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
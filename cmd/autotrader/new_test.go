@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewStrategy(t *testing.T) {
+	dir := t.TempDir()
+	var out bytes.Buffer
+	if err := run([]string{"new", "strategy", "-o", dir, "-symbol", "GBP_USD", "MyRSIStrategy"}, &out); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	path := filepath.Join(dir, "my_rsi_strategy.go")
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected strategy file at %s, got %v", path, err)
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), path, src, 0)
+	if err != nil {
+		t.Fatalf("Expected generated file to parse, got %v", err)
+	}
+	if file.Name.Name != "main" {
+		t.Errorf("Expected package main, got %s", file.Name.Name)
+	}
+	for _, want := range []string{"type MyRSIStrategy struct", "func (s *MyRSIStrategy) Init(", "func (s *MyRSIStrategy) Next(", "func NewMyRSIStrategy(", `Symbol:        "GBP_USD"`} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("Expected generated file to contain %q", want)
+		}
+	}
+
+	if err := run([]string{"new", "strategy", "-o", dir, "MyRSIStrategy"}, &out); !os.IsExist(err) {
+		t.Errorf("Expected existing file error, got %v", err)
+	}
+	if err := run([]string{"new", "strategy", "-o", dir, "myStrategy"}, &out); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("Expected ErrInvalidName, got %v", err)
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	for name, expected := range map[string]string{
+		"MyStrategy":    "my_strategy",
+		"MyRSIStrategy": "my_rsi_strategy",
+		"SMA":           "sma",
+		"Ichimoku2":     "ichimoku2",
+	} {
+		if got := snakeCase(name); got != expected {
+			t.Errorf("Expected %s, got %s", expected, got)
+		}
+	}
+}
@@ -3,6 +3,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 
@@ -16,7 +17,7 @@ func main() {
 		return
 	}
 
-	candles, err := broker.Candles("EUR_USD", "D", 100)
+	candles, err := broker.Candles(context.Background(), "EUR_USD", "D", 100)
 	if err != nil {
 		panic(err)
 	}
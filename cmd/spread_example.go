@@ -28,11 +28,13 @@ func (s *SpreadPayupStrategy) Next(t *auto.Trader) {
 }
 
 func main() {
-	/* data, err := auto.EURUSD()
-	if err != nil {
-		panic(err)
-	}
-	*/
+	// To backtest against a CSV file instead, pass its data to NewTestBroker with a nil data broker:
+	//
+	//	data, err := auto.EURUSD() // Or auto.IndexedFrameFromCSV(path, layout)
+	//	if err != nil {
+	//		panic(err)
+	//	}
+	//	broker := auto.NewTestBroker(nil, data, 10000, 50, 0.0002, 0)
 	broker, err := oanda.NewOandaBroker(os.Getenv("OANDA_TOKEN"), os.Getenv("OANDA_ACCOUNT_ID"), true)
 	if err != nil {
 		fmt.Println("error:", err)
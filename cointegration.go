@@ -0,0 +1,212 @@
+package autotrader
+
+import (
+	"errors"
+	"math"
+)
+
+var (
+	ErrNotEnoughData    = errors.New("not enough data")
+	ErrSingularMatrix   = errors.New("regressors are linearly dependent")
+	ErrLengthMismatched = errors.New("series have different lengths")
+)
+
+// ADFResult is the result of an augmented Dickey-Fuller test for a unit root.
+type ADFResult struct {
+	Statistic float64 // Statistic is the t-statistic of the lagged level. The more negative it is, the stronger the evidence that the series is stationary.
+	Lags      int     // Lags is the number of lagged differences that were included in the regression.
+	// Critical1, Critical5, and Critical10 are the critical values of the statistic at the 1%, 5%, and 10% significance levels.
+	Critical1, Critical5, Critical10 float64
+}
+
+// Stationary returns true if the series is stationary at the 5% significance level, meaning the statistic is below the 5% critical value.
+func (r ADFResult) Stationary() bool {
+	return r.Statistic < r.Critical5
+}
+
+// ADF runs the augmented Dickey-Fuller test on series with a constant and the given number of lagged differences. If lags is negative, it is picked from the length of the series with the rule of Schwert, 12*(n/100)^(1/4). A stationary series reverts to its mean, which is what a spread must do to be traded with mean reversion.
+//
+// The critical values are the asymptotic values of MacKinnon for a regression with a constant.
+func ADF(series *FloatSeries, lags int) (ADFResult, error) {
+	result, err := adf(series.Values(), lags)
+	result.Critical1, result.Critical5, result.Critical10 = -3.43, -2.86, -2.57
+	return result, err
+}
+
+// adf returns the statistic and lags of the augmented Dickey-Fuller test without critical values.
+func adf(values []float64, lags int) (ADFResult, error) {
+	if lags < 0 {
+		lags = int(12 * math.Pow(float64(len(values))/100, 0.25))
+	}
+	if len(values)-1-lags <= lags+3 { // Too few differences to regress on the lags.
+		return ADFResult{Lags: lags}, ErrNotEnoughData
+	}
+	diffs := make([]float64, len(values)-1)
+	for i := range diffs {
+		diffs[i] = values[i+1] - values[i]
+	}
+	// Regress each difference on a constant, the previous level, and the previous lags differences.
+	var x [][]float64
+	var y []float64
+	for t := lags; t < len(diffs); t++ {
+		row := []float64{1, values[t]}
+		for lag := 1; lag <= lags; lag++ {
+			row = append(row, diffs[t-lag])
+		}
+		x = append(x, row)
+		y = append(y, diffs[t])
+	}
+	coef, stdErr, err := ols(x, y)
+	if err != nil {
+		return ADFResult{Lags: lags}, err
+	}
+	return ADFResult{Statistic: coef[1] / stdErr[1], Lags: lags}, nil
+}
+
+// CointegrationResult is the result of an Engle-Granger cointegration test of two series.
+type CointegrationResult struct {
+	HedgeRatio float64   // HedgeRatio is the units of b to sell for each unit of a bought, so the spread is a - HedgeRatio*b - Intercept.
+	Intercept  float64   // Intercept is the mean of the spread.
+	ADF        ADFResult // ADF is the test of the spread for stationarity, with the critical values of Engle and Granger for two series.
+}
+
+// Cointegrated returns true if the series are cointegrated at the 5% significance level.
+func (r CointegrationResult) Cointegrated() bool {
+	return r.ADF.Stationary()
+}
+
+// Cointegration runs the Engle-Granger test of whether a and b are cointegrated, which means a spread of a long and b short at the hedge ratio is stationary even though a and b wander on their own. The hedge ratio is found by regressing a on b, then the spread is tested with ADF using lags lagged differences, or a number picked from the length of the series if lags is negative.
+func Cointegration(a, b *FloatSeries, lags int) (CointegrationResult, error) {
+	if a.Len() != b.Len() {
+		return CointegrationResult{}, ErrLengthMismatched
+	}
+	beta, alpha, err := hedgeRatio(a.Values(), b.Values())
+	if err != nil {
+		return CointegrationResult{}, err
+	}
+	spread := make([]float64, a.Len())
+	for i := range spread {
+		spread[i] = a.Value(i) - beta*b.Value(i) - alpha
+	}
+	result, err := adf(spread, lags)
+	result.Critical1, result.Critical5, result.Critical10 = -3.90, -3.34, -3.04
+	return CointegrationResult{HedgeRatio: beta, Intercept: alpha, ADF: result}, err
+}
+
+// HedgeRatio returns the slope and intercept of the least squares regression of a on b, which is the number of units of b that hedge one unit of a.
+func HedgeRatio(a, b *FloatSeries) (beta, alpha float64, err error) {
+	if a.Len() != b.Len() {
+		return 0, 0, ErrLengthMismatched
+	}
+	return hedgeRatio(a.Values(), b.Values())
+}
+
+func hedgeRatio(a, b []float64) (beta, alpha float64, err error) {
+	if len(a) < 2 {
+		return 0, 0, ErrNotEnoughData
+	}
+	x := make([][]float64, len(b))
+	for i := range b {
+		x[i] = []float64{1, b[i]}
+	}
+	coef, _, err := ols(x, a)
+	if err != nil {
+		return 0, 0, err
+	}
+	return coef[1], coef[0], nil
+}
+
+// RollingHedgeRatio returns the hedge ratio of a on b over the last period values at each row, so the ratio adapts as the relationship between the series drifts. The first rows use the values available so far, and rows where the hedge ratio cannot be calculated are 0. The series must have the same length.
+func RollingHedgeRatio(a, b *FloatSeries, period int) *FloatSeries {
+	ratios := make([]float64, Min(a.Len(), b.Len()))
+	for i := range ratios {
+		start := Max(i-period+1, 0)
+		ratios[i], _, _ = hedgeRatio(a.ValueRange(start, i-start+1), b.ValueRange(start, i-start+1))
+	}
+	return NewFloatSeries("HedgeRatio", ratios...)
+}
+
+// ols returns the coefficients of the ordinary least squares regression of y on the rows of x, and their standard errors.
+func ols(x [][]float64, y []float64) (coef, stdErr []float64, err error) {
+	k := len(x[0])
+	// Build the normal equations X'X and X'y.
+	xtx := make([][]float64, k)
+	xty := make([]float64, k)
+	for i := range xtx {
+		xtx[i] = make([]float64, k)
+	}
+	for r, row := range x {
+		for i := 0; i < k; i++ {
+			xty[i] += row[i] * y[r]
+			for j := 0; j < k; j++ {
+				xtx[i][j] += row[i] * row[j]
+			}
+		}
+	}
+	inv, err := invert(xtx)
+	if err != nil {
+		return nil, nil, err
+	}
+	coef = make([]float64, k)
+	for i := range coef {
+		for j := range xty {
+			coef[i] += inv[i][j] * xty[j]
+		}
+	}
+	// The variance of the residuals gives the standard errors of the coefficients.
+	var ssr float64
+	for r, row := range x {
+		var fitted float64
+		for i, v := range row {
+			fitted += coef[i] * v
+		}
+		ssr += (y[r] - fitted) * (y[r] - fitted)
+	}
+	variance := ssr / float64(Max(len(y)-k, 1))
+	stdErr = make([]float64, k)
+	for i := range stdErr {
+		stdErr[i] = math.Sqrt(variance * inv[i][i])
+	}
+	return coef, stdErr, nil
+}
+
+// invert returns the inverse of the square matrix m using Gauss-Jordan elimination with partial pivoting.
+func invert(m [][]float64) ([][]float64, error) {
+	n := len(m)
+	a := make([][]float64, n)
+	for i := range a {
+		a[i] = make([]float64, 2*n)
+		copy(a[i], m[i])
+		a[i][n+i] = 1
+	}
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(a[pivot][col]) < 1e-12 {
+			return nil, ErrSingularMatrix
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		scale := a[col][col]
+		for j := range a[col] {
+			a[col][j] /= scale
+		}
+		for row := 0; row < n; row++ {
+			if row == col || a[row][col] == 0 {
+				continue
+			}
+			factor := a[row][col]
+			for j := range a[row] {
+				a[row][j] -= factor * a[col][j]
+			}
+		}
+	}
+	inv := make([][]float64, n)
+	for i := range inv {
+		inv[i] = a[i][n:]
+	}
+	return inv, nil
+}
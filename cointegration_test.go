@@ -0,0 +1,116 @@
+package autotrader
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// randomWalk returns a deterministic random walk of n steps starting at start.
+func randomWalk(r *rand.Rand, n int, start float64) []float64 {
+	values := make([]float64, n)
+	values[0] = start
+	for i := 1; i < n; i++ {
+		values[i] = values[i-1] + r.NormFloat64()
+	}
+	return values
+}
+
+func TestADF(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	walk := NewFloatSeries("Walk", randomWalk(r, 500, 100)...)
+	result, err := ADF(walk, 1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Stationary() {
+		t.Errorf("Expected random walk to not be stationary, got statistic %f", result.Statistic)
+	}
+
+	// An AR(1) process with a coefficient of 0.5 reverts to its mean.
+	reverting := make([]float64, 500)
+	for i := 1; i < len(reverting); i++ {
+		reverting[i] = 0.5*reverting[i-1] + r.NormFloat64()
+	}
+	result, err = ADF(NewFloatSeries("Reverting", reverting...), -1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !result.Stationary() {
+		t.Errorf("Expected mean reverting series to be stationary, got statistic %f", result.Statistic)
+	}
+	if result.Lags != 17 {
+		t.Errorf("Expected 17 lags, got %d", result.Lags)
+	}
+	if result.Critical5 != -2.86 {
+		t.Errorf("Expected 5%% critical value -2.86, got %f", result.Critical5)
+	}
+
+	for _, series := range []*FloatSeries{NewFloatSeries("Short", 1, 2, 3), NewFloatSeries("Empty")} {
+		if _, err := ADF(series, 0); err != ErrNotEnoughData {
+			t.Errorf("Expected ErrNotEnoughData for %d values, got %v", series.Len(), err)
+		}
+	}
+	if _, err := ADF(NewFloatSeries("Empty"), -1); err != ErrNotEnoughData {
+		t.Errorf("Expected ErrNotEnoughData for no values with automatic lags, got %v", err)
+	}
+}
+
+func TestCointegration(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	b := randomWalk(r, 500, 50)
+	a := make([]float64, len(b))
+	for i := range a {
+		a[i] = 2*b[i] + 10 + r.NormFloat64()
+	}
+	result, err := Cointegration(NewFloatSeries("A", a...), NewFloatSeries("B", b...), 1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !result.Cointegrated() {
+		t.Errorf("Expected series to be cointegrated, got statistic %f", result.ADF.Statistic)
+	}
+	if math.Abs(result.HedgeRatio-2) > 0.05 {
+		t.Errorf("Expected hedge ratio near 2, got %f", result.HedgeRatio)
+	}
+	if result.ADF.Critical5 != -3.34 {
+		t.Errorf("Expected 5%% critical value -3.34, got %f", result.ADF.Critical5)
+	}
+
+	other := randomWalk(r, 500, 50)
+	result, err = Cointegration(NewFloatSeries("Other", other...), NewFloatSeries("B", b...), 1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Cointegrated() {
+		t.Errorf("Expected independent random walks to not be cointegrated, got statistic %f", result.ADF.Statistic)
+	}
+
+	if _, err := Cointegration(NewFloatSeries("A", 1, 2), NewFloatSeries("B", 1), 0); err != ErrLengthMismatched {
+		t.Errorf("Expected ErrLengthMismatched, got %v", err)
+	}
+}
+
+func TestHedgeRatio(t *testing.T) {
+	beta, alpha, err := HedgeRatio(NewFloatSeries("A", 3, 5, 7, 9), NewFloatSeries("B", 1, 2, 3, 4))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if math.Abs(beta-2) > 1e-9 || math.Abs(alpha-1) > 1e-9 {
+		t.Errorf("Expected beta 2 and alpha 1, got %f and %f", beta, alpha)
+	}
+
+	// The relationship changes from a = 2b+1 to a = 3b+5 halfway through.
+	a := NewFloatSeries("A", 3, 5, 7, 9, 20, 23, 26)
+	b := NewFloatSeries("B", 1, 2, 3, 4, 5, 6, 7)
+	ratios := RollingHedgeRatio(a, b, 3)
+	if ratios.Len() != 7 {
+		t.Fatalf("Expected 7 ratios, got %d", ratios.Len())
+	}
+	expected := map[int]float64{0: 0, 1: 2, 2: 2, 3: 2, 6: 3}
+	for i, ratio := range expected {
+		if math.Abs(ratios.Value(i)-ratio) > 1e-9 {
+			t.Errorf("Expected ratio %f at %d, got %f", ratio, i, ratios.Value(i))
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package autotrader
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrNoConversionRate = errors.New("no conversion rate")
+
+// ConversionRateProvider returns the rate that converts an amount in the quote currency of a symbol into the account currency. For example, with a USD account, the rate for EUR_GBP is the price of GBP in USD.
+//
+// The TestBroker uses its ConversionRateProvider to convert position values and PL into the account currency when a position is opened, marked to market, and closed.
+type ConversionRateProvider interface {
+	ConversionRate(symbol string) (float64, error)
+}
+
+// StaticConversionRates is a ConversionRateProvider with a fixed rate for each symbol. Symbols that are not in the map return ErrNoConversionRate.
+type StaticConversionRates map[string]float64
+
+func (r StaticConversionRates) ConversionRate(symbol string) (float64, error) {
+	rate, ok := r[symbol]
+	if !ok {
+		return 0, fmt.Errorf("%w for %s", ErrNoConversionRate, symbol)
+	}
+	return rate, nil
+}
+
+// BrokerConversionRates is a ConversionRateProvider that uses the prices of a Broker. The rate is the bid price of the QUOTE_ACCOUNT symbol, or one divided by the ask price of the ACCOUNT_QUOTE symbol if the broker has no price for the former. The rate is 1 if the quote currency is the account currency.
+type BrokerConversionRates struct {
+	Broker          Broker
+	AccountCurrency string // AccountCurrency is the currency the account is denominated in, like "USD".
+}
+
+func (r *BrokerConversionRates) ConversionRate(symbol string) (float64, error) {
+	_, quote := SplitSymbol(symbol)
+	if quote == "" || quote == r.AccountCurrency {
+		return 1, nil
+	}
+	if bid := r.Broker.Bid(quote + "_" + r.AccountCurrency); bid > 0 {
+		return bid, nil
+	}
+	if ask := r.Broker.Ask(r.AccountCurrency + "_" + quote); ask > 0 {
+		return 1 / ask, nil
+	}
+	return 0, fmt.Errorf("%w from %s to %s", ErrNoConversionRate, quote, r.AccountCurrency)
+}
+
+// SplitSymbol returns the base and quote currencies of a currency pair symbol like "EUR_USD" or "EUR/USD". If the symbol is not a pair, the symbol is returned as the base and the quote is empty.
+func SplitSymbol(symbol string) (base, quote string) {
+	if i := strings.IndexAny(symbol, "_/"); i >= 0 {
+		return symbol[:i], symbol[i+1:]
+	}
+	return symbol, ""
+}
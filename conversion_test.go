@@ -0,0 +1,74 @@
+package autotrader
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// quoteBroker is a TestBroker with fixed bid and ask prices for each symbol.
+type quoteBroker struct {
+	*TestBroker
+	bids, asks map[string]float64
+}
+
+func (b *quoteBroker) Bid(symbol string) float64 { return b.bids[symbol] }
+func (b *quoteBroker) Ask(symbol string) float64 { return b.asks[symbol] }
+
+func TestBrokerConversionRates(t *testing.T) {
+	broker := &quoteBroker{
+		bids: map[string]float64{"GBP_USD": 1.25},
+		asks: map[string]float64{"USD_JPY": 150},
+	}
+	rates := &BrokerConversionRates{Broker: broker, AccountCurrency: "USD"}
+
+	if rate, err := rates.ConversionRate("EUR_USD"); err != nil || rate != 1 {
+		t.Errorf("Expected a rate of 1 for a USD quote, got %f, %v", rate, err)
+	}
+	if rate, err := rates.ConversionRate("EUR_GBP"); err != nil || rate != 1.25 {
+		t.Errorf("Expected a rate of 1.25 from GBP_USD, got %f, %v", rate, err)
+	}
+	if rate, err := rates.ConversionRate("EUR/JPY"); err != nil || !EqualApprox(rate, 1.0/150) {
+		t.Errorf("Expected a rate of 1/150 from USD_JPY, got %f, %v", rate, err)
+	}
+	if _, err := rates.ConversionRate("EUR_CHF"); !errors.Is(err, ErrNoConversionRate) {
+		t.Errorf("Expected ErrNoConversionRate, got %v", err)
+	}
+}
+
+func TestTestBrokerConversion(t *testing.T) {
+	rates := StaticConversionRates{"EUR_GBP": 1.25}
+	broker := NewTestBroker(nil, testData, 100_000, 1, 0, 0)
+	broker.Slippage = 0
+	broker.Conversion = rates
+
+	if _, err := broker.Order(context.Background(), Market, "EUR_CHF", 1000, 0, 0, 0); !errors.Is(err, ErrNoConversionRate) {
+		t.Errorf("Expected an order without a conversion rate to fail, got %v", err)
+	}
+
+	order, err := broker.Order(context.Background(), Market, "EUR_GBP", 1000, 0, 0, 0) // Bought at 1.15 GBP
+	if err != nil {
+		t.Fatal(err)
+	}
+	position := order.Position()
+	if !EqualApprox(position.EntryValue(), 1000*1.15*1.25) {
+		t.Errorf("Expected an entry value of %f USD, got %f", 1000*1.15*1.25, position.EntryValue())
+	}
+	if !EqualApprox(broker.Cash, 100_000-1000*1.15*1.25) {
+		t.Errorf("Expected cash to be %f, got %f", 100_000-1000*1.15*1.25, broker.Cash)
+	}
+
+	broker.Advance() // The price rises to 1.2 GBP and GBP strengthens.
+	rates["EUR_GBP"] = 1.5
+	if expected := 1000*1.2*1.5 - 1000*1.15*1.25; !EqualApprox(position.PL(), expected) {
+		t.Errorf("Expected a marked-to-market PL of %f USD, got %f", expected, position.PL())
+	}
+
+	delete(rates, "EUR_GBP") // The last known rate is used when the rate is unavailable.
+	if err := position.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !EqualApprox(broker.Cash, 100_000+1000*1.2*1.5-1000*1.15*1.25) {
+		t.Errorf("Expected cash to be %f, got %f", 100_000+1000*1.2*1.5-1000*1.15*1.25, broker.Cash)
+	}
+}
@@ -0,0 +1,173 @@
+package autotrader
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+var (
+	ErrUnknownAction   = errors.New("unknown corporate action")
+	ErrAlreadyAdjusted = errors.New("candles are already adjusted")
+)
+
+// CorporateActionType is the kind of a CorporateAction.
+type CorporateActionType string
+
+const (
+	Split    CorporateActionType = "split"    // Split multiplies the number of shares by the Value of the action, like 4 for a 4-for-1 split or 0.1 for a 1-for-10 reverse split.
+	Dividend CorporateActionType = "dividend" // Dividend pays the Value of the action per share to the holders of the stock.
+)
+
+// CorporateAction is a split or dividend of a stock that takes effect on its ex-date.
+type CorporateAction struct {
+	Symbol string // Symbol is the stock of the action. An empty symbol applies to every symbol.
+	Date   time.Time
+	Type   CorporateActionType
+	Value  float64 // Value is the split ratio of a split or the amount per share of a dividend.
+}
+
+// CorporateActionsFromCSV reads the corporate actions in the CSV file at path like CorporateActionsFromCSVReader.
+func CorporateActionsFromCSV(path, dateFormat string) ([]CorporateAction, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return CorporateActionsFromCSVReader(f, dateFormat)
+}
+
+// CorporateActionsFromCSVReader reads corporate actions from a CSV file with a header row of Date, Action, and Value columns and an optional Symbol column. The Action is "split" or "dividend", and splits may also be written as a ratio like "4:1" or "1/10". Dates are parsed with dateFormat, or as "2006-01-02" if it is empty. The actions are returned in date order.
+func CorporateActionsFromCSVReader(r io.Reader, dateFormat string) ([]CorporateAction, error) {
+	if dateFormat == "" {
+		dateFormat = time.DateOnly
+	}
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(cleanCSVHeader(name))] = i
+	}
+	for _, name := range []string{"date", "action", "value"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w: %q", ErrMissingColumn, name)
+		}
+	}
+	symbolCol, hasSymbol := columns["symbol"]
+
+	var actions []CorporateAction
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		var action CorporateAction
+		if hasSymbol {
+			action.Symbol = strings.TrimSpace(record[symbolCol])
+		}
+		if action.Date, err = time.Parse(dateFormat, strings.TrimSpace(record[columns["date"]])); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		action.Type = CorporateActionType(strings.ToLower(strings.TrimSpace(record[columns["action"]])))
+		if action.Type != Split && action.Type != Dividend {
+			return nil, fmt.Errorf("line %d: %w: %q", line, ErrUnknownAction, action.Type)
+		}
+		if action.Value, err = parseActionValue(strings.TrimSpace(record[columns["value"]])); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		actions = append(actions, action)
+	}
+	slices.SortStableFunc(actions, func(a, b CorporateAction) bool { return a.Date.Before(b.Date) })
+	return actions, nil
+}
+
+// parseActionValue parses a number or a ratio like "4:1" or "1/10".
+func parseActionValue(field string) (float64, error) {
+	if num, den, ok := strings.Cut(strings.Replace(field, "/", ":", 1), ":"); ok {
+		n, err := strconv.ParseFloat(num, 64)
+		if err != nil {
+			return 0, err
+		}
+		d, err := strconv.ParseFloat(den, 64)
+		if err != nil {
+			return 0, err
+		}
+		return n / d, nil
+	}
+	return strconv.ParseFloat(field, 64)
+}
+
+// AdjustCandles adjusts the prices and volumes of candles for the splits and, if dividends is true, the dividends of symbol, or of every action if symbol is empty, so the candles before each action are comparable to the candles after it. Prices before a split are divided by its ratio and volumes are multiplied by it. Prices before a dividend are multiplied by one minus the dividend divided by the close before the ex-date. The unadjusted closes are kept in an "Unadjusted Close" column.
+//
+// Backtests that credit dividends with TestBroker.CorporateActions should only adjust for splits, otherwise the dividends are counted twice.
+func AdjustCandles(candles *IndexedFrame[UnixTime], actions []CorporateAction, symbol string, dividends bool) error {
+	if candles.Series("Unadjusted Close") != nil {
+		return ErrAlreadyAdjusted
+	}
+	if err := candles.PushSeries(candles.Series("Close").Copy().SetName("Unadjusted Close")); err != nil {
+		return err
+	}
+	// Each candle is adjusted by the product of the factors of the actions after it.
+	priceFactors := make([]float64, candles.Len())
+	volumeFactors := make([]float64, candles.Len())
+	for i := range priceFactors {
+		priceFactors[i], volumeFactors[i] = 1, 1
+	}
+	for _, action := range actions {
+		if symbol != "" && action.Symbol != "" && action.Symbol != symbol {
+			continue
+		}
+		// The action applies to every candle before its ex-date.
+		exDate := UnixTime(action.Date.Unix())
+		end, _ := candles.Series("Close").rowsBetween(exDate, exDate)
+		var price, volume float64
+		switch action.Type {
+		case Split:
+			if action.Value <= 0 {
+				return fmt.Errorf("invalid split ratio %v on %s", action.Value, action.Date.Format(time.DateOnly))
+			}
+			price, volume = 1/action.Value, action.Value
+		case Dividend:
+			if !dividends || end == 0 {
+				continue
+			}
+			price, volume = 1-action.Value/candles.Close(end-1), 1
+		default:
+			return fmt.Errorf("%w: %q", ErrUnknownAction, action.Type)
+		}
+		for i := 0; i < end; i++ {
+			priceFactors[i] *= price
+			volumeFactors[i] *= volume
+		}
+	}
+	for _, name := range []string{"Open", "High", "Low", "Close"} {
+		series := candles.Series(name)
+		for i, factor := range priceFactors {
+			series.SetValue(i, series.Float(i)*factor)
+		}
+	}
+	if series := candles.Series("Volume"); series != nil {
+		for i, factor := range volumeFactors {
+			switch v := series.Value(i).(type) {
+			case int64:
+				series.SetValue(i, int64(math.Round(float64(v)*factor)))
+			case float64:
+				series.SetValue(i, v*factor)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package autotrader
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCorporateActionsFromCSV(t *testing.T) {
+	data := `Symbol,Date,Action,Value
+AAPL,2022-01-05,Dividend,0.12
+AAPL,2022-01-03,Split,2:1
+MSFT,2022-01-04,split,1/10
+`
+	actions, err := CorporateActionsFromCSVReader(strings.NewReader(data), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []CorporateAction{
+		{"AAPL", time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC), Split, 2},
+		{"MSFT", time.Date(2022, 1, 4, 0, 0, 0, 0, time.UTC), Split, 0.1},
+		{"AAPL", time.Date(2022, 1, 5, 0, 0, 0, 0, time.UTC), Dividend, 0.12},
+	}
+	if len(actions) != len(expected) {
+		t.Fatalf("Expected %d actions, got %d", len(expected), len(actions))
+	}
+	for i := range expected {
+		if actions[i] != expected[i] {
+			t.Errorf("Expected action %d to be %v, got %v", i, expected[i], actions[i])
+		}
+	}
+
+	_, err = CorporateActionsFromCSVReader(strings.NewReader("Date,Action,Value\n2022-01-03,merger,1\n"), "")
+	if !errors.Is(err, ErrUnknownAction) {
+		t.Errorf("Expected ErrUnknownAction, got %v", err)
+	}
+}
+
+func TestAdjustCandles(t *testing.T) {
+	candles := testData.Copy()
+	actions := []CorporateAction{
+		{"AAPL", time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC), Split, 2},
+		{"AAPL", time.Date(2022, 1, 5, 0, 0, 0, 0, time.UTC), Dividend, 0.12}, // The close before is 1.1.
+		{"MSFT", time.Date(2022, 1, 5, 0, 0, 0, 0, time.UTC), Split, 10},
+	}
+	if err := AdjustCandles(candles, actions, "AAPL", true); err != nil {
+		t.Fatal(err)
+	}
+	dividend := 1 - 0.12/1.1
+	for i, expected := range []float64{1.15 / 2 * dividend, 1.2 / 2 * dividend, 1.25 * dividend, 1.1 * dividend, 1.15} {
+		if !EqualApprox(candles.Close(i), expected) {
+			t.Errorf("Expected adjusted close %d to be %f, got %f", i, expected, candles.Close(i))
+		}
+	}
+	if !EqualApprox(candles.Float("Unadjusted Close", 0), 1.15) {
+		t.Errorf("Expected the unadjusted close to be kept, got %f", candles.Float("Unadjusted Close", 0))
+	}
+	if !EqualApprox(candles.Float("Volume", 0), 200) || !EqualApprox(candles.Float("Volume", 2), 120) {
+		t.Errorf("Expected volumes before the split to be doubled, got %v and %v", candles.Float("Volume", 0), candles.Float("Volume", 2))
+	}
+	if err := AdjustCandles(candles, actions, "AAPL", true); !errors.Is(err, ErrAlreadyAdjusted) {
+		t.Errorf("Expected ErrAlreadyAdjusted, got %v", err)
+	}
+	if !EqualApprox(testData.Close(0), 1.15) {
+		t.Error("Expected the copied data to be unchanged")
+	}
+}
+
+func TestBacktestingBrokerDividends(t *testing.T) {
+	broker := NewTestBroker(nil, testData, 100_000, 1, 0, 0)
+	broker.Slippage = 0
+	broker.CorporateActions = []CorporateAction{
+		{"AAPL", time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC), Dividend, 0.01},
+		{"AAPL", time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC), Split, 2}, // Ignored.
+		{"MSFT", time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC), Dividend, 1},
+	}
+	long, err := broker.Order(context.Background(), Market, "AAPL", 1000, 0, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	short, err := broker.Order(context.Background(), Market, "AAPL", -500, 0, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker.Advance()
+	broker.Advance()
+	if !EqualApprox(broker.DividendsReceived(), 10-5) {
+		t.Errorf("Expected 10 of dividends received and 5 paid, got %f", broker.DividendsReceived())
+	}
+	long.Position().Close()
+	short.Position().Close()
+	if financing := long.Position().CloseCosts().Financing; !EqualApprox(financing, -10) {
+		t.Errorf("Expected the long to report -10 of financing, got %f", financing)
+	}
+	if financing := short.Position().CloseCosts().Financing; !EqualApprox(financing, 5) {
+		t.Errorf("Expected the short to report 5 of financing, got %f", financing)
+	}
+	if expected := 100_000 + 0.1*500 + 5; !EqualApprox(broker.NAV(), expected) { // 500 net long units made 0.1 each.
+		t.Errorf("Expected NAV to be %f, got %f", expected, broker.NAV())
+	}
+}
@@ -0,0 +1,430 @@
+package autotrader
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrMissingColumn = errors.New("missing column in CSV header")
+
+// CSVParser converts the raw text of a CSV field into a value. Parsers for the Date column must return a time.Time, parsers for the Open, High, Low, and Close columns must return a float64, and parsers for the Volume column must return an int64 or float64. Parsers for extra columns may return anything.
+type CSVParser func(field string) (any, error)
+
+// DataCSVLayout describes how the columns of a CSV file map to the Date, Open, High, Low, Close, and Volume columns of a Frame. Column names are matched against the header row of the CSV file, ignoring surrounding quotes and whitespace.
+type DataCSVLayout struct {
+	LatestFirst bool   // LatestFirst is true if the latest data is on the first row of the file.
+	DateFormat  string // DateFormat is the layout of the Date column as understood by time.Parse. Example: "01/02/2006"
+	Date        string
+	Open        string
+	High        string
+	Low         string
+	Close       string
+	Volume      string // Volume is optional. If empty or the column is missing, the volume of every candle is zero.
+
+	Delimiter          rune     // Delimiter separates the fields of a row. The default is ','.
+	ThousandsSeparator string   // ThousandsSeparator is removed from numbers before they are parsed. Example: "," for "1,024.5"
+	Extra              []string // Extra is a list of additional columns to keep, such as sentiment or open interest. Their series are named after the column.
+	// Parsers maps a column name to the function used to parse its fields, overriding the default parsing of that column. By default, numbers may have a K, M, or B suffix ("1.2K" is 1200) and extra columns that are not numbers are kept as strings.
+	Parsers map[string]CSVParser
+
+	// Actions are corporate actions, like those read with CorporateActionsFromCSV, that IndexedFrameFromCSV adjusts the candles for with AdjustCandles. Only the actions of Symbol and actions without a symbol are applied, or every action if Symbol is empty. Only splits are adjusted for unless AdjustDividends is set.
+	Actions         []CorporateAction
+	Symbol          string // Symbol is the symbol of the candles in the file, which selects its Actions.
+	AdjustDividends bool
+}
+
+// EURUSD returns the daily EUR/USD candles from the "EUR_USD Historical Data.csv" file in the working directory, as exported by investing.com.
+func EURUSD() (*IndexedFrame[UnixTime], error) {
+	return IndexedFrameFromCSV("./EUR_USD Historical Data.csv", DataCSVLayout{
+		LatestFirst:        true,
+		DateFormat:         "01/02/2006",
+		Date:               "Date",
+		Open:               "Open",
+		High:               "High",
+		Low:                "Low",
+		Close:              "Price",
+		Volume:             "Vol.",
+		ThousandsSeparator: ",",
+	})
+}
+
+// FrameFromCSV reads the CSV file at path and returns a Frame with Date, Open, High, Low, Close, and Volume columns, plus any extra columns of the layout, ordered from oldest to latest.
+func FrameFromCSV(path string, layout DataCSVLayout) (*Frame, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return FrameFromCSVReader(f, layout)
+}
+
+// FrameFromCSVReader is like FrameFromCSV but reads the CSV data from r.
+func FrameFromCSVReader(r io.Reader, layout DataCSVLayout) (*Frame, error) {
+	var rows []csvRow
+	err := readCSVRows(r, layout, func(row csvRow) error {
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	frame := NewDOHLCVFrame()
+	for _, name := range layout.Extra {
+		frame.PushSeries(NewSeries(name))
+	}
+	for i := range rows {
+		row := rows[i]
+		if layout.LatestFirst {
+			row = rows[len(rows)-1-i]
+		}
+		frame.PushCandle(row.date, row.open, row.high, row.low, row.close, row.volume)
+		for j, name := range layout.Extra {
+			frame.Series(name).Push(row.extra[j])
+		}
+	}
+	return frame, nil
+}
+
+// IndexedFrameFromCSV reads the CSV file at path and returns an IndexedFrame of candles indexed by their UnixTime, plus any extra columns of the layout.
+func IndexedFrameFromCSV(path string, layout DataCSVLayout) (*IndexedFrame[UnixTime], error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return IndexedFrameFromCSVReader(f, layout)
+}
+
+// IndexedFrameFromCSVReader is like IndexedFrameFromCSV but reads the CSV data from r. The rows are sorted by their index, so LatestFirst has no effect on the result.
+func IndexedFrameFromCSVReader(r io.Reader, layout DataCSVLayout) (*IndexedFrame[UnixTime], error) {
+	frame := NewDOHLCVIndexedFrame[UnixTime]()
+	for _, name := range layout.Extra {
+		frame.PushSeries(NewIndexedSeries[UnixTime, any](name, nil))
+	}
+	err := readCSVRows(r, layout, func(row csvRow) error {
+		index := UnixTime(row.date.Unix())
+		frame.PushCandle(index, row.open, row.high, row.low, row.close, row.volume)
+		for j, name := range layout.Extra {
+			frame.Series(name).Insert(index, row.extra[j])
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(layout.Actions) > 0 {
+		if err := AdjustCandles(frame, layout.Actions, layout.Symbol, layout.AdjustDividends); err != nil {
+			return nil, err
+		}
+	}
+	return frame, nil
+}
+
+// CandlesCSVLayout is the layout of the CSV files written by WriteCandlesCSV.
+var CandlesCSVLayout = DataCSVLayout{
+	DateFormat: time.RFC3339,
+	Date:       "Date",
+	Open:       "Open",
+	High:       "High",
+	Low:        "Low",
+	Close:      "Close",
+	Volume:     "Volume",
+}
+
+// WriteCandlesCSV writes the candles of frame to w as CSV data that can be read back with CandlesCSVLayout.
+func WriteCandlesCSV(w io.Writer, frame *IndexedFrame[UnixTime]) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"Date", "Open", "High", "Low", "Close", "Volume"}); err != nil {
+		return err
+	}
+	for i := 0; i < frame.Len(); i++ {
+		record := []string{
+			frame.Date(i).Time().UTC().Format(time.RFC3339),
+			strconv.FormatFloat(frame.Open(i), 'f', -1, 64),
+			strconv.FormatFloat(frame.High(i), 'f', -1, 64),
+			strconv.FormatFloat(frame.Low(i), 'f', -1, 64),
+			strconv.FormatFloat(frame.Close(i), 'f', -1, 64),
+			fmt.Sprint(frame.Value("Volume", i)),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Candle is a single candlestick.
+type Candle struct {
+	Date   time.Time
+	Open   float64
+	High   float64
+	Low    float64
+	Close  float64
+	Volume int64
+}
+
+// CandleChunkReader reads candles in chronological order a chunk at a time. ReadChunk returns up to n candles and io.EOF once there are no candles left to read.
+type CandleChunkReader interface {
+	ReadChunk(n int) (*IndexedFrame[UnixTime], error)
+}
+
+var _ CandleChunkReader = (*CSVCandleReader)(nil) // Compile-time interface check.
+
+// CSVCandleReader reads candles from CSV data one row at a time, so that files too large to fit in memory can be processed as a stream. The rows must be ordered from oldest to latest, so the LatestFirst option of the layout is ignored.
+type CSVCandleReader struct {
+	layout    DataCSVLayout
+	reader    *csv.Reader
+	header    []string
+	dateCol   int
+	priceCols [4]int // Open, High, Low, Close
+	volumeCol int    // -1 if there is no volume column.
+	extraCols []int
+	line      int
+}
+
+// NewCSVCandleReader reads the header of the CSV data in r and returns a CSVCandleReader positioned at the first row of candles.
+func NewCSVCandleReader(r io.Reader, layout DataCSVLayout) (*CSVCandleReader, error) {
+	br := bufio.NewReader(r)
+	if bom, _ := br.Peek(3); string(bom) == "\ufeff" {
+		br.Discard(3) // Skip the byte order mark, which encoding/csv would otherwise treat as part of the first field.
+	}
+	reader := csv.NewReader(br)
+	reader.ReuseRecord = true
+	if layout.Delimiter != 0 {
+		reader.Comma = layout.Delimiter
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading CSV header: %w", err)
+	}
+	header = append([]string(nil), header...) // The reader reuses the record's backing array.
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[cleanCSVHeader(name)] = i
+	}
+	column := func(name string, required bool) (int, error) {
+		if i, ok := columns[cleanCSVHeader(name)]; ok {
+			return i, nil
+		} else if required {
+			return -1, fmt.Errorf("%w: %q", ErrMissingColumn, name)
+		}
+		return -1, nil
+	}
+
+	c := &CSVCandleReader{
+		layout:    layout,
+		reader:    reader,
+		header:    header,
+		extraCols: make([]int, len(layout.Extra)),
+	}
+	for _, col := range []struct {
+		dst      *int
+		name     string
+		required bool
+	}{
+		{&c.dateCol, layout.Date, true},
+		{&c.priceCols[0], layout.Open, true},
+		{&c.priceCols[1], layout.High, true},
+		{&c.priceCols[2], layout.Low, true},
+		{&c.priceCols[3], layout.Close, true},
+		{&c.volumeCol, layout.Volume, false},
+	} {
+		if *col.dst, err = column(col.name, col.required); err != nil {
+			return nil, err
+		}
+	}
+	for i, name := range layout.Extra {
+		if c.extraCols[i], err = column(name, true); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Next returns the next candle or io.EOF if there are no rows left. The values of extra columns are only available through ReadChunk.
+func (c *CSVCandleReader) Next() (Candle, error) {
+	row, err := c.next()
+	if err != nil {
+		return Candle{}, err
+	}
+	return Candle{row.date, row.open, row.high, row.low, row.close, row.volume}, nil
+}
+
+// ReadChunk returns an IndexedFrame of up to n candles, including the extra columns of the layout. When the end of the data is reached, the remaining candles are returned with a nil error and the following call returns a nil frame and io.EOF.
+func (c *CSVCandleReader) ReadChunk(n int) (*IndexedFrame[UnixTime], error) {
+	frame := NewDOHLCVIndexedFrame[UnixTime]()
+	for _, name := range c.layout.Extra {
+		frame.PushSeries(NewIndexedSeries[UnixTime, any](name, nil))
+	}
+	for i := 0; i < n; i++ {
+		row, err := c.next()
+		if err == io.EOF {
+			if i == 0 {
+				return nil, io.EOF
+			}
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		index := UnixTime(row.date.Unix())
+		frame.PushCandle(index, row.open, row.high, row.low, row.close, row.volume)
+		for j, name := range c.layout.Extra {
+			frame.Series(name).Insert(index, row.extra[j])
+		}
+	}
+	return frame, nil
+}
+
+// csvRow is a single parsed row of a CSV file of candles.
+type csvRow struct {
+	date                   time.Time
+	open, high, low, close float64
+	volume                 int64
+	extra                  []any // Values of the layout's extra columns, in the same order.
+}
+
+// readCSVRows parses every row of the CSV data in r according to layout and calls push with each row in the order they appear in the file. Reading stops at the first error returned by push.
+func readCSVRows(r io.Reader, layout DataCSVLayout, push func(row csvRow) error) error {
+	reader, err := NewCSVCandleReader(r, layout)
+	if err != nil {
+		return err
+	}
+	for {
+		row, err := reader.next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := push(row); err != nil {
+			return err
+		}
+	}
+}
+
+// parse runs the parser of the column col on its field in record, or defaultParser if the layout has no parser for the column.
+func (c *CSVCandleReader) parse(record []string, col int, defaultParser CSVParser) (any, error) {
+	parser := defaultParser
+	if p, ok := c.layout.Parsers[cleanCSVHeader(c.header[col])]; ok {
+		parser = p
+	}
+	return parser(strings.TrimSpace(record[col]))
+}
+
+func (c *CSVCandleReader) parseDate(field string) (any, error) {
+	return time.Parse(c.layout.DateFormat, field)
+}
+
+func (c *CSVCandleReader) parseNumber(field string) (any, error) {
+	return parseCSVNumber(field, c.layout.ThousandsSeparator)
+}
+
+func (c *CSVCandleReader) parseExtra(field string) (any, error) {
+	if f, err := parseCSVNumber(field, c.layout.ThousandsSeparator); err == nil {
+		return f, nil
+	}
+	return field, nil
+}
+
+// next reads and parses the next row of the CSV data.
+func (c *CSVCandleReader) next() (csvRow, error) {
+	var row csvRow
+	record, err := c.reader.Read()
+	if err == io.EOF {
+		return row, io.EOF
+	}
+	c.line++
+	if err != nil {
+		return row, fmt.Errorf("error reading CSV row %d: %w", c.line, err)
+	}
+
+	val, err := c.parse(record, c.dateCol, c.parseDate)
+	if err != nil {
+		return row, fmt.Errorf("error parsing date on CSV row %d: %w", c.line, err)
+	} else if row.date, err = csvValue[time.Time](val, c.header[c.dateCol]); err != nil {
+		return row, fmt.Errorf("CSV row %d: %w", c.line, err)
+	}
+	for i, dst := range []*float64{&row.open, &row.high, &row.low, &row.close} {
+		col := c.priceCols[i]
+		val, err := c.parse(record, col, c.parseNumber)
+		if err != nil {
+			return row, fmt.Errorf("error parsing %q on CSV row %d: %w", c.header[col], c.line, err)
+		} else if *dst, err = csvValue[float64](val, c.header[col]); err != nil {
+			return row, fmt.Errorf("CSV row %d: %w", c.line, err)
+		}
+	}
+	if c.volumeCol >= 0 {
+		if field := strings.TrimSpace(record[c.volumeCol]); field != "" && field != "-" {
+			val, err := c.parse(record, c.volumeCol, c.parseNumber)
+			if err != nil {
+				return row, fmt.Errorf("error parsing %q on CSV row %d: %w", c.header[c.volumeCol], c.line, err)
+			}
+			switch val := val.(type) {
+			case int64:
+				row.volume = val
+			case float64:
+				row.volume = int64(val)
+			default:
+				return row, fmt.Errorf("CSV row %d: expected %q to be an int64 or float64, got %T", c.line, c.header[c.volumeCol], val)
+			}
+		}
+	}
+	if len(c.extraCols) > 0 {
+		row.extra = make([]any, len(c.extraCols))
+		for i, col := range c.extraCols {
+			if row.extra[i], err = c.parse(record, col, c.parseExtra); err != nil {
+				return row, fmt.Errorf("error parsing %q on CSV row %d: %w", c.header[col], c.line, err)
+			}
+		}
+	}
+	return row, nil
+}
+
+// csvValue asserts that the parsed value of column is of type T.
+func csvValue[T any](val any, column string) (T, error) {
+	t, ok := val.(T)
+	if !ok {
+		return t, fmt.Errorf("expected %q to be a %T, got %T", column, t, val)
+	}
+	return t, nil
+}
+
+// parseCSVNumber parses a number after removing the thousands separator. The number may end with a K, M, or B suffix to multiply it by a thousand, million, or billion respectively.
+func parseCSVNumber(field, thousandsSeparator string) (float64, error) {
+	if thousandsSeparator != "" {
+		field = strings.ReplaceAll(field, thousandsSeparator, "")
+	}
+	multiplier := 1.0
+	if n := len(field); n > 0 {
+		switch field[n-1] {
+		case 'K', 'k':
+			multiplier = 1e3
+		case 'M', 'm':
+			multiplier = 1e6
+		case 'B', 'b':
+			multiplier = 1e9
+		}
+		if multiplier != 1 {
+			field = field[:n-1]
+		}
+	}
+	f, err := strconv.ParseFloat(field, 64)
+	if err != nil {
+		return 0, err
+	}
+	return f * multiplier, nil
+}
+
+// cleanCSVHeader strips quotes and surrounding whitespace from a CSV column name.
+func cleanCSVHeader(name string) string {
+	return strings.Trim(name, "\" \t")
+}
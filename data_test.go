@@ -0,0 +1,202 @@
+package autotrader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testEURUSDCSV = "\ufeff\"Date\",\"Price\",\"Open\",\"High\",\"Low\",\"Vol.\",\"Change %\"\n" +
+	"\"01/04/2022\",\"1.1290\",\"1.1302\",\"1.1322\",\"1.1273\",\"\",\"-0.11%\"\n" +
+	"\"01/03/2022\",\"1.1302\",\"1.1370\",\"1.1376\",\"1.1280\",\"\",\"-0.61%\"\n" +
+	"\"12/31/2021\",\"1.1371\",\"1.1326\",\"1.1379\",\"1.1300\",\"\",\"0.40%\"\n"
+
+var testEURUSDLayout = DataCSVLayout{
+	LatestFirst: true,
+	DateFormat:  "01/02/2006",
+	Date:        "Date",
+	Open:        "Open",
+	High:        "High",
+	Low:         "Low",
+	Close:       "Price",
+	Volume:      "Vol.",
+}
+
+func TestFrameFromCSVReader(t *testing.T) {
+	data, err := FrameFromCSVReader(strings.NewReader(testEURUSDCSV), testEURUSDLayout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Len() != 3 {
+		t.Fatalf("Expected 3 rows, got %d", data.Len())
+	}
+	if !data.Date(0).Equal(time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected first date to be 2021-12-31, got %v", data.Date(0))
+	}
+	if data.Close(-1) != 1.1290 {
+		t.Errorf("Expected latest close to be 1.1290, got %f", data.Close(-1))
+	}
+}
+
+func TestIndexedFrameFromCSVReader(t *testing.T) {
+	data, err := IndexedFrameFromCSVReader(strings.NewReader(testEURUSDCSV), testEURUSDLayout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Len() != 3 {
+		t.Fatalf("Expected 3 rows, got %d", data.Len())
+	}
+	if !data.Date(0).Time().Equal(time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected first date to be 2021-12-31, got %v", data.Date(0))
+	}
+	if data.Open(-1) != 1.1302 || data.High(-1) != 1.1322 || data.Low(-1) != 1.1273 || data.Close(-1) != 1.1290 {
+		t.Errorf("Unexpected latest candle: %v", data)
+	}
+
+	layout := testEURUSDLayout
+	layout.Close = "Close"
+	if _, err := IndexedFrameFromCSVReader(strings.NewReader(testEURUSDCSV), layout); !errors.Is(err, ErrMissingColumn) {
+		t.Errorf("Expected ErrMissingColumn, got %v", err)
+	}
+}
+
+func TestIndexedFrameFromCSVActions(t *testing.T) {
+	layout := testEURUSDLayout
+	layout.Symbol = "EUR_USD"
+	layout.Actions = []CorporateAction{
+		{"EUR_USD", time.Date(2022, 1, 4, 0, 0, 0, 0, time.UTC), Split, 2},
+		{"GBP_USD", time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC), Split, 10},
+	}
+	data, err := IndexedFrameFromCSVReader(strings.NewReader(testEURUSDCSV), layout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []float64{1.1371 / 2, 1.1302 / 2, 1.1290} {
+		if !EqualApprox(data.Close(i), expected) {
+			t.Errorf("Expected close %d to be adjusted for the split of EUR_USD only to %f, got %f", i, expected, data.Close(i))
+		}
+	}
+}
+
+func TestCSVLayoutOptions(t *testing.T) {
+	const csvData = "Time;Open;High;Low;Close;Vol.;Sentiment;Regime\n" +
+		"2022-01-01;1,100.5;1,200;1,000;1,150.25;1.2K;0.5;bull\n" +
+		"2022-01-02;1,150.25;1,210;1,140;1,205;3M;-0.25;bear\n"
+	layout := DataCSVLayout{
+		DateFormat:         time.DateOnly,
+		Date:               "Time",
+		Open:               "Open",
+		High:               "High",
+		Low:                "Low",
+		Close:              "Close",
+		Volume:             "Vol.",
+		Delimiter:          ';',
+		ThousandsSeparator: ",",
+		Extra:              []string{"Sentiment", "Regime"},
+		Parsers: map[string]CSVParser{
+			"Regime": func(field string) (any, error) {
+				return field == "bull", nil
+			},
+		},
+	}
+	data, err := IndexedFrameFromCSVReader(strings.NewReader(csvData), layout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Len() != 2 {
+		t.Fatalf("Expected 2 rows, got %d", data.Len())
+	}
+	if data.Open(0) != 1100.5 || data.Close(0) != 1150.25 {
+		t.Errorf("Expected thousands separators to be removed, got open %f and close %f", data.Open(0), data.Close(0))
+	}
+	if vol := data.Series("Volume").Value(0); vol != int64(1200) {
+		t.Errorf("Expected volume of 1.2K to be 1200, got %v", vol)
+	}
+	if vol := data.Series("Volume").Value(1); vol != int64(3_000_000) {
+		t.Errorf("Expected volume of 3M to be 3000000, got %v", vol)
+	}
+	if data.Float("Sentiment", 1) != -0.25 {
+		t.Errorf("Expected sentiment to be -0.25, got %v", data.Value("Sentiment", 1))
+	}
+	if data.Value("Regime", 0) != true || data.Value("Regime", 1) != false {
+		t.Errorf("Expected the custom parser to be used for Regime, got %v and %v", data.Value("Regime", 0), data.Value("Regime", 1))
+	}
+
+	frame, err := FrameFromCSVReader(strings.NewReader(csvData), layout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frame.Str("Regime", 0) != "" || frame.Value("Regime", 0) != true {
+		t.Errorf("Expected Regime to be kept in the Frame, got %v", frame.Value("Regime", 0))
+	}
+
+	layout.Parsers = map[string]CSVParser{"Close": func(field string) (any, error) { return field, nil }}
+	if _, err := FrameFromCSVReader(strings.NewReader(csvData), layout); err == nil {
+		t.Error("Expected an error when a parser returns the wrong type for Close")
+	}
+}
+
+func TestCSVCandleReaderStream(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("Date,Open,High,Low,Close,Volume\n")
+	for i := 0; i < 10; i++ {
+		price := 1 + float64(i)/10
+		fmt.Fprintf(&sb, "2022-01-%02d,%f,%f,%f,%f,%d\n", i+1, price, price+0.05, price-0.05, price, 100+i)
+	}
+	layout := DataCSVLayout{DateFormat: time.DateOnly, Date: "Date", Open: "Open", High: "High", Low: "Low", Close: "Close", Volume: "Volume"}
+
+	reader, err := NewCSVCandleReader(strings.NewReader(sb.String()), layout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	candle, err := reader.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !candle.Date.Equal(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)) || candle.Volume != 100 {
+		t.Errorf("Unexpected first candle: %+v", candle)
+	}
+	chunk, err := reader.ReadChunk(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chunk.Len() != 4 || chunk.Close(0) != 1.1 {
+		t.Errorf("Expected a chunk of 4 candles starting at 1.1, got %v", chunk)
+	}
+
+	// Stream the whole file through a TestBroker, two candles at a time.
+	reader, err = NewCSVCandleReader(strings.NewReader(sb.String()), layout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker := NewTestBroker(nil, nil, 0, 0, 0, 0)
+	broker.Stream = reader
+	broker.StreamChunk = 2
+	broker.StreamKeep = 3
+	var closes []float64
+	for {
+		candles, err := broker.Candles(context.Background(), "", "D", 3)
+		if err != nil && err != ErrEOF {
+			t.Fatal(err)
+		}
+		if broker.Data.Len() > broker.StreamKeep+broker.StreamChunk {
+			t.Fatalf("Expected at most %d candles in memory, got %d", broker.StreamKeep+broker.StreamChunk, broker.Data.Len())
+		}
+		closes = append(closes, candles.Close(-1))
+		if err == ErrEOF { // The last candle is returned along with ErrEOF.
+			break
+		}
+		broker.Advance()
+	}
+	if len(closes) != 10 {
+		t.Fatalf("Expected to see 10 candles, got %d: %v", len(closes), closes)
+	}
+	for i, c := range closes {
+		if !EqualApprox(c, 1+float64(i)/10) {
+			t.Errorf("(%d)\tExpected close %f, got %f", i, 1+float64(i)/10, c)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package autotrader
+
+import (
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+// DrawdownPeriod is a period in which the equity was below its previous peak, from the peak until the equity recovered to it or the backtest ended.
+type DrawdownPeriod struct {
+	Peak       time.Time     `json:"peak"`     // Peak is the time of the candle the equity was highest at before falling.
+	Trough     time.Time     `json:"trough"`   // Trough is the time of the candle the equity was lowest at during the period.
+	Recovery   time.Time     `json:"recovery"` // Recovery is the time of the candle the equity regained its peak, or zero if it never did.
+	PeakEquity float64       `json:"peak_equity"`
+	Depth      float64       `json:"depth"`     // Depth is the fall of the equity from the peak to the trough.
+	DepthPct   float64       `json:"depth_pct"` // DepthPct is the depth as a percentage of the peak equity.
+	Duration   time.Duration `json:"duration"`  // Duration is the time from the peak to the recovery, or to the last candle if the equity never recovered. It is encoded in JSON as nanoseconds.
+}
+
+// Recovered returns true if the equity regained its peak before the backtest ended.
+func (d DrawdownPeriod) Recovered() bool {
+	return !d.Recovery.IsZero()
+}
+
+// Drawdowns returns every period in which the equity of stats was below its running peak, in the order they started. Unlike the Drawdown of TraderStats, which is measured from the starting equity, these are measured from the highest equity before each one. The last period has not recovered if the equity ended below its peak.
+func Drawdowns(stats *TraderStats) []DrawdownPeriod {
+	if stats.Dated == nil || stats.Dated.Len() == 0 {
+		return nil
+	}
+	var drawdowns []DrawdownPeriod
+	var current *DrawdownPeriod
+	peak, peakDate := stats.Dated.Float("Equity", 0), stats.Dated.Date(0)
+	for i := 1; i < stats.Dated.Len(); i++ {
+		equity, date := stats.Dated.Float("Equity", i), stats.Dated.Date(i)
+		switch {
+		case equity >= peak:
+			if current != nil {
+				current.Recovery = date
+				current.Duration = date.Sub(current.Peak)
+				drawdowns = append(drawdowns, *current)
+				current = nil
+			}
+			peak, peakDate = equity, date
+		case current == nil:
+			current = &DrawdownPeriod{Peak: peakDate, PeakEquity: peak}
+			fallthrough
+		case peak-equity > current.Depth:
+			current.Trough = date
+			current.Depth = peak - equity
+			current.DepthPct = 100 * current.Depth / peak
+		}
+	}
+	if current != nil {
+		current.Duration = stats.Dated.Date(-1).Sub(current.Peak)
+		drawdowns = append(drawdowns, *current)
+	}
+	return drawdowns
+}
+
+// TopDrawdowns returns the n deepest of drawdowns, from the deepest.
+func TopDrawdowns(drawdowns []DrawdownPeriod, n int) []DrawdownPeriod {
+	top := slices.Clone(drawdowns)
+	slices.SortStableFunc(top, func(a, b DrawdownPeriod) bool {
+		return a.Depth > b.Depth
+	})
+	return top[:Min(n, len(top))]
+}
+
+// DrawdownsSection prints a table of the 5 deepest drawdowns of the equity from its peaks, with when each started, bottomed, and recovered, and how long each lasted.
+var DrawdownsSection ReportSection = ReportSectionFunc(renderDrawdowns)
+
+func renderDrawdowns(ctx *ReportContext) error {
+	w := tabwriter.NewWriter(ctx.Out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Top Drawdowns:")
+	fmt.Fprintln(w, "Peak\tTrough\tRecovery\tDepth\tDuration\t")
+	for _, d := range TopDrawdowns(Drawdowns(ctx.Stats), 5) {
+		recovery := "-"
+		if d.Recovered() {
+			recovery = d.Recovery.Format(ctx.DateLayout)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s (%s)\t%s\t\n", d.Peak.Format(ctx.DateLayout), d.Trough.Format(ctx.DateLayout), recovery, ctx.Money(d.Depth), ctx.Percent(d.DepthPct), d.Duration)
+	}
+	fmt.Fprintln(w)
+	return w.Flush()
+}
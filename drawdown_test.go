@@ -0,0 +1,66 @@
+package autotrader
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDrawdowns(t *testing.T) {
+	day := func(i int) time.Time { return time.Date(2022, 1, 1+i, 0, 0, 0, 0, time.UTC) }
+	dates := NewSeries("Date")
+	equity := NewSeries("Equity")
+	for i, value := range []float64{100, 110, 100, 95, 112, 108, 111, 113, 100} {
+		dates.Push(day(i))
+		equity.Push(value)
+	}
+	stats := &TraderStats{Dated: NewFrame(dates, equity)}
+
+	drawdowns := Drawdowns(stats)
+	expected := []DrawdownPeriod{
+		{Peak: day(1), Trough: day(3), Recovery: day(4), PeakEquity: 110, Depth: 15, Duration: 3 * 24 * time.Hour},
+		{Peak: day(4), Trough: day(5), Recovery: day(7), PeakEquity: 112, Depth: 4, Duration: 3 * 24 * time.Hour},
+		{Peak: day(7), Trough: day(8), PeakEquity: 113, Depth: 13, Duration: 24 * time.Hour},
+	}
+	if len(drawdowns) != len(expected) {
+		t.Fatalf("Expected %d drawdowns, got %+v", len(expected), drawdowns)
+	}
+	for i, d := range drawdowns {
+		e := expected[i]
+		if !d.Peak.Equal(e.Peak) || !d.Trough.Equal(e.Trough) || !d.Recovery.Equal(e.Recovery) || d.PeakEquity != e.PeakEquity || d.Depth != e.Depth || d.Duration != e.Duration {
+			t.Errorf("Expected drawdown %d to be %+v, got %+v", i, e, d)
+		}
+		if !EqualApprox(d.DepthPct, 100*e.Depth/e.PeakEquity) {
+			t.Errorf("Expected drawdown %d to be %.2f%% deep, got %.2f%%", i, 100*e.Depth/e.PeakEquity, d.DepthPct)
+		}
+	}
+	if drawdowns[2].Recovered() {
+		t.Error("Expected the last drawdown not to have recovered")
+	}
+	if top := TopDrawdowns(drawdowns, 2); len(top) != 2 || top[0].Depth != 15 || top[1].Depth != 13 {
+		t.Errorf("Expected the 2 deepest drawdowns, got %+v", top)
+	}
+
+	var out strings.Builder
+	if err := renderDrawdowns(&ReportContext{Stats: stats, Out: &out, DateLayout: time.DateOnly}); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 5 || !strings.Contains(lines[2], "2022-01-05") || !strings.HasPrefix(lines[3], "2022-01-08") || !strings.Contains(lines[3], " - ") {
+		t.Errorf("Expected a table of the drawdowns from the deepest, got:\n%s", out.String())
+	}
+}
+
+func TestSummarizeDrawdowns(t *testing.T) {
+	trader, broker := runTestBacktest(t, &roundTripStrategy{})
+	summary := Summarize(trader.Stats(), broker)
+	drawdowns := Drawdowns(trader.Stats())
+	if len(drawdowns) == 0 || drawdowns[len(drawdowns)-1].Recovered() {
+		t.Fatalf("Expected a losing round trip to end in a drawdown, got %+v", drawdowns)
+	}
+	if last := drawdowns[len(drawdowns)-1]; !EqualApprox(summary.CurrentDrawdown, last.PeakEquity-broker.NAV()) || summary.CurrentDrawdown <= 0 {
+		t.Errorf("Expected the current drawdown to be the final equity below its peak, got %f", summary.CurrentDrawdown)
+	}
+	if summary.LongestDrawdown <= 0 || summary.LongestDrawdown > summary.Timespan {
+		t.Errorf("Expected the longest drawdown to be within the backtest, got %s", summary.LongestDrawdown)
+	}
+}
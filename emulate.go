@@ -0,0 +1,148 @@
+package autotrader
+
+import (
+	"math"
+)
+
+// emulatedExit is a stop loss, take profit, or trailing stop of an order that the broker does not support, which the Trader enforces itself by closing the position at market.
+type emulatedExit struct {
+	order      Order
+	stopLoss   float64
+	takeProfit float64
+	trailing   TrailingStop
+	trailingSL float64 // trailingSL is the price of the trailing stop once the position is open.
+}
+
+// emulateExits removes the exits of an order that the broker does not support, according to its Capabilities, and returns them so the Trader can enforce them after the order is filled. The stop loss and take profit are only sent to the broker if it supports both, so a bracket is never half on the broker and half emulated. A negative stopLoss is a trailing stop, like for Broker.Order. The returned exit is nil if the broker supports every exit of the order.
+func (t *Trader) emulateExits(units, stopLoss, takeProfit float64, options []OrderOption) (float64, float64, []OrderOption, *emulatedExit) {
+	capabilities := t.Broker.Capabilities()
+	trailing := NewOrderOptions(options...).TrailingStop
+	if trailing.Value <= 0 && stopLoss < 0 {
+		trailing = TrailingStop{Mode: TrailingDistance, Value: -stopLoss}
+	}
+	exit := &emulatedExit{}
+	if trailing.Value > 0 && !capabilities.SupportsTrailingStop(trailing.Mode) {
+		exit.trailing = trailing
+		options = append(options[:len(options):len(options)], func(o *OrderOptions) { o.TrailingStop = TrailingStop{} })
+		stopLoss = 0 // The trailing stop replaces the stop loss.
+	}
+	if (stopLoss > 0 || takeProfit != 0) && !(capabilities.StopLoss && capabilities.TakeProfit) {
+		exit.stopLoss, exit.takeProfit = stopLoss, takeProfit
+		stopLoss, takeProfit = 0, 0
+	}
+	if exit.trailing.Value <= 0 && exit.stopLoss == 0 && exit.takeProfit == 0 {
+		return stopLoss, takeProfit, options, nil
+	}
+	return stopLoss, takeProfit, options, exit
+}
+
+// checkEmulatedExits closes the positions whose emulated exits were reached at the current price and moves their emulated trailing stops. Exits of orders that were cancelled or whose positions were closed are forgotten. The Trader calls it on every candle before the strategy runs, so emulated exits are only as fast as the candles of the Trader.
+func (t *Trader) checkEmulatedExits() {
+	active := t.emulated[:0]
+	for _, exit := range t.emulated {
+		position := exit.order.Position()
+		if position == nil {
+			if containsOrder(t.Broker.OpenOrders(), exit.order) {
+				active = append(active, exit) // The order has not been filled yet.
+			}
+			continue
+		}
+		if position.Closed() {
+			continue
+		}
+		units := position.Units()
+		price := t.Broker.Price(position.Symbol(), units < 0) // The price the position would be closed at.
+		if exit.trailing.Value > 0 {
+			data := t.SymbolData(position.Symbol())
+			if data == nil {
+				data = t.data
+			}
+			distance := exit.trailing.Distance(price, data, data.Len()-1)
+			if units > 0 {
+				exit.trailingSL = math.Max(exit.trailingSL, price-distance)
+			} else if stop := price + distance; exit.trailingSL == 0 || stop < exit.trailingSL {
+				exit.trailingSL = stop
+			}
+		}
+		var closeType OrderCloseType
+		switch {
+		case exit.stopLoss > 0 && (units > 0 && price <= exit.stopLoss || units < 0 && price >= exit.stopLoss):
+			closeType = CloseStopLoss
+		case exit.trailingSL > 0 && (units > 0 && price <= exit.trailingSL || units < 0 && price >= exit.trailingSL):
+			closeType = CloseTrailingStop
+		case exit.takeProfit > 0 && (units > 0 && price >= exit.takeProfit || units < 0 && price <= exit.takeProfit):
+			closeType = CloseTakeProfit
+		default:
+			active = append(active, exit)
+			continue
+		}
+		// The PositionClosed handler labels the trade with the close type, and it may run inside Close, like with a TestBroker, or later on a goroutine of a live broker. Either way the close type must be there first.
+		t.emulatedMu.Lock()
+		t.emulatedCloses[position.Id()] = closeType
+		t.emulatedMu.Unlock()
+		if err := position.Close(); err != nil {
+			t.emulatedClose(position.Id())
+			t.Log.Printf("error closing position %s at its emulated %s: %v", position.Id(), closeType, err)
+			active = append(active, exit)
+		}
+	}
+	t.emulated = active
+}
+
+// emulatedClose returns and forgets the close type of the position with id if it was closed by an emulated exit.
+func (t *Trader) emulatedClose(id string) (OrderCloseType, bool) {
+	t.emulatedMu.Lock()
+	defer t.emulatedMu.Unlock()
+	closeType, ok := t.emulatedCloses[id]
+	delete(t.emulatedCloses, id)
+	return closeType, ok
+}
+
+// containsOrder returns true if orders contains an order with the ID of order.
+func containsOrder(orders []Order, order Order) bool {
+	for _, o := range orders {
+		if o.Id() == order.Id() {
+			return true
+		}
+	}
+	return false
+}
+
+// OCO links orders so that once any of them is filled, the others are cancelled, like a breakout strategy with a buy stop above and a sell stop below the market. The Trader cancels the other orders itself on the tick after the broker signals the fill, so it works with any broker, but orders reached within the same candle may all be filled. Call OCO from the strategy.
+func (t *Trader) OCO(orders ...Order) {
+	if len(orders) > 1 {
+		t.oco = append(t.oco, orders)
+	}
+}
+
+// orderFilled queues order for cancelOCO. It is called by the OrderFulfilled signal of the broker, which may be emitted from a goroutine of the broker that must not wait on a request to it, so the orders are not cancelled here.
+func (t *Trader) orderFilled(order Order) {
+	t.filledMu.Lock()
+	t.filled = append(t.filled, order)
+	t.filledMu.Unlock()
+}
+
+// cancelOCO cancels the orders linked by OCO to the orders filled since the last tick. The Trader calls it on every candle before the strategy runs.
+func (t *Trader) cancelOCO() {
+	t.filledMu.Lock()
+	filled := t.filled
+	t.filled = nil
+	t.filledMu.Unlock()
+	for _, order := range filled {
+		active := t.oco[:0]
+		for _, group := range t.oco {
+			if !containsOrder(group, order) {
+				active = append(active, group)
+				continue
+			}
+			for _, other := range group {
+				if other.Id() != order.Id() && !other.Fulfilled() {
+					if err := other.Cancel(); err != nil {
+						t.Log.Printf("error cancelling order %s of an OCO group: %v", other.Id(), err)
+					}
+				}
+			}
+		}
+		t.oco = active
+	}
+}
@@ -0,0 +1,106 @@
+package autotrader
+
+import "testing"
+
+// plainOrders are the capabilities of a broker that only supports plain orders, so every exit is emulated.
+var plainOrders = Capabilities{OrderTypes: []OrderType{Market, Limit, Stop}}
+
+func TestEmulatedExits(t *testing.T) {
+	var bracket, trailing Order
+	strategy := &scriptedStrategy{actions: map[int]func(*Trader){
+		1: func(t *Trader) {
+			var err error
+			if bracket, err = t.Buy(1000, 1.05, 1.22); err != nil { // Take profit on the third candle.
+				panic(err)
+			}
+			if trailing, err = t.Buy(1000, -0.08, 0); err != nil { // Trailing stop at 1.17 on the third candle, hit on the fourth.
+				panic(err)
+			}
+		},
+	}}
+	broker := newExecutionBroker()
+	trader := newExecutionTrader(strategy, &limitedBroker{broker, plainOrders})
+	if _, err := trader.Buy(1000, 1.2, 0); err == nil {
+		t.Error("Expected an emulated stop loss above the price to be rejected")
+	}
+	for i := 0; i < 5; i++ {
+		trader.Tick()
+		if i == 0 {
+			for _, position := range broker.OpenPositions() {
+				if position.StopLoss() != 0 || position.TakeProfit() != 0 || position.TrailingStop() != 0 {
+					t.Errorf("Expected no exits to be sent to the broker, got %v, %v, and %v", position.StopLoss(), position.TakeProfit(), position.TrailingStop())
+				}
+			}
+		}
+		broker.Advance()
+	}
+
+	if position := bracket.Position(); !position.Closed() || position.ClosePrice() != 1.25 {
+		t.Errorf("Expected the take profit to close the position at the next price of 1.25, got %v", position.ClosePrice())
+	}
+	if position := trailing.Position(); !position.Closed() || position.ClosePrice() != 1.1 {
+		t.Errorf("Expected the trailing stop to close the position at 1.1, got %v", position.ClosePrice())
+	}
+	closeTypes := make(map[OrderCloseType]int)
+	for _, trade := range trader.Stats().Trades() {
+		if trade.Exit {
+			closeTypes[trade.CloseType]++
+		}
+	}
+	if closeTypes[CloseTakeProfit] != 1 || closeTypes[CloseTrailingStop] != 1 {
+		t.Errorf("Expected a take profit and a trailing stop exit, got %v", closeTypes)
+	}
+	if len(trader.emulated) != 0 {
+		t.Errorf("Expected the exits of closed positions to be forgotten, got %d", len(trader.emulated))
+	}
+}
+
+func TestOCO(t *testing.T) {
+	var limit, stop Order
+	strategy := &scriptedStrategy{actions: map[int]func(*Trader){
+		1: func(t *Trader) {
+			limit, _ = t.Order(Limit, 1000, 1.0, 0, 0) // Would fill on the fourth candle.
+			stop, _ = t.Order(Stop, 1000, 1.22, 0, 0)  // Fills on the third candle.
+			t.OCO(limit, stop)
+		},
+	}}
+	broker := newExecutionBroker()
+	trader := newExecutionTrader(strategy, &limitedBroker{broker, plainOrders})
+	for i := 0; i < 5; i++ {
+		trader.Tick()
+		broker.Advance()
+	}
+	if !stop.Fulfilled() || limit.Fulfilled() {
+		t.Errorf("Expected only the stop order to be filled, got %t and %t", stop.Fulfilled(), limit.Fulfilled())
+	}
+	if orders := len(broker.OpenOrders()); orders != 0 {
+		t.Errorf("Expected the limit order to be cancelled, got %d open orders", orders)
+	}
+	if err := limit.Cancel(); err == nil {
+		t.Error("Expected a cancelled order not to be cancelled again")
+	}
+}
+
+func TestOCOCancelsOnTick(t *testing.T) {
+	var limit, stop Order
+	strategy := &scriptedStrategy{actions: map[int]func(*Trader){
+		1: func(t *Trader) {
+			limit, _ = t.Order(Limit, 1000, 1.0, 0, 0)
+			stop, _ = t.Order(Stop, 1000, 1.22, 0, 0) // Fills on the third candle.
+			t.OCO(limit, stop)
+		},
+	}}
+	broker := newExecutionBroker()
+	trader := newExecutionTrader(strategy, &limitedBroker{broker, plainOrders})
+	for i := 0; i < 2; i++ {
+		trader.Tick()
+		broker.Advance()
+	}
+	if !stop.Fulfilled() || len(broker.OpenOrders()) != 1 {
+		t.Fatalf("Expected the limit order to stay open until the next tick, got %d open orders", len(broker.OpenOrders()))
+	}
+	trader.Tick()
+	if len(broker.OpenOrders()) != 0 || len(trader.oco) != 0 {
+		t.Errorf("Expected the tick to cancel the limit order and forget the group, got %d open orders and %d groups", len(broker.OpenOrders()), len(trader.oco))
+	}
+}
@@ -0,0 +1,177 @@
+package autotrader
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+var ErrInvalidWindow = errors.New("invalid event window")
+
+// EventsFromCSV reads the events in the CSV file at path like EventsFromCSVReader.
+func EventsFromCSV(path, dateFormat string) (*IndexedSeries[UnixTime], error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return EventsFromCSVReader(f, dateFormat)
+}
+
+// EventsFromCSVReader reads timestamped events, like earnings, news, or macro releases, from a CSV file with a header row of Date and Name columns, and returns them as an IndexedSeries named "Events" of the names indexed by their time. Events at the same time are joined with "; ". Dates are parsed with dateFormat, or as "2006-01-02 15:04:05" with an optional time if it is empty.
+func EventsFromCSVReader(r io.Reader, dateFormat string) (*IndexedSeries[UnixTime], error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(cleanCSVHeader(name))] = i
+	}
+	for _, name := range []string{"date", "name"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w: %q", ErrMissingColumn, name)
+		}
+	}
+	events := NewIndexedSeries[UnixTime, any]("Events", nil)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		date, err := parseEventDate(strings.TrimSpace(record[columns["date"]]), dateFormat)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		AddEvent(events, date, strings.TrimSpace(record[columns["name"]]))
+	}
+	return events, nil
+}
+
+// parseEventDate parses field with dateFormat, or as a date with an optional time if dateFormat is empty.
+func parseEventDate(field, dateFormat string) (time.Time, error) {
+	if dateFormat != "" {
+		return time.Parse(dateFormat, field)
+	}
+	if date, err := time.Parse(time.DateTime, field); err == nil {
+		return date, nil
+	}
+	return time.Parse(time.DateOnly, field)
+}
+
+// AddEvent adds an event named name at date to the events series, joining it to any event already at that time with "; ".
+func AddEvent(events *IndexedSeries[UnixTime], date time.Time, name string) {
+	index := UnixTime(date.Unix())
+	if existing, ok := events.ValueIndex(index).(string); ok && existing != "" {
+		name = existing + "; " + name
+	}
+	events.Insert(index, name)
+}
+
+// eventAnnotations returns the events as annotations, so they can be drawn on the charts of a report.
+func eventAnnotations(events *IndexedSeries[UnixTime]) []Annotation {
+	if events == nil {
+		return nil
+	}
+	annotations := make([]Annotation, 0, events.Len())
+	events.ForEach(func(i int, val any) {
+		annotations = append(annotations, Annotation{Time: events.Index(i).Time(), Name: fmt.Sprint(val)})
+	})
+	return annotations
+}
+
+// EventStudy is the average behavior of a series in a window of candles around events, like the price before and after earnings or the equity of a strategy around rate decisions.
+type EventStudy struct {
+	Offsets  []int     // Offsets are the candles relative to the event candle, from -before to after. The event candle, at offset 0, is the first candle at or after the time of the event.
+	Mean     []float64 // Mean is the mean return from the event candle to each offset, like -0.01 for a value 1% lower than at the event candle.
+	Positive []float64 // Positive is the fraction of events with a positive return at each offset.
+	Events   int       // Events is the number of events with a complete window. Events too close to the ends of the series are left out.
+}
+
+// StudyEvents measures the average return of values in the window of before candles to after candles around each event. Returns are relative to the value at the event candle, so values must be positive prices or equities. ErrInvalidWindow is returned if before or after is negative.
+func StudyEvents(events, values *IndexedSeries[UnixTime], before, after int) (EventStudy, error) {
+	if before < 0 || after < 0 {
+		return EventStudy{}, fmt.Errorf("%w: %d candles before and %d after", ErrInvalidWindow, before, after)
+	}
+	size := before + after + 1
+	study := EventStudy{Offsets: make([]int, size), Mean: make([]float64, size), Positive: make([]float64, size)}
+	for i := range study.Offsets {
+		study.Offsets[i] = i - before
+	}
+	for i := 0; i < events.Len(); i++ {
+		row, _ := slices.BinarySearch(values.indexes, *events.Index(i))
+		if row-before < 0 || row+after >= values.Len() {
+			continue
+		}
+		base := values.Float(row)
+		if base == 0 || math.IsNaN(base) {
+			continue
+		}
+		for j := range study.Offsets {
+			ret := values.Float(row+study.Offsets[j])/base - 1
+			study.Mean[j] += ret
+			if ret > 0 {
+				study.Positive[j]++
+			}
+		}
+		study.Events++
+	}
+	if study.Events > 0 {
+		for j := range study.Offsets {
+			study.Mean[j] /= float64(study.Events)
+			study.Positive[j] /= float64(study.Events)
+		}
+	}
+	return study, nil
+}
+
+// EventStudySection returns a ReportSection that prints the event study of the closes of the data of the TestBroker, or the final data of the Trader if there is no broker, and the equity of the strategy in the window of before to after candles around the events of the report. Nothing is printed if the report has no events.
+func EventStudySection(before, after int) ReportSection {
+	return ReportSectionFunc(func(ctx *ReportContext) error {
+		if ctx.Events == nil || ctx.Events.Len() == 0 {
+			return nil
+		}
+		candles := ctx.Trader.data
+		if ctx.Broker != nil {
+			candles = ctx.Broker.Data
+		}
+		price, err := StudyEvents(ctx.Events, candles.Closes(), before, after)
+		if err != nil {
+			return err
+		}
+		equity, err := StudyEvents(ctx.Events, ctx.Stats.Equity(), before, after)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(ctx.Out, "\nEvent study of %d events (%d with a complete window of the data):\n", ctx.Events.Len(), price.Events)
+		w := tabwriter.NewWriter(ctx.Out, 0, 0, 1, ' ', 0)
+		fmt.Fprintln(w, "Offset\tPrice\tUp\tEquity\tUp\t")
+		for i, offset := range price.Offsets {
+			fmt.Fprintf(w, "%+d\t%.2f%%\t%.0f%%\t%.2f%%\t%.0f%%\t\n", offset, 100*price.Mean[i], 100*price.Positive[i], 100*equity.Mean[i], 100*equity.Positive[i])
+		}
+		return w.Flush()
+	})
+}
+
+// Equity returns the equity of each candle of Dated as an IndexedSeries indexed by the date of the candle.
+func (s *TraderStats) Equity() *IndexedSeries[UnixTime] {
+	equity := NewIndexedSeries[UnixTime, any]("Equity", nil)
+	if s.Dated == nil {
+		return equity
+	}
+	for i := 0; i < s.Dated.Len(); i++ {
+		equity.Insert(UnixTime(s.Dated.Date(i).Unix()), s.Dated.Float("Equity", i))
+	}
+	return equity
+}
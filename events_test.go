@@ -0,0 +1,63 @@
+package autotrader
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventsFromCSV(t *testing.T) {
+	data := `Date,Name
+2022-01-03,CPI
+2022-01-06 12:00:00,Earnings
+2022-01-03,FOMC
+`
+	events, err := EventsFromCSVReader(strings.NewReader(data), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if events.Len() != 2 {
+		t.Fatalf("Expected 2 event times, got %d", events.Len())
+	}
+	if name := events.Value(0); name != "CPI; FOMC" {
+		t.Errorf("Expected events at the same time to be joined, got %q", name)
+	}
+	if date := events.Index(1).Time().UTC(); !date.Equal(time.Date(2022, 1, 6, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the second event at noon on 2022-01-06, got %v", date)
+	}
+}
+
+func TestStudyEvents(t *testing.T) {
+	events := NewIndexedSeries[UnixTime, any]("Events", nil)
+	AddEvent(events, time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), "Too early") // No candle before it.
+	AddEvent(events, time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC), "A")
+	AddEvent(events, time.Date(2022, 1, 6, 12, 0, 0, 0, time.UTC), "B") // The event candle is 2022-01-07.
+	study, err := StudyEvents(events, testData.Closes(), 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if study.Events != 2 {
+		t.Fatalf("Expected 2 events with a complete window, got %d", study.Events)
+	}
+	for i, expected := range []float64{1.2/1.25 - 1, 0, 1.1/1.25 - 1} {
+		if study.Offsets[i] != i-1 || !EqualApprox(study.Mean[i], expected) || study.Positive[i] != 0 {
+			t.Errorf("Expected offset %d to have a mean of %f, got %d with %f and %f positive", i-1, expected, study.Offsets[i], study.Mean[i], study.Positive[i])
+		}
+	}
+
+	trader, broker := runTestBacktest(t, &roundTripStrategy{})
+	AddEvent(events, time.Date(2022, 1, 10, 12, 0, 0, 0, time.UTC), "After the data")
+	var out bytes.Buffer
+	ctx := &ReportContext{Trader: trader, Broker: broker, Stats: trader.Stats(), Out: &out, Events: events}
+	annotations := ctx.annotations()
+	if len(annotations) != 1 || annotations[0].Name != "B" || !annotations[0].Time.Equal(time.Date(2022, 1, 6, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected event B on the candle of 2022-01-06, got %v", annotations)
+	}
+	if err := EventStudySection(1, 1).Render(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Event study of 4 events (2 with") || !strings.Contains(out.String(), "-12.00%") {
+		t.Errorf("Expected the event study table, got:\n%s", out.String())
+	}
+}
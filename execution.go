@@ -0,0 +1,255 @@
+package autotrader
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"golang.org/x/exp/slices"
+)
+
+var (
+	ErrInvalidExecution   = errors.New("invalid execution algorithm")
+	ErrExecutionAbandoned = errors.New("execution abandoned")
+)
+
+// DefaultMaxChildFailures is the number of child orders in a row that may fail to be placed before a parent order is abandoned, unless its Execution sets MaxFailures.
+const DefaultMaxChildFailures = 3
+
+// ExecutionAlgo is an algorithm that splits a large parent order into smaller child orders to reduce its market impact.
+type ExecutionAlgo string
+
+const (
+	TWAP    ExecutionAlgo = "twap"    // TWAP splits the order into Slices equal child orders placed every Interval candles, so it is filled at about the time-weighted average price.
+	Iceberg ExecutionAlgo = "iceberg" // Iceberg only shows Display units at a time, placing the next child order once the previous one is filled.
+)
+
+// Execution configures how a parent order is split into child orders. See WithTWAP and WithIceberg.
+type Execution struct {
+	Algo     ExecutionAlgo
+	Slices   int     // Slices is the number of child orders of a TWAP order.
+	Interval int     // Interval is the number of candles between the child orders of a TWAP order. The default is 1.
+	Display  float64 // Display is the absolute number of units shown by each child order of an Iceberg order.
+	// MaxFailures is the number of child orders in a row that may fail to be placed before the parent order is abandoned. The default is DefaultMaxChildFailures.
+	MaxFailures int
+}
+
+// WithTWAP splits an order into slices child orders of equal size, placing the first at once and the next every interval candles.
+func WithTWAP(slices, interval int) OrderOption {
+	return func(o *OrderOptions) {
+		o.Execution = Execution{Algo: TWAP, Slices: slices, Interval: interval}
+	}
+}
+
+// WithIceberg splits an order into child orders of display units, placing the next once the previous one is filled, so the market only ever sees display units of it.
+func WithIceberg(display float64) OrderOption {
+	return func(o *OrderOptions) {
+		o.Execution = Execution{Algo: Iceberg, Display: display}
+	}
+}
+
+// withoutExecution removes the execution algorithm from the options of a child order, so it is sent to the broker as is.
+func withoutExecution(o *OrderOptions) {
+	o.Execution = Execution{}
+}
+
+// ParentOrder is an order that the Trader executes as child orders according to its Execution. Child orders are placed with the broker as the Trader ticks and are reported like any other order.
+type ParentOrder struct {
+	Symbol     string
+	OrderType  OrderType
+	Units      float64 // Units is the total number of units of the order. A short order has negative units.
+	Price      float64
+	StopLoss   float64
+	TakeProfit float64
+	Execution  Execution
+	Children   []Order // Children are the child orders placed so far, in order.
+	// Errors are the errors of child orders that failed to be placed. A failed child is tried again on the next candle, until MaxFailures of the Execution fail in a row and the order is abandoned with an error wrapping ErrExecutionAbandoned.
+	Errors []error
+
+	options   []OrderOption
+	placed    float64        // placed is the absolute number of units of the child orders placed so far.
+	next      int            // next is the candle the next TWAP child order is due.
+	failures  int            // failures is the number of child orders in a row that failed to be placed.
+	ended     map[Order]bool // ended are the child orders that were cancelled or expired before they were filled.
+	cancelled bool
+	abandoned bool
+}
+
+// Remaining returns the absolute number of units that have not been placed as child orders.
+func (p *ParentOrder) Remaining() float64 {
+	if p.cancelled {
+		return 0
+	}
+	return math.Max(math.Abs(p.Units)-p.placed, 0)
+}
+
+// Filled returns the units of the child orders that have been filled, which are negative for a short order.
+func (p *ParentOrder) Filled() float64 {
+	var filled float64
+	for _, child := range p.Children {
+		if child.Fulfilled() {
+			filled += child.Units()
+		}
+	}
+	return filled
+}
+
+// Abandoned returns true if the order stopped placing child orders because too many failed to be placed.
+func (p *ParentOrder) Abandoned() bool {
+	return p.abandoned
+}
+
+// Done returns true once every child order has been placed or the order was cancelled or abandoned.
+func (p *ParentOrder) Done() bool {
+	return p.Remaining() <= 0
+}
+
+// Cancel stops placing child orders and cancels the child orders that have not been filled.
+func (p *ParentOrder) Cancel() error {
+	p.cancelled = true
+	var errs []error
+	for _, child := range p.Children {
+		if !child.Fulfilled() && !p.ended[child] {
+			if err := child.Cancel(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// update marks the child orders that are neither filled nor among the open orders of the broker as ended, since they were cancelled or expired. An ended child is a completed slice, so an iceberg shows its next child.
+func (p *ParentOrder) update(open []Order) {
+	for _, child := range p.Children {
+		if child.Fulfilled() || p.ended[child] || slices.Contains(open, child) {
+			continue
+		}
+		if p.ended == nil {
+			p.ended = make(map[Order]bool)
+		}
+		p.ended[child] = true
+	}
+}
+
+// childUnits returns the units of the next child order, or zero if no child order is due on candle.
+func (p *ParentOrder) childUnits(candle int) float64 {
+	remaining := p.Remaining()
+	if remaining <= 0 {
+		return 0
+	}
+	var size float64
+	switch p.Execution.Algo {
+	case TWAP:
+		if candle < p.next {
+			return 0
+		}
+		size = math.Abs(p.Units) / float64(p.Execution.Slices)
+	case Iceberg:
+		if n := len(p.Children); n > 0 && !p.Children[n-1].Fulfilled() && !p.ended[p.Children[n-1]] {
+			return 0 // The previous child is still showing.
+		}
+		size = p.Execution.Display
+	}
+	if remaining-size < 1e-9*math.Abs(p.Units) {
+		size = remaining // The last child takes what is left, so rounding never leaves a sliver.
+	}
+	return math.Copysign(size, p.Units)
+}
+
+// validate returns an error wrapping ErrInvalidExecution if the Execution cannot split the order.
+func (e Execution) validate() error {
+	switch e.Algo {
+	case TWAP:
+		if e.Slices < 1 {
+			return fmt.Errorf("%w: a TWAP order needs at least one slice, got %d", ErrInvalidExecution, e.Slices)
+		}
+	case Iceberg:
+		if e.Display <= 0 {
+			return fmt.Errorf("%w: an iceberg order needs positive display units, got %v", ErrInvalidExecution, e.Display)
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidExecution, e.Algo)
+	}
+	return nil
+}
+
+// execute starts executing an order with an execution algorithm by placing its first child order, which is returned. If the first child order fails, the whole order is abandoned and the error is returned.
+func (t *Trader) execute(symbol string, orderType OrderType, units, price, stopLoss, takeProfit float64, options []OrderOption) (Order, error) {
+	execution := NewOrderOptions(options...).Execution
+	if err := execution.validate(); err != nil {
+		return nil, err
+	}
+	if execution.Interval < 1 {
+		execution.Interval = 1
+	}
+	if execution.MaxFailures < 1 {
+		execution.MaxFailures = DefaultMaxChildFailures
+	}
+	parent := &ParentOrder{
+		Symbol:     symbol,
+		OrderType:  orderType,
+		Units:      units,
+		Price:      price,
+		StopLoss:   stopLoss,
+		TakeProfit: takeProfit,
+		Execution:  execution,
+		options:    append(options[:len(options):len(options)], withoutExecution),
+	}
+	t.placeChild(parent)
+	if len(parent.Children) == 0 {
+		return nil, parent.Errors[0]
+	}
+	t.parents = append(t.parents, parent)
+	return parent.Children[0], nil
+}
+
+// placeChild places the next child order of parent if one is due. The parent is abandoned once MaxFailures child orders in a row fail to be placed.
+func (t *Trader) placeChild(parent *ParentOrder) {
+	var candle int
+	if t.stats.Dated != nil {
+		candle = t.stats.Dated.Len()
+	}
+	units := parent.childUnits(candle)
+	if units == 0 {
+		return
+	}
+	order, err := t.placeOrder(parent.OrderType, parent.Symbol, units, parent.Price, parent.StopLoss, parent.TakeProfit, parent.options)
+	if err != nil {
+		t.Log.Printf("error placing child order of %v units: %v", units, err)
+		parent.Errors = append(parent.Errors, err)
+		if parent.failures++; parent.failures >= parent.Execution.MaxFailures && len(parent.Children) > 0 {
+			err := fmt.Errorf("%w: %d child orders of %s failed to be placed in a row", ErrExecutionAbandoned, parent.failures, parent.Symbol)
+			t.Log.Printf("%v", err)
+			parent.Errors = append(parent.Errors, err)
+			parent.abandoned = true
+			parent.Cancel()
+		}
+		return
+	}
+	parent.failures = 0
+	parent.Children = append(parent.Children, order)
+	parent.placed += math.Abs(units)
+	parent.next = candle + parent.Execution.Interval
+}
+
+// executeParents places the child orders that are due of the parent orders that are not done, and forgets the parent orders that are.
+func (t *Trader) executeParents() {
+	if len(t.parents) == 0 {
+		return
+	}
+	open := t.Broker.OpenOrders()
+	active := t.parents[:0]
+	for _, parent := range t.parents {
+		parent.update(open)
+		t.placeChild(parent)
+		if !parent.Done() {
+			active = append(active, parent)
+		}
+	}
+	t.parents = active
+}
+
+// ParentOrders returns the orders with an execution algorithm that are still placing child orders.
+func (t *Trader) ParentOrders() []*ParentOrder {
+	return t.parents
+}
@@ -0,0 +1,204 @@
+package autotrader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+// newExecutionBroker returns a TestBroker of testData without slippage.
+func newExecutionBroker() *TestBroker {
+	broker := NewTestBroker(nil, testData, 10_000, 50, 0, 0)
+	broker.Slippage = 0
+	return broker
+}
+
+// newExecutionTrader returns an initialized Trader of strategy on broker, which is usually a broker from newExecutionBroker or a wrapper of one.
+func newExecutionTrader(strategy Strategy, broker Broker) *Trader {
+	trader := NewTrader(TraderConfig{Broker: broker, Strategy: strategy, Symbol: "EUR_USD", Frequency: "D", CandlesToKeep: 5})
+	trader.Log.SetOutput(io.Discard)
+	trader.Init()
+	return trader
+}
+
+func TestTWAP(t *testing.T) {
+	var parent *ParentOrder
+	strategy := &scriptedStrategy{actions: map[int]func(*Trader){
+		1: func(t *Trader) {
+			t.Buy(30, 0, 0, WithTWAP(3, 2))
+			parent = t.ParentOrders()[0]
+		},
+	}}
+	broker := newExecutionBroker()
+	trader := newExecutionTrader(strategy, broker)
+	var children []int
+	for i := 0; i < 7; i++ {
+		trader.Tick()
+		children = append(children, len(parent.Children))
+		broker.Advance()
+	}
+	expected := []int{1, 1, 2, 2, 3, 3, 3}
+	for i := range expected {
+		if children[i] != expected[i] {
+			t.Fatalf("Expected child orders on every other candle %v, got %v", expected, children)
+		}
+	}
+	if !EqualApprox(parent.Filled(), 30) || !parent.Done() {
+		t.Errorf("Expected 30 units to be filled, got %f", parent.Filled())
+	}
+	if len(trader.ParentOrders()) != 0 {
+		t.Errorf("Expected the done order to be forgotten, got %d parent orders", len(trader.ParentOrders()))
+	}
+	if trades := len(trader.Stats().Trades()); trades != 3 {
+		t.Errorf("Expected 3 trades, got %d", trades)
+	}
+}
+
+func TestIceberg(t *testing.T) {
+	var parent *ParentOrder
+	strategy := &scriptedStrategy{actions: map[int]func(*Trader){
+		1: func(t *Trader) {
+			t.Sell(100, 0, 0, WithIceberg(40))
+			parent = t.ParentOrders()[0]
+		},
+	}}
+	broker := newExecutionBroker()
+	trader := newExecutionTrader(strategy, broker)
+	for i := 0; i < 4; i++ {
+		trader.Tick()
+		broker.Advance()
+	}
+	expected := []float64{-40, -40, -20}
+	if len(parent.Children) != len(expected) {
+		t.Fatalf("Expected %d child orders, got %d", len(expected), len(parent.Children))
+	}
+	for i, child := range parent.Children {
+		if child.Units() != expected[i] {
+			t.Errorf("Expected child %d to show %v units, got %v", i, expected[i], child.Units())
+		}
+	}
+	if parent.Filled() != -100 {
+		t.Errorf("Expected -100 units to be filled, got %f", parent.Filled())
+	}
+}
+
+func TestParentOrderCancel(t *testing.T) {
+	var parent *ParentOrder
+	strategy := &scriptedStrategy{actions: map[int]func(*Trader){
+		1: func(t *Trader) {
+			t.Buy(30, 0, 0, WithTWAP(3, 1))
+			parent = t.ParentOrders()[0]
+		},
+		2: func(t *Trader) { t.CloseOrdersAndPositions() },
+	}}
+	broker := newExecutionBroker()
+	trader := newExecutionTrader(strategy, broker)
+	for i := 0; i < 4; i++ {
+		trader.Tick()
+		broker.Advance()
+	}
+	if len(parent.Children) != 2 || !parent.Done() {
+		t.Errorf("Expected no child orders after the second, got %d", len(parent.Children))
+	}
+	if positions := len(broker.OpenPositions()); positions != 0 {
+		t.Errorf("Expected no open positions, got %d", positions)
+	}
+}
+
+func TestIcebergEndedChild(t *testing.T) {
+	var parent *ParentOrder
+	strategy := &scriptedStrategy{actions: map[int]func(*Trader){
+		1: func(t *Trader) {
+			t.Order(Limit, 100, 0.5, 0, 0, WithIceberg(40)) // The limit is never reached.
+			parent = t.ParentOrders()[0]
+		},
+		2: func(t *Trader) { parent.Children[0].Cancel() },
+	}}
+	broker := newExecutionBroker()
+	trader := newExecutionTrader(strategy, broker)
+	for i := 0; i < 3; i++ {
+		trader.Tick()
+		broker.Advance()
+	}
+	if len(parent.Children) != 2 || parent.Remaining() != 20 {
+		t.Errorf("Expected the cancelled child to be a completed slice and the next to be shown, got %d children and %v units remaining", len(parent.Children), parent.Remaining())
+	}
+}
+
+// rejectingBroker is a TestBroker that rejects every order while reject is true.
+type rejectingBroker struct {
+	*TestBroker
+	reject bool
+}
+
+func (b *rejectingBroker) Order(ctx context.Context, orderType OrderType, symbol string, units, price, stopLoss, takeProfit float64, options ...OrderOption) (Order, error) {
+	if b.reject {
+		return nil, ErrMarketClosed
+	}
+	return b.TestBroker.Order(ctx, orderType, symbol, units, price, stopLoss, takeProfit, options...)
+}
+
+func TestParentOrderAbandoned(t *testing.T) {
+	broker := &rejectingBroker{TestBroker: NewTestBroker(nil, testData, 10_000, 50, 0, 0)}
+	var parent *ParentOrder
+	strategy := &scriptedStrategy{actions: map[int]func(*Trader){
+		1: func(t *Trader) {
+			t.Buy(50, 0, 0, WithTWAP(5, 1))
+			parent = t.ParentOrders()[0]
+			broker.reject = true
+		},
+	}}
+	trader := NewTrader(TraderConfig{Broker: broker, Strategy: strategy, Symbol: "EUR_USD", Frequency: "D", CandlesToKeep: 5})
+	trader.Log.SetOutput(io.Discard)
+	trader.Init()
+	for i := 0; i < 6; i++ {
+		trader.Tick()
+		broker.Advance()
+	}
+	if !parent.Abandoned() || !parent.Done() || len(trader.ParentOrders()) != 0 {
+		t.Fatalf("Expected the order to be abandoned and forgotten, got abandoned: %v, done: %v", parent.Abandoned(), parent.Done())
+	}
+	if n := len(parent.Errors); n != DefaultMaxChildFailures+1 || !errors.Is(parent.Errors[n-1], ErrExecutionAbandoned) {
+		t.Errorf("Expected %d failures and ErrExecutionAbandoned, got %v", DefaultMaxChildFailures, parent.Errors)
+	}
+	if len(parent.Children) != 1 {
+		t.Errorf("Expected only the first child order, got %d", len(parent.Children))
+	}
+}
+
+func TestInvalidExecution(t *testing.T) {
+	trader := newExecutionTrader(&scriptedStrategy{}, newExecutionBroker())
+	if _, err := trader.Buy(30, 0, 0, WithTWAP(0, 1)); !errors.Is(err, ErrInvalidExecution) {
+		t.Errorf("Expected ErrInvalidExecution for a TWAP without slices, got %v", err)
+	}
+	if _, err := trader.Buy(30, 0, 0, WithIceberg(0)); !errors.Is(err, ErrInvalidExecution) {
+		t.Errorf("Expected ErrInvalidExecution for an iceberg without display units, got %v", err)
+	}
+	if len(trader.ParentOrders()) != 0 {
+		t.Errorf("Expected invalid orders not to be executed, got %d parent orders", len(trader.ParentOrders()))
+	}
+}
+
+func TestMarketImpact(t *testing.T) {
+	broker := NewTestBroker(nil, testData, 10_000, 50, 0, 0)
+	broker.Slippage = 0
+	broker.MarketImpact = 0.1
+	order, err := broker.Order(context.Background(), Market, "EUR_USD", 10, 0, 0, 0) // 10% of the volume of 100.
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price := order.Position().EntryPrice(); !EqualApprox(price, 1.15*1.01) {
+		t.Errorf("Expected to buy 1%% above the close, got %f", price)
+	}
+	if slippage := order.Costs().Slippage; !EqualApprox(slippage, 0.0115*10) {
+		t.Errorf("Expected the impact to be counted as slippage, got %f", slippage)
+	}
+	order, err = broker.Order(context.Background(), Market, "EUR_USD", -10, 0, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price := order.Position().EntryPrice(); !EqualApprox(price, 1.15*0.99) {
+		t.Errorf("Expected to sell 1%% below the close, got %f", price)
+	}
+}
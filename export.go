@@ -0,0 +1,229 @@
+package autotrader
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrUnknownFormat = errors.New("unknown trade export format")
+
+// TradeFormat is a file format of trades that external tools can import, so backtest and live results can be reviewed in them. See ExportTrades.
+type TradeFormat string
+
+const (
+	// TradingViewCSV is a CSV file like the List of Trades of the TradingView strategy tester, with an entry and an exit row for every trade.
+	TradingViewCSV TradeFormat = "tradingview"
+	// TradingViewPine is a Pine Script indicator that draws a marker for every fill on the chart it is added to, so trades can be replayed on TradingView charts.
+	TradingViewPine TradeFormat = "pine"
+	// NinjaTraderCSV is a CSV file like the Trades grid of the NinjaTrader trade performance, with a row for every closed position.
+	NinjaTraderCSV TradeFormat = "ninjatrader"
+	// TradervueCSV is the generic CSV import of Tradervue, with a row for every fill.
+	TradervueCSV TradeFormat = "tradervue"
+	// EdgewonkCSV is a CSV file for the custom import of Edgewonk, with a row for every closed position.
+	EdgewonkCSV TradeFormat = "edgewonk"
+)
+
+// ExportTrades writes trades, like those of TraderStats.Trades, to w in format. Formats of closed positions leave out positions that are still open and positions that were opened before the trades start. ErrUnknownFormat is returned for any other format.
+func ExportTrades(w io.Writer, format TradeFormat, trades []TradeStat) error {
+	switch format {
+	case TradingViewCSV:
+		return writeTradingViewCSV(w, trades)
+	case TradingViewPine:
+		return writeTradingViewPine(w, trades)
+	case NinjaTraderCSV:
+		return writeNinjaTraderCSV(w, trades)
+	case TradervueCSV:
+		return writeTradervueCSV(w, trades)
+	case EdgewonkCSV:
+		return writeEdgewonkCSV(w, trades)
+	}
+	return fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+}
+
+// TradeExportSection returns a ReportSection that writes the trades of the backtest in format to filename in the report directory.
+func TradeExportSection(format TradeFormat, filename string) ReportSection {
+	return ReportSectionFunc(func(ctx *ReportContext) error {
+		f, err := os.Create(ctx.Path(filename))
+		if err != nil {
+			return err
+		}
+		if err := ExportTrades(f, format, ctx.Stats.Trades()); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	})
+}
+
+// closedTrades returns the exit trades of trades that are linked to their entries.
+func closedTrades(trades []TradeStat) []TradeStat {
+	closed := make([]TradeStat, 0, len(trades)/2)
+	for _, trade := range trades {
+		if trade.Exit && trade.Entry != nil {
+			closed = append(closed, trade)
+		}
+	}
+	return closed
+}
+
+// tradeTime returns the time of the fill of trade.
+func tradeTime(trade TradeStat) time.Time {
+	if trade.Exit {
+		return trade.CloseTime
+	}
+	return trade.OpenTime
+}
+
+// direction returns "Long" for positive units and "Short" for negative units.
+func direction(units float64) string {
+	if units < 0 {
+		return "Short"
+	}
+	return "Long"
+}
+
+func formatExportFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func writeTradingViewCSV(w io.Writer, trades []TradeStat) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"Trade #", "Type", "Signal", "Date/Time", "Price", "Contracts", "Profit"})
+	for i, trade := range closedTrades(trades) {
+		number := strconv.Itoa(i + 1)
+		side := direction(trade.Units)
+		cw.Write([]string{number, "Exit " + side, exitSignal(trade), trade.CloseTime.UTC().Format("2006-01-02 15:04"), formatExportFloat(trade.Price), formatExportFloat(math.Abs(trade.Units)), formatExportFloat(trade.PL)})
+		cw.Write([]string{number, "Entry " + side, trade.Entry.Tags.String(), trade.Entry.OpenTime.UTC().Format("2006-01-02 15:04"), formatExportFloat(trade.Entry.Price), formatExportFloat(math.Abs(trade.Units)), formatExportFloat(trade.PL)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// exitSignal returns the reason an exit trade was made, which is its close type or "Close" if it was closed by the strategy.
+func exitSignal(trade TradeStat) string {
+	if trade.CloseType != "" {
+		return string(trade.CloseType)
+	}
+	return "Close"
+}
+
+func writeTradingViewPine(w io.Writer, trades []TradeStat) error {
+	var times, prices, units, exits []string
+	for _, trade := range trades {
+		times = append(times, strconv.FormatInt(tradeTime(trade).UnixMilli(), 10))
+		prices = append(prices, formatExportFloat(trade.Price))
+		units = append(units, formatExportFloat(trade.Units))
+		exits = append(exits, strconv.FormatBool(trade.Exit))
+	}
+	_, err := fmt.Fprintf(w, `//@version=5
+indicator("autotrader trades", overlay=true, max_labels_count=500)
+
+// Generated by autotrader. Add this indicator to a chart of the traded symbol to see every fill.
+var times = %s
+var prices = %s
+var units = %s
+var exits = %s
+var int next = 0
+
+while next < array.size(times) and array.get(times, next) < time_close
+    float qty = array.get(units, next)
+    bool isExit = array.get(exits, next)
+    // Exits sell longs and buy back shorts, so they are drawn on the opposite side.
+    bool buy = isExit ? qty < 0 : qty > 0
+    label.new(bar_index, array.get(prices, next), (isExit ? "Exit " : "Entry ") + str.tostring(math.abs(qty)), yloc=buy ? yloc.belowbar : yloc.abovebar, style=buy ? label.style_label_up : label.style_label_down, color=buy ? color.green : color.red, textcolor=color.white, size=size.small)
+    next += 1
+`, pineArray(times, "int"), pineArray(prices, "float"), pineArray(units, "float"), pineArray(exits, "bool"))
+	return err
+}
+
+// pineArray returns a Pine Script array of typ holding values.
+func pineArray(values []string, typ string) string {
+	if len(values) == 0 {
+		return "array.new_" + typ + "(0)" // array.from needs at least one value.
+	}
+	return "array.from(" + strings.Join(values, ", ") + ")"
+}
+
+func writeNinjaTraderCSV(w io.Writer, trades []TradeStat) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"Trade number", "Instrument", "Market pos.", "Qty", "Entry price", "Exit price", "Entry time", "Exit time", "Entry name", "Exit name", "Profit", "Commission"})
+	for i, trade := range closedTrades(trades) {
+		cw.Write([]string{
+			strconv.Itoa(i + 1),
+			trade.Symbol,
+			direction(trade.Units),
+			formatExportFloat(math.Abs(trade.Units)),
+			formatExportFloat(trade.Entry.Price),
+			formatExportFloat(trade.Price),
+			trade.Entry.OpenTime.UTC().Format("1/2/2006 3:04:05 PM"),
+			trade.CloseTime.UTC().Format("1/2/2006 3:04:05 PM"),
+			trade.Entry.Tags.String(),
+			exitSignal(trade),
+			formatExportFloat(trade.PL),
+			formatExportFloat(trade.Entry.Commission + trade.Commission),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeTradervueCSV(w io.Writer, trades []TradeStat) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"Date", "Time", "Symbol", "Quantity", "Price", "Side", "Commission", "TransFee", "ECNFee"})
+	for _, trade := range trades {
+		var side string
+		switch {
+		case !trade.Exit && trade.Units > 0:
+			side = "Buy"
+		case !trade.Exit:
+			side = "Short"
+		case trade.Units > 0:
+			side = "Sell"
+		default:
+			side = "Cover"
+		}
+		date := tradeTime(trade).UTC()
+		cw.Write([]string{
+			date.Format("01/02/2006"),
+			date.Format("15:04:05"),
+			trade.Symbol,
+			formatExportFloat(math.Abs(trade.Units)),
+			formatExportFloat(trade.Price),
+			side,
+			formatExportFloat(trade.Commission),
+			formatExportFloat(trade.Financing), // Swaps are the closest to a transaction fee.
+			"0",
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeEdgewonkCSV(w io.Writer, trades []TradeStat) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"Instrument", "Direction", "Entry Date", "Entry Price", "Exit Date", "Exit Price", "Position Size", "Commission", "Swap", "Profit", "Setup"})
+	for _, trade := range closedTrades(trades) {
+		cw.Write([]string{
+			trade.Symbol,
+			direction(trade.Units),
+			trade.Entry.OpenTime.UTC().Format("2006-01-02 15:04:05"),
+			formatExportFloat(trade.Entry.Price),
+			trade.CloseTime.UTC().Format("2006-01-02 15:04:05"),
+			formatExportFloat(trade.Price),
+			formatExportFloat(math.Abs(trade.Units)),
+			formatExportFloat(trade.Entry.Commission + trade.Commission),
+			formatExportFloat(trade.Financing),
+			formatExportFloat(trade.PL),
+			trade.Entry.Tags.String(),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
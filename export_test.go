@@ -0,0 +1,77 @@
+package autotrader
+
+import (
+	"encoding/csv"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExportTrades(t *testing.T) {
+	trader, _ := runTestBacktest(t, &roundTripStrategy{})
+	trades := trader.Stats().Trades()
+	if len(trades) != 2 || trades[1].Symbol != "EUR_USD" || !EqualApprox(trades[1].PL, -100) {
+		t.Fatalf("Expected a losing round trip in EUR_USD, got %+v", trades)
+	}
+
+	expected := map[TradeFormat][][]string{
+		TradingViewCSV: {
+			{"Trade #", "Type", "Signal", "Date/Time", "Price", "Contracts", "Profit"},
+			{"1", "Exit Long", "M", "2022-01-04 00:00", "1.1", "1000", trades[1].exportPL()},
+			{"1", "Entry Long", "", "2022-01-02 00:00", "1.2", "1000", trades[1].exportPL()},
+		},
+		NinjaTraderCSV: {
+			{"Trade number", "Instrument", "Market pos.", "Qty", "Entry price", "Exit price", "Entry time", "Exit time", "Entry name", "Exit name", "Profit", "Commission"},
+			{"1", "EUR_USD", "Long", "1000", "1.2", "1.1", "1/2/2022 12:00:00 AM", "1/4/2022 12:00:00 AM", "", "M", trades[1].exportPL(), "0"},
+		},
+		TradervueCSV: {
+			{"Date", "Time", "Symbol", "Quantity", "Price", "Side", "Commission", "TransFee", "ECNFee"},
+			{"01/02/2022", "00:00:00", "EUR_USD", "1000", "1.2", "Buy", "0", "0", "0"},
+			{"01/04/2022", "00:00:00", "EUR_USD", "1000", "1.1", "Sell", "0", "0", "0"},
+		},
+		EdgewonkCSV: {
+			{"Instrument", "Direction", "Entry Date", "Entry Price", "Exit Date", "Exit Price", "Position Size", "Commission", "Swap", "Profit", "Setup"},
+			{"EUR_USD", "Long", "2022-01-02 00:00:00", "1.2", "2022-01-04 00:00:00", "1.1", "1000", "0", "0", trades[1].exportPL(), ""},
+		},
+	}
+	for format, rows := range expected {
+		var sb strings.Builder
+		if err := ExportTrades(&sb, format, trades); err != nil {
+			t.Fatal(err)
+		}
+		records, err := csv.NewReader(strings.NewReader(sb.String())).ReadAll()
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if len(records) != len(rows) {
+			t.Errorf("%s: Expected %d rows, got %d", format, len(rows), len(records))
+			continue
+		}
+		for i := range rows {
+			if strings.Join(records[i], ",") != strings.Join(rows[i], ",") {
+				t.Errorf("%s: Expected row %d to be %v, got %v", format, i, rows[i], records[i])
+			}
+		}
+	}
+
+	var pine strings.Builder
+	if err := ExportTrades(&pine, TradingViewPine, trades); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(pine.String(), "//@version=5") || !strings.Contains(pine.String(), "var times = array.from(1641081600000, 1641254400000)") {
+		t.Errorf("Expected a Pine Script with the times of both fills, got:\n%s", pine.String())
+	}
+	pine.Reset()
+	if err := ExportTrades(&pine, TradingViewPine, nil); err != nil || !strings.Contains(pine.String(), "var times = array.new_int(0)") {
+		t.Errorf("Expected empty arrays without trades, got %v:\n%s", err, pine.String())
+	}
+
+	if err := ExportTrades(&pine, "metatrader", trades); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Expected ErrUnknownFormat, got %v", err)
+	}
+}
+
+// exportPL returns the PL of the trade as it is exported.
+func (s TradeStat) exportPL() string {
+	return formatExportFloat(s.PL)
+}
@@ -0,0 +1,118 @@
+package autotrader
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/components"
+	"github.com/go-echarts/go-echarts/v2/opts"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+var ErrCurrencyExposure = errors.New("currency exposure over the limit")
+
+// CurrencyExposure returns the net exposure of positions to each individual currency in the account currency. A position in a currency pair is long its base currency and short its quote currency by its value, so a long EUR_USD and a long EUR_JPY are together long EUR and short both USD and JPY, and a long EUR_USD and a long USD_JPY cancel out in USD. A position in a symbol that is not a pair, like a stock, is only exposed to the symbol. Closed positions are left out, and currencies whose exposures cancel out are kept with an exposure of zero.
+func CurrencyExposure(positions []Position) map[string]float64 {
+	exposure := make(map[string]float64)
+	for _, position := range positions {
+		if position.Closed() {
+			continue
+		}
+		addCurrencyExposure(exposure, position.Symbol(), position.Value())
+	}
+	return exposure
+}
+
+// addCurrencyExposure adds value of symbol to the base currency of symbol and subtracts it from the quote currency.
+func addCurrencyExposure(exposure map[string]float64, symbol string, value float64) {
+	base, quote := SplitSymbol(symbol)
+	exposure[base] += value
+	if quote != "" {
+		exposure[quote] -= value
+	}
+}
+
+// Currencies returns the currencies with an exposure in the Currencies column of Dated, in alphabetical order.
+func (s *TraderStats) Currencies() []string {
+	seen := make(map[string]bool)
+	if s.Dated != nil {
+		s.Dated.Series("Currencies").ForEach(func(_ int, val any) {
+			exposure, _ := val.(map[string]float64)
+			for currency := range exposure {
+				seen[currency] = true
+			}
+		})
+	}
+	currencies := maps.Keys(seen)
+	slices.Sort(currencies)
+	return currencies
+}
+
+// CurrencyExposure returns the net exposure to currency at the end of each candle of Dated, which is zero on candles without any exposure to it.
+func (s *TraderStats) CurrencyExposure(currency string) *Series {
+	exposure := NewSeries(currency)
+	if s.Dated == nil {
+		return exposure
+	}
+	s.Dated.Series("Currencies").ForEach(func(_ int, val any) {
+		currencies, _ := val.(map[string]float64)
+		exposure.Push(currencies[currency])
+	})
+	return exposure
+}
+
+// checkCurrencyExposure returns an error if an order for units of symbol at price would take the net exposure to either of its currencies over MaxCurrencyExposure. Orders that reduce the exposure to a currency are allowed even if it is over the limit.
+func (r *RiskManager) checkCurrencyExposure(t *Trader, symbol string, units, price float64) error {
+	if r.MaxCurrencyExposure <= 0 {
+		return nil
+	}
+	before := CurrencyExposure(t.Broker.OpenPositions())
+	after := maps.Clone(before)
+	addCurrencyExposure(after, symbol, units*price)
+	limit := r.MaxCurrencyExposure * t.Broker.NAV()
+	base, quote := SplitSymbol(symbol)
+	for _, currency := range []string{base, quote} {
+		if currency == "" {
+			continue
+		}
+		if exposure := math.Abs(after[currency]); exposure > limit && exposure > math.Abs(before[currency]) {
+			return fmt.Errorf("%w: %w: net exposure of $%.2f to %s is over the limit of $%.2f", ErrRiskLimit, ErrCurrencyExposure, exposure, currency, limit)
+		}
+	}
+	return nil
+}
+
+func newCurrencyChart(ctx *ReportContext) components.Charter {
+	stats := ctx.Stats
+	currencies := stats.Currencies()
+	if len(currencies) == 0 {
+		return nil
+	}
+	chart := charts.NewBar()
+	chart.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{
+			Title:    "Currency Exposure",
+			Subtitle: "Net exposure to each currency",
+		}),
+		charts.WithTooltipOpts(opts.Tooltip{
+			Show:      true,
+			Trigger:   "axis",
+			TriggerOn: "mousemove|click",
+		}),
+		charts.WithLegendOpts(opts.Legend{
+			Show: true,
+		}))
+	chart.SetXAxis(seriesStringArray(stats.Dated.Dates(), ctx.DateLayout))
+	for _, currency := range currencies {
+		exposure := stats.CurrencyExposure(currency)
+		data := make([]opts.BarData, exposure.Len())
+		for i := range data {
+			data[i] = opts.BarData{Value: Round(exposure.Float(i), 2)}
+		}
+		chart.AddSeries(currency, data, charts.WithBarChartOpts(opts.BarChart{Stack: "exposure"}))
+	}
+	return chart
+}
@@ -0,0 +1,101 @@
+package autotrader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestCurrencyExposure(t *testing.T) {
+	broker := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	broker.Slippage = 0
+	ctx := context.Background()
+	// Every symbol is priced at 1.15 by the single series of test data.
+	for _, order := range []struct {
+		symbol string
+		units  float64
+	}{{"EUR_USD", 10_000}, {"EUR_JPY", 10_000}, {"USD_JPY", -10_000}, {"AAPL", 100}} {
+		if _, err := broker.Order(ctx, Market, order.symbol, order.units, 0, 0, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exposure := CurrencyExposure(broker.OpenPositions())
+	expected := map[string]float64{"EUR": 23_000, "USD": -23_000, "JPY": 0, "AAPL": 115}
+	if len(exposure) != len(expected) {
+		t.Errorf("Expected exposure to %d currencies, got %v", len(expected), exposure)
+	}
+	for currency, value := range expected {
+		if !EqualApprox(exposure[currency], value) {
+			t.Errorf("Expected an exposure of %.2f to %s, got %.2f", value, currency, exposure[currency])
+		}
+	}
+}
+
+func TestRiskManagerCurrencyExposure(t *testing.T) {
+	broker := NewTestBroker(nil, testData, 10_000, 50, 0, 0)
+	broker.Slippage = 0
+	trader := NewTrader(TraderConfig{Broker: broker, Symbol: "EUR_USD", Frequency: "D", Risk: &RiskManager{MaxCurrencyExposure: 2}})
+	trader.Log.SetOutput(io.Discard)
+
+	if _, err := trader.Buy(10_000, 0, 0); err != nil { // $11,500 long EUR.
+		t.Fatal(err)
+	}
+	trader.Symbol = "EUR_JPY"
+	if _, err := trader.Buy(10_000, 0, 0); !errors.Is(err, ErrCurrencyExposure) || !errors.Is(err, ErrRiskLimit) { // $23,000 long EUR is over the $20,000 limit.
+		t.Errorf("Expected buying more EUR to exceed the currency limit, got %v", err)
+	}
+	if _, err := trader.Sell(10_000, 0, 0); err != nil { // Selling EUR_JPY hedges the EUR.
+		t.Errorf("Expected selling EUR to be allowed, got %v", err)
+	}
+	trader.Symbol = "GBP_USD"
+	if _, err := trader.Buy(5000, 0, 0); err != nil { // $17,250 short USD.
+		t.Errorf("Expected buying GBP_USD to be allowed, got %v", err)
+	}
+	if _, err := trader.Buy(5000, 0, 0); !errors.Is(err, ErrCurrencyExposure) { // $23,000 short USD.
+		t.Errorf("Expected shorting more USD to exceed the currency limit, got %v", err)
+	}
+}
+
+func TestTraderStatsCurrencies(t *testing.T) {
+	strategy := &scriptedStrategy{actions: map[int]func(*Trader){
+		2: func(t *Trader) {
+			t.Buy(1000, 0, 0)
+			t.Symbol = "GBP_USD"
+			t.Sell(1000, 0, 0)
+			t.Symbol = "EUR_USD"
+		},
+		4: func(t *Trader) {
+			for _, position := range t.Broker.OpenPositions() {
+				position.Close()
+			}
+		},
+	}}
+	broker := NewTestBroker(nil, testData, 10_000, 50, 0, 0)
+	broker.Slippage = 0
+	trader := NewTrader(TraderConfig{Broker: broker, Strategy: strategy, Symbol: "EUR_USD", Frequency: "D", CandlesToKeep: 5})
+	trader.Log.SetOutput(io.Discard)
+	trader.Init()
+	for i := 0; i < 5; i++ {
+		trader.Tick()
+		broker.Advance()
+	}
+	stats := trader.Stats()
+	currencies := stats.Currencies()
+	if len(currencies) != 3 || currencies[0] != "EUR" || currencies[1] != "GBP" || currencies[2] != "USD" {
+		t.Errorf("Expected EUR, GBP, and USD, got %v", currencies)
+	}
+	eur := stats.CurrencyExposure("EUR")
+	if eur.Len() != stats.Dated.Len() {
+		t.Fatalf("Expected an exposure for each of %d candles, got %d", stats.Dated.Len(), eur.Len())
+	}
+	if eur.Float(0) != 0 || eur.Float(1) <= 0 || eur.Float(2) <= 0 || eur.Float(3) != 0 {
+		t.Errorf("Expected long EUR only while the positions were open, got %v", eur.Values())
+	}
+	if usd := stats.CurrencyExposure("USD").Float(1); !EqualApprox(usd, 0) {
+		t.Errorf("Expected the USD of EUR_USD and GBP_USD to cancel out, got %f", usd)
+	}
+	if gbp := stats.CurrencyExposure("GBP").Float(1); gbp >= 0 {
+		t.Errorf("Expected to be short GBP, got %f", gbp)
+	}
+}
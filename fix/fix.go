@@ -0,0 +1,846 @@
+package fix
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	auto "github.com/fivemoreminix/autotrader"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	BeginStringFIX44 = "FIX.4.4"
+	timeLayout       = "20060102-15:04:05.000" // timeLayout is the UTCTimestamp format of FIX.
+)
+
+var (
+	ErrNotLoggedOn        = errors.New("FIX session is not logged on")
+	ErrRejected           = errors.New("rejected by the counterparty")
+	ErrSequence           = errors.New("MsgSeqNum lower than expected")
+	ErrHeartbeatTimeout   = errors.New("counterparty stopped responding")
+	ErrMalformedSeqNumber = errors.New("malformed sequence numbers")
+)
+
+var _ auto.Broker = (*FIXBroker)(nil) // Compile-time interface check.
+
+// Config configures a FIX session with an acceptor, like a prime broker or an ECN.
+type Config struct {
+	Addr         string        // Addr is the host:port of the acceptor.
+	BeginString  string        // BeginString is the version of the protocol. The default is FIX.4.4.
+	SenderCompID string        // SenderCompID identifies this side of the session.
+	TargetCompID string        // TargetCompID identifies the acceptor.
+	Username     string        // Username is sent on Logon if it is set.
+	Password     string        // Password is sent on Logon if it is set.
+	HeartBtInt   time.Duration // HeartBtInt is the heartbeat interval of the session. The default is 30 seconds.
+	// Balance is the cash balance of the account, since FIX 4.4 has no standard message for it. The realized profit and loss of the session is added to it by NAV.
+	Balance float64
+	// Data provides the candles returned by Candles, since a FIX session only streams quotes. If nil, Candles fails with ErrNoData.
+	Data auto.Broker
+	// Dial opens the connection to Addr. The default is a plain TCP connection, so set it to a tls.Dialer for acceptors that require TLS.
+	Dial func(ctx context.Context, addr string) (net.Conn, error)
+	// CloseTimeout bounds how long Position.Close waits for the closing order to be filled. The default is 30 seconds.
+	CloseTimeout time.Duration
+	Log          *log.Logger // Log receives rejects and session errors. The default discards them.
+	// Instruments are the trading constraints of symbols, like their minimum and maximum units, which orders are validated against before they are sent. Symbols without an instrument are only checked for zero units.
+	Instruments map[string]auto.Instrument
+	// SeqFile is a file the last MsgSeqNum sent and the next MsgSeqNum expected are saved to after every message, so a session resumes its sequence numbers after a restart and the counterparty can resend what was missed. If it is empty or does not exist yet, the sequence numbers are reset on Logon. Only the sequence numbers are saved, not the messages, so a ResendRequest for messages sent before the restart is answered with a SequenceReset that skips them.
+	SeqFile string
+	// ResendLimit is how many of the last messages sent are kept to answer ResendRequests, of which only application messages are resent. Older messages are skipped with a SequenceReset. The default is DefaultResendLimit.
+	ResendLimit int
+}
+
+// DefaultResendLimit is how many of the last messages sent a session keeps to be resent unless its Config sets ResendLimit.
+const DefaultResendLimit = 10_000
+
+// FIXBroker is a Broker that trades over a FIX 4.4 session. Prices come from market data subscriptions, which are made the first time the price of a symbol is requested or with Subscribe, and orders are sent as NewOrderSingle messages and tracked with execution reports. Positions are kept by the broker from the fills of its orders, one per filled order.
+//
+// The session checks the MsgSeqNum of every message it receives. A gap is filled with a ResendRequest, a duplicate is ignored, and a number lower than expected ends the session with ErrSequence. Sent application messages are kept to answer the ResendRequests of the counterparty, and administrative messages are replaced by a SequenceReset that fills their gap. When nothing is received for HeartBtInt, a TestRequest is sent, and the session ends with ErrHeartbeatTimeout if it is not answered within another HeartBtInt.
+//
+// The session layer is implemented here rather than with quickfixgo, since a broker only needs the initiator side of one FIX 4.4 session and this keeps the module free of dependencies besides autotrader, including the data dictionaries and message stores of quickfixgo.
+//
+// Orders with a stop loss, take profit, or trailing stop are rejected with ErrUnsupportedOrder, since NewOrderSingle has no fields for them. Signals are emitted from the goroutine that reads the session.
+type FIXBroker struct {
+	*auto.SignalManager
+	config Config
+	conn   net.Conn
+
+	writeMu  sync.Mutex       // writeMu serializes writes and guards sent.
+	seqMu    sync.Mutex       // seqMu guards seq, inSeq, and the SeqFile. It is never held while writing to the session, so the goroutine that reads the session cannot wait on a write.
+	seq      int              // seq is the MsgSeqNum of the last message sent.
+	inSeq    int              // inSeq is the MsgSeqNum expected of the next message received. It is only changed by the goroutine that reads the session.
+	sent     map[int]*Message // sent are the application messages sent by their MsgSeqNum among the last ResendLimit messages, which are resent on a ResendRequest.
+	resendTo int              // resendTo is the MsgSeqNum that revealed a gap while a ResendRequest is outstanding, or zero. It is only used by the goroutine that reads the session.
+	lastSent atomic.Int64     // lastSent is when the last message was sent in Unix nanoseconds.
+	lastRecv atomic.Int64     // lastRecv is when the last message was received in Unix nanoseconds.
+
+	mu         sync.Mutex
+	nextID     int
+	quotes     map[string]*quote
+	orders     map[string]*Order // orders are by ClOrdID.
+	orderList  []*Order
+	positions  []*Position
+	acks       map[string]chan error // acks are waiting for the first response to a ClOrdID.
+	realizedPL float64
+	loggedOn   chan error
+	done       chan struct{} // done is closed when the session ends.
+	err        error         // err is why the session ended.
+	reason     error         // reason is why the session was ended by this side, which becomes err.
+}
+
+type quote struct {
+	bid, ask float64
+}
+
+// Dial connects to the acceptor of config and logs on. The session runs until Logout is called or the connection is lost.
+func Dial(ctx context.Context, config Config) (*FIXBroker, error) {
+	if config.BeginString == "" {
+		config.BeginString = BeginStringFIX44
+	}
+	if config.HeartBtInt <= 0 {
+		config.HeartBtInt = 30 * time.Second
+	}
+	if config.CloseTimeout <= 0 {
+		config.CloseTimeout = 30 * time.Second
+	}
+	if config.ResendLimit <= 0 {
+		config.ResendLimit = DefaultResendLimit
+	}
+	if config.Log == nil {
+		config.Log = log.New(io.Discard, "", 0)
+	}
+	if config.Dial == nil {
+		var dialer net.Dialer
+		config.Dial = func(ctx context.Context, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "tcp", addr)
+		}
+	}
+	conn, err := config.Dial(ctx, config.Addr)
+	if err != nil {
+		return nil, err
+	}
+	b := &FIXBroker{
+		SignalManager: &auto.SignalManager{},
+		config:        config,
+		conn:          conn,
+		inSeq:         1,
+		sent:          make(map[int]*Message),
+		quotes:        make(map[string]*quote),
+		orders:        make(map[string]*Order),
+		acks:          make(map[string]chan error),
+		loggedOn:      make(chan error, 1),
+		done:          make(chan struct{}),
+	}
+	resumed, err := b.loadSeqs()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	b.lastRecv.Store(time.Now().UnixNano())
+	go b.read()
+
+	logon := NewMessage(MsgLogon).
+		Add(TagEncryptMethod, "0").
+		Add(TagHeartBtInt, strconv.Itoa(int(config.HeartBtInt/time.Second)))
+	if !resumed {
+		logon.Add(TagResetSeqNumFlag, "Y")
+	}
+	if config.Username != "" {
+		logon.Add(TagUsername, config.Username)
+	}
+	if config.Password != "" {
+		logon.Add(TagPassword, config.Password)
+	}
+	if err := b.send(logon); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	select {
+	case err = <-b.loggedOn:
+	case <-b.done:
+		err = b.err
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("%w: %w", ErrNotLoggedOn, err)
+	}
+	go b.heartbeat()
+	return b, nil
+}
+
+// Logout ends the session and closes the connection.
+func (b *FIXBroker) Logout(ctx context.Context) error {
+	defer b.conn.Close()
+	if err := b.send(NewMessage(MsgLogout)); err != nil {
+		return err
+	}
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
+
+// Done returns a channel that is closed when the session ends, after which Err returns why.
+func (b *FIXBroker) Done() <-chan struct{} {
+	return b.done
+}
+
+// Err returns why the session ended, or nil if it is still running.
+func (b *FIXBroker) Err() error {
+	select {
+	case <-b.done:
+		return b.err
+	default:
+		return nil
+	}
+}
+
+// loadSeqs loads the sequence numbers of the SeqFile of the Config and returns true if the session resumes them.
+func (b *FIXBroker) loadSeqs() (bool, error) {
+	if b.config.SeqFile == "" {
+		return false, nil
+	}
+	data, err := os.ReadFile(b.config.SeqFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if _, err := fmt.Sscan(string(data), &b.seq, &b.inSeq); err != nil || b.seq < 0 || b.inSeq < 1 {
+		return false, fmt.Errorf("%w in %s: %q", ErrMalformedSeqNumber, b.config.SeqFile, data)
+	}
+	return true, nil
+}
+
+// saveSeqs saves the sequence numbers to the SeqFile of the Config, if there is one. seqMu must be held.
+func (b *FIXBroker) saveSeqs() {
+	if b.config.SeqFile == "" {
+		return
+	}
+	if err := os.WriteFile(b.config.SeqFile, []byte(fmt.Sprintf("%d %d\n", b.seq, b.inSeq)), 0o644); err != nil {
+		b.config.Log.Printf("error saving sequence numbers: %v", err)
+	}
+}
+
+// send fills in the header of msg and writes it to the session. Application messages are kept to be resent until ResendLimit more messages were sent.
+func (b *FIXBroker) send(msg *Message) error {
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+	b.seqMu.Lock()
+	b.seq++
+	seq := b.seq
+	b.saveSeqs()
+	b.seqMu.Unlock()
+	msg.Set(TagSenderCompID, b.config.SenderCompID).
+		Set(TagTargetCompID, b.config.TargetCompID).
+		Set(TagMsgSeqNum, strconv.Itoa(seq)).
+		Set(TagSendingTime, time.Now().UTC().Format(timeLayout))
+	if !isAdmin(msg.Type()) {
+		b.sent[seq] = msg
+		delete(b.sent, seq-b.config.ResendLimit)
+	}
+	return b.write(msg)
+}
+
+// write writes msg to the session as it is. writeMu must be held.
+func (b *FIXBroker) write(msg *Message) error {
+	b.lastSent.Store(time.Now().UnixNano())
+	_, err := b.conn.Write(msg.Bytes(b.config.BeginString))
+	return err
+}
+
+// isAdmin returns true if msgType is a message of the session layer, which is never resent.
+func isAdmin(msgType string) bool {
+	switch msgType {
+	case MsgHeartbeat, MsgTestRequest, MsgResendRequest, MsgReject, MsgSequenceReset, MsgLogout, MsgLogon:
+		return true
+	}
+	return false
+}
+
+// resend answers a ResendRequest for the messages from begin to end, or to the last message sent if end is zero. Kept application messages are resent as possible duplicates, and every other message is skipped with a SequenceReset in gap fill mode.
+func (b *FIXBroker) resend(begin, end int) {
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+	b.seqMu.Lock()
+	if end == 0 || end > b.seq {
+		end = b.seq
+	}
+	b.seqMu.Unlock()
+	gapFill := func(from, to int) error {
+		return b.write(NewMessage(MsgSequenceReset).
+			Add(TagSenderCompID, b.config.SenderCompID).
+			Add(TagTargetCompID, b.config.TargetCompID).
+			Add(TagMsgSeqNum, strconv.Itoa(from)).
+			Add(TagSendingTime, time.Now().UTC().Format(timeLayout)).
+			Add(TagPossDupFlag, "Y").
+			Add(TagGapFillFlag, "Y").
+			Add(TagNewSeqNo, strconv.Itoa(to)))
+	}
+	gap := 0 // gap is the first MsgSeqNum of the gap being skipped, or zero.
+	for seq := auto.Max(begin, 1); seq <= end; seq++ {
+		msg, ok := b.sent[seq]
+		if !ok {
+			if gap == 0 {
+				gap = seq
+			}
+			continue
+		}
+		if gap != 0 {
+			if err := gapFill(gap, seq); err != nil {
+				b.config.Log.Printf("error filling the gap of a resend: %v", err)
+				return
+			}
+			gap = 0
+		}
+		dup := &Message{Fields: append([]Field(nil), msg.Fields...)}
+		dup.Set(TagOrigSendingTime, msg.Get(TagSendingTime)).
+			Set(TagSendingTime, time.Now().UTC().Format(timeLayout)).
+			Set(TagPossDupFlag, "Y")
+		if err := b.write(dup); err != nil {
+			b.config.Log.Printf("error resending message %d: %v", seq, err)
+			return
+		}
+	}
+	if gap != 0 {
+		if err := gapFill(gap, end+1); err != nil {
+			b.config.Log.Printf("error filling the gap of a resend: %v", err)
+		}
+	}
+}
+
+// heartbeat sends a Heartbeat whenever nothing was sent for HeartBtInt, and a TestRequest once nothing was received for HeartBtInt and a fifth, until the session ends. If the TestRequest is not answered within another HeartBtInt, the session ends with ErrHeartbeatTimeout.
+func (b *FIXBroker) heartbeat() {
+	interval := b.config.HeartBtInt
+	ticker := time.NewTicker(interval / 10)
+	defer ticker.Stop()
+	var testSent time.Time // testSent is when the outstanding TestRequest was sent, or zero.
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.done:
+			return
+		}
+		now := time.Now()
+		lastRecv := time.Unix(0, b.lastRecv.Load())
+		if lastRecv.After(testSent) {
+			testSent = time.Time{} // Anything received answers the TestRequest.
+		}
+		switch {
+		case !testSent.IsZero() && now.Sub(testSent) > interval:
+			b.end(fmt.Errorf("%w: nothing received for %s", ErrHeartbeatTimeout, now.Sub(lastRecv).Round(time.Millisecond)), false)
+			return
+		case testSent.IsZero() && now.Sub(lastRecv) > interval+interval/5:
+			if err := b.send(NewMessage(MsgTestRequest).Add(TagTestReqID, strconv.FormatInt(now.UnixNano(), 10))); err != nil {
+				b.config.Log.Printf("error sending test request: %v", err)
+			}
+			testSent = now
+		case now.Sub(time.Unix(0, b.lastSent.Load())) >= interval:
+			if err := b.send(NewMessage(MsgHeartbeat)); err != nil {
+				b.config.Log.Printf("error sending heartbeat: %v", err)
+			}
+		}
+	}
+}
+
+// end ends the session because of err, which Err returns once the session has ended. A Logout with the reason is sent first if logout is true.
+func (b *FIXBroker) end(err error, logout bool) {
+	b.config.Log.Printf("ending FIX session: %v", err)
+	b.mu.Lock()
+	if b.reason == nil {
+		b.reason = err
+	}
+	b.mu.Unlock()
+	if logout {
+		b.conn.SetWriteDeadline(time.Now().Add(time.Second))
+		b.send(NewMessage(MsgLogout).Add(TagText, err.Error()))
+	}
+	b.conn.Close()
+}
+
+// read handles the messages of the session until the connection is closed.
+func (b *FIXBroker) read() {
+	r := bufio.NewReader(b.conn)
+	for {
+		msg, err := ReadMessage(r)
+		if err != nil {
+			b.mu.Lock()
+			if b.reason != nil {
+				err = b.reason
+			}
+			b.mu.Unlock()
+			b.err = err
+			close(b.done)
+			return
+		}
+		b.lastRecv.Store(time.Now().UnixNano())
+		if b.sequence(msg) {
+			b.handle(msg)
+		}
+	}
+}
+
+// sequence checks the MsgSeqNum of msg against the one expected and returns true if msg should be handled. A gap is filled with a ResendRequest, and only session messages that cannot wait are handled until it is filled.
+func (b *FIXBroker) sequence(msg *Message) bool {
+	seq, err := strconv.Atoi(msg.Get(TagMsgSeqNum))
+	if err != nil {
+		b.config.Log.Printf("FIX message without a MsgSeqNum: %v", msg)
+		return false
+	}
+	msgType := msg.Type()
+	if msgType == MsgLogon && msg.Get(TagResetSeqNumFlag) == "Y" {
+		b.setInSeq(seq)
+	}
+	if msgType == MsgSequenceReset && msg.Get(TagGapFillFlag) != "Y" {
+		// A reset of the sequence numbers, which ignores the MsgSeqNum of the message.
+		if newSeq, err := strconv.Atoi(msg.Get(TagNewSeqNo)); err == nil && newSeq > b.inSeq {
+			b.setInSeq(newSeq)
+		}
+		return false
+	}
+	switch {
+	case seq < b.inSeq:
+		if msg.Get(TagPossDupFlag) != "Y" {
+			b.end(fmt.Errorf("%w: got %d, expected %d", ErrSequence, seq, b.inSeq), true)
+		}
+		return false // A duplicate of a message that was already handled.
+	case seq > b.inSeq:
+		if b.resendTo == 0 {
+			b.resendTo = seq
+			if err := b.send(NewMessage(MsgResendRequest).Add(TagBeginSeqNo, strconv.Itoa(b.inSeq)).Add(TagEndSeqNo, "0")); err != nil {
+				b.config.Log.Printf("error requesting a resend: %v", err)
+			}
+		}
+		return msgType == MsgLogon || msgType == MsgLogout || msgType == MsgResendRequest
+	}
+	next := seq + 1
+	if msgType == MsgSequenceReset { // A gap fill of messages that will not be resent.
+		if newSeq, err := strconv.Atoi(msg.Get(TagNewSeqNo)); err == nil && newSeq > next {
+			next = newSeq
+		}
+	}
+	b.setInSeq(next)
+	if b.resendTo != 0 && b.inSeq > b.resendTo {
+		b.resendTo = 0
+	}
+	return msgType != MsgSequenceReset
+}
+
+// setInSeq sets the MsgSeqNum expected of the next message received and saves it.
+func (b *FIXBroker) setInSeq(seq int) {
+	b.seqMu.Lock()
+	b.inSeq = seq
+	b.saveSeqs()
+	b.seqMu.Unlock()
+}
+
+func (b *FIXBroker) handle(msg *Message) {
+	switch msg.Type() {
+	case MsgLogon:
+		select {
+		case b.loggedOn <- nil:
+		default:
+		}
+	case MsgLogout:
+		select {
+		case b.loggedOn <- fmt.Errorf("%w: %s", ErrRejected, msg.Get(TagText)):
+		default:
+		}
+		b.conn.Close()
+	case MsgTestRequest:
+		if err := b.send(NewMessage(MsgHeartbeat).Add(TagTestReqID, msg.Get(TagTestReqID))); err != nil {
+			b.config.Log.Printf("error answering test request: %v", err)
+		}
+	case MsgResendRequest:
+		begin, _ := strconv.Atoi(msg.Get(TagBeginSeqNo))
+		end, _ := strconv.Atoi(msg.Get(TagEndSeqNo))
+		b.resend(begin, end)
+	case MsgMarketDataSnapshot, MsgMarketDataIncrement:
+		b.marketData(msg)
+	case MsgExecutionReport:
+		b.execution(msg)
+	case MsgOrderCancelReject:
+		b.ack(msg.Get(TagClOrdID), fmt.Errorf("%w: %s", auto.ErrCancelFailed, msg.Get(TagText)))
+	case MsgReject, MsgBusinessMessageReject, MsgMarketDataReqReject:
+		b.config.Log.Printf("FIX reject: %v", msg)
+	}
+}
+
+// marketData updates the quotes from the bid and offer entries of a market data message.
+func (b *FIXBroker) marketData(msg *Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	symbol := msg.Get(TagSymbol)
+	var entryType string
+	for _, f := range msg.Fields {
+		switch f.Tag {
+		case TagSymbol:
+			symbol = f.Value // Incremental refreshes name the symbol of each entry.
+		case TagMDEntryType:
+			entryType = f.Value
+		case TagMDEntryPx:
+			price, err := strconv.ParseFloat(f.Value, 64)
+			if err != nil {
+				continue
+			}
+			q := b.quotes[symbol]
+			if q == nil {
+				q = &quote{}
+				b.quotes[symbol] = q
+			}
+			switch entryType {
+			case "0":
+				q.bid = price
+			case "1":
+				q.ask = price
+			}
+		}
+	}
+}
+
+// ack delivers the response to the request with clOrdID, if one is waiting for it.
+func (b *FIXBroker) ack(clOrdID string, err error) {
+	b.mu.Lock()
+	ch, ok := b.acks[clOrdID]
+	delete(b.acks, clOrdID)
+	b.mu.Unlock()
+	if ok {
+		ch <- err
+	}
+}
+
+// execution applies an execution report to its order and emits the resulting signals.
+func (b *FIXBroker) execution(msg *Message) {
+	clOrdID := msg.Get(TagClOrdID)
+	execType := msg.Get(TagExecType)
+	if execType == "8" { // Rejected
+		b.ack(clOrdID, fmt.Errorf("%w: %s", ErrRejected, msg.Get(TagText)))
+		return
+	}
+
+	b.mu.Lock()
+	o, ok := b.orders[clOrdID]
+	if !ok {
+		o, ok = b.orders[msg.Get(TagOrigClOrdID)] // Cancel reports name the cancel request.
+	}
+	if !ok {
+		b.mu.Unlock()
+		b.ack(clOrdID, nil)
+		return
+	}
+	placed := !o.placed && o.closes == nil // The first report of an order accepts it.
+	o.placed = true
+	var emit func()
+	switch execType {
+	case "F": // Trade
+		o.filledQty += msg.Float(TagLastQty)
+		if msg.Get(TagOrdStatus) == "2" && !o.filled {
+			emit = b.fill(o, msg.Float(TagAvgPx))
+		}
+	case "4", "C": // Canceled or Expired
+		if !o.cancelled {
+			o.cancelled = true
+			emit = func() { b.SignalEmit(auto.OrderCancelled, o) }
+		}
+	}
+	b.mu.Unlock()
+	// Handlers may call the broker, so signals are emitted without the lock. OrderPlaced is emitted before the request returns and before any fill.
+	if placed {
+		b.SignalEmit(auto.OrderPlaced, o)
+	}
+	b.ack(clOrdID, nil)
+	if emit != nil {
+		emit()
+	}
+}
+
+// fill marks o as filled at price, which either opens a position or closes the position o was sent to close. It returns a function that emits the signal. b.mu must be held.
+func (b *FIXBroker) fill(o *Order, price float64) func() {
+	o.filled = true
+	o.fillPrice = price
+	if p := o.closes; p != nil {
+		p.closed = true
+		p.closePrice = price
+		b.realizedPL += (price - p.entryPrice) * p.units
+		close(p.closedCh)
+		return func() { b.SignalEmit(auto.PositionClosed, p) }
+	}
+	p := &Position{
+		broker:     b,
+		id:         o.id,
+		symbol:     o.symbol,
+		units:      o.units,
+		entryPrice: price,
+		time:       time.Now(),
+		tags:       o.tags,
+		closedCh:   make(chan struct{}),
+	}
+	o.position = p
+	b.positions = append(b.positions, p)
+	return func() { b.SignalEmit(auto.OrderFulfilled, o) }
+}
+
+// newID returns a new ClOrdID.
+func (b *FIXBroker) newID() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextID++
+	return fmt.Sprintf("%s-%d-%d", b.config.SenderCompID, time.Now().Unix(), b.nextID)
+}
+
+// request sends msg and waits for the response to clOrdID.
+func (b *FIXBroker) request(ctx context.Context, clOrdID string, msg *Message) error {
+	ch := make(chan error, 1)
+	b.mu.Lock()
+	b.acks[clOrdID] = ch
+	b.mu.Unlock()
+	if err := b.send(msg); err != nil {
+		b.ack(clOrdID, nil)
+		return err
+	}
+	select {
+	case err := <-ch:
+		return err
+	case <-b.done:
+		return fmt.Errorf("%w: %w", ErrNotLoggedOn, b.err)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Subscribe requests a stream of the bid and offer of symbol, which updates the prices returned by Price.
+func (b *FIXBroker) Subscribe(symbol string) error {
+	b.mu.Lock()
+	if _, ok := b.quotes[symbol]; ok {
+		b.mu.Unlock()
+		return nil
+	}
+	b.quotes[symbol] = &quote{}
+	b.mu.Unlock()
+	return b.send(NewMessage(MsgMarketDataRequest).
+		Add(TagMDReqID, b.newID()).
+		Add(TagSubscriptionRequestType, "1"). // Snapshot and updates.
+		Add(TagMarketDepth, "1").
+		Add(TagMDUpdateType, "1").
+		Add(TagNoMDEntryTypes, "2").
+		Add(TagMDEntryType, "0").
+		Add(TagMDEntryType, "1").
+		Add(TagNoRelatedSym, "1").
+		Add(TagSymbol, symbol))
+}
+
+// Price returns the ask price if wantToBuy is true and the bid price if wantToBuy is false. The first request for a symbol subscribes to it and returns 0 until the first quote arrives.
+func (b *FIXBroker) Price(symbol string, wantToBuy bool) float64 {
+	b.mu.Lock()
+	q, ok := b.quotes[symbol]
+	var price float64
+	if ok {
+		price = q.bid
+		if wantToBuy {
+			price = q.ask
+		}
+	}
+	b.mu.Unlock()
+	if !ok {
+		if err := b.Subscribe(symbol); err != nil {
+			b.config.Log.Printf("error subscribing to %s: %v", symbol, err)
+		}
+	}
+	return price
+}
+
+func (b *FIXBroker) Bid(symbol string) float64 {
+	return b.Price(symbol, false)
+}
+
+func (b *FIXBroker) Ask(symbol string) float64 {
+	return b.Price(symbol, true)
+}
+
+// Candles returns the candles of the Data broker of the Config.
+func (b *FIXBroker) Candles(ctx context.Context, symbol, frequency string, count int) (*auto.IndexedFrame[auto.UnixTime], error) {
+	if b.config.Data == nil {
+		return nil, fmt.Errorf("%w: a FIX session does not provide candles without a Data broker", auto.ErrNoData)
+	}
+	return b.config.Data.Candles(ctx, symbol, frequency, count)
+}
+
+// Order sends a NewOrderSingle and returns once the acceptor has accepted or rejected it. OrderPlaced is emitted when the first execution report of the order arrives, and the order is filled when the reports of its trades arrive, which emits OrderFulfilled.
+func (b *FIXBroker) Order(ctx context.Context, orderType auto.OrderType, symbol string, units, price, stopLoss, takeProfit float64, options ...auto.OrderOption) (auto.Order, error) {
+	orderOptions := auto.NewOrderOptions(options...)
+	orderErr := func(err error, reason string) error {
+		return &auto.OrderError{Err: err, OrderType: orderType, Symbol: symbol, Units: units, Price: price, Reason: reason}
+	}
+	if stopLoss != 0 || takeProfit != 0 || orderOptions.TrailingStop.Value > 0 || orderOptions.BreakEven.Trigger > 0 {
+		return nil, orderErr(auto.ErrUnsupportedOrder, "FIX orders do not carry a stop loss or take profit")
+	}
+	instrument, ok := b.config.Instruments[symbol]
+	if !ok {
+		instrument.Symbol = symbol
+	}
+	if err := auto.ValidateOrder(instrument, orderType, units, price, 0, 0, b.Price(symbol, units > 0)); err != nil {
+		return nil, err
+	}
+	o := &Order{broker: b, id: b.newID(), symbol: symbol, orderType: orderType, units: units, price: price, time: time.Now(), tags: orderOptions.Tags}
+	msg := b.newOrderSingle(o.id, symbol, units)
+	switch orderType {
+	case auto.Market:
+		msg.Add(TagOrdType, "1")
+	case auto.Limit:
+		msg.Add(TagOrdType, "2").Add(TagPrice, formatFloat(price))
+	case auto.Stop:
+		msg.Add(TagOrdType, "3").Add(TagStopPx, formatFloat(price))
+	default:
+		return nil, orderErr(auto.ErrUnsupportedOrder, string(orderType))
+	}
+
+	// The order is registered before it is sent, since its execution reports may arrive before the request returns.
+	b.mu.Lock()
+	b.orders[o.id] = o
+	b.orderList = append(b.orderList, o)
+	b.mu.Unlock()
+	if err := b.request(ctx, o.id, msg); err != nil {
+		b.mu.Lock()
+		delete(b.orders, o.id)
+		if i := slices.Index(b.orderList, o); i >= 0 {
+			b.orderList = slices.Delete(b.orderList, i, i+1)
+		}
+		b.mu.Unlock()
+		return nil, orderErr(err, err.Error())
+	}
+	return o, nil
+}
+
+// newOrderSingle returns a NewOrderSingle for units of symbol without an OrdType.
+func (b *FIXBroker) newOrderSingle(clOrdID, symbol string, units float64) *Message {
+	return NewMessage(MsgNewOrderSingle).
+		Add(TagClOrdID, clOrdID).
+		Add(TagSymbol, symbol).
+		Add(TagSide, side(units)).
+		Add(TagOrderQty, formatFloat(math.Abs(units))).
+		Add(TagTransactTime, time.Now().UTC().Format(timeLayout))
+}
+
+// side returns the Side of an order for units, which is 1 to buy and 2 to sell.
+func side(units float64) string {
+	if units < 0 {
+		return "2"
+	}
+	return "1"
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// NAV returns the Balance of the Config plus the realized and unrealized profit and loss of the session.
+func (b *FIXBroker) NAV() float64 {
+	return b.config.Balance + b.PL()
+}
+
+// PL returns the realized and unrealized profit and loss of the positions of the session.
+func (b *FIXBroker) PL() float64 {
+	b.mu.Lock()
+	pl := b.realizedPL
+	positions := make([]*Position, len(b.positions))
+	copy(positions, b.positions)
+	b.mu.Unlock()
+	for _, p := range positions {
+		if !p.Closed() {
+			pl += p.PL()
+		}
+	}
+	return pl
+}
+
+func (b *FIXBroker) OpenOrders() []auto.Order {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var orders []auto.Order
+	for _, o := range b.orderList {
+		if !o.filled && !o.cancelled {
+			orders = append(orders, o)
+		}
+	}
+	return orders
+}
+
+func (b *FIXBroker) OpenPositions() []auto.Position {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var positions []auto.Position
+	for _, p := range b.positions {
+		if !p.closed {
+			positions = append(positions, p)
+		}
+	}
+	return positions
+}
+
+// Orders returns every order placed in the session.
+func (b *FIXBroker) Orders() []auto.Order {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	orders := make([]auto.Order, len(b.orderList))
+	for i, o := range b.orderList {
+		orders[i] = o
+	}
+	return orders
+}
+
+// Positions returns every position opened in the session.
+func (b *FIXBroker) Positions() []auto.Position {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	positions := make([]auto.Position, len(b.positions))
+	for i, p := range b.positions {
+		positions[i] = p
+	}
+	return positions
+}
+
+func (b *FIXBroker) OrderByID(_ context.Context, id string) (auto.Order, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if o, ok := b.orders[id]; ok && o.closes == nil {
+		return o, nil
+	}
+	return nil, auto.ErrOrderNotFound
+}
+
+func (b *FIXBroker) PositionByID(_ context.Context, id string) (auto.Position, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, p := range b.positions {
+		if p.id == id {
+			return p, nil
+		}
+	}
+	return nil, auto.ErrPositionNotFound
+}
+
+// Capabilities returns the capabilities of a FIX session, which places plain market, limit, and stop orders without exits and opens a position for every fill. Candles come from the Data broker of the Config, so its MinFrequency is reported and nothing is streamed.
+func (b *FIXBroker) Capabilities() auto.Capabilities {
+	capabilities := auto.Capabilities{
+		OrderTypes: []auto.OrderType{auto.Market, auto.Limit, auto.Stop},
+		Hedging:    true,
+	}
+	if b.config.Data != nil {
+		capabilities.MinFrequency = b.config.Data.Capabilities().MinFrequency
+	}
+	return capabilities
+}
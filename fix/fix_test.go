@@ -0,0 +1,352 @@
+package fix
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	auto "github.com/fivemoreminix/autotrader"
+)
+
+func TestMessage(t *testing.T) {
+	msg := NewMessage(MsgNewOrderSingle).Add(TagClOrdID, "1").Add(TagSymbol, "EUR/USD").Add(TagMDEntryType, "0").Add(TagMDEntryType, "1")
+	data := msg.Bytes(BeginStringFIX44)
+	read, err := ReadMessage(bufio.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.String() != msg.String() {
+		t.Errorf("Expected %s, got %s", msg, read)
+	}
+	data[len(data)-2]++ // Corrupt the checksum.
+	if _, err := ReadMessage(bufio.NewReader(bytes.NewReader(data))); !errors.Is(err, ErrBadChecksum) {
+		t.Errorf("Expected ErrBadChecksum, got %v", err)
+	}
+}
+
+// counterparty is the acceptor side of a test session, which numbers the messages it sends.
+type counterparty struct {
+	conn net.Conn
+	r    *bufio.Reader
+	seq  int
+}
+
+func newCounterparty(conn net.Conn) *counterparty {
+	return &counterparty{conn: conn, r: bufio.NewReader(conn)}
+}
+
+// send sends msg with the next MsgSeqNum.
+func (c *counterparty) send(msg *Message) {
+	c.seq++
+	c.conn.Write(msg.Set(TagMsgSeqNum, strconv.Itoa(c.seq)).Bytes(BeginStringFIX44))
+}
+
+func (c *counterparty) read() (*Message, error) {
+	return ReadMessage(c.r)
+}
+
+// acceptor answers the messages of a session over conn like a minimal FIX acceptor that fills every order at 1.1.
+func acceptor(t *testing.T, conn net.Conn) {
+	c := newCounterparty(conn)
+	for {
+		msg, err := c.read()
+		if err != nil {
+			return
+		}
+		switch msg.Type() {
+		case MsgLogon:
+			c.send(NewMessage(MsgLogon).Add(TagResetSeqNumFlag, msg.Get(TagResetSeqNumFlag)))
+		case MsgMarketDataRequest:
+			c.send(NewMessage(MsgMarketDataSnapshot).Add(TagSymbol, msg.Get(TagSymbol)).Add(TagNoMDEntries, "2").
+				Add(TagMDEntryType, "0").Add(TagMDEntryPx, "1.1").Add(TagMDEntryType, "1").Add(TagMDEntryPx, "1.2"))
+		case MsgNewOrderSingle:
+			if msg.Get(TagSymbol) == "BAD" {
+				c.send(NewMessage(MsgExecutionReport).Add(TagClOrdID, msg.Get(TagClOrdID)).Add(TagExecType, "8").Add(TagText, "unknown symbol"))
+				continue
+			}
+			c.send(NewMessage(MsgExecutionReport).Add(TagClOrdID, msg.Get(TagClOrdID)).Add(TagExecType, "F").Add(TagOrdStatus, "2").
+				Add(TagLastQty, msg.Get(TagOrderQty)).Add(TagLastPx, "1.1").Add(TagAvgPx, "1.1"))
+		case MsgLogout:
+			c.send(NewMessage(MsgLogout))
+			conn.Close()
+			return
+		}
+	}
+}
+
+// dialPipe logs a broker on to a session with the counterparty run by serve over an in-memory connection.
+func dialPipe(t *testing.T, ctx context.Context, config Config, serve func(net.Conn)) (*FIXBroker, error) {
+	t.Helper()
+	client, server := net.Pipe()
+	go serve(server)
+	config.SenderCompID, config.TargetCompID = "CLIENT", "BROKER"
+	config.Dial = func(context.Context, string) (net.Conn, error) {
+		return client, nil
+	}
+	return Dial(ctx, config)
+}
+
+func TestFIXBroker(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	broker, err := dialPipe(t, ctx, Config{Balance: 10_000}, func(conn net.Conn) { acceptor(t, conn) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := broker.Subscribe("EUR/USD"); err != nil {
+		t.Fatal(err)
+	}
+	for broker.Ask("EUR/USD") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	filled := make(chan auto.Order, 1)
+	var signals []string
+	broker.SignalConnect(auto.OrderPlaced, t, func(args ...any) { signals = append(signals, auto.OrderPlaced) })
+	broker.SignalConnect(auto.OrderFulfilled, t, func(args ...any) {
+		signals = append(signals, auto.OrderFulfilled)
+		filled <- args[0].(auto.Order)
+	})
+	order, err := broker.Order(ctx, auto.Market, "EUR/USD", 1000, 0, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o := <-filled; o != order || !order.Fulfilled() || order.Position().EntryPrice() != 1.1 {
+		t.Errorf("Expected the order to be filled at 1.1, got %v", order.Position())
+	}
+	if len(signals) != 2 || signals[0] != auto.OrderPlaced {
+		t.Errorf("Expected OrderPlaced before OrderFulfilled, got %v", signals)
+	}
+	if positions := broker.OpenPositions(); len(positions) != 1 {
+		t.Fatalf("Expected 1 open position, got %d", len(positions))
+	}
+	if err := order.Position().Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !order.Position().Closed() || len(broker.OpenPositions()) != 0 || broker.NAV() != 10_000 {
+		t.Errorf("Expected the position to be closed at its entry, got NAV %f", broker.NAV())
+	}
+
+	if _, err := broker.Order(ctx, auto.Market, "BAD", 1000, 0, 0, 0); !errors.Is(err, ErrRejected) {
+		t.Errorf("Expected ErrRejected, got %v", err)
+	}
+	if _, err := broker.Order(ctx, auto.Market, "EUR/USD", 1000, 0, 1.0, 0); !errors.Is(err, auto.ErrUnsupportedOrder) {
+		t.Errorf("Expected ErrUnsupportedOrder with a stop loss, got %v", err)
+	}
+	if err := broker.Logout(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFIXSequenceGap(t *testing.T) {
+	resent := make(chan *Message, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	broker, err := dialPipe(t, ctx, Config{}, func(conn net.Conn) {
+		c := newCounterparty(conn)
+		c.read() // Logon
+		c.send(NewMessage(MsgLogon).Add(TagResetSeqNumFlag, "Y"))
+		c.seq++ // Skip a message, which the broker must ask to be resent.
+		c.send(NewMessage(MsgMarketDataSnapshot).Add(TagSymbol, "EUR/USD").Add(TagMDEntryType, "0").Add(TagMDEntryPx, "1.5"))
+		for {
+			msg, err := c.read()
+			if err != nil {
+				return
+			}
+			switch msg.Type() {
+			case MsgResendRequest:
+				resent <- msg // Skip the stale quote, and send a fresh one after the gap fill.
+				reset := NewMessage(MsgSequenceReset).Add(TagGapFillFlag, "Y").Add(TagNewSeqNo, "4").Add(TagPossDupFlag, "Y").Set(TagMsgSeqNum, "2")
+				conn.Write(reset.Bytes(BeginStringFIX44))
+				c.send(NewMessage(MsgMarketDataSnapshot).Add(TagSymbol, "EUR/USD").Add(TagMDEntryType, "0").Add(TagMDEntryPx, "1.2"))
+			case MsgLogout:
+				conn.Close()
+				return
+			}
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := <-resent
+	if msg.Get(TagBeginSeqNo) != "2" || msg.Get(TagEndSeqNo) != "0" {
+		t.Errorf("Expected a resend from 2, got %v", msg)
+	}
+	for broker.Bid("EUR/USD") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if bid := broker.Bid("EUR/USD"); bid != 1.2 {
+		t.Errorf("Expected the quote out of sequence to be ignored and the next one handled, got a bid of %v", bid)
+	}
+	broker.Logout(ctx)
+}
+
+func TestFIXSequenceTooLow(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	broker, err := dialPipe(t, ctx, Config{}, func(conn net.Conn) {
+		c := newCounterparty(conn)
+		c.read() // Logon
+		c.send(NewMessage(MsgLogon).Add(TagResetSeqNumFlag, "Y"))
+		c.seq = 0
+		c.send(NewMessage(MsgHeartbeat)) // Repeats MsgSeqNum 1 without PossDupFlag.
+		for {
+			if _, err := c.read(); err != nil {
+				return
+			}
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-broker.Done()
+	if !errors.Is(broker.Err(), ErrSequence) {
+		t.Errorf("Expected the session to end with ErrSequence, got %v", broker.Err())
+	}
+}
+
+// resendOrder places an order with a broker of config and returns the first n messages it sends in answer to a ResendRequest from 1.
+func resendOrder(t *testing.T, config Config, n int) []*Message {
+	t.Helper()
+	resent := make(chan []*Message, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	broker, err := dialPipe(t, ctx, config, func(conn net.Conn) {
+		c := newCounterparty(conn)
+		c.read() // Logon
+		c.send(NewMessage(MsgLogon).Add(TagResetSeqNumFlag, "Y"))
+		order, _ := c.read()
+		for order != nil && order.Type() != MsgNewOrderSingle { // Skip the MarketDataRequest of the price of the order.
+			order, _ = c.read()
+		}
+		c.send(NewMessage(MsgExecutionReport).Add(TagClOrdID, order.Get(TagClOrdID)).Add(TagExecType, "0").Add(TagOrdStatus, "0"))
+		c.send(NewMessage(MsgResendRequest).Add(TagBeginSeqNo, "1").Add(TagEndSeqNo, "0"))
+		var msgs []*Message
+		for len(msgs) < n {
+			msg, err := c.read()
+			if err != nil {
+				return
+			}
+			msgs = append(msgs, msg)
+		}
+		resent <- msgs
+		for {
+			if _, err := c.read(); err != nil {
+				return
+			}
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer broker.conn.Close()
+	if _, err := broker.Order(ctx, auto.Limit, "EUR/USD", 1000, 1.0, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	return <-resent
+}
+
+func TestFIXResendRequest(t *testing.T) {
+	msgs := resendOrder(t, Config{}, 3)
+	if reset := msgs[0]; reset.Type() != MsgSequenceReset || reset.Get(TagMsgSeqNum) != "1" || reset.Get(TagGapFillFlag) != "Y" || reset.Get(TagNewSeqNo) != "2" {
+		t.Errorf("Expected the Logon to be skipped with a gap fill, got %v", reset)
+	}
+	if request := msgs[1]; request.Type() != MsgMarketDataRequest || request.Get(TagMsgSeqNum) != "2" || request.Get(TagPossDupFlag) != "Y" {
+		t.Errorf("Expected the MarketDataRequest to be resent as a possible duplicate, got %v", request)
+	}
+	if order := msgs[2]; order.Type() != MsgNewOrderSingle || order.Get(TagMsgSeqNum) != "3" || order.Get(TagPossDupFlag) != "Y" || order.Get(TagOrigSendingTime) == "" {
+		t.Errorf("Expected the order to be resent as a possible duplicate, got %v", order)
+	}
+
+	msgs = resendOrder(t, Config{ResendLimit: 1}, 2)
+	if reset := msgs[0]; reset.Type() != MsgSequenceReset || reset.Get(TagMsgSeqNum) != "1" || reset.Get(TagNewSeqNo) != "3" {
+		t.Errorf("Expected the messages before the last to be skipped with a gap fill, got %v", reset)
+	}
+	if order := msgs[1]; order.Type() != MsgNewOrderSingle || order.Get(TagMsgSeqNum) != "3" {
+		t.Errorf("Expected the last message to be resent, got %v", order)
+	}
+}
+
+func TestFIXHeartbeatTimeout(t *testing.T) {
+	testRequests := make(chan *Message, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	broker, err := dialPipe(t, ctx, Config{HeartBtInt: 50 * time.Millisecond}, func(conn net.Conn) {
+		c := newCounterparty(conn)
+		c.read() // Logon
+		c.send(NewMessage(MsgLogon).Add(TagResetSeqNumFlag, "Y"))
+		for { // Stop responding.
+			msg, err := c.read()
+			if err != nil {
+				return
+			}
+			if msg.Type() == MsgTestRequest {
+				select {
+				case testRequests <- msg:
+				default:
+				}
+			}
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-broker.Done():
+	case <-ctx.Done():
+		t.Fatal("Expected the session to end when the counterparty stops responding")
+	}
+	if len(testRequests) != 1 {
+		t.Error("Expected a TestRequest before the session ended")
+	}
+	if !errors.Is(broker.Err(), ErrHeartbeatTimeout) {
+		t.Errorf("Expected ErrHeartbeatTimeout, got %v", broker.Err())
+	}
+}
+
+func TestFIXSeqFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seqs")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	broker, err := dialPipe(t, ctx, Config{SeqFile: path}, func(conn net.Conn) { acceptor(t, conn) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := broker.Order(ctx, auto.Market, "EUR/USD", 1000, 0, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := broker.Logout(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "4 5\n" {
+		t.Fatalf("Expected 4 messages sent and 4 received, got %q (%v)", data, err)
+	}
+
+	logons := make(chan *Message, 1)
+	broker, err = dialPipe(t, ctx, Config{SeqFile: path}, func(conn net.Conn) {
+		c := newCounterparty(conn)
+		c.seq = 4
+		logon, _ := c.read()
+		logons <- logon
+		c.send(NewMessage(MsgLogon))
+		for {
+			if _, err := c.read(); err != nil {
+				return
+			}
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if logon := <-logons; logon.Get(TagMsgSeqNum) != "5" || logon.Get(TagResetSeqNumFlag) != "" {
+		t.Errorf("Expected the Logon to resume at 5 without a reset, got %v", logon)
+	}
+	broker.conn.Close()
+}
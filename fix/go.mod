@@ -0,0 +1,3 @@
+module github.com/fivemoreminix/autotrader/fix
+
+go 1.20
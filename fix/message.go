@@ -0,0 +1,229 @@
+package fix
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const soh = '\x01' // soh separates the fields of a message.
+
+// Tags of the fields used by the adapter.
+const (
+	TagAvgPx                   = 6
+	TagBeginSeqNo              = 7
+	TagBeginString             = 8
+	TagBodyLength              = 9
+	TagCheckSum                = 10
+	TagClOrdID                 = 11
+	TagCumQty                  = 14
+	TagEndSeqNo                = 16
+	TagLastPx                  = 31
+	TagLastQty                 = 32
+	TagMsgSeqNum               = 34
+	TagMsgType                 = 35
+	TagNewSeqNo                = 36
+	TagOrderID                 = 37
+	TagOrderQty                = 38
+	TagOrdStatus               = 39
+	TagOrdType                 = 40
+	TagOrigClOrdID             = 41
+	TagPossDupFlag             = 43
+	TagPrice                   = 44
+	TagSenderCompID            = 49
+	TagSendingTime             = 52
+	TagSide                    = 54
+	TagSymbol                  = 55
+	TagTargetCompID            = 56
+	TagText                    = 58
+	TagTransactTime            = 60
+	TagEncryptMethod           = 98
+	TagStopPx                  = 99
+	TagHeartBtInt              = 108
+	TagTestReqID               = 112
+	TagOrigSendingTime         = 122
+	TagGapFillFlag             = 123
+	TagResetSeqNumFlag         = 141
+	TagNoRelatedSym            = 146
+	TagExecType                = 150
+	TagLeavesQty               = 151
+	TagMDReqID                 = 262
+	TagSubscriptionRequestType = 263
+	TagMarketDepth             = 264
+	TagMDUpdateType            = 265
+	TagNoMDEntryTypes          = 267
+	TagNoMDEntries             = 268
+	TagMDEntryType             = 269
+	TagMDEntryPx               = 270
+	TagMDUpdateAction          = 279
+	TagUsername                = 553
+	TagPassword                = 554
+)
+
+// Message types used by the adapter.
+const (
+	MsgHeartbeat             = "0"
+	MsgTestRequest           = "1"
+	MsgResendRequest         = "2"
+	MsgReject                = "3"
+	MsgSequenceReset         = "4"
+	MsgLogout                = "5"
+	MsgExecutionReport       = "8"
+	MsgOrderCancelReject     = "9"
+	MsgLogon                 = "A"
+	MsgNewOrderSingle        = "D"
+	MsgOrderCancelRequest    = "F"
+	MsgMarketDataRequest     = "V"
+	MsgMarketDataSnapshot    = "W"
+	MsgMarketDataIncrement   = "X"
+	MsgMarketDataReqReject   = "Y"
+	MsgBusinessMessageReject = "j"
+)
+
+var (
+	ErrMalformedMessage = errors.New("malformed FIX message")
+	ErrBadChecksum      = errors.New("FIX message checksum mismatch")
+)
+
+// Field is a tag and value of a message.
+type Field struct {
+	Tag   int
+	Value string
+}
+
+// Message is a FIX message as an ordered list of fields, which keeps the order of repeating groups like market data entries. The BeginString, BodyLength, and CheckSum fields are added by Bytes and removed by ReadMessage.
+type Message struct {
+	Fields []Field
+}
+
+// NewMessage returns a message of msgType.
+func NewMessage(msgType string) *Message {
+	return &Message{Fields: []Field{{TagMsgType, msgType}}}
+}
+
+// Add appends a field to the message, even if the tag is already set, which is how repeating groups are built.
+func (m *Message) Add(tag int, value string) *Message {
+	m.Fields = append(m.Fields, Field{tag, value})
+	return m
+}
+
+// Set replaces the value of the first field with tag, or appends the field if the message does not have it.
+func (m *Message) Set(tag int, value string) *Message {
+	for i := range m.Fields {
+		if m.Fields[i].Tag == tag {
+			m.Fields[i].Value = value
+			return m
+		}
+	}
+	return m.Add(tag, value)
+}
+
+// Get returns the value of the first field with tag, or an empty string if the message does not have it.
+func (m *Message) Get(tag int) string {
+	for _, f := range m.Fields {
+		if f.Tag == tag {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// Float returns the value of the first field with tag as a float64, or 0 if it is missing or not a number.
+func (m *Message) Float(tag int) float64 {
+	v, _ := strconv.ParseFloat(m.Get(tag), 64)
+	return v
+}
+
+// Type returns the MsgType of the message.
+func (m *Message) Type() string {
+	return m.Get(TagMsgType)
+}
+
+// String returns the message with the fields separated by '|' for logging.
+func (m *Message) String() string {
+	var sb strings.Builder
+	for i, f := range m.Fields {
+		if i > 0 {
+			sb.WriteByte('|')
+		}
+		fmt.Fprintf(&sb, "%d=%s", f.Tag, f.Value)
+	}
+	return sb.String()
+}
+
+// Bytes returns the message encoded for the wire with the BeginString of beginString and the BodyLength and CheckSum calculated. MsgType is always the first field of the body.
+func (m *Message) Bytes(beginString string) []byte {
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "%d=%s%c", TagMsgType, m.Type(), soh)
+	for _, f := range m.Fields {
+		if f.Tag != TagMsgType {
+			fmt.Fprintf(&body, "%d=%s%c", f.Tag, f.Value, soh)
+		}
+	}
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "%d=%s%c%d=%d%c", TagBeginString, beginString, soh, TagBodyLength, body.Len(), soh)
+	out.Write(body.Bytes())
+	fmt.Fprintf(&out, "%d=%03d%c", TagCheckSum, checksum(out.Bytes()), soh)
+	return out.Bytes()
+}
+
+// checksum returns the sum of the bytes modulo 256.
+func checksum(b []byte) int {
+	var sum int
+	for _, c := range b {
+		sum += int(c)
+	}
+	return sum % 256
+}
+
+// ReadMessage reads the next message from r and checks its BodyLength and CheckSum.
+func ReadMessage(r *bufio.Reader) (*Message, error) {
+	begin, err := r.ReadBytes(soh)
+	if err != nil {
+		return nil, err
+	}
+	length, err := r.ReadBytes(soh)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(begin, []byte("8=")) || !bytes.HasPrefix(length, []byte("9=")) {
+		return nil, fmt.Errorf("%w: expected BeginString and BodyLength, got %q", ErrMalformedMessage, append(begin, length...))
+	}
+	n, err := strconv.Atoi(string(length[2 : len(length)-1]))
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("%w: invalid BodyLength %q", ErrMalformedMessage, length)
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	trailer, err := r.ReadBytes(soh)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(trailer, []byte("10=")) {
+		return nil, fmt.Errorf("%w: expected CheckSum, got %q", ErrMalformedMessage, trailer)
+	}
+	sum, err := strconv.Atoi(string(trailer[3 : len(trailer)-1]))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid CheckSum %q", ErrMalformedMessage, trailer)
+	}
+	if expected := checksum(append(append(begin, length...), body...)); sum != expected {
+		return nil, fmt.Errorf("%w: got %03d, expected %03d", ErrBadChecksum, sum, expected)
+	}
+
+	msg := &Message{}
+	for _, field := range bytes.Split(bytes.TrimSuffix(body, []byte{soh}), []byte{soh}) {
+		tag, value, ok := bytes.Cut(field, []byte("="))
+		t, err := strconv.Atoi(string(tag))
+		if !ok || err != nil {
+			return nil, fmt.Errorf("%w: invalid field %q", ErrMalformedMessage, field)
+		}
+		msg.Fields = append(msg.Fields, Field{t, string(value)})
+	}
+	return msg, nil
+}
@@ -0,0 +1,247 @@
+package fix
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	auto "github.com/fivemoreminix/autotrader"
+)
+
+var (
+	_ auto.Order    = (*Order)(nil) // Compile-time interface check.
+	_ auto.Position = (*Position)(nil)
+)
+
+// Order is an order sent over a FIX session.
+type Order struct {
+	broker    *FIXBroker
+	id        string // id is the ClOrdID of the order.
+	symbol    string
+	orderType auto.OrderType
+	units     float64
+	price     float64
+	time      time.Time
+	tags      auto.Tags
+	filledQty float64
+	fillPrice float64
+	placed    bool // placed is true once the acceptor has reported on the order.
+	filled    bool
+	cancelled bool
+	position  *Position
+	closes    *Position // closes is the position the order was sent to close.
+}
+
+// Cancel sends an OrderCancelRequest and returns once the acceptor has cancelled the order or rejected the request.
+func (o *Order) Cancel() error {
+	b := o.broker
+	b.mu.Lock()
+	done := o.filled || o.cancelled
+	b.mu.Unlock()
+	if done {
+		return auto.ErrCancelFailed
+	}
+	cancelID := b.newID()
+	msg := NewMessage(MsgOrderCancelRequest).
+		Add(TagOrigClOrdID, o.id).
+		Add(TagClOrdID, cancelID).
+		Add(TagSymbol, o.symbol).
+		Add(TagSide, side(o.units)).
+		Add(TagOrderQty, formatFloat(math.Abs(o.units))).
+		Add(TagTransactTime, time.Now().UTC().Format(timeLayout))
+	if err := b.request(context.Background(), cancelID, msg); err != nil {
+		return fmt.Errorf("%w: %w", auto.ErrCancelFailed, err)
+	}
+	return nil
+}
+
+func (o *Order) Costs() auto.TradeCosts {
+	return auto.TradeCosts{} // Commissions are not reported in a standard way by FIX 4.4.
+}
+
+func (o *Order) Fulfilled() bool {
+	o.broker.mu.Lock()
+	defer o.broker.mu.Unlock()
+	return o.filled
+}
+
+func (o *Order) Id() string {
+	return o.id
+}
+
+func (o *Order) Leverage() float64 {
+	return 1
+}
+
+func (o *Order) Position() auto.Position {
+	o.broker.mu.Lock()
+	defer o.broker.mu.Unlock()
+	if o.position == nil {
+		return nil
+	}
+	return o.position
+}
+
+func (o *Order) Price() float64 {
+	return o.price
+}
+
+func (o *Order) Symbol() string {
+	return o.symbol
+}
+
+func (o *Order) TrailingStop() float64 {
+	return 0
+}
+
+func (o *Order) StopLoss() float64 {
+	return 0
+}
+
+func (o *Order) Tags() auto.Tags {
+	return o.tags
+}
+
+func (o *Order) TakeProfit() float64 {
+	return 0
+}
+
+func (o *Order) Time() time.Time {
+	return o.time
+}
+
+func (o *Order) Type() auto.OrderType {
+	return o.orderType
+}
+
+func (o *Order) Units() float64 {
+	return o.units
+}
+
+// Position is the position opened by a filled Order.
+type Position struct {
+	broker     *FIXBroker
+	id         string // id is the ClOrdID of the order that opened the position.
+	symbol     string
+	units      float64
+	entryPrice float64
+	time       time.Time
+	tags       auto.Tags
+	closed     bool
+	closePrice float64
+	closedCh   chan struct{} // closedCh is closed when the position is closed.
+}
+
+// Close sends a market order for the opposite units and waits for it to be filled, up to the CloseTimeout of the Config.
+func (p *Position) Close() error {
+	b := p.broker
+	b.mu.Lock()
+	if p.closed {
+		b.mu.Unlock()
+		return auto.ErrPositionClosed
+	}
+	b.mu.Unlock()
+
+	o := &Order{broker: b, id: b.newID(), symbol: p.symbol, orderType: auto.Market, units: -p.units, time: time.Now(), closes: p}
+	b.mu.Lock()
+	b.orders[o.id] = o
+	b.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), b.config.CloseTimeout)
+	defer cancel()
+	if err := b.request(ctx, o.id, b.newOrderSingle(o.id, p.symbol, -p.units).Add(TagOrdType, "1")); err != nil {
+		return err
+	}
+	select {
+	case <-p.closedCh:
+		return nil
+	case <-b.done:
+		return fmt.Errorf("%w: %w", ErrNotLoggedOn, b.err)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Position) Closed() bool {
+	p.broker.mu.Lock()
+	defer p.broker.mu.Unlock()
+	return p.closed
+}
+
+func (p *Position) CloseType() auto.OrderCloseType {
+	return auto.CloseMarket
+}
+
+func (p *Position) CloseCosts() auto.TradeCosts {
+	return auto.TradeCosts{}
+}
+
+func (p *Position) ClosePrice() float64 {
+	p.broker.mu.Lock()
+	defer p.broker.mu.Unlock()
+	return p.closePrice
+}
+
+func (p *Position) EntryPrice() float64 {
+	return p.entryPrice
+}
+
+func (p *Position) EntryValue() float64 {
+	return p.units * p.entryPrice
+}
+
+func (p *Position) Id() string {
+	return p.id
+}
+
+func (p *Position) Leverage() float64 {
+	return 1
+}
+
+// PL returns the profit or loss of the position at the price it could be closed at now, or at its close price once it is closed.
+func (p *Position) PL() float64 {
+	return (p.price() - p.entryPrice) * p.units
+}
+
+// price returns the close price of a closed position and the price to close it at otherwise.
+func (p *Position) price() float64 {
+	p.broker.mu.Lock()
+	closed, closePrice := p.closed, p.closePrice
+	p.broker.mu.Unlock()
+	if closed {
+		return closePrice
+	}
+	return p.broker.Price(p.symbol, p.units < 0)
+}
+
+func (p *Position) Symbol() string {
+	return p.symbol
+}
+
+func (p *Position) TrailingStop() float64 {
+	return 0
+}
+
+func (p *Position) StopLoss() float64 {
+	return 0
+}
+
+func (p *Position) Tags() auto.Tags {
+	return p.tags
+}
+
+func (p *Position) TakeProfit() float64 {
+	return 0
+}
+
+func (p *Position) Time() time.Time {
+	return p.time
+}
+
+func (p *Position) Units() float64 {
+	return p.units
+}
+
+func (p *Position) Value() float64 {
+	return p.units * p.price()
+}
@@ -1,13 +1,12 @@
 package autotrader
 
 import (
-	"bytes"
 	"fmt"
-	"strings"
-	"text/tabwriter"
+	"strconv"
 	"time"
 
 	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 )
 
 type Frame struct {
@@ -85,60 +84,53 @@ func (d *Frame) Select(names ...string) *Frame {
 //		1  2019-01-01  1     2     3    4      5
 //	    2  2019-01-02  4     5     6    7      8
 //
-// The order of the columns is not defined.
+// The Date, Open, High, Low, Close, and Volume columns come first, followed by the other columns in alphabetical order.
 //
-// If the Frame has more than 20 rows, the output will include the first ten rows and the last ten rows.
+// If the Frame has more than 20 rows, the output will include the first ten rows and the last ten rows. Use StringWith to change how the Frame is printed.
 func (d *Frame) String() string {
+	return d.StringWith(PrintOptions{})
+}
+
+// StringWith returns a string representation of the Frame like String, printed with options. For example, options can print a Markdown table of the Close column with four decimals.
+func (d *Frame) StringWith(options PrintOptions) string {
 	if d == nil {
 		return fmt.Sprintf("%T[nil]", d)
 	}
-	names := d.Names() // Defines the order of the columns.
+	if options.DateLayout == "" {
+		options.DateLayout = defaultDateLayout
+	}
+	names := options.columns(d.orderedNames()) // Defines the order of the columns.
 	series := make([]*Series, len(names))
 	for i, name := range names {
 		series[i] = d.Series(name)
 	}
 
-	buffer := new(bytes.Buffer)
-	t := tabwriter.NewWriter(buffer, 0, 0, 2, ' ', 0)
-	fmt.Fprintf(t, "%T[%dx%d]\n", d, d.Len(), len(d.series))
-	fmt.Fprintf(t, "\t%s\t\n", strings.Join(names, "\t"))
-
-	printRow := func(i int) {
-		row := make([]string, len(series))
-		for j, s := range series {
-			switch typ := s.Value(i).(type) {
-			case time.Time:
-				row[j] = typ.Format("2006-01-02 15:04:05")
-			case string:
-				row[j] = fmt.Sprintf("%q", typ)
-			default:
-				row[j] = fmt.Sprintf("%v", typ)
-			}
+	header := append([]string{""}, names...)
+	return options.table(fmt.Sprintf("%T[%dx%d]", d, d.Len(), len(d.series)), header, d.Len(), func(i int) []string {
+		row := []string{strconv.Itoa(i)}
+		for _, s := range series {
+			row = append(row, options.value(s.Value(i)))
 		}
-		fmt.Fprintf(t, "%d\t%s\t\n", i, strings.Join(row, "\t"))
-	}
+		return row
+	})
+}
 
-	// Print the first ten rows and the last ten rows if the Frame has more than 20 rows.
-	if d.Len() > 20 {
-		for i := 0; i < 10; i++ {
-			printRow(i)
+// orderedNames returns the names of the columns with the DOHLCV columns first and the rest in alphabetical order.
+func (d *Frame) orderedNames() []string {
+	var names []string
+	for _, name := range []string{"Date", "Open", "High", "Low", "Close", "Volume"} {
+		if d.Contains(name) {
+			names = append(names, name)
 		}
-		fmt.Fprintf(t, "...\t")
-		for range names {
-			fmt.Fprint(t, "\t") // Keeps alignment.
-		}
-		fmt.Fprintln(t) // Print new line character.
-		for i := 10; i > 0; i-- {
-			printRow(d.Len() - i)
-		}
-	} else {
-		for i := 0; i < d.Len(); i++ {
-			printRow(i)
+	}
+	rest := d.Names()
+	slices.Sort(rest)
+	for _, name := range rest {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
 		}
 	}
-
-	t.Flush()
-	return buffer.String()
+	return names
 }
 
 // Date returns the value of the Date column at index i. i is an EasyIndex. If i is out of bounds, time.Time{} is returned. This is equivalent to calling Time("Date", i).
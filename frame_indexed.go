@@ -1,26 +1,48 @@
 package autotrader
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
-	"strings"
-	"text/tabwriter"
+	"sort"
+	"strconv"
 	"time"
 
-	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+var (
+	ErrDuplicateCandle  = errors.New("candle already exists")
+	ErrOutOfOrderCandle = errors.New("candle is older than the latest candle")
+)
+
+// CandlePolicy decides what PushCandle does with a candle whose index already exists or is older than the latest candle, which happens when a live feed resends or corrects candles.
+type CandlePolicy int
+
+const (
+	CandleOverwrite CandlePolicy = iota // CandleOverwrite replaces an existing candle, or inserts an older candle in order. This is the default.
+	CandleSkip                          // CandleSkip ignores the candle.
+	CandleError                         // CandleError ignores the candle and returns ErrDuplicateCandle or ErrOutOfOrderCandle.
 )
 
 // It is worth mentioning that if you want to use time.Time as an index type, then you should use the public UnixTime as a Unix int64 time which can be converted back into a time.Time easily. See [time.Time](https://pkg.go.dev/time#Time) for more information on why you should not compare Time with == (or a map, which is what the IndexedFrame uses).
+//
+// Signals:
+//   - CandleOutOfOrder(index I) - Emitted by PushCandle when a candle older than the latest candle is pushed, whatever the OutOfOrderPolicy is.
+//   - CandleRevised(CandleRevision) - Emitted by a Trader on its Data after a candle it already had was revised by the broker, like a candle that closed before all of its ticks arrived. Stateful indicators can connect to it to recompute their values from the revised candle instead of drifting.
 type IndexedFrame[I Index] struct {
 	*SignalManager
+	DuplicatePolicy  CandlePolicy // DuplicatePolicy is what PushCandle does with a candle whose index already exists.
+	OutOfOrderPolicy CandlePolicy // OutOfOrderPolicy is what PushCandle does with a new candle that is older than the latest candle.
+
 	series map[string]*IndexedSeries[I]
+	names  []string // Names of the series in the order they were added.
 }
 
 // It is worth mentioning that if you want to use time.Time as an index type, then you should use int64 as a Unix time. See [time.Time](https://pkg.go.dev/time#Time) for more information on why you should not compare Time with == (or a map, which is what the IndexedFrame uses).
 func NewIndexedFrame[I Index](series ...*IndexedSeries[I]) *IndexedFrame[I] {
 	f := &IndexedFrame[I]{
-		&SignalManager{},
-		make(map[string]*IndexedSeries[I], len(series)),
+		SignalManager: &SignalManager{},
+		series:        make(map[string]*IndexedSeries[I], len(series)),
 	}
 	f.PushSeries(series...)
 	return f
@@ -51,13 +73,105 @@ func (f *IndexedFrame[I]) Copy() *IndexedFrame[I] {
 //	Copy(-1, 1) - copy the last row
 //	Copy(-10, -1) - copy the last 10 rows
 func (f *IndexedFrame[I]) CopyRange(start, count int) *IndexedFrame[I] {
-	out := &IndexedFrame[I]{SignalManager: &SignalManager{}}
-	for _, s := range f.series {
-		out.PushSeries(s.CopyRange(start, count))
+	out := &IndexedFrame[I]{SignalManager: &SignalManager{}, DuplicatePolicy: f.DuplicatePolicy, OutOfOrderPolicy: f.OutOfOrderPolicy}
+	for _, name := range f.names {
+		out.PushSeries(f.series[name].CopyRange(start, count))
+	}
+	return out
+}
+
+// View returns an IndexedFrame of the rows in the given range that shares the rows of this IndexedFrame instead of copying them, which makes it O(columns) rather than O(rows x columns) like CopyRange. start is an EasyIndex and count is the number of rows like CopyRange. Candles pushed to either frame are not seen by the other, but changing a value of the view changes this IndexedFrame too, so the view should be treated as read-only.
+func (f *IndexedFrame[I]) View(start, count int) *IndexedFrame[I] {
+	out := &IndexedFrame[I]{SignalManager: &SignalManager{}, DuplicatePolicy: f.DuplicatePolicy, OutOfOrderPolicy: f.OutOfOrderPolicy}
+	for _, name := range f.names {
+		out.PushSeries(f.series[name].View(start, count))
+	}
+	return out
+}
+
+// dropFront removes the first n rows without copying the rest, so a frame that is trimmed on every candle does not move its rows each time.
+func (f *IndexedFrame[I]) dropFront(n int) {
+	f.RemoveRange(0, n)
+}
+
+// RemoveRange deletes the rows in the given range from every series, like IndexedSeries.RemoveRange. Trimming the oldest rows of a frame that is used as a live buffer is O(count) rather than O(rows).
+func (f *IndexedFrame[I]) RemoveRange(start, count int) {
+	for _, name := range f.names {
+		f.series[name].RemoveRange(start, count)
+	}
+}
+
+// RemoveIndexes deletes the rows of every one of indexes that exists from every series in a single pass, like IndexedSeries.RemoveIndexes.
+func (f *IndexedFrame[I]) RemoveIndexes(indexes ...I) {
+	for _, name := range f.names {
+		f.series[name].RemoveIndexes(indexes...)
+	}
+}
+
+// Between returns a new IndexedFrame with a copy of the rows whose index is from start to end, inclusive, like IndexedSeries.Between. If no rows are in the range then an IndexedFrame is returned with a length of zero but with the same column names as the original.
+func (f *IndexedFrame[I]) Between(start, end I) *IndexedFrame[I] {
+	out := &IndexedFrame[I]{SignalManager: &SignalManager{}, DuplicatePolicy: f.DuplicatePolicy, OutOfOrderPolicy: f.OutOfOrderPolicy}
+	for _, name := range f.names {
+		out.PushSeries(f.series[name].Between(start, end))
 	}
 	return out
 }
 
+// SliceTime returns a new IndexedFrame with a copy of the candles from the time from up to but not including the time to, so consecutive windows do not share candles. The IndexedFrame must be indexed by UnixTime, UnixMilli, UnixNano, or int64 Unix seconds, otherwise it panics.
+func (f *IndexedFrame[I]) SliceTime(from, to time.Time) *IndexedFrame[I] {
+	return f.sliceRows(f.timeRows(from, to))
+}
+
+// Last returns a new IndexedFrame with a copy of the candles within d of the last candle, including the last candle. For example, Last(24 * time.Hour) of hourly candles returns the last 24 candles, or fewer if there are gaps like weekends. The IndexedFrame must be indexed by time like SliceTime.
+func (f *IndexedFrame[I]) Last(d time.Duration) *IndexedFrame[I] {
+	last := f.Index(-1)
+	if last == nil {
+		return f.sliceRows(0, 0)
+	}
+	end := indexTime(*last)
+	from, _ := f.timeRows(end.Add(-d), end)
+	// The candle exactly d before the last is outside the window.
+	if from < f.Len() && indexTime(*f.Index(from)).Equal(end.Add(-d)) {
+		from++
+	}
+	return f.sliceRows(from, f.Len())
+}
+
+// timeRows returns the first row at or after from and the first row at or after to.
+func (f *IndexedFrame[I]) timeRows(from, to time.Time) (start, end int) {
+	if len(f.names) == 0 {
+		return 0, 0
+	}
+	indexes := f.series[f.names[0]].indexes
+	start = sort.Search(len(indexes), func(i int) bool { return !indexTime(indexes[i]).Before(from) })
+	end = sort.Search(len(indexes), func(i int) bool { return !indexTime(indexes[i]).Before(to) })
+	return start, Max(start, end)
+}
+
+// sliceRows returns a new IndexedFrame with a copy of the rows from start up to end.
+func (f *IndexedFrame[I]) sliceRows(start, end int) *IndexedFrame[I] {
+	out := &IndexedFrame[I]{SignalManager: &SignalManager{}, DuplicatePolicy: f.DuplicatePolicy, OutOfOrderPolicy: f.OutOfOrderPolicy}
+	for _, name := range f.names {
+		out.PushSeries(f.series[name].CopyRange(start, end-start))
+	}
+	return out
+}
+
+// indexTime returns the time of an index of type UnixTime, UnixMilli, UnixNano, or int64 Unix seconds. It panics for other index types.
+func indexTime[I Index](index I) time.Time {
+	switch index := any(index).(type) {
+	case UnixTime:
+		return index.Time()
+	case UnixMilli:
+		return index.Time()
+	case UnixNano:
+		return index.Time()
+	case int64:
+		return time.Unix(index, 0)
+	}
+	panic(fmt.Errorf("autotrader: index of type %T is not a time", index))
+}
+
 // Len returns the number of rows in the IndexedFrame or 0 if the IndexedFrame has no rows. If the IndexedFrame has series of different lengths, then the longest length series is returned.
 func (f *IndexedFrame[I]) Len() int {
 	if len(f.series) == 0 {
@@ -90,62 +204,37 @@ func (f *IndexedFrame[I]) Select(names ...string) *IndexedFrame[I] {
 //		1  2019-01-01  1     2     3    4      5
 //	    2  2019-01-02  4     5     6    7      8
 //
-// The order of the columns is not defined.
+// The columns are in the order they were added to the IndexedFrame.
 //
-// If the IndexedFrame has more than 20 rows, the output will include the first ten rows and the last ten rows.
+// If the IndexedFrame has more than 20 rows, the output will include the first ten rows and the last ten rows. Use StringWith to change how the IndexedFrame is printed.
 func (f *IndexedFrame[I]) String() string {
+	return f.StringWith(PrintOptions{})
+}
+
+// StringWith returns a string representation of the IndexedFrame like String, printed with options. The DateLayout of options also formats indexes with a Time method, like UnixTime.
+func (f *IndexedFrame[I]) StringWith(options PrintOptions) string {
 	if f == nil {
 		return fmt.Sprintf("%T[nil]", f)
 	}
-	names := f.Names() // Defines the order of the columns.
+	names := options.columns(f.Names()) // Defines the order of the columns.
 	series := make([]*IndexedSeries[I], len(names))
 	for i, name := range names {
 		series[i] = f.Series(name)
 	}
 
-	buffer := new(bytes.Buffer)
-	t := tabwriter.NewWriter(buffer, 0, 0, 2, ' ', 0)
-	fmt.Fprintf(t, "%T[%dx%d]\n", f, f.Len(), len(series))
-	fmt.Fprintf(t, "[Row]\t[Index]\t%s\t\n", strings.Join(names, "\t"))
-
-	printRow := func(row int, index I) {
-		seriesVals := make([]string, len(series))
-		// For every IndexedSeries in the series slice...
-		for j, s := range series {
-			// Get the value at the row i.
-			i := s.Row(index)
-			switch typ := s.Value(i).(type) {
-			case string:
-				seriesVals[j] = fmt.Sprintf("%q", typ)
-			default:
-				seriesVals[j] = fmt.Sprintf("%v", typ)
-			}
-		}
-		fmt.Fprintf(t, "%d\t%v\t%s\t\n", row, index, strings.Join(seriesVals, "\t"))
+	var indexes []I
+	if len(f.names) > 0 {
+		indexes = f.series[f.names[0]].indexes
 	}
-
-	indexes := series[0].indexes
-	// Print the first ten rows and the last ten rows if the IndexedFrame has more than 20 rows.
-	if f.Len() > 20 {
-		for i := 0; i < 10; i++ {
-			printRow(i+1, indexes[i])
+	header := append([]string{"[Row]", "[Index]"}, names...)
+	return options.table(fmt.Sprintf("%T[%dx%d]", f, f.Len(), len(f.names)), header, len(indexes), func(i int) []string {
+		index := indexes[i]
+		row := []string{strconv.Itoa(i + 1), options.value(index)}
+		for _, s := range series {
+			row = append(row, options.value(s.ValueIndex(index)))
 		}
-		fmt.Fprintf(t, "...\t")
-		for range names {
-			fmt.Fprint(t, "\t") // Keeps alignment.
-		}
-		fmt.Fprintln(t) // Print new line character.
-		for i := 10; i > 0; i-- {
-			printRow(len(indexes)-i, indexes[len(indexes)-i])
-		}
-	} else {
-		for i := 0; i < f.Len(); i++ {
-			printRow(i+1, indexes[i])
-		}
-	}
-
-	t.Flush()
-	return buffer.String()
+		return row
+	})
 }
 
 func (f *IndexedFrame[I]) Index(row int) *I {
@@ -251,10 +340,28 @@ func (f *IndexedFrame[I]) ContainsDOHLCV() bool {
 }
 
 // PushCandle pushes a candlestick to the IndexedFrame. If the IndexedFrame does not contain the series "Date", "Open", "High", "Low", "Close", and "Volume", an error is returned.
+//
+// A candle whose index already exists is handled by the DuplicatePolicy, and a new candle that is older than the latest candle is handled by the OutOfOrderPolicy. Both overwrite or insert the candle by default.
 func (f *IndexedFrame[I]) PushCandle(date I, open, high, low, close float64, volume int64) error {
 	if !f.ContainsDOHLCV() {
 		return fmt.Errorf("IndexedFrame does not contain Open, High, Low, Close, Volume columns")
 	}
+	closes := f.series["Close"]
+	if latest := closes.Index(-1); latest != nil && date <= *latest {
+		if date < *latest {
+			f.SignalEmit("CandleOutOfOrder", date)
+		}
+		policy, err := f.OutOfOrderPolicy, ErrOutOfOrderCandle
+		if closes.Row(date) >= 0 {
+			policy, err = f.DuplicatePolicy, ErrDuplicateCandle
+		}
+		switch policy {
+		case CandleSkip:
+			return nil
+		case CandleError:
+			return fmt.Errorf("%w: %v", err, date)
+		}
+	}
 	f.series["Open"].Insert(date, open)
 	f.series["High"].Insert(date, high)
 	f.series["Low"].Insert(date, low)
@@ -276,6 +383,7 @@ func (f *IndexedFrame[I]) PushSeries(series ...*IndexedSeries[I]) error {
 		}
 		s.SignalConnect("NameChanged", f, f.onSeriesNameChanged, name)
 		f.series[name] = s
+		f.names = append(f.names, name)
 	}
 
 	return nil
@@ -286,10 +394,13 @@ func (f *IndexedFrame[I]) RemoveSeries(names ...string) {
 	for _, name := range names {
 		s, ok := f.series[name]
 		if !ok {
-			return
+			continue
 		}
 		s.SignalDisconnect("NameChanged", f, f.onSeriesNameChanged)
 		delete(f.series, name)
+		if i := slices.Index(f.names, name); i >= 0 {
+			f.names = slices.Delete(f.names, i, i+1)
+		}
 	}
 }
 
@@ -302,15 +413,18 @@ func (f *IndexedFrame[I]) onSeriesNameChanged(args ...any) {
 
 	f.series[newName] = f.series[oldName]
 	delete(f.series, oldName)
+	if i := slices.Index(f.names, oldName); i >= 0 {
+		f.names[i] = newName
+	}
 
 	// Reconnect our signal handlers to update the name we use in the handlers.
 	f.series[newName].SignalDisconnect("NameChanged", f, f.onSeriesNameChanged)
 	f.series[newName].SignalConnect("NameChanged", f, f.onSeriesNameChanged, newName)
 }
 
-// Names returns a slice of the names of the series in the IndexedFrame.
+// Names returns a slice of the names of the series in the IndexedFrame in the order they were added.
 func (f *IndexedFrame[I]) Names() []string {
-	return maps.Keys(f.series)
+	return slices.Clone(f.names)
 }
 
 // Series returns a Series of the column with the given name. If the column does not exist, nil is returned.
@@ -414,9 +528,10 @@ func (f *IndexedFrame[I]) TimeIndex(column string, index I) time.Time {
 	return val
 }
 
+// ForEachSeries calls fn with each series in the order they were added.
 func (f *IndexedFrame[I]) ForEachSeries(fn func(*IndexedSeries[I])) {
-	for _, s := range f.series {
-		fn(s)
+	for _, name := range f.names {
+		fn(f.series[name])
 	}
 }
 
@@ -0,0 +1,61 @@
+package autotrader
+
+import "sync"
+
+// SyncIndexedFrame guards an IndexedFrame with a read-write mutex so it can be shared between goroutines, like a network goroutine that pushes candles from a live stream while the strategy reads them on the scheduler goroutine.
+//
+// The frame must only be accessed through the methods of the SyncIndexedFrame. Read gives access to the frame for as long as the callback runs, and Snapshot returns a copy that can be kept and used freely afterwards.
+type SyncIndexedFrame[I Index] struct {
+	mu    sync.RWMutex
+	frame *IndexedFrame[I]
+}
+
+// NewSyncIndexedFrame returns a SyncIndexedFrame that guards frame. If frame is nil, an empty DOHLCV frame is used.
+func NewSyncIndexedFrame[I Index](frame *IndexedFrame[I]) *SyncIndexedFrame[I] {
+	if frame == nil {
+		frame = NewDOHLCVIndexedFrame[I]()
+	}
+	return &SyncIndexedFrame[I]{frame: frame}
+}
+
+// Read calls f with the frame while holding a read lock. Any number of readers may run at the same time. The frame must not be modified or kept after f returns.
+func (s *SyncIndexedFrame[I]) Read(f func(frame *IndexedFrame[I])) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	f(s.frame)
+}
+
+// Write calls f with the frame while holding the write lock, so no readers or other writers run at the same time.
+func (s *SyncIndexedFrame[I]) Write(f func(frame *IndexedFrame[I])) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f(s.frame)
+}
+
+// PushCandle pushes a candlestick to the frame while holding the write lock.
+func (s *SyncIndexedFrame[I]) PushCandle(date I, open, high, low, close float64, volume int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.frame.PushCandle(date, open, high, low, close, volume)
+}
+
+// Len returns the number of rows in the frame.
+func (s *SyncIndexedFrame[I]) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.frame.Len()
+}
+
+// Snapshot returns a copy of the frame that is safe to use without locking.
+func (s *SyncIndexedFrame[I]) Snapshot() *IndexedFrame[I] {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.frame.Copy()
+}
+
+// SnapshotRange returns a copy of count rows of the frame starting at start, like IndexedFrame.CopyRange. start is an EasyIndex and a count of -1 copies every row to the end.
+func (s *SyncIndexedFrame[I]) SnapshotRange(start, count int) *IndexedFrame[I] {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.frame.CopyRange(start, count)
+}
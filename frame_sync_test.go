@@ -0,0 +1,53 @@
+package autotrader
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestSyncIndexedFrame pushes candles from one goroutine while others read. Run with -race to detect data races.
+func TestSyncIndexedFrame(t *testing.T) {
+	frame := NewSyncIndexedFrame[UnixTime](nil)
+	const candles = 200
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < candles; i++ {
+			if err := frame.PushCandle(UnixTime(i*60), float64(i), float64(i), float64(i), float64(i), int64(i)); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < candles; i++ {
+				snapshot := frame.SnapshotRange(-10, -1)
+				if n := snapshot.Len(); n > 0 && snapshot.Close(-1) != float64(*snapshot.Date(-1)/60) {
+					t.Errorf("Expected the close of the latest candle to match its date, got %v", snapshot.Close(-1))
+					return
+				}
+				frame.Read(func(f *IndexedFrame[UnixTime]) {
+					if f.Len() > 0 && f.Close(0) != 0 {
+						t.Errorf("Expected the first close to be 0, got %v", f.Close(0))
+					}
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if frame.Len() != candles {
+		t.Fatalf("Expected %d candles, got %d", candles, frame.Len())
+	}
+	frame.Write(func(f *IndexedFrame[UnixTime]) {
+		f.Closes().Insert(UnixTime(0), 1.0)
+	})
+	if snapshot := frame.Snapshot(); snapshot.Close(0) != 1 {
+		t.Errorf("Expected Write to modify the frame, got %v", snapshot.Close(0))
+	}
+}
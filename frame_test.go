@@ -1,6 +1,8 @@
 package autotrader
 
 import (
+	"errors"
+	"strings"
 	"testing"
 	"time"
 )
@@ -106,6 +108,24 @@ func TestIndexedFrame(t *testing.T) {
 	t.Log(data.String())
 }
 
+func TestIndexedFrameColumnOrder(t *testing.T) {
+	data := NewDOHLCVIndexedFrame[UnixTime]()
+	data.PushSeries(NewIndexedSeries[UnixTime, any]("Signal", nil))
+	data.Series("High").SetName("Peak")
+	data.RemoveSeries("Missing", "Low")
+
+	expected := []string{"Open", "Peak", "Close", "Volume", "Signal"}
+	for i := 0; i < 10; i++ { // Map iteration order is random, so check more than once.
+		names := data.Names()
+		if strings.Join(names, ",") != strings.Join(expected, ",") {
+			t.Fatalf("Expected columns %v, got %v", expected, names)
+		}
+		if copied := data.Copy().Names(); strings.Join(copied, ",") != strings.Join(expected, ",") {
+			t.Fatalf("Expected copied columns %v, got %v", expected, copied)
+		}
+	}
+}
+
 func TestIndexedFrameFunctions(t *testing.T) {
 	data := NewDOHLCVIndexedFrame[UnixTime]()
 	data.PushCandle(UnixTime(time.Date(2021, 5, 13, 0, 0, 0, 0, time.UTC).Unix()), 0.8, 1.2, 0.6, 1.0, 1)
@@ -182,3 +202,130 @@ func TestDOHLCVDataFrame(t *testing.T) {
 		t.Fatalf("Expected latest close to be 1.2, got %f", data.Close(-1))
 	}
 }
+
+func TestIndexedFramePushCandlePolicies(t *testing.T) {
+	data := NewDOHLCVIndexedFrame[UnixTime]()
+	var outOfOrder []UnixTime
+	data.SignalConnect("CandleOutOfOrder", t, func(args ...any) {
+		outOfOrder = append(outOfOrder, args[0].(UnixTime))
+	})
+	for _, date := range []UnixTime{10, 20, 30} {
+		if err := data.PushCandle(date, 1, 1, 1, float64(date), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The default policies overwrite duplicates and insert older candles in order.
+	data.PushCandle(30, 1, 1, 1, 31, 1)
+	data.PushCandle(15, 1, 1, 1, 15, 1)
+	if data.Len() != 4 || data.Close(-1) != 31 || data.Close(1) != 15 {
+		t.Errorf("Expected the candles to be overwritten and inserted, got %v", data)
+	}
+
+	data.DuplicatePolicy = CandleSkip
+	data.OutOfOrderPolicy = CandleError
+	if err := data.PushCandle(30, 1, 1, 1, 32, 1); err != nil || data.Close(-1) != 31 {
+		t.Errorf("Expected the duplicate candle to be skipped, got error %v and close %v", err, data.Close(-1))
+	}
+	if err := data.PushCandle(25, 1, 1, 1, 25, 1); !errors.Is(err, ErrOutOfOrderCandle) || data.Len() != 4 {
+		t.Errorf("Expected ErrOutOfOrderCandle, got %v", err)
+	}
+	data.DuplicatePolicy = CandleError
+	if err := data.PushCandle(20, 1, 1, 1, 21, 1); !errors.Is(err, ErrDuplicateCandle) || data.Close(2) != 20 {
+		t.Errorf("Expected ErrDuplicateCandle, got %v", err)
+	}
+	if err := data.PushCandle(40, 1, 1, 1, 40, 1); err != nil || data.Len() != 5 {
+		t.Errorf("Expected a newer candle to be pushed, got %v", err)
+	}
+
+	if len(outOfOrder) != 3 || outOfOrder[0] != 15 || outOfOrder[1] != 25 || outOfOrder[2] != 20 {
+		t.Errorf("Expected out of order signals for 15, 25, and 20, got %v", outOfOrder)
+	}
+	if copied := data.Copy(); copied.DuplicatePolicy != CandleError || copied.OutOfOrderPolicy != CandleError {
+		t.Error("Expected copies to keep the candle policies")
+	}
+}
+
+func TestFrameStringWith(t *testing.T) {
+	data := NewDOHLCVFrame()
+	data.PushSeries(NewSeries("Note"))
+	for i := 0; i < 5; i++ {
+		data.PushCandle(time.Date(2022, 1, 1+i, 0, 0, 0, 0, time.UTC), 1.0/3, 1, 1, float64(i)+0.123456, 10)
+		data.Series("Note").Push("a|b")
+	}
+	got := data.StringWith(PrintOptions{MaxRows: 2, Columns: []string{"Close", "Date", "Missing", "Note"}, FloatPrecision: 2, DateLayout: "Jan 2", Markdown: true})
+	expected := "|  | Close | Date | Note |\n| --- | --- | --- | --- |\n| 0 | 0.12 | Jan 1 | a\\|b |\n| ... |  |  |  |\n| 4 | 4.12 | Jan 5 | a\\|b |\n"
+	if got != expected {
+		t.Errorf("Expected the Markdown table:\n%s\ngot:\n%s", expected, got)
+	}
+
+	lines := strings.Split(data.String(), "\n")
+	if len(lines) != 8 || strings.Join(strings.Fields(lines[1]), " ") != "Date Open High Low Close Volume Note" {
+		t.Errorf("Expected the DOHLCV columns first and every row, got:\n%s", data.String())
+	}
+	if n := strings.Count(data.StringWith(PrintOptions{MaxRows: -1}), "\n"); n != 7 {
+		t.Errorf("Expected 7 lines, got %d", n)
+	}
+
+	indexed := NewIndexedFrame(NewIndexedSeries("Score", map[UnixTime]float64{0: 0.5, 86400: 0.25}))
+	got = indexed.StringWith(PrintOptions{DateLayout: "2006-01-02", Markdown: true})
+	expected = "| [Row] | [Index] | Score |\n| --- | --- | --- |\n| 1 | 1970-01-01 | 0.5 |\n| 2 | 1970-01-02 | 0.25 |\n"
+	if got != expected {
+		t.Errorf("Expected the Markdown table:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestIndexedFrameBetween(t *testing.T) {
+	data := NewDOHLCVIndexedFrame[UnixTime]()
+	for i := 0; i < 5; i++ {
+		data.PushCandle(UnixTime(i*60), 1, 2, 0.5, float64(i), 10)
+	}
+	between := data.Between(60, 180)
+	if between.Len() != 3 || *between.Date(0) != 60 || between.Close(-1) != 3 {
+		t.Errorf("Expected 3 candles from 60 to 180, got:\n%v", between)
+	}
+	if empty := data.Between(1000, 2000); empty.Len() != 0 || !empty.ContainsDOHLCV() {
+		t.Errorf("Expected an empty frame with the same columns, got:\n%v", empty)
+	}
+}
+
+func TestIndexedFrameSliceTime(t *testing.T) {
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	data := NewDOHLCVIndexedFrame[UnixTime]()
+	for i := 0; i < 48; i++ {
+		data.PushCandle(UnixTime(start.Add(time.Duration(i)*time.Hour).Unix()), 1, 2, 0.5, float64(i), 10)
+	}
+	day := data.SliceTime(start.Add(24*time.Hour), start.Add(48*time.Hour))
+	if day.Len() != 24 || day.Close(0) != 24 || day.Close(-1) != 47 {
+		t.Errorf("Expected the 24 candles of the second day, got %d from %f to %f", day.Len(), day.Close(0), day.Close(-1))
+	}
+	last := data.Last(24 * time.Hour)
+	if last.Len() != 24 || last.Close(0) != 24 {
+		t.Errorf("Expected the last 24 candles, got %d from %f", last.Len(), last.Close(0))
+	}
+	if empty := data.SliceTime(start.AddDate(1, 0, 0), start.AddDate(2, 0, 0)); empty.Len() != 0 {
+		t.Errorf("Expected no candles, got %d", empty.Len())
+	}
+	if empty := NewDOHLCVIndexedFrame[UnixTime]().Last(time.Hour); empty.Len() != 0 {
+		t.Errorf("Expected no candles, got %d", empty.Len())
+	}
+}
+
+func TestIndexedFrameView(t *testing.T) {
+	data := NewDOHLCVIndexedFrame[UnixTime]()
+	for i := 0; i < 5; i++ {
+		data.PushCandle(UnixTime(i), 1, 2, 0.5, float64(i), 10)
+	}
+	view := data.View(1, 3)
+	if view.Len() != 3 || *view.Date(0) != 1 || view.Close(-1) != 3 {
+		t.Errorf("Expected candles 1 to 3, got:\n%v", view)
+	}
+	data.Closes().SetValue(2, 20.0)
+	if view.Close(1) != 20 {
+		t.Errorf("Expected the view to share values, got %f", view.Close(1))
+	}
+	view.PushCandle(UnixTime(10), 1, 2, 0.5, 10, 10)
+	if data.Len() != 5 || data.Close(4) != 4 {
+		t.Errorf("Expected pushing to the view to leave the frame alone, got %d candles ending with %f", data.Len(), data.Close(4))
+	}
+}
@@ -0,0 +1,113 @@
+package autotrader
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FundingRates are the funding rates of perpetual futures by symbol, which the TestBroker charges to and credits to open positions. Each series holds the rate of every funding time, like every 8 hours, as a fraction of the value of a position. A positive rate is paid by longs to shorts and a negative rate is paid by shorts to longs.
+type FundingRates map[string]*IndexedSeries[UnixTime]
+
+// Between returns the sum of the funding rates of symbol with funding times after start up to and including end.
+func (r FundingRates) Between(symbol string, start, end time.Time) float64 {
+	rates := r[symbol]
+	if rates == nil || !end.After(start) {
+		return 0
+	}
+	from, to := rates.rowsBetween(UnixTime(start.Unix()+1), UnixTime(end.Unix()))
+	var sum float64
+	for i := from; i < to; i++ {
+		sum += rates.Float(i)
+	}
+	return sum
+}
+
+// FundingCSVLayout describes the columns of a CSV file of funding rates, like the funding history exported by an exchange.
+type FundingCSVLayout struct {
+	DateFormat string // DateFormat is the layout of the Date column as understood by time.Parse. If empty, dates are Unix times in milliseconds.
+	Date       string // Date is the column of the funding time.
+	Rate       string // Rate is the column of the funding rate. Rates ending with a '%', like "0.0100%", are divided by 100.
+	Symbol     string // Symbol is the optional column of the symbol of each rate. If empty or the column is missing, every rate is of the symbol given to the reader.
+}
+
+// FundingRatesFromCSV reads the funding rates in the CSV file at path like FundingRatesFromCSVReader.
+func FundingRatesFromCSV(path, symbol string, layout FundingCSVLayout) (FundingRates, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return FundingRatesFromCSVReader(f, symbol, layout)
+}
+
+// FundingRatesFromCSVReader reads funding rates from a CSV file with a header row. Rows without a symbol column are rates of symbol. The rows may be in any order.
+func FundingRatesFromCSVReader(r io.Reader, symbol string, layout FundingCSVLayout) (FundingRates, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[cleanCSVHeader(name)] = i
+	}
+	dateCol, ok := columns[layout.Date]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrMissingColumn, layout.Date)
+	}
+	rateCol, ok := columns[layout.Rate]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrMissingColumn, layout.Rate)
+	}
+	symbolCol, hasSymbol := columns[layout.Symbol]
+	hasSymbol = hasSymbol && layout.Symbol != ""
+
+	rates := make(FundingRates)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rates, nil
+		} else if err != nil {
+			return nil, err
+		}
+		date, err := parseFundingDate(record[dateCol], layout.DateFormat)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		field := strings.TrimSpace(record[rateCol])
+		percent := strings.HasSuffix(field, "%")
+		rate, err := strconv.ParseFloat(strings.TrimSuffix(field, "%"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if percent {
+			rate /= 100
+		}
+		sym := symbol
+		if hasSymbol {
+			sym = record[symbolCol]
+		}
+		if rates[sym] == nil {
+			rates[sym] = NewIndexedSeries[UnixTime, any](sym, nil)
+		}
+		rates[sym].Insert(UnixTime(date.Unix()), rate)
+	}
+}
+
+// parseFundingDate parses a date with layout, or as Unix milliseconds if layout is empty.
+func parseFundingDate(field, layout string) (time.Time, error) {
+	field = strings.TrimSpace(field)
+	if layout != "" {
+		return time.Parse(layout, field)
+	}
+	ms, err := strconv.ParseInt(field, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(ms), nil
+}
@@ -0,0 +1,89 @@
+package autotrader
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFundingRatesFromCSV(t *testing.T) {
+	data := `Time,Symbol,Funding Rate
+2022-01-01 16:00:00,BTC_USD,0.0100%
+2022-01-01 08:00:00,BTC_USD,-0.0050%
+2022-01-01 08:00:00,ETH_USD,0.0200%
+`
+	rates, err := FundingRatesFromCSVReader(strings.NewReader(data), "", FundingCSVLayout{DateFormat: "2006-01-02 15:04:05", Date: "Time", Rate: "Funding Rate", Symbol: "Symbol"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rates) != 2 {
+		t.Fatalf("Expected rates of 2 symbols, got %d", len(rates))
+	}
+	btc := rates["BTC_USD"]
+	if btc.Len() != 2 || !EqualApprox(btc.Float(0), -0.00005) || !EqualApprox(btc.Float(1), 0.0001) {
+		t.Errorf("Expected BTC_USD rates [-0.00005 0.0001] in time order, got %v", btc.Values())
+	}
+	start := time.Date(2022, 1, 1, 8, 0, 0, 0, time.UTC)
+	if sum := rates.Between("BTC_USD", start, start.Add(8*time.Hour)); !EqualApprox(sum, 0.0001) {
+		t.Errorf("Expected only the rate after the start to be summed, got %v", sum)
+	}
+
+	rates, err = FundingRatesFromCSVReader(strings.NewReader("time,rate\n1641024000000,0.0001\n"), "BTC_USD", FundingCSVLayout{Date: "time", Rate: "rate"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index := rates["BTC_USD"].Index(0); index == nil || *index != UnixTime(1641024000) {
+		t.Errorf("Expected a rate at Unix time 1641024000, got %v", index)
+	}
+}
+
+func TestBacktestingBrokerFunding(t *testing.T) {
+	at := func(day, hour int) UnixTime {
+		return UnixTime(time.Date(2022, 1, day, hour, 0, 0, 0, time.UTC).Unix())
+	}
+	btc := NewIndexedSeries[UnixTime, any]("BTC_USD", nil)
+	btc.Insert(at(1, 0), 0.5) // At the open of the first candle, before any position was open.
+	btc.Insert(at(1, 8), 0.01)
+	btc.Insert(at(1, 16), 0.01)
+	btc.Insert(at(2, 0), 0.01)
+	btc.Insert(at(2, 8), -0.02)
+
+	broker := NewTestBroker(nil, testData, 100_000, 1, 0, 0)
+	broker.Slippage = 0
+	broker.Funding = FundingRates{"BTC_USD": btc}
+
+	var positions []Position
+	for _, order := range []struct {
+		symbol string
+		units  float64
+	}{{"BTC_USD", 1000}, {"BTC_USD", -1000}, {"ETH_USD", 1000}} {
+		o, err := broker.Order(context.Background(), Market, order.symbol, order.units, 0, 0, 0) // Filled at 1.15.
+		if err != nil {
+			t.Fatal(err)
+		}
+		positions = append(positions, o.Position())
+	}
+
+	broker.Advance() // Three rates of 0.01 on positions worth 1200.
+	if !EqualApprox(broker.Cash, 100_000-1150+1150-1150-36+36) {
+		t.Errorf("Expected the long to pay 36 and the short to receive 36, got cash %f", broker.Cash)
+	}
+	broker.Advance() // A rate of -0.02 on positions worth 1250.
+	for _, p := range positions {
+		if err := p.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, expected := range []float64{36 - 25, -36 + 25, 0} {
+		if financing := positions[i].CloseCosts().Financing; !EqualApprox(financing, expected) {
+			t.Errorf("Expected position %d to have paid %f of funding, got %f", i, expected, financing)
+		}
+	}
+	if !EqualApprox(broker.FundingPaid(), 0) {
+		t.Errorf("Expected the funding of the long and short to cancel out, got %f", broker.FundingPaid())
+	}
+	if !EqualApprox(broker.NAV(), 100_000+100) { // The long and short cancel out and the ETH_USD long made 100.
+		t.Errorf("Expected NAV to be %f, got %f", 100_000.0+100, broker.NAV())
+	}
+}
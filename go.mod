@@ -4,18 +4,18 @@ go 1.20
 
 require (
 	github.com/go-co-op/gocron v1.26.0
+	github.com/go-echarts/go-echarts/v2 v2.2.6
 	github.com/rocketlaunchr/dataframe-go v0.0.0-20211025052708-a1030444159b
+	github.com/spatialcurrent/go-math v0.0.0-20211120210754-b3872f7000fe
 	golang.org/x/exp v0.0.0-20230510235704-dd950f8aeaea
 )
 
 require (
-	github.com/go-echarts/go-echarts/v2 v2.2.6 // indirect
 	github.com/google/go-cmp v0.5.8 // indirect
 	github.com/guptarohit/asciigraph v0.5.1 // indirect
 	github.com/mattn/go-runewidth v0.0.7 // indirect
 	github.com/olekukonko/tablewriter v0.0.4 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/robfig/cron/v3 v3.0.1 // indirect
-	github.com/spatialcurrent/go-math v0.0.0-20211120210754-b3872f7000fe // indirect
 	golang.org/x/sync v0.0.0-20200317015054-43a5402ce75a // indirect
 )
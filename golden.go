@@ -0,0 +1,149 @@
+package autotrader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var ErrGoldenMismatch = errors.New("backtest result does not match the golden file")
+
+// BacktestResult is what a backtest produced, in a form that can be saved to a golden file and compared with later runs to verify that a change to the engine did not change the results. See CheckGolden.
+type BacktestResult struct {
+	Summary   BacktestSummary `json:"summary"`
+	Trades    int             `json:"trades"`     // Trades is the number of entry and exit trades.
+	TradeHash string          `json:"trade_hash"` // TradeHash is a SHA-256 hash of the time, direction, units, price, costs, and close type of every trade. Numbers are rounded to 8 significant digits so the hash does not change with the last bits of floating point results.
+}
+
+// NewBacktestResult returns the result of the finished backtest of trader, whose broker must be a TestBroker. The Seed of the TestBroker should be set so the slippage of every run is the same.
+func NewBacktestResult(trader *Trader) (BacktestResult, error) {
+	broker, ok := trader.Broker.(*TestBroker)
+	if !ok {
+		return BacktestResult{}, fmt.Errorf("%w: got %T", ErrNotTestBroker, trader.Broker)
+	}
+	trades := trader.Stats().Trades()
+	hash := sha256.New()
+	for _, trade := range trades {
+		fmt.Fprintf(hash, "%d %d %t %s %s %s %s %s\n", trade.OpenTime.Unix(), trade.CloseTime.Unix(), trade.Exit,
+			goldenFloat(trade.Units), goldenFloat(trade.Price), goldenFloat(trade.Cost()), goldenFloat(trade.Financing), trade.CloseType)
+	}
+	return BacktestResult{
+		Summary:   Summarize(trader.Stats(), broker),
+		Trades:    len(trades),
+		TradeHash: hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+// goldenFloat formats f with 8 significant digits.
+func goldenFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', 8, 64)
+}
+
+// GoldenTolerance is how much the metrics of a result may differ from the golden file. A metric matches if it is within either tolerance. The zero value requires the metrics to be equal.
+type GoldenTolerance struct {
+	Absolute float64 // Absolute is the largest difference allowed between two metrics.
+	Relative float64 // Relative is the largest difference allowed as a fraction of the golden metric, like 0.001 for 0.1%.
+}
+
+func (t GoldenTolerance) match(golden, got float64) bool {
+	diff := math.Abs(got - golden)
+	return diff <= t.Absolute || diff <= t.Relative*math.Abs(golden) || EqualApprox(golden, got)
+}
+
+// WriteGolden writes result to the golden file at path as indented JSON, creating its directory if needed.
+func WriteGolden(path string, result BacktestResult) error {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// ReadGolden reads the result in the golden file at path.
+func ReadGolden(path string) (BacktestResult, error) {
+	var result BacktestResult
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return result, err
+	}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+// CheckGolden compares result with the golden file at path and returns an error wrapping ErrGoldenMismatch that lists every difference if they do not match. The metrics of the summary are compared with tolerance, and the number of trades and the trade hash must be equal. If the golden file does not exist, it is written with result, so the first run of a new golden test records the snapshot. Delete the file or use WriteGolden to accept new results.
+func CheckGolden(path string, result BacktestResult, tolerance GoldenTolerance) error {
+	golden, err := ReadGolden(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return WriteGolden(path, result)
+	} else if err != nil {
+		return err
+	}
+	diffs, err := compareResults(golden, result, tolerance)
+	if err != nil {
+		return err
+	}
+	if len(diffs) > 0 {
+		return fmt.Errorf("%w %s:\n  %s", ErrGoldenMismatch, path, strings.Join(diffs, "\n  "))
+	}
+	return nil
+}
+
+// compareResults returns a description of every difference between golden and got. The summaries are compared by their JSON fields, so new metrics are compared without changing this function.
+func compareResults(golden, got BacktestResult, tolerance GoldenTolerance) ([]string, error) {
+	var diffs []string
+	if golden.Trades != got.Trades {
+		diffs = append(diffs, fmt.Sprintf("trades: golden %d, got %d", golden.Trades, got.Trades))
+	}
+	if golden.TradeHash != got.TradeHash {
+		diffs = append(diffs, fmt.Sprintf("trade_hash: golden %s, got %s", golden.TradeHash, got.TradeHash))
+	}
+	goldenFields, err := jsonFields(golden.Summary)
+	if err != nil {
+		return nil, err
+	}
+	gotFields, err := jsonFields(got.Summary)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(goldenFields))
+	for name := range goldenFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		g, v := goldenFields[name], gotFields[name]
+		gf, gIsNum := g.(float64)
+		vf, vIsNum := v.(float64)
+		if gIsNum && vIsNum {
+			if !tolerance.match(gf, vf) {
+				diffs = append(diffs, fmt.Sprintf("summary.%s: golden %v, got %v", name, gf, vf))
+			}
+		} else if fmt.Sprint(g) != fmt.Sprint(v) {
+			diffs = append(diffs, fmt.Sprintf("summary.%s: golden %v, got %v", name, g, v))
+		}
+	}
+	return diffs, nil
+}
+
+// jsonFields returns the fields of v as they are encoded in JSON.
+func jsonFields(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]any
+	err = json.Unmarshal(data, &fields)
+	return fields, err
+}
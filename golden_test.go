@@ -0,0 +1,69 @@
+package autotrader
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestGoldenBacktest guards the results of the engine against unintended changes. Delete the golden file to record new results after an intended change.
+func TestGoldenBacktest(t *testing.T) {
+	trader, _ := runTestBacktest(t, &roundTripStrategy{})
+	result, err := NewBacktestResult(trader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckGolden(filepath.Join("testdata", "golden_roundtrip.json"), result, GoldenTolerance{Absolute: 1e-6}); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestGoldenFlatBacktest guards the results of a strategy that never trades, whose metrics must still be encodable.
+func TestGoldenFlatBacktest(t *testing.T) {
+	trader, _ := runTestBacktest(t, &countingStrategy{})
+	result, err := NewBacktestResult(trader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Trades != 0 || result.Summary.ProfitFactor != 0 {
+		t.Errorf("Expected no trades and a profit factor of 0, got %d and %v", result.Trades, result.Summary.ProfitFactor)
+	}
+	if err := CheckGolden(filepath.Join("testdata", "golden_flat.json"), result, GoldenTolerance{Absolute: 1e-6}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCheckGolden(t *testing.T) {
+	trader, _ := runTestBacktest(t, &roundTripStrategy{})
+	result, err := NewBacktestResult(trader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Trades != 2 || len(result.TradeHash) != 64 {
+		t.Errorf("Expected 2 trades and a SHA-256 hash, got %d and %q", result.Trades, result.TradeHash)
+	}
+	path := filepath.Join(t.TempDir(), "golden", "result.json")
+	if err := CheckGolden(path, result, GoldenTolerance{}); err != nil {
+		t.Fatalf("Expected the first check to write the golden file, got %v", err)
+	}
+	if err := CheckGolden(path, result, GoldenTolerance{}); err != nil {
+		t.Errorf("Expected the same result to match, got %v", err)
+	}
+
+	changed := result
+	changed.Summary.NetProfit += 1
+	if err := CheckGolden(path, changed, GoldenTolerance{Absolute: 2}); err != nil {
+		t.Errorf("Expected a difference within the tolerance to match, got %v", err)
+	}
+	err = CheckGolden(path, changed, GoldenTolerance{Absolute: 0.5})
+	if !errors.Is(err, ErrGoldenMismatch) || !strings.Contains(err.Error(), "summary.net_profit") {
+		t.Errorf("Expected a net profit mismatch, got %v", err)
+	}
+
+	changed = result
+	changed.TradeHash = "different"
+	if err := CheckGolden(path, changed, GoldenTolerance{Relative: 1}); !errors.Is(err, ErrGoldenMismatch) {
+		t.Errorf("Expected a trade hash mismatch regardless of tolerance, got %v", err)
+	}
+}
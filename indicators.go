@@ -1,41 +1,109 @@
 package autotrader
 
 import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
 	"math"
+	"runtime"
+	"sync"
 	"time"
 )
 
-// RSI calculates the Relative Strength Index for a given Series. Typically, the input series is the Close column of a DataFrame. Returns a Series of RSI values of the same length as the input.
+// Smoothing is a method of averaging a series of values over a period, used by indicators like the RSI.
+type Smoothing int
+
+const (
+	SimpleSmoothing      Smoothing = iota // SimpleSmoothing is the simple moving average of the last period values.
+	WilderSmoothing                       // WilderSmoothing is the running average of J. Welles Wilder, which is seeded with the simple average of the first period values and then adds 1/period of each new value. It is the smoothing of the classic RSI.
+	ExponentialSmoothing                  // ExponentialSmoothing is the exponential moving average with a weight of 2/(period+1), seeded with the first value.
+)
+
+// smooth returns the values averaged over periods with the smoothing method. The first values are averaged over the values available so far.
+func smooth(values []float64, periods int, smoothing Smoothing) []float64 {
+	result := make([]float64, len(values))
+	var avg float64
+	for i, v := range values {
+		switch {
+		case smoothing == SimpleSmoothing:
+			if i >= periods {
+				avg += (v - values[i-periods]) / float64(periods)
+			} else {
+				avg += (v - avg) / float64(i+1)
+			}
+		case smoothing == ExponentialSmoothing && i > 0:
+			avg += (v - avg) * 2 / float64(periods+1)
+		case i < periods: // Wilder seeds with the simple average and so does exponential smoothing with the first value.
+			avg += (v - avg) / float64(i+1)
+		default:
+			avg += (v - avg) / float64(periods)
+		}
+		result[i] = avg
+	}
+	return result
+}
+
+// RSI calculates the Relative Strength Index for a given Series with a simple average of the gains and losses. Typically, the input series is the Close column of a DataFrame. Returns a Series of RSI values of the same length as the input. See RSIWithSmoothing for the classic RSI of Wilder.
 //
 // Traditionally, an RSI reading of 70 or above indicates an overbought condition, and a reading of 30 or below indicates an oversold condition.
 //
 // Typically, the RSI is calculated with a period of 14 days.
 func RSI(series *FloatSeries, periods int) *FloatSeries {
-	// Calculate the difference between each day's close and the previous day's close.
-	delta := series.Copy().MapReverse(func(i int, v float64) float64 {
-		if i == 0 {
-			return 0
-		}
-		return v - series.Value(i-1)
-	})
-	// Calculate the average gain and average loss.
-	avgGain := &FloatSeries{delta.Copy().
-		Map(func(i int, val float64) float64 { return math.Max(val, 0) }).
-		Rolling(periods).Average()}
-	avgLoss := &FloatSeries{delta.Copy().
-		Map(func(i int, val float64) float64 { return math.Abs(math.Min(val, 0)) }).
-		Rolling(periods).Average()}
+	return RSIWithSmoothing(series, periods, SimpleSmoothing)
+}
+
+// RSIWithSmoothing calculates the Relative Strength Index for a given Series, averaging the gains and losses with the smoothing method. WilderSmoothing gives the classic RSI as charted by most platforms. Returns a Series of RSI values of the same length as the input. The first value is always 100, since there is no previous price.
+func RSIWithSmoothing(series *FloatSeries, periods int, smoothing Smoothing) *FloatSeries {
+	// Calculate the gain or loss of each day's close over the previous day's close.
+	gains := make([]float64, Max(series.Len()-1, 0))
+	losses := make([]float64, len(gains))
+	for i := range gains {
+		delta := series.Value(i+1) - series.Value(i)
+		gains[i], losses[i] = math.Max(delta, 0), math.Max(-delta, 0)
+	}
+	avgGain, avgLoss := smooth(gains, periods, smoothing), smooth(losses, periods, smoothing)
 
 	// Calculate the RSI.
-	return avgGain.Map(func(i int, val float64) float64 {
-		loss := avgLoss.Float(i)
-		if loss == 0 {
-			return float64(100)
+	return series.Copy().Map(func(i int, _ float64) float64 {
+		if i == 0 || avgLoss[i-1] == 0 {
+			return 100
 		}
-		return float64(100 - 100/(1+val/loss))
+		return 100 - 100/(1+avgGain[i-1]/avgLoss[i-1])
 	}).SetName("RSI")
 }
 
+// StochRSI calculates the Stochastic RSI, which is where the RSI is between its lowest and highest values over the last stochPeriods values, from 0 to 100. The RSI is calculated with rsiPeriods and the smoothing method. Where the RSI did not change over the period, the StochRSI is 50. Returns a Series of the same length as the input.
+//
+// Typically, both periods are 14 and the RSI uses WilderSmoothing. Readings above 80 are considered overbought and below 20 oversold.
+func StochRSI(series *FloatSeries, rsiPeriods, stochPeriods int, smoothing Smoothing) *FloatSeries {
+	rsi := RSIWithSmoothing(series, rsiPeriods, smoothing)
+	lowest := &FloatSeries{rsi.Copy().Rolling(stochPeriods).Min()}
+	highest := &FloatSeries{rsi.Copy().Rolling(stochPeriods).Max()}
+	return rsi.Map(func(i int, val float64) float64 {
+		low, high := lowest.Value(i), highest.Value(i)
+		if high == low {
+			return 50
+		}
+		return 100 * (val - low) / (high - low)
+	}).SetName("StochRSI")
+}
+
+// ATR calculates the Average True Range of the candles in dohlcv, which is the average of the true range of each candle over the last periods candles. The true range is the greatest of the candle's high minus low, high minus previous close, and previous close minus low. Returns a Series of ATR values of the same length as the input.
+//
+// Typically, the ATR is calculated with a period of 14 candles.
+func ATR(dohlcv *IndexedFrame[UnixTime], periods int) *FloatSeries {
+	tr := make([]float64, dohlcv.Len())
+	for i := range tr {
+		high, low := dohlcv.High(i), dohlcv.Low(i)
+		tr[i] = high - low
+		if i > 0 {
+			prevClose := dohlcv.Close(i - 1)
+			tr[i] = math.Max(tr[i], math.Max(math.Abs(high-prevClose), math.Abs(low-prevClose)))
+		}
+	}
+	return &FloatSeries{NewFloatSeries("ATR", tr...).Rolling(periods).Average()}
+}
+
 // Ichimoku calculates the Ichimoku Cloud for a given Series. Returns a DataFrame of the same length as the input with float64 values. The series input must contain only float64 values, which are traditionally the close prices.
 //
 // The standard values:
@@ -50,13 +118,19 @@ func RSI(series *FloatSeries, periods int) *FloatSeries {
 //   - LeadingB
 //   - Lagging
 func Ichimoku(price *IndexedFrame[UnixTime], convPeriod, basePeriod, leadingPeriods int, frequency time.Duration) *IndexedFrame[UnixTime] {
-	// TODO: make this run concurrently.
-
-	conv := price.Highs().Copy().Rolling(convPeriod).Max().Add(price.Lows().Copy().Rolling(convPeriod).Min()).DivFloat(2)
-	base := price.Highs().Copy().Rolling(basePeriod).Max().Add(price.Lows().Copy().Rolling(basePeriod).Min()).DivFloat(2)
+	midpoint := func(periods int) func(price *IndexedFrame[UnixTime]) *IndexedSeries[UnixTime] {
+		return func(price *IndexedFrame[UnixTime]) *IndexedSeries[UnixTime] {
+			return price.Highs().Copy().Rolling(periods).Max().Add(price.Lows().Copy().Rolling(periods).Min()).DivFloat(2)
+		}
+	}
+	results := ComputeParallel(price, 0,
+		midpoint(convPeriod),
+		midpoint(basePeriod),
+		midpoint(leadingPeriods),
+		func(price *IndexedFrame[UnixTime]) *IndexedSeries[UnixTime] { return price.Closes().Copy() },
+	)
+	conv, base, leadingB, lagging := results[0], results[1], results[2], results[3]
 	leadingA := conv.Copy().Add(base).DivFloat(2)
-	leadingB := price.Highs().Copy().Rolling(leadingPeriods).Max().Add(price.Lows().Copy().Rolling(leadingPeriods).Min()).DivFloat(2)
-	lagging := price.Closes().Copy()
 
 	// Return a DataFrame of the results.
 	return NewIndexedFrame(
@@ -67,3 +141,142 @@ func Ichimoku(price *IndexedFrame[UnixTime], convPeriod, basePeriod, leadingPeri
 		lagging.SetName("Lagging").ShiftIndex(-basePeriod, UnixTimeStep(frequency)),
 	)
 }
+
+// ComputeParallel calls each function with frame concurrently, running at most workers at once, and returns the series they computed in the same order as the functions. If workers is zero or less, runtime.GOMAXPROCS(0) workers are used. The functions must only read from frame, so this is useful for computing several independent rolling operations or indicators of the same candles at once.
+func ComputeParallel[I Index](frame *IndexedFrame[I], workers int, fns ...func(frame *IndexedFrame[I]) *IndexedSeries[I]) []*IndexedSeries[I] {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	results := make([]*IndexedSeries[I], len(fns))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < Min(workers, len(fns)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = fns[i](frame)
+			}
+		}()
+	}
+	for i := range fns {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// DefaultIndicatorCacheEntries is the number of results an IndicatorCache keeps when its MaxEntries is zero.
+const DefaultIndicatorCacheEntries = 10_000
+
+// IndicatorCache holds the results of indicators computed on candles, keyed by the candles, the name of the indicator, and its parameters. Candles are identified by their length, their first and last dates, and the values of their first and last rows, so looking up a result does not read every candle. The backtests of an Optimizer see the same candles on the same candle of the data, so when they share a cache through Optimizer.Indicators, an indicator like RSI(14) is computed once for every candidate that uses it instead of once per candidate. It is safe for concurrent use.
+type IndicatorCache struct {
+	MaxEntries int // MaxEntries is the number of results kept, after which the oldest are evicted. Zero keeps DefaultIndicatorCacheEntries and a negative number keeps every result.
+
+	mu      sync.Mutex
+	entries map[indicatorKey]*indicatorEntry
+	order   []indicatorKey // order is the keys of entries from the oldest.
+	hits    int
+	misses  int
+}
+
+// indicatorKey identifies the result of an indicator of some candles.
+type indicatorKey struct {
+	first, last UnixTime // first and last are the dates of the first and last candles.
+	len         int
+	rows        uint64 // rows is a hash of the values of the first and last candles, which tells apart the candles of different symbols and a revised last candle.
+	indicator   string // indicator is the name, parameters, and result type of the indicator.
+}
+
+// indicatorEntry is a cached result, which is computed once by the first caller.
+type indicatorEntry struct {
+	once  sync.Once
+	value any
+}
+
+// NewIndicatorCache returns an empty IndicatorCache that keeps up to maxEntries results, or DefaultIndicatorCacheEntries if maxEntries is zero, or every result if it is negative.
+func NewIndicatorCache(maxEntries int) *IndicatorCache {
+	return &IndicatorCache{MaxEntries: maxEntries}
+}
+
+// Stats returns the number of results that were found in the cache and the number that had to be computed.
+func (c *IndicatorCache) Stats() (hits, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// Len returns the number of cached results.
+func (c *IndicatorCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// entry returns the entry of key, adding an empty entry if there is none.
+func (c *IndicatorCache) entry(key indicatorKey) *indicatorEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.hits++
+		return e
+	}
+	c.misses++
+	if c.entries == nil {
+		c.entries = make(map[indicatorKey]*indicatorEntry)
+	}
+	e := &indicatorEntry{}
+	c.entries[key] = e
+	c.order = append(c.order, key)
+	maxEntries := c.MaxEntries
+	if maxEntries == 0 {
+		maxEntries = DefaultIndicatorCacheEntries
+	}
+	if maxEntries > 0 && len(c.order) > maxEntries {
+		delete(c.entries, c.order[0])
+		c.order = c.order[1:]
+	}
+	return e
+}
+
+// CachedIndicator returns the result of compute on candles, which is the indicator called name with params, like CachedIndicator(t.Indicators, t.Data(), "ATR", func(c *IndexedFrame[UnixTime]) *FloatSeries { return ATR(c, 14) }, 14) in the Next of a strategy. The result is computed once for the same candles, name, and params and then returned from cache, so it is shared and must not be changed. Every parameter that changes the result must be given in params. If cache is nil, compute is called every time.
+func CachedIndicator[T any](cache *IndicatorCache, candles *IndexedFrame[UnixTime], name string, compute func(candles *IndexedFrame[UnixTime]) T, params ...any) T {
+	if cache == nil {
+		return compute(candles)
+	}
+	var zero T
+	key := indicatorKey{len: candles.Len(), indicator: fmt.Sprintf("%s%v %T", name, params, zero)}
+	if key.len > 0 {
+		key.first, key.last, key.rows = *candles.Date(0), *candles.Date(-1), hashRows(candles, 0, -1)
+	}
+	e := cache.entry(key)
+	e.once.Do(func() { e.value = compute(candles) })
+	return e.value.(T)
+}
+
+// hashRows returns a hash of the values of every series of candles in rows.
+func hashRows(candles *IndexedFrame[UnixTime], rows ...int) uint64 {
+	h := fnv.New64a()
+	var buf [8]byte
+	write := func(v uint64) {
+		binary.LittleEndian.PutUint64(buf[:], v)
+		h.Write(buf[:])
+	}
+	for _, name := range candles.Names() {
+		h.Write([]byte(name))
+		for _, i := range rows {
+			switch v := candles.Value(name, i).(type) {
+			case float64:
+				write(math.Float64bits(v))
+			case int:
+				write(uint64(v))
+			case int64:
+				write(uint64(v))
+			default:
+				fmt.Fprintf(h, "%v|", v)
+			}
+		}
+	}
+	return h.Sum64()
+}
@@ -1,7 +1,10 @@
 package autotrader
 
 import (
+	"math"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestRSI(t *testing.T) {
@@ -18,3 +21,202 @@ func TestRSI(t *testing.T) {
 		t.Errorf("RSI[-1] is %f, expected 63.157895", rsi.Value(-1))
 	}
 }
+
+func TestWilderRSI(t *testing.T) {
+	// The reference values are from the 14 day RSI example of StockCharts, which rounds its intermediate values to two decimals.
+	prices := NewFloatSeries("Prices", 44.34, 44.09, 44.15, 43.61, 44.33, 44.83, 45.10, 45.42, 45.84, 46.08, 45.89, 46.03, 45.61, 46.28, 46.28,
+		46.00, 46.03, 46.41, 46.22, 45.64, 46.21, 46.25, 45.71, 46.45, 45.78, 45.35, 44.03, 44.18, 44.22, 44.57, 43.42, 42.66, 43.13)
+	rsi := RSIWithSmoothing(prices, 14, WilderSmoothing)
+	if rsi.Len() != prices.Len() {
+		t.Fatalf("RSI length is %d, expected %d", rsi.Len(), prices.Len())
+	}
+	for i, expected := range map[int]float64{14: 70.53, 15: 66.32, 20: 62.93, 26: 39.99, 32: 37.77} {
+		if math.Abs(rsi.Value(i)-expected) > 0.1 {
+			t.Errorf("RSI[%d] is %f, expected %.2f", i, rsi.Value(i), expected)
+		}
+	}
+
+	simple := RSIWithSmoothing(prices, 14, SimpleSmoothing)
+	if !EqualApprox(simple.Value(-1), RSI(prices, 14).Value(-1)) {
+		t.Errorf("Expected RSI to use simple smoothing, got %f and %f", simple.Value(-1), RSI(prices, 14).Value(-1))
+	}
+	if exp := RSIWithSmoothing(prices, 14, ExponentialSmoothing); exp.Value(-1) == simple.Value(-1) || exp.Value(-1) == rsi.Value(-1) {
+		t.Errorf("Expected exponential smoothing to differ from the others, got %f", exp.Value(-1))
+	}
+}
+
+func TestStochRSI(t *testing.T) {
+	prices := NewFloatSeries("Prices", 1, 2, 3, 2, 1, 2, 3, 4, 5, 4)
+	stoch := StochRSI(prices, 2, 3, WilderSmoothing)
+	rsi := RSIWithSmoothing(prices, 2, WilderSmoothing)
+	if stoch.Len() != prices.Len() {
+		t.Fatalf("StochRSI length is %d, expected %d", stoch.Len(), prices.Len())
+	}
+	for i := 0; i < stoch.Len(); i++ {
+		low, high := rsi.Value(i), rsi.Value(i)
+		for j := Max(i-2, 0); j <= i; j++ {
+			low, high = math.Min(low, rsi.Value(j)), math.Max(high, rsi.Value(j))
+		}
+		expected := 50.0
+		if high != low {
+			expected = 100 * (rsi.Value(i) - low) / (high - low)
+		}
+		if !EqualApprox(stoch.Value(i), expected) {
+			t.Errorf("StochRSI[%d] is %f, expected %f", i, stoch.Value(i), expected)
+		}
+	}
+	if stoch.Value(1) != 50 { // The RSI is 100 for the first two prices.
+		t.Errorf("StochRSI[1] is %f, expected 50", stoch.Value(1))
+	}
+	if stoch.Value(-1) != 0 { // The RSI fell to its lowest of the period.
+		t.Errorf("StochRSI[-1] is %f, expected 0", stoch.Value(-1))
+	}
+}
+
+func TestATR(t *testing.T) {
+	atr := ATR(testData, 3)
+	if atr.Len() != testData.Len() {
+		t.Fatalf("ATR length is %d, expected %d", atr.Len(), testData.Len())
+	}
+	if !EqualApprox(atr.Value(0), 0.2) { // Only the high minus low of the first candle.
+		t.Errorf("ATR[0] is %f, expected 0.2", atr.Value(0))
+	}
+	if !EqualApprox(atr.Value(2), 0.15) { // (0.2 + 0.1 + 0.15) / 3
+		t.Errorf("ATR[2] is %f, expected 0.15", atr.Value(2))
+	}
+	if !EqualApprox(atr.Value(3), 0.55/3) { // (0.1 + 0.15 + 0.3) / 3
+		t.Errorf("ATR[3] is %f, expected %f", atr.Value(3), 0.55/3)
+	}
+}
+
+func TestIchimoku(t *testing.T) {
+	data := hourlyTestData(3)
+	ichimoku := Ichimoku(data, 9, 26, 52, time.Hour)
+	if names := ichimoku.Names(); len(names) != 5 {
+		t.Fatalf("Expected 5 columns, got %v", names)
+	}
+
+	conv := data.Highs().Copy().Rolling(9).Max().Add(data.Lows().Copy().Rolling(9).Min()).DivFloat(2)
+	base := data.Highs().Copy().Rolling(26).Max().Add(data.Lows().Copy().Rolling(26).Min()).DivFloat(2)
+	leadingA := conv.Copy().Add(base).DivFloat(2).ShiftIndex(52, UnixTimeStep(time.Hour))
+	for i := 0; i < data.Len(); i++ {
+		if got := ichimoku.Series("Conversion").Float(i); !EqualApprox(got, conv.Float(i)) {
+			t.Errorf("Conversion[%d] is %f, expected %f", i, got, conv.Float(i))
+		}
+		if got := ichimoku.Series("LeadingA").Float(i); !EqualApprox(got, leadingA.Float(i)) {
+			t.Errorf("LeadingA[%d] is %f, expected %f", i, got, leadingA.Float(i))
+		}
+	}
+	if first := *ichimoku.Series("Lagging").Index(0); first != *data.Index(0)-UnixTime(26*time.Hour/time.Second) {
+		t.Errorf("Expected the lagging span to be shifted back 26 candles, starts at %v", first)
+	}
+}
+
+func TestComputeParallel(t *testing.T) {
+	data := hourlyTestData(3)
+	fns := make([]func(*IndexedFrame[UnixTime]) *IndexedSeries[UnixTime], 10)
+	for i := range fns {
+		period := i + 1
+		fns[i] = func(frame *IndexedFrame[UnixTime]) *IndexedSeries[UnixTime] {
+			return frame.Closes().Copy().Rolling(period).Mean()
+		}
+	}
+	for _, workers := range []int{0, 1, 3, 20} {
+		results := ComputeParallel(data, workers, fns...)
+		if len(results) != len(fns) {
+			t.Fatalf("Expected %d results with %d workers, got %d", len(fns), workers, len(results))
+		}
+		for i, result := range results {
+			if expected := fns[i](data); !EqualApprox(result.Float(-1), expected.Float(-1)) {
+				t.Errorf("Expected result %d with %d workers to be %f, got %f", i, workers, expected.Float(-1), result.Float(-1))
+			}
+		}
+	}
+}
+
+func TestIndicatorCache(t *testing.T) {
+	var computed int
+	atr := func(cache *IndicatorCache, candles *IndexedFrame[UnixTime], periods int) *FloatSeries {
+		return CachedIndicator(cache, candles, "ATR", func(c *IndexedFrame[UnixTime]) *FloatSeries {
+			computed++
+			return ATR(c, periods)
+		}, periods)
+	}
+	atr(nil, testData, 3)
+	atr(nil, testData, 3)
+	if computed != 2 {
+		t.Errorf("Expected a nil cache to compute every time, got %d computations", computed)
+	}
+
+	computed = 0
+	cache := NewIndicatorCache(2)
+	first := atr(cache, testData, 3)
+	if second := atr(cache, testData.Copy(), 3); second != first || computed != 1 {
+		t.Errorf("Expected the same candles to be computed once, got %d computations", computed)
+	}
+	if !EqualApprox(first.Value(-1), ATR(testData, 3).Value(-1)) {
+		t.Errorf("Expected the cached ATR to be %f, got %f", ATR(testData, 3).Value(-1), first.Value(-1))
+	}
+	atr(cache, testData, 4)
+	atr(cache, testData.CopyRange(0, 5), 3)
+	if hits, misses := cache.Stats(); computed != 3 || hits != 1 || misses != 3 || cache.Len() != 2 {
+		t.Errorf("Expected other parameters and candles to be computed and the oldest result evicted, got %d computations, %d hits, %d misses, and %d results", computed, hits, misses, cache.Len())
+	}
+	atr(cache, testData, 3)
+	if computed != 4 {
+		t.Errorf("Expected the evicted result to be computed again, got %d computations", computed)
+	}
+	revised := testData.Copy()
+	revised.Closes().SetValue(-1, 1.35)
+	if atr(cache, revised, 3); computed != 5 {
+		t.Errorf("Expected a revised last candle to be computed again, got %d computations", computed)
+	}
+
+	cache = NewIndicatorCache(0)
+	for i := 1; i <= DefaultIndicatorCacheEntries+1; i++ {
+		cache.entry(indicatorKey{len: i})
+	}
+	if cache.Len() != DefaultIndicatorCacheEntries {
+		t.Errorf("Expected a cache without MaxEntries to keep %d results, got %d", DefaultIndicatorCacheEntries, cache.Len())
+	}
+}
+
+// atrStrategy computes the ATR of the candles with CachedIndicator on every candle and buys Size units on the first.
+type atrStrategy struct {
+	Size     float64
+	computed *atomic.Int64
+}
+
+func (s *atrStrategy) Init(_ *Trader) {}
+
+func (s *atrStrategy) Next(t *Trader) {
+	CachedIndicator(t.Indicators, t.Data(), "ATR", func(c *IndexedFrame[UnixTime]) *FloatSeries {
+		s.computed.Add(1)
+		return ATR(c, 3)
+	}, 3)
+	if !t.IsLong() && s.Size > 0 {
+		t.Buy(s.Size, 0, 0)
+	}
+}
+
+func TestOptimizerIndicatorCache(t *testing.T) {
+	var computed atomic.Int64
+	optimizer := &Optimizer{
+		Data:          testData,
+		Symbol:        "EUR_USD",
+		Frequency:     "D",
+		CandlesToKeep: 5,
+		NewStrategy: func(params Parameters) Strategy {
+			return &atrStrategy{Size: params["Size"].(float64), computed: &computed}
+		},
+		Workers:    2,
+		Indicators: NewIndicatorCache(0),
+	}
+	if _, err := optimizer.Optimize(ParameterSpace{"Size": {0.0, 1000.0, 2000.0}}); err != nil {
+		t.Fatal(err)
+	}
+	hits, misses := optimizer.Indicators.Stats()
+	if computed.Load() != int64(misses) || misses == 0 || hits != 2*misses {
+		t.Errorf("Expected each candle to be computed by one of the 3 backtests, got %d computations, %d hits, and %d misses", computed.Load(), hits, misses)
+	}
+}
@@ -0,0 +1,94 @@
+package autotrader
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// InstrumentType is the kind of contract an Instrument is.
+type InstrumentType string
+
+const (
+	Spot   InstrumentType = ""       // Spot is a currency pair, stock, or anything else that is traded directly and does not expire.
+	Future InstrumentType = "future" // Future is a futures contract that is settled at the price of the contract when it expires.
+	Option InstrumentType = "option" // Option is an options contract that is settled at its intrinsic value when it expires.
+)
+
+// OptionRight is whether an option is the right to buy or to sell the underlying.
+type OptionRight string
+
+const (
+	Call OptionRight = "call"
+	Put  OptionRight = "put"
+)
+
+// Instrument describes the contract of a symbol, like the multiplier and expiry of a futures contract. The zero value is a spot instrument with a multiplier of 1.
+type Instrument struct {
+	Symbol     string
+	Type       InstrumentType
+	Underlying string    // Underlying is the symbol an option is on. Its price is used to settle the option at expiry. It is required for options.
+	Multiplier float64   // Multiplier is the amount of the underlying in one contract, like 50 for an E-mini S&P 500 future or 100 for a stock option. Values, profits, and costs are multiplied by it. If zero, it is 1.
+	TickSize   float64   // TickSize is the smallest price increment of the contract. Order prices are rounded to it. If zero, prices are not rounded.
+	Expiry     time.Time // Expiry is when the contract expires. Open positions are settled on the first candle at or after it. If zero, the contract does not expire.
+	Strike     float64   // Strike is the strike price of an option.
+	Right      OptionRight
+	// MinUnits and MaxUnits are the smallest and largest absolute number of units of an order. Orders outside of them fail with ErrUnitsBelowMinimum or ErrUnitsAboveMaximum, which both wrap ErrInvalidUnits. If MaxUnits is zero, there is no maximum.
+	MinUnits float64
+	MaxUnits float64
+	// MaxPriceDistance is the largest distance of the price of a limit or stop order from the market price as a fraction of the market price, like 0.1 for 10%. Orders further away fail with ErrPriceTooFar. If zero, there is no limit.
+	MaxPriceDistance float64
+}
+
+// ContractMultiplier returns the Multiplier of the instrument, or 1 if it is not set.
+func (i Instrument) ContractMultiplier() float64 {
+	if i.Multiplier == 0 {
+		return 1
+	}
+	return i.Multiplier
+}
+
+// RoundPrice returns price rounded to the nearest tick. Zero prices, which mean no price, are not rounded.
+func (i Instrument) RoundPrice(price float64) float64 {
+	if i.TickSize <= 0 || price == 0 {
+		return price
+	}
+	return math.Round(price/i.TickSize) * i.TickSize
+}
+
+// Expired returns true if the instrument has an expiry that is not after t.
+func (i Instrument) Expired(t time.Time) bool {
+	return !i.Expiry.IsZero() && !t.Before(i.Expiry)
+}
+
+// IntrinsicValue returns the value of exercising an option at the price of the underlying, which is never negative. It returns underlyingPrice for every other type of instrument.
+func (i Instrument) IntrinsicValue(underlyingPrice float64) float64 {
+	if i.Type != Option {
+		return underlyingPrice
+	}
+	if i.Right == Put {
+		return math.Max(i.Strike-underlyingPrice, 0)
+	}
+	return math.Max(underlyingPrice-i.Strike, 0)
+}
+
+// Label returns a description of the contract for reports, like "ESZ2 (future x50, expires 2022-12-16)" or "AAPL221216C150 (AAPL 150 call x100, expires 2022-12-16)". The label of a spot instrument is its symbol.
+func (i Instrument) Label() string {
+	var desc string
+	switch i.Type {
+	case Future:
+		desc = fmt.Sprintf("future x%g", i.ContractMultiplier())
+	case Option:
+		underlying := i.Underlying
+		if underlying == "" {
+			underlying = "option"
+		}
+		desc = fmt.Sprintf("%s %g %s x%g", underlying, i.Strike, i.Right, i.ContractMultiplier())
+	default:
+		return i.Symbol
+	}
+	if !i.Expiry.IsZero() {
+		desc += ", expires " + i.Expiry.Format(time.DateOnly)
+	}
+	return fmt.Sprintf("%s (%s)", i.Symbol, desc)
+}
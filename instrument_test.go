@@ -0,0 +1,101 @@
+package autotrader
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestInstrument(t *testing.T) {
+	expiry := time.Date(2022, 12, 16, 0, 0, 0, 0, time.UTC)
+	future := Instrument{Symbol: "ESZ2", Type: Future, Multiplier: 50, TickSize: 0.25, Expiry: expiry}
+	if label := future.Label(); label != "ESZ2 (future x50, expires 2022-12-16)" {
+		t.Errorf("Expected future label, got %q", label)
+	}
+	if price := future.RoundPrice(4000.1); price != 4000 {
+		t.Errorf("Expected 4000.1 to round to 4000, got %v", price)
+	}
+	if future.Expired(expiry.Add(-time.Second)) || !future.Expired(expiry) {
+		t.Error("Expected the future to expire exactly at its expiry")
+	}
+
+	call := Instrument{Symbol: "AAPL221216C150", Type: Option, Underlying: "AAPL", Multiplier: 100, Strike: 150, Right: Call, Expiry: expiry}
+	if label := call.Label(); label != "AAPL221216C150 (AAPL 150 call x100, expires 2022-12-16)" {
+		t.Errorf("Expected option label, got %q", label)
+	}
+	if v := call.IntrinsicValue(155); v != 5 {
+		t.Errorf("Expected call intrinsic value of 5, got %v", v)
+	}
+	put := Instrument{Type: Option, Strike: 150, Right: Put}
+	if v := put.IntrinsicValue(155); v != 0 {
+		t.Errorf("Expected out of the money put to be worth 0, got %v", v)
+	}
+	if m := (Instrument{Symbol: "EUR_USD"}).ContractMultiplier(); m != 1 {
+		t.Errorf("Expected spot multiplier of 1, got %v", m)
+	}
+}
+
+func TestBacktestingBrokerInstruments(t *testing.T) {
+	expiry := time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC)
+	broker := NewTestBroker(nil, testData, 100_000, 1, 0, 0)
+	broker.Slippage = 0
+	broker.Instruments = map[string]Instrument{
+		"FUT":   {Symbol: "FUT", Type: Future, Multiplier: 50, TickSize: 0.25, Expiry: expiry},
+		"CALL":  {Symbol: "CALL", Type: Option, Underlying: "UND", Multiplier: 100, Strike: 1.2, Right: Call, Expiry: expiry},
+		"NOUND": {Symbol: "NOUND", Type: Option, Multiplier: 100, Strike: 1.2, Right: Call, Expiry: expiry},
+	}
+	// The call is priced at a premium of a tenth of the underlying, which is 0.1 above the prices of Data.
+	call, underlying := NewDOHLCVIndexedFrame[UnixTime](), NewDOHLCVIndexedFrame[UnixTime]()
+	for i := 0; i < testData.Len(); i++ {
+		call.PushCandle(*testData.Date(i), testData.Open(i)/10, testData.High(i)/10, testData.Low(i)/10, testData.Close(i)/10, 0)
+		underlying.PushCandle(*testData.Date(i), testData.Open(i)+0.1, testData.High(i)+0.1, testData.Low(i)+0.1, testData.Close(i)+0.1, 0)
+	}
+	broker.SymbolData = map[string]*IndexedFrame[UnixTime]{"CALL": call, "UND": underlying}
+
+	future, err := broker.Order(context.Background(), Market, "FUT", 2, 0, 0, 0) // Filled at 1.15.
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value := future.Position().EntryValue(); !EqualApprox(value, 1.15*2*50) {
+		t.Errorf("Expected entry value of %f, got %f", 1.15*2*50, value)
+	}
+	option, err := broker.Order(context.Background(), Market, "CALL", 1, 0, 0, 0) // Filled at a premium of 0.115.
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := broker.Order(context.Background(), Market, "NOUND", 1, 0, 0, 0); !errors.Is(err, ErrNoUnderlying) {
+		t.Errorf("Expected ErrNoUnderlying for an option without an underlying, got %v", err)
+	}
+	limit, err := broker.Order(context.Background(), Limit, "FUT", 1, 1.1, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limit.Price() != 1 {
+		t.Errorf("Expected the limit price to be rounded to the tick size of 0.25, got %v", limit.Price())
+	}
+
+	broker.Advance()
+	if future.Position().Closed() {
+		t.Fatal("Expected the future to be open before its expiry")
+	}
+	broker.Advance() // Expires at the close of 1.25.
+	position := future.Position()
+	if !position.Closed() || position.CloseType() != CloseExpiry || position.ClosePrice() != 1.25 {
+		t.Errorf("Expected the future to be settled at 1.25 with CloseExpiry, got %v at %v", position.CloseType(), position.ClosePrice())
+	}
+	if !EqualApprox(position.PL(), 0.1*2*50) {
+		t.Errorf("Expected PL of %f, got %f", 0.1*2*50, position.PL())
+	}
+	// The underlying closes at 1.35, so the call is in the money by 0.15.
+	if position := option.Position(); !position.Closed() || !EqualApprox(position.ClosePrice(), 0.15) {
+		t.Errorf("Expected the call to be settled at its intrinsic value of 0.15, got %v", position.ClosePrice())
+	} else if !EqualApprox(position.PL(), (0.15-0.115)*100) {
+		t.Errorf("Expected the call to profit %f, got %f", (0.15-0.115)*100, position.PL())
+	}
+
+	_, err = broker.Order(context.Background(), Market, "FUT", 1, 0, 0, 0)
+	if !errors.Is(err, ErrContractExpired) {
+		t.Errorf("Expected ErrContractExpired, got %v", err)
+	}
+}
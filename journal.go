@@ -0,0 +1,171 @@
+package autotrader
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// JournalEntry is a record of a single order or position event, along with a snapshot of the account when it happened.
+type JournalEntry struct {
+	Time       time.Time // Time is when the event was recorded.
+	Event      string    // Event is the name of the broker signal, like OrderPlaced or PositionClosed.
+	Symbol     string
+	OrderID    string
+	PositionID string
+	OrderType  OrderType      // OrderType is only set for order events.
+	CloseType  OrderCloseType // CloseType is only set when a position is closed.
+	Units      float64
+	Price      float64 // Price is the order price when an order is placed, the entry price when it is filled, and the close price when a position is closed.
+	StopLoss   float64
+	TakeProfit float64
+	PL         float64 // PL is the profit or loss of the position.
+	Tags       Tags
+	NAV        float64 // NAV is the net asset value of the account after the event.
+	AccountPL  float64 // AccountPL is the profit or loss of the account after the event.
+}
+
+// Journal records every order and position event of a Trader so that trading history survives restarts and can be audited. Set it on Trader.Journal before calling Init.
+type Journal interface {
+	Record(entry JournalEntry) error
+}
+
+// journalEntry creates the JournalEntry of a broker signal at now. The data is an Order or Position as given by the signal.
+func journalEntry(event string, data any, broker Broker, now time.Time) JournalEntry {
+	entry := JournalEntry{
+		Time:      now.UTC(),
+		Event:     event,
+		NAV:       broker.NAV(),
+		AccountPL: broker.PL(),
+	}
+	switch v := data.(type) {
+	case Order:
+		entry.Symbol = v.Symbol()
+		entry.OrderID = v.Id()
+		entry.OrderType = v.Type()
+		entry.Units = v.Units()
+		entry.Price = v.Price()
+		entry.StopLoss = v.StopLoss()
+		entry.TakeProfit = v.TakeProfit()
+		entry.Tags = v.Tags()
+		if v.Fulfilled() {
+			entry.PositionID = v.Position().Id()
+			entry.Price = v.Position().EntryPrice()
+		}
+	case Position:
+		entry.Symbol = v.Symbol()
+		entry.PositionID = v.Id()
+		entry.Units = v.Units()
+		entry.Price = v.EntryPrice()
+		entry.StopLoss = v.StopLoss()
+		if v.TrailingStop() > 0 {
+			entry.StopLoss = v.TrailingStop()
+		}
+		entry.TakeProfit = v.TakeProfit()
+		entry.PL = v.PL()
+		entry.Tags = v.Tags()
+		if v.Closed() {
+			entry.CloseType = v.CloseType()
+			entry.Price = v.ClosePrice()
+		}
+	}
+	return entry
+}
+
+// JournalTrades rebuilds the trades of a journal as TradeStats, like the ones recorded in the Trades column of the stats of a Trader. Entry trades come from OrderFulfilled events and exit trades come from PositionClosed events. Trade costs are not journaled, so they are zero.
+func JournalTrades(entries []JournalEntry) []TradeStat {
+	trades := make([]TradeStat, 0)
+	open := make(map[string]*TradeStat)
+	for _, entry := range entries {
+		switch entry.Event {
+		case OrderFulfilled:
+			trade := TradeStat{Price: entry.Price, Units: entry.Units, PositionID: entry.PositionID, OpenTime: entry.Time, Tags: entry.Tags}
+			trades = append(trades, trade)
+			open[entry.PositionID] = &trade
+		case PositionClosed:
+			trade := TradeStat{Price: entry.Price, Units: entry.Units, Exit: true, PositionID: entry.PositionID, CloseTime: entry.Time, Tags: entry.Tags}
+			if opened, ok := open[entry.PositionID]; ok {
+				trade.OpenTime = opened.OpenTime
+				trade.Entry = opened
+				delete(open, entry.PositionID)
+			}
+			trades = append(trades, trade)
+		}
+	}
+	return trades
+}
+
+// SQLiteJournal is a Journal that writes entries to a table named journal in a SQLite database. The database driver is not imported by this package, so import one like github.com/mattn/go-sqlite3 in your program and open the database with sql.Open.
+type SQLiteJournal struct {
+	db *sql.DB
+}
+
+// NewSQLiteJournal returns a SQLiteJournal that writes to db, and creates the journal table if it does not exist.
+func NewSQLiteJournal(db *sql.DB) (*SQLiteJournal, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS journal (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		time TEXT NOT NULL,
+		event TEXT NOT NULL,
+		symbol TEXT NOT NULL,
+		order_id TEXT NOT NULL,
+		position_id TEXT NOT NULL,
+		order_type TEXT NOT NULL,
+		close_type TEXT NOT NULL,
+		units REAL NOT NULL,
+		price REAL NOT NULL,
+		stop_loss REAL NOT NULL,
+		take_profit REAL NOT NULL,
+		pl REAL NOT NULL,
+		tags TEXT NOT NULL,
+		nav REAL NOT NULL,
+		account_pl REAL NOT NULL
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("creating journal table: %w", err)
+	}
+	return &SQLiteJournal{db}, nil
+}
+
+func (j *SQLiteJournal) Record(entry JournalEntry) error {
+	tags, err := json.Marshal(entry.Tags)
+	if err != nil {
+		return err
+	}
+	_, err = j.db.Exec(`INSERT INTO journal (time, event, symbol, order_id, position_id, order_type, close_type, units, price, stop_loss, take_profit, pl, tags, nav, account_pl)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.Time.Format(time.RFC3339Nano), entry.Event, entry.Symbol, entry.OrderID, entry.PositionID,
+		string(entry.OrderType), string(entry.CloseType), entry.Units, entry.Price, entry.StopLoss,
+		entry.TakeProfit, entry.PL, string(tags), entry.NAV, entry.AccountPL)
+	return err
+}
+
+// Entries returns every entry in the journal in the order they were recorded.
+func (j *SQLiteJournal) Entries() ([]JournalEntry, error) {
+	rows, err := j.db.Query(`SELECT time, event, symbol, order_id, position_id, order_type, close_type, units, price, stop_loss, take_profit, pl, tags, nav, account_pl
+		FROM journal ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []JournalEntry
+	for rows.Next() {
+		var entry JournalEntry
+		var date, orderType, closeType, tags string
+		err := rows.Scan(&date, &entry.Event, &entry.Symbol, &entry.OrderID, &entry.PositionID, &orderType, &closeType,
+			&entry.Units, &entry.Price, &entry.StopLoss, &entry.TakeProfit, &entry.PL, &tags, &entry.NAV, &entry.AccountPL)
+		if err != nil {
+			return nil, err
+		}
+		if entry.Time, err = time.Parse(time.RFC3339Nano, date); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(tags), &entry.Tags); err != nil {
+			return nil, err
+		}
+		entry.OrderType, entry.CloseType = OrderType(orderType), OrderCloseType(closeType)
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
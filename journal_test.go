@@ -0,0 +1,70 @@
+package autotrader
+
+import (
+	"io"
+	"testing"
+)
+
+type memoryJournal struct {
+	entries []JournalEntry
+}
+
+func (j *memoryJournal) Record(entry JournalEntry) error {
+	j.entries = append(j.entries, entry)
+	return nil
+}
+
+func TestJournal(t *testing.T) {
+	journal := &memoryJournal{}
+	broker := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	trader := NewTrader(TraderConfig{
+		Broker:        broker,
+		Strategy:      &roundTripStrategy{},
+		Symbol:        "EUR_USD",
+		Frequency:     "D",
+		CandlesToKeep: 5,
+		Journal:       journal,
+	})
+	trader.Log.SetOutput(io.Discard)
+	trader.Init()
+	for !trader.EOF {
+		trader.Tick()
+		broker.Advance()
+	}
+
+	events := make([]string, len(journal.entries))
+	for i, entry := range journal.entries {
+		events[i] = entry.Event
+	}
+	expected := []string{OrderPlaced, OrderFulfilled, PositionClosed}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected events %v, got %v", expected, events)
+	}
+	for i := range expected {
+		if events[i] != expected[i] {
+			t.Errorf("Expected event %d to be %s, got %s", i, expected[i], events[i])
+		}
+	}
+	for _, entry := range journal.entries {
+		if entry.Symbol != "EUR_USD" {
+			t.Errorf("Expected symbol EUR_USD in %s, got %q", entry.Event, entry.Symbol)
+		}
+		if entry.NAV <= 0 {
+			t.Errorf("Expected a NAV snapshot in %s, got %v", entry.Event, entry.NAV)
+		}
+	}
+
+	trades := JournalTrades(journal.entries)
+	stats := trader.Stats().Trades()
+	if len(trades) != len(stats) {
+		t.Fatalf("Expected %d trades, got %d", len(stats), len(trades))
+	}
+	for i := range trades {
+		if trades[i].Price != stats[i].Price || trades[i].Units != stats[i].Units || trades[i].Exit != stats[i].Exit {
+			t.Errorf("Expected trade %d to be %+v, got %+v", i, stats[i], trades[i])
+		}
+	}
+	if trades[1].Entry == nil || trades[1].Entry.PositionID != trades[0].PositionID {
+		t.Errorf("Expected the exit trade to link to its entry, got %+v", trades[1].Entry)
+	}
+}
@@ -0,0 +1,90 @@
+package autotrader
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Locale formats the money and numbers of reports the way readers of a country and account currency expect, like "$1,234.56", "1.234,56 €", or "¥1,235".
+type Locale struct {
+	Currency        string // Currency is the symbol of the account currency, like "$", "€", or "¥".
+	CurrencyAfter   bool   // CurrencyAfter places the currency symbol after the amount, separated by a space, like "12,50 €".
+	Decimal         string // Decimal separates the fraction from the whole of a number. It is "." if empty.
+	Thousands       string // Thousands groups the digits of the whole of a number by three, like "," or ".". Digits are not grouped if empty.
+	MoneyDecimals   int    // MoneyDecimals is the number of decimals of money amounts, like 2 for cents or 0 for yen.
+	DateLayout      string // DateLayout replaces the year, month, and day of the layout of dates picked from the frequency of the trader, like "02.01.2006". Times of day are kept. It is not replaced if empty.
+	PercentDecimals int    // PercentDecimals is the number of decimals of percentages.
+}
+
+var (
+	// DefaultLocale formats money in dollars with two decimals and no grouping of thousands, like "$1234.56". It is used by reports without a Locale.
+	DefaultLocale = Locale{Currency: "$", Decimal: ".", MoneyDecimals: 2, PercentDecimals: 2}
+	LocaleUS      = Locale{Currency: "$", Decimal: ".", Thousands: ",", MoneyDecimals: 2, PercentDecimals: 2}                                                  // LocaleUS formats money like "$1,234.56".
+	LocaleUK      = Locale{Currency: "£", Decimal: ".", Thousands: ",", MoneyDecimals: 2, PercentDecimals: 2, DateLayout: "02/01/2006"}                        // LocaleUK formats money like "£1,234.56" and dates like "31/12/2022".
+	LocaleEuro    = Locale{Currency: "€", CurrencyAfter: true, Decimal: ",", Thousands: ".", MoneyDecimals: 2, PercentDecimals: 2, DateLayout: "02.01.2006"}   // LocaleEuro formats money like "1.234,56 €" and dates like "31.12.2022".
+	LocaleSwiss   = Locale{Currency: "CHF", CurrencyAfter: true, Decimal: ".", Thousands: "'", MoneyDecimals: 2, PercentDecimals: 2, DateLayout: "02.01.2006"} // LocaleSwiss formats money like "1'234.56 CHF".
+	LocaleJapan   = Locale{Currency: "¥", Decimal: ".", Thousands: ",", MoneyDecimals: 0, PercentDecimals: 2, DateLayout: "2006/01/02"}                        // LocaleJapan formats money in whole yen like "¥1,235" and dates like "2022/12/31".
+)
+
+// Number formats v with the given number of decimals and the separators of the locale, like "1,234.5".
+func (l Locale) Number(v float64, decimals int) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	digits := strconv.FormatFloat(math.Abs(v), 'f', decimals, 64)
+	whole, fraction, _ := strings.Cut(digits, ".")
+	var b strings.Builder
+	if v < 0 && strings.Trim(digits, "0.") != "" { // No "-0.00".
+		b.WriteByte('-')
+	}
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteString(l.Thousands)
+		}
+		b.WriteRune(digit)
+	}
+	if fraction != "" {
+		if l.Decimal == "" {
+			b.WriteByte('.')
+		} else {
+			b.WriteString(l.Decimal)
+		}
+		b.WriteString(fraction)
+	}
+	return b.String()
+}
+
+// Money formats an amount of the account currency, like "-$1,234.56" or "-1.234,56 €".
+func (l Locale) Money(v float64) string {
+	number := l.Number(v, l.MoneyDecimals)
+	if l.CurrencyAfter {
+		return number + " " + l.Currency
+	}
+	if sign, ok := strings.CutPrefix(number, "-"); ok {
+		return "-" + l.Currency + sign
+	}
+	return l.Currency + number
+}
+
+// Percent formats a percentage, like "12.34%".
+func (l Locale) Percent(v float64) string {
+	return l.Number(v, l.PercentDecimals) + "%"
+}
+
+// dateLayout returns layout with its year, month, and day in the order of the locale.
+func (l Locale) dateLayout(layout string) string {
+	if l.DateLayout == "" {
+		return layout
+	}
+	return strings.Replace(layout, time.DateOnly, l.DateLayout, 1)
+}
+
+// axisFormatter returns the ECharts formatter of an axis of money, like "${value}" or "{value} €".
+func (l Locale) axisFormatter() string {
+	if l.CurrencyAfter {
+		return "{value} " + l.Currency
+	}
+	return l.Currency + "{value}"
+}
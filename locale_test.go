@@ -0,0 +1,65 @@
+package autotrader
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocale(t *testing.T) {
+	for _, test := range []struct {
+		locale   Locale
+		v        float64
+		expected string
+	}{
+		{DefaultLocale, 1234567.891, "$1234567.89"},
+		{DefaultLocale, -0.001, "$0.00"},
+		{LocaleUS, 1234567.891, "$1,234,567.89"},
+		{LocaleUS, -1234.5, "-$1,234.50"},
+		{LocaleUS, 999.999, "$1,000.00"},
+		{LocaleEuro, -1234.5, "-1.234,50 €"},
+		{LocaleSwiss, 1234.5, "1'234.50 CHF"},
+		{LocaleJapan, 1234.5, "¥1,234"}, // Rounded half to even.
+		{LocaleJapan, 123456, "¥123,456"},
+	} {
+		if money := test.locale.Money(test.v); money != test.expected {
+			t.Errorf("Expected %v to be formatted as %q, got %q", test.v, test.expected, money)
+		}
+	}
+	if pct := LocaleEuro.Percent(12.5); pct != "12,50%" {
+		t.Errorf("Expected a percentage with a decimal comma, got %q", pct)
+	}
+	if nan := LocaleUS.Number(math.NaN(), 2); nan != "NaN" {
+		t.Errorf("Expected NaN, got %q", nan)
+	}
+	if layout := LocaleEuro.dateLayout("2006-01-02 15:04"); layout != "02.01.2006 15:04" {
+		t.Errorf("Expected the time of day to be kept, got %q", layout)
+	}
+}
+
+func TestReportLocale(t *testing.T) {
+	trader, broker := runTestBacktest(t, &roundTripStrategy{})
+	var out bytes.Buffer
+	report := &Report{
+		Filename: filepath.Join(t.TempDir(), "report.html"),
+		Out:      &out,
+		Sections: []ReportSection{SummarySection, TradesSection, EquitySection},
+		Locale:   &LocaleEuro,
+	}
+	if err := report.Generate(trader, broker, 0); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "$") || !strings.Contains(out.String(), " €") || !strings.Contains(out.String(), ".01.2022") {
+		t.Errorf("Expected money in euros and dates day first, got:\n%s", out.String())
+	}
+	page, err := os.ReadFile(report.Filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(page), "{value} €") {
+		t.Error("Expected the axes of the charts to be in euros")
+	}
+}
@@ -0,0 +1,101 @@
+package autotrader
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+)
+
+// ParameterizedStrategy is an optional interface a Strategy may implement to report the parameters it was run with. Strategies that do not implement it have their exported fields of basic types recorded instead.
+type ParameterizedStrategy interface {
+	Parameters() map[string]any
+}
+
+// RunManifest is a machine-readable record of a single backtest run, written as result.json so external tooling like dashboards and experiment trackers can index runs.
+type RunManifest struct {
+	Strategy       string          `json:"strategy"`
+	Parameters     map[string]any  `json:"parameters"`
+	Symbol         string          `json:"symbol"`
+	Frequency      string          `json:"frequency"`
+	Start          time.Time       `json:"start"` // Start is the date of the first candle.
+	End            time.Time       `json:"end"`   // End is the date of the last candle.
+	Seed           uint64          `json:"seed"`  // Seed is the seed of the TestBroker, which reproduces the slippage of the run.
+	GeneratedAt    time.Time       `json:"generated_at"`
+	ElapsedSeconds float64         `json:"elapsed_seconds"`
+	Summary        BacktestSummary `json:"summary"`
+}
+
+// NewRunManifest creates the RunManifest of the backtest described by ctx.
+func NewRunManifest(ctx *ReportContext) RunManifest {
+	m := RunManifest{
+		Strategy:       strategyName(ctx.Trader.Strategy),
+		Parameters:     StrategyParameters(ctx.Trader.Strategy),
+		Symbol:         ctx.Trader.Symbol,
+		Frequency:      ctx.Trader.Frequency,
+		GeneratedAt:    time.Now().UTC(),
+		ElapsedSeconds: ctx.Elapsed.Seconds(),
+		Summary:        ctx.Summary,
+	}
+	if ctx.Stats.Dated != nil && ctx.Stats.Dated.Len() > 0 {
+		m.Start = ctx.Stats.Dated.Date(0)
+		m.End = ctx.Stats.Dated.Date(-1)
+	}
+	if ctx.Broker != nil {
+		m.Seed = ctx.Broker.Seed
+	}
+	return m
+}
+
+// ManifestSection returns a ReportSection that writes the RunManifest of the backtest as indented JSON to filename in the report directory.
+func ManifestSection(filename string) ReportSection {
+	return ReportSectionFunc(func(ctx *ReportContext) error {
+		data, err := json.MarshalIndent(NewRunManifest(ctx), "", "  ")
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(ctx.Path(filename), append(data, '\n'), 0644)
+	})
+}
+
+// StrategyParameters returns the parameters of the strategy. If the strategy implements ParameterizedStrategy, its parameters are returned. Otherwise, the exported fields of the strategy struct that are numbers, strings, or booleans are returned by name.
+func StrategyParameters(strategy Strategy) map[string]any {
+	if p, ok := strategy.(ParameterizedStrategy); ok {
+		return p.Parameters()
+	}
+	params := make(map[string]any)
+	v := reflect.Indirect(reflect.ValueOf(strategy))
+	if v.Kind() != reflect.Struct {
+		return params
+	}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		switch field.Type.Kind() {
+		case reflect.Bool, reflect.String,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			params[field.Name] = v.Field(i).Interface()
+		}
+	}
+	return params
+}
+
+// strategyName returns the name of the strategy's type without the package or pointer.
+func strategyName(strategy Strategy) string {
+	t := reflect.TypeOf(strategy)
+	if t == nil {
+		return ""
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Name() == "" {
+		return fmt.Sprintf("%T", strategy)
+	}
+	return t.Name()
+}
@@ -0,0 +1,65 @@
+package autotrader
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type paramStrategy struct {
+	roundTripStrategy
+	Period    int
+	Threshold float64
+	OnSignal  func() // Not a parameter.
+	private   int
+}
+
+func TestStrategyParameters(t *testing.T) {
+	params := StrategyParameters(&paramStrategy{Period: 14, Threshold: 0.5})
+	if len(params) != 2 {
+		t.Fatalf("Expected 2 parameters, got %v", params)
+	}
+	if params["Period"] != 14 || params["Threshold"] != 0.5 {
+		t.Errorf("Expected Period 14 and Threshold 0.5, got %v", params)
+	}
+	if name := strategyName(&paramStrategy{}); name != "paramStrategy" {
+		t.Errorf("Expected strategy name to be paramStrategy, got %q", name)
+	}
+}
+
+func TestManifestSection(t *testing.T) {
+	trader, broker := runTestBacktest(t, &paramStrategy{Period: 3})
+	broker.Seed = 42
+
+	filename := filepath.Join(t.TempDir(), "result.json")
+	report := &Report{Sections: []ReportSection{ManifestSection(filename)}}
+	if err := report.Generate(trader, broker, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest RunManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Strategy != "paramStrategy" {
+		t.Errorf("Expected strategy to be paramStrategy, got %q", manifest.Strategy)
+	}
+	if manifest.Parameters["Period"] != 3.0 { // JSON numbers decode as float64.
+		t.Errorf("Expected Period parameter to be 3, got %v", manifest.Parameters["Period"])
+	}
+	if manifest.Seed != 42 {
+		t.Errorf("Expected seed to be 42, got %d", manifest.Seed)
+	}
+	if !manifest.Start.Equal(testData.Date(0).Time()) || !manifest.End.Equal(testData.Date(-1).Time()) {
+		t.Errorf("Expected data range %s to %s, got %s to %s", testData.Date(0), testData.Date(-1), manifest.Start, manifest.End)
+	}
+	if manifest.Summary.Trades != 1 {
+		t.Errorf("Expected the summary to have 1 trade, got %d", manifest.Summary.Trades)
+	}
+}
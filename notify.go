@@ -0,0 +1,27 @@
+package autotrader
+
+import (
+	"log"
+)
+
+// Notifier sends alerts about a running Trader to its operator, like a log, a chat message, or an email.
+type Notifier interface {
+	Notify(message string) error
+}
+
+// NotifierFunc is a function that implements Notifier.
+type NotifierFunc func(message string) error
+
+func (f NotifierFunc) Notify(message string) error {
+	return f(message)
+}
+
+// LogNotifier is a Notifier that prints every alert to a logger.
+type LogNotifier struct {
+	Log *log.Logger
+}
+
+func (n *LogNotifier) Notify(message string) error {
+	n.Log.Printf("Alert: %s", message)
+	return nil
+}
@@ -13,6 +13,12 @@ type CandlestickResponse struct {
 	Candles     []Candlestick `json:"candles"`     // The list of candlesticks that satisfy the request.
 }
 
+// ErrorResponse represents the body of an unsuccessful response from the Oanda API.
+type ErrorResponse struct {
+	ErrorCode    string `json:"errorCode"`    // The code of the error, like a reject reason.
+	ErrorMessage string `json:"errorMessage"` // The human-readable description of the error.
+}
+
 // Candlestick represents a single candlestick.
 type Candlestick struct {
 	Time     time.Time        `json:"time"`     // The start time of the candlestick.
@@ -55,3 +61,60 @@ func (d CandlestickData) Parse(o, h, l, c *float64) error {
 	}
 	return nil
 }
+
+// OrderRequest is the body of a request to create an order.
+type OrderRequest struct {
+	Order OrderSpec `json:"order"`
+}
+
+// OrderSpec specifies a market, limit, or stop order to create.
+type OrderSpec struct {
+	Type                   string         `json:"type"`                             // The type of the order: MARKET, LIMIT, or STOP.
+	Instrument             string         `json:"instrument"`                       // The instrument of the order.
+	Units                  string         `json:"units"`                            // The units of the order. Positive units buy and negative units sell.
+	Price                  string         `json:"price,omitempty"`                  // The price of a limit or stop order.
+	TimeInForce            string         `json:"timeInForce"`                      // How long the order is in force, like FOK for market orders and GTC for the others.
+	GtdTime                *time.Time     `json:"gtdTime,omitempty"`                // When a GTD order expires.
+	StopLossOnFill         *OnFillDetails `json:"stopLossOnFill,omitempty"`         // The stop loss to create for the trade the order opens.
+	TakeProfitOnFill       *OnFillDetails `json:"takeProfitOnFill,omitempty"`       // The take profit to create for the trade the order opens.
+	TrailingStopLossOnFill *OnFillDetails `json:"trailingStopLossOnFill,omitempty"` // The trailing stop loss to create for the trade the order opens.
+}
+
+// OnFillDetails specifies an exit of the trade opened by an order, by its price or its distance from the fill.
+type OnFillDetails struct {
+	Price    string `json:"price,omitempty"`
+	Distance string `json:"distance,omitempty"`
+}
+
+// OrderResponse is the response to a request to create, cancel, or close an order or trade. Only the transactions that happened are set.
+type OrderResponse struct {
+	OrderCreateTransaction       *Transaction `json:"orderCreateTransaction"`       // The transaction that created the order.
+	OrderFillTransaction         *Transaction `json:"orderFillTransaction"`         // The transaction that filled the order, if it was filled immediately.
+	OrderCancelTransaction       *Transaction `json:"orderCancelTransaction"`       // The transaction that cancelled the order, like a market order that could not be filled.
+	OrderRejectTransaction       *Transaction `json:"orderRejectTransaction"`       // The transaction that rejected the order.
+	OrderCancelRejectTransaction *Transaction `json:"orderCancelRejectTransaction"` // The transaction that rejected a request to cancel the order.
+	ErrorCode                    string       `json:"errorCode"`                    // The code of the error of an unsuccessful request.
+	ErrorMessage                 string       `json:"errorMessage"`                 // The human-readable description of the error of an unsuccessful request.
+}
+
+// Transaction is a transaction of an account. Only the fields used by the broker are decoded.
+type Transaction struct {
+	ID             string     `json:"id"`                    // The ID of the transaction.
+	Time           time.Time  `json:"time"`                  // The time of the transaction.
+	OrderID        string     `json:"orderID"`               // The ID of the order the transaction is about.
+	Price          float64    `json:"price,string"`          // The price the order was filled at.
+	PL             float64    `json:"pl,string"`             // The profit or loss realized by the fill.
+	Commission     float64    `json:"commission,string"`     // The commission charged for the fill.
+	Financing      float64    `json:"financing,string"`      // The financing paid or received by the trades closed by the fill.
+	HalfSpreadCost float64    `json:"halfSpreadCost,string"` // The cost of half the spread paid by the fill.
+	Reason         string     `json:"reason"`                // The reason an order was cancelled.
+	RejectReason   string     `json:"rejectReason"`          // The reason an order was rejected.
+	TradeOpened    *TradeOpen `json:"tradeOpened"`           // The trade opened by the fill.
+}
+
+// TradeOpen is the trade opened by the fill of an order.
+type TradeOpen struct {
+	TradeID string  `json:"tradeID"`      // The ID of the trade.
+	Units   float64 `json:"units,string"` // The units of the trade.
+	Price   float64 `json:"price,string"` // The price the trade was opened at.
+}
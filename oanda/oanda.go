@@ -1,10 +1,15 @@
 package oanda
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	auto "github.com/fivemoreminix/autotrader"
@@ -22,10 +27,15 @@ var _ auto.Broker = (*OandaBroker)(nil) // Compile-time interface check.
 
 type OandaBroker struct {
 	*auto.SignalManager
-	client    *http.Client
-	token     string
-	accountID string
-	baseUrl   string // Either oandaLiveURL or oandaPracticeURL.
+	// Instruments are the trading constraints of symbols, like their minimum and maximum units, which orders are validated against before they are sent. Symbols without an instrument are only checked for zero units and the sides of their stop loss and take profit.
+	Instruments map[string]auto.Instrument
+	client      *http.Client
+	token       string
+	accountID   string
+	baseUrl     string     // Either oandaLiveURL or oandaPracticeURL.
+	mu          sync.Mutex // mu guards the orders and positions and their state.
+	orders      []*Order   // orders are the orders placed by the broker.
+	positions   []*Position
 }
 
 func NewOandaBroker(token, accountID string, practice bool) (*OandaBroker, error) {
@@ -64,8 +74,8 @@ func (b *OandaBroker) Ask(symbol string) float64 {
 	return 0
 }
 
-func (b *OandaBroker) Candles(symbol, frequency string, count int) (*auto.IndexedFrame[auto.UnixTime], error) {
-	req, err := http.NewRequest("GET", b.baseUrl+"/v3/accounts/"+b.accountID+"/instruments/"+symbol+"/candles", nil)
+func (b *OandaBroker) Candles(ctx context.Context, symbol, frequency string, count int) (*auto.IndexedFrame[auto.UnixTime], error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", b.baseUrl+"/v3/accounts/"+b.accountID+"/instruments/"+symbol+"/candles", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -79,6 +89,9 @@ func (b *OandaBroker) Candles(symbol, frequency string, count int) (*auto.Indexe
 		return nil, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
 
 	var candlestickResponse *CandlestickResponse
 	if err := json.NewDecoder(resp.Body).Decode(&candlestickResponse); err != nil {
@@ -88,8 +101,129 @@ func (b *OandaBroker) Candles(symbol, frequency string, count int) (*auto.Indexe
 	return newDataframe(candlestickResponse)
 }
 
-func (b *OandaBroker) Order(orderType auto.OrderType, symbol string, units, price, stopLoss, takeProfit float64) (auto.Order, error) {
-	return nil, nil
+// Order places an order with Oanda. Limit and stop orders are good until cancelled unless another time in force is given, and market orders are fill or kill. A negative stopLoss is a trailing stop distance, like for any Broker. Orders that Oanda rejects or cancels, like market orders it cannot fill, fail with an *auto.OrderError that wraps the error of the reason.
+func (b *OandaBroker) Order(ctx context.Context, orderType auto.OrderType, symbol string, units, price, stopLoss, takeProfit float64, options ...auto.OrderOption) (auto.Order, error) {
+	orderOptions := auto.NewOrderOptions(options...)
+	unsupported := func(reason string) error {
+		return &auto.OrderError{Err: auto.ErrUnsupportedOrder, OrderType: orderType, Symbol: symbol, Units: units, Price: price, Reason: reason}
+	}
+	instrument, ok := b.Instruments[symbol]
+	if !ok {
+		instrument.Symbol = symbol
+	}
+	if err := auto.ValidateOrder(instrument, orderType, units, price, stopLoss, takeProfit, b.Price(symbol, units > 0)); err != nil {
+		return nil, err
+	}
+
+	spec := OrderSpec{Instrument: symbol, Units: formatFloat(units), TimeInForce: "GTC"}
+	switch orderType {
+	case auto.Market:
+		spec.Type, spec.TimeInForce = "MARKET", "FOK"
+	case auto.Limit:
+		spec.Type, spec.Price = "LIMIT", formatFloat(price)
+	case auto.Stop:
+		spec.Type, spec.Price = "STOP", formatFloat(price)
+	default:
+		return nil, unsupported(string(orderType))
+	}
+	switch orderOptions.TimeInForce {
+	case auto.GoodTilCancelled:
+	case auto.GoodTilDate:
+		if orderType == auto.Market {
+			return nil, unsupported("market orders cannot be good til date")
+		}
+		expiry := orderOptions.Expiry.UTC()
+		spec.TimeInForce, spec.GtdTime = "GTD", &expiry
+	default:
+		spec.TimeInForce = string(orderOptions.TimeInForce)
+	}
+	trailing := orderOptions.TrailingStop
+	if trailing.Value <= 0 && stopLoss < 0 {
+		trailing = auto.TrailingStop{Mode: auto.TrailingDistance, Value: -stopLoss}
+	}
+	o := &Order{broker: b, symbol: symbol, orderType: orderType, units: units, price: price, takeProfit: takeProfit, time: time.Now(), tags: orderOptions.Tags}
+	if trailing.Value > 0 {
+		if trailing.Mode != auto.TrailingDistance {
+			return nil, unsupported("Oanda only trails stop losses by a distance")
+		}
+		spec.TrailingStopLossOnFill = &OnFillDetails{Distance: formatFloat(trailing.Value)}
+		o.trailingStop = trailing.Value
+	} else if stopLoss > 0 {
+		spec.StopLossOnFill = &OnFillDetails{Price: formatFloat(stopLoss)}
+		o.stopLoss = stopLoss
+	}
+	if takeProfit > 0 {
+		spec.TakeProfitOnFill = &OnFillDetails{Price: formatFloat(takeProfit)}
+	}
+
+	var resp OrderResponse
+	if err := b.send(ctx, "POST", "/orders", OrderRequest{Order: spec}, &resp); err != nil {
+		return nil, rejectError(&resp, err, orderType, symbol, units, price)
+	}
+	if resp.OrderCreateTransaction == nil {
+		return nil, fmt.Errorf("oanda did not create the order")
+	}
+	o.id = resp.OrderCreateTransaction.ID
+	if !resp.OrderCreateTransaction.Time.IsZero() {
+		o.time = resp.OrderCreateTransaction.Time
+	}
+	b.mu.Lock()
+	b.orders = append(b.orders, o)
+	b.mu.Unlock()
+	b.SignalEmit(auto.OrderPlaced, o)
+
+	if cancel := resp.OrderCancelTransaction; cancel != nil {
+		b.mu.Lock()
+		o.cancelled = true
+		b.mu.Unlock()
+		b.SignalEmit(auto.OrderCancelled, o)
+		return nil, orderError(cancel.Reason, orderType, symbol, units, price)
+	}
+	if fill := resp.OrderFillTransaction; fill != nil && fill.TradeOpened != nil {
+		trade := fill.TradeOpened
+		p := &Position{broker: b, id: trade.TradeID, symbol: symbol, units: trade.Units, entryPrice: trade.Price, stopLoss: o.stopLoss, takeProfit: takeProfit, trailingStop: o.trailingStop, time: fill.Time, tags: o.tags}
+		b.mu.Lock()
+		o.position, o.costs = p, auto.TradeCosts{Spread: fill.HalfSpreadCost, Commission: fill.Commission}
+		b.positions = append(b.positions, p)
+		b.mu.Unlock()
+		b.SignalEmit(auto.OrderFulfilled, o)
+	}
+	return o, nil
+}
+
+// send makes a request with a JSON body to path under the account and decodes the JSON response into out, which is also decoded for an unsuccessful response before its error is returned.
+func (b *OandaBroker) send(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, b.baseUrl+"/v3/accounts/"+b.accountID+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+b.token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil && resp.StatusCode < 300 {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("oanda responded with status %s", resp.Status)
+	}
+	return nil
+}
+
+// formatFloat formats f for a request without an exponent or trailing zeros.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
 }
 
 func (b *OandaBroker) NAV() float64 {
@@ -100,25 +234,142 @@ func (b *OandaBroker) PL() float64 {
 	return 0
 }
 
+// OpenOrders returns the orders placed by the broker that have not been filled or cancelled.
 func (b *OandaBroker) OpenOrders() []auto.Order {
-	return nil
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var orders []auto.Order
+	for _, o := range b.orders {
+		if o.position == nil && !o.cancelled {
+			orders = append(orders, o)
+		}
+	}
+	return orders
 }
 
+// OpenPositions returns the trades opened by the orders of the broker that have not been closed.
 func (b *OandaBroker) OpenPositions() []auto.Position {
-	return nil
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var positions []auto.Position
+	for _, p := range b.positions {
+		if !p.closed {
+			positions = append(positions, p)
+		}
+	}
+	return positions
 }
 
+// Orders returns every order placed by the broker.
 func (b *OandaBroker) Orders() []auto.Order {
-	return nil
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	orders := make([]auto.Order, len(b.orders))
+	for i, o := range b.orders {
+		orders[i] = o
+	}
+	return orders
 }
 
+// Positions returns every trade opened by the orders of the broker.
 func (b *OandaBroker) Positions() []auto.Position {
-	return nil
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	positions := make([]auto.Position, len(b.positions))
+	for i, p := range b.positions {
+		positions[i] = p
+	}
+	return positions
+}
+
+func (b *OandaBroker) OrderByID(_ context.Context, id string) (auto.Order, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, o := range b.orders {
+		if o.id == id {
+			return o, nil
+		}
+	}
+	return nil, auto.ErrOrderNotFound
+}
+
+func (b *OandaBroker) PositionByID(_ context.Context, id string) (auto.Position, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, p := range b.positions {
+		if p.id == id {
+			return p, nil
+		}
+	}
+	return nil, auto.ErrPositionNotFound
+}
+
+// Capabilities returns the capabilities of an OANDA v20 account, which does not hedge by default and only trails stop losses by a distance. Candles are polled rather than streamed.
+func (b *OandaBroker) Capabilities() auto.Capabilities {
+	return auto.Capabilities{
+		OrderTypes:    []auto.OrderType{auto.Market, auto.Limit, auto.Stop},
+		StopLoss:      true,
+		TakeProfit:    true,
+		TrailingStops: []auto.TrailingStopMode{auto.TrailingDistance},
+		MinFrequency:  "S5",
+	}
 }
 
 func (b *OandaBroker) fetchAccountUpdates() {
 }
 
+// responseError returns the error of an unsuccessful response. Unknown instruments are reported as auto.ErrSymbolNotFound.
+func responseError(resp *http.Response) error {
+	var errResp ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
+		return fmt.Errorf("oanda responded with status %s", resp.Status)
+	}
+	if resp.StatusCode == http.StatusNotFound || strings.Contains(errResp.ErrorMessage, "instrument") {
+		return fmt.Errorf("%w: %s", auto.ErrSymbolNotFound, errResp.ErrorMessage)
+	}
+	return fmt.Errorf("oanda responded with status %s: %s", resp.Status, errResp.ErrorMessage)
+}
+
+// rejectReasonErrors maps the reject reasons of Oanda order transactions to the order errors of autotrader.
+var rejectReasonErrors = map[string]error{
+	"INSUFFICIENT_MARGIN":      auto.ErrInsufficientMargin,
+	"MARKET_HALTED":            auto.ErrMarketClosed,
+	"INSTRUMENT_NOT_TRADEABLE": auto.ErrMarketClosed,
+	"UNITS_MINIMUM_NOT_MET":    auto.ErrUnitsBelowMinimum,
+	"UNITS_LIMIT_EXCEEDED":     auto.ErrUnitsAboveMaximum,
+	"UNITS_INVALID":            auto.ErrInvalidUnits,
+	"UNITS_PRECISION_EXCEEDED": auto.ErrInvalidUnits,
+	"PRICE_DISTANCE_EXCEEDED":  auto.ErrPriceTooFar,
+	"INSTRUMENT_UNKNOWN":       auto.ErrSymbolNotFound,
+	"INSTRUMENT_MISSING":       auto.ErrSymbolNotFound,
+}
+
+// rejectError returns the order error of the reject reason or error code in the response of an unsuccessful order request, or err if the response has neither.
+func rejectError(resp *OrderResponse, err error, orderType auto.OrderType, symbol string, units, price float64) error {
+	if reject := resp.OrderRejectTransaction; reject != nil {
+		return orderError(reject.RejectReason, orderType, symbol, units, price)
+	} else if resp.ErrorCode != "" {
+		return orderError(resp.ErrorCode, orderType, symbol, units, price)
+	}
+	return err
+}
+
+// orderError returns the auto.OrderError of an order that Oanda rejected for reason. Reasons about the stop loss or take profit on fill are reported as auto.ErrInvalidStopLoss or auto.ErrInvalidTakeProfit.
+func orderError(reason string, orderType auto.OrderType, symbol string, units, price float64) error {
+	err, ok := rejectReasonErrors[reason]
+	if !ok {
+		switch {
+		case strings.HasPrefix(reason, "STOP_LOSS"), strings.HasPrefix(reason, "TRAILING_STOP_LOSS"):
+			err = auto.ErrInvalidStopLoss
+		case strings.HasPrefix(reason, "TAKE_PROFIT"):
+			err = auto.ErrInvalidTakeProfit
+		default:
+			err = fmt.Errorf("order rejected")
+		}
+	}
+	return &auto.OrderError{Err: err, OrderType: orderType, Symbol: symbol, Units: units, Price: price, Reason: reason}
+}
+
 func newDataframe(candles *CandlestickResponse) (*auto.IndexedFrame[auto.UnixTime], error) {
 	if candles == nil {
 		return nil, fmt.Errorf("candles is nil or empty")
@@ -0,0 +1,251 @@
+package oanda
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	auto "github.com/fivemoreminix/autotrader"
+)
+
+var (
+	_ auto.Order    = (*Order)(nil) // Compile-time interface check.
+	_ auto.Position = (*Position)(nil)
+)
+
+// Order is an order placed with Oanda.
+type Order struct {
+	broker       *OandaBroker
+	id           string
+	symbol       string
+	orderType    auto.OrderType
+	units        float64
+	price        float64
+	stopLoss     float64
+	takeProfit   float64
+	trailingStop float64
+	time         time.Time
+	tags         auto.Tags
+	costs        auto.TradeCosts
+	cancelled    bool
+	position     *Position
+}
+
+// Cancel cancels the order with Oanda. Orders that were filled or cancelled fail with auto.ErrCancelFailed.
+func (o *Order) Cancel() error {
+	b := o.broker
+	b.mu.Lock()
+	done := o.position != nil || o.cancelled
+	b.mu.Unlock()
+	if done {
+		return auto.ErrCancelFailed
+	}
+	var resp OrderResponse
+	if err := b.send(context.Background(), "PUT", "/orders/"+o.id+"/cancel", nil, &resp); err != nil {
+		return fmt.Errorf("%w: %w", auto.ErrCancelFailed, err)
+	}
+	b.mu.Lock()
+	o.cancelled = true
+	b.mu.Unlock()
+	b.SignalEmit(auto.OrderCancelled, o)
+	return nil
+}
+
+// Costs returns the half spread cost and commission of the fill of the order.
+func (o *Order) Costs() auto.TradeCosts {
+	return o.costs
+}
+
+// Fulfilled returns true if the order was filled when it was placed. The fills of limit and stop orders after they are placed are not followed yet.
+func (o *Order) Fulfilled() bool {
+	o.broker.mu.Lock()
+	defer o.broker.mu.Unlock()
+	return o.position != nil
+}
+
+func (o *Order) Id() string {
+	return o.id
+}
+
+func (o *Order) Leverage() float64 {
+	return 1
+}
+
+func (o *Order) Position() auto.Position {
+	o.broker.mu.Lock()
+	defer o.broker.mu.Unlock()
+	if o.position == nil {
+		return nil
+	}
+	return o.position
+}
+
+func (o *Order) Price() float64 {
+	return o.price
+}
+
+func (o *Order) Symbol() string {
+	return o.symbol
+}
+
+func (o *Order) TrailingStop() float64 {
+	return o.trailingStop
+}
+
+func (o *Order) StopLoss() float64 {
+	return o.stopLoss
+}
+
+func (o *Order) Tags() auto.Tags {
+	return o.tags
+}
+
+func (o *Order) TakeProfit() float64 {
+	return o.takeProfit
+}
+
+func (o *Order) Time() time.Time {
+	return o.time
+}
+
+func (o *Order) Type() auto.OrderType {
+	return o.orderType
+}
+
+func (o *Order) Units() float64 {
+	return o.units
+}
+
+// Position is the trade opened by a filled Order.
+type Position struct {
+	broker       *OandaBroker
+	id           string // id is the ID of the trade.
+	symbol       string
+	units        float64
+	entryPrice   float64
+	stopLoss     float64
+	takeProfit   float64
+	trailingStop float64
+	time         time.Time
+	tags         auto.Tags
+	closed       bool
+	closePrice   float64
+	pl           float64 // pl is the profit or loss realized when the trade was closed.
+	closeCosts   auto.TradeCosts
+}
+
+// Close closes the trade at market with Oanda.
+func (p *Position) Close() error {
+	b := p.broker
+	b.mu.Lock()
+	closed := p.closed
+	b.mu.Unlock()
+	if closed {
+		return auto.ErrPositionClosed
+	}
+	var resp OrderResponse
+	if err := b.send(context.Background(), "PUT", "/trades/"+p.id+"/close", map[string]string{"units": "ALL"}, &resp); err != nil {
+		return rejectError(&resp, err, auto.Market, p.symbol, -p.units, 0)
+	}
+	fill := resp.OrderFillTransaction
+	if fill == nil {
+		reason := "close not filled"
+		if cancel := resp.OrderCancelTransaction; cancel != nil {
+			reason = cancel.Reason
+		}
+		return orderError(reason, auto.Market, p.symbol, -p.units, 0)
+	}
+	b.mu.Lock()
+	p.closed, p.closePrice, p.pl = true, fill.Price, fill.PL
+	p.closeCosts = auto.TradeCosts{Spread: fill.HalfSpreadCost, Commission: fill.Commission, Financing: -fill.Financing}
+	b.mu.Unlock()
+	b.SignalEmit(auto.PositionClosed, p)
+	return nil
+}
+
+func (p *Position) Closed() bool {
+	p.broker.mu.Lock()
+	defer p.broker.mu.Unlock()
+	return p.closed
+}
+
+func (p *Position) CloseType() auto.OrderCloseType {
+	return auto.CloseMarket
+}
+
+func (p *Position) CloseCosts() auto.TradeCosts {
+	p.broker.mu.Lock()
+	defer p.broker.mu.Unlock()
+	return p.closeCosts
+}
+
+func (p *Position) ClosePrice() float64 {
+	p.broker.mu.Lock()
+	defer p.broker.mu.Unlock()
+	return p.closePrice
+}
+
+func (p *Position) EntryPrice() float64 {
+	return p.entryPrice
+}
+
+func (p *Position) EntryValue() float64 {
+	return p.units * p.entryPrice
+}
+
+func (p *Position) Id() string {
+	return p.id
+}
+
+func (p *Position) Leverage() float64 {
+	return 1
+}
+
+// PL returns the profit or loss realized by Oanda once the trade is closed, and the profit or loss at the price it could be closed at now otherwise.
+func (p *Position) PL() float64 {
+	p.broker.mu.Lock()
+	closed, pl := p.closed, p.pl
+	p.broker.mu.Unlock()
+	if closed {
+		return pl
+	}
+	return (p.broker.Price(p.symbol, p.units < 0) - p.entryPrice) * p.units
+}
+
+func (p *Position) Symbol() string {
+	return p.symbol
+}
+
+func (p *Position) TrailingStop() float64 {
+	return p.trailingStop
+}
+
+func (p *Position) StopLoss() float64 {
+	return p.stopLoss
+}
+
+func (p *Position) Tags() auto.Tags {
+	return p.tags
+}
+
+func (p *Position) TakeProfit() float64 {
+	return p.takeProfit
+}
+
+func (p *Position) Time() time.Time {
+	return p.time
+}
+
+func (p *Position) Units() float64 {
+	return p.units
+}
+
+func (p *Position) Value() float64 {
+	p.broker.mu.Lock()
+	closed, closePrice := p.closed, p.closePrice
+	p.broker.mu.Unlock()
+	if closed {
+		return p.units * closePrice
+	}
+	return p.units * p.broker.Price(p.symbol, p.units < 0)
+}
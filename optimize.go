@@ -0,0 +1,394 @@
+package autotrader
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/components"
+	"github.com/go-echarts/go-echarts/v2/opts"
+	anymath "github.com/spatialcurrent/go-math/pkg/math"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/rand"
+	"golang.org/x/exp/slices"
+)
+
+var (
+	ErrNoCandidates     = errors.New("no parameter candidates to optimize")
+	ErrInvalidParameter = errors.New("invalid parameter")
+)
+
+// Parameters is a set of strategy parameters by name, like {"Fast": 7, "Slow": 20}.
+type Parameters map[string]any
+
+// String returns the parameters as "name=value" pairs sorted by name.
+func (p Parameters) String() string {
+	names := maps.Keys(p)
+	slices.Sort(names)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf("%s=%v", name, p[name])
+	}
+	return strings.Join(pairs, " ")
+}
+
+// ParameterSpace is the values to try for each strategy parameter by name, like {"Fast": {5, 7, 9}, "Slow": {20, 30}}.
+type ParameterSpace map[string][]any
+
+// Size returns the number of parameter sets in the grid of the space.
+func (s ParameterSpace) Size() int {
+	if len(s) == 0 {
+		return 0
+	}
+	size := 1
+	for _, values := range s {
+		size *= len(values)
+	}
+	return size
+}
+
+// Grid returns every combination of the values of the space. The combinations are ordered by the parameter names, with the values of the last name changing fastest.
+func (s ParameterSpace) Grid() []Parameters {
+	grid := make([]Parameters, s.Size())
+	for i := range grid {
+		grid[i] = s.combination(i)
+	}
+	return grid
+}
+
+// Sample returns n distinct combinations of the values of the space, drawn at random with seed, in the order they are drawn. This explores a space too large for a grid search. If n is at least the size of the space, the whole grid is returned.
+func (s ParameterSpace) Sample(n int, seed uint64) []Parameters {
+	size := s.Size()
+	if n >= size {
+		return s.Grid()
+	}
+	sample := make([]Parameters, 0, Max(n, 0))
+	for _, i := range rand.New(rand.NewSource(seed)).Perm(size)[:Max(n, 0)] {
+		sample = append(sample, s.combination(i))
+	}
+	return sample
+}
+
+// combination returns the i-th combination of the grid of the space.
+func (s ParameterSpace) combination(i int) Parameters {
+	names := maps.Keys(s)
+	slices.Sort(names)
+	params := make(Parameters, len(names))
+	for j := len(names) - 1; j >= 0; j-- {
+		values := s[names[j]]
+		params[names[j]] = values[i%len(values)]
+		i /= len(values)
+	}
+	return params
+}
+
+// ApplyParameters sets the fields of the struct that dst points to from params, so a strategy can expose its parameters as fields, like in NewStrategy of an Optimizer or in SetParameters of a TunableStrategy. A parameter sets the field with a matching `param:"name"` tag, or else the exported field of the same name. Values are converted to the type of their field, so an int parameter can set a float64 field. An error wrapping ErrInvalidParameter is returned if a parameter has no field or its value cannot be converted.
+func ApplyParameters(dst any, params Parameters) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w: expected a pointer to a struct, got %T", ErrInvalidParameter, dst)
+	}
+	v = v.Elem()
+	fields := make(map[string]int)
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if name, ok := field.Tag.Lookup("param"); ok {
+			fields[name] = i
+		} else if _, ok := fields[field.Name]; field.IsExported() && !ok {
+			fields[field.Name] = i
+		}
+	}
+	for name, value := range params {
+		i, ok := fields[name]
+		if !ok {
+			return fmt.Errorf("%w: %T has no field for %q", ErrInvalidParameter, dst, name)
+		}
+		field := v.Field(i)
+		val := reflect.ValueOf(value)
+		if !val.IsValid() || !field.CanSet() || !val.CanConvert(field.Type()) {
+			return fmt.Errorf("%w: cannot set %s of %T to %v (%T)", ErrInvalidParameter, name, dst, value, value)
+		}
+		field.Set(val.Convert(field.Type()))
+	}
+	return nil
+}
+
+// ObjectiveNetProfit scores a backtest by its net profit. It is the default Objective of an Optimizer.
+func ObjectiveNetProfit(summary BacktestSummary) float64 {
+	return summary.NetProfit
+}
+
+// ObjectiveSharpe scores a backtest by its Sharpe ratio, which prefers steady returns over larger but more volatile ones.
+func ObjectiveSharpe(summary BacktestSummary) float64 {
+	return summary.SharpeRatio
+}
+
+// ObjectiveProfitFactor scores a backtest by its profit factor, which is the net profit for each dollar of maximum drawdown.
+func ObjectiveProfitFactor(summary BacktestSummary) float64 {
+	return summary.ProfitFactor
+}
+
+// Optimizer backtests a strategy with many parameter candidates to find the best performing ones. Each candidate is run on a fresh Trader and TestBroker with the same slippage seed, so that candidates are only compared by their parameters.
+type Optimizer struct {
+	Data          *IndexedFrame[UnixTime] // Data holds the candles to backtest on.
+	Symbol        string
+	Frequency     string
+	CandlesToKeep int
+	// NewStrategy returns a new strategy configured with params. It is called once for each backtest.
+	NewStrategy func(params Parameters) Strategy
+	// NewBroker returns a new TestBroker for data which starts with startCandles visible. If nil, a broker with $10,000 of cash, no leverage, and no spread is used.
+	NewBroker func(data *IndexedFrame[UnixTime], startCandles int) *TestBroker
+	// Objective scores the summary of a backtest, where a higher score is better, like ObjectiveSharpe. If nil, ObjectiveNetProfit is used.
+	Objective func(summary BacktestSummary) float64
+	// TestSplit is the fraction of Data, from the end, that is held out from optimization and only used to evaluate each candidate out-of-sample. For example, 0.3 optimizes on the first 70% of the candles and tests on the last 30%. Zero disables the test segment.
+	TestSplit float64
+	Seed      uint64 // Seed is the slippage seed of every backtest and the seed of the candidates that Optimize samples. If zero, 1 is used.
+	// Workers is the number of backtests that are run at once. If zero, runtime.GOMAXPROCS(0) is used. NewStrategy and NewBroker are called from every worker, so they must be safe for concurrent use, or Workers must be 1.
+	Workers int
+	// Samples is the number of candidates that Optimize draws at random from the parameter space. If zero, Optimize runs the whole grid.
+	Samples int
+	// Indicators is given to the Trader of every backtest, so the indicators that strategies compute with CachedIndicator are computed once for every candidate and worker instead of once per backtest. It may be kept between runs, like by a Reoptimizer, to start warm. If nil, indicators are not cached.
+	Indicators *IndicatorCache
+}
+
+// OptimizationResult holds the performance of one parameter candidate.
+type OptimizationResult struct {
+	Parameters  Parameters
+	InSample    BacktestSummary // InSample is the summary of the backtest on the training segment, or all of the data if there is no test segment.
+	OutOfSample BacktestSummary // OutOfSample is the summary of the backtest on the test segment. It is zero if the optimizer has no TestSplit.
+	Score       float64         // Score is the objective of the in-sample backtest.
+	TestScore   float64         // TestScore is the objective of the out-of-sample backtest.
+}
+
+// Optimize backtests the grid of space, or Samples candidates drawn from it at random, and returns the results sorted by their in-sample score from best to worst, so the best parameters are those of the first result.
+func (o *Optimizer) Optimize(space ParameterSpace) ([]OptimizationResult, error) {
+	if o.Samples > 0 {
+		return o.Run(space.Sample(o.Samples, Max(o.Seed, 1)))
+	}
+	return o.Run(space.Grid())
+}
+
+// Run backtests every candidate on Workers goroutines and returns the results sorted by their in-sample score from best to worst. If any backtest fails, the error of the first failing candidate is returned and the candidates that have not started are skipped.
+func (o *Optimizer) Run(candidates []Parameters) ([]OptimizationResult, error) {
+	if len(candidates) == 0 {
+		return nil, ErrNoCandidates
+	}
+	if o.TestSplit < 0 || o.TestSplit >= 1 {
+		return nil, fmt.Errorf("test split must be in the range [0, 1), got %v", o.TestSplit)
+	}
+	split := o.Data.Len() - int(float64(o.Data.Len())*o.TestSplit)
+	if split < 1 {
+		return nil, ErrNoData
+	}
+	train := o.Data
+	if split < o.Data.Len() {
+		train = o.Data.CopyRange(0, split)
+	}
+
+	results := make([]OptimizationResult, len(candidates))
+	errs := make([]error, len(candidates))
+	var next atomic.Int64
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	workers := o.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	for w := 0; w < Min(workers, len(candidates)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := int(next.Add(1) - 1); i < len(candidates) && !failed.Load(); i = int(next.Add(1) - 1) {
+				if results[i], errs[i] = o.evaluate(candidates[i], train, split); errs[i] != nil {
+					failed.Store(true)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	slices.SortStableFunc(results, func(a, b OptimizationResult) bool {
+		return a.Score > b.Score
+	})
+	return results, nil
+}
+
+// evaluate backtests params on train and, if the data is split, out-of-sample from the candle at split.
+func (o *Optimizer) evaluate(params Parameters, train *IndexedFrame[UnixTime], split int) (OptimizationResult, error) {
+	result := OptimizationResult{Parameters: params}
+	var err error
+	if result.InSample, err = o.backtest(params, train, 0); err != nil {
+		return result, fmt.Errorf("backtesting %v: %w", params, err)
+	}
+	result.Score = o.objective(result.InSample)
+	if split < o.Data.Len() {
+		// Start at the first test candle while keeping the training candles visible to the strategy as history.
+		if result.OutOfSample, err = o.backtest(params, o.Data, split+1); err != nil {
+			return result, fmt.Errorf("backtesting %v out-of-sample: %w", params, err)
+		}
+		result.TestScore = o.objective(result.OutOfSample)
+	}
+	return result, nil
+}
+
+func (o *Optimizer) backtest(params Parameters, data *IndexedFrame[UnixTime], startCandles int) (BacktestSummary, error) {
+	var broker *TestBroker
+	if o.NewBroker != nil {
+		broker = o.NewBroker(data, startCandles)
+	} else {
+		broker = NewTestBroker(nil, data, 10_000, 1, 0, startCandles)
+	}
+	broker.Seed = Max(o.Seed, 1)
+	trader := NewTrader(TraderConfig{
+		Broker:        broker,
+		Strategy:      o.NewStrategy(params),
+		Symbol:        o.Symbol,
+		Frequency:     o.Frequency,
+		CandlesToKeep: o.CandlesToKeep,
+		Indicators:    o.Indicators,
+	})
+	trader.Log.SetOutput(io.Discard)
+	return RunBacktest(trader)
+}
+
+func (o *Optimizer) objective(summary BacktestSummary) float64 {
+	if o.Objective != nil {
+		return o.Objective(summary)
+	}
+	return ObjectiveNetProfit(summary)
+}
+
+// PrintOptimization writes a table of the results with their in-sample and out-of-sample scores to w.
+func PrintOptimization(w io.Writer, results []OptimizationResult) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Parameters\tIn-Sample\tOut-of-Sample\t")
+	for _, result := range results {
+		fmt.Fprintf(tw, "%v\t%.2f\t%.2f\t\n", result.Parameters, result.Score, result.TestScore)
+	}
+	return tw.Flush()
+}
+
+// OptimizationHeatmap returns a heatmap of the score of each result across the values of the parameters x and y. This makes it easy to see whether the best parameters sit on a stable plateau or an isolated spike. If outOfSample is true, the TestScore of each result is plotted instead of the in-sample Score. Results that are missing either parameter are skipped.
+func OptimizationHeatmap(results []OptimizationResult, x, y string, outOfSample bool) *charts.HeatMap {
+	var xValues, yValues []any
+	for _, result := range results {
+		xv, xok := result.Parameters[x]
+		yv, yok := result.Parameters[y]
+		if !xok || !yok {
+			continue
+		}
+		if !slices.Contains(xValues, xv) {
+			xValues = append(xValues, xv)
+		}
+		if !slices.Contains(yValues, yv) {
+			yValues = append(yValues, yv)
+		}
+	}
+	sortParameterValues(xValues)
+	sortParameterValues(yValues)
+
+	data := make([]opts.HeatMapData, 0, len(results))
+	minScore, maxScore := math.Inf(1), math.Inf(-1)
+	for _, result := range results {
+		xi, yi := slices.Index(xValues, result.Parameters[x]), slices.Index(yValues, result.Parameters[y])
+		if xi < 0 || yi < 0 {
+			continue
+		}
+		score := result.Score
+		if outOfSample {
+			score = result.TestScore
+		}
+		minScore, maxScore = Min(minScore, score), Max(maxScore, score)
+		data = append(data, opts.HeatMapData{Value: [3]any{xi, yi, Round(score, 2)}})
+	}
+	if len(data) == 0 {
+		minScore, maxScore = 0, 0
+	}
+
+	title := "In-Sample Score"
+	if outOfSample {
+		title = "Out-of-Sample Score"
+	}
+	heatmap := charts.NewHeatMap()
+	heatmap.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{
+			Title:    title,
+			Subtitle: fmt.Sprintf("%s by %s", x, y),
+		}),
+		charts.WithTooltipOpts(opts.Tooltip{Show: true}),
+		charts.WithXAxisOpts(opts.XAxis{
+			Name: x,
+			Type: "category",
+		}),
+		charts.WithYAxisOpts(opts.YAxis{
+			Name: y,
+			Type: "category",
+			Data: parameterLabels(yValues),
+		}),
+		charts.WithVisualMapOpts(opts.VisualMap{
+			Calculable: true,
+			Min:        float32(minScore),
+			Max:        float32(maxScore),
+			InRange: &opts.VisualMapInRange{
+				Color: []string{"#d73027", "#ffffbf", "#1a9850"}, // Red for the worst scores and green for the best.
+			},
+		}),
+	)
+	heatmap.SetXAxis(parameterLabels(xValues)).AddSeries("Score", data)
+	return heatmap
+}
+
+// WriteOptimizationReport writes an HTML page to filename with a heatmap of the in-sample scores of the results across the parameters x and y. If the results were tested out-of-sample, a heatmap of the out-of-sample scores is included as well.
+func WriteOptimizationReport(filename string, results []OptimizationResult, x, y string) error {
+	page := components.NewPage()
+	page.PageTitle = "Optimization Report"
+	page.AddCharts(OptimizationHeatmap(results, x, y, false))
+	for _, result := range results {
+		if result.OutOfSample.Candles > 0 {
+			page.AddCharts(OptimizationHeatmap(results, x, y, true))
+			break
+		}
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := page.Render(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// sortParameterValues sorts numbers numerically and anything else by its string representation.
+func sortParameterValues(values []any) {
+	slices.SortStableFunc(values, func(a, b any) bool {
+		if c, err := anymath.Compare(a, b); err == nil {
+			return c < 0
+		}
+		return fmt.Sprint(a) < fmt.Sprint(b)
+	})
+}
+
+func parameterLabels(values []any) []string {
+	labels := make([]string, len(values))
+	for i, v := range values {
+		labels[i] = fmt.Sprint(v)
+	}
+	return labels
+}
@@ -0,0 +1,201 @@
+package autotrader
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-echarts/go-echarts/v2/opts"
+)
+
+// sizedStrategy buys Size units on the first candle and holds them until the end.
+type sizedStrategy struct {
+	Size float64
+}
+
+func (s *sizedStrategy) Init(_ *Trader) {}
+
+func (s *sizedStrategy) Next(t *Trader) {
+	if !t.IsLong() && s.Size > 0 {
+		t.Buy(s.Size, 0, 0)
+	}
+}
+
+func TestOptimizerTrainTestSplit(t *testing.T) {
+	optimizer := &Optimizer{
+		Data:          testData,
+		Symbol:        "EUR_USD",
+		Frequency:     "D",
+		CandlesToKeep: 5,
+		NewStrategy: func(params Parameters) Strategy {
+			return &sizedStrategy{Size: params["Size"].(float64)}
+		},
+		NewBroker: func(data *IndexedFrame[UnixTime], startCandles int) *TestBroker {
+			broker := NewTestBroker(nil, data, 100_000, 50, 0, startCandles)
+			broker.Slippage = 0
+			return broker
+		},
+		TestSplit: 1.0 / 3,
+	}
+	results, err := optimizer.Run([]Parameters{{"Size": 1000.0}, {"Size": 0.0}, {"Size": 2000.0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+	for i := 1; i < len(results); i++ {
+		if results[i-1].Score < results[i].Score {
+			t.Errorf("Expected results to be sorted by score, got %f before %f", results[i-1].Score, results[i].Score)
+		}
+	}
+	for _, result := range results {
+		if result.InSample.Candles != 6 {
+			t.Errorf("Expected %v to be trained on 6 candles, got %d", result.Parameters, result.InSample.Candles)
+		}
+		if result.OutOfSample.Candles != 3 {
+			t.Errorf("Expected %v to be tested on 3 candles, got %d", result.Parameters, result.OutOfSample.Candles)
+		}
+		if result.Parameters["Size"] == 0.0 && (result.Score != 0 || result.TestScore != 0) {
+			t.Errorf("Expected a strategy that never trades to score 0, got %f and %f", result.Score, result.TestScore)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := PrintOptimization(&out, results); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Size=2000") {
+		t.Errorf("Expected the table to list the parameters, got %q", out.String())
+	}
+
+	if _, err := optimizer.Run(nil); err != ErrNoCandidates {
+		t.Errorf("Expected ErrNoCandidates, got %v", err)
+	}
+}
+
+// scaledStrategy buys Size times Scale units on the first candle and holds them until the end.
+type scaledStrategy struct {
+	Size  float64 `param:"size"`
+	Scale int
+	sizedStrategy
+}
+
+func (s *scaledStrategy) Next(t *Trader) {
+	s.sizedStrategy.Size = s.Size * float64(s.Scale)
+	s.sizedStrategy.Next(t)
+}
+
+func TestOptimizerGrid(t *testing.T) {
+	space := ParameterSpace{"size": {0.0, 1000.0, 2000.0}, "Scale": {1, 2}}
+	grid := space.Grid()
+	if len(grid) != 6 || grid[0].String() != "Scale=1 size=0" || grid[1].String() != "Scale=1 size=1000" || grid[5].String() != "Scale=2 size=2000" {
+		t.Fatalf("Expected every combination with the last name changing fastest, got %v", grid)
+	}
+	sample := space.Sample(4, 7)
+	if len(sample) != 4 {
+		t.Fatalf("Expected 4 samples, got %d", len(sample))
+	}
+	for i := range sample {
+		for j := i + 1; j < len(sample); j++ {
+			if sample[i].String() == sample[j].String() {
+				t.Errorf("Expected distinct samples, got %v twice", sample[i])
+			}
+		}
+	}
+	if len(space.Sample(10, 7)) != 6 {
+		t.Error("Expected the whole grid when sampling more than its size")
+	}
+
+	optimizer := &Optimizer{
+		Data:          testData,
+		Symbol:        "EUR_USD",
+		Frequency:     "D",
+		CandlesToKeep: 5,
+		NewStrategy: func(params Parameters) Strategy {
+			strategy := &scaledStrategy{}
+			if err := ApplyParameters(strategy, params); err != nil {
+				t.Error(err)
+			}
+			return strategy
+		},
+		NewBroker: func(data *IndexedFrame[UnixTime], startCandles int) *TestBroker {
+			return NewTestBroker(nil, data, 100_000, 50, 0, startCandles)
+		},
+		Workers: 4,
+	}
+	results, err := optimizer.Optimize(space)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 6 || results[0].Parameters.String() != "Scale=2 size=2000" || results[5].Score > 0 {
+		t.Fatalf("Expected the largest position to be best in a rising market, got %v first of %d", results[0].Parameters, len(results))
+	}
+	// Parallel backtests are seeded alike, so they score the same as sequential ones.
+	optimizer.Workers = 1
+	sequential, err := optimizer.Optimize(space)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range results {
+		if results[i].Score != sequential[i].Score {
+			t.Errorf("Expected result %d to score %v sequentially, got %v", i, sequential[i].Score, results[i].Score)
+		}
+	}
+
+	optimizer.Samples = 3
+	optimizer.Objective = ObjectiveSharpe
+	if results, err = optimizer.Optimize(space); err != nil || len(results) != 3 {
+		t.Fatalf("Expected 3 sampled results, got %d: %v", len(results), err)
+	}
+	for _, result := range results {
+		if result.Score != result.InSample.SharpeRatio {
+			t.Errorf("Expected %v to be scored by its Sharpe ratio %v, got %v", result.Parameters, result.InSample.SharpeRatio, result.Score)
+		}
+	}
+
+	var strategy scaledStrategy
+	for _, params := range []Parameters{{"Size": 1.0}, {"size": "large"}, {"Scale": nil}} {
+		if err := ApplyParameters(&strategy, params); !errors.Is(err, ErrInvalidParameter) {
+			t.Errorf("Expected ErrInvalidParameter for %v, got %v", params, err)
+		}
+	}
+	if err := ApplyParameters(strategy, Parameters{}); !errors.Is(err, ErrInvalidParameter) {
+		t.Errorf("Expected ErrInvalidParameter for a struct that is not a pointer, got %v", err)
+	}
+}
+
+func TestOptimizationHeatmap(t *testing.T) {
+	results := []OptimizationResult{
+		{Parameters: Parameters{"Fast": 7, "Slow": 20}, Score: 3},
+		{Parameters: Parameters{"Fast": 3, "Slow": 20}, Score: 1},
+		{Parameters: Parameters{"Fast": 7, "Slow": 10}, Score: 2, TestScore: -1},
+		{Parameters: Parameters{"Fast": 3}, Score: 5}, // Missing Slow so it is skipped.
+	}
+	heatmap := OptimizationHeatmap(results, "Fast", "Slow", false)
+	if len(heatmap.MultiSeries) != 1 {
+		t.Fatalf("Expected 1 series, got %d", len(heatmap.MultiSeries))
+	}
+	data := heatmap.MultiSeries[0].Data.([]opts.HeatMapData)
+	if len(data) != 3 {
+		t.Fatalf("Expected 3 cells, got %d", len(data))
+	}
+	// Fast=7 is the second x value and Slow=20 is the second y value once sorted numerically.
+	if cell := data[0].Value.([3]any); cell[0] != 1 || cell[1] != 1 || cell[2] != 3.0 {
+		t.Errorf("Expected the first cell to be [1 1 3], got %v", cell)
+	}
+	if cell := OptimizationHeatmap(results, "Fast", "Slow", true).MultiSeries[0].Data.([]opts.HeatMapData)[2].Value.([3]any); cell[2] != -1.0 {
+		t.Errorf("Expected the out-of-sample heatmap to plot the test score, got %v", cell)
+	}
+
+	filename := filepath.Join(t.TempDir(), "optimization.html")
+	if err := WriteOptimizationReport(filename, results, "Fast", "Slow"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filename); err != nil {
+		t.Error(err)
+	}
+}
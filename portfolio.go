@@ -0,0 +1,133 @@
+package autotrader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+var ErrInvalidHolding = errors.New("invalid holding")
+
+// Holding is an open position of a Portfolio.
+type Holding struct {
+	Symbol       string       `json:"symbol"`
+	Units        float64      `json:"units"` // Units is negative for a short position.
+	EntryPrice   float64      `json:"entry_price"`
+	StopLoss     float64      `json:"stop_loss,omitempty"`
+	TakeProfit   float64      `json:"take_profit,omitempty"`
+	TrailingStop TrailingStop `json:"trailing_stop"` // TrailingStop replaces StopLoss when its Value is set.
+	Time         time.Time    `json:"time"`          // Time is when the position was opened. If zero, it is the time the portfolio is set.
+	Tags         Tags         `json:"tags,omitempty"`
+}
+
+// Portfolio is the cash and open positions of an account, so a backtest can start from an existing book or resume from the end of a prior run instead of starting flat. Cash is the balance without the value of the positions, like the Cash of a TestBroker, so the NAV is Cash plus the value of the holdings.
+type Portfolio struct {
+	Cash     float64   `json:"cash"`
+	Holdings []Holding `json:"holdings"`
+}
+
+// Portfolio returns the cash and open positions of the broker, which SetPortfolio of another broker can resume from. Trailing stops are returned with their current stop loss, so they keep trailing from where they were.
+func (b *TestBroker) Portfolio() Portfolio {
+	portfolio := Portfolio{Cash: b.Cash}
+	for _, position := range b.positions {
+		if position.Closed() {
+			continue
+		}
+		p := position.(*TestPosition)
+		holding := Holding{
+			Symbol:       p.symbol,
+			Units:        p.units,
+			EntryPrice:   p.entryPrice,
+			StopLoss:     p.stopLoss,
+			TakeProfit:   p.takeProfit,
+			TrailingStop: p.trailing,
+			Time:         p.time,
+			Tags:         p.tags,
+		}
+		if p.trailing.Value > 0 {
+			holding.StopLoss = p.trailingSL
+		}
+		portfolio.Holdings = append(portfolio.Holdings, holding)
+	}
+	return portfolio
+}
+
+// SetPortfolio sets the Cash of the broker and opens a position for every holding of portfolio at its entry price, without any costs and without emitting OrderFulfilled, since the positions were opened before the backtest. It should be called before the backtest starts. The positions are closed like any other, so their exit trades are recorded by the Trader without an Entry. An error wrapping ErrInvalidHolding is returned without changing the broker if any holding has no units, no entry price, or a symbol that cannot be traded.
+func (b *TestBroker) SetPortfolio(portfolio Portfolio) error {
+	for i, holding := range portfolio.Holdings {
+		switch {
+		case holding.Units == 0:
+			return fmt.Errorf("%w: holding %d of %s has no units", ErrInvalidHolding, i, holding.Symbol)
+		case holding.EntryPrice <= 0:
+			return fmt.Errorf("%w: holding %d of %s has no entry price", ErrInvalidHolding, i, holding.Symbol)
+		case len(b.Symbols) > 0 && !slices.Contains(b.Symbols, holding.Symbol):
+			return fmt.Errorf("%w: holding %d: %w %s", ErrInvalidHolding, i, ErrSymbolNotFound, holding.Symbol)
+		}
+	}
+	b.Cash = portfolio.Cash
+	for _, holding := range portfolio.Holdings {
+		rate, err := b.conversionRate(holding.Symbol)
+		if err != nil {
+			rate = 1
+		}
+		position := &TestPosition{
+			broker:     b,
+			entryPrice: holding.EntryPrice,
+			entryRate:  rate,
+			tags:       holding.Tags,
+			rate:       rate,
+			multiplier: b.instrument(holding.Symbol).ContractMultiplier(),
+			id:         strconv.Itoa(b.random().Int()),
+			leverage:   b.Leverage,
+			symbol:     holding.Symbol,
+			takeProfit: holding.TakeProfit,
+			time:       holding.Time,
+			units:      holding.Units,
+			openedAt:   b.advances,
+		}
+		if position.time.IsZero() {
+			position.time = b.Now()
+		}
+		if holding.TrailingStop.Value > 0 {
+			position.trailing = holding.TrailingStop
+			position.trailingSL = holding.StopLoss
+		} else {
+			position.stopLoss = holding.StopLoss
+		}
+		b.positions = append(b.positions, position)
+		if b.positionsByID == nil {
+			b.positionsByID = make(map[string]*TestPosition)
+		}
+		b.positionsByID[position.id] = position
+	}
+	return nil
+}
+
+// WritePortfolio writes portfolio to the file at path as indented JSON, creating its directory if needed.
+func WritePortfolio(path string, portfolio Portfolio) error {
+	data, err := json.MarshalIndent(portfolio, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// ReadPortfolio reads the portfolio in the file at path.
+func ReadPortfolio(path string) (Portfolio, error) {
+	var portfolio Portfolio
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return portfolio, err
+	}
+	err = json.Unmarshal(data, &portfolio)
+	return portfolio, err
+}
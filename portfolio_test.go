@@ -0,0 +1,63 @@
+package autotrader
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+func TestPortfolio(t *testing.T) {
+	broker := NewTestBroker(nil, testData, 10_000, 50, 0, 0)
+	broker.Slippage = 0
+	err := broker.SetPortfolio(Portfolio{Cash: 9000, Holdings: []Holding{{Symbol: "EUR_USD", Units: 1000, EntryPrice: 1.1, TakeProfit: 1.22, Tags: Tags{"book": "legacy"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nav := broker.NAV(); !EqualApprox(nav, 10_150) {
+		t.Errorf("Expected a NAV of the cash plus the position at 1.15, got %v", nav)
+	}
+
+	trader := NewTrader(TraderConfig{Broker: broker, Strategy: &scriptedStrategy{}, Symbol: "EUR_USD", Frequency: "D", CandlesToKeep: 5})
+	trader.Log.SetOutput(io.Discard)
+	trader.Init()
+	for i := 0; i < 4; i++ {
+		trader.Tick()
+		broker.Advance()
+	}
+	trades := trader.Stats().Trades()
+	if len(trades) != 1 || !trades[0].Exit || trades[0].Entry != nil {
+		t.Fatalf("Expected only the exit of the holding, got %+v", trades)
+	}
+	if trades[0].CloseType != CloseTakeProfit || !EqualApprox(trades[0].PL, 120) || trades[0].Tags["book"] != "legacy" {
+		t.Errorf("Expected the holding to take a profit of 120, got %+v", trades[0])
+	}
+
+	// A prior run's end state can be saved and resumed.
+	broker = NewTestBroker(nil, testData, 10_000, 50, 0, 0)
+	broker.SetPortfolio(Portfolio{Cash: 5000, Holdings: []Holding{{Symbol: "EUR_USD", Units: -500, EntryPrice: 1.2, TrailingStop: TrailingStop{Mode: TrailingDistance, Value: 0.1}, StopLoss: 1.3}}})
+	path := filepath.Join(t.TempDir(), "state", "portfolio.json")
+	if err := WritePortfolio(path, broker.Portfolio()); err != nil {
+		t.Fatal(err)
+	}
+	portfolio, err := ReadPortfolio(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(portfolio.Holdings) != 1 || portfolio.Cash != 5000 {
+		t.Fatalf("Expected the cash and the short holding, got %+v", portfolio)
+	}
+	if holding := portfolio.Holdings[0]; holding.Units != -500 || holding.EntryPrice != 1.2 || holding.StopLoss != 1.3 || holding.TrailingStop.Value != 0.1 || holding.Time.IsZero() {
+		t.Errorf("Expected the short holding with its trailing stop at 1.3, got %+v", holding)
+	}
+
+	broker.Symbols = []string{"EUR_USD"}
+	for _, holding := range []Holding{{Symbol: "EUR_USD", EntryPrice: 1.1}, {Symbol: "EUR_USD", Units: 1}, {Symbol: "GBP_USD", Units: 1, EntryPrice: 1.3}} {
+		if err := broker.SetPortfolio(Portfolio{Holdings: []Holding{holding}}); !errors.Is(err, ErrInvalidHolding) {
+			t.Errorf("Expected ErrInvalidHolding for %+v, got %v", holding, err)
+		}
+	}
+	if broker.Cash != 5000 {
+		t.Errorf("Expected an invalid portfolio not to change the cash, got %v", broker.Cash)
+	}
+}
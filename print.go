@@ -0,0 +1,114 @@
+package autotrader
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+const defaultDateLayout = "2006-01-02 15:04:05"
+
+// PrintOptions change how a Frame or IndexedFrame is printed by StringWith. The zero value prints like String.
+type PrintOptions struct {
+	MaxRows        int      // MaxRows is the most rows printed before the middle rows are replaced by "...". The default is 20, and a negative value prints every row.
+	Columns        []string // Columns are the columns to print in order. Columns that do not exist are skipped. The default is every column in the order of String.
+	FloatPrecision int      // FloatPrecision is the number of decimals printed of float values. The default of zero prints the shortest representation of each value.
+	DateLayout     string   // DateLayout is the layout of time.Time values and of indexes with a Time method, like UnixTime. The default prints a Frame's times as "2006-01-02 15:04:05" and everything else with its String method.
+	Markdown       bool     // Markdown prints a Markdown table without the type and size header, for including data in documents and issues.
+}
+
+// columns returns the columns of names to print.
+func (o PrintOptions) columns(names []string) []string {
+	if o.Columns == nil {
+		return names
+	}
+	columns := make([]string, 0, len(o.Columns))
+	for _, name := range o.Columns {
+		if slices.Contains(names, name) {
+			columns = append(columns, name)
+		}
+	}
+	return columns
+}
+
+// value returns val formatted for printing.
+func (o PrintOptions) value(val any) string {
+	switch v := val.(type) {
+	case time.Time:
+		if o.DateLayout != "" {
+			return v.Format(o.DateLayout)
+		}
+	case interface{ Time() time.Time }:
+		if o.DateLayout != "" {
+			return v.Time().UTC().Format(o.DateLayout)
+		}
+	case string:
+		if o.Markdown {
+			return strings.ReplaceAll(v, "|", `\|`)
+		}
+		return strconv.Quote(v)
+	case float64:
+		if o.FloatPrecision > 0 {
+			return strconv.FormatFloat(v, 'f', o.FloatPrecision, 64)
+		}
+	case float32:
+		if o.FloatPrecision > 0 {
+			return strconv.FormatFloat(float64(v), 'f', o.FloatPrecision, 32)
+		}
+	}
+	return fmt.Sprintf("%v", val)
+}
+
+// table prints a table with the header and length rows returned by row. If there are more than MaxRows rows, only the first and last rows are printed around a row of "...".
+func (o PrintOptions) table(title string, header []string, length int, row func(i int) []string) string {
+	buffer := new(bytes.Buffer)
+	t := tabwriter.NewWriter(buffer, 0, 0, 2, ' ', 0)
+	printRow := func(cells []string) {
+		if o.Markdown {
+			fmt.Fprintf(buffer, "| %s |\n", strings.Join(cells, " | "))
+		} else {
+			fmt.Fprintf(t, "%s\t\n", strings.Join(cells, "\t"))
+		}
+	}
+
+	if o.Markdown {
+		printRow(header)
+		separator := make([]string, len(header))
+		for i := range separator {
+			separator[i] = "---"
+		}
+		printRow(separator)
+	} else {
+		fmt.Fprintln(t, title)
+		printRow(header)
+	}
+
+	maxRows := o.MaxRows
+	if maxRows == 0 {
+		maxRows = 20
+	}
+	if maxRows > 0 && length > maxRows {
+		head := (maxRows + 1) / 2
+		for i := 0; i < head; i++ {
+			printRow(row(i))
+		}
+		ellipsis := make([]string, len(header)) // Keeps alignment.
+		ellipsis[0] = "..."
+		printRow(ellipsis)
+		for i := length - (maxRows - head); i < length; i++ {
+			printRow(row(i))
+		}
+	} else {
+		for i := 0; i < length; i++ {
+			printRow(row(i))
+		}
+	}
+
+	t.Flush()
+	return buffer.String()
+}
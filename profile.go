@@ -0,0 +1,45 @@
+package autotrader
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/pprof"
+)
+
+// ProfileHandler returns a handler of the net/http/pprof profiles under /debug/pprof/, so they can be served by an existing server. Use ProfileAddr to serve them from the Trader instead.
+func ProfileHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// startProfiler serves the profiles of ProfileHandler on ProfileAddr until ctx is done, if ProfileAddr is set. The SignalMetrics of the Trader are served under /debug/signals, if set.
+func (t *Trader) startProfiler(ctx context.Context) {
+	if t.ProfileAddr == "" {
+		return
+	}
+	listener, err := net.Listen("tcp", t.ProfileAddr)
+	if err != nil {
+		t.Log.Printf("error starting profiler: %v", err)
+		return
+	}
+	t.Log.Printf("Serving profiles on http://%s/debug/pprof/", listener.Addr())
+	handler := ProfileHandler()
+	if t.SignalMetrics != nil {
+		mux := http.NewServeMux()
+		mux.Handle("/debug/pprof/", handler)
+		mux.Handle("/debug/signals", t.SignalMetrics)
+		handler = mux
+	}
+	server := &http.Server{Handler: handler}
+	go server.Serve(listener)
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+}
@@ -0,0 +1,103 @@
+package autotrader
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+var ErrSignalsOnly = errors.New("order published as a signal and not sent to the broker")
+
+// TradeSignalPublished is the name of the signal emitted by a SignalBus when a TradeSignal is published.
+const TradeSignalPublished = "TradeSignalPublished"
+
+// TradeSignal is an order that a strategy wanted to place while its Trader was in signals-only mode.
+type TradeSignal struct {
+	Time       time.Time `json:"time"` // Time is the date of the candle the signal was made on.
+	Symbol     string    `json:"symbol"`
+	OrderType  OrderType `json:"order_type,omitempty"`
+	Units      float64   `json:"units,omitempty"` // Units is positive to buy and negative to sell.
+	Price      float64   `json:"price,omitempty"` // Price is the target price of limit and stop orders, or the market price of market orders.
+	StopLoss   float64   `json:"stop_loss,omitempty"`
+	TakeProfit float64   `json:"take_profit,omitempty"`
+	Close      bool      `json:"close,omitempty"` // Close is true when the strategy wanted to close its orders and positions instead of placing an order.
+	Tags       Tags      `json:"tags,omitempty"`
+}
+
+// SignalPublisher sends the trade signals of a Trader in signals-only mode somewhere, like a log, a webhook, or a channel of subscribers.
+type SignalPublisher interface {
+	Publish(signal TradeSignal) error
+}
+
+// SignalPublisherFunc is a function that implements SignalPublisher.
+type SignalPublisherFunc func(signal TradeSignal) error
+
+func (f SignalPublisherFunc) Publish(signal TradeSignal) error {
+	return f(signal)
+}
+
+// LogPublisher is a SignalPublisher that prints every signal to a logger.
+type LogPublisher struct {
+	Log *log.Logger
+}
+
+func (p *LogPublisher) Publish(signal TradeSignal) error {
+	if signal.Close {
+		p.Log.Printf("Signal: close %s", signal.Symbol)
+		return nil
+	}
+	p.Log.Printf("Signal: %v %s %v units @ $%.5f, stopLoss: %v, takeProfit: %v %v", signal.OrderType, signal.Symbol, signal.Units, signal.Price, signal.StopLoss, signal.TakeProfit, signal.Tags)
+	return nil
+}
+
+// WebhookPublisher is a SignalPublisher that posts every signal as JSON to a URL. A response status other than 2xx is returned as an error.
+type WebhookPublisher struct {
+	URL    string
+	Client *http.Client // Client sends the requests. If nil, http.DefaultClient is used.
+}
+
+func (p *WebhookPublisher) Publish(signal TradeSignal) error {
+	body, err := json.Marshal(signal)
+	if err != nil {
+		return err
+	}
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Post(p.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %s", resp.Status)
+	}
+	return nil
+}
+
+// SignalBus is a SignalPublisher that emits TradeSignalPublished with each TradeSignal, so any number of subscribers in the same program can connect to it.
+//
+// Signals:
+//   - TradeSignalPublished(TradeSignal) - Emitted when a trade signal is published.
+type SignalBus struct {
+	SignalManager
+}
+
+func (b *SignalBus) Publish(signal TradeSignal) error {
+	b.SignalEmit(TradeSignalPublished, signal)
+	return nil
+}
+
+// publish sends the signal to every publisher of the Trader and logs the ones that fail.
+func (t *Trader) publish(signal TradeSignal) {
+	for _, publisher := range t.Publishers {
+		if err := publisher.Publish(signal); err != nil {
+			t.Log.Printf("error publishing signal: %v", err)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package autotrader
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSignalsOnly(t *testing.T) {
+	var received []TradeSignal
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var signal TradeSignal
+		if err := json.NewDecoder(r.Body).Decode(&signal); err != nil {
+			t.Error(err)
+		}
+		received = append(received, signal)
+	}))
+	defer server.Close()
+
+	bus := &SignalBus{}
+	var published []TradeSignal
+	bus.SignalConnect(TradeSignalPublished, t, func(args ...any) {
+		published = append(published, args[0].(TradeSignal))
+	})
+
+	broker := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	trader := NewTrader(TraderConfig{
+		Broker:        broker,
+		Strategy:      &roundTripStrategy{},
+		Symbol:        "EUR_USD",
+		Frequency:     "D",
+		CandlesToKeep: 5,
+		Tags:          Tags{"strategy": "roundtrip"},
+		SignalsOnly:   true,
+		Publishers:    []SignalPublisher{bus, &WebhookPublisher{URL: server.URL}},
+	})
+	trader.Log.SetOutput(io.Discard)
+	trader.Init()
+	for !trader.EOF {
+		trader.Tick()
+		broker.Advance()
+	}
+
+	if len(broker.orders) != 0 || len(trader.Stats().Trades()) != 0 {
+		t.Errorf("Expected no orders to be sent to the broker, got %d orders", len(broker.orders))
+	}
+	if len(published) != 2 || len(received) != 2 {
+		t.Fatalf("Expected 2 signals to be published and received, got %d and %d", len(published), len(received))
+	}
+	buy, exit := published[0], published[1]
+	if buy.Units != 1000 || buy.OrderType != Market || buy.Close {
+		t.Errorf("Expected a market signal to buy 1000 units, got %+v", buy)
+	}
+	if buy.Price != testData.Close(1) || !buy.Time.Equal(testData.Date(1).Time()) {
+		t.Errorf("Expected the signal at the second candle, got %+v", buy)
+	}
+	if buy.Tags["strategy"] != "roundtrip" {
+		t.Errorf("Expected the signal to carry the trader tags, got %v", buy.Tags)
+	}
+	if !exit.Close {
+		t.Errorf("Expected a close signal, got %+v", exit)
+	}
+	if received[0].Units != buy.Units || !received[0].Time.Equal(buy.Time) {
+		t.Errorf("Expected the webhook to receive %+v, got %+v", buy, received[0])
+	}
+}
+
+func TestWebhookPublisherStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	publisher := &WebhookPublisher{URL: server.URL}
+	if err := publisher.Publish(TradeSignal{Symbol: "EUR_USD"}); err == nil {
+		t.Error("Expected an error for a failed request")
+	}
+}
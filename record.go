@@ -0,0 +1,212 @@
+package autotrader
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// The kinds of BrokerRecord.
+const (
+	RecordStart   = "start"   // RecordStart is written when recording starts, with the NAV of the account.
+	RecordCandles = "candles" // RecordCandles is a response to Candles.
+	RecordOrder   = "order"   // RecordOrder is a request to Order and the ID of the order it placed.
+	RecordEvent   = "event"   // RecordEvent is an order or position signal of the broker.
+)
+
+// recordedSignals are the signals of the broker that a RecordingBroker records.
+var recordedSignals = []string{OrderFulfilled, OrderCancelled, OrderExpired, PositionClosed, PositionModified}
+
+// BrokerRecord is a single interaction with a broker recorded by a RecordingBroker. Only the fields of its Kind are set.
+type BrokerRecord struct {
+	Time       time.Time     `json:"time"` // Time is the time of the broker when the interaction happened.
+	Kind       string        `json:"kind"`
+	Symbol     string        `json:"symbol,omitempty"`
+	Frequency  string        `json:"frequency,omitempty"`
+	Count      int           `json:"count,omitempty"`   // Count is the number of candles requested.
+	Candles    []Candle      `json:"candles,omitempty"` // Candles are the candles returned, from the oldest.
+	OrderType  OrderType     `json:"order_type,omitempty"`
+	Units      float64       `json:"units,omitempty"`
+	Price      float64       `json:"price,omitempty"`
+	StopLoss   float64       `json:"stop_loss,omitempty"`
+	TakeProfit float64       `json:"take_profit,omitempty"`
+	OrderID    string        `json:"order_id,omitempty"` // OrderID is the ID of the placed order.
+	Event      *JournalEntry `json:"event,omitempty"`    // Event is the signal of a RecordEvent.
+	NAV        float64       `json:"nav,omitempty"`      // NAV is the net asset value of the account of a RecordStart.
+	Err        string        `json:"error,omitempty"`    // Err is the error returned by the broker, if any.
+}
+
+// RecordingBroker wraps a live Broker and appends every Candles response, order request, and fill, cancel, close, and modification of its orders and positions to a file as a line of JSON, so an incident can be reproduced later by replaying the file with ReplayBroker. Each record is written as soon as it happens, so the file survives a crash.
+//
+// All other methods are passed through to the wrapped Broker.
+type RecordingBroker struct {
+	Broker
+
+	mu      sync.Mutex
+	file    *os.File
+	err     error          // err is the first error writing a record.
+	placing int            // placing is the number of orders being placed.
+	held    []BrokerRecord // held are the events signalled while orders were placed.
+}
+
+// NewRecordingBroker returns a RecordingBroker that appends the interactions with broker to the file at path, which is created if it does not exist. Call Close when done to close the file.
+func NewRecordingBroker(broker Broker, path string) (*RecordingBroker, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening broker recording: %w", err)
+	}
+	b := &RecordingBroker{Broker: broker, file: file}
+	for _, signal := range recordedSignals {
+		signal := signal
+		broker.SignalConnect(signal, b, func(args ...any) {
+			entry := journalEntry(signal, args[0], broker, brokerNow(broker))
+			b.record(BrokerRecord{Kind: RecordEvent, Symbol: entry.Symbol, Event: &entry})
+		})
+	}
+	b.record(BrokerRecord{Kind: RecordStart, NAV: broker.NAV()})
+	return b, nil
+}
+
+// Candles returns the candles of the wrapped broker and records them along with any error.
+func (b *RecordingBroker) Candles(ctx context.Context, symbol, frequency string, count int) (*IndexedFrame[UnixTime], error) {
+	candles, err := b.Broker.Candles(ctx, symbol, frequency, count)
+	record := BrokerRecord{Kind: RecordCandles, Symbol: symbol, Frequency: frequency, Count: count, Err: errorString(err)}
+	if candles != nil {
+		record.Candles = make([]Candle, candles.Len())
+		for i := range record.Candles {
+			record.Candles[i] = frameCandle(candles, i)
+		}
+	}
+	b.record(record)
+	return candles, err
+}
+
+// Order places an order with the wrapped broker and records the request along with the ID of the order or the error. Events signalled while the order is placed, like the fill of a market order, are recorded after it.
+func (b *RecordingBroker) Order(ctx context.Context, orderType OrderType, symbol string, units, price, stopLoss, takeProfit float64, options ...OrderOption) (Order, error) {
+	record := BrokerRecord{Kind: RecordOrder, Time: brokerNow(b.Broker), Symbol: symbol, OrderType: orderType, Units: units, Price: price, StopLoss: stopLoss, TakeProfit: takeProfit}
+	b.mu.Lock()
+	b.placing++
+	b.mu.Unlock()
+	order, err := b.Broker.Order(ctx, orderType, symbol, units, price, stopLoss, takeProfit, options...)
+	if order != nil {
+		record.OrderID = order.Id()
+	}
+	record.Err = errorString(err)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.placing--
+	b.write(record)
+	if b.placing == 0 {
+		for _, held := range b.held {
+			b.write(held)
+		}
+		b.held = nil
+	}
+	return order, err
+}
+
+// Close closes the file and returns the first error writing a record, if any.
+func (b *RecordingBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.file.Close(); b.err == nil {
+		b.err = err
+	}
+	return b.err
+}
+
+// record appends record to the file, timestamped with the time of the broker if it has none. Events are held while orders are placed.
+func (b *RecordingBroker) record(record BrokerRecord) {
+	if record.Time.IsZero() {
+		record.Time = brokerNow(b.Broker)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if record.Kind == RecordEvent && b.placing > 0 {
+		b.held = append(b.held, record)
+		return
+	}
+	b.write(record)
+}
+
+// write appends record to the file as a line of JSON. b.mu must be held.
+func (b *RecordingBroker) write(record BrokerRecord) {
+	line, err := json.Marshal(record)
+	if err == nil {
+		_, err = b.file.Write(append(line, '\n'))
+	}
+	if err != nil && b.err == nil {
+		b.err = fmt.Errorf("error writing broker recording: %w", err)
+	}
+}
+
+// errorString returns the message of err, or an empty string if err is nil.
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// ReadBrokerRecords reads the records of a file written by a RecordingBroker, in the order they were recorded.
+func ReadBrokerRecords(path string) ([]BrokerRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var records []BrokerRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 64<<20) // Responses of thousands of candles make long lines.
+	for line := 1; scanner.Scan(); line++ {
+		var record BrokerRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return records, fmt.Errorf("error reading broker record on line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// ReplayBroker returns a TestBroker of the candles of symbol at frequency that the live strategy was given in records, so a backtest of the strategy sees exactly what it saw live. Candles that were revised between responses hold their last recorded values. The candles of other symbols at frequency become the SymbolData of the broker. The broker starts with the candles of the first response of symbol and the NAV of the account when recording started as its cash, at the given leverage and without a spread. Positions that were open when recording started are not replayed. Compare the orders placed in the backtest with the RecordOrder records to find where the strategy diverged.
+func ReplayBroker(records []BrokerRecord, symbol, frequency string, leverage float64) (*TestBroker, error) {
+	frames := make(map[string]*IndexedFrame[UnixTime])
+	var cash float64
+	var first *BrokerRecord
+	for i := range records {
+		record := &records[i]
+		switch {
+		case record.Kind == RecordStart && cash == 0:
+			cash = record.NAV
+		case record.Kind == RecordCandles && record.Frequency == frequency:
+			frame, ok := frames[record.Symbol]
+			if !ok {
+				frame = NewDOHLCVIndexedFrame[UnixTime]()
+				frames[record.Symbol] = frame
+			}
+			for _, c := range record.Candles {
+				frame.PushCandle(UnixTime(c.Date.Unix()), c.Open, c.High, c.Low, c.Close, c.Volume)
+			}
+			if first == nil && record.Symbol == symbol && len(record.Candles) > 0 {
+				first = record
+			}
+		}
+	}
+	data := frames[symbol]
+	if first == nil {
+		return nil, fmt.Errorf("%w: no candles of %s at %s were recorded", ErrNoData, symbol, frequency)
+	}
+	delete(frames, symbol)
+	start := data.Closes().Row(UnixTime(first.Candles[len(first.Candles)-1].Date.Unix())) + 1
+	broker := NewTestBroker(nil, data, cash, leverage, 0, start)
+	broker.Frequency = frequency
+	if len(frames) > 0 {
+		broker.SymbolData = frames
+	}
+	return broker, nil
+}
@@ -0,0 +1,70 @@
+package autotrader
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecordingBrokerReplay(t *testing.T) {
+	run := func(broker Broker, advance func()) *Trader {
+		trader := NewTrader(TraderConfig{Broker: broker, Strategy: &roundTripStrategy{}, Symbol: "EUR_USD", Frequency: "D", CandlesToKeep: 5})
+		trader.Log.SetOutput(io.Discard)
+		trader.Init()
+		for !trader.EOF {
+			trader.Tick()
+			advance()
+		}
+		return trader
+	}
+
+	live := NewTestBroker(nil, testData, 100_000, 50, 0, 3)
+	live.Slippage = 0
+	path := filepath.Join(t.TempDir(), "broker.jsonl")
+	recorder, err := NewRecordingBroker(live, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	liveTrades := run(recorder, live.Advance).Stats().Trades()
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := ReadBrokerRecords(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := make(map[string]int)
+	for i, record := range records {
+		kinds[record.Kind]++
+		if record.Kind == RecordOrder && (record.OrderID == "" || i+1 == len(records) || records[i+1].Event == nil || records[i+1].Event.Event != OrderFulfilled) {
+			t.Errorf("Expected the market order to be followed by its fill, got %+v", records[i:])
+		}
+	}
+	if records[0].Kind != RecordStart || records[0].NAV != 100_000 || kinds[RecordCandles] == 0 || kinds[RecordOrder] != 1 || kinds[RecordEvent] < 2 {
+		t.Fatalf("Expected the start, the candles, the order, and its fill and close to be recorded, got %v", kinds)
+	}
+
+	replay, err := ReplayBroker(records, "EUR_USD", "D", 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay.Slippage = 0
+	if replay.Data.Len() != testData.Len() || replay.Cash != 100_000 {
+		t.Fatalf("Expected every candle and the cash to be replayed, got %d candles and $%.2f", replay.Data.Len(), replay.Cash)
+	}
+	replayTrades := run(replay, replay.Advance).Stats().Trades()
+	if len(replayTrades) != len(liveTrades) {
+		t.Fatalf("Expected the %d trades of the recording, got %+v", len(liveTrades), replayTrades)
+	}
+	for i := range liveTrades {
+		if replayTrades[i].Units != liveTrades[i].Units || replayTrades[i].Price != liveTrades[i].Price || !replayTrades[i].OpenTime.Equal(liveTrades[i].OpenTime) {
+			t.Errorf("Expected trade %d to be %+v, got %+v", i, liveTrades[i], replayTrades[i])
+		}
+	}
+
+	if _, err := ReplayBroker(records, "GBP_USD", "D", 50); !errors.Is(err, ErrNoData) {
+		t.Errorf("Expected ErrNoData replaying a symbol that was not recorded, got %v", err)
+	}
+}
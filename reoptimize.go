@@ -0,0 +1,112 @@
+package autotrader
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrNotTunable = errors.New("strategy does not implement TunableStrategy")
+
+// ParameterChange is a swap of the parameters of a strategy by a Reoptimizer.
+type ParameterChange struct {
+	Time       time.Time
+	Parameters Parameters
+	Score      float64 // Score is the in-sample score of the parameters on the window they were chosen on.
+}
+
+// Reoptimizer periodically re-runs an Optimizer on the trailing window of candles of a Trader and swaps the parameters of its strategy for the best candidate, so the strategy adapts to changing regimes. The strategy must implement TunableStrategy. The optimization runs within a tick of the Trader, so the window and candidates should be small enough to finish well within a candle when trading live.
+type Reoptimizer struct {
+	// Optimizer backtests the candidates. Its Data is replaced by the window, and its Symbol, Frequency, and CandlesToKeep default to those of the Trader.
+	Optimizer  *Optimizer
+	Candidates []Parameters
+	Window     time.Duration // Window is the trailing period of candles to optimize on, like 180 days. No optimization runs until the broker has a full window of candles.
+	Every      time.Duration // Every is the time between optimizations, like 30 days. Zero optimizes on every candle.
+	// Confirm is a safety hook that is asked before the parameters are swapped for the best result, like to reject parameters that lost money or to ask an operator. The current parameters are nil before the first swap. If nil, every swap is confirmed.
+	Confirm func(t *Trader, current Parameters, best OptimizationResult) bool
+
+	last    time.Time
+	current Parameters
+	changes []ParameterChange
+}
+
+// Parameters returns the parameters the strategy was last given, or nil if it has not been re-optimized yet.
+func (r *Reoptimizer) Parameters() Parameters {
+	return r.current
+}
+
+// Changes returns every swap of the parameters in the order they were made.
+func (r *Reoptimizer) Changes() []ParameterChange {
+	return r.changes
+}
+
+// check re-optimizes the strategy of t if the time since the last optimization is at least Every. The Trader calls it on every candle before the strategy runs.
+func (r *Reoptimizer) check(t *Trader) {
+	now := t.Now()
+	if !r.last.IsZero() && now.Sub(r.last) < r.Every {
+		return
+	}
+	ran, err := r.reoptimize(t)
+	if err != nil {
+		t.Log.Printf("error re-optimizing the strategy: %v", err)
+	}
+	if ran || err != nil {
+		r.last = now // Errors wait for the next period instead of retrying on every candle.
+	}
+}
+
+// reoptimize optimizes the candidates on the trailing window and swaps the parameters of the strategy if the best candidate differs and is confirmed. It returns false without optimizing if the broker does not have a full window of candles yet.
+func (r *Reoptimizer) reoptimize(t *Trader) (bool, error) {
+	tunable, ok := t.Strategy.(TunableStrategy)
+	if !ok {
+		return false, fmt.Errorf("%w: %T", ErrNotTunable, t.Strategy)
+	}
+	duration, err := FrequencyDuration(t.Frequency)
+	if err != nil {
+		return false, err
+	}
+	count := int(r.Window / duration)
+	if count < 1 {
+		return false, fmt.Errorf("window of %v is shorter than a candle of %s", r.Window, t.Frequency)
+	}
+	ctx, cancel := t.brokerContext()
+	data, err := t.Broker.Candles(ctx, t.Symbol, t.Frequency, count)
+	cancel()
+	if err != nil && err != ErrEOF {
+		return false, err
+	} else if data.Len() < count {
+		return false, nil
+	}
+
+	optimizer := *r.Optimizer
+	optimizer.Data = data
+	if optimizer.Symbol == "" {
+		optimizer.Symbol = t.Symbol
+	}
+	if optimizer.Frequency == "" {
+		optimizer.Frequency = t.Frequency
+	}
+	if optimizer.CandlesToKeep == 0 {
+		optimizer.CandlesToKeep = t.CandlesToKeep
+	}
+	results, err := optimizer.Run(r.Candidates)
+	if err != nil {
+		return true, err
+	}
+	best := results[0]
+	if r.current != nil && best.Parameters.String() == r.current.String() {
+		t.Log.Printf("Re-optimization kept the parameters %v with a score of %.2f", best.Parameters, best.Score)
+		return true, nil
+	}
+	if r.Confirm != nil && !r.Confirm(t, r.current, best) {
+		t.Log.Printf("Re-optimization to %v with a score of %.2f was not confirmed", best.Parameters, best.Score)
+		return true, nil
+	}
+	if err := tunable.SetParameters(best.Parameters); err != nil {
+		return true, err
+	}
+	r.current = best.Parameters
+	r.changes = append(r.changes, ParameterChange{Time: t.Now(), Parameters: best.Parameters, Score: best.Score})
+	t.Log.Printf("Re-optimized the parameters to %v with a score of %.2f on %d candles", best.Parameters, best.Score, data.Len())
+	return true, nil
+}
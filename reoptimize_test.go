@@ -0,0 +1,86 @@
+package autotrader
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+// tunedStrategy holds a position of Units, which are replaced by SetParameters.
+type tunedStrategy struct {
+	Units float64
+}
+
+func (s *tunedStrategy) Init(_ *Trader) {}
+
+func (s *tunedStrategy) Next(t *Trader) {
+	if len(t.Broker.OpenPositions()) == 0 && s.Units != 0 {
+		t.Order(Market, s.Units, 0, 0, 0)
+	}
+}
+
+func (s *tunedStrategy) SetParameters(params Parameters) error {
+	s.Units = params["Units"].(float64)
+	return nil
+}
+
+func TestReoptimizer(t *testing.T) {
+	newReoptimizer := func(confirm func(*Trader, Parameters, OptimizationResult) bool) *Reoptimizer {
+		return &Reoptimizer{
+			Optimizer: &Optimizer{
+				NewStrategy: func(params Parameters) Strategy {
+					return &tunedStrategy{Units: params["Units"].(float64)}
+				},
+				NewBroker: func(data *IndexedFrame[UnixTime], startCandles int) *TestBroker {
+					broker := NewTestBroker(nil, data, 10_000, 1, 0, startCandles)
+					broker.Slippage = 0
+					return broker
+				},
+			},
+			Candidates: []Parameters{{"Units": 1000.0}, {"Units": -1000.0}},
+			Window:     3 * 24 * time.Hour,
+			Every:      2 * 24 * time.Hour,
+			Confirm:    confirm,
+		}
+	}
+	run := func(reoptimizer *Reoptimizer) *tunedStrategy {
+		strategy := &tunedStrategy{}
+		broker := NewTestBroker(nil, testData, 10_000, 1, 0, 0)
+		trader := NewTrader(TraderConfig{Broker: broker, Strategy: strategy, Symbol: "EUR_USD", Frequency: "D", CandlesToKeep: 5, Reoptimizer: reoptimizer})
+		trader.Log.SetOutput(io.Discard)
+		trader.Init()
+		for i := 0; i < testData.Len(); i++ {
+			trader.Tick()
+			broker.Advance()
+		}
+		return strategy
+	}
+
+	// The windows rise to the third candle, fall to the fifth, rise to the seventh, and rise to the ninth again.
+	reoptimizer := newReoptimizer(nil)
+	strategy := run(reoptimizer)
+	changes := reoptimizer.Changes()
+	expected := []float64{1000, -1000, 1000}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %d parameter changes, got %+v", len(expected), changes)
+	}
+	for i, units := range expected {
+		if changes[i].Parameters["Units"] != units || changes[i].Time != testData.Date(2*i+2).Time() || !EqualApprox(changes[i].Score, 100) {
+			t.Errorf("Expected change %d to be %v units on candle %d, got %+v", i, units, 2*i+2, changes[i])
+		}
+	}
+	if strategy.Units != 1000 || reoptimizer.Parameters()["Units"] != 1000.0 {
+		t.Errorf("Expected the strategy to end with 1000 units, got %v", strategy.Units)
+	}
+
+	// The confirmation hook can reject short parameters.
+	var asked int
+	reoptimizer = newReoptimizer(func(_ *Trader, _ Parameters, best OptimizationResult) bool {
+		asked++
+		return best.Parameters["Units"].(float64) > 0
+	})
+	strategy = run(reoptimizer)
+	if len(reoptimizer.Changes()) != 1 || asked != 2 || strategy.Units != 1000 {
+		t.Errorf("Expected only the first long parameters to be confirmed of 2, got %d of %d", len(reoptimizer.Changes()), asked)
+	}
+}
@@ -0,0 +1,1024 @@
+package autotrader
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/components"
+	"github.com/go-echarts/go-echarts/v2/opts"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// ReportContext is given to every ReportSection while a backtest report is generated. It holds the finished backtest and the outputs sections write to.
+type ReportContext struct {
+	Trader     *Trader
+	Broker     *TestBroker
+	Stats      *TraderStats
+	Summary    BacktestSummary          // Summary holds the performance metrics of the backtest.
+	DateLayout string                   // DateLayout is the layout used to format dates on charts, picked from the frequency of the trader.
+	Elapsed    time.Duration            // Elapsed is how long the backtest took to run.
+	Out        io.Writer                // Out receives the text output of the report, like the summary table.
+	Page       *components.Page         // Page receives the charts of the report.
+	Dir        string                   // Dir is the directory that files written by sections are placed in.
+	Events     *IndexedSeries[UnixTime] // Events are the events of the report, like earnings or news, which are drawn on the charts with the annotations of the strategy. It may be nil.
+	// Locale formats the money and numbers of the report. DefaultLocale is used if nil.
+	Locale *Locale
+}
+
+// locale returns the Locale of the report, or DefaultLocale if it has none.
+func (ctx *ReportContext) locale() Locale {
+	if ctx.Locale == nil {
+		return DefaultLocale
+	}
+	return *ctx.Locale
+}
+
+// Money formats an amount of the account currency with the Locale of the report, like "$1234.56".
+func (ctx *ReportContext) Money(v float64) string {
+	return ctx.locale().Money(v)
+}
+
+// Percent formats a percentage with the Locale of the report, like "12.34%".
+func (ctx *ReportContext) Percent(v float64) string {
+	return ctx.locale().Percent(v)
+}
+
+// SymbolLabel returns the symbol of the trader, labeled with its contract if the broker has an Instrument for it, like "ESZ2 (future x50, expires 2022-12-16)".
+func (ctx *ReportContext) SymbolLabel() string {
+	if ctx.Broker == nil {
+		return ctx.Trader.Symbol
+	}
+	return ctx.Broker.instrument(ctx.Trader.Symbol).Label()
+}
+
+// Path returns the path of a file named name in the output directory of the report. Absolute names are returned unchanged.
+func (ctx *ReportContext) Path(name string) string {
+	if ctx.Dir == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(ctx.Dir, name)
+}
+
+// ReportSection is one part of a backtest report. A section may print text to ReportContext.Out, add charts to ReportContext.Page, or both.
+type ReportSection interface {
+	Render(ctx *ReportContext) error
+}
+
+// ReportSectionFunc is a function that implements ReportSection.
+type ReportSectionFunc func(ctx *ReportContext) error
+
+func (f ReportSectionFunc) Render(ctx *ReportContext) error {
+	return f(ctx)
+}
+
+// ChartSection returns a ReportSection that adds the chart created by newChart to the page. This is the easiest way to add your own charts, like indicator diagnostics, to a report.
+func ChartSection(newChart func(ctx *ReportContext) components.Charter) ReportSection {
+	return ReportSectionFunc(func(ctx *ReportContext) error {
+		if chart := newChart(ctx); chart != nil {
+			ctx.Page.AddCharts(chart)
+		}
+		return nil
+	})
+}
+
+var (
+	SummarySection ReportSection = ReportSectionFunc(renderSummary) // SummarySection prints a table of the performance metrics to Out.
+	EquitySection  ReportSection = ChartSection(newEquityChart)     // EquitySection charts the equity and profit of the account over time.
+	KlineSection   ReportSection = ChartSection(newKlineChart)      // KlineSection charts the candles of the final data with markers for each trade.
+	ReturnsSection ReportSection = ChartSection(newReturnsChart)    // ReturnsSection charts the returns of each candle, sorted from least to greatest.
+	TradesSection  ReportSection = ReportSectionFunc(renderTrades)  // TradesSection prints a table of every trade and its tags to Out.
+	// CostsSection prints the total spread, commission, financing, and slippage costs as amounts and as percentages of the gross profit, and charts the cumulative costs over time.
+	CostsSection ReportSection = ReportSectionFunc(renderCosts)
+	// RecordedSection charts the numeric values recorded by the strategy with Trader.Record over time. Nothing is added if no values were recorded.
+	RecordedSection ReportSection = ChartSection(newRecordedChart)
+	// CurrencySection charts the net exposure to each currency over time as stacked bars, so concentrated exposure across currency pairs, like long EUR from both EUR_USD and EUR_JPY, can be spotted. Nothing is added if there were never any open positions.
+	CurrencySection ReportSection = ChartSection(newCurrencyChart)
+)
+
+// Report generates the output of a backtest from a list of sections, which are rendered in order. The charts of every section are written to a single HTML page.
+type Report struct {
+	Title    string          // Title is the title of the HTML page.
+	Filename string          // Filename is the path the HTML page is written to. If empty, no page is written.
+	Open     bool            // Open the page in the default browser once it has been written.
+	Out      io.Writer       // Out receives text output. It is os.Stdout if nil.
+	Dir      string          // Dir is the directory the page and other files are written to. If empty, the files are written to a new run of Runs, or the current directory if Runs is nil.
+	Runs     *RunArchive     // Runs gives every backtest a new timestamped directory for its files when Dir is empty, so earlier runs are not overwritten.
+	Sections []ReportSection // Sections are rendered in order.
+	// Events are timestamped events, like earnings, news, or macro releases from EventsFromCSV, which are drawn on the equity and kline charts and studied by EventStudySection. Events between candles are drawn on the candle they happened in.
+	Events *IndexedSeries[UnixTime]
+	// Locale formats the money, numbers, and dates of the report in the account currency and the conventions of the reader, like LocaleEuro for "1.234,56 €". DefaultLocale is used if nil.
+	Locale *Locale
+}
+
+// NewReport returns a Report with the default sections that writes the run manifest to result.json, the trades to trades.csv, and the charts to backtest.html in a new directory of the "runs" archive, then opens the page in the browser.
+func NewReport() *Report {
+	return &Report{
+		Title:    "Backtest Report",
+		Filename: "backtest.html",
+		Open:     true,
+		Runs:     &RunArchive{},
+		Sections: []ReportSection{SummarySection, ManifestSection("result.json"), TradesCSVSection("trades.csv"), EquitySection, CostsSection, DrawdownsSection, SetupsSection, CurrencySection, KlineSection, RecordedSection, ReturnsSection},
+	}
+}
+
+// NewHeadlessReport returns a Report that only prints the summary, writes it as JSON to summaryFile, writes the run manifest to result.json, and writes the trades to trades.csv in a new directory of the "runs" archive, skipping chart generation entirely. This is useful for quick iteration and for servers without a browser.
+func NewHeadlessReport(summaryFile string) *Report {
+	return &Report{
+		Runs:     &RunArchive{},
+		Sections: []ReportSection{SummarySection, SummaryJSONSection(summaryFile), ManifestSection("result.json"), TradesCSVSection("trades.csv")},
+	}
+}
+
+// TradesCSVSection returns a ReportSection that writes every trade of the backtest as CSV to filename in the report directory.
+func TradesCSVSection(filename string) ReportSection {
+	return ReportSectionFunc(func(ctx *ReportContext) error {
+		f, err := os.Create(ctx.Path(filename))
+		if err != nil {
+			return err
+		}
+		w := csv.NewWriter(f)
+		w.Write([]string{"Time", "Type", "Units", "Price", "Cost", "Position", "Tags"})
+		for _, trade := range ctx.Stats.Trades() {
+			date, kind := trade.OpenTime, "Entry"
+			if trade.Exit {
+				date, kind = trade.CloseTime, "Exit"
+			}
+			w.Write([]string{
+				date.Format(time.RFC3339),
+				kind,
+				strconv.FormatFloat(trade.Units, 'f', -1, 64),
+				strconv.FormatFloat(trade.Price, 'f', -1, 64),
+				strconv.FormatFloat(trade.Cost(), 'f', -1, 64),
+				trade.PositionID,
+				trade.Tags.String(),
+			})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	})
+}
+
+// SummaryJSONSection returns a ReportSection that writes the BacktestSummary as indented JSON to filename in the report directory.
+func SummaryJSONSection(filename string) ReportSection {
+	return ReportSectionFunc(func(ctx *ReportContext) error {
+		data, err := json.MarshalIndent(ctx.Summary, "", "  ")
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(ctx.Path(filename), append(data, '\n'), 0644)
+	})
+}
+
+// EquityCSVSection returns a ReportSection that writes the equity curve of the backtest as CSV to filename in the report directory, with the date, equity, profit, and drawdown of every candle.
+func EquityCSVSection(filename string) ReportSection {
+	return ReportSectionFunc(func(ctx *ReportContext) error {
+		f, err := os.Create(ctx.Path(filename))
+		if err != nil {
+			return err
+		}
+		w := csv.NewWriter(f)
+		w.Write([]string{"Date", "Equity", "Profit", "Drawdown"})
+		dated := ctx.Stats.Dated
+		for i := 0; i < dated.Len(); i++ {
+			w.Write([]string{
+				dated.Date(i).Format(time.RFC3339),
+				strconv.FormatFloat(dated.Float("Equity", i), 'f', -1, 64),
+				strconv.FormatFloat(dated.Float("Profit", i), 'f', -1, 64),
+				strconv.FormatFloat(dated.Float("Drawdown", i), 'f', -1, 64),
+			})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	})
+}
+
+// Add appends sections to the end of the report and returns the report.
+func (r *Report) Add(sections ...ReportSection) *Report {
+	r.Sections = append(r.Sections, sections...)
+	return r
+}
+
+// Generate renders each section of the report for the finished backtest of trader and writes the page to Filename.
+func (r *Report) Generate(trader *Trader, broker *TestBroker, elapsed time.Duration) error {
+	dir, err := r.runDir(trader)
+	if err != nil {
+		return err
+	}
+	return r.generate(trader, broker, elapsed, dir)
+}
+
+// runDir returns the directory of the files of the report, creating a new run of the archive if Dir is empty.
+func (r *Report) runDir(trader *Trader) (string, error) {
+	if r.Dir != "" || r.Runs == nil {
+		return r.Dir, nil
+	}
+	return r.Runs.Create(strategyName(trader.Strategy))
+}
+
+func (r *Report) generate(trader *Trader, broker *TestBroker, elapsed time.Duration, dir string) error {
+	ctx := &ReportContext{
+		Trader:     trader,
+		Broker:     broker,
+		Stats:      trader.Stats(),
+		Summary:    Summarize(trader.Stats(), broker),
+		DateLayout: frequencyDateLayout(trader.Frequency),
+		Elapsed:    elapsed,
+		Out:        r.Out,
+		Page:       components.NewPage(),
+		Dir:        dir,
+		Events:     r.Events,
+		Locale:     r.Locale,
+	}
+	ctx.DateLayout = ctx.locale().dateLayout(ctx.DateLayout)
+	if ctx.Out == nil {
+		ctx.Out = os.Stdout
+	}
+	ctx.Page.PageTitle = r.Title
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	for _, section := range r.Sections {
+		if err := section.Render(ctx); err != nil {
+			return err
+		}
+	}
+
+	if r.Filename == "" || len(ctx.Page.Charts) == 0 {
+		return nil
+	}
+	filename := ctx.Path(r.Filename)
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := ctx.Page.Render(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if r.Open {
+		return Open(filename)
+	}
+	return nil
+}
+
+// BacktestSummary holds the performance metrics of a finished backtest.
+type BacktestSummary struct {
+	Timespan       time.Duration `json:"timespan"`     // Timespan is the time between the first and last candles. It is encoded in JSON as nanoseconds.
+	Candles        int           `json:"candles"`      // Candles is the number of candles the strategy was run on.
+	Trades         int           `json:"trades"`       // Trades is the number of positions that were opened.
+	TotalTraded    float64       `json:"total_traded"` // TotalTraded is the value of every entry trade.
+	NetProfit      float64       `json:"net_profit"`
+	NetProfitPct   float64       `json:"net_profit_pct"` // NetProfitPct is the net profit as a percentage of the starting equity.
+	ProfitFactor   float64       `json:"profit_factor"`  // ProfitFactor is the net profit divided by the maximum drawdown.
+	SharpeRatio    float64       `json:"sharpe_ratio"`   // SharpeRatio is the mean return of each candle divided by its standard deviation, annualized by the number of candles in a year.
+	MaxDrawdown    float64       `json:"max_drawdown"`
+	MaxDrawdownPct float64       `json:"max_drawdown_pct"` // MaxDrawdownPct is the maximum drawdown as a percentage of the starting equity.
+	Spread         float64       `json:"spread"`           // Spread is the total spread paid on trades.
+	SpreadPips     float64       `json:"spread_pips"`      // SpreadPips is the total spread paid on trades in pips.
+	Commission     float64       `json:"commission"`       // Commission is the total commission paid on trades.
+	Slippage       float64       `json:"slippage"`         // Slippage is the total slippage paid on trades.
+	Financing      float64       `json:"financing"`        // Financing is the total swap or funding paid on positions.
+	// SkippedSignals is the number of orders that were not placed because they were outside the TradingSchedule of the Trader.
+	SkippedSignals int `json:"skipped_signals"`
+	// LongestDrawdown is the longest time the equity spent below a previous peak before recovering to it, or until the end of the backtest. It is encoded in JSON as nanoseconds.
+	LongestDrawdown    time.Duration `json:"longest_drawdown"`
+	CurrentDrawdown    float64       `json:"current_drawdown"`     // CurrentDrawdown is how far the final equity is below its peak.
+	CurrentDrawdownPct float64       `json:"current_drawdown_pct"` // CurrentDrawdownPct is the current drawdown as a percentage of the peak equity.
+	// Symbols are the results of each symbol when more than one symbol was traded, like by a Trader with Symbols.
+	Symbols map[string]SymbolResult `json:"symbols,omitempty"`
+}
+
+// Summarize calculates the performance metrics of a finished backtest from the stats of its trader.
+func Summarize(stats *TraderStats, broker *TestBroker) BacktestSummary {
+	var s BacktestSummary
+	if stats.Dated == nil || stats.Dated.Len() == 0 {
+		return s
+	}
+	for _, trade := range stats.Trades() {
+		s.Slippage += trade.Slippage
+		s.Financing += trade.Financing
+		if trade.Exit { // Only count entry trades.
+			continue
+		}
+		s.Trades++
+		s.TotalTraded += trade.Price * math.Abs(trade.Units)
+	}
+	stats.Dated.Series("Drawdown").ForEach(func(i int, val any) {
+		if f := val.(float64); f > s.MaxDrawdown {
+			s.MaxDrawdown = f
+		}
+	})
+	if stats.Samples != nil { // Samples catch drawdowns within the candles of the strategy.
+		stats.Samples.Series("Drawdown").ForEach(func(i int, val any) {
+			if f := val.(float64); f > s.MaxDrawdown {
+				s.MaxDrawdown = f
+			}
+		})
+	}
+	startingEquity := stats.Dated.Float("Equity", 0)
+	s.Candles = stats.Dated.Len()
+	s.SkippedSignals = len(stats.Skipped())
+	if symbols := stats.SymbolResults(); len(symbols) > 1 {
+		s.Symbols = symbols
+	}
+	s.Timespan = stats.Dated.Date(-1).Sub(stats.Dated.Date(0)).Round(time.Second)
+	s.NetProfit = stats.Dated.Float("Profit", -1)
+	s.NetProfitPct = 100 * s.NetProfit / startingEquity
+	if s.MaxDrawdown > 0 {
+		s.ProfitFactor = s.NetProfit / s.MaxDrawdown // Divide net profit by maximum drawdown to get the profit factor.
+	}
+	s.MaxDrawdownPct = 100 * s.MaxDrawdown / startingEquity
+	s.SharpeRatio = sharpeRatio(stats.Dated.Series("Equity"), s.Timespan)
+	if drawdowns := Drawdowns(stats); len(drawdowns) > 0 {
+		for _, d := range drawdowns {
+			s.LongestDrawdown = Max(s.LongestDrawdown, d.Duration)
+		}
+		if last := drawdowns[len(drawdowns)-1]; !last.Recovered() {
+			s.CurrentDrawdown = last.PeakEquity - stats.Dated.Float("Equity", -1)
+			s.CurrentDrawdownPct = 100 * s.CurrentDrawdown / last.PeakEquity
+		}
+	}
+	if broker != nil {
+		s.Spread = broker.SpreadCollected()
+		s.SpreadPips = broker.SpreadCollectedPips()
+		s.Commission = broker.CommissionPaid()
+	}
+	return s
+}
+
+// sharpeRatio returns the annualized Sharpe ratio of the returns between each value of equity over timespan, without a risk-free rate. It is zero if there are too few values or the returns do not vary.
+func sharpeRatio(equity *Series, timespan time.Duration) float64 {
+	if equity.Len() < 3 || timespan <= 0 {
+		return 0
+	}
+	returns := make([]float64, 0, equity.Len()-1)
+	for i := 1; i < equity.Len(); i++ {
+		if prev := equity.Float(i - 1); prev != 0 {
+			returns = append(returns, equity.Float(i)/prev-1)
+		}
+	}
+	series := NewFloatSeries("Returns", returns...)
+	stdDev := series.StdDev()
+	if len(returns) < 2 || stdDev == 0 || math.IsNaN(stdDev) {
+		return 0
+	}
+	perYear := float64(equity.Len()-1) / timespan.Hours() * 24 * 365
+	return series.Mean() / stdDev * math.Sqrt(perYear)
+}
+
+func renderSummary(ctx *ReportContext) error {
+	s, l := ctx.Summary, ctx.locale()
+	w := tabwriter.NewWriter(ctx.Out, 0, 0, 1, ' ', 0)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Timespan:\t%s\t\n", s.Timespan)
+	fmt.Fprintf(w, "Candles:\t%d\t\n", s.Candles)
+	fmt.Fprintf(w, "Trades:\t%d\t\n", s.Trades)
+	fmt.Fprintf(w, "Total Traded:\t%s\t\n", l.Money(s.TotalTraded))
+	fmt.Fprintf(w, "Net Profit:\t%s (%s)\t\n", l.Money(s.NetProfit), l.Percent(s.NetProfitPct))
+	fmt.Fprintf(w, "Profit Factor:\t%s\t\n", l.Number(s.ProfitFactor, 2))
+	fmt.Fprintf(w, "Sharpe Ratio:\t%s\t\n", l.Number(s.SharpeRatio, 2))
+	fmt.Fprintf(w, "Max Drawdown:\t%s (%s)\t\n", l.Money(s.MaxDrawdown), l.Percent(s.MaxDrawdownPct))
+	fmt.Fprintf(w, "Longest Drawdown:\t%s\t\n", s.LongestDrawdown)
+	fmt.Fprintf(w, "Current Drawdown:\t%s (%s)\t\n", l.Money(s.CurrentDrawdown), l.Percent(s.CurrentDrawdownPct))
+	fmt.Fprintf(w, "Spread collected:\t%s (%s pips)\t\n", l.Money(s.Spread), l.Number(s.SpreadPips, 1))
+	fmt.Fprintf(w, "Commission paid:\t%s\t\n", l.Money(s.Commission))
+	fmt.Fprintf(w, "Slippage:\t%s\t\n", l.Money(s.Slippage))
+	fmt.Fprintf(w, "Financing:\t%s\t\n", l.Money(s.Financing))
+	if s.SkippedSignals > 0 {
+		fmt.Fprintf(w, "Skipped Signals:\t%d\t\n", s.SkippedSignals)
+	}
+	if len(s.Symbols) > 0 {
+		symbols := maps.Keys(s.Symbols)
+		slices.Sort(symbols)
+		for _, symbol := range symbols {
+			result := s.Symbols[symbol]
+			fmt.Fprintf(w, "%s:\t%d trades, %d wins, %s profit, %s costs\t\n", symbol, result.Trades, result.Wins, l.Money(result.Profit), l.Money(result.Costs))
+		}
+	}
+	fmt.Fprintln(w)
+	return w.Flush()
+}
+
+// CostBreakdown is the total of each kind of execution cost paid over a backtest.
+type CostBreakdown struct {
+	Spread      float64
+	Commission  float64
+	Financing   float64
+	Slippage    float64
+	GrossProfit float64 // GrossProfit is the net profit plus the total costs, which is what the strategy would have made without execution costs.
+}
+
+// NewCostBreakdown totals the costs of the trades in stats, given the net profit of the backtest.
+func NewCostBreakdown(stats *TraderStats, netProfit float64) CostBreakdown {
+	var c CostBreakdown
+	for _, trade := range stats.Trades() {
+		c.Spread += trade.Spread
+		c.Commission += trade.Commission
+		c.Financing += trade.Financing
+		c.Slippage += trade.Slippage
+	}
+	c.GrossProfit = netProfit + c.Total()
+	return c
+}
+
+// Total returns the sum of every cost.
+func (c CostBreakdown) Total() float64 {
+	return c.Spread + c.Commission + c.Financing + c.Slippage
+}
+
+// Pct returns cost as a percentage of the gross profit, or NaN if there was no gross profit.
+func (c CostBreakdown) Pct(cost float64) float64 {
+	if c.GrossProfit <= 0 {
+		return math.NaN()
+	}
+	return 100 * cost / c.GrossProfit
+}
+
+func renderCosts(ctx *ReportContext) error {
+	c := NewCostBreakdown(ctx.Stats, ctx.Summary.NetProfit)
+	w := tabwriter.NewWriter(ctx.Out, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(w, "Gross Profit:\t%s\t\n", ctx.Money(c.GrossProfit))
+	for _, cost := range []struct {
+		name   string
+		amount float64
+	}{{"Spread", c.Spread}, {"Commission", c.Commission}, {"Financing", c.Financing}, {"Slippage", c.Slippage}, {"Total Costs", c.Total()}} {
+		if pct := c.Pct(cost.amount); math.IsNaN(pct) {
+			fmt.Fprintf(w, "%s:\t%s\t\n", cost.name, ctx.Money(cost.amount))
+		} else {
+			fmt.Fprintf(w, "%s:\t%s (%s of gross profit)\t\n", cost.name, ctx.Money(cost.amount), ctx.Percent(pct))
+		}
+	}
+	fmt.Fprintln(w)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	ctx.Page.AddCharts(newCostsChart(ctx))
+	return nil
+}
+
+// cumulativeCosts returns the running total of each kind of cost at every candle of stats, in the order spread, commission, financing, slippage, and total.
+func cumulativeCosts(stats *Frame) [5][]opts.LineData {
+	var lines [5][]opts.LineData
+	var totals [5]float64
+	for i := 0; i < stats.Len(); i++ {
+		if trades, ok := stats.Value("Trades", i).([]TradeStat); ok {
+			for _, trade := range trades {
+				totals[0] += trade.Spread
+				totals[1] += trade.Commission
+				totals[2] += trade.Financing
+				totals[3] += trade.Slippage
+				totals[4] += trade.Cost()
+			}
+		}
+		for j, total := range totals {
+			lines[j] = append(lines[j], opts.LineData{Value: Round(total, 2)})
+		}
+	}
+	return lines
+}
+
+func newCostsChart(ctx *ReportContext) components.Charter {
+	chart := charts.NewLine()
+	chart.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{Title: "Cumulative Costs"}),
+		charts.WithTooltipOpts(opts.Tooltip{Show: true, Trigger: "axis"}),
+		charts.WithYAxisOpts(opts.YAxis{AxisLabel: &opts.AxisLabel{Show: true, Formatter: ctx.locale().axisFormatter()}}),
+		charts.WithLegendOpts(opts.Legend{Show: true}),
+	)
+	lines := cumulativeCosts(ctx.Stats.Dated)
+	chart.SetXAxis(seriesStringArray(ctx.Stats.Dated.Dates(), ctx.DateLayout))
+	for i, name := range []string{"Spread", "Commission", "Financing", "Slippage", "Total"} {
+		chart.AddSeries(name, lines[i])
+	}
+	return chart
+}
+
+func renderTrades(ctx *ReportContext) error {
+	w := tabwriter.NewWriter(ctx.Out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Trades of %s:\n", ctx.SymbolLabel())
+	fmt.Fprintln(w, "Time\tType\tUnits\tPrice\tCost\tPosition\tTags\t")
+	for _, trade := range ctx.Stats.Trades() {
+		date, kind := trade.OpenTime, "Entry"
+		if trade.Exit {
+			date, kind = trade.CloseTime, "Exit"
+			if trade.CloseType != "" {
+				kind += " (" + string(trade.CloseType) + ")"
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%v\t%v\t%s\t%s\t%s\t\n", date.Format(ctx.DateLayout), kind, trade.Units, trade.Price, ctx.Money(trade.Cost()), trade.PositionID, trade.Tags)
+	}
+	fmt.Fprintln(w)
+	return w.Flush()
+}
+
+// frequencyDateLayout picks a datetime layout based on the frequency.
+func frequencyDateLayout(frequency string) string {
+	dateLayout := time.DateTime
+	if strings.HasPrefix(strings.ToUpper(frequency), "MS") { // Milliseconds
+		dateLayout = "15:04:05.000"
+	} else if strings.Contains(frequency, "S") { // Seconds
+		dateLayout = "15:04:05"
+	} else if strings.Contains(frequency, "H") { // Hours
+		dateLayout = "2006-01-02 15:04"
+	} else if strings.Contains(frequency, "D") || frequency == "W" { // Days or Weeks
+		dateLayout = time.DateOnly
+	} else if frequency == "M" { // Months
+		dateLayout = "2006-01"
+	} else if strings.Contains(frequency, "M") { // Minutes
+		dateLayout = "01-02 15:04"
+	}
+	return dateLayout
+}
+
+func newEquityChart(ctx *ReportContext) components.Charter {
+	stats := ctx.Stats
+	// Create a new line balChart based on account equity.
+	balChart := charts.NewLine()
+	balChart.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{
+			Title:    "Balance",
+			Subtitle: fmt.Sprintf("%s %s %T  %s (took %.2f seconds)", ctx.SymbolLabel(), ctx.Trader.Frequency, ctx.Trader.Strategy, time.Now().Format(time.DateTime), ctx.Elapsed.Seconds()),
+		}),
+		charts.WithTooltipOpts(opts.Tooltip{
+			Show:      true,
+			Trigger:   "axis",
+			TriggerOn: "mousemove|click",
+		}),
+		charts.WithYAxisOpts(opts.YAxis{
+			AxisLabel: &opts.AxisLabel{
+				Show:      true,
+				Formatter: ctx.locale().axisFormatter(),
+			},
+		}),
+		charts.WithLegendOpts(opts.Legend{
+			Show:     true,
+			Selected: map[string]bool{"Equity": false, "Profit": true},
+		}))
+	balChart.SetXAxis(seriesStringArray(stats.Dated.Dates(), ctx.DateLayout)).
+		AddSeries("Equity", lineDataFromSeries(stats.Dated.Series("Equity"))).
+		SetSeriesOptions(
+			charts.WithMarkPointNameTypeItemOpts(
+				opts.MarkPointNameTypeItem{Name: "Peak", Type: "max", ItemStyle: &opts.ItemStyle{
+					Color: balChart.Colors[1],
+				}},
+				opts.MarkPointNameTypeItem{Name: "Drawdown", Type: "min", ItemStyle: &opts.ItemStyle{
+					Color: balChart.Colors[3],
+				}},
+			),
+		)
+	balChart.AddSeries("Profit", lineDataFromSeries(stats.Dated.Series("Profit")),
+		charts.WithMarkPointNameCoordItemOpts(annotationMarks(ctx.annotations(), ctx.DateLayout, func(date time.Time) (float64, bool) {
+			for i := 0; i < stats.Dated.Len(); i++ {
+				if stats.Dated.Date(i).Equal(date) {
+					return stats.Dated.Float("Profit", i), true
+				}
+			}
+			return 0, false
+		})...))
+	return balChart
+}
+
+func newKlineChart(ctx *ReportContext) components.Charter {
+	kline := newKline(ctx.Trader.data, ctx.Stats.Dated, ctx.DateLayout)
+	addExitLevels(kline, ctx.Stats.Dated, ctx.Trader.data, ctx.DateLayout)
+	addTradeConnectors(kline, ctx.Stats.Trades(), ctx.Trader.data, ctx.DateLayout)
+	if annotations := ctx.annotations(); len(annotations) > 0 {
+		highs := ctx.Trader.data.Highs()
+		kline.AddSeries("Annotations", nil, charts.WithMarkPointNameCoordItemOpts(
+			annotationMarks(annotations, ctx.DateLayout, func(date time.Time) (float64, bool) {
+				row := highs.Row(UnixTime(date.Unix()))
+				return highs.Float(row), row >= 0
+			})...))
+	}
+	return kline
+}
+
+// addExitLevels draws the stop loss, take profit, and trailing stop of each position over the candles it was open as step lines on the kline chart. Candles that are not on the chart are skipped.
+func addExitLevels(kline *charts.Kline, stats *Frame, dohlcv *IndexedFrame[UnixTime], dateLayout string) {
+	levelsSeries := stats.Series("Levels")
+	if levelsSeries == nil {
+		return
+	}
+	kinds := []struct {
+		name  string
+		color string
+		level func(PositionLevels) float64
+	}{
+		{"Stop Loss", "#d73027", func(l PositionLevels) float64 { return l.StopLoss }},
+		{"Take Profit", "#1a9850", func(l PositionLevels) float64 { return l.TakeProfit }},
+		{"Trailing Stop", "#fc8d59", func(l PositionLevels) float64 { return l.TrailingStop }},
+	}
+	for _, kind := range kinds {
+		// Collect the level of each position on each candle it was open.
+		type point struct {
+			date  time.Time
+			level float64
+		}
+		var positions []string
+		points := make(map[string][]point)
+		levelsSeries.ForEach(func(i int, val any) {
+			levels, _ := val.([]PositionLevels)
+			for _, l := range levels {
+				if kind.level(l) == 0 {
+					continue
+				}
+				if _, ok := points[l.PositionID]; !ok {
+					positions = append(positions, l.PositionID)
+				}
+				points[l.PositionID] = append(points[l.PositionID], point{stats.Date(i), kind.level(l)})
+			}
+		})
+
+		var segments []lineSegment
+		for _, id := range positions {
+			path := points[id]
+			for start := 0; start < len(path); {
+				end := start
+				for end+1 < len(path) && path[end+1].level == path[start].level {
+					end++
+				}
+				// Step to the candle where the level changes, if any.
+				to := path[end].date
+				if end+1 < len(path) {
+					to = path[end+1].date
+				}
+				if dohlcv.Closes().Row(UnixTime(path[start].date.Unix())) >= 0 && dohlcv.Closes().Row(UnixTime(to.Unix())) >= 0 {
+					segments = append(segments, lineSegment{
+						From:  []any{path[start].date.Format(dateLayout), path[start].level},
+						To:    []any{to.Format(dateLayout), path[start].level},
+						Color: kind.color,
+						Type:  "dashed",
+					})
+				}
+				start = end + 1
+			}
+		}
+		if len(segments) > 0 {
+			kline.AddSeries(kind.name, nil, withLineSegments(segments))
+		}
+	}
+}
+
+// addTradeConnectors draws a line from the entry of each closed position to its exit on the kline chart, green for a profit and red for a loss. Trades that entered or exited on candles that are not on the chart are skipped.
+func addTradeConnectors(kline *charts.Kline, trades []TradeStat, dohlcv *IndexedFrame[UnixTime], dateLayout string) {
+	var segments []lineSegment
+	for _, trade := range trades {
+		if !trade.Exit || trade.Entry == nil {
+			continue
+		}
+		entry := trade.Entry
+		if dohlcv.Closes().Row(UnixTime(entry.OpenTime.Unix())) < 0 || dohlcv.Closes().Row(UnixTime(trade.CloseTime.Unix())) < 0 {
+			continue
+		}
+		color := "#1a9850"
+		if (trade.Price-entry.Price)*entry.Units < 0 {
+			color = "#d73027"
+		}
+		segments = append(segments, lineSegment{
+			From:  []any{entry.OpenTime.Format(dateLayout), entry.Price},
+			To:    []any{trade.CloseTime.Format(dateLayout), trade.Price},
+			Color: color,
+			Type:  "solid",
+		})
+	}
+	if len(segments) > 0 {
+		kline.AddSeries("Trade Lines", nil, withLineSegments(segments))
+	}
+}
+
+// lineSegment is a straight line between two coordinates of a chart.
+type lineSegment struct {
+	From, To []any
+	Color    string
+	Type     string // Type is the style of the line: "solid", "dashed", or "dotted".
+}
+
+// withLineSegments draws each segment on the series as a mark line without end symbols or labels.
+func withLineSegments(segments []lineSegment) charts.SeriesOpts {
+	type coord struct {
+		Coord     []any           `json:"coord"`
+		LineStyle *opts.LineStyle `json:"lineStyle,omitempty"`
+	}
+	return func(s *charts.SingleSeries) {
+		if s.MarkLines == nil {
+			s.MarkLines = &opts.MarkLines{}
+		}
+		s.MarkLines.Symbol = []string{"none", "none"}
+		s.MarkLines.Label = &opts.Label{Show: false}
+		for _, segment := range segments {
+			s.MarkLines.Data = append(s.MarkLines.Data, []coord{
+				{Coord: segment.From, LineStyle: &opts.LineStyle{Color: segment.Color, Type: segment.Type, Width: 1.5}},
+				{Coord: segment.To},
+			})
+		}
+	}
+}
+
+// annotations returns the annotations of the strategy followed by the events of the report, which are moved to the start of the candle of the data they happened in.
+func (ctx *ReportContext) annotations() []Annotation {
+	annotations := ctx.Stats.Annotations()
+	if ctx.Events == nil || ctx.Trader.data == nil {
+		return annotations
+	}
+	annotations = slices.Clone(annotations)
+	dates := ctx.Trader.data.Closes().indexes
+	duration, _ := FrequencyDuration(ctx.Trader.Frequency)
+	for _, event := range eventAnnotations(ctx.Events) {
+		row, found := slices.BinarySearch(dates, UnixTime(event.Time.Unix()))
+		if !found {
+			row-- // The candle before the event is the one it happened in.
+		}
+		if row < 0 || !dates[row].Time().Add(duration).After(event.Time) && !found {
+			continue // The event is not in a candle of the data.
+		}
+		annotations = append(annotations, Annotation{Time: dates[row].Time(), Name: event.Name})
+	}
+	return annotations
+}
+
+// annotationMarks returns a pin for each annotation placed at the value returned for its date. Annotations are skipped when no value is found, like when the candle is not on the chart.
+func annotationMarks(annotations []Annotation, dateLayout string, valueAt func(date time.Time) (float64, bool)) []opts.MarkPointNameCoordItem {
+	marks := make([]opts.MarkPointNameCoordItem, 0, len(annotations))
+	for _, annotation := range annotations {
+		value, ok := valueAt(annotation.Time)
+		if !ok {
+			continue
+		}
+		marks = append(marks, opts.MarkPointNameCoordItem{
+			Name:       annotation.Name,
+			Value:      annotation.Name,
+			Coordinate: []interface{}{annotation.Time.Format(dateLayout), value},
+			Label: &opts.Label{
+				Show:      true,
+				Formatter: "{b}",
+			},
+			ItemStyle: &opts.ItemStyle{
+				Color: "#5470c6",
+			},
+			Symbol:     "pin",
+			SymbolSize: 40,
+		})
+	}
+	return marks
+}
+
+func newRecordedChart(ctx *ReportContext) components.Charter {
+	stats := ctx.Stats
+	if len(stats.Recorded()) == 0 {
+		return nil
+	}
+	chart := charts.NewLine()
+	chart.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{
+			Title:    "Recorded",
+			Subtitle: "Values recorded by the strategy",
+		}),
+		charts.WithTooltipOpts(opts.Tooltip{
+			Show:      true,
+			Trigger:   "axis",
+			TriggerOn: "mousemove|click",
+		}),
+		charts.WithYAxisOpts(opts.YAxis{
+			Scale: true,
+		}),
+		charts.WithLegendOpts(opts.Legend{
+			Show: true,
+		}))
+	chart.SetXAxis(seriesStringArray(stats.Dated.Dates(), ctx.DateLayout))
+	for _, name := range stats.Recorded() {
+		if data, ok := recordedLineData(stats.Dated.Series(name)); ok {
+			chart.AddSeries(name, data)
+		}
+	}
+	return chart
+}
+
+// recordedLineData returns the values of a recorded series as line data, where candles without a value are left as gaps. False is returned if the series holds values that cannot be plotted, like strings.
+func recordedLineData(s *Series) ([]opts.LineData, bool) {
+	data := make([]opts.LineData, s.Len())
+	for i := 0; i < s.Len(); i++ {
+		switch val := s.Value(i).(type) {
+		case nil:
+			data[i] = opts.LineData{Value: "-"} // ECharts draws a gap for "-".
+		case float64:
+			data[i] = opts.LineData{Value: Round(val, 4)}
+		case float32:
+			data[i] = opts.LineData{Value: Round(float64(val), 4)}
+		case int:
+			data[i] = opts.LineData{Value: val}
+		case int64:
+			data[i] = opts.LineData{Value: val}
+		case bool:
+			if val {
+				data[i] = opts.LineData{Value: 1}
+			} else {
+				data[i] = opts.LineData{Value: 0}
+			}
+		default:
+			return nil, false
+		}
+	}
+	return data, true
+}
+
+func newReturnsChart(ctx *ReportContext) components.Charter {
+	// Sort Returns by value.
+	// Plot returns as a bar chart.
+	returnsSeries := ctx.Stats.Dated.Series("Returns")
+	returns := make([]float64, 0, returnsSeries.Len())
+	// Remove nil values.
+	for i := 0; i < returnsSeries.Len(); i++ {
+		r := returnsSeries.Value(i)
+		if r != nil {
+			returns = append(returns, r.(float64))
+		}
+	}
+	// Sort the returns.
+	slices.Sort(returns)
+	// Create the X axis labels for the returns chart based on length of the returns slice.
+	returnsLabels := make([]int, len(returns))
+	for i := range returns {
+		returnsLabels[i] = i + 1
+	}
+	returnsBars := make([]opts.BarData, len(returns))
+	for i, r := range returns {
+		returnsBars[i] = opts.BarData{Value: r}
+	}
+	var avg float64
+	for _, r := range returns {
+		avg += r
+	}
+	avg /= float64(len(returns))
+	returnsAverage := make([]opts.LineData, len(returns))
+	for i := range returnsAverage {
+		returnsAverage[i] = opts.LineData{Value: avg}
+	}
+
+	returnsChart := charts.NewBar()
+	returnsChart.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{
+			Title:    "Returns",
+			Subtitle: "Average: " + ctx.Money(avg),
+		}),
+		charts.WithYAxisOpts(opts.YAxis{
+			AxisLabel: &opts.AxisLabel{
+				Show:      true,
+				Formatter: ctx.locale().axisFormatter(),
+			},
+		}))
+	returnsChart.SetXAxis(returnsLabels).
+		AddSeries("Returns", returnsBars)
+
+	returnsChartAvg := charts.NewLine()
+	returnsChartAvg.SetGlobalOptions(charts.WithTitleOpts(opts.Title{
+		Title: "Average Returns",
+	}))
+	returnsChartAvg.SetXAxis(returnsLabels).
+		AddSeries("Average", returnsAverage, func(s *charts.SingleSeries) {
+			s.LineStyle = &opts.LineStyle{
+				Width: 2,
+			}
+		})
+	returnsChart.Overlap(returnsChartAvg)
+	return returnsChart
+}
+
+// newKline charts the candles of dohlcv with a marker for each trade in the Trades column of stats. Trades made on candles that are not in dohlcv are skipped.
+func newKline(dohlcv *IndexedFrame[UnixTime], stats *Frame, dateLayout string) *charts.Kline {
+	kline := charts.NewKLine()
+
+	x := make([]string, dohlcv.Len())
+	y := make([]opts.KlineData, dohlcv.Len())
+	for i := 0; i < dohlcv.Len(); i++ {
+		x[i] = dohlcv.Date(i).Time().Format(dateLayout)
+		y[i] = opts.KlineData{Value: [4]float64{
+			dohlcv.Open(i),
+			dohlcv.Close(i),
+			dohlcv.Low(i),
+			dohlcv.High(i),
+		}}
+	}
+
+	marks := make([]opts.MarkPointNameCoordItem, 0)
+	trades := stats.Series("Trades")
+	for j := 0; j < trades.Len(); j++ {
+		i := dohlcv.Closes().Row(UnixTime(stats.Date(j).Unix()))
+		if i < 0 {
+			continue
+		}
+		if slice := trades.Value(j); slice != nil {
+			for _, trade := range slice.([]TradeStat) {
+				color := "green"
+				rotation := float32(0)
+				if trade.Units < 0 {
+					color = "red"
+					rotation = 180
+				}
+				if trade.Exit {
+					color = "black"
+				}
+				marks = append(marks, opts.MarkPointNameCoordItem{
+					Name:       "Trade",
+					Value:      fmt.Sprintf("%v units", trade.Units),
+					Coordinate: []interface{}{x[i], y[i].Value.([4]float64)[1]},
+					Label: &opts.Label{
+						Show:     true,
+						Position: "inside",
+					},
+					ItemStyle: &opts.ItemStyle{
+						Color: color,
+					},
+					Symbol:       "arrow",
+					SymbolRotate: rotation,
+					SymbolSize:   25,
+				})
+			}
+		}
+	}
+
+	kline.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{
+			Title:    "Trades",
+			Subtitle: fmt.Sprintf("Showing %d candles", dohlcv.Len()),
+		}),
+		charts.WithXAxisOpts(opts.XAxis{
+			SplitNumber: 20,
+		}),
+		charts.WithYAxisOpts(opts.YAxis{
+			Scale: true,
+		}),
+		charts.WithTooltipOpts(opts.Tooltip{ // Enable seeing details on hover.
+			Show:      true,
+			Trigger:   "axis",
+			TriggerOn: "mousemove|click",
+		}),
+		charts.WithDataZoomOpts(opts.DataZoom{ // Support zooming with scroll wheel.
+			Type:       "inside",
+			Start:      0,
+			End:        100,
+			XAxisIndex: []int{0},
+		}),
+		charts.WithDataZoomOpts(opts.DataZoom{ // Support zooming with bottom slider.
+			Type:       "slider",
+			Start:      0,
+			End:        100,
+			XAxisIndex: []int{0},
+		}),
+	)
+	kline.SetXAxis(x).AddSeries("Price Action", y, charts.WithMarkPointNameCoordItemOpts(marks...))
+	return kline
+}
+
+func lineDataFromSeries(s *Series) []opts.LineData {
+	if s == nil || s.Len() == 0 {
+		return []opts.LineData{}
+	}
+	data := make([]opts.LineData, s.Len())
+	for i := 0; i < s.Len(); i++ {
+		data[i] = opts.LineData{Value: Round(s.Value(i).(float64), 2)}
+	}
+	return data
+}
+
+func seriesStringArray(s *Series, dateLayout string) []string {
+	if s == nil || s.Len() == 0 {
+		return []string{}
+	}
+	data := make([]string, s.Len())
+	for i := 0; i < s.Len(); i++ {
+		switch val := s.Value(i).(type) {
+		case time.Time:
+			data[i] = val.Format(dateLayout)
+		case string:
+			data[i] = fmt.Sprintf("%q", val)
+		default:
+			data[i] = fmt.Sprintf("%v", val)
+		}
+	}
+	return data
+}
@@ -0,0 +1,420 @@
+package autotrader
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/components"
+)
+
+func runTestBacktest(t *testing.T, strategy Strategy) (*Trader, *TestBroker) {
+	t.Helper()
+	broker := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	broker.Slippage = 0
+	trader := NewTrader(TraderConfig{
+		Broker:        broker,
+		Strategy:      strategy,
+		Symbol:        "EUR_USD",
+		Frequency:     "D",
+		CandlesToKeep: 5,
+	})
+	trader.Log.SetOutput(io.Discard)
+	trader.Init()
+	for !trader.EOF {
+		trader.Tick()
+		broker.Advance()
+	}
+	return trader, broker
+}
+
+func TestReportSections(t *testing.T) {
+	trader, broker := runTestBacktest(t, &roundTripStrategy{})
+
+	var order []string
+	var out bytes.Buffer
+	report := &Report{
+		Title:    "Test Report",
+		Filename: filepath.Join(t.TempDir(), "report.html"),
+		Out:      &out,
+		Sections: []ReportSection{SummarySection, EquitySection},
+	}
+	report.Add(
+		ReportSectionFunc(func(ctx *ReportContext) error {
+			order = append(order, "custom")
+			if ctx.Stats != trader.Stats() || ctx.Broker != broker {
+				t.Error("Expected the context to hold the backtest")
+			}
+			return nil
+		}),
+		ChartSection(func(ctx *ReportContext) components.Charter {
+			order = append(order, "chart")
+			return charts.NewLine()
+		}),
+	)
+	if err := report.Generate(trader, broker, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(order, ",") != "custom,chart" {
+		t.Errorf("Expected sections to render in order, got %v", order)
+	}
+	if !strings.Contains(out.String(), "Net Profit:") {
+		t.Errorf("Expected the summary to be printed, got %q", out.String())
+	}
+	page, err := os.ReadFile(report.Filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(page), "Test Report") {
+		t.Error("Expected the page to have the report title")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	trader, broker := runTestBacktest(t, &roundTripStrategy{})
+
+	summary := Summarize(trader.Stats(), broker)
+	if !EqualApprox(summary.TotalTraded, 1200) { // 1000 units bought at 1.2
+		t.Errorf("Expected total traded to be 1200, got %f", summary.TotalTraded)
+	}
+	if !EqualApprox(summary.NetProfit, trader.Stats().Dated.Float("Profit", -1)) {
+		t.Errorf("Expected net profit to be the final profit, got %f", summary.NetProfit)
+	}
+	if summary.Timespan != 8*24*time.Hour {
+		t.Errorf("Expected timespan to be 8 days, got %s", summary.Timespan)
+	}
+	if summary.SharpeRatio >= 0 {
+		t.Errorf("Expected a losing round trip to have a negative Sharpe ratio, got %f", summary.SharpeRatio)
+	}
+	if sharpe := sharpeRatio(NewSeries("Equity", 100.0, 101.0, 102.01), 2*24*time.Hour); sharpe != 0 {
+		t.Errorf("Expected returns that do not vary to have no Sharpe ratio, got %f", sharpe)
+	}
+}
+
+func TestHeadlessReport(t *testing.T) {
+	trader, broker := runTestBacktest(t, &roundTripStrategy{})
+
+	var out bytes.Buffer
+	report := NewHeadlessReport("summary.json")
+	report.Out = &out
+	report.Dir = t.TempDir()
+	if err := report.Generate(trader, broker, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(out.String(), "Trades:") {
+		t.Errorf("Expected the summary to be printed, got %q", out.String())
+	}
+	data, err := os.ReadFile(filepath.Join(report.Dir, "summary.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summary BacktestSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		t.Fatal(err)
+	}
+	if summary.Trades != 1 {
+		t.Errorf("Expected 1 trade in the summary JSON, got %d", summary.Trades)
+	}
+	if summary.Candles != testData.Len() {
+		t.Errorf("Expected %d candles in the summary JSON, got %d", testData.Len(), summary.Candles)
+	}
+	if _, err := os.Stat(filepath.Join(report.Dir, "result.json")); err != nil {
+		t.Errorf("Expected the run manifest to be written: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(report.Dir, "backtest.html")); err == nil {
+		t.Error("Expected no charts to be written")
+	}
+}
+
+func TestBacktestConfig(t *testing.T) {
+	trader, broker := runTestBacktest(t, &roundTripStrategy{})
+
+	config := BacktestConfig{Dir: t.TempDir(), Name: "ci", Formats: []OutputFormat{OutputJSON, OutputCSV}, Out: io.Discard}
+	report, err := config.Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := report.Generate(trader, broker, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(config.Dir, "ci.json")); err != nil {
+		t.Errorf("Expected the summary to be written: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(config.Dir, "ci.html")); err == nil {
+		t.Error("Expected no charts to be written")
+	}
+	equity, err := os.ReadFile(filepath.Join(config.Dir, "ci.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(equity)), "\n")
+	if len(lines) != testData.Len()+1 || lines[0] != "Date,Equity,Profit,Drawdown" || !strings.HasPrefix(lines[1], "2022-01-01T00:00:00Z,100000,") {
+		t.Errorf("Expected a header and the equity of every candle, got:\n%s", equity)
+	}
+
+	report, err = BacktestConfig{NoBrowser: true}.Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Filename != "backtest.html" || report.Open || report.Runs == nil {
+		t.Errorf("Expected the page to be written to a new run without opening it, got %q, %t, and %v", report.Filename, report.Open, report.Runs)
+	}
+	if _, err := (BacktestConfig{Formats: []OutputFormat{"pdf"}}).Report(); !errors.Is(err, ErrUnknownOutput) {
+		t.Errorf("Expected ErrUnknownOutput, got %v", err)
+	}
+}
+
+func TestTradesSection(t *testing.T) {
+	trader, broker := runTestBacktest(t, &taggedStrategy{})
+
+	var out bytes.Buffer
+	report := &Report{Out: &out, Sections: []ReportSection{TradesSection}}
+	if err := report.Generate(trader, broker, 0); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(out.String(), "setup=breakout"); lines != 2 {
+		t.Errorf("Expected the entry and exit to be listed with their tags, got %q", out.String())
+	}
+}
+
+func TestCostsSection(t *testing.T) {
+	broker := NewTestBroker(nil, testData, 100_000, 50, 0.01, 0)
+	broker.Slippage = 0
+	broker.Commission = 0.001
+	trader := NewTrader(TraderConfig{
+		Broker:        broker,
+		Strategy:      &roundTripStrategy{},
+		Symbol:        "EUR_USD",
+		Frequency:     "D",
+		CandlesToKeep: 5,
+	})
+	trader.Log.SetOutput(io.Discard)
+	trader.Init()
+	for !trader.EOF {
+		trader.Tick()
+		broker.Advance()
+	}
+
+	costs := NewCostBreakdown(trader.Stats(), Summarize(trader.Stats(), broker).NetProfit)
+	if !EqualApprox(costs.Spread, broker.SpreadCollected()) || !EqualApprox(costs.Commission, broker.CommissionPaid()) {
+		t.Errorf("Expected the spread and commission to match the broker, got %v and %v", costs.Spread, costs.Commission)
+	}
+	if costs.Total() <= 0 || !EqualApprox(costs.GrossProfit-costs.Total(), trader.Stats().Dated.Float("Profit", -1)) {
+		t.Errorf("Expected the gross profit to be the net profit plus %v of costs, got %v", costs.Total(), costs.GrossProfit)
+	}
+
+	lines := cumulativeCosts(trader.Stats().Dated)
+	if len(lines[4]) != trader.Stats().Dated.Len() || lines[4][0].Value != 0.0 || lines[4][len(lines[4])-1].Value != Round(costs.Total(), 2) {
+		t.Errorf("Expected the cumulative total to rise from 0 to %v, got %v", costs.Total(), lines[4])
+	}
+
+	var out bytes.Buffer
+	report := &Report{Out: &out, Sections: []ReportSection{CostsSection}}
+	if err := report.Generate(trader, broker, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Commission:") || !strings.Contains(out.String(), "Total Costs:") {
+		t.Errorf("Expected the costs to be printed, got %q", out.String())
+	}
+}
+
+type recordingStrategy struct {
+	candle int
+}
+
+func (s *recordingStrategy) Init(_ *Trader) {}
+
+func (s *recordingStrategy) Next(t *Trader) {
+	s.candle++
+	t.Record("Close", t.Data().Close(-1))
+	if s.candle >= 3 {
+		t.Record("Trending", s.candle%2 == 0)
+	}
+	t.Record("Equity", 0.0) // Built-in columns cannot be overwritten.
+}
+
+func TestTraderRecord(t *testing.T) {
+	trader, broker := runTestBacktest(t, &recordingStrategy{})
+	stats := trader.Stats()
+
+	if names := strings.Join(stats.Recorded(), ","); names != "Close,Trending" {
+		t.Fatalf("Expected recorded columns Close,Trending, got %s", names)
+	}
+	closes, trending := stats.Dated.Series("Close"), stats.Dated.Series("Trending")
+	if closes.Len() != stats.Dated.Len() || trending.Len() != stats.Dated.Len() {
+		t.Fatalf("Expected recorded columns to have %d rows, got %d and %d", stats.Dated.Len(), closes.Len(), trending.Len())
+	}
+	if closes.Value(0) == nil {
+		t.Error("Expected the first close to be recorded")
+	}
+	if trending.Value(0) != nil || trending.Value(1) != nil {
+		t.Errorf("Expected candles before the first record to be nil, got %v and %v", trending.Value(0), trending.Value(1))
+	}
+	if trending.Value(2) != false || trending.Value(3) != true {
+		t.Errorf("Expected Trending to be false then true, got %v and %v", trending.Value(2), trending.Value(3))
+	}
+	if stats.Dated.Float("Equity", 0) == 0 {
+		t.Error("Expected the Equity column to be unchanged")
+	}
+
+	report := &Report{
+		Filename: filepath.Join(t.TempDir(), "report.html"),
+		Out:      io.Discard,
+		Sections: []ReportSection{RecordedSection},
+	}
+	if err := report.Generate(trader, broker, 0); err != nil {
+		t.Fatal(err)
+	}
+	page, err := os.ReadFile(report.Filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(page), "Trending") {
+		t.Error("Expected the page to chart the recorded values")
+	}
+}
+
+type annotatingStrategy struct {
+	candle int
+}
+
+func (s *annotatingStrategy) Init(_ *Trader) {}
+
+func (s *annotatingStrategy) Next(t *Trader) {
+	s.candle++
+	if s.candle == 3 {
+		t.Annotate("regime change")
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	trader, broker := runTestBacktest(t, &annotatingStrategy{})
+
+	annotations := trader.Stats().Annotations()
+	if len(annotations) != 1 {
+		t.Fatalf("Expected 1 annotation, got %d", len(annotations))
+	}
+	if annotations[0].Name != "regime change" {
+		t.Errorf("Expected annotation named regime change, got %q", annotations[0].Name)
+	}
+	if date := trader.Stats().Dated.Date(2); !annotations[0].Time.Equal(date) {
+		t.Errorf("Expected annotation on %v, got %v", date, annotations[0].Time)
+	}
+
+	report := &Report{
+		Filename: filepath.Join(t.TempDir(), "report.html"),
+		Out:      io.Discard,
+		Sections: []ReportSection{EquitySection},
+	}
+	if err := report.Generate(trader, broker, 0); err != nil {
+		t.Fatal(err)
+	}
+	page, err := os.ReadFile(report.Filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(page), "regime change") {
+		t.Error("Expected the equity chart to show the annotation")
+	}
+}
+
+type exitLevelsStrategy struct {
+	candle int
+}
+
+func (s *exitLevelsStrategy) Init(_ *Trader) {}
+
+func (s *exitLevelsStrategy) Next(t *Trader) {
+	s.candle++
+	if s.candle == 5 {
+		t.Buy(1000, 0.95, 1.35)
+		t.Buy(1000, -0.15, 0)
+	}
+}
+
+func TestExitLevels(t *testing.T) {
+	trader, broker := runTestBacktest(t, &exitLevelsStrategy{})
+	levels := trader.Stats().Dated.Series("Levels")
+
+	// The trailing stop is only set by the broker on the candle after the position opens.
+	first, ok := levels.Value(4).([]PositionLevels)
+	if !ok || len(first) != 1 {
+		t.Fatalf("Expected levels of 1 position on the fifth candle, got %v", levels.Value(4))
+	}
+	if first[0].StopLoss != 0.95 || first[0].TakeProfit != 1.35 {
+		t.Errorf("Expected a stop loss of 0.95 and take profit of 1.35, got %+v", first[0])
+	}
+	if levels.Value(3) != nil {
+		t.Errorf("Expected no levels before the positions opened, got %v", levels.Value(3))
+	}
+	trailing := make(map[float64]bool)
+	levels.ForEach(func(_ int, val any) {
+		candleLevels, _ := val.([]PositionLevels)
+		for _, l := range candleLevels {
+			if l.TrailingStop > 0 {
+				trailing[l.TrailingStop] = true
+			}
+		}
+	})
+	if len(trailing) < 2 {
+		t.Errorf("Expected the trailing stop to move, got levels %v", trailing)
+	}
+
+	report := &Report{
+		Filename: filepath.Join(t.TempDir(), "report.html"),
+		Out:      io.Discard,
+		Sections: []ReportSection{KlineSection},
+	}
+	if err := report.Generate(trader, broker, 0); err != nil {
+		t.Fatal(err)
+	}
+	page, err := os.ReadFile(report.Filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Stop Loss", "Take Profit", "Trailing Stop"} {
+		if !strings.Contains(string(page), name) {
+			t.Errorf("Expected the kline chart to draw the %s", name)
+		}
+	}
+}
+
+func TestTradeConnectors(t *testing.T) {
+	trader, _ := runTestBacktest(t, &exitLevelsStrategy{})
+	stats := trader.Stats()
+
+	kline := newKline(trader.data, stats.Dated, "2006-01-02")
+	addTradeConnectors(kline, stats.Trades(), trader.data, "2006-01-02")
+	var connectors *charts.SingleSeries
+	for i := range kline.MultiSeries {
+		if kline.MultiSeries[i].Name == "Trade Lines" {
+			connectors = &kline.MultiSeries[i]
+		}
+	}
+	if connectors == nil || connectors.MarkLines == nil {
+		t.Fatal("Expected the kline chart to connect entries to exits")
+	}
+	if len(connectors.MarkLines.Data) != 2 {
+		t.Fatalf("Expected 2 connectors, got %d", len(connectors.MarkLines.Data))
+	}
+	data, err := json.Marshal(connectors.MarkLines.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The take profit is a win and the trailing stop below the entry is a loss.
+	if !strings.Contains(string(data), "#1a9850") || !strings.Contains(string(data), "#d73027") {
+		t.Errorf("Expected connectors to be colored by profit and loss, got %s", data)
+	}
+	if !strings.Contains(string(data), `"coord":["2022-01-05",1.15]`) {
+		t.Errorf("Expected connectors to start at the entry, got %s", data)
+	}
+}
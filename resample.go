@@ -0,0 +1,101 @@
+package autotrader
+
+import (
+	"strings"
+	"time"
+)
+
+// Resample combines the candles of data into candles of frequency, like daily candles from hourly data. Each new candle opens with the first candle of its period, closes with the last, and has the highest high, lowest low, and total volume. Periods are aligned to UTC: days start at midnight, weeks start on Monday, and months start on the first day of the month.
+//
+// The last candle may be incomplete if data ends before its period does. Use ResampleClosed to only get candles that have closed.
+func Resample(data *IndexedFrame[UnixTime], frequency string) (*IndexedFrame[UnixTime], error) {
+	if _, err := FrequencyDuration(frequency); err != nil {
+		return nil, err
+	}
+	out := NewDOHLCVIndexedFrame[UnixTime]()
+	var period time.Time
+	var open, high, low, close float64
+	var volume int64
+	for i := 0; i < data.Len(); i++ {
+		date := data.Date(i).Time()
+		start := periodStart(date, frequency)
+		if i == 0 || !start.Equal(period) {
+			if i > 0 {
+				out.PushCandle(UnixTime(period.Unix()), open, high, low, close, volume)
+			}
+			period = start
+			open, high, low, volume = data.Open(i), data.High(i), data.Low(i), 0
+		}
+		high, low, close = Max(high, data.High(i)), Min(low, data.Low(i)), data.Close(i)
+		volume += candleVolume(data, i)
+	}
+	if data.Len() > 0 {
+		out.PushCandle(UnixTime(period.Unix()), open, high, low, close, volume)
+	}
+	return out, nil
+}
+
+// ResampleClosed is like Resample but leaves out the last candle if its period has not ended by the close of the last candle of data, which lasts for dataFrequency. This prevents a strategy from seeing a candle that has not closed yet.
+func ResampleClosed(data *IndexedFrame[UnixTime], dataFrequency, frequency string) (*IndexedFrame[UnixTime], error) {
+	out, err := Resample(data, frequency)
+	if err != nil || out.Len() == 0 {
+		return out, err
+	}
+	dataDuration, err := FrequencyDuration(dataFrequency)
+	if err != nil {
+		return nil, err
+	}
+	last := out.Date(-1).Time()
+	if data.Date(-1).Time().Add(dataDuration).Before(periodEnd(last, frequency)) {
+		return out.CopyRange(0, out.Len()-1), nil
+	}
+	return out, nil
+}
+
+// frameCandle returns the candle at row i of data.
+func frameCandle(data *IndexedFrame[UnixTime], i int) Candle {
+	return Candle{
+		Date:   data.Date(i).Time(),
+		Open:   data.Open(i),
+		High:   data.High(i),
+		Low:    data.Low(i),
+		Close:  data.Close(i),
+		Volume: candleVolume(data, i),
+	}
+}
+
+// candleVolume returns the volume of the candle at row i whether it is stored as an int, int64, or float64.
+func candleVolume(data *IndexedFrame[UnixTime], i int) int64 {
+	switch v := data.Value("Volume", i).(type) {
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	}
+	return 0
+}
+
+// periodStart returns the start of the period of frequency that date is in.
+func periodStart(date time.Time, frequency string) time.Time {
+	date = date.UTC()
+	switch {
+	case frequency == "M":
+		return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case strings.ToUpper(frequency) == "W":
+		day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7) // Go back to Monday.
+	}
+	duration, _ := FrequencyDuration(frequency)
+	return date.Truncate(duration)
+}
+
+// periodEnd returns the end of the period of frequency that starts at start.
+func periodEnd(start time.Time, frequency string) time.Time {
+	if frequency == "M" {
+		return start.AddDate(0, 1, 0)
+	}
+	duration, _ := FrequencyDuration(frequency)
+	return start.Add(duration)
+}
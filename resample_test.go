@@ -0,0 +1,147 @@
+package autotrader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// hourlyTestData returns days of hourly candles starting on Monday, January 3, 2022. The close of each candle is its hour since the start.
+func hourlyTestData(days int) *IndexedFrame[UnixTime] {
+	frame := NewDOHLCVIndexedFrame[UnixTime]()
+	start := time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < days*24; i++ {
+		date := start.Add(time.Duration(i) * time.Hour)
+		frame.PushCandle(UnixTime(date.Unix()), float64(i), float64(i)+0.5, float64(i)-0.5, float64(i)+0.25, 10)
+	}
+	return frame
+}
+
+func TestResample(t *testing.T) {
+	data := hourlyTestData(3)
+	daily, err := Resample(data, "D")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if daily.Len() != 3 {
+		t.Fatalf("Expected 3 daily candles, got %d", daily.Len())
+	}
+	if daily.Open(1) != 24 || daily.High(1) != 47.5 || daily.Low(1) != 23.5 || daily.Close(1) != 47.25 || daily.Value("Volume", 1) != int64(240) {
+		t.Errorf("Expected the second day to be 24, 47.5, 23.5, 47.25, 240, got %v, %v, %v, %v, %v", daily.Open(1), daily.High(1), daily.Low(1), daily.Close(1), daily.Value("Volume", 1))
+	}
+	if date := daily.Date(1).Time().UTC(); !date.Equal(time.Date(2022, 1, 4, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the second day to start on 2022-01-04, got %v", date)
+	}
+
+	weekly, err := Resample(data, "W")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if weekly.Len() != 1 || weekly.Value("Volume", 0) != int64(720) {
+		t.Errorf("Expected 1 weekly candle with a volume of 720, got %d candles", weekly.Len())
+	}
+
+	closed, err := ResampleClosed(data.CopyRange(0, 30), "H1", "D")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if closed.Len() != 1 {
+		t.Errorf("Expected only 1 closed daily candle after 30 hours, got %d", closed.Len())
+	}
+	if _, err := Resample(data, "X"); err == nil {
+		t.Error("Expected an error for an invalid frequency")
+	}
+}
+
+type multiFrequencyStrategy struct {
+	closes []time.Time
+	daily  []int // The number of daily candles available on each hour.
+}
+
+func (s *multiFrequencyStrategy) Init(_ *Trader) {}
+
+func (s *multiFrequencyStrategy) Next(t *Trader) {
+	s.daily = append(s.daily, t.DataOf("D").Len())
+}
+
+func (s *multiFrequencyStrategy) Frequencies() []string {
+	return []string{"D"}
+}
+
+func (s *multiFrequencyStrategy) OnClose(t *Trader, frequency string) {
+	if frequency != "D" {
+		return
+	}
+	s.closes = append(s.closes, t.DataOf("D").Date(-1).Time().UTC())
+}
+
+func TestMultiFrequencyStrategy(t *testing.T) {
+	broker := NewTestBroker(nil, hourlyTestData(3), 100_000, 1, 0, 0)
+	broker.Frequency = "H1"
+	strategy := &multiFrequencyStrategy{}
+	trader := NewTrader(TraderConfig{
+		Broker:        broker,
+		Strategy:      strategy,
+		Symbol:        "EUR_USD",
+		Frequency:     "H1",
+		CandlesToKeep: 10,
+	})
+	trader.Log.SetOutput(io.Discard)
+	trader.Init()
+	for !trader.EOF {
+		trader.Tick()
+		broker.Advance()
+	}
+
+	if len(strategy.closes) != 3 {
+		t.Fatalf("Expected 3 daily closes, got %v", strategy.closes)
+	}
+	for i, date := range strategy.closes {
+		if expected := time.Date(2022, 1, 3+i, 0, 0, 0, 0, time.UTC); !date.Equal(expected) {
+			t.Errorf("Expected daily close %d to be %v, got %v", i, expected, date)
+		}
+	}
+	// The first day closes with the 24th hourly candle.
+	if strategy.daily[22] != 0 || strategy.daily[23] != 1 || strategy.daily[47] != 2 {
+		t.Errorf("Expected daily candles to appear as they close, got %v", strategy.daily)
+	}
+	if daily := trader.DataOf("D"); daily.Close(0) != 23.25 {
+		t.Errorf("Expected the first daily close to be 23.25, got %v", daily.Close(0))
+	}
+}
+
+// failingFrequencyBroker is a TestBroker whose requests for candles of frequency fail while fail is true.
+type failingFrequencyBroker struct {
+	*TestBroker
+	frequency string
+	fail      bool
+}
+
+func (b *failingFrequencyBroker) Candles(ctx context.Context, symbol, frequency string, count int) (*IndexedFrame[UnixTime], error) {
+	if b.fail && frequency == b.frequency {
+		return nil, errors.New("connection reset")
+	}
+	return b.TestBroker.Candles(ctx, symbol, frequency, count)
+}
+
+func TestMultiFrequencyFetchError(t *testing.T) {
+	broker := &failingFrequencyBroker{TestBroker: NewTestBroker(nil, hourlyTestData(3), 100_000, 1, 0, 0), frequency: "D"}
+	broker.Frequency = "H1"
+	strategy := &multiFrequencyStrategy{}
+	trader := NewTrader(TraderConfig{Broker: broker, Strategy: strategy, Symbol: "EUR_USD", Frequency: "H1", CandlesToKeep: 10})
+	trader.Log.SetOutput(io.Discard)
+	trader.Init()
+	for i := 0; i < 30; i++ {
+		broker.fail = i == 28
+		trader.Tick()
+		broker.Advance()
+	}
+	if daily := trader.DataOf("D"); daily == nil || daily.Len() != 1 {
+		t.Fatalf("Expected the daily candles to be kept when a fetch fails, got %v", daily)
+	}
+	if len(strategy.daily) != 30 {
+		t.Errorf("Expected the strategy to run on every candle, got %d", len(strategy.daily))
+	}
+}
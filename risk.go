@@ -0,0 +1,395 @@
+package autotrader
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+var (
+	ErrRiskLimit      = errors.New("order exceeds risk limits")
+	ErrMaxPositions   = errors.New("too many open positions")
+	ErrOrderRateLimit = errors.New("too many orders placed this candle")
+	ErrCooldown       = errors.New("re-entry during cooldown")
+	ErrStopTooClose   = errors.New("stop loss too close to entry")
+)
+
+// RiskManager checks orders against exposure limits before the Trader sends them to the broker. Set it on Trader.Risk to have every order checked.
+//
+// Positions are not treated independently: symbols whose returns are highly correlated over a rolling window, like EUR_USD and GBP_USD, form a cluster and the combined exposure of the cluster is capped. Exposure in a negatively correlated symbol counts toward the cluster in the opposite direction, since it moves against the others.
+type RiskManager struct {
+	// MaxClusterExposure is the maximum combined exposure of a cluster of correlated positions as a fraction of NAV. For example, 2 allows $20,000 of correlated exposure on a $10,000 account. Zero disables the limit.
+	MaxClusterExposure float64
+	// MaxCurrencyExposure is the maximum absolute net exposure to any single currency across all open positions as a fraction of NAV, where a position in a currency pair is long its base currency and short its quote currency. See CurrencyExposure. Zero disables the limit.
+	MaxCurrencyExposure float64
+	// CorrelationThreshold is the absolute correlation at which two symbols are considered to be in the same cluster. The default is 0.8.
+	CorrelationThreshold float64
+	// CorrelationPeriod is the number of candles used to calculate rolling correlations. The default is 50.
+	CorrelationPeriod int
+	// MaxOpenPositions is the maximum number of open positions and pending orders across all symbols. Zero disables the limit.
+	MaxOpenPositions int
+	// MaxOpenPositionsPerSymbol is the maximum number of open positions and pending orders in a single symbol. Zero disables the limit.
+	MaxOpenPositionsPerSymbol int
+	// MaxOrdersPerCandle is the maximum number of orders placed on a single candle across all symbols of the Traders that share the RiskManager. Zero disables the limit.
+	MaxOrdersPerCandle int
+	// MaxOrdersPerCandlePerSymbol is the maximum number of orders placed on a single candle in a single symbol. Zero disables the limit.
+	MaxOrdersPerCandlePerSymbol int
+	// Cooldown is the number of candles after a position closes before another position may be opened in the same symbol and direction. For example, 1 blocks re-entry on the candle the position closed on. Zero disables the limit.
+	Cooldown int
+	// CooldownDuration is how long after a position closes before another position may be opened in the same symbol and direction. Zero disables the limit.
+	CooldownDuration time.Duration
+	// CooldownBothDirections makes the cooldown block entries in either direction, rather than only the direction of the closed position.
+	CooldownBothDirections bool
+	// BreakEven moves the stop loss of every open position of the Trader to break-even once it is in profit, on brokers whose positions implement StopLossModifier. It is checked at the start of every candle.
+	BreakEven BreakEven
+	// MaxHolding closes every open position of the Trader at market once it has been held for too long, measured in candles of the Trader and in time since the position was opened. It is checked at the start of every candle, and the trades are marked with CloseTimeExit.
+	MaxHolding MaxHolding
+	// MinStopDistance rejects orders whose stop loss is so close to the entry that the noise of the spread or of an ordinary candle would trigger it. See CheckStop.
+	MinStopDistance MinStopDistance
+
+	mu          sync.Mutex
+	orderCandle time.Time                 // orderCandle is the date of the candle the orders were counted on.
+	orders      map[string]int            // orders is the number of orders placed on orderCandle by symbol.
+	closes      map[cooldownKey]time.Time // closes is the time the last position of each symbol and direction was closed.
+	timeExits   map[string]bool           // timeExits are the IDs of positions closed because of MaxHolding.
+}
+
+// cooldownKey identifies the symbol and direction of a closed position.
+type cooldownKey struct {
+	symbol string
+	long   bool
+}
+
+// Check returns an error wrapping ErrRiskLimit if placing an order for units of symbol at price would exceed a limit. Limits on the number of positions and orders and on currency exposure also wrap ErrMaxPositions, ErrOrderRateLimit, and ErrCurrencyExposure.
+func (r *RiskManager) Check(t *Trader, symbol string, units, price float64) error {
+	if err := r.checkPositions(t, symbol); err != nil {
+		return err
+	}
+	if err := r.checkOrderRate(t, symbol); err != nil {
+		return err
+	}
+	if err := r.checkCooldown(t, symbol, units); err != nil {
+		return err
+	}
+	if err := r.checkCurrencyExposure(t, symbol, units, price); err != nil {
+		return err
+	}
+	if r.MaxClusterExposure <= 0 {
+		return nil
+	}
+	exposure, err := r.ClusterExposure(t, symbol)
+	if err != nil {
+		return err
+	}
+	exposure = math.Abs(exposure + units*price)
+	if limit := r.MaxClusterExposure * t.Broker.NAV(); exposure > limit {
+		return fmt.Errorf("%w: exposure of $%.2f to the cluster of %s is over the limit of $%.2f", ErrRiskLimit, exposure, symbol, limit)
+	}
+	return nil
+}
+
+// MinStopDistance is the closest a stop loss may be to the entry of an order, in multiples of the current spread and of the average true range of the symbol. The larger of the two applies.
+type MinStopDistance struct {
+	Spreads   float64 // Spreads is the minimum distance in multiples of the spread, like 3. Zero disables the limit.
+	ATRs      float64 // ATRs is the minimum distance in multiples of the ATR of the candles of the symbol, like 0.5. Zero disables the limit.
+	ATRPeriod int     // ATRPeriod is the number of candles of the ATR. The default is 14. The ATR limit is skipped until the Trader has that many candles.
+	WarnOnly  bool    // WarnOnly logs stops that are too close instead of rejecting their orders.
+}
+
+// Distance returns the minimum distance of a stop loss when the spread is spread and the ATR is atr.
+func (m MinStopDistance) Distance(spread, atr float64) float64 {
+	return math.Max(m.Spreads*spread, m.ATRs*atr)
+}
+
+// CheckStop returns an error wrapping ErrRiskLimit and ErrStopTooClose if stopLoss is closer to entry than MinStopDistance allows for symbol. If WarnOnly is set, the stop is logged instead and nil is returned. Orders without a stop loss are not checked. The Trader calls it with Check for every order.
+func (r *RiskManager) CheckStop(t *Trader, symbol string, entry, stopLoss float64) error {
+	m := r.MinStopDistance
+	if stopLoss == 0 || (m.Spreads <= 0 && m.ATRs <= 0) {
+		return nil
+	}
+	spread := t.Broker.Price(symbol, true) - t.Broker.Price(symbol, false)
+	var atr float64
+	if m.ATRs > 0 {
+		period := m.ATRPeriod
+		if period <= 0 {
+			period = 14
+		}
+		if data := t.SymbolData(symbol); data != nil && data.Len() >= period {
+			atr = ATR(data, period).Value(-1)
+		}
+	}
+	minimum := m.Distance(spread, atr)
+	if distance := math.Abs(entry - stopLoss); distance < minimum {
+		err := fmt.Errorf("%w: %w: stop loss of %s at %v is %v from the entry at %v, under the minimum of %v (spread %v, ATR %v)", ErrRiskLimit, ErrStopTooClose, symbol, stopLoss, distance, entry, minimum, spread, atr)
+		if m.WarnOnly {
+			t.Log.Printf("Warning: %v", err)
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// ManageExits closes the open positions in the symbols of t that have reached MaxHolding, and moves the stop losses of the rest to break-even when they reach the trigger of BreakEven. Positions with a trailing stop and positions that do not implement StopLossModifier are left alone by BreakEven. The Trader calls it on every candle before the strategy runs.
+func (r *RiskManager) ManageExits(t *Trader) {
+	r.closeExpired(t)
+	if r.BreakEven.Trigger <= 0 {
+		return
+	}
+	for _, position := range t.Broker.OpenPositions() {
+		modifier, ok := position.(StopLossModifier)
+		if !ok || !t.TradesSymbol(position.Symbol()) || position.Closed() || position.TrailingStop() != 0 {
+			continue
+		}
+		price := t.Broker.Price(position.Symbol(), position.Units() < 0)
+		if stop, ok := r.BreakEven.Stop(position.Units(), position.EntryPrice(), position.StopLoss(), price); ok {
+			if err := modifier.SetStopLoss(stop); err != nil {
+				t.Log.Printf("error moving stop loss of position %s to break-even: %v", position.Id(), err)
+			}
+		}
+	}
+}
+
+// closeExpired closes the open positions in the symbols of t that have been held for longer than MaxHolding.
+func (r *RiskManager) closeExpired(t *Trader) {
+	if r.MaxHolding.Candles <= 0 && r.MaxHolding.Duration <= 0 {
+		return
+	}
+	for _, position := range t.Broker.OpenPositions() {
+		if !t.TradesSymbol(position.Symbol()) || position.Closed() {
+			continue
+		}
+		if !r.MaxHolding.Expired(candlesSince(t, position.Time()), t.Now().Sub(position.Time())) {
+			continue
+		}
+		r.mu.Lock()
+		if r.timeExits == nil {
+			r.timeExits = make(map[string]bool)
+		}
+		r.timeExits[position.Id()] = true // Recorded before closing, since the PositionClosed handler that reads it may run inside Close or on a goroutine of the broker.
+		r.mu.Unlock()
+		if err := position.Close(); err != nil {
+			t.Log.Printf("error closing position %s after its maximum holding period: %v", position.Id(), err)
+		}
+	}
+}
+
+// timeExited returns true once if the position with id was closed because of MaxHolding.
+func (r *RiskManager) timeExited(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.timeExits[id] {
+		return false
+	}
+	delete(r.timeExits, id)
+	return true
+}
+
+// checkPositions returns an error if another position in symbol would exceed MaxOpenPositions or MaxOpenPositionsPerSymbol.
+func (r *RiskManager) checkPositions(t *Trader, symbol string) error {
+	if r.MaxOpenPositions <= 0 && r.MaxOpenPositionsPerSymbol <= 0 {
+		return nil
+	}
+	var total, inSymbol int
+	count := func(s string) {
+		total++
+		if s == symbol {
+			inSymbol++
+		}
+	}
+	for _, position := range t.Broker.OpenPositions() {
+		count(position.Symbol())
+	}
+	for _, order := range t.Broker.OpenOrders() {
+		count(order.Symbol()) // Pending orders become positions once they are filled.
+	}
+	if r.MaxOpenPositions > 0 && total >= r.MaxOpenPositions {
+		return fmt.Errorf("%w: %w: %d open positions and orders is at the limit of %d", ErrRiskLimit, ErrMaxPositions, total, r.MaxOpenPositions)
+	}
+	if r.MaxOpenPositionsPerSymbol > 0 && inSymbol >= r.MaxOpenPositionsPerSymbol {
+		return fmt.Errorf("%w: %w: %d open positions and orders in %s is at the limit of %d", ErrRiskLimit, ErrMaxPositions, inSymbol, symbol, r.MaxOpenPositionsPerSymbol)
+	}
+	return nil
+}
+
+// checkOrderRate returns an error if another order in symbol on the current candle of t would exceed MaxOrdersPerCandle or MaxOrdersPerCandlePerSymbol.
+func (r *RiskManager) checkOrderRate(t *Trader, symbol string) error {
+	if r.MaxOrdersPerCandle <= 0 && r.MaxOrdersPerCandlePerSymbol <= 0 {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	orders := r.ordersOn(currentCandle(t))
+	var total int
+	for _, n := range orders {
+		total += n
+	}
+	if r.MaxOrdersPerCandle > 0 && total >= r.MaxOrdersPerCandle {
+		return fmt.Errorf("%w: %w: %d orders placed this candle is at the limit of %d", ErrRiskLimit, ErrOrderRateLimit, total, r.MaxOrdersPerCandle)
+	}
+	if r.MaxOrdersPerCandlePerSymbol > 0 && orders[symbol] >= r.MaxOrdersPerCandlePerSymbol {
+		return fmt.Errorf("%w: %w: %d orders placed in %s this candle is at the limit of %d", ErrRiskLimit, ErrOrderRateLimit, orders[symbol], symbol, r.MaxOrdersPerCandlePerSymbol)
+	}
+	return nil
+}
+
+// OrderPlaced counts an order placed in symbol on the current candle of t toward the order limits. The Trader calls it after the broker accepts an order.
+func (r *RiskManager) OrderPlaced(t *Trader, symbol string) {
+	r.mu.Lock()
+	r.ordersOn(currentCandle(t))[symbol]++
+	r.mu.Unlock()
+}
+
+// PositionClosed starts the cooldown of the symbol and direction of position. The Trader calls it when the broker closes a position.
+func (r *RiskManager) PositionClosed(t *Trader, position Position) {
+	if r.Cooldown <= 0 && r.CooldownDuration <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closes == nil {
+		r.closes = make(map[cooldownKey]time.Time)
+	}
+	r.closes[cooldownKey{position.Symbol(), position.Units() > 0}] = t.Now()
+}
+
+// checkCooldown returns an error if a position in the same symbol and direction as units was closed within Cooldown candles or CooldownDuration.
+func (r *RiskManager) checkCooldown(t *Trader, symbol string, units float64) error {
+	if r.Cooldown <= 0 && r.CooldownDuration <= 0 {
+		return nil
+	}
+	r.mu.Lock()
+	closed, ok := r.closes[cooldownKey{symbol, units > 0}]
+	if other, found := r.closes[cooldownKey{symbol, units <= 0}]; r.CooldownBothDirections && found && (!ok || other.After(closed)) {
+		closed, ok = other, true
+	}
+	r.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	if r.CooldownDuration > 0 {
+		if elapsed := t.Now().Sub(closed); elapsed < r.CooldownDuration {
+			return fmt.Errorf("%w: %w: a position in %s closed %v ago, the cooldown is %v", ErrRiskLimit, ErrCooldown, symbol, elapsed, r.CooldownDuration)
+		}
+	}
+	if r.Cooldown > 0 {
+		if candles := candlesSince(t, closed); candles < r.Cooldown {
+			return fmt.Errorf("%w: %w: a position in %s closed %d candles ago, the cooldown is %d candles", ErrRiskLimit, ErrCooldown, symbol, candles, r.Cooldown)
+		}
+	}
+	return nil
+}
+
+// candlesSince returns the number of candles of t that opened after date. Candles that are no longer kept by t are not counted.
+func candlesSince(t *Trader, date time.Time) int {
+	if t.data == nil {
+		return 0
+	}
+	var n int
+	for i := t.data.Len() - 1; i >= 0 && t.data.Date(i).Time().After(date); i-- {
+		n++
+	}
+	return n
+}
+
+// ordersOn returns the order counts of the candle at date, resetting them when a new candle starts.
+func (r *RiskManager) ordersOn(date time.Time) map[string]int {
+	if r.orders == nil || !date.Equal(r.orderCandle) {
+		r.orders = make(map[string]int)
+		r.orderCandle = date
+	}
+	return r.orders
+}
+
+// currentCandle returns the date of the latest candle of t, or the time of t if it has no candles yet.
+func currentCandle(t *Trader) time.Time {
+	if t.data == nil || t.data.Len() == 0 {
+		return t.Now()
+	}
+	return t.data.Date(-1).Time()
+}
+
+// ClusterExposure returns the signed value of the open positions that are correlated with symbol, including positions in symbol itself. Long exposure is positive and short exposure is negative, relative to the direction of symbol.
+func (r *RiskManager) ClusterExposure(t *Trader, symbol string) (float64, error) {
+	threshold := r.CorrelationThreshold
+	if threshold <= 0 {
+		threshold = 0.8
+	}
+	correlations := map[string]float64{symbol: 1}
+	var exposure float64
+	for _, position := range t.Broker.OpenPositions() {
+		corr, ok := correlations[position.Symbol()]
+		if !ok {
+			var err error
+			if corr, err = r.Correlation(t, symbol, position.Symbol()); err != nil {
+				return 0, err
+			}
+			correlations[position.Symbol()] = corr
+		}
+		if math.Abs(corr) < threshold {
+			continue
+		}
+		exposure += math.Copysign(1, corr) * position.Value()
+	}
+	return exposure, nil
+}
+
+// Correlation returns the correlation of the close to close returns of symbols a and b over the last CorrelationPeriod candles of the Trader's frequency. Only candles that both symbols have are compared.
+func (r *RiskManager) Correlation(t *Trader, a, b string) (float64, error) {
+	if a == b {
+		return 1, nil
+	}
+	period := r.CorrelationPeriod
+	if period <= 0 {
+		period = 50
+	}
+	candlesA, err := t.Broker.Candles(t.Context(), a, t.Frequency, period+1)
+	if err != nil && err != ErrEOF {
+		return 0, err
+	}
+	candlesB, err := t.Broker.Candles(t.Context(), b, t.Frequency, period+1)
+	if err != nil && err != ErrEOF {
+		return 0, err
+	}
+
+	var returnsA, returnsB []float64
+	for i := 1; i < candlesA.Len(); i++ {
+		rowB := candlesB.Closes().Row(*candlesA.Index(i))
+		if rowB < 1 {
+			continue
+		}
+		if prevA, prevB := candlesA.Index(i-1), candlesB.Index(rowB-1); *prevA != *prevB {
+			continue // Both symbols need the current and previous candles.
+		}
+		returnsA = append(returnsA, candlesA.Close(i)/candlesA.Close(i-1)-1)
+		returnsB = append(returnsB, candlesB.Close(rowB)/candlesB.Close(rowB-1)-1)
+	}
+	return Correlation(returnsA, returnsB), nil
+}
+
+// Correlation returns the Pearson correlation coefficient of a and b, which is between -1 and 1. Zero is returned if either slice has no variance or the slices have different lengths.
+func Correlation(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) < 2 {
+		return 0
+	}
+	var meanA, meanB float64
+	for i := range a {
+		meanA += a[i]
+		meanB += b[i]
+	}
+	meanA /= float64(len(a))
+	meanB /= float64(len(b))
+	var cov, varA, varB float64
+	for i := range a {
+		da, db := a[i]-meanA, b[i]-meanB
+		cov += da * db
+		varA += da * da
+		varB += db * db
+	}
+	if varA == 0 || varB == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varA*varB)
+}
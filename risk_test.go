@@ -0,0 +1,291 @@
+package autotrader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// multiSymbolBroker is a TestBroker that returns different candles for each symbol.
+type multiSymbolBroker struct {
+	*TestBroker
+	candles map[string]*IndexedFrame[UnixTime]
+}
+
+func (b *multiSymbolBroker) Candles(ctx context.Context, symbol, frequency string, count int) (*IndexedFrame[UnixTime], error) {
+	if candles, ok := b.candles[symbol]; ok {
+		return candles.CopyRange(-count, -1), nil
+	}
+	return nil, ErrSymbolNotFound
+}
+
+func TestCorrelation(t *testing.T) {
+	a := []float64{1, 2, 3, 4}
+	if c := Correlation(a, []float64{2, 4, 6, 8}); !EqualApprox(c, 1) {
+		t.Errorf("Expected a correlation of 1, got %f", c)
+	}
+	if c := Correlation(a, []float64{4, 3, 2, 1}); !EqualApprox(c, -1) {
+		t.Errorf("Expected a correlation of -1, got %f", c)
+	}
+	if c := Correlation(a, []float64{1, 1, 1, 1}); c != 0 {
+		t.Errorf("Expected a correlation of 0 without variance, got %f", c)
+	}
+}
+
+func TestRiskManagerCorrelatedClusters(t *testing.T) {
+	// B moves exactly with A and C moves exactly against A.
+	b := NewDOHLCVIndexedFrame[UnixTime]()
+	c := NewDOHLCVIndexedFrame[UnixTime]()
+	closeC := 1.0
+	for i := 0; i < testData.Len(); i++ {
+		date := *testData.Index(i)
+		if i > 0 {
+			closeC *= 1 - (testData.Close(i)/testData.Close(i-1) - 1)
+		}
+		b.PushCandle(date, 0, 0, 0, 2*testData.Close(i), 0)
+		c.PushCandle(date, 0, 0, 0, closeC, 0)
+	}
+	testBroker := NewTestBroker(nil, testData, 100_000, 50, 0, testData.Len())
+	testBroker.Slippage = 0
+	broker := &multiSymbolBroker{testBroker, map[string]*IndexedFrame[UnixTime]{"A": testData, "B": b, "C": c}}
+
+	risk := &RiskManager{MaxClusterExposure: 1, CorrelationPeriod: 8}
+	trader := NewTrader(TraderConfig{Broker: broker, Frequency: "D", Risk: risk})
+	trader.Log.SetOutput(io.Discard)
+
+	if _, err := broker.Order(context.Background(), Market, "A", 50_000, 0, 0, 0); err != nil { // $65,000 long A
+		t.Fatal(err)
+	}
+	if corr, _ := risk.Correlation(trader, "A", "C"); !EqualApprox(corr, -1) {
+		t.Errorf("Expected A and C to have a correlation of -1, got %f", corr)
+	}
+
+	trader.Symbol = "B"
+	if _, err := trader.Buy(30_000, 0, 0); !errors.Is(err, ErrRiskLimit) { // $65,000 + $39,000 is over the $100,000 limit.
+		t.Errorf("Expected buying the correlated B to exceed the limit, got %v", err)
+	}
+	trader.Symbol = "C"
+	if _, err := trader.Sell(30_000, 0, 0); !errors.Is(err, ErrRiskLimit) { // Shorting C is the same as buying A.
+		t.Errorf("Expected selling the anti-correlated C to exceed the limit, got %v", err)
+	}
+	if _, err := trader.Buy(30_000, 0, 0); err != nil { // Buying C hedges A.
+		t.Errorf("Expected buying the anti-correlated C to be allowed, got %v", err)
+	}
+}
+
+// burstStrategy tries to place several orders on every candle and keeps the errors.
+type burstStrategy struct {
+	orders int
+	errs   [][]error
+}
+
+func (s *burstStrategy) Init(_ *Trader) {}
+
+func (s *burstStrategy) Next(t *Trader) {
+	var errs []error
+	for i := 0; i < s.orders; i++ {
+		_, err := t.Buy(1000, 0, 0)
+		errs = append(errs, err)
+	}
+	s.errs = append(s.errs, errs)
+}
+
+func TestRiskManagerOrderLimits(t *testing.T) {
+	strategy := &burstStrategy{orders: 3}
+	broker := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	trader := NewTrader(TraderConfig{
+		Broker:        broker,
+		Strategy:      strategy,
+		Symbol:        "EUR_USD",
+		Frequency:     "D",
+		CandlesToKeep: 5,
+		Risk:          &RiskManager{MaxOrdersPerCandlePerSymbol: 2, MaxOpenPositions: 3},
+	})
+	trader.Log.SetOutput(io.Discard)
+	trader.Init()
+	for i := 0; i < 2; i++ {
+		trader.Tick()
+		broker.Advance()
+	}
+
+	first, second := strategy.errs[0], strategy.errs[1]
+	if first[0] != nil || first[1] != nil {
+		t.Fatalf("Expected the first 2 orders to be placed, got %v", first)
+	}
+	if !errors.Is(first[2], ErrOrderRateLimit) || !errors.Is(first[2], ErrRiskLimit) {
+		t.Errorf("Expected the third order of a candle to fail with ErrOrderRateLimit, got %v", first[2])
+	}
+	if second[0] != nil {
+		t.Errorf("Expected the order limit to reset on the next candle, got %v", second[0])
+	}
+	if !errors.Is(second[1], ErrMaxPositions) {
+		t.Errorf("Expected the fourth position to fail with ErrMaxPositions, got %v", second[1])
+	}
+	if n := len(broker.OpenPositions()); n != 3 {
+		t.Errorf("Expected 3 open positions, got %d", n)
+	}
+}
+
+// scriptedStrategy runs the action of each candle by its number, starting at 1.
+type scriptedStrategy struct {
+	candle  int
+	actions map[int]func(t *Trader)
+}
+
+func (s *scriptedStrategy) Init(_ *Trader) {}
+
+func (s *scriptedStrategy) Next(t *Trader) {
+	s.candle++
+	if action, ok := s.actions[s.candle]; ok {
+		action(t)
+	}
+}
+
+func TestRiskManagerCooldown(t *testing.T) {
+	errs := make(map[string]error)
+	order := func(name string, units float64) func(t *Trader) {
+		return func(t *Trader) {
+			_, errs[name] = t.Order(Market, units, 0, 0, 0)
+		}
+	}
+	strategy := &scriptedStrategy{actions: map[int]func(t *Trader){
+		1: order("entry", 1000),
+		2: func(t *Trader) {
+			t.CloseOrdersAndPositions()
+			order("same candle", 1000)(t)
+			order("short", -1000)(t)
+		},
+		3: order("next candle", 1000),
+		4: order("after cooldown", 1000),
+	}}
+	risk := &RiskManager{Cooldown: 2}
+	broker := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	trader := NewTrader(TraderConfig{Broker: broker, Strategy: strategy, Symbol: "EUR_USD", Frequency: "D", CandlesToKeep: 5, Risk: risk})
+	trader.Log.SetOutput(io.Discard)
+	trader.Init()
+	for i := 0; i < 4; i++ {
+		trader.Tick()
+		broker.Advance()
+	}
+
+	if errs["entry"] != nil || errs["short"] != nil || errs["after cooldown"] != nil {
+		t.Errorf("Expected entries outside of the cooldown to be placed, got %v", errs)
+	}
+	for _, name := range []string{"same candle", "next candle"} {
+		if !errors.Is(errs[name], ErrCooldown) {
+			t.Errorf("Expected the %s entry to fail with ErrCooldown, got %v", name, errs[name])
+		}
+	}
+
+	// Blocking both directions stops the short, which was closed most recently.
+	risk.CooldownBothDirections = true
+	risk.CooldownDuration = 48 * time.Hour
+	trader.CloseOrdersAndPositions()
+	if _, err := trader.Buy(1000, 0, 0); !errors.Is(err, ErrCooldown) {
+		t.Errorf("Expected a long entry to fail with ErrCooldown after a short closed, got %v", err)
+	}
+}
+
+func TestRiskManagerMinStopDistance(t *testing.T) {
+	errs := make(map[string]error)
+	var minimum float64
+	risk := &RiskManager{MinStopDistance: MinStopDistance{Spreads: 3, ATRs: 1, ATRPeriod: 3}}
+	order := func(t *Trader, name string, distance float64) {
+		price := t.Broker.Price("EUR_USD", true)
+		_, errs[name] = t.Buy(1000, price-distance, 0)
+	}
+	strategy := &scriptedStrategy{actions: map[int]func(t *Trader){
+		1: func(t *Trader) {
+			order(t, "within spreads", 0.02) // The ATR is not used before there are 3 candles.
+			order(t, "beyond spreads", 0.05)
+		},
+		4: func(t *Trader) {
+			minimum = risk.MinStopDistance.Distance(0.01, ATR(t.Data(), 3).Value(-1))
+			order(t, "within ATR", minimum-0.001)
+			order(t, "beyond ATR", minimum+0.001)
+			_, errs["no stop"] = t.Buy(1000, 0, 0)
+			risk.MinStopDistance.WarnOnly = true
+			order(t, "warned", minimum-0.001)
+		},
+	}}
+	broker := NewTestBroker(nil, testData, 100_000, 50, 0.01, 0)
+	trader := NewTrader(TraderConfig{Broker: broker, Strategy: strategy, Symbol: "EUR_USD", Frequency: "D", CandlesToKeep: 5, Risk: risk})
+	trader.Log.SetOutput(io.Discard)
+	trader.Init()
+	for i := 0; i < 4; i++ {
+		trader.Tick()
+		broker.Advance()
+	}
+
+	if minimum <= 0.03 {
+		t.Fatalf("Expected the ATR to be wider than 3 spreads, got a minimum of %v", minimum)
+	}
+	for _, name := range []string{"within spreads", "within ATR"} {
+		if !errors.Is(errs[name], ErrStopTooClose) || !errors.Is(errs[name], ErrRiskLimit) {
+			t.Errorf("Expected the order %s to fail with ErrStopTooClose, got %v", name, errs[name])
+		}
+	}
+	for _, name := range []string{"beyond spreads", "beyond ATR", "no stop", "warned"} {
+		if errs[name] != nil {
+			t.Errorf("Expected the order %s to be placed, got %v", name, errs[name])
+		}
+	}
+}
+
+func TestRiskManagerBreakEven(t *testing.T) {
+	strategy := &scriptedStrategy{actions: map[int]func(t *Trader){
+		1: func(t *Trader) { t.Buy(1000, 1.0, 0) },
+	}}
+	broker := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	broker.Slippage = 0
+	trader := NewTrader(TraderConfig{Broker: broker, Strategy: strategy, Symbol: "EUR_USD", Frequency: "D", CandlesToKeep: 5, Risk: &RiskManager{BreakEven: BreakEven{Trigger: 0.04}}})
+	trader.Log.SetOutput(io.Discard)
+	trader.Init()
+	for i := 0; i < 3; i++ {
+		trader.Tick()
+		broker.Advance()
+	}
+
+	// The close of 1.2 on the second candle triggers the break-even stop at the entry of 1.15, which the low of the third candle touches.
+	position := broker.Positions()[0]
+	if !EqualApprox(position.StopLoss(), 1.15) {
+		t.Errorf("Expected the stop loss to be moved to the entry of 1.15, got %f", position.StopLoss())
+	}
+	if !position.Closed() || position.CloseType() != CloseStopLoss || !EqualApprox(position.ClosePrice(), 1.15) {
+		t.Errorf("Expected the position to be closed at break-even, got %v at %f", position.CloseType(), position.ClosePrice())
+	}
+}
+
+func TestRiskManagerMaxHolding(t *testing.T) {
+	strategy := &scriptedStrategy{actions: map[int]func(t *Trader){
+		1: func(t *Trader) { t.Buy(1000, 0, 0) },
+	}}
+	broker := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	broker.Slippage = 0
+	trader := NewTrader(TraderConfig{Broker: broker, Strategy: strategy, Symbol: "EUR_USD", Frequency: "D", CandlesToKeep: 5, Risk: &RiskManager{MaxHolding: MaxHolding{Candles: 2}}})
+	trader.Log.SetOutput(io.Discard)
+	trader.Init()
+	for i := 0; i < 5; i++ {
+		trader.Tick()
+		broker.Advance()
+	}
+
+	position := broker.Positions()[0]
+	if !position.Closed() {
+		t.Fatal("Expected the position to be closed after its maximum holding period")
+	}
+	var exits int
+	for _, trade := range trader.Stats().Trades() {
+		if trade.Exit {
+			exits++
+			if trade.CloseType != CloseTimeExit {
+				t.Errorf("Expected the exit to be marked %v, got %v", CloseTimeExit, trade.CloseType)
+			}
+		}
+	}
+	if exits != 1 {
+		t.Errorf("Expected 1 exit trade, got %d", exits)
+	}
+}
@@ -0,0 +1,208 @@
+package autotrader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var _ Broker = (*RouterBroker)(nil) // Compile-time interface check.
+
+// routedSignals are the signals of the routed brokers that a RouterBroker emits as its own.
+var routedSignals = []string{OrderPlaced, OrderCancelled, OrderExpired, OrderFulfilled, PositionClosed, PositionModified, CandleOpened, CandleClosed}
+
+// RouterBroker is a Broker that routes each symbol to the broker of the account that trades it, like forex to one broker and crypto to another, so a single Trader or several Traders can work across accounts. Orders, prices, and candles of a symbol go to its route, or to the default broker if it has none. The orders and positions of every account are combined, and NAV and PL are the sums of the accounts, so the stats and reports of a Trader cover all of them. The accounts are assumed to be in the same currency.
+//
+// To route by strategy instead, give each Trader its own broker and give a RouterBroker of every broker to whatever needs the consolidated NAV and PL.
+//
+// The signals of every routed broker are emitted by the RouterBroker as well.
+type RouterBroker struct {
+	SignalManager
+	Default Broker // Default is the broker of symbols without a route. If nil, they fail with ErrSymbolNotFound.
+
+	routes  map[string]Broker
+	brokers []Broker // brokers are the distinct brokers in the order they were added.
+}
+
+// NewRouterBroker returns a RouterBroker that routes symbols by routes and everything else to defaultBroker, which may be nil.
+func NewRouterBroker(defaultBroker Broker, routes map[string]Broker) *RouterBroker {
+	b := &RouterBroker{routes: make(map[string]Broker, len(routes))}
+	if defaultBroker != nil {
+		b.Default = defaultBroker
+		b.connect(defaultBroker)
+	}
+	for symbol, broker := range routes {
+		b.Route(symbol, broker)
+	}
+	return b
+}
+
+// Route sends the orders, prices, and candles of symbol to broker.
+func (b *RouterBroker) Route(symbol string, broker Broker) {
+	if b.routes == nil {
+		b.routes = make(map[string]Broker)
+	}
+	b.routes[symbol] = broker
+	b.connect(broker)
+}
+
+// Brokers returns every distinct broker of the RouterBroker, starting with the default broker.
+func (b *RouterBroker) Brokers() []Broker {
+	brokers := make([]Broker, len(b.brokers))
+	copy(brokers, b.brokers)
+	return brokers
+}
+
+// BrokerOf returns the broker that symbol is routed to, or nil if there is none.
+func (b *RouterBroker) BrokerOf(symbol string) Broker {
+	if broker, ok := b.routes[symbol]; ok {
+		return broker
+	}
+	return b.Default
+}
+
+// connect forwards the signals of broker the first time it is added.
+func (b *RouterBroker) connect(broker Broker) {
+	for _, existing := range b.brokers {
+		if existing == broker {
+			return
+		}
+	}
+	b.brokers = append(b.brokers, broker)
+	for _, signal := range routedSignals {
+		signal := signal
+		broker.SignalConnect(signal, b, func(args ...any) {
+			b.SignalEmit(signal, args...)
+		})
+	}
+}
+
+// Now returns the time of the default broker, or of the first broker if there is no default, so a RouterBroker of TestBrokers follows the simulated time.
+func (b *RouterBroker) Now() time.Time {
+	if b.Default != nil {
+		return brokerNow(b.Default)
+	} else if len(b.brokers) > 0 {
+		return brokerNow(b.brokers[0])
+	}
+	return time.Now()
+}
+
+func (b *RouterBroker) Price(symbol string, wantToBuy bool) float64 {
+	if broker := b.BrokerOf(symbol); broker != nil {
+		return broker.Price(symbol, wantToBuy)
+	}
+	return 0
+}
+
+func (b *RouterBroker) Bid(symbol string) float64 {
+	return b.Price(symbol, false)
+}
+
+func (b *RouterBroker) Ask(symbol string) float64 {
+	return b.Price(symbol, true)
+}
+
+func (b *RouterBroker) Candles(ctx context.Context, symbol, frequency string, count int) (*IndexedFrame[UnixTime], error) {
+	broker := b.BrokerOf(symbol)
+	if broker == nil {
+		return nil, fmt.Errorf("%w: %s has no route", ErrSymbolNotFound, symbol)
+	}
+	return broker.Candles(ctx, symbol, frequency, count)
+}
+
+func (b *RouterBroker) Order(ctx context.Context, orderType OrderType, symbol string, units, price, stopLoss, takeProfit float64, options ...OrderOption) (Order, error) {
+	broker := b.BrokerOf(symbol)
+	if broker == nil {
+		return nil, &OrderError{Err: ErrSymbolNotFound, OrderType: orderType, Symbol: symbol, Units: units, Price: price, Reason: "no route"}
+	}
+	return broker.Order(ctx, orderType, symbol, units, price, stopLoss, takeProfit, options...)
+}
+
+// NAV returns the sum of the net asset values of the accounts.
+func (b *RouterBroker) NAV() float64 {
+	var nav float64
+	for _, broker := range b.brokers {
+		nav += broker.NAV()
+	}
+	return nav
+}
+
+// PL returns the sum of the profits and losses of the accounts.
+func (b *RouterBroker) PL() float64 {
+	var pl float64
+	for _, broker := range b.brokers {
+		pl += broker.PL()
+	}
+	return pl
+}
+
+func (b *RouterBroker) OpenOrders() []Order {
+	return b.orders(Broker.OpenOrders)
+}
+
+func (b *RouterBroker) OpenPositions() []Position {
+	return b.positions(Broker.OpenPositions)
+}
+
+func (b *RouterBroker) Orders() []Order {
+	return b.orders(Broker.Orders)
+}
+
+func (b *RouterBroker) Positions() []Position {
+	return b.positions(Broker.Positions)
+}
+
+func (b *RouterBroker) orders(list func(Broker) []Order) []Order {
+	var orders []Order
+	for _, broker := range b.brokers {
+		orders = append(orders, list(broker)...)
+	}
+	return orders
+}
+
+func (b *RouterBroker) positions(list func(Broker) []Position) []Position {
+	var positions []Position
+	for _, broker := range b.brokers {
+		positions = append(positions, list(broker)...)
+	}
+	return positions
+}
+
+// OrderByID returns the order with id from the first account that has it. IDs are assumed to be unique across the accounts.
+func (b *RouterBroker) OrderByID(ctx context.Context, id string) (Order, error) {
+	for _, broker := range b.brokers {
+		order, err := broker.OrderByID(ctx, id)
+		if err == nil {
+			return order, nil
+		} else if !errors.Is(err, ErrOrderNotFound) {
+			return nil, err
+		}
+	}
+	return nil, ErrOrderNotFound
+}
+
+// PositionByID returns the position with id from the first account that has it, like OrderByID.
+func (b *RouterBroker) PositionByID(ctx context.Context, id string) (Position, error) {
+	for _, broker := range b.brokers {
+		position, err := broker.PositionByID(ctx, id)
+		if err == nil {
+			return position, nil
+		} else if !errors.Is(err, ErrPositionNotFound) {
+			return nil, err
+		}
+	}
+	return nil, ErrPositionNotFound
+}
+
+// Capabilities returns the capabilities shared by every broker of the router. Use the Capabilities of BrokerOf for what a single symbol supports.
+func (b *RouterBroker) Capabilities() Capabilities {
+	if len(b.brokers) == 0 {
+		return Capabilities{}
+	}
+	capabilities := b.brokers[0].Capabilities()
+	for _, broker := range b.brokers[1:] {
+		capabilities = capabilities.Intersect(broker.Capabilities())
+	}
+	return capabilities
+}
@@ -0,0 +1,86 @@
+package autotrader
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRouterBroker(t *testing.T) {
+	forex := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	crypto := NewTestBroker(nil, testData, 50_000, 2, 0, 0)
+	forex.Slippage, crypto.Slippage = 0, 0
+	crypto.Symbols = []string{"BTC_USD"}
+	router := NewRouterBroker(forex, map[string]Broker{"BTC_USD": crypto})
+
+	var placed int
+	router.SignalConnect(OrderPlaced, t, func(...any) { placed++ })
+	if _, err := router.Order(context.Background(), Market, "EUR_USD", 1000, 0, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := router.Order(context.Background(), Market, "BTC_USD", 1, 0, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(forex.OpenPositions()) != 1 || len(crypto.OpenPositions()) != 1 || crypto.OpenPositions()[0].Symbol() != "BTC_USD" {
+		t.Errorf("Expected one position in each account, got %d and %d", len(forex.OpenPositions()), len(crypto.OpenPositions()))
+	}
+	if placed != 2 {
+		t.Errorf("Expected 2 forwarded OrderPlaced signals, got %d", placed)
+	}
+	if positions := router.OpenPositions(); len(positions) != 2 {
+		t.Errorf("Expected 2 open positions across the accounts, got %d", len(positions))
+	}
+	if nav := router.NAV(); !EqualApprox(nav, forex.NAV()+crypto.NAV()) {
+		t.Errorf("Expected the NAV to be the sum of the accounts, got %f", nav)
+	}
+	if router.BrokerOf("BTC_USD") != Broker(crypto) || len(router.Brokers()) != 2 {
+		t.Errorf("Expected BTC_USD to be routed to the crypto account among 2 brokers, got %d brokers", len(router.Brokers()))
+	}
+
+	unrouted := NewRouterBroker(nil, map[string]Broker{"BTC_USD": crypto})
+	if _, err := unrouted.Order(context.Background(), Market, "EUR_USD", 1000, 0, 0, 0); !errors.Is(err, ErrSymbolNotFound) {
+		t.Errorf("Expected ErrSymbolNotFound without a route, got %v", err)
+	}
+	if _, err := unrouted.PositionByID(context.Background(), "missing"); !errors.Is(err, ErrPositionNotFound) {
+		t.Errorf("Expected ErrPositionNotFound, got %v", err)
+	}
+}
+
+// limitedBroker is a TestBroker that reports other capabilities.
+type limitedBroker struct {
+	*TestBroker
+	capabilities Capabilities
+}
+
+func (b *limitedBroker) Capabilities() Capabilities {
+	return b.capabilities
+}
+
+func TestRouterBrokerCapabilities(t *testing.T) {
+	forex := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	forex.Frequency = "M1"
+	stocks := &limitedBroker{NewTestBroker(nil, testData, 50_000, 2, 0, 0), Capabilities{
+		OrderTypes:    []OrderType{Market, Limit},
+		StopLoss:      true,
+		TrailingStops: []TrailingStopMode{TrailingPercent},
+		MinFrequency:  "M5",
+	}}
+	router := NewRouterBroker(forex, map[string]Broker{"AAPL": stocks})
+
+	capabilities := router.Capabilities()
+	if !capabilities.SupportsOrderType(Limit) || capabilities.SupportsOrderType(Stop) || len(capabilities.OrderTypes) != 2 {
+		t.Errorf("Expected market and limit orders, got %v", capabilities.OrderTypes)
+	}
+	if !capabilities.SupportsTrailingStop(TrailingPercent) || capabilities.SupportsTrailingStop(TrailingATR) {
+		t.Errorf("Expected only percent trailing stops, got %v", capabilities.TrailingStops)
+	}
+	if !capabilities.StopLoss || capabilities.TakeProfit || capabilities.Hedging || capabilities.Streaming {
+		t.Errorf("Expected only stop losses to be supported by both brokers, got %+v", capabilities)
+	}
+	if capabilities.MinFrequency != "M5" {
+		t.Errorf("Expected the coarser minimum frequency M5, got %q", capabilities.MinFrequency)
+	}
+	if !router.BrokerOf("EUR_USD").Capabilities().SupportsOrderType(Stop) {
+		t.Error("Expected the forex broker to support stop orders")
+	}
+}
@@ -0,0 +1,165 @@
+package autotrader
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+// runTimeLayout is the layout of the timestamp in the name of a run directory. It sorts in chronological order.
+const runTimeLayout = "20060102-150405"
+
+// RunArchive keeps the outputs of each backtest in a directory of its own named "<strategy>-<timestamp>" inside Root, rather than overwriting the outputs of the previous run. A symlink named Latest always points to the newest run, and old runs are deleted according to Keep and MaxAge when a new run is created.
+type RunArchive struct {
+	Root   string        // Root is the directory that holds the runs. The default is "runs".
+	Keep   int           // Keep is the number of most recent runs of each strategy to keep. Older runs are deleted when a new run is created. Zero keeps every run.
+	MaxAge time.Duration // MaxAge is how long runs are kept. Older runs are deleted when a new run is created. Zero keeps runs of any age.
+	Latest string        // Latest is the name of the symlink in Root to the newest run. The default is "latest".
+
+	now func() time.Time
+}
+
+// Run is a directory of a single backtest run in a RunArchive.
+type Run struct {
+	Dir      string    // Dir is the path of the directory of the run.
+	Strategy string    // Strategy is the name of the strategy that was backtested.
+	Time     time.Time // Time is when the run was created.
+}
+
+// Create makes a new directory for a run of strategy, points the latest symlink to it, and deletes old runs. A suffix is added to the name if a run of strategy was already created in the same second.
+func (a *RunArchive) Create(strategy string) (string, error) {
+	now := a.clock()
+	name := fmt.Sprintf("%s-%s", runStrategyName(strategy), now.Format(runTimeLayout))
+	dir := filepath.Join(a.root(), name)
+	for i := 2; ; i++ {
+		err := os.MkdirAll(filepath.Dir(dir), 0755)
+		if err == nil {
+			err = os.Mkdir(dir, 0755)
+		}
+		if err == nil {
+			break
+		} else if !os.IsExist(err) {
+			return "", err
+		}
+		dir = filepath.Join(a.root(), fmt.Sprintf("%s-%d", name, i))
+	}
+
+	if err := a.link(dir); err != nil {
+		log.Printf("Could not link the latest run: %v\n", err) // Symlinks are not always permitted, like on Windows without privileges.
+	}
+	if err := a.Cleanup(strategy); err != nil {
+		return dir, err
+	}
+	return dir, nil
+}
+
+// Runs returns the runs of strategy from oldest to newest. If strategy is empty, the runs of every strategy are returned.
+func (a *RunArchive) Runs(strategy string) ([]Run, error) {
+	entries, err := os.ReadDir(a.root())
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var runs []Run
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue // Skips the latest symlink and any other files.
+		}
+		run, ok := parseRun(entry.Name())
+		if !ok || strategy != "" && run.Strategy != runStrategyName(strategy) {
+			continue
+		}
+		run.Dir = filepath.Join(a.root(), entry.Name())
+		runs = append(runs, run)
+	}
+	slices.SortStableFunc(runs, func(a, b Run) bool {
+		return a.Time.Before(b.Time)
+	})
+	return runs, nil
+}
+
+// Cleanup deletes the runs of strategy that are older than MaxAge or beyond the Keep most recent runs. The newest run is never deleted.
+func (a *RunArchive) Cleanup(strategy string) error {
+	runs, err := a.Runs(strategy)
+	if err != nil || len(runs) == 0 {
+		return err
+	}
+	var errs []error
+	now := a.clock()
+	for i, run := range runs[:len(runs)-1] {
+		expired := a.MaxAge > 0 && now.Sub(run.Time) > a.MaxAge
+		excess := a.Keep > 0 && i < len(runs)-a.Keep
+		if expired || excess {
+			errs = append(errs, os.RemoveAll(run.Dir))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// link points the latest symlink to dir, replacing the previous link.
+func (a *RunArchive) link(dir string) error {
+	latest := a.Latest
+	if latest == "" {
+		latest = "latest"
+	}
+	path := filepath.Join(a.root(), latest)
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSymlink == 0 {
+			return fmt.Errorf("%s exists and is not a symlink", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	return os.Symlink(filepath.Base(dir), path) // Relative to Root, so the archive can be moved.
+}
+
+func (a *RunArchive) root() string {
+	if a.Root == "" {
+		return "runs"
+	}
+	return a.Root
+}
+
+func (a *RunArchive) clock() time.Time {
+	if a.now != nil {
+		return a.now()
+	}
+	return time.Now()
+}
+
+var runNamePattern = regexp.MustCompile(`^(.+)-(\d{8}-\d{6})(?:-\d+)?$`)
+
+// parseRun parses the strategy and time from the name of a run directory.
+func parseRun(name string) (Run, bool) {
+	match := runNamePattern.FindStringSubmatch(name)
+	if match == nil {
+		return Run{}, false
+	}
+	t, err := time.ParseInLocation(runTimeLayout, match[2], time.Local)
+	if err != nil {
+		return Run{}, false
+	}
+	return Run{Strategy: match[1], Time: t}, true
+}
+
+// runStrategyName makes a strategy name safe to use in a directory name.
+func runStrategyName(strategy string) string {
+	if strategy == "" {
+		return "run"
+	}
+	return strings.Map(func(r rune) rune {
+		if r == filepath.Separator || r == '/' || r == ' ' || r == '*' || r == '.' {
+			return '_'
+		}
+		return r
+	}, strategy)
+}
@@ -0,0 +1,116 @@
+package autotrader
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunArchive(t *testing.T) {
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.Local)
+	archive := &RunArchive{Root: t.TempDir(), Keep: 2, now: func() time.Time { return now }}
+
+	var dirs []string
+	for i := 0; i < 3; i++ {
+		dir, err := archive.Create("SMAStrategy")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		dirs = append(dirs, dir)
+		now = now.Add(time.Minute)
+	}
+	if filepath.Base(dirs[0]) != "SMAStrategy-20230501-120000" {
+		t.Errorf("Expected run directory SMAStrategy-20230501-120000, got %s", filepath.Base(dirs[0]))
+	}
+	if _, err := os.Stat(dirs[0]); !os.IsNotExist(err) {
+		t.Errorf("Expected the oldest run to be deleted, got %v", err)
+	}
+	runs, err := archive.Runs("SMAStrategy")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(runs) != 2 || runs[1].Dir != dirs[2] {
+		t.Fatalf("Expected the 2 newest runs, got %v", runs)
+	}
+	link, err := os.Readlink(filepath.Join(archive.Root, "latest"))
+	if err != nil {
+		t.Fatalf("Expected a latest symlink, got %v", err)
+	}
+	if link != filepath.Base(dirs[2]) {
+		t.Errorf("Expected latest to point to %s, got %s", filepath.Base(dirs[2]), link)
+	}
+
+	// A second run in the same second gets a suffix.
+	now = now.Add(-time.Minute)
+	dir, err := archive.Create("SMAStrategy")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if dir != dirs[2]+"-2" {
+		t.Errorf("Expected %s-2, got %s", dirs[2], dir)
+	}
+
+	// Runs of other strategies are kept separately and expire with MaxAge.
+	archive.Keep = 0
+	archive.MaxAge = time.Hour
+	if _, err := archive.Create("Other"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	now = now.Add(2 * time.Hour)
+	if _, err := archive.Create("SMAStrategy"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if runs, _ := archive.Runs("SMAStrategy"); len(runs) != 1 {
+		t.Errorf("Expected expired runs to be deleted, got %d runs", len(runs))
+	}
+	if runs, _ := archive.Runs("Other"); len(runs) != 1 {
+		t.Errorf("Expected the run of another strategy to be kept, got %d runs", len(runs))
+	}
+}
+
+func TestBacktestRunArchive(t *testing.T) {
+	broker := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	trader := NewTrader(TraderConfig{
+		Broker:        broker,
+		Strategy:      &roundTripStrategy{},
+		Symbol:        "EUR_USD",
+		Frequency:     "D",
+		CandlesToKeep: 5,
+	})
+	trader.Log.SetOutput(io.Discard)
+	report := NewHeadlessReport("summary.json")
+	report.Out = io.Discard
+	report.Runs.Root = t.TempDir()
+	BacktestWithReport(trader, report)
+
+	runs, err := report.Runs.Runs("")
+	if err != nil || len(runs) != 1 {
+		t.Fatalf("Expected 1 run, got %d (%v)", len(runs), err)
+	}
+	if runs[0].Strategy != "roundTripStrategy" {
+		t.Errorf("Expected the run of roundTripStrategy, got %s", runs[0].Strategy)
+	}
+	for _, name := range []string{"summary.json", "result.json", "trades.csv", "backtest.log"} {
+		if _, err := os.Stat(filepath.Join(runs[0].Dir, name)); err != nil {
+			t.Errorf("Expected %s to be written to the run: %v", name, err)
+		}
+	}
+	trades, err := os.ReadFile(filepath.Join(runs[0].Dir, "trades.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(trades)), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], "2022-01-02") || !strings.Contains(lines[2], ",Exit,") {
+		t.Errorf("Expected a header, an entry, and an exit in trades.csv, got %q", lines)
+	}
+	logged, err := os.ReadFile(filepath.Join(runs[0].Dir, "backtest.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logged) == 0 {
+		t.Error("Expected the log of the trader to be written")
+	}
+}
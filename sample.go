@@ -0,0 +1,102 @@
+package autotrader
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// sample records the equity, drawdown, and exposure of the account in TraderStats.Samples if SampleEquity is set and no sample was recorded yet in the current period of SampleEquity. The drawdown is measured from the first sample like the Drawdown of TraderStats.Dated, and the exposure is the total absolute value of the open positions.
+func (t *Trader) sample() {
+	samples := t.stats.Samples
+	if samples == nil {
+		return
+	}
+	now := t.Now()
+	if t.SampleEquity != "tick" && samples.Len() > 0 && !periodStart(now, t.SampleEquity).After(periodStart(samples.Date(-1), t.SampleEquity)) {
+		return
+	}
+	nav := t.Broker.NAV()
+	start := nav
+	if samples.Len() > 0 {
+		start = samples.Float("Equity", 0)
+	}
+	var exposure float64
+	for _, position := range t.Broker.OpenPositions() {
+		exposure += math.Abs(position.Value())
+	}
+	if err := samples.PushValues(map[string]any{
+		"Date":     now,
+		"Equity":   nav,
+		"Drawdown": Max(start-nav, 0),
+		"Exposure": exposure,
+	}); err != nil {
+		t.Log.Printf("error pushing values to samples dataframe: %v", err)
+	}
+}
+
+// startSampler samples the equity in the background while the Trader runs live, on every CandleClosed signal of the broker if SampleEquity is "tick" and otherwise on a timer of its frequency.
+func (t *Trader) startSampler(ctx context.Context) {
+	switch t.SampleEquity {
+	case "":
+		return
+	case "tick":
+		onClose := func(_ ...any) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.sample()
+		}
+		t.Broker.SignalConnect(CandleClosed, t.stats, onClose)
+		go func() {
+			<-ctx.Done()
+			t.Broker.SignalDisconnect(CandleClosed, t.stats, onClose)
+		}()
+		return
+	}
+	period, err := FrequencyDuration(t.SampleEquity)
+	if err != nil {
+		t.Log.Printf("error starting the equity sampler: %v", err)
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				t.mu.Lock()
+				t.sample()
+				t.mu.Unlock()
+			}
+		}
+	}()
+}
+
+// closesCandle returns true if a candle of frequency closes with the current candle of Data, which is when the period of frequency ends with the current candle or the next candle starts a new period. The last candle always closes.
+func (b *TestBroker) closesCandle(frequency string) bool {
+	if b.candleCount >= b.Data.Len() {
+		return true
+	}
+	duration, err := FrequencyDuration(b.Frequency)
+	if err != nil {
+		return true
+	}
+	date, next := b.Data.Date(b.CandleIndex()).Time(), b.Data.Date(b.CandleIndex()+1).Time()
+	start := periodStart(date, frequency)
+	return !date.Add(duration).Before(periodEnd(start, frequency)) || periodStart(next, frequency).After(start)
+}
+
+// coarserFrequency returns true if frequency has longer candles than the Frequency of the TestBroker.
+func (b *TestBroker) coarserFrequency(frequency string) bool {
+	if b.Frequency == "" || frequency == "" || frequency == b.Frequency {
+		return false
+	}
+	base, err := FrequencyDuration(b.Frequency)
+	if err != nil {
+		return false
+	}
+	duration, err := FrequencyDuration(frequency)
+	return err == nil && duration > base
+}
@@ -0,0 +1,48 @@
+package autotrader
+
+import (
+	"io"
+	"testing"
+)
+
+func TestSampleEquity(t *testing.T) {
+	data := hourlyTestData(3)
+	data.Series("High").SetValue(30, 100.0) // A spike within the second day that its daily close hides.
+	data.Series("Close").SetValue(30, 100.0)
+	broker := NewTestBroker(nil, data, 100_000, 1, 0, 0)
+	broker.Slippage = 0
+	broker.Frequency = "H1"
+	strategy := &scriptedStrategy{actions: map[int]func(t *Trader){
+		1: func(t *Trader) { t.Sell(1, 0, 0) },
+	}}
+	trader := NewTrader(TraderConfig{
+		Broker:        broker,
+		Strategy:      strategy,
+		Symbol:        "EUR_USD",
+		Frequency:     "D",
+		CandlesToKeep: 5,
+		SampleEquity:  "H1",
+	})
+	trader.Log.SetOutput(io.Discard)
+	summary, err := RunBacktest(trader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strategy.candle != 3 || trader.Stats().Dated.Len() != 3 {
+		t.Errorf("Expected the trader to tick once per day, got %d ticks and %d rows", strategy.candle, trader.Stats().Dated.Len())
+	}
+	samples := trader.Stats().Samples
+	if samples.Len() != data.Len() {
+		t.Fatalf("Expected a sample for every hour, got %d", samples.Len())
+	}
+	if exposure := samples.Float("Exposure", 24); !EqualApprox(exposure, 24.25) {
+		t.Errorf("Expected an exposure of 24.25 on the second day, got %v", exposure)
+	}
+	if drawdown := samples.Float("Drawdown", 30); !EqualApprox(drawdown, 100-23.25) {
+		t.Errorf("Expected a drawdown of %v at the spike, got %v", 100-23.25, drawdown)
+	}
+	if !EqualApprox(summary.MaxDrawdown, 100-23.25) {
+		t.Errorf("Expected the max drawdown to include the spike, got %v", summary.MaxDrawdown)
+	}
+}
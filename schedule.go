@@ -0,0 +1,71 @@
+package autotrader
+
+import (
+	"errors"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+var ErrOutsideSchedule = errors.New("order outside the trading schedule")
+
+// TradingWindow is a span of time of day on some days of the week, like 08:00 to 16:30 on weekdays.
+type TradingWindow struct {
+	Days  []time.Weekday // Days are the days of the week the window starts on. If empty, it is every day.
+	Start time.Duration  // Start is the time of day the window starts, like 8 * time.Hour.
+	// End is the time of day the window ends, which is not part of the window. If End is before Start, the window wraps past midnight into the next day. If Start and End are both zero, the window is the whole day.
+	End time.Duration
+}
+
+// Contains returns true if the time of day tod on weekday is in the window.
+func (w TradingWindow) Contains(weekday time.Weekday, tod time.Duration) bool {
+	onDay := func(day time.Weekday) bool {
+		return len(w.Days) == 0 || slices.Contains(w.Days, day)
+	}
+	switch {
+	case w.Start == 0 && w.End == 0:
+		return onDay(weekday)
+	case w.Start < w.End:
+		return onDay(weekday) && tod >= w.Start && tod < w.End
+	default: // The window wraps past midnight, so the early hours belong to the window of the previous day.
+		return onDay(weekday) && tod >= w.Start || onDay((weekday+6)%7) && tod < w.End
+	}
+}
+
+// TradingSchedule tells when a strategy may open trades, like "only trade the London session and never on Friday after 18:00". Orders placed by the Trader outside the schedule fail with ErrOutsideSchedule and are counted as skipped signals in the stats. Closing orders and positions is always allowed.
+type TradingSchedule struct {
+	Location *time.Location  // Location is the time zone of the windows. The default is UTC.
+	Allow    []TradingWindow // Allow are the windows trading is allowed in. If empty, trading is allowed at any time outside Block.
+	Block    []TradingWindow // Block are the windows trading is not allowed in, even if they overlap Allow.
+}
+
+// Allowed returns true if trading is allowed at t.
+func (s *TradingSchedule) Allowed(t time.Time) bool {
+	location := s.Location
+	if location == nil {
+		location = time.UTC
+	}
+	t = t.In(location)
+	weekday, tod := t.Weekday(), t.Sub(dayStart(t))
+	contains := func(windows []TradingWindow) bool {
+		return slices.ContainsFunc(windows, func(w TradingWindow) bool { return w.Contains(weekday, tod) })
+	}
+	if contains(s.Block) {
+		return false
+	}
+	return len(s.Allow) == 0 || contains(s.Allow)
+}
+
+// SkippedSignal is an order of the strategy that was not placed because it was outside the TradingSchedule of the Trader.
+type SkippedSignal struct {
+	Time      time.Time // Time is the date of the candle of the signal.
+	Symbol    string
+	OrderType OrderType
+	Units     float64
+	Price     float64 // Price is the price of the order, or the market price of a market order.
+}
+
+// Skipped returns the signals skipped because they were outside the TradingSchedule of the Trader, in the order they were made.
+func (s *TraderStats) Skipped() []SkippedSignal {
+	return s.skipped
+}
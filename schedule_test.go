@@ -0,0 +1,83 @@
+package autotrader
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestTradingSchedule(t *testing.T) {
+	schedule := &TradingSchedule{
+		Allow: []TradingWindow{{Days: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}, Start: 7 * time.Hour, End: 19 * time.Hour}},
+		Block: []TradingWindow{{Days: []time.Weekday{time.Friday}, Start: 18 * time.Hour, End: 24 * time.Hour}},
+	}
+	for _, test := range []struct {
+		time    time.Time
+		allowed bool
+	}{
+		{time.Date(2022, 1, 3, 8, 0, 0, 0, time.UTC), true},    // Monday morning.
+		{time.Date(2022, 1, 3, 6, 59, 0, 0, time.UTC), false},  // Before the session.
+		{time.Date(2022, 1, 3, 19, 0, 0, 0, time.UTC), false},  // The end is not part of the window.
+		{time.Date(2022, 1, 7, 17, 59, 0, 0, time.UTC), true},  // Friday afternoon.
+		{time.Date(2022, 1, 7, 18, 30, 0, 0, time.UTC), false}, // Friday evening is blocked.
+		{time.Date(2022, 1, 8, 12, 0, 0, 0, time.UTC), false},  // Saturday.
+	} {
+		if allowed := schedule.Allowed(test.time); allowed != test.allowed {
+			t.Errorf("Expected trading at %v to be allowed %t, got %t", test.time, test.allowed, allowed)
+		}
+	}
+
+	// A window that wraps past midnight belongs to the day it starts on.
+	overnight := &TradingSchedule{Allow: []TradingWindow{{Days: []time.Weekday{time.Sunday}, Start: 22 * time.Hour, End: 2 * time.Hour}}}
+	if !overnight.Allowed(time.Date(2022, 1, 2, 23, 0, 0, 0, time.UTC)) || !overnight.Allowed(time.Date(2022, 1, 3, 1, 0, 0, 0, time.UTC)) {
+		t.Error("Expected Sunday night and early Monday to be allowed")
+	}
+	if overnight.Allowed(time.Date(2022, 1, 3, 23, 0, 0, 0, time.UTC)) || overnight.Allowed(time.Date(2022, 1, 2, 1, 0, 0, 0, time.UTC)) {
+		t.Error("Expected Monday night and early Sunday not to be allowed")
+	}
+
+	// The windows are in the time zone of the schedule.
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	session := &TradingSchedule{Location: newYork, Allow: []TradingWindow{{Start: 9*time.Hour + 30*time.Minute, End: 16 * time.Hour}}}
+	if !session.Allowed(time.Date(2022, 1, 3, 15, 0, 0, 0, time.UTC)) { // 10:00 in New York.
+		t.Error("Expected 15:00 UTC to be in the New York session")
+	}
+}
+
+func TestTraderSchedule(t *testing.T) {
+	strategy := &burstStrategy{orders: 1}
+	broker := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	trader := NewTrader(TraderConfig{
+		Broker:        broker,
+		Strategy:      strategy,
+		Symbol:        "EUR_USD",
+		Frequency:     "D",
+		CandlesToKeep: 5,
+		Schedule:      &TradingSchedule{Block: []TradingWindow{{Days: []time.Weekday{time.Saturday, time.Sunday}}}},
+	})
+	trader.Log.SetOutput(io.Discard)
+	trader.Init()
+	for i := 0; i < 4; i++ { // Saturday, Sunday, Monday, and Tuesday.
+		trader.Tick()
+		broker.Advance()
+	}
+	for i, errs := range strategy.errs {
+		if weekend := i < 2; weekend != errors.Is(errs[0], ErrOutsideSchedule) {
+			t.Errorf("Expected the order on candle %d to be skipped %t, got %v", i, weekend, errs[0])
+		}
+	}
+	skipped := trader.Stats().Skipped()
+	if len(skipped) != 2 {
+		t.Fatalf("Expected 2 skipped signals, got %d", len(skipped))
+	}
+	if skipped[1].Time.Weekday() != time.Sunday || skipped[1].Units != 1000 || skipped[1].Price == 0 {
+		t.Errorf("Expected a skipped buy of 1000 units on Sunday, got %+v", skipped[1])
+	}
+	if summary := Summarize(trader.Stats(), broker); summary.SkippedSignals != 2 {
+		t.Errorf("Expected 2 skipped signals in the summary, got %d", summary.SkippedSignals)
+	}
+}
@@ -0,0 +1,166 @@
+package autotrader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"golang.org/x/exp/slices"
+)
+
+// ScreenFunc returns true if the candles of a symbol pass a screen, like an RSI below 30. The last candle is the latest one. Screens return false when there are too few candles to decide.
+type ScreenFunc func(candles *IndexedFrame[UnixTime]) bool
+
+// AllOf returns a screen that passes when every one of screens passes.
+func AllOf(screens ...ScreenFunc) ScreenFunc {
+	return func(candles *IndexedFrame[UnixTime]) bool {
+		for _, screen := range screens {
+			if !screen(candles) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// AnyOf returns a screen that passes when any of screens passes.
+func AnyOf(screens ...ScreenFunc) ScreenFunc {
+	return func(candles *IndexedFrame[UnixTime]) bool {
+		for _, screen := range screens {
+			if screen(candles) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not returns a screen that passes when screen does not, which includes when there are too few candles for screen to decide.
+func Not(screen ScreenFunc) ScreenFunc {
+	return func(candles *IndexedFrame[UnixTime]) bool {
+		return !screen(candles)
+	}
+}
+
+// RSIBelow passes when the RSI of the closes over periods is below level, like 30 for oversold symbols.
+func RSIBelow(periods int, level float64) ScreenFunc {
+	return func(candles *IndexedFrame[UnixTime]) bool {
+		return candles.Len() > periods && RSI(closes(candles), periods).Value(-1) < level
+	}
+}
+
+// RSIAbove passes when the RSI of the closes over periods is above level, like 70 for overbought symbols.
+func RSIAbove(periods int, level float64) ScreenFunc {
+	return func(candles *IndexedFrame[UnixTime]) bool {
+		return candles.Len() > periods && RSI(closes(candles), periods).Value(-1) > level
+	}
+}
+
+// PriceAboveSMA passes when the last close is above the simple moving average of the last periods closes, like 200 for symbols in an uptrend.
+func PriceAboveSMA(periods int) ScreenFunc {
+	return func(candles *IndexedFrame[UnixTime]) bool {
+		sma, ok := lastSMA(candles, periods)
+		return ok && candles.Close(-1) > sma
+	}
+}
+
+// PriceBelowSMA passes when the last close is below the simple moving average of the last periods closes.
+func PriceBelowSMA(periods int) ScreenFunc {
+	return func(candles *IndexedFrame[UnixTime]) bool {
+		sma, ok := lastSMA(candles, periods)
+		return ok && candles.Close(-1) < sma
+	}
+}
+
+// BullishEngulfing passes when the last candle closed up with a body that engulfs the body of the previous candle, which closed down.
+func BullishEngulfing() ScreenFunc {
+	return func(candles *IndexedFrame[UnixTime]) bool {
+		if candles.Len() < 2 {
+			return false
+		}
+		prevOpen, prevClose, open, close := candles.Open(-2), candles.Close(-2), candles.Open(-1), candles.Close(-1)
+		return prevClose < prevOpen && close > open && open <= prevClose && close >= prevOpen
+	}
+}
+
+// BearishEngulfing passes when the last candle closed down with a body that engulfs the body of the previous candle, which closed up.
+func BearishEngulfing() ScreenFunc {
+	return func(candles *IndexedFrame[UnixTime]) bool {
+		if candles.Len() < 2 {
+			return false
+		}
+		prevOpen, prevClose, open, close := candles.Open(-2), candles.Close(-2), candles.Open(-1), candles.Close(-1)
+		return prevClose > prevOpen && close < open && open >= prevClose && close <= prevOpen
+	}
+}
+
+// Doji passes when the body of the last candle is at most maxBody of its range, like 0.1, which signals indecision.
+func Doji(maxBody float64) ScreenFunc {
+	return func(candles *IndexedFrame[UnixTime]) bool {
+		if candles.Len() < 1 {
+			return false
+		}
+		length := candles.High(-1) - candles.Low(-1)
+		return length > 0 && math.Abs(candles.Close(-1)-candles.Open(-1)) <= maxBody*length
+	}
+}
+
+// closes returns the closes of candles as a FloatSeries for the indicators.
+func closes(candles *IndexedFrame[UnixTime]) *FloatSeries {
+	values := make([]float64, candles.Len())
+	for i := range values {
+		values[i] = candles.Close(i)
+	}
+	return NewFloatSeries("Close", values...)
+}
+
+// lastSMA returns the simple moving average of the last periods closes of candles. It returns false if there are fewer candles than periods.
+func lastSMA(candles *IndexedFrame[UnixTime], periods int) (float64, bool) {
+	if periods < 1 || candles.Len() < periods {
+		return 0, false
+	}
+	var sum float64
+	for i := candles.Len() - periods; i < candles.Len(); i++ {
+		sum += candles.Close(i)
+	}
+	return sum / float64(periods), true
+}
+
+// Screener screens a universe of symbols with a ScreenFunc on the latest candles of a broker, like finding the oversold symbols in an uptrend with AllOf(RSIBelow(14, 30), PriceAboveSMA(200)). It can be run on its own or feed the Symbols of a Trader every period.
+type Screener struct {
+	Broker    Broker
+	Symbols   []string // Symbols is the universe of symbols to screen.
+	Frequency string
+	Candles   int // Candles is the number of candles fetched for each symbol, which must cover the longest period of the screen.
+	Screen    ScreenFunc
+}
+
+// Run returns the symbols that pass the screen on their latest candles, in the order of Symbols. Symbols whose candles cannot be fetched are left out, and their errors are joined into the returned error, so one bad symbol does not hide the matches of the rest.
+func (s *Screener) Run(ctx context.Context) ([]string, error) {
+	var matches []string
+	var errs []error
+	for _, symbol := range s.Symbols {
+		candles, err := s.Broker.Candles(ctx, symbol, s.Frequency, s.Candles)
+		if err != nil && err != ErrEOF {
+			errs = append(errs, fmt.Errorf("%s: %w", symbol, err))
+			continue
+		}
+		if s.Screen(candles) {
+			matches = append(matches, symbol)
+		}
+	}
+	return matches, errors.Join(errs...)
+}
+
+// Feed screens the universe and replaces the Symbols of t with the matches, so a basket strategy trades the symbols that currently pass the screen. Symbols with open positions stay in the basket until they are closed, so the strategy can still manage them. The Symbol of the Trader is not changed, and the candles of new symbols are fetched from the next candle of the Trader.
+func (s *Screener) Feed(t *Trader) error {
+	matches, err := s.Run(t.Context())
+	for _, position := range t.Broker.OpenPositions() {
+		if symbol := position.Symbol(); symbol != t.Symbol && t.TradesSymbol(symbol) && !slices.Contains(matches, symbol) {
+			matches = append(matches, symbol)
+		}
+	}
+	t.Symbols = matches
+	return err
+}
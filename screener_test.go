@@ -0,0 +1,69 @@
+package autotrader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+// trendData returns 9 daily candles that close at each of closes with bodies of 0.5.
+func trendData(closes ...float64) *IndexedFrame[UnixTime] {
+	data := NewDOHLCVIndexedFrame[UnixTime]()
+	for i, close := range closes {
+		date := time.Date(2022, 1, 1+i, 0, 0, 0, 0, time.UTC)
+		data.PushCandle(UnixTime(date.Unix()), close-0.5, close+0.1, close-0.6, close, 100)
+	}
+	return data
+}
+
+func TestScreener(t *testing.T) {
+	candles := map[string]*IndexedFrame[UnixTime]{
+		"UP":      trendData(1, 2, 3, 4, 5, 6, 7, 8, 9),
+		"DOWN":    trendData(9, 8, 7, 6, 5, 4, 3, 2, 1),
+		"EUR_USD": testData,
+	}
+	broker := &multiSymbolBroker{NewTestBroker(nil, testData, 100_000, 50, 0, 0), candles}
+	screener := &Screener{Broker: broker, Symbols: []string{"UP", "DOWN", "EUR_USD", "BAD"}, Frequency: "D", Candles: 9}
+
+	for _, test := range []struct {
+		name    string
+		screen  ScreenFunc
+		matches []string
+	}{
+		{"uptrend", AllOf(PriceAboveSMA(3), RSIAbove(5, 70)), []string{"UP"}},
+		{"oversold", RSIBelow(5, 30), []string{"DOWN"}},
+		{"any", AnyOf(PriceBelowSMA(3), BullishEngulfing()), []string{"DOWN", "EUR_USD"}},
+		{"too few candles", PriceAboveSMA(20), nil},
+		{"not", Not(PriceAboveSMA(20)), []string{"UP", "DOWN", "EUR_USD"}},
+		{"engulfing", BearishEngulfing(), nil},
+		{"doji", Doji(0.1), nil},
+	} {
+		screener.Screen = test.screen
+		matches, err := screener.Run(context.Background())
+		if !errors.Is(err, ErrSymbolNotFound) {
+			t.Errorf("%s: Expected the error of the unknown symbol, got %v", test.name, err)
+		}
+		if !slices.Equal(matches, test.matches) {
+			t.Errorf("%s: Expected %v to match, got %v", test.name, test.matches, matches)
+		}
+	}
+
+	// Feeding a Trader keeps the symbols it still has positions in.
+	trader := NewTrader(TraderConfig{Broker: broker, Strategy: &scriptedStrategy{}, Symbol: "EUR_USD", Symbols: []string{"DOWN"}, Frequency: "D"})
+	trader.Log.SetOutput(io.Discard)
+	if _, err := broker.Order(context.Background(), Market, "DOWN", 1000, 0, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	screener.Symbols = []string{"UP", "DOWN"}
+	screener.Screen = PriceAboveSMA(3)
+	if err := screener.Feed(trader); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(trader.Symbols, []string{"UP", "DOWN"}) {
+		t.Errorf("Expected the basket to be UP and the held DOWN, got %v", trader.Symbols)
+	}
+}
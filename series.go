@@ -64,6 +64,12 @@ func (s *Series) CopyRange(start, count int) *Series {
 	return NewSeries(s.name, data...)
 }
 
+// View returns a Series of the rows in the given range that shares the values of this series instead of copying them, which makes it O(1). start is an EasyIndex and count is the number of rows like CopyRange. Values pushed to either series are not seen by the other, but changing a value of the view, like with Map, changes this series too, so the view should be treated as read-only.
+func (s *Series) View(start, count int) *Series {
+	start, end := s.Range(start, count)
+	return NewSeries(s.name, s.data[start:end:end]...) // The capacity is capped so pushing to the view does not overwrite rows of s.
+}
+
 // Range takes an EasyIndex start and a number of items to select with count, and returns a range from begin to end, exclusive. If count is negative then the range spans to the end of the series. begin will always be between 0 and len-1. end will always be between start and len. If the range is empty then begin and end will be the same value.
 func (s *Series) Range(start, count int) (begin, end int) {
 	start = EasyIndex(start, s.Len())   // Allow for negative indexing.
@@ -108,15 +114,19 @@ func (s *Series) Reverse() *Series {
 	return s
 }
 
-func (s *Series) Insert(i int, value any) *Series {
+// Insert inserts vals before the value at index i and emits a single LengthChanged signal. An index of Len() or beyond appends vals like PushMany, and a negative index counts back from the end like EasyIndex, where -1 also appends and -2 inserts before the last value.
+func (s *Series) Insert(i int, vals ...any) *Series {
+	if len(vals) == 0 {
+		return s
+	}
 	i = EasyIndex(i, s.Len()+1)
 	if i < 0 {
 		return s
-	} else if i <= s.Len() { // Remember the length will grow by 1. We want to allow inserting at the end.
-		s.data = slices.Insert(s.data, i, value)
+	} else if i <= s.Len() { // Remember the length will grow. We want to allow inserting at the end.
+		s.data = slices.Insert(s.data, i, vals...)
 		s.SignalEmit("LengthChanged", s.Len())
 	} else {
-		_ = s.Push(value) // Emits a LengthChanged signal
+		_ = s.PushMany(vals...) // Emits a LengthChanged signal
 	}
 	return s
 }
@@ -125,24 +135,41 @@ func (s *Series) Insert(i int, value any) *Series {
 func (s *Series) Remove(i int) any {
 	if i = EasyIndex(i, s.Len()); i < s.Len() && i >= 0 {
 		value := s.data[i]
-		s.data = append(s.data[:i], s.data[i+1:]...)
+		s.data = removeRows(s.data, i, i+1)
 		s.SignalEmit("LengthChanged", s.Len())
 		return value
 	}
 	return nil
 }
 
-// RemoveRange removes count items starting at index start and emits a LengthChanged signal.
+// RemoveRange removes count items starting at index start and emits a LengthChanged signal. Only the items on the shorter side of the range are moved, so removing items from either end of a long series is O(count).
 func (s *Series) RemoveRange(start, count int) *Series {
 	start, end := s.Range(start, count)
 	if start == end {
 		return s
 	}
-	s.data = append(s.data[:start], s.data[end:]...)
+	s.data = removeRows(s.data, start, end)
 	s.SignalEmit("LengthChanged", s.Len())
 	return s
 }
 
+// removeRows removes the rows from start up to end of data by moving the rows on the shorter side of the range over it, and returns the remaining rows. Rows before the range are moved forward and the front is resliced off, so trimming the oldest rows of a buffer does not move the rest. The vacated slots are cleared so their values can be garbage collected.
+func removeRows[T any](data []T, start, end int) []T {
+	var zero T
+	if start < len(data)-end {
+		copy(data[end-start:end], data[:start])
+		for i := 0; i < end-start; i++ {
+			data[i] = zero
+		}
+		return data[end-start:]
+	}
+	n := copy(data[start:], data[end:])
+	for i := start + n; i < len(data); i++ {
+		data[i] = zero
+	}
+	return data[:start+n]
+}
+
 // Push will append a value to the end of the Series and emit a LengthChanged signal.
 func (s *Series) Push(value any) *Series {
 	s.data = append(s.data, value)
@@ -150,6 +177,16 @@ func (s *Series) Push(value any) *Series {
 	return s
 }
 
+// PushMany appends vals to the end of the Series and emits a single LengthChanged signal, so large datasets can be built without a signal for every value.
+func (s *Series) PushMany(vals ...any) *Series {
+	if len(vals) == 0 {
+		return s
+	}
+	s.data = append(s.data, vals...)
+	s.SignalEmit("LengthChanged", s.Len())
+	return s
+}
+
 // Pop will remove the last value from the Series and emit a LengthChanged signal.
 func (s *Series) Pop() any {
 	if len(s.data) != 0 {
@@ -289,6 +326,69 @@ func (s *Series) Div(other *Series) *Series {
 	return s
 }
 
+// AddFloat adds num to every value of the series. Values that are not numbers are left unchanged.
+func (s *Series) AddFloat(num float64) *Series {
+	return s.scalar(num, anymath.Add)
+}
+
+// SubFloat subtracts num from every value of the series. Values that are not numbers are left unchanged.
+func (s *Series) SubFloat(num float64) *Series {
+	return s.scalar(num, anymath.Subtract)
+}
+
+// MulFloat multiplies every value of the series by num. Values that are not numbers are left unchanged.
+func (s *Series) MulFloat(num float64) *Series {
+	return s.scalar(num, anymath.Multiply)
+}
+
+// DivFloat divides every value of the series by num. Values that are not numbers are left unchanged.
+func (s *Series) DivFloat(num float64) *Series {
+	return s.scalar(num, anymath.Divide)
+}
+
+// scalar replaces every value of the series with the result of op on the value and num.
+func (s *Series) scalar(num float64, op func(a, b any) (any, error)) *Series {
+	for i := 0; i < s.Len(); i++ {
+		val, err := op(s.Value(i), num)
+		if err != nil {
+			continue
+		}
+		s.data[i] = val
+		s.SignalEmit("ValueChanged", i, val)
+	}
+	return s
+}
+
+// Gt returns a new series with the same name that is true where the value of this series is greater than num. Values that are not numbers are false.
+func (s *Series) Gt(num float64) *Series {
+	return s.compare(func(v float64) bool { return v > num })
+}
+
+// Ge returns a new series with the same name that is true where the value of this series is greater than or equal to num. Values that are not numbers are false.
+func (s *Series) Ge(num float64) *Series {
+	return s.compare(func(v float64) bool { return v >= num })
+}
+
+// Lt returns a new series with the same name that is true where the value of this series is less than num. Values that are not numbers are false.
+func (s *Series) Lt(num float64) *Series {
+	return s.compare(func(v float64) bool { return v < num })
+}
+
+// Le returns a new series with the same name that is true where the value of this series is less than or equal to num. Values that are not numbers are false.
+func (s *Series) Le(num float64) *Series {
+	return s.compare(func(v float64) bool { return v <= num })
+}
+
+// compare returns a new series of the result of f on every value of the series that is a number.
+func (s *Series) compare(f func(v float64) bool) *Series {
+	out := make([]any, s.Len())
+	for i, val := range s.data {
+		v, ok := number(val)
+		out[i] = ok && f(v)
+	}
+	return NewSeries(s.Name(), out...)
+}
+
 func (s *Series) Filter(f func(i int, val any) bool) *Series {
 	for i := 0; i < s.Len(); i++ {
 		if val := s.data[i]; !f(i, val) {
@@ -409,6 +509,156 @@ func (s *Series) MinInt() int {
 	return min
 }
 
+// ZScore maps each value to the number of standard deviations it is from the mean of the series, which standardizes the series to a mean of 0 and a standard deviation of 1. Values are 0 if every value is the same.
+//
+// Will work with all signed int and float types. Other values are left unchanged.
+func (s *Series) ZScore() *Series {
+	mean, stdDev := meanStdDev(numbers(s.data))
+	return s.Standardize(mean, stdDev)
+}
+
+// Standardize maps each value to (value - mean) / stdDev as a float64, or 0 if stdDev is 0. This applies the same standardization to several series, like scaling test data with the mean and standard deviation of the training data.
+//
+// Will work with all signed int and float types. Other values are left unchanged.
+func (s *Series) Standardize(mean, stdDev float64) *Series {
+	return s.Map(func(_ int, val any) any {
+		v := numbers([]any{val})
+		if len(v) == 0 {
+			return val
+		} else if stdDev == 0 {
+			return 0.0
+		}
+		return (v[0] - mean) / stdDev
+	})
+}
+
+// Normalize maps each value to where it is between the minimum and maximum of the series, from 0 to 1, as a float64. Values are 0 if every value is the same.
+//
+// Will work with all signed int and float types. Other values are left unchanged.
+func (s *Series) Normalize() *Series {
+	values := numbers(s.data)
+	if len(values) == 0 {
+		return s
+	}
+	low, high := minMax(values)
+	return s.Map(func(_ int, val any) any {
+		v := numbers([]any{val})
+		if len(v) == 0 {
+			return val
+		} else if high == low {
+			return 0.0
+		}
+		return (v[0] - low) / (high - low)
+	})
+}
+
+// IdxMax returns the row of the highest value of the series, or -1 if there are no numbers. The first row is returned if several rows share the highest value. This locates things like a swing high or the peak before a drawdown.
+//
+// Will work with all signed int and float types. Ignores all other values.
+func (s *Series) IdxMax() int {
+	return argExtreme(s.data, func(a, b float64) bool { return a > b })
+}
+
+// IdxMin returns the row of the lowest value of the series, or -1 if there are no numbers. The first row is returned if several rows share the lowest value.
+//
+// Will work with all signed int and float types. Ignores all other values.
+func (s *Series) IdxMin() int {
+	return argExtreme(s.data, func(a, b float64) bool { return a < b })
+}
+
+// argExtreme returns the position of the first number in values that no other number is better than, or -1 if there are no numbers.
+func argExtreme(values []any, better func(a, b float64) bool) int {
+	row := -1
+	var best float64
+	for i, val := range values {
+		v := numbers([]any{val})
+		if len(v) == 0 || math.IsNaN(v[0]) {
+			continue
+		}
+		if row < 0 || better(v[0], best) {
+			row, best = i, v[0]
+		}
+	}
+	return row
+}
+
+// SeriesSummary holds the summary statistics of the numbers in a Series, as returned by Describe.
+type SeriesSummary struct {
+	Count  int     // Count is the number of values that are numbers.
+	Mean   float64 // Mean is the average of the values.
+	StdDev float64 // StdDev is the population standard deviation of the values.
+	Min    float64 // Min is the lowest value.
+	Q1     float64 // Q1 is the first quartile, which 25% of the values are below.
+	Median float64 // Median is the middle value.
+	Q3     float64 // Q3 is the third quartile, which 75% of the values are below.
+	Max    float64 // Max is the highest value.
+}
+
+// Sum returns the sum of the values of the series as a float64, or 0 if the series is empty.
+//
+// Will work with all signed int and float types. Ignores all other values.
+func (s *Series) Sum() float64 {
+	var sum float64
+	for _, v := range numbers(s.data) {
+		sum += v
+	}
+	return sum
+}
+
+// Mean returns the average of the values of the series as a float64, or 0 if there are no numbers.
+//
+// Will work with all signed int and float types. Ignores all other values.
+func (s *Series) Mean() float64 {
+	mean, _ := meanStdDev(numbers(s.data))
+	return mean
+}
+
+// StdDev returns the population standard deviation of the values of the series as a float64, or 0 if there are no numbers.
+//
+// Will work with all signed int and float types. Ignores all other values.
+func (s *Series) StdDev() float64 {
+	_, stdDev := meanStdDev(numbers(s.data))
+	return stdDev
+}
+
+// Median returns the middle value of the series as a float64, or the average of the two middle values if there is an even number of them. It returns 0 if there are no numbers.
+//
+// Will work with all signed int and float types. Ignores all other values.
+func (s *Series) Median() float64 {
+	return s.Quantile(0.5)
+}
+
+// Quantile returns the q quantile of the values of the series as a float64, or 0 if there are no numbers. The q is between 0 and 1, and the quantile is interpolated linearly between the two nearest values like RollingSeries.Quantile.
+//
+// Will work with all signed int and float types. Ignores all other values.
+func (s *Series) Quantile(q float64) float64 {
+	values := numbers(s.data)
+	slices.Sort(values)
+	return quantile(values, q)
+}
+
+// Describe returns the summary statistics of the numbers in the series in a single pass over the sorted values, which is cheaper than calling each method on its own.
+//
+// Will work with all signed int and float types. Ignores all other values.
+func (s *Series) Describe() SeriesSummary {
+	values := numbers(s.data)
+	if len(values) == 0 {
+		return SeriesSummary{}
+	}
+	slices.Sort(values)
+	mean, stdDev := meanStdDev(values)
+	return SeriesSummary{
+		Count:  len(values),
+		Mean:   mean,
+		StdDev: stdDev,
+		Min:    values[0],
+		Q1:     quantile(values, 0.25),
+		Median: quantile(values, 0.5),
+		Q3:     quantile(values, 0.75),
+		Max:    values[len(values)-1],
+	}
+}
+
 func (s *Series) Rolling(period int) *RollingSeries {
 	return NewRollingSeries(s, period)
 }
@@ -688,41 +938,146 @@ func (s *RollingSeries) Median() *Series {
 	})
 }
 
-// StdDev returns the standard deviation of the period as a float64 or 0 if the period requested is empty.
-func (s *RollingSeries) StdDev() *Series {
+// Quantile returns the underlying series with each value mapped to the q quantile of its period as a float64, or 0 if the period requested is empty. The q is between 0 and 1, and the quantile is interpolated linearly between the two nearest values, so Quantile(0.5) is the median and Quantile(0.9) is the value that 90% of the period is below.
+//
+// Will work with all signed int and float types. Ignores all other values.
+func (s *RollingSeries) Quantile(q float64) *Series {
+	q = math.Max(0, math.Min(q, 1))
 	return s.series.MapReverse(func(i int, _ any) any {
-		period := s.Period(i)
-		if len(period) == 0 {
-			return 0
-		}
+		period := numbers(s.Period(i))
+		slices.Sort(period)
+		return quantile(period, q)
+	})
+}
 
-		mean := s.Mean().Value(i).(float64) // Take the mean of the last period values for the current index
-		period = s.Period(i)
-		var sum float64
-		var ignored int
+// quantile returns the q quantile of the sorted values interpolated linearly between the two nearest values, or 0 if there are no values.
+func quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	q = math.Max(0, math.Min(q, 1))
+	pos := q * float64(len(sorted)-1)
+	lower := int(pos)
+	if lower == len(sorted)-1 {
+		return sorted[lower]
+	}
+	return sorted[lower] + (sorted[lower+1]-sorted[lower])*(pos-float64(lower))
+}
+
+// PercentRank returns the underlying series with each value mapped to the percentage of the other values of its period that are less than or equal to it, from 0 to 100, as a float64. For example, a PercentRank above 90 means the value is in the top decile of its period. The value is 0 if it is not a number or if there are no other values in its period.
+//
+// Will work with all signed int and float types. Ignores all other values.
+func (s *RollingSeries) PercentRank() *Series {
+	return s.series.MapReverse(func(i int, val any) any {
+		current := numbers([]any{val})
+		period := numbers(s.Period(i))
+		if len(current) == 0 || len(period) < 2 {
+			return 0.0
+		}
+		var count int
 		for _, v := range period {
-			switch v := v.(type) {
-			case float64:
-				sum += (v - mean) * (v - mean)
-			case float32:
-				sum += (float64(v) - mean) * (float64(v) - mean)
-			case int:
-				sum += (float64(v) - mean) * (float64(v) - mean)
-			case int64:
-				sum += (float64(v) - mean) * (float64(v) - mean)
-			case int32:
-				sum += (float64(v) - mean) * (float64(v) - mean)
-			case int16:
-				sum += (float64(v) - mean) * (float64(v) - mean)
-			case int8:
-				sum += (float64(v) - mean) * (float64(v) - mean)
-			default:
-				ignored++
+			if v <= current[0] {
+				count++
 			}
 		}
-		if ignored >= len(period) {
-			return 0
+		return 100 * float64(count-1) / float64(len(period)-1) // Do not count the current value.
+	})
+}
+
+// ZScore returns the underlying series with each value mapped to the number of standard deviations it is from the mean of its period as a float64. The value is 0 if it is not a number or if every value of its period is the same. A rolling z-score is a common mean reversion signal, like entering when the price is more than 2 standard deviations from its average.
+//
+// Will work with all signed int and float types. Ignores all other values.
+func (s *RollingSeries) ZScore() *Series {
+	return s.series.MapReverse(func(i int, val any) any {
+		current := numbers([]any{val})
+		mean, stdDev := meanStdDev(numbers(s.Period(i)))
+		if len(current) == 0 || stdDev == 0 {
+			return 0.0
 		}
-		return math.Sqrt(sum / float64(len(period)-ignored))
+		return (current[0] - mean) / stdDev
+	})
+}
+
+// Normalize returns the underlying series with each value mapped to where it is between the minimum and maximum of its period, from 0 to 1, as a float64. The value is 0 if it is not a number or if every value of its period is the same.
+//
+// Will work with all signed int and float types. Ignores all other values.
+func (s *RollingSeries) Normalize() *Series {
+	return s.series.MapReverse(func(i int, val any) any {
+		current := numbers([]any{val})
+		period := numbers(s.Period(i))
+		if len(current) == 0 || len(period) == 0 {
+			return 0.0
+		}
+		low, high := minMax(period)
+		if high == low {
+			return 0.0
+		}
+		return (current[0] - low) / (high - low)
+	})
+}
+
+// minMax returns the lowest and highest of values, which must not be empty.
+func minMax(values []float64) (low, high float64) {
+	low, high = values[0], values[0]
+	for _, v := range values[1:] {
+		low, high = math.Min(low, v), math.Max(high, v)
+	}
+	return low, high
+}
+
+// meanStdDev returns the mean and population standard deviation of values, or zeros if there are no values.
+func meanStdDev(values []float64) (mean, stdDev float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	for _, v := range values {
+		stdDev += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(stdDev / float64(len(values)))
+}
+
+// numbers returns the values that are signed ints or floats as float64s and skips the rest.
+func numbers(values []any) []float64 {
+	floats := make([]float64, 0, len(values))
+	for _, v := range values {
+		if f, ok := number(v); ok {
+			floats = append(floats, f)
+		}
+	}
+	return floats
+}
+
+// number returns v as a float64 and true if it is a signed int or float.
+func number(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// StdDev returns the population standard deviation of the period as a float64 or 0 if the period requested is empty.
+//
+// Will work with all signed int and float types. Ignores all other values.
+func (s *RollingSeries) StdDev() *Series {
+	return s.series.MapReverse(func(i int, _ any) any {
+		_, stdDev := meanStdDev(numbers(s.Period(i)))
+		return stdDev
 	})
 }
@@ -20,6 +20,30 @@ func (s *FloatSeries) Add(other *FloatSeries) *FloatSeries {
 	return s
 }
 
+// AddFloat adds num to every value of the series.
+func (s *FloatSeries) AddFloat(num float64) *FloatSeries {
+	_ = s.Series.AddFloat(num)
+	return s
+}
+
+// SubFloat subtracts num from every value of the series.
+func (s *FloatSeries) SubFloat(num float64) *FloatSeries {
+	_ = s.Series.SubFloat(num)
+	return s
+}
+
+// MulFloat multiplies every value of the series by num.
+func (s *FloatSeries) MulFloat(num float64) *FloatSeries {
+	_ = s.Series.MulFloat(num)
+	return s
+}
+
+// DivFloat divides every value of the series by num.
+func (s *FloatSeries) DivFloat(num float64) *FloatSeries {
+	_ = s.Series.DivFloat(num)
+	return s
+}
+
 func (s *FloatSeries) Copy() *FloatSeries {
 	return s.CopyRange(0, -1)
 }
@@ -53,6 +77,21 @@ func (s *FloatSeries) Map(f func(i int, val float64) float64) *FloatSeries {
 	return s
 }
 
+func (s *FloatSeries) ZScore() *FloatSeries {
+	_ = s.Series.ZScore()
+	return s
+}
+
+func (s *FloatSeries) Standardize(mean, stdDev float64) *FloatSeries {
+	_ = s.Series.Standardize(mean, stdDev)
+	return s
+}
+
+func (s *FloatSeries) Normalize() *FloatSeries {
+	_ = s.Series.Normalize()
+	return s
+}
+
 func (s *FloatSeries) MapReverse(f func(i int, val float64) float64) *FloatSeries {
 	_ = s.Series.MapReverse(func(i int, val any) any {
 		return f(i, val.(float64))
@@ -155,7 +194,7 @@ func (s *FloatSeries) ValueRange(start, count int) []float64 {
 	}
 	vals := make([]float64, end-start)
 	for i := start; i < end; i++ {
-		vals[i] = s.Series.data[i].(float64)
+		vals[i-start] = s.Series.data[i].(float64)
 	}
 	return vals
 }
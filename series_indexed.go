@@ -8,7 +8,6 @@ import (
 
 	anymath "github.com/spatialcurrent/go-math/pkg/math"
 	"golang.org/x/exp/constraints"
-	"golang.org/x/exp/maps"
 	"golang.org/x/exp/slices"
 )
 
@@ -20,7 +19,7 @@ func (e ErrIndexExists) Error() string {
 	return fmt.Sprintf("index already exists: %v", e.any)
 }
 
-// UnixTime is a wrapper over the number of milliseconds since January 1, 1970, AKA Unix time.
+// UnixTime is a wrapper over the number of seconds since January 1, 1970, AKA Unix time. Use UnixMilli or UnixNano to index tick data and sub-second bars.
 type UnixTime int64
 
 // Time converts the UnixTime to a time.Time.
@@ -40,17 +39,56 @@ func UnixTimeStep(frequency time.Duration) func(UnixTime, int) UnixTime {
 	}
 }
 
+// UnixMilli is the number of milliseconds since January 1, 1970. It is an index type for sub-second bars.
+type UnixMilli int64
+
+// Time converts the UnixMilli to a time.Time.
+func (t UnixMilli) Time() time.Time {
+	return time.UnixMilli(int64(t))
+}
+
+// String returns the string representation of the UnixMilli.
+func (t UnixMilli) String() string {
+	return t.Time().UTC().String()
+}
+
+// UnixMilliStep returns a function that adds a number of increments to a UnixMilli.
+func UnixMilliStep(frequency time.Duration) func(UnixMilli, int) UnixMilli {
+	return func(t UnixMilli, amt int) UnixMilli {
+		return UnixMilli(t.Time().Add(frequency * time.Duration(amt)).UnixMilli())
+	}
+}
+
+// UnixNano is the number of nanoseconds since January 1, 1970. It is an index type for tick data, where several ticks may arrive within a millisecond.
+type UnixNano int64
+
+// Time converts the UnixNano to a time.Time.
+func (t UnixNano) Time() time.Time {
+	return time.Unix(0, int64(t))
+}
+
+// String returns the string representation of the UnixNano.
+func (t UnixNano) String() string {
+	return t.Time().UTC().String()
+}
+
+// UnixNanoStep returns a function that adds a number of increments to a UnixNano.
+func UnixNanoStep(frequency time.Duration) func(UnixNano, int) UnixNano {
+	return func(t UnixNano, amt int) UnixNano {
+		return UnixNano(t.Time().Add(frequency * time.Duration(amt)).UnixNano())
+	}
+}
+
 type Index interface {
 	comparable
 	constraints.Ordered
 }
 
-// IndexedSeries is a Series with a custom index type.
+// IndexedSeries is a Series with a custom index type. The indexes are kept in a sorted slice alongside the values, so the row of an index is found by binary search and rows are always in index order.
 type IndexedSeries[I Index] struct {
 	*SignalManager
 	series  *Series
-	indexes []I // Sorted slice of indexes.
-	index   map[I]int
+	indexes []I // Sorted slice of indexes. The index of row i is indexes[i].
 }
 
 // NewIndexedSeries returns a new IndexedSeries with the given name and index type.
@@ -58,20 +96,22 @@ func NewIndexedSeries[I Index, V any](name string, vals map[I]V) *IndexedSeries[
 	out := &IndexedSeries[I]{
 		&SignalManager{},
 		NewSeries(name),
-		make([]I, 0),
-		make(map[I]int),
+		make([]I, 0, len(vals)),
 	}
+	indexes := make([]I, 0, len(vals))
+	values := make([]any, 0, len(vals))
 	for index, val := range vals {
-		out.Insert(index, val)
+		indexes = append(indexes, index)
+		values = append(values, val)
 	}
-	return out
+	return out.InsertMany(indexes, values)
 }
 
 // Add adds the values of the other series to the values of this series. The other series must have the same index type. The values are added by comparing their indexes. For example, adding two IndexedSeries that share no indexes will result in no change of values.
 func (s *IndexedSeries[I]) Add(other *IndexedSeries[I]) *IndexedSeries[I] {
 	// For each index in self, add the corresponding value of the other series.
-	for index, row := range s.index {
-		if otherRow, ok := other.index[index]; ok {
+	for row, index := range s.indexes {
+		if otherRow := other.Row(index); otherRow >= 0 {
 			val, err := anymath.Add(s.series.Value(row), other.series.Value(otherRow))
 			if err != nil {
 				panic(fmt.Errorf("error adding values at index %v: %w", index, err))
@@ -83,7 +123,7 @@ func (s *IndexedSeries[I]) Add(other *IndexedSeries[I]) *IndexedSeries[I] {
 }
 
 func (s *IndexedSeries[I]) AddFloat(num float64) *IndexedSeries[I] {
-	for index, row := range s.index {
+	for row, index := range s.indexes {
 		newValue, err := anymath.Add(s.series.Value(row), num)
 		if err != nil {
 			panic(fmt.Errorf("error adding values at index %v: %w", index, err))
@@ -109,22 +149,43 @@ func (s *IndexedSeries[I]) CopyRange(start, count int) *IndexedSeries[I] {
 	// Copy the index values over.
 	indexes := make([]I, count)
 	copy(indexes, s.indexes[start:end])
-	index := make(map[I]int, count)
-	for i, _index := range indexes {
-		index[_index] = i
-	}
 	return &IndexedSeries[I]{
 		&SignalManager{},
 		s.series.CopyRange(start, count),
 		indexes,
-		index,
+	}
+}
+
+// Between returns a copy of the rows whose index is from start to end, inclusive. The bounds are found by binary search on the sorted indexes, so it is O(log n) plus the rows copied. For example, with a UnixTime index it returns the values of a session or of the window around an event.
+func (s *IndexedSeries[I]) Between(start, end I) *IndexedSeries[I] {
+	from, to := s.rowsBetween(start, end)
+	return s.CopyRange(from, to-from)
+}
+
+// rowsBetween returns the first row with an index of at least start and the row after the last index of at most end. They are the same if no index is between start and end.
+func (s *IndexedSeries[I]) rowsBetween(start, end I) (from, to int) {
+	from, _ = slices.BinarySearch(s.indexes, start)
+	to, found := slices.BinarySearch(s.indexes, end)
+	if found {
+		to++
+	}
+	return from, Max(from, to)
+}
+
+// View returns an IndexedSeries of the rows in the given range that shares the values and indexes of this series instead of copying them, like Series.View. The view should be treated as read-only.
+func (s *IndexedSeries[I]) View(start, count int) *IndexedSeries[I] {
+	start, end := s.series.Range(start, count)
+	return &IndexedSeries[I]{
+		&SignalManager{},
+		s.series.View(start, end-start),
+		s.indexes[start:end:end],
 	}
 }
 
 // Div divides this series values with the other series values. The other series must have the same index type. The values are divided by comparing their indexes. For example, dividing two IndexedSeries that share no indexes will result in no change of values.
 func (s *IndexedSeries[I]) Div(other *IndexedSeries[I]) *IndexedSeries[I] {
-	for index, row := range s.index {
-		if otherRow, ok := other.index[index]; ok {
+	for row, index := range s.indexes {
+		if otherRow := other.Row(index); otherRow >= 0 {
 			val, err := anymath.Divide(s.series.Value(row), other.series.Value(otherRow))
 			if err != nil {
 				panic(fmt.Errorf("error dividing values at index %v: %w", index, err))
@@ -136,7 +197,7 @@ func (s *IndexedSeries[I]) Div(other *IndexedSeries[I]) *IndexedSeries[I] {
 }
 
 func (s *IndexedSeries[I]) DivFloat(num float64) *IndexedSeries[I] {
-	for index, row := range s.index {
+	for row, index := range s.indexes {
 		newValue, err := anymath.Divide(s.series.Value(row), num)
 		if err != nil {
 			panic(fmt.Errorf("error dividing values at index %v: %w", index, err))
@@ -179,11 +240,34 @@ func (s *IndexedSeries[I]) Index(row int) *I {
 	return &s.indexes[row]
 }
 
+// IndexOfMax returns the index of the highest value of the series, and false if there are no numbers. The earliest index is returned if several share the highest value.
+//
+// Will work with all signed int and float types. Ignores all other values.
+func (s *IndexedSeries[I]) IndexOfMax() (I, bool) {
+	return s.indexOf(s.series.IdxMax())
+}
+
+// IndexOfMin returns the index of the lowest value of the series, and false if there are no numbers. The earliest index is returned if several share the lowest value.
+//
+// Will work with all signed int and float types. Ignores all other values.
+func (s *IndexedSeries[I]) IndexOfMin() (I, bool) {
+	return s.indexOf(s.series.IdxMin())
+}
+
+// indexOf returns the index of row, and false if row is -1.
+func (s *IndexedSeries[I]) indexOf(row int) (I, bool) {
+	if row < 0 {
+		var zero I
+		return zero, false
+	}
+	return s.indexes[row], true
+}
+
 // Row returns the row of the given index or -1 if the index does not exist.
 //
-// The performance of this operation is O(1).
+// The performance of this operation is O(log n) where n is the number of rows in the series.
 func (s *IndexedSeries[I]) Row(index I) int {
-	if i, ok := s.index[index]; ok {
+	if i, found := slices.BinarySearch(s.indexes, index); found {
 		return i
 	}
 	return -1
@@ -212,8 +296,8 @@ func (s *IndexedSeries[I]) MapReverse(f func(index I, row int, val any) any) *In
 
 // Mul multiplies this series values with the other series values. The other series must have the same index type. The values are multiplied by comparing their indexes. For example, multiplying two IndexedSeries that share no indexes will result in no change of values.
 func (s *IndexedSeries[I]) Mul(other *IndexedSeries[I]) *IndexedSeries[I] {
-	for index, row := range s.index {
-		if otherRow, ok := other.index[index]; ok {
+	for row, index := range s.indexes {
+		if otherRow := other.Row(index); otherRow >= 0 {
 			val, err := anymath.Multiply(s.series.Value(row), other.series.Value(otherRow))
 			if err != nil {
 				panic(fmt.Errorf("error multiplying values at index %v: %w", index, err))
@@ -225,7 +309,7 @@ func (s *IndexedSeries[I]) Mul(other *IndexedSeries[I]) *IndexedSeries[I] {
 }
 
 func (s *IndexedSeries[I]) MulFloat(num float64) *IndexedSeries[I] {
-	for index, row := range s.index {
+	for row, index := range s.indexes {
 		newValue, err := anymath.Multiply(s.series.Value(row), num)
 		if err != nil {
 			panic(fmt.Errorf("error multiplying values at index %v: %w", index, err))
@@ -240,24 +324,18 @@ func (s *IndexedSeries[I]) Name() string {
 	return s.series.Name()
 }
 
-// insertIndex will insert the provided index somewhere in the sorted slice of indexes. If the index already exists, the existing index will be returned.
+// insertIndex will insert the provided index somewhere in the sorted slice of indexes. If the index already exists, the existing row will be returned.
 func (s *IndexedSeries[I]) insertIndex(index I) (row int, exists bool) {
-	// Sort the indexes.
+	// Check if we're just appending the index, which is the common case of pushing the latest candle.
+	if n := len(s.indexes); n == 0 || s.indexes[n-1] < index {
+		s.indexes = append(s.indexes, index)
+		return n, false
+	}
 	idx, found := slices.BinarySearch(s.indexes, index)
 	if found {
 		return idx, true
 	}
-	s.index[index] = idx // Create the index to row mapping.
-	// Check if we're just appending the index. Just an optimization.
-	if idx >= len(s.indexes) {
-		s.indexes = append(s.indexes, index) // Append the index to our sorted slice of indexes.
-		return idx, false
-	}
 	s.indexes = slices.Insert(s.indexes, idx, index)
-	// Shift the row values of all indexes after the inserted index.
-	for i := idx + 1; i < len(s.indexes); i++ {
-		s.index[s.indexes[i]]++
-	}
 	return idx, false
 }
 
@@ -272,49 +350,127 @@ func (s *IndexedSeries[I]) Insert(index I, val any) *IndexedSeries[I] {
 	return s
 }
 
+// PushMany appends vals at indexes, which must be the same length, and emits a single LengthChanged signal, so large datasets can be built without a binary search and a signal for every row. It is O(k) when indexes are sorted and all come after the last index of the series, like a batch of the latest candles, and otherwise falls back to InsertMany.
+func (s *IndexedSeries[I]) PushMany(indexes []I, vals []any) *IndexedSeries[I] {
+	if len(indexes) != len(vals) {
+		panic(fmt.Sprintf("autotrader: expected %d values for %d indexes, got %d", len(indexes), len(indexes), len(vals)))
+	}
+	for i, index := range indexes {
+		if (i == 0 && len(s.indexes) > 0 && index <= s.indexes[len(s.indexes)-1]) || (i > 0 && index <= indexes[i-1]) {
+			return s.InsertMany(indexes, vals)
+		}
+	}
+	s.indexes = append(s.indexes, indexes...)
+	s.series.PushMany(vals...)
+	return s
+}
+
+// InsertMany adds vals at indexes, which must be the same length and may be in any order, and emits a single LengthChanged signal. Like Insert, the value of an index that already exists is overwritten, and the last of repeated indexes wins. The rows are merged in a single pass, so the operation is O(n + k log k) instead of the O(n·k) of calling Insert for each row.
+func (s *IndexedSeries[I]) InsertMany(indexes []I, vals []any) *IndexedSeries[I] {
+	if len(indexes) != len(vals) {
+		panic(fmt.Sprintf("autotrader: expected %d values for %d indexes, got %d", len(indexes), len(indexes), len(vals)))
+	}
+	if len(indexes) == 0 {
+		return s
+	}
+	order := make([]int, len(indexes))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) bool { return indexes[a] < indexes[b] })
+
+	mergedIndexes := make([]I, 0, len(s.indexes)+len(indexes))
+	mergedData := make([]any, 0, len(s.indexes)+len(indexes))
+	var overwritten []int // The merged rows of existing indexes, which emit ValueChanged like Insert.
+	row := 0
+	for k := 0; k < len(order); k++ {
+		index := indexes[order[k]]
+		for k+1 < len(order) && indexes[order[k+1]] == index {
+			k++ // The last of repeated indexes wins.
+		}
+		for row < len(s.indexes) && s.indexes[row] < index {
+			mergedIndexes = append(mergedIndexes, s.indexes[row])
+			mergedData = append(mergedData, s.series.data[row])
+			row++
+		}
+		if row < len(s.indexes) && s.indexes[row] == index {
+			overwritten = append(overwritten, len(mergedIndexes))
+			row++
+		}
+		mergedIndexes = append(mergedIndexes, index)
+		mergedData = append(mergedData, vals[order[k]])
+	}
+	mergedIndexes = append(mergedIndexes, s.indexes[row:]...)
+	mergedData = append(mergedData, s.series.data[row:]...)
+
+	grew := len(mergedIndexes) != len(s.indexes)
+	s.indexes = mergedIndexes
+	s.series.data = mergedData
+	if grew {
+		s.series.SignalEmit("LengthChanged", s.series.Len())
+	}
+	for _, row := range overwritten {
+		s.series.SignalEmit("ValueChanged", row, s.series.data[row])
+	}
+	return s
+}
+
 // Remove deletes the row at the given index and returns it.
 func (s *IndexedSeries[I]) Remove(index I) any {
-	row, ok := s.index[index]
-	if !ok {
+	row := s.Row(index)
+	if row < 0 {
 		return nil
 	}
-	delete(s.index, index)
-	// Shift each index after the removed index down by one.
-	for key, j := range s.index {
-		if j > row {
-			s.index[key] = j - 1
-		}
-	}
+	s.indexes = removeRows(s.indexes, row, row+1)
 	// Remove the value from the series.
 	return s.series.Remove(row)
 }
 
 // RemoveRange deletes the rows in the given range and returns the series.
 //
-// The operation is O(n) where n is the number of rows in the series.
+// Only the rows on the shorter side of the range are moved, so the operation is O(count) when the range is at either end of the series, like when the oldest candles of a live buffer are trimmed, and O(n) at worst.
 func (s *IndexedSeries[I]) RemoveRange(start, count int) *IndexedSeries[I] {
 	start, end := s.series.Range(start, count)
 	if start == end {
 		return s
 	}
-	count = end - start
-	// Remove the indexes from the map.
-	for index, i := range s.index {
-		if i >= start && i < end {
-			idx := slices.Index(s.indexes, index)
-			slices.Delete(s.indexes, idx, idx+1)
-			delete(s.index, index)
+	s.indexes = removeRows(s.indexes, start, end)
+	// Remove the values from the series.
+	_ = s.series.RemoveRange(start, end-start)
+	return s
+}
+
+// RemoveIndexes deletes the rows of every one of indexes that exists in a single pass over the series, instead of moving the rows once for each index like Remove, and returns the number of rows deleted.
+//
+// The operation is O(n + k log k) where k is the number of indexes.
+func (s *IndexedSeries[I]) RemoveIndexes(indexes ...I) int {
+	if len(indexes) == 0 {
+		return 0
+	}
+	remove := slices.Clone(indexes)
+	slices.Sort(remove)
+	kept := 0
+	for row, index := range s.indexes {
+		if _, found := slices.BinarySearch(remove, index); found {
+			continue
 		}
+		s.indexes[kept] = index
+		s.series.data[kept] = s.series.data[row]
+		kept++
 	}
-	// Shift each index after the removed index down by count.
-	for key, i := range s.index {
-		if i >= end {
-			s.index[key] = i - count
-		}
+	removed := len(s.indexes) - kept
+	if removed == 0 {
+		return 0
 	}
-	// Remove the values from the series.
-	_ = s.series.RemoveRange(start, count)
-	return s
+	var zero I
+	for row := kept; row < len(s.indexes); row++ {
+		s.indexes[row] = zero
+		s.series.data[row] = nil
+	}
+	s.indexes = s.indexes[:kept]
+	s.series.data = s.series.data[:kept]
+	s.series.SignalEmit("LengthChanged", s.series.Len())
+	return removed
 }
 
 // Reverse reverses the rows of the series.
@@ -324,12 +480,35 @@ func (s *IndexedSeries[I]) Reverse() *IndexedSeries[I] {
 	return s
 }
 
+// ZScore maps each value to the number of standard deviations it is from the mean of the series. See Series.ZScore.
+func (s *IndexedSeries[I]) ZScore() *IndexedSeries[I] {
+	_ = s.series.ZScore()
+	return s
+}
+
+// Standardize maps each value to (value - mean) / stdDev. See Series.Standardize.
+func (s *IndexedSeries[I]) Standardize(mean, stdDev float64) *IndexedSeries[I] {
+	_ = s.series.Standardize(mean, stdDev)
+	return s
+}
+
+// Normalize maps each value to where it is between the minimum and maximum of the series, from 0 to 1. See Series.Normalize.
+func (s *IndexedSeries[I]) Normalize() *IndexedSeries[I] {
+	_ = s.series.Normalize()
+	return s
+}
+
 func (s *IndexedSeries[I]) Rolling(period int) *IndexedRollingSeries[I] {
 	return NewIndexedRollingSeries(s, period)
 }
 
+// SetName sets the name of the series to name and emits a NameChanged signal.
 func (s *IndexedSeries[I]) SetName(name string) *IndexedSeries[I] {
+	if name == s.Name() {
+		return s
+	}
 	_ = s.series.SetName(name)
+	s.SignalEmit("NameChanged", name)
 	return s
 }
 
@@ -356,23 +535,10 @@ func (s *IndexedSeries[I]) ShiftIndex(periods int, step func(prev I, amt int) I)
 	if periods == 0 {
 		return s
 	}
-	// Update the index values.
-	for index, i := range s.index {
-		s.indexes[i] = step(index, periods)
-	}
-
-	// Reassign the index map.
-	maps.Clear(s.index)
+	// Update the index values. The step must keep the indexes in order.
 	for i, index := range s.indexes {
-		s.index[index] = i
-	}
-
-	// Shift the indexes.
-	newIndexes := make(map[I]int, len(s.index))
-	for index, i := range s.index {
-		newIndexes[step(index, periods)] = i
+		s.indexes[i] = step(index, periods)
 	}
-	s.index = newIndexes
 	return s
 }
 
@@ -395,8 +561,8 @@ func (s *IndexedSeries[I]) String() string {
 
 // Sub subtracts the other series values from this series values. The other series must have the same index type. The values are subtracted by comparing their indexes. For example, subtracting two IndexedSeries that share no indexes will result in no change of values.
 func (s *IndexedSeries[I]) Sub(other *IndexedSeries[I]) *IndexedSeries[I] {
-	for index, row := range s.index {
-		if otherRow, ok := other.index[index]; ok {
+	for row, index := range s.indexes {
+		if otherRow := other.Row(index); otherRow >= 0 {
 			val, err := anymath.Divide(s.series.Value(row), other.series.Value(otherRow))
 			if err != nil {
 				panic(fmt.Errorf("error subtracting values at index %v: %w", index, err))
@@ -408,7 +574,7 @@ func (s *IndexedSeries[I]) Sub(other *IndexedSeries[I]) *IndexedSeries[I] {
 }
 
 func (s *IndexedSeries[I]) SubFloat(num float64) *IndexedSeries[I] {
-	for index, row := range s.index {
+	for row, index := range s.indexes {
 		newValue, err := anymath.Subtract(s.series.Value(row), num)
 		if err != nil {
 			panic(fmt.Errorf("error subtracting values at index %v: %w", index, err))
@@ -489,3 +655,23 @@ func (s *IndexedRollingSeries[I]) StdDev() *IndexedSeries[I] {
 	_ = s.rolling.StdDev() // Mutate the underlying series.
 	return s.series
 }
+
+func (s *IndexedRollingSeries[I]) Quantile(q float64) *IndexedSeries[I] {
+	_ = s.rolling.Quantile(q) // Mutate the underlying series.
+	return s.series
+}
+
+func (s *IndexedRollingSeries[I]) PercentRank() *IndexedSeries[I] {
+	_ = s.rolling.PercentRank() // Mutate the underlying series.
+	return s.series
+}
+
+func (s *IndexedRollingSeries[I]) ZScore() *IndexedSeries[I] {
+	_ = s.rolling.ZScore() // Mutate the underlying series.
+	return s.series
+}
+
+func (s *IndexedRollingSeries[I]) Normalize() *IndexedSeries[I] {
+	_ = s.rolling.Normalize() // Mutate the underlying series.
+	return s.series
+}
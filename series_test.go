@@ -4,6 +4,8 @@ import (
 	"math"
 	"testing"
 	"time"
+
+	"golang.org/x/exp/slices"
 )
 
 func TestSeries(t *testing.T) {
@@ -121,6 +123,98 @@ func TestSeriesFunctional(t *testing.T) {
 	}
 }
 
+func TestRollingQuantile(t *testing.T) {
+	series := NewSeries("test", 5.0, 1, 4.0, 2.0, 3.0, "skipped", 10.0)
+	median := series.Copy().Rolling(5).Quantile(0.5)
+	medianExpected := []float64{5, 3, 4, 3, 3, 2.5, 3.5}
+	for i, expected := range medianExpected {
+		if val := median.Float(i); !EqualApprox(val, expected) {
+			t.Errorf("(%d)\tExpected median %f, got %v", i, expected, val)
+		}
+	}
+	top := series.Copy().Rolling(5).Quantile(0.9)
+	if val := top.Float(4); !EqualApprox(val, 4.6) { // 90% of the way from the 4th to the 5th of 1, 2, 3, 4, 5.
+		t.Errorf("Expected the 0.9 quantile to be 4.6, got %v", val)
+	}
+	if val := top.Float(0); !EqualApprox(val, 5) {
+		t.Errorf("Expected the quantile of a single value to be itself, got %v", val)
+	}
+
+	indexed := NewIndexedSeries("test", map[UnixTime]float64{1: 1, 2: 2, 3: 3})
+	if val := indexed.Rolling(3).Quantile(1).Float(-1); val != 3 {
+		t.Errorf("Expected the 1 quantile to be the maximum of 3, got %v", val)
+	}
+}
+
+func TestRollingPercentRank(t *testing.T) {
+	series := NewSeries("test", 1.0, 3.0, 2.0, 5.0, 4.0, 4.0, "skipped")
+	rank := series.Copy().Rolling(4).PercentRank()
+	rankExpected := []float64{0, 100, 50, 100, 200.0 / 3, 200.0 / 3, 0}
+	for i, expected := range rankExpected {
+		if val := rank.Float(i); !EqualApprox(val, expected) {
+			t.Errorf("(%d)\tExpected percent rank %f, got %v", i, expected, val)
+		}
+	}
+}
+
+func TestRollingStdDev(t *testing.T) {
+	series := NewSeries("test", 1.0, 3.0, 2.0, 6.0, 6.0)
+	stdDev := series.Copy().Rolling(2).StdDev()
+	for i, expected := range []float64{0, 1, 0.5, 2, 0} {
+		if val := stdDev.Float(i); !EqualApprox(val, expected) {
+			t.Errorf("(%d)\tExpected standard deviation %f, got %v", i, expected, val)
+		}
+	}
+}
+
+func TestZScore(t *testing.T) {
+	series := NewSeries("test", 2.0, 4, 4.0, 4.0, 5.0, 5.0, 7.0, 9.0, "skipped") // Mean of 5 and standard deviation of 2.
+	zscore := series.Copy().ZScore()
+	zscoreExpected := []float64{-1.5, -0.5, -0.5, -0.5, 0, 0, 1, 2}
+	for i, expected := range zscoreExpected {
+		if val := zscore.Float(i); !EqualApprox(val, expected) {
+			t.Errorf("(%d)\tExpected z-score %f, got %v", i, expected, val)
+		}
+	}
+	if val := zscore.Value(-1); val != "skipped" {
+		t.Errorf("Expected values that are not numbers to be left unchanged, got %v", val)
+	}
+
+	standardized := NewFloatSeries("test", 1, 3).Standardize(5, 2)
+	if standardized.Value(0) != -2 || standardized.Value(1) != -1 {
+		t.Errorf("Expected standardizing by a mean of 5 and standard deviation of 2 to give -2 and -1, got %v", standardized.Values())
+	}
+	if constant := NewFloatSeries("test", 3, 3).ZScore(); constant.Value(0) != 0 {
+		t.Errorf("Expected the z-score of a constant series to be 0, got %v", constant.Value(0))
+	}
+
+	rolling := NewSeries("test", 1.0, 2.0, 3.0, 3.0, 3.0).Rolling(3).ZScore()
+	rollingExpected := []float64{0, 1, 1.224744871391589, 0.7071067811865475, 0}
+	for i, expected := range rollingExpected {
+		if val := rolling.Float(i); !EqualApprox(val, expected) {
+			t.Errorf("(%d)\tExpected rolling z-score %f, got %v", i, expected, val)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	normalized := NewIndexedSeries("test", map[UnixTime]float64{1: 10, 2: 20, 3: 15, 4: 30}).Normalize()
+	normalizedExpected := []float64{0, 0.5, 0.25, 1}
+	for i, expected := range normalizedExpected {
+		if val := normalized.Float(i); !EqualApprox(val, expected) {
+			t.Errorf("(%d)\tExpected normalized value %f, got %v", i, expected, val)
+		}
+	}
+
+	rolling := NewSeries("test", 10.0, 20.0, 15.0, 30.0, 30.0).Rolling(2).Normalize()
+	rollingExpected := []float64{0, 1, 0, 1, 0}
+	for i, expected := range rollingExpected {
+		if val := rolling.Float(i); !EqualApprox(val, expected) {
+			t.Errorf("(%d)\tExpected rolling normalized value %f, got %v", i, expected, val)
+		}
+	}
+}
+
 func TestRollingSeries(t *testing.T) {
 	// Test rolling average.
 	series := NewSeries("test", 1.0, 2.0, 3.0, 4.0, 5.0, 6.0, 7.0, 8.0, 9.0, 10.0)
@@ -174,6 +268,32 @@ func TestRollingSeries(t *testing.T) {
 	}
 }
 
+func TestSubSecondIndexes(t *testing.T) {
+	start := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+	data := NewDOHLCVIndexedFrame[UnixMilli]()
+	step := UnixMilliStep(250 * time.Millisecond)
+	for i := 0; i < 4; i++ {
+		data.PushCandle(step(UnixMilli(start.UnixMilli()), i), 1, 1, 1, float64(i), 1)
+	}
+	if data.Len() != 4 {
+		t.Fatalf("Expected 4 sub-second candles, got %d", data.Len())
+	}
+	if date := data.Date(-1).Time(); !date.Equal(start.Add(750 * time.Millisecond)) {
+		t.Errorf("Expected the last candle at %v, got %v", start.Add(750*time.Millisecond), date)
+	}
+	if s := data.Date(1).String(); s != "2022-01-01 12:00:00.25 +0000 UTC" {
+		t.Errorf("Expected the index to print milliseconds, got %q", s)
+	}
+
+	tick := UnixNano(start.UnixNano())
+	if next := UnixNanoStep(time.Microsecond)(tick, 3); next.Time().Sub(tick.Time()) != 3*time.Microsecond {
+		t.Errorf("Expected a step of 3 microseconds, got %v", next.Time().Sub(tick.Time()))
+	}
+	if layout := frequencyDateLayout("MS100"); layout != "15:04:05.000" {
+		t.Errorf("Expected a millisecond date layout, got %q", layout)
+	}
+}
+
 func TestIndexedSeriesInsert(t *testing.T) {
 	indexed := NewIndexedSeries("test", map[UnixTime]float64{
 		UnixTime(0):  1.0,
@@ -206,6 +326,128 @@ func TestIndexedSeriesInsert(t *testing.T) {
 	}
 }
 
+func TestSeriesInsertMany(t *testing.T) {
+	series := NewSeries("test", 1.0, 4.0)
+	var lengthChanges int
+	series.SignalConnect("LengthChanged", t, func(...any) { lengthChanges++ })
+
+	series.Insert(1, 2.0, 3.0).PushMany(5.0, 6.0).Insert(-2, 5.5).Insert(100, 7.0).Insert(0)
+	expected := []any{1.0, 2.0, 3.0, 4.0, 5.0, 5.5, 6.0, 7.0}
+	if !slices.Equal(series.Values(), expected) {
+		t.Errorf("Expected %v, got %v", expected, series.Values())
+	}
+	if lengthChanges != 4 {
+		t.Errorf("Expected a LengthChanged signal for each call that added values, got %d", lengthChanges)
+	}
+}
+
+func TestIndexedSeriesInsertMany(t *testing.T) {
+	indexed := NewIndexedSeries[int, any]("test", nil)
+	var lengthChanges, valueChanges int
+	indexed.series.SignalConnect("LengthChanged", t, func(...any) { lengthChanges++ })
+	indexed.series.SignalConnect("ValueChanged", t, func(...any) { valueChanges++ })
+
+	indexed.PushMany([]int{2, 4, 6}, []any{2.0, 4.0, 6.0})
+	indexed.PushMany([]int{8, 10}, []any{8.0, 10.0})
+	indexed.PushMany([]int{7, 1}, []any{7.0, 1.0}) // Out of order falls back to InsertMany.
+	indexed.InsertMany([]int{5, 0, 4, 5}, []any{-5.0, 0.0, -4.0, 5.0})
+	expected := map[int]float64{0: 0, 1: 1, 2: 2, 4: -4, 5: 5, 6: 6, 7: 7, 8: 8, 10: 10}
+	if indexed.Len() != len(expected) {
+		t.Fatalf("Expected %d rows, got %d", len(expected), indexed.Len())
+	}
+	for row := 0; row < indexed.Len(); row++ {
+		index := *indexed.Index(row)
+		if row > 0 && *indexed.Index(row - 1) >= index {
+			t.Errorf("Expected sorted indexes, got %d after %d", index, *indexed.Index(row - 1))
+		}
+		if indexed.Float(row) != expected[index] || indexed.Row(index) != row {
+			t.Errorf("Expected index %d to have value %v, got %v", index, expected[index], indexed.Float(row))
+		}
+	}
+	if lengthChanges != 4 || valueChanges != 1 {
+		t.Errorf("Expected 4 LengthChanged and 1 ValueChanged signals, got %d and %d", lengthChanges, valueChanges)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic when the indexes and values differ in length")
+		}
+	}()
+	indexed.PushMany([]int{11, 12}, []any{11.0})
+}
+
+func TestIndexedSeriesRemove(t *testing.T) {
+	indexed := NewIndexedSeries[int, any]("test", nil)
+	for _, i := range []int{8, 2, 6, 0, 4, 10} { // Insert out of order.
+		indexed.Insert(i, float64(i))
+	}
+	for row := 0; row < indexed.Len(); row++ {
+		if index := *indexed.Index(row); index != row*2 || indexed.Float(row) != float64(index) {
+			t.Fatalf("Expected row %d to have index %d, got index %d with value %v", row, row*2, index, indexed.Float(row))
+		}
+	}
+
+	if val := indexed.Remove(4); val != 4.0 {
+		t.Errorf("Expected removed value 4.0, got %v", val)
+	}
+	if indexed.Len() != 5 || indexed.Row(6) != 2 || indexed.ValueIndex(6) != 6.0 {
+		t.Errorf("Expected index 6 to move to row 2, got row %d", indexed.Row(6))
+	}
+	if indexed.Remove(4) != nil {
+		t.Error("Expected nil when removing a missing index")
+	}
+
+	indexed.RemoveRange(1, 2) // Remove indexes 2 and 6.
+	if indexed.Len() != 3 || indexed.Row(2) != -1 || indexed.Row(8) != 1 || indexed.ValueIndex(10) != 10.0 {
+		t.Errorf("Expected indexes 0, 8, and 10 to remain, got %v", indexed)
+	}
+
+	indexed.ShiftIndex(1, func(prev int, amt int) int { return prev + amt })
+	if indexed.Row(9) != 1 || indexed.ValueIndex(11) != 10.0 || indexed.Row(10) != -1 {
+		t.Errorf("Expected shifted indexes to be found by row, got %v", indexed)
+	}
+}
+
+func TestIndexedSeriesRemoveRange(t *testing.T) {
+	newIndexed := func() *IndexedSeries[int] {
+		indexed := NewIndexedSeries[int, any]("test", nil)
+		for i := 0; i < 10; i++ {
+			indexed.Insert(i, float64(i))
+		}
+		return indexed
+	}
+	expect := func(indexed *IndexedSeries[int], indexes ...int) {
+		t.Helper()
+		if indexed.Len() != len(indexes) {
+			t.Fatalf("Expected %d rows, got %d", len(indexes), indexed.Len())
+		}
+		for row, index := range indexes {
+			if *indexed.Index(row) != index || indexed.Float(row) != float64(index) || indexed.Row(index) != row {
+				t.Errorf("Expected row %d to have index %d, got index %d with value %v", row, index, *indexed.Index(row), indexed.Float(row))
+			}
+		}
+	}
+
+	expect(newIndexed().RemoveRange(0, 3), 3, 4, 5, 6, 7, 8, 9)
+	expect(newIndexed().RemoveRange(2, 2), 0, 1, 4, 5, 6, 7, 8, 9) // Moves the front.
+	expect(newIndexed().RemoveRange(6, 2), 0, 1, 2, 3, 4, 5, 8, 9) // Moves the back.
+	expect(newIndexed().RemoveRange(-3, 3), 0, 1, 2, 3, 4, 5, 6)
+
+	// Trimming the front keeps the rows appendable.
+	indexed := newIndexed().RemoveRange(0, 8)
+	indexed.Insert(10, 10.0)
+	expect(indexed, 8, 9, 10)
+
+	indexed = newIndexed()
+	if removed := indexed.RemoveIndexes(7, 1, 1, 12, 4); removed != 3 {
+		t.Errorf("Expected 3 rows removed, got %d", removed)
+	}
+	expect(indexed, 0, 2, 3, 5, 6, 8, 9)
+	if removed := indexed.RemoveIndexes(); removed != 0 {
+		t.Errorf("Expected no rows removed, got %d", removed)
+	}
+}
+
 func TestIndexedSeries(t *testing.T) {
 	intIndexed := NewIndexedSeries("test", map[int]float64{
 		0:  1.0,
@@ -290,3 +532,110 @@ func TestIndexedOperations(t *testing.T) {
 		}
 	}
 }
+
+func TestSeriesScalar(t *testing.T) {
+	series := NewSeries("test", 1.0, 2.0, "skipped", 4.0)
+	series.AddFloat(1).MulFloat(3).SubFloat(2).DivFloat(2) // ((x + 1) * 3 - 2) / 2
+	for i, expected := range []any{2.0, 3.5, "skipped", 6.5} {
+		if val := series.Value(i); val != expected {
+			t.Errorf("(%d)\tExpected %v, got %v", i, expected, val)
+		}
+	}
+
+	floats := NewFloatSeries("test", 1, 2, 3).MulFloat(2).AddFloat(0.5)
+	if floats.Value(2) != 6.5 {
+		t.Errorf("Expected 6.5, got %v", floats.Value(2))
+	}
+
+	for _, test := range []struct {
+		name     string
+		got      *Series
+		expected []bool
+	}{
+		{"Gt", series.Gt(3.5), []bool{false, false, false, true}},
+		{"Ge", series.Ge(3.5), []bool{false, true, false, true}},
+		{"Lt", series.Lt(3.5), []bool{true, false, false, false}},
+		{"Le", floats.Le(4.5), []bool{true, true, false}},
+	} {
+		if test.got.Len() != len(test.expected) {
+			t.Fatalf("%s: expected length %d, got %d", test.name, len(test.expected), test.got.Len())
+		}
+		for i, expected := range test.expected {
+			if val := test.got.Value(i); val != expected {
+				t.Errorf("%s(%d): expected %v, got %v", test.name, i, expected, val)
+			}
+		}
+	}
+	if series.Value(0) != 2.0 {
+		t.Error("Expected comparisons to leave the series unchanged")
+	}
+}
+
+func TestSeriesSummary(t *testing.T) {
+	series := NewSeries("Test", 4.0, 1, "skipped", 3.0, nil, 2.0)
+	if sum := series.Sum(); sum != 10 {
+		t.Errorf("Expected sum 10, got %f", sum)
+	}
+	if mean := series.Mean(); mean != 2.5 {
+		t.Errorf("Expected mean 2.5, got %f", mean)
+	}
+	if median := series.Median(); median != 2.5 {
+		t.Errorf("Expected median 2.5, got %f", median)
+	}
+	if stdDev := series.StdDev(); !EqualApprox(stdDev, math.Sqrt(1.25)) {
+		t.Errorf("Expected standard deviation %f, got %f", math.Sqrt(1.25), stdDev)
+	}
+	if q := series.Quantile(0.9); !EqualApprox(q, 3.7) {
+		t.Errorf("Expected 0.9 quantile 3.7, got %f", q)
+	}
+
+	summary := NewFloatSeries("Test", 5, 1, 3).Describe()
+	expected := SeriesSummary{Count: 3, Mean: 3, StdDev: math.Sqrt(8.0 / 3), Min: 1, Q1: 2, Median: 3, Q3: 4, Max: 5}
+	if summary != expected {
+		t.Errorf("Expected %+v, got %+v", expected, summary)
+	}
+	if empty := NewFloatSeries("Empty").Describe(); empty != (SeriesSummary{}) {
+		t.Errorf("Expected an empty summary, got %+v", empty)
+	}
+}
+
+func TestSeriesIdxMaxMin(t *testing.T) {
+	series := NewSeries("Test", "skipped", 2, 5.0, 1.0, 5, math.NaN(), 1)
+	if row := series.IdxMax(); row != 2 {
+		t.Errorf("Expected the first highest value at row 2, got %d", row)
+	}
+	if row := series.IdxMin(); row != 3 {
+		t.Errorf("Expected the first lowest value at row 3, got %d", row)
+	}
+	if row := NewSeries("Empty", "a").IdxMax(); row != -1 {
+		t.Errorf("Expected -1 without numbers, got %d", row)
+	}
+
+	indexed := NewIndexedSeries("Test", map[UnixTime]float64{300: 1.5, 100: 1.2, 200: 0.9})
+	if index, ok := indexed.IndexOfMax(); !ok || index != 300 {
+		t.Errorf("Expected the highest value at index 300, got %v", index)
+	}
+	if index, ok := indexed.IndexOfMin(); !ok || index != 200 {
+		t.Errorf("Expected the lowest value at index 200, got %v", index)
+	}
+	if _, ok := NewIndexedSeries[UnixTime, float64]("Empty", nil).IndexOfMin(); ok {
+		t.Error("Expected no index of an empty series")
+	}
+}
+
+func TestIndexedSeriesBetween(t *testing.T) {
+	series := NewIndexedSeries("Test", map[UnixTime]float64{100: 1, 200: 2, 300: 3, 400: 4})
+	between := series.Between(150, 300)
+	if between.Len() != 2 || *between.Index(0) != 200 || *between.Index(1) != 300 {
+		t.Errorf("Expected indexes 200 and 300, got %v", between.Values())
+	}
+	if between := series.Between(100, 100); between.Len() != 1 || between.Float(0) != 1 {
+		t.Errorf("Expected only the value at 100, got %v", between.Values())
+	}
+	if between := series.Between(401, 500); between.Len() != 0 {
+		t.Errorf("Expected no values after the last index, got %v", between.Values())
+	}
+	if between := series.Between(300, 200); between.Len() != 0 {
+		t.Errorf("Expected no values when the bounds are reversed, got %v", between.Values())
+	}
+}
@@ -0,0 +1,154 @@
+package autotrader
+
+import (
+	"errors"
+	"fmt"
+	"text/tabwriter"
+	"time"
+)
+
+// SetupStatus is the state of a Setup.
+type SetupStatus string
+
+const (
+	SetupPending   SetupStatus = "pending"   // SetupPending is a setup whose condition has not passed yet.
+	SetupTriggered SetupStatus = "triggered" // SetupTriggered is a setup whose condition passed and whose order was placed.
+	SetupExpired   SetupStatus = "expired"   // SetupExpired is a setup whose condition did not pass within its Expiry.
+	SetupCancelled SetupStatus = "cancelled" // SetupCancelled is a setup that was cancelled with Trader.CancelSetup.
+	SetupFailed    SetupStatus = "failed"    // SetupFailed is a setup whose condition passed but whose order failed, like when the RiskManager rejected it.
+)
+
+// Setup is a trade idea that a strategy hands to the Trader, like "buy if the price closes above 1.2 within 5 candles". The Trader checks the Condition on every candle after the setup was added and places the order once it passes, or expires the setup after Expiry candles. Setups wait while the Trader is paused or outside its TradingSchedule.
+type Setup struct {
+	Name      string     // Name describes the setup in the logs and the report, like "breakout".
+	Symbol    string     // Symbol is the symbol the condition is checked on and the order is placed for. It is the Symbol of the Trader if empty.
+	Condition ScreenFunc // Condition passes on the candles of Symbol when the order should be placed. See CloseAbove and CloseBelow.
+	Expiry    int        // Expiry is the number of candles the condition is checked on before the setup expires. Zero means the setup never expires.
+
+	// The order placed when the condition passes. See Trader.OrderSymbol.
+	OrderType  OrderType
+	Units      float64
+	Price      float64
+	StopLoss   float64
+	TakeProfit float64
+	Options    []OrderOption
+
+	Status   SetupStatus
+	Created  time.Time // Created is the time of the Trader when the setup was added.
+	Resolved time.Time // Resolved is the time of the Trader when the setup stopped pending, or zero while it is pending.
+	Order    Order     // Order is the order placed when the setup triggered. It is nil in signals-only mode.
+	Err      error     // Err is the error placing the order of a failed setup.
+	checked  int       // checked is the number of candles the condition was checked on.
+}
+
+// Pending returns true if the condition of the setup has not passed and the setup has not expired or been cancelled.
+func (s *Setup) Pending() bool {
+	return s.Status == SetupPending
+}
+
+// CloseAbove passes when the last candle closed at or above price, like the breakout of a resistance level.
+func CloseAbove(price float64) ScreenFunc {
+	return func(candles *IndexedFrame[UnixTime]) bool {
+		return candles.Len() > 0 && candles.Close(-1) >= price
+	}
+}
+
+// CloseBelow passes when the last candle closed at or below price, like the breakdown of a support level.
+func CloseBelow(price float64) ScreenFunc {
+	return func(candles *IndexedFrame[UnixTime]) bool {
+		return candles.Len() > 0 && candles.Close(-1) <= price
+	}
+}
+
+// AddSetup hands setup to the Trader, which checks its condition from the next candle on. It returns setup, whose Status tells what became of it.
+func (t *Trader) AddSetup(setup *Setup) *Setup {
+	if setup.Symbol == "" {
+		setup.Symbol = t.Symbol
+	}
+	setup.Status, setup.Created, setup.checked = SetupPending, t.Now(), 0
+	t.setups = append(t.setups, setup)
+	t.stats.setups = append(t.stats.setups, setup)
+	return setup
+}
+
+// CancelSetup stops checking the condition of setup if it is pending.
+func (t *Trader) CancelSetup(setup *Setup) {
+	if setup.Pending() {
+		t.resolveSetup(setup, SetupCancelled)
+	}
+}
+
+// Setups returns the pending setups in the order they were added.
+func (t *Trader) Setups() []*Setup {
+	return t.setups
+}
+
+// checkSetups places the orders of the pending setups whose conditions pass on the latest candles and expires the setups that ran out of candles.
+func (t *Trader) checkSetups() {
+	for _, setup := range t.setups {
+		if !setup.Pending() {
+			continue
+		}
+		candles := t.SymbolData(setup.Symbol)
+		if candles == nil {
+			continue
+		}
+		setup.checked++
+		switch {
+		case setup.Condition(candles):
+			order, err := t.OrderSymbol(setup.Symbol, setup.OrderType, setup.Units, setup.Price, setup.StopLoss, setup.TakeProfit, setup.Options...)
+			setup.Order = order
+			if err != nil && !errors.Is(err, ErrSignalsOnly) {
+				setup.Err = err
+				t.resolveSetup(setup, SetupFailed)
+			} else {
+				t.resolveSetup(setup, SetupTriggered)
+			}
+		case setup.Expiry > 0 && setup.checked >= setup.Expiry:
+			t.resolveSetup(setup, SetupExpired)
+		}
+	}
+}
+
+// resolveSetup ends setup with status and forgets it.
+func (t *Trader) resolveSetup(setup *Setup, status SetupStatus) {
+	setup.Status, setup.Resolved = status, t.Now()
+	t.Log.Printf("Setup %q on %s %s", setup.Name, setup.Symbol, status)
+	for i, s := range t.setups {
+		if s == setup {
+			t.setups = append(t.setups[:i], t.setups[i+1:]...)
+			break
+		}
+	}
+}
+
+// Setups returns every setup added by the strategy in the order they were added, with what became of them.
+func (s *TraderStats) Setups() []*Setup {
+	return s.setups
+}
+
+// SetupsSection prints a table of the setups of the strategy and what became of them to Out. Nothing is printed if the strategy added no setups.
+var SetupsSection ReportSection = ReportSectionFunc(renderSetups)
+
+func renderSetups(ctx *ReportContext) error {
+	setups := ctx.Stats.Setups()
+	if len(setups) == 0 {
+		return nil
+	}
+	w := tabwriter.NewWriter(ctx.Out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Setups:")
+	fmt.Fprintln(w, "Name\tSymbol\tCreated\tStatus\tResolved\t")
+	for _, setup := range setups {
+		resolved := "-"
+		if !setup.Resolved.IsZero() {
+			resolved = setup.Resolved.Format(ctx.DateLayout)
+		}
+		status := string(setup.Status)
+		if setup.Err != nil {
+			status = fmt.Sprintf("%s: %v", status, setup.Err)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n", setup.Name, setup.Symbol, setup.Created.Format(ctx.DateLayout), status, resolved)
+	}
+	fmt.Fprintln(w)
+	return w.Flush()
+}
@@ -0,0 +1,59 @@
+package autotrader
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTraderSetups(t *testing.T) {
+	broker := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	var breakout, breakdown, cancelled, pending *Setup
+	strategy := &scriptedStrategy{actions: map[int]func(*Trader){
+		1: func(t *Trader) {
+			breakout = t.AddSetup(&Setup{Name: "breakout", Condition: CloseAbove(1.22), Expiry: 3, OrderType: Market, Units: 1000})
+			breakdown = t.AddSetup(&Setup{Name: "breakdown", Condition: CloseBelow(1.0), Expiry: 2, OrderType: Market, Units: -1000})
+			cancelled = t.AddSetup(&Setup{Name: "cancelled", Condition: CloseAbove(2), OrderType: Market, Units: 1000})
+		},
+		2: func(t *Trader) { t.CancelSetup(cancelled) },
+		4: func(t *Trader) {
+			pending = t.AddSetup(&Setup{Name: "moonshot", Condition: CloseAbove(5), OrderType: Market, Units: 1000})
+		},
+	}}
+	trader := NewTrader(TraderConfig{Broker: broker, Strategy: strategy, Symbol: "EUR_USD", Frequency: "D", CandlesToKeep: 5})
+	trader.Log.SetOutput(io.Discard)
+	trader.Init()
+	for !trader.EOF {
+		trader.Tick()
+		broker.Advance()
+	}
+
+	day := func(i int) time.Time { return time.Date(2022, 1, i, 0, 0, 0, 0, time.UTC) }
+	if breakout.Status != SetupTriggered || breakout.Order == nil || !breakout.Resolved.Equal(day(3)) {
+		t.Errorf("Expected the breakout to trigger on the close of 1.25, got %+v", breakout)
+	}
+	if breakdown.Status != SetupExpired || !breakdown.Resolved.Equal(day(3)) {
+		t.Errorf("Expected the breakdown to expire after 2 candles, got %+v", breakdown)
+	}
+	if cancelled.Status != SetupCancelled || pending.Status != SetupPending || !pending.Resolved.IsZero() {
+		t.Errorf("Expected a cancelled and a pending setup, got %s and %s", cancelled.Status, pending.Status)
+	}
+	if setups := trader.Setups(); len(setups) != 1 || setups[0] != pending {
+		t.Errorf("Expected only the pending setup to be checked, got %+v", setups)
+	}
+	if trades := trader.Stats().Trades(); len(trades) != 1 || trades[0].Units != 1000 {
+		t.Errorf("Expected the breakout to be the only trade, got %+v", trades)
+	}
+
+	if reply := (&TelegramBot{}).Command(trader, "/setups"); !strings.Contains(reply, "moonshot: 1000 EUR_USD") {
+		t.Errorf("Expected the pending setup to be listed, got %q", reply)
+	}
+	var out strings.Builder
+	if err := renderSetups(&ReportContext{Stats: trader.Stats(), Out: &out, DateLayout: time.DateOnly}); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 6 || !strings.Contains(lines[2], "triggered") || !strings.Contains(lines[5], "pending") {
+		t.Errorf("Expected a table of the 4 setups, got:\n%s", out.String())
+	}
+}
@@ -1,6 +1,17 @@
 package autotrader
 
-import "reflect"
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
 
 // Signaler is an interface for objects that can emit signals which fire event handlers. This is used to implement event-driven programming. Embed a pointer to a SignalManager in your struct to have signals entirely for free.
 //
@@ -31,33 +42,42 @@ type SignalHandler struct {
 	Bindings []any        // Bindings are arguments that are passed to the callback function when the signal is emitted. These are typically used to pass context.
 }
 
-// SignalManager is a struct that implements the Signaler interface. Embed this into your struct to have signals entirely for free. Emitting a signal will call all handlers connected to the signal, but if no handlers are connected then it is a no-op. This means signals are very cheap and only come at a cost when they're actually used.
+// SignalManager is a struct that implements the Signaler interface. Embed this into your struct to have signals entirely for free. Emitting a signal will call all handlers connected to the signal, but if no handlers are connected then it is a no-op. This means signals are very cheap and only come at a cost when they're actually used. It is safe to connect and disconnect handlers from one goroutine while signals are emitted from another.
 type SignalManager struct {
-	signalConnections map[string][]SignalHandler
+	mu                sync.RWMutex
+	signalConnections map[string][]SignalHandler // The slices are never modified in place, so an emission can call the handlers of a slice without holding the lock.
+	metrics           *SignalMetrics
+}
+
+// SignalInstrument records every emission of the signals of s and the time taken by each of their handlers in metrics, so slow handlers can be found. Many managers may share the same metrics. A nil metrics stops recording.
+func (s *SignalManager) SignalInstrument(metrics *SignalMetrics) {
+	s.mu.Lock()
+	s.metrics = metrics
+	s.mu.Unlock()
 }
 
 // SignalConnect connects a callback function to the signal. The callback function will be called when the signal is emitted. The identity is used to identify functions implemented on the same type. It is typically a pointer to an object that owns the callback function, but it can be a string or any other type. Bindings are arguments that are passed to the callback function when the signal is emitted. These are typically used to pass context.
 func (s *SignalManager) SignalConnect(signal string, identity any, callback func(...any), bindings ...any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if s.signalConnections == nil {
 		s.signalConnections = make(map[string][]SignalHandler)
 	}
 	// Check if the callback and identity is already connected to the signal.
-	if connections, ok := s.signalConnections[signal]; ok {
-		for _, h := range connections {
-			if h.Identity == identity && reflect.ValueOf(h.Callback).Pointer() == reflect.ValueOf(callback).Pointer() {
-				return nil
-			}
+	connections := s.signalConnections[signal]
+	for _, h := range connections {
+		if h.Identity == identity && reflect.ValueOf(h.Callback).Pointer() == reflect.ValueOf(callback).Pointer() {
+			return nil
 		}
 	}
-	s.signalConnections[signal] = append(s.signalConnections[signal], SignalHandler{identity, callback, bindings})
+	s.signalConnections[signal] = append(slices.Clip(connections), SignalHandler{identity, callback, bindings})
 	return nil
 }
 
 // SignalConnected returns true if the callback function under the identity is connected to the signal.
 func (s *SignalManager) SignalConnected(signal string, identity any, callback func(...any)) bool {
-	if s.signalConnections == nil {
-		return false
-	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	for _, h := range s.signalConnections[signal] {
 		if h.Identity == identity && reflect.ValueOf(h.Callback).Pointer() == reflect.ValueOf(callback).Pointer() {
 			return true
@@ -68,35 +88,153 @@ func (s *SignalManager) SignalConnected(signal string, identity any, callback fu
 
 // SignalConnections returns a slice of handlers connected to the signal.
 func (s *SignalManager) SignalConnections(signal string) []SignalHandler {
-	if s.signalConnections == nil {
-		return nil
-	}
-	return s.signalConnections[signal]
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clip(s.signalConnections[signal])
 }
 
 // SignalDisconnect removes the equivalent callback function under the identity from the signal.
 func (s *SignalManager) SignalDisconnect(signal string, identity any, callback func(...any)) {
-	if s.signalConnections == nil {
-		return
-	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	connections := s.signalConnections[signal]
 	for i, h := range connections {
 		if h.Identity == identity && reflect.ValueOf(h.Callback).Pointer() == reflect.ValueOf(callback).Pointer() {
-			s.signalConnections[signal] = append(connections[:i], connections[i+1:]...)
+			s.signalConnections[signal] = slices.Delete(slices.Clone(connections), i, i+1)
 			break
 		}
 	}
 }
 
-// SignalEmit calls all handlers connected to the signal with the data. If no handlers are connected then it is a no-op.
+// SignalEmit calls all handlers connected to the signal with the data. If no handlers are connected then it is a no-op. The handlers are called without holding the lock, so they may connect and disconnect handlers themselves.
 func (s *SignalManager) SignalEmit(signal string, data ...any) {
-	if s.signalConnections == nil {
-		return
+	s.mu.RLock()
+	connections, metrics := s.signalConnections[signal], s.metrics
+	s.mu.RUnlock()
+	if metrics != nil {
+		metrics.emitted(signal)
 	}
-	for _, handler := range s.signalConnections[signal] {
+	for _, handler := range connections {
 		args := make([]any, len(data)+len(handler.Bindings))
 		copy(args, data)
 		copy(args[len(data):], handler.Bindings)
+		if metrics != nil {
+			start := time.Now()
+			handler.Callback(args...)
+			metrics.handled(signal, handler, time.Since(start))
+			continue
+		}
 		handler.Callback(args...)
 	}
 }
+
+// SignalMetrics records the emissions of signals and the time taken by their handlers for the SignalManagers instrumented with it by SignalInstrument. It is safe for concurrent use, and it is an http.Handler that serves its Signals as JSON.
+type SignalMetrics struct {
+	mu      sync.Mutex
+	signals map[string]*SignalMetric
+}
+
+// SignalMetric is the emissions of a signal and the time taken by each of its handlers.
+type SignalMetric struct {
+	Signal   string          `json:"signal"`
+	Emits    int             `json:"emits"`
+	Total    time.Duration   `json:"total"` // Total is the time taken by all handlers of the signal. It is encoded in JSON as nanoseconds.
+	Handlers []HandlerMetric `json:"handlers"`
+}
+
+// HandlerMetric is the time taken by a handler of a signal.
+type HandlerMetric struct {
+	Handler string        `json:"handler"` // Handler names the type of the identity of the handler and its callback function, like "*autotrader.Trader autotrader.(*Trader).Init.func1".
+	Calls   int           `json:"calls"`
+	Total   time.Duration `json:"total"` // Total is the time taken by every call. It is encoded in JSON as nanoseconds.
+	Max     time.Duration `json:"max"`   // Max is the time taken by the slowest call. It is encoded in JSON as nanoseconds.
+}
+
+// Mean returns the average time taken by a call of the handler.
+func (h HandlerMetric) Mean() time.Duration {
+	if h.Calls == 0 {
+		return 0
+	}
+	return h.Total / time.Duration(h.Calls)
+}
+
+func (m *SignalMetrics) emitted(signal string) {
+	m.mu.Lock()
+	m.signal(signal).Emits++
+	m.mu.Unlock()
+}
+
+func (m *SignalMetrics) handled(signal string, handler SignalHandler, took time.Duration) {
+	name := handlerName(handler)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	metric := m.signal(signal)
+	metric.Total += took
+	i := slices.IndexFunc(metric.Handlers, func(h HandlerMetric) bool { return h.Handler == name })
+	if i < 0 {
+		metric.Handlers = append(metric.Handlers, HandlerMetric{Handler: name})
+		i = len(metric.Handlers) - 1
+	}
+	h := &metric.Handlers[i]
+	h.Calls++
+	h.Total += took
+	h.Max = Max(h.Max, took)
+}
+
+// signal returns the metric of signal, creating it if needed. The lock must be held.
+func (m *SignalMetrics) signal(signal string) *SignalMetric {
+	if m.signals == nil {
+		m.signals = make(map[string]*SignalMetric)
+	}
+	metric, ok := m.signals[signal]
+	if !ok {
+		metric = &SignalMetric{Signal: signal}
+		m.signals[signal] = metric
+	}
+	return metric
+}
+
+// handlerName returns the type of the identity of handler and the name of its callback function.
+func handlerName(handler SignalHandler) string {
+	name := "unknown"
+	if f := runtime.FuncForPC(reflect.ValueOf(handler.Callback).Pointer()); f != nil {
+		name = f.Name()
+	}
+	if identity, ok := handler.Identity.(string); ok {
+		return identity + " " + name
+	}
+	return fmt.Sprintf("%T %s", handler.Identity, name)
+}
+
+// Signals returns a copy of the metrics of every signal, sorted from the most to the least time taken by their handlers. The handlers of each signal are sorted the same way.
+func (m *SignalMetrics) Signals() []SignalMetric {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	signals := make([]SignalMetric, 0, len(m.signals))
+	for _, name := range maps.Keys(m.signals) {
+		metric := *m.signals[name]
+		metric.Handlers = slices.Clone(metric.Handlers)
+		slices.SortStableFunc(metric.Handlers, func(a, b HandlerMetric) bool { return a.Total > b.Total })
+		signals = append(signals, metric)
+	}
+	slices.SortFunc(signals, func(a, b SignalMetric) bool {
+		if a.Total != b.Total {
+			return a.Total > b.Total
+		}
+		return a.Signal < b.Signal
+	})
+	return signals
+}
+
+// Reset forgets every recorded emission and handler call.
+func (m *SignalMetrics) Reset() {
+	m.mu.Lock()
+	m.signals = nil
+	m.mu.Unlock()
+}
+
+// ServeHTTP writes the Signals as JSON.
+func (m *SignalMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(m.Signals())
+}
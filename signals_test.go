@@ -0,0 +1,98 @@
+package autotrader
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSignalMetrics(t *testing.T) {
+	metrics := &SignalMetrics{}
+	manager := &SignalManager{}
+	manager.SignalInstrument(metrics)
+	manager.SignalConnect("Tick", "fast", func(...any) {})
+	manager.SignalConnect("Tick", "slow", func(...any) { time.Sleep(2 * time.Millisecond) })
+	for i := 0; i < 3; i++ {
+		manager.SignalEmit("Tick")
+	}
+	manager.SignalEmit("Unhandled")
+
+	signals := metrics.Signals()
+	if len(signals) != 2 || signals[0].Signal != "Tick" || signals[1].Signal != "Unhandled" {
+		t.Fatalf("Expected Tick and then Unhandled, got %+v", signals)
+	}
+	tick := signals[0]
+	if tick.Emits != 3 || len(tick.Handlers) != 2 || signals[1].Emits != 1 || len(signals[1].Handlers) != 0 {
+		t.Fatalf("Expected 3 emits of Tick with 2 handlers, got %+v", signals)
+	}
+	slow := tick.Handlers[0]
+	if !strings.HasPrefix(slow.Handler, "slow ") || slow.Calls != 3 || slow.Max < 2*time.Millisecond || slow.Mean() < 2*time.Millisecond {
+		t.Errorf("Expected the slow handler first with 3 calls of at least 2ms, got %+v", slow)
+	}
+	if tick.Total != slow.Total+tick.Handlers[1].Total {
+		t.Errorf("Expected the total of the signal to be the total of its handlers, got %v", tick.Total)
+	}
+
+	recorder := httptest.NewRecorder()
+	metrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/debug/signals", nil))
+	var served []SignalMetric
+	if err := json.NewDecoder(recorder.Body).Decode(&served); err != nil {
+		t.Fatal(err)
+	}
+	if len(served) != 2 || served[0].Handlers[0].Calls != 3 {
+		t.Errorf("Expected the signals as JSON, got %+v", served)
+	}
+
+	metrics.Reset()
+	manager.SignalInstrument(nil)
+	manager.SignalEmit("Tick")
+	if signals := metrics.Signals(); len(signals) != 0 {
+		t.Errorf("Expected no metrics after a reset without instrumentation, got %+v", signals)
+	}
+}
+
+func TestTraderSignalMetrics(t *testing.T) {
+	broker := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	metrics := &SignalMetrics{}
+	trader := NewTrader(TraderConfig{Broker: broker, Strategy: &roundTripStrategy{}, Symbol: "EUR_USD", Frequency: "D", CandlesToKeep: 5, SignalMetrics: metrics})
+	trader.Log.SetOutput(io.Discard)
+	trader.Init()
+	for !trader.EOF {
+		trader.Tick()
+		broker.Advance()
+	}
+	for _, signal := range metrics.Signals() {
+		if signal.Signal == OrderFulfilled {
+			if signal.Emits != 1 || len(signal.Handlers) == 0 || !strings.Contains(signal.Handlers[0].Handler, "Trader") {
+				t.Errorf("Expected the entry fill handled by the Trader, got %+v", signal)
+			}
+			return
+		}
+	}
+	t.Errorf("Expected the fills of the broker to be recorded, got %+v", metrics.Signals())
+}
+
+func TestSignalConcurrentConnect(t *testing.T) {
+	manager := &SignalManager{}
+	var calls atomic.Int32
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			manager.SignalConnect("Tick", i, func(...any) { calls.Add(1) })
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		manager.SignalEmit("Tick")
+	}
+	<-done
+	calls.Store(0)
+	manager.SignalEmit("Tick")
+	if calls.Load() != 100 {
+		t.Errorf("Expected 100 handlers to be called, got %d", calls.Load())
+	}
+}
@@ -0,0 +1,93 @@
+package autotrader
+
+import (
+	"math"
+	"time"
+)
+
+// PositionSizer decides how many units a Trader should buy or sell when it opens a position. Set it on Trader.Sizer and call Trader.Size from a strategy.
+type PositionSizer interface {
+	Units(t *Trader) float64 // Units returns the unsigned number of units to trade at the latest candle.
+}
+
+// VolatilityMethod is how a VolatilitySizer measures the volatility of a symbol.
+type VolatilityMethod string
+
+const (
+	VolatilityStdDev VolatilityMethod = "STDDEV" // VolatilityStdDev uses the standard deviation of the close to close returns.
+	VolatilityATR    VolatilityMethod = "ATR"    // VolatilityATR uses the Average True Range relative to the latest close.
+)
+
+// VolatilitySizer sizes positions so each one contributes a constant annualized volatility to the account, measured as a fraction of the NAV. Positions grow when the market is calm and shrink when it is volatile.
+//
+// For example, with a TargetVolatility of 0.1 and an account of $10,000, a position is sized so that a one standard deviation move over a year would change its value by $1,000.
+type VolatilitySizer struct {
+	TargetVolatility float64          // TargetVolatility is the annualized volatility of each position as a fraction of NAV, like 0.1 for 10%.
+	Period           int              // Period is the number of candles to measure volatility over. The default is 20.
+	Method           VolatilityMethod // Method is how volatility is measured. The default is VolatilityStdDev.
+	PeriodsPerYear   float64          // PeriodsPerYear is the number of candles in a year used to annualize volatility. If zero, it is calculated from the frequency of the Trader assuming the market never closes.
+	MaxUnits         float64          // MaxUnits caps the number of units returned. Zero means no limit.
+}
+
+// Units returns the number of units that would give a position the target volatility at the latest close. Zero is returned if there is not enough data to measure the volatility.
+func (s *VolatilitySizer) Units(t *Trader) float64 {
+	data := t.Data()
+	if data == nil || data.Len() < 2 {
+		return 0
+	}
+	price := data.Close(-1)
+	vol := s.Volatility(data)
+	periodsPerYear := s.PeriodsPerYear
+	if periodsPerYear <= 0 {
+		freq, err := FrequencyDuration(t.Frequency)
+		if err != nil {
+			return 0
+		}
+		periodsPerYear = float64(365*24*time.Hour) / float64(freq)
+	}
+	annualVol := vol * math.Sqrt(periodsPerYear)
+	if annualVol <= 0 || price <= 0 {
+		return 0
+	}
+	units := s.TargetVolatility * t.Broker.NAV() / (price * annualVol)
+	if s.MaxUnits > 0 {
+		units = Min(units, s.MaxUnits)
+	}
+	return units
+}
+
+// Volatility returns the volatility of a single candle over the last Period candles of data as a fraction of the price.
+func (s *VolatilitySizer) Volatility(data *IndexedFrame[UnixTime]) float64 {
+	period := s.Period
+	if period <= 0 {
+		period = 20
+	}
+	if s.Method == VolatilityATR {
+		price := data.Close(-1)
+		if price == 0 {
+			return 0
+		}
+		return ATR(data.CopyRange(-Min(period+1, data.Len()), -1), period).Value(-1) / price
+	}
+
+	start := Max(data.Len()-period-1, 0)
+	returns := make([]float64, 0, period)
+	for i := start + 1; i < data.Len(); i++ {
+		if prev := data.Close(i - 1); prev != 0 {
+			returns = append(returns, data.Close(i)/prev-1)
+		}
+	}
+	if len(returns) < 2 {
+		return 0
+	}
+	var mean float64
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	var variance float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	return math.Sqrt(variance / float64(len(returns)-1))
+}
@@ -0,0 +1,46 @@
+package autotrader
+
+import (
+	"testing"
+)
+
+func TestVolatilitySizer(t *testing.T) {
+	broker := NewTestBroker(nil, testData, 100_000, 1, 0, 0)
+	trader := NewTrader(TraderConfig{Broker: broker, Frequency: "D"})
+	trader.data = testData.CopyRange(0, 4)
+
+	if trader.Size() != 0 {
+		t.Errorf("Expected Size to be 0 without a Sizer, got %f", trader.Size())
+	}
+
+	trader.Sizer = &VolatilitySizer{TargetVolatility: 0.1, Period: 3, Method: VolatilityATR, PeriodsPerYear: 1}
+	expected := 0.1 * 100_000 / (0.55 / 3) // Target risk divided by the ATR of the last candle.
+	if units := trader.Size(); !EqualApprox(units, expected) {
+		t.Errorf("Expected %f units, got %f", expected, units)
+	}
+	trader.Sizer.(*VolatilitySizer).MaxUnits = 1000
+	if units := trader.Size(); units != 1000 {
+		t.Errorf("Expected units to be capped at 1000, got %f", units)
+	}
+
+	// Units scale inversely with volatility.
+	calm := NewDOHLCVIndexedFrame[UnixTime]()
+	wild := NewDOHLCVIndexedFrame[UnixTime]()
+	for i, r := range []float64{0.01, -0.01, 0.01, -0.01, 0.01} {
+		date := UnixTime(int64(i) * 86400)
+		calm.PushCandle(date, 1, 1, 1, 1+r, 0)
+		wild.PushCandle(date, 1, 1, 1, 1+2*r, 0)
+	}
+	sizer := &VolatilitySizer{TargetVolatility: 0.1, Period: 4}
+	trader.Sizer = sizer
+	trader.data = calm
+	calmUnits := trader.Size()
+	trader.data = wild
+	wildUnits := trader.Size()
+	if calmUnits <= 0 || wildUnits <= 0 {
+		t.Fatalf("Expected positive units, got %f and %f", calmUnits, wildUnits)
+	}
+	if ratio := calmUnits / wildUnits; ratio < 1.9 || ratio > 2.1 {
+		t.Errorf("Expected twice the volatility to roughly halve the units, got a ratio of %f", ratio)
+	}
+}
@@ -0,0 +1,392 @@
+package autotrader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Spread is a synthetic instrument that is long one unit of symbol A and short HedgeRatio units of symbol B for each of its units, like a pair of cointegrated symbols found with Cointegration. Its price is A - HedgeRatio*B.
+type Spread struct {
+	A, B       string
+	HedgeRatio float64
+}
+
+// SpreadBroker wraps a Broker and lets a Trader treat spreads as if they were symbols. The candles of a spread are derived from the candles of its two symbols, and an order for a spread is placed as two market orders, one for each leg. If the second leg is rejected, the first leg is closed again so that a spread is never left half open. Open spread positions are returned by OpenPositions in place of their legs.
+//
+// Spread orders must be market orders without a stop loss or take profit, because a level on the price of a spread cannot be split into levels on its legs. The wrapped broker emits the signals of each leg as usual. All other methods are passed through to the wrapped Broker.
+type SpreadBroker struct {
+	Broker
+	Spreads map[string]Spread // Spreads are the synthetic instruments by their symbol names.
+
+	positions []*SpreadPosition
+	pending   []*SpreadOrder // Orders whose legs have not both been filled, like orders queued while the market is closed.
+}
+
+// NewSpreadBroker returns a SpreadBroker that trades spreads on broker.
+func NewSpreadBroker(broker Broker, spreads map[string]Spread) *SpreadBroker {
+	if spreads == nil {
+		spreads = make(map[string]Spread)
+	}
+	return &SpreadBroker{Broker: broker, Spreads: spreads}
+}
+
+// Price returns the price to buy the spread if wantToBuy is true, which buys A at its ask and sells B at its bid, and the price to sell it otherwise. If symbol is not a spread, the price is passed through.
+func (b *SpreadBroker) Price(symbol string, wantToBuy bool) float64 {
+	spread, ok := b.Spreads[symbol]
+	if !ok {
+		return b.Broker.Price(symbol, wantToBuy)
+	}
+	sellB := wantToBuy == (spread.HedgeRatio >= 0) // A negative hedge ratio buys B along with A.
+	return b.Broker.Price(spread.A, wantToBuy) - spread.HedgeRatio*b.Broker.Price(spread.B, !sellB)
+}
+
+func (b *SpreadBroker) Bid(symbol string) float64 {
+	return b.Price(symbol, false)
+}
+
+func (b *SpreadBroker) Ask(symbol string) float64 {
+	return b.Price(symbol, true)
+}
+
+// Candles returns the candles of symbol, which are derived from the candles of both legs if symbol is a spread. Only the dates that both legs have candles for are returned. The high and low are the widest the spread could have been, since the legs may not have made their highs and lows at the same time, and the volume is the smaller volume of the legs.
+func (b *SpreadBroker) Candles(ctx context.Context, symbol, frequency string, count int) (*IndexedFrame[UnixTime], error) {
+	spread, ok := b.Spreads[symbol]
+	if !ok {
+		return b.Broker.Candles(ctx, symbol, frequency, count)
+	}
+	a, errA := b.Broker.Candles(ctx, spread.A, frequency, count)
+	if a == nil {
+		return nil, errA
+	}
+	legB, errB := b.Broker.Candles(ctx, spread.B, frequency, count)
+	if legB == nil {
+		return nil, errB
+	}
+
+	rowsB := make(map[UnixTime]int, legB.Len())
+	for i := 0; i < legB.Len(); i++ {
+		rowsB[*legB.Date(i)] = i
+	}
+	candles := NewDOHLCVIndexedFrame[UnixTime]()
+	r := spread.HedgeRatio
+	for i := 0; i < a.Len(); i++ {
+		j, ok := rowsB[*a.Date(i)]
+		if !ok {
+			continue
+		}
+		lowB, highB := r*legB.Low(j), r*legB.High(j)
+		if r < 0 {
+			lowB, highB = highB, lowB
+		}
+		err := candles.PushCandle(*a.Date(i),
+			a.Open(i)-r*legB.Open(j),
+			a.High(i)-lowB,
+			a.Low(i)-highB,
+			a.Close(i)-r*legB.Close(j),
+			Min(candleVolume(a, i), candleVolume(legB, j)))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if errA != nil { // Some brokers return candles along with an error, like ErrEOF.
+		return candles, errA
+	}
+	return candles, errB
+}
+
+// Order places an order for symbol. If symbol is a spread, a market order is placed for units of A and -units*HedgeRatio of B, and a *SpreadOrder is returned. If the second leg fails, the first leg is cancelled or closed and the error of the second leg is returned.
+func (b *SpreadBroker) Order(ctx context.Context, orderType OrderType, symbol string, units, price, stopLoss, takeProfit float64, options ...OrderOption) (Order, error) {
+	spread, ok := b.Spreads[symbol]
+	if !ok {
+		return b.Broker.Order(ctx, orderType, symbol, units, price, stopLoss, takeProfit, options...)
+	}
+	marketPrice := b.Price(symbol, units > 0)
+	reject := func(err error, reason string) error {
+		return &OrderError{Err: err, OrderType: orderType, Symbol: symbol, Units: units, Price: marketPrice, Reason: reason}
+	}
+	if orderType != Market {
+		return nil, reject(ErrUnsupportedOrder, "spreads only support market orders")
+	} else if stopLoss != 0 {
+		return nil, reject(ErrInvalidStopLoss, "spreads do not support stop losses")
+	} else if takeProfit != 0 {
+		return nil, reject(ErrInvalidTakeProfit, "spreads do not support take profits")
+	}
+
+	legA, err := b.Broker.Order(ctx, Market, spread.A, units, 0, 0, 0, options...)
+	if err != nil {
+		return nil, err
+	}
+	legB, err := b.Broker.Order(ctx, Market, spread.B, -units*spread.HedgeRatio, 0, 0, 0, options...)
+	if err != nil {
+		var unwindErr error
+		if legA.Fulfilled() {
+			unwindErr = legA.Position().Close()
+		} else {
+			unwindErr = legA.Cancel()
+		}
+		if unwindErr != nil {
+			return nil, fmt.Errorf("error unwinding first leg of spread %s: %w", symbol, errors.Join(err, unwindErr))
+		}
+		return nil, err
+	}
+
+	order := &SpreadOrder{symbol: symbol, spread: spread, units: units, price: marketPrice, time: brokerNow(b.Broker), tags: NewOrderOptions(options...).Tags, legs: [2]Order{legA, legB}}
+	b.pending = append(b.pending, order)
+	b.promote()
+	return order, nil
+}
+
+// OpenPositions returns the open positions of the wrapped broker with the legs of open spreads replaced by their spread positions.
+func (b *SpreadBroker) OpenPositions() []Position {
+	return b.withSpreads(b.Broker.OpenPositions())
+}
+
+// Positions returns the positions of the wrapped broker with the legs of open spreads replaced by their spread positions.
+func (b *SpreadBroker) Positions() []Position {
+	return b.withSpreads(b.Broker.Positions())
+}
+
+// PositionByID returns the spread position with the id, or the position of the wrapped broker.
+func (b *SpreadBroker) PositionByID(ctx context.Context, id string) (Position, error) {
+	for _, position := range b.positions {
+		if position.Id() == id {
+			return position, nil
+		}
+	}
+	return b.Broker.PositionByID(ctx, id)
+}
+
+// promote moves the positions of filled pending orders to the open spread positions and forgets pending orders that are no longer open, like cancelled orders.
+func (b *SpreadBroker) promote() {
+	if len(b.pending) == 0 {
+		return
+	}
+	open := make(map[string]bool)
+	for _, order := range b.Broker.OpenOrders() {
+		open[order.Id()] = true
+	}
+	pending := b.pending[:0]
+	for _, order := range b.pending {
+		if position := order.Position(); position != nil {
+			b.positions = append(b.positions, position.(*SpreadPosition))
+		} else if open[order.legs[0].Id()] || open[order.legs[1].Id()] {
+			pending = append(pending, order)
+		}
+	}
+	b.pending = pending
+}
+
+func (b *SpreadBroker) withSpreads(positions []Position) []Position {
+	b.promote()
+	legs := make(map[string]bool)
+	open := b.positions[:0]
+	for _, position := range b.positions {
+		if position.Closed() {
+			continue // Forget spreads once both legs are closed.
+		}
+		open = append(open, position)
+		legs[position.legs[0].Id()] = true
+		legs[position.legs[1].Id()] = true
+	}
+	b.positions = open
+
+	result := make([]Position, 0, len(positions))
+	for _, position := range positions {
+		if !legs[position.Id()] {
+			result = append(result, position)
+		}
+	}
+	for _, position := range open {
+		result = append(result, position)
+	}
+	return result
+}
+
+// SpreadOrder is an order for a spread that was placed as an order for each leg.
+type SpreadOrder struct {
+	symbol   string
+	spread   Spread
+	units    float64
+	price    float64
+	time     time.Time
+	tags     Tags
+	legs     [2]Order
+	position *SpreadPosition
+}
+
+// Legs returns the orders of symbol A and symbol B.
+func (o *SpreadOrder) Legs() (a, b Order) {
+	return o.legs[0], o.legs[1]
+}
+
+// Cancel cancels both legs.
+func (o *SpreadOrder) Cancel() error {
+	return errors.Join(o.legs[0].Cancel(), o.legs[1].Cancel())
+}
+
+func (o *SpreadOrder) Costs() TradeCosts {
+	return addCosts(o.legs[0].Costs(), o.legs[1].Costs())
+}
+
+// Fulfilled returns true if both legs have been filled.
+func (o *SpreadOrder) Fulfilled() bool {
+	return o.legs[0].Fulfilled() && o.legs[1].Fulfilled()
+}
+
+// Id returns the ids of both legs separated by a slash.
+func (o *SpreadOrder) Id() string {
+	return o.legs[0].Id() + "/" + o.legs[1].Id()
+}
+
+func (o *SpreadOrder) Leverage() float64 {
+	return o.legs[0].Leverage()
+}
+
+// Position returns the spread position once both legs have been filled.
+func (o *SpreadOrder) Position() Position {
+	if o.position == nil {
+		if !o.Fulfilled() {
+			return nil
+		}
+		o.position = &SpreadPosition{symbol: o.symbol, spread: o.spread, units: o.units, legs: [2]Position{o.legs[0].Position(), o.legs[1].Position()}}
+	}
+	return o.position
+}
+
+func (o *SpreadOrder) Price() float64 {
+	return o.price
+}
+
+func (o *SpreadOrder) Symbol() string {
+	return o.symbol
+}
+
+func (o *SpreadOrder) TrailingStop() float64 {
+	return 0
+}
+
+func (o *SpreadOrder) StopLoss() float64 {
+	return 0
+}
+
+func (o *SpreadOrder) Tags() Tags {
+	return o.tags
+}
+
+func (o *SpreadOrder) TakeProfit() float64 {
+	return 0
+}
+
+func (o *SpreadOrder) Time() time.Time {
+	return o.time
+}
+
+func (o *SpreadOrder) Type() OrderType {
+	return Market
+}
+
+func (o *SpreadOrder) Units() float64 {
+	return o.units
+}
+
+// SpreadPosition is an open spread made of a position in each leg. Its prices are prices of the spread and its values are the sums of the values of the legs.
+type SpreadPosition struct {
+	symbol string
+	spread Spread
+	units  float64
+	legs   [2]Position
+}
+
+// Legs returns the positions of symbol A and symbol B.
+func (p *SpreadPosition) Legs() (a, b Position) {
+	return p.legs[0], p.legs[1]
+}
+
+// Close closes both legs.
+func (p *SpreadPosition) Close() error {
+	return errors.Join(p.legs[0].Close(), p.legs[1].Close())
+}
+
+// Closed returns true if both legs have been closed.
+func (p *SpreadPosition) Closed() bool {
+	return p.legs[0].Closed() && p.legs[1].Closed()
+}
+
+// CloseType returns the close type of leg A.
+func (p *SpreadPosition) CloseType() OrderCloseType {
+	return p.legs[0].CloseType()
+}
+
+func (p *SpreadPosition) CloseCosts() TradeCosts {
+	return addCosts(p.legs[0].CloseCosts(), p.legs[1].CloseCosts())
+}
+
+func (p *SpreadPosition) ClosePrice() float64 {
+	return p.legs[0].ClosePrice() - p.spread.HedgeRatio*p.legs[1].ClosePrice()
+}
+
+func (p *SpreadPosition) EntryPrice() float64 {
+	return p.legs[0].EntryPrice() - p.spread.HedgeRatio*p.legs[1].EntryPrice()
+}
+
+func (p *SpreadPosition) EntryValue() float64 {
+	return p.legs[0].EntryValue() + p.legs[1].EntryValue()
+}
+
+// Id returns the ids of both legs separated by a slash.
+func (p *SpreadPosition) Id() string {
+	return p.legs[0].Id() + "/" + p.legs[1].Id()
+}
+
+func (p *SpreadPosition) Leverage() float64 {
+	return p.legs[0].Leverage()
+}
+
+func (p *SpreadPosition) PL() float64 {
+	return p.legs[0].PL() + p.legs[1].PL()
+}
+
+func (p *SpreadPosition) Symbol() string {
+	return p.symbol
+}
+
+func (p *SpreadPosition) TrailingStop() float64 {
+	return 0
+}
+
+func (p *SpreadPosition) StopLoss() float64 {
+	return 0
+}
+
+func (p *SpreadPosition) Tags() Tags {
+	return p.legs[0].Tags()
+}
+
+func (p *SpreadPosition) TakeProfit() float64 {
+	return 0
+}
+
+func (p *SpreadPosition) Time() time.Time {
+	return p.legs[0].Time()
+}
+
+func (p *SpreadPosition) Units() float64 {
+	return p.units
+}
+
+func (p *SpreadPosition) Value() float64 {
+	return p.legs[0].Value() + p.legs[1].Value()
+}
+
+// addCosts returns the sum of the costs of two fills.
+func addCosts(a, b TradeCosts) TradeCosts {
+	return TradeCosts{
+		Spread:     a.Spread + b.Spread,
+		Commission: a.Commission + b.Commission,
+		Slippage:   a.Slippage + b.Slippage,
+		Financing:  a.Financing + b.Financing,
+		SpreadPips: a.SpreadPips + b.SpreadPips,
+	}
+}
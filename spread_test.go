@@ -0,0 +1,108 @@
+package autotrader
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSpreadBrokerCandles(t *testing.T) {
+	day := func(d int) UnixTime {
+		return UnixTime(time.Date(2022, 1, d, 0, 0, 0, 0, time.UTC).Unix())
+	}
+	a := NewDOHLCVIndexedFrame[UnixTime]()
+	a.PushCandle(day(1), 10, 12, 9, 11, 100)
+	a.PushCandle(day(2), 11, 13, 10, 12, 200)
+	a.PushCandle(day(3), 12, 14, 11, 13, 300)
+	b := NewDOHLCVIndexedFrame[UnixTime]()
+	b.PushCandle(day(1), 4, 6, 3, 5, 50)
+	b.PushCandle(day(3), 6, 8, 5, 7, 500)
+
+	broker := NewSpreadBroker(&multiSymbolBroker{candles: map[string]*IndexedFrame[UnixTime]{"A": a, "B": b}}, map[string]Spread{
+		"A-B": {A: "A", B: "B", HedgeRatio: 0.5},
+	})
+	candles, err := broker.Candles(context.Background(), "A-B", "D", 3)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if candles.Len() != 2 {
+		t.Fatalf("Expected 2 candles on the dates both legs have, got %d", candles.Len())
+	}
+	if *candles.Date(1) != day(3) {
+		t.Errorf("Expected second candle on %v, got %v", day(3).Time(), candles.Date(1).Time())
+	}
+	expected := []float64{12 - 3, 14 - 2.5, 11 - 4, 13 - 3.5}
+	got := []float64{candles.Open(1), candles.High(1), candles.Low(1), candles.Close(1)}
+	for i := range expected {
+		if math.Abs(got[i]-expected[i]) > 1e-9 {
+			t.Errorf("Expected OHLC %v, got %v", expected, got)
+			break
+		}
+	}
+	if volume := candleVolume(candles, 1); volume != 300 {
+		t.Errorf("Expected volume 300, got %d", volume)
+	}
+}
+
+func TestSpreadBrokerOrder(t *testing.T) {
+	testBroker := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	testBroker.Symbols = []string{"A", "B"}
+	testBroker.Slippage = 0
+	broker := NewSpreadBroker(testBroker, map[string]Spread{
+		"A-B": {A: "A", B: "B", HedgeRatio: 2},
+		"A-C": {A: "A", B: "C", HedgeRatio: 2},
+	})
+	ctx := context.Background()
+
+	if _, err := broker.Order(ctx, Limit, "A-B", 1000, 1, 0, 0); !errors.Is(err, ErrUnsupportedOrder) {
+		t.Errorf("Expected ErrUnsupportedOrder, got %v", err)
+	}
+	if _, err := broker.Order(ctx, Market, "A-B", 1000, 0, -0.1, 0); !errors.Is(err, ErrInvalidStopLoss) {
+		t.Errorf("Expected ErrInvalidStopLoss, got %v", err)
+	}
+
+	// The second leg is rejected, so the first leg must be closed again.
+	if _, err := broker.Order(ctx, Market, "A-C", 1000, 0, 0, 0); !errors.Is(err, ErrSymbolNotFound) {
+		t.Fatalf("Expected ErrSymbolNotFound, got %v", err)
+	}
+	if len(testBroker.OpenPositions()) != 0 {
+		t.Fatalf("Expected first leg to be closed, got %d open positions", len(testBroker.OpenPositions()))
+	}
+
+	order, err := broker.Order(ctx, Market, "A-B", 1000, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	a, b := order.(*SpreadOrder).Legs()
+	if a.Symbol() != "A" || a.Units() != 1000 || b.Symbol() != "B" || b.Units() != -2000 {
+		t.Errorf("Expected legs of 1000 A and -2000 B, got %v %s and %v %s", a.Units(), a.Symbol(), b.Units(), b.Symbol())
+	}
+	if len(testBroker.OpenPositions()) != 2 {
+		t.Errorf("Expected 2 leg positions, got %d", len(testBroker.OpenPositions()))
+	}
+	positions := broker.OpenPositions()
+	if len(positions) != 1 {
+		t.Fatalf("Expected 1 spread position, got %d", len(positions))
+	}
+	position := positions[0]
+	if position.Symbol() != "A-B" || position.Units() != 1000 {
+		t.Errorf("Expected 1000 units of A-B, got %v units of %s", position.Units(), position.Symbol())
+	}
+	if price := testData.Close(0) - 2*testData.Close(0); math.Abs(position.EntryPrice()-price) > 1e-9 {
+		t.Errorf("Expected entry price %f, got %f", price, position.EntryPrice())
+	}
+
+	testBroker.Advance()
+	// A rose by 0.05 and B rose by 0.05, so the spread fell by 0.05.
+	if math.Abs(position.PL()-(-50)) > 1e-6 {
+		t.Errorf("Expected PL -50, got %f", position.PL())
+	}
+	if err := position.Close(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !position.Closed() || len(testBroker.OpenPositions()) != 0 || len(broker.OpenPositions()) != 0 {
+		t.Errorf("Expected both legs to be closed")
+	}
+}
@@ -0,0 +1,50 @@
+package autotrader
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// SaveState writes the state of the strategy to StateFile if the strategy is a StatefulStrategy and StateFile is set. The file is replaced atomically, so a crash while saving leaves the previous state intact. Call it from the strategy or while the Trader is stopped, since it does not wait for a tick in progress.
+func (t *Trader) SaveState() error {
+	strategy, ok := t.Strategy.(StatefulStrategy)
+	if !ok || t.StateFile == "" {
+		return nil
+	}
+	data, err := strategy.SaveState()
+	if err != nil {
+		return fmt.Errorf("saving state of %s: %w", strategyName(t.Strategy), err)
+	}
+	if dir := filepath.Dir(t.StateFile); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	tmp := t.StateFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, t.StateFile)
+}
+
+// LoadState restores the state of the strategy from StateFile if the strategy is a StatefulStrategy and StateFile is set. A missing file is not an error, since there is no state to restore on the first run.
+func (t *Trader) LoadState() error {
+	strategy, ok := t.Strategy.(StatefulStrategy)
+	if !ok || t.StateFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(t.StateFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err := strategy.LoadState(data); err != nil {
+		return fmt.Errorf("loading state of %s from %s: %w", strategyName(t.Strategy), t.StateFile, err)
+	}
+	t.Log.Printf("Loaded strategy state from %s", t.StateFile)
+	return nil
+}
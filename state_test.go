@@ -0,0 +1,64 @@
+package autotrader
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// countingStrategy counts its candles and keeps the count as its state.
+type countingStrategy struct {
+	candles int
+}
+
+func (s *countingStrategy) Init(_ *Trader) {}
+
+func (s *countingStrategy) Next(_ *Trader) {
+	s.candles++
+}
+
+func (s *countingStrategy) SaveState() ([]byte, error) {
+	return []byte(strconv.Itoa(s.candles)), nil
+}
+
+func (s *countingStrategy) LoadState(data []byte) (err error) {
+	s.candles, err = strconv.Atoi(string(data))
+	return err
+}
+
+func TestStrategyState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "counter")
+	broker := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	trader := NewTrader(TraderConfig{Broker: broker, Strategy: &countingStrategy{}, Symbol: "EUR_USD", Frequency: "D", CandlesToKeep: 5, StateFile: path, CheckpointEvery: 4})
+	trader.Log.SetOutput(io.Discard)
+	if _, err := RunBacktest(trader); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "8" {
+		t.Errorf("Expected the checkpoint of the 8th candle, got %q (%v)", data, err)
+	}
+
+	strategy := &countingStrategy{}
+	live := newConnectBroker(NewTestBroker(nil, testData, 100_000, 50, 0, 0), CandleClosed)
+	trader = NewTrader(TraderConfig{Broker: live, Strategy: strategy, Symbol: "EUR_USD", Frequency: "D", CandlesToKeep: 5, CandleDriven: true, StateFile: path})
+	trader.Log.SetOutput(io.Discard)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		trader.RunContext(ctx)
+		close(done)
+	}()
+	<-live.connected
+	live.Advance()
+	cancel()
+	<-done
+	if strategy.candles != 9 {
+		t.Errorf("Expected the strategy to continue from 8 candles, got %d", strategy.candles)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "9" {
+		t.Errorf("Expected the state to be saved on stop, got %q (%v)", data, err)
+	}
+}
@@ -4,3 +4,28 @@ type Strategy interface {
 	Init(t *Trader)
 	Next(t *Trader)
 }
+
+// StrategyEnder is an optional interface a Strategy may implement to be notified when the Trader has run out of data. End is called once, after the final call to Next, and before any statistics or reports are generated. This is the place to flatten positions, log final diagnostics, or persist learned state.
+type StrategyEnder interface {
+	End(t *Trader)
+}
+
+// StatefulStrategy is an optional interface a Strategy may implement to keep its state across restarts, like the levels of a grid, the step of a martingale, or the weights of a model. When the Trader has a StateFile, RunContext loads the state after Init and saves it when it stops, and CheckpointEvery also saves it periodically in live trading and backtests.
+type StatefulStrategy interface {
+	Strategy
+	SaveState() ([]byte, error)  // SaveState returns the state of the strategy in any encoding, like JSON.
+	LoadState(data []byte) error // LoadState restores the state returned by SaveState.
+}
+
+// MultiFrequencyStrategy is an optional interface a Strategy may implement to use candles of other frequencies besides the frequency of its Trader. For example, a strategy trading on "M15" candles can also read daily candles. Next is still called on every candle of the Trader's frequency, and the candles of each other frequency are available from Trader.DataOf. The broker must return candles of every frequency; a TestBroker resamples its data when its Frequency is set.
+type MultiFrequencyStrategy interface {
+	Strategy
+	Frequencies() []string               // Frequencies returns the other frequencies used by the strategy, like "D".
+	OnClose(t *Trader, frequency string) // OnClose is called before Next when a new candle of one of the other frequencies has closed.
+}
+
+// TunableStrategy is an optional interface a Strategy may implement to have its parameters replaced while it runs, like by a Reoptimizer. SetParameters is called between candles with parameters like the ones given to Optimizer.NewStrategy, and the strategy keeps its positions and state.
+type TunableStrategy interface {
+	Strategy
+	SetParameters(params Parameters) error
+}
@@ -0,0 +1,213 @@
+package autotrader
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+var (
+	ErrNotStruct    = errors.New("type is not a struct or a pointer to a struct")
+	ErrFieldType    = errors.New("value cannot be assigned to field")
+	ErrMissingIndex = errors.New("struct has no field tagged as the index")
+)
+
+// structField is an exported field of a struct that maps to a column of a frame.
+type structField struct {
+	column string // column is the name of the column, which is the name in the frame tag or the name of the field.
+	index  []int  // index is the index sequence of the field for reflect.Value.FieldByIndex.
+	typ    reflect.Type
+	isIdx  bool // isIdx is true if the field is tagged as the index of an IndexedFrame.
+}
+
+// structFields returns the fields of the struct type t, or the struct that t points to, that map to columns. Fields are mapped by their name unless they have a frame tag like `frame:"Name"`, and `frame:"-"` skips a field. The option `frame:",index"` marks the field holding the index of an IndexedFrame. Unexported fields are skipped and the fields of embedded structs are promoted.
+func structFields(t reflect.Type) ([]structField, error) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: %s", ErrNotStruct, t)
+	}
+	var fields []structField
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || (f.Anonymous && f.Type.Kind() == reflect.Struct) {
+			continue
+		}
+		tag := f.Tag.Get("frame")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, structField{column: name, index: f.Index, typ: f.Type, isIdx: options == "index"})
+	}
+	return fields, nil
+}
+
+// structValue returns the struct value of row, which is nil if row is a nil pointer.
+func structValue(row reflect.Value) (reflect.Value, bool) {
+	if row.Kind() == reflect.Pointer {
+		if row.IsNil() {
+			return reflect.Value{}, false
+		}
+		row = row.Elem()
+	}
+	return row, true
+}
+
+// newStruct returns a new value of type t, which is a struct or a pointer to a struct, and the struct value to set the fields of.
+func newStruct(t reflect.Type) (row, fields reflect.Value) {
+	if t.Kind() == reflect.Pointer {
+		row = reflect.New(t.Elem())
+		return row, row.Elem()
+	}
+	row = reflect.New(t).Elem()
+	return row, row
+}
+
+// setField assigns val to the field, converting between numeric types. A nil val leaves the field at its zero value.
+func setField(field reflect.Value, column string, val any) error {
+	if val == nil {
+		return nil
+	}
+	v := reflect.ValueOf(val)
+	switch {
+	case v.Type().AssignableTo(field.Type()):
+		field.Set(v)
+	case isNumeric(v.Kind()) && isNumeric(field.Kind()), v.Kind() == field.Kind() && v.CanConvert(field.Type()):
+		field.Set(v.Convert(field.Type()))
+	default:
+		return fmt.Errorf("%w: %T to %s field of column %q", ErrFieldType, val, field.Type(), column)
+	}
+	return nil
+}
+
+func isNumeric(kind reflect.Kind) bool {
+	return kind >= reflect.Int && kind <= reflect.Float64
+}
+
+// FromStructs returns a Frame with a column for each exported field of T and a row for each element of rows. T is a struct or a pointer to a struct, and a nil pointer is a row of nil values. Fields are mapped to columns by their name unless they have a frame tag like `frame:"Name"`, and `frame:"-"` skips a field. For example, a Date field of type time.Time makes the Frame work with the candlestick methods like Dates.
+func FromStructs[T any](rows []T) (*Frame, error) {
+	fields, err := structFields(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return nil, err
+	}
+	columns := make([][]any, len(fields))
+	for i := range columns {
+		columns[i] = make([]any, len(rows))
+	}
+	for r := range rows {
+		row, ok := structValue(reflect.ValueOf(&rows[r]).Elem())
+		if !ok {
+			continue
+		}
+		for i, field := range fields {
+			columns[i][r] = row.FieldByIndex(field.index).Interface()
+		}
+	}
+	frame := NewFrame()
+	for i, field := range fields {
+		if err := frame.PushSeries(NewSeries(field.column, columns[i]...)); err != nil {
+			return nil, err
+		}
+	}
+	return frame, nil
+}
+
+// ToStructs returns a T for each row of frame with the fields set to the values of their columns. It is the reverse of FromStructs and uses the same frame tags. Fields whose column is missing or whose value is nil are left at their zero value, and numeric values are converted to the type of the field. An error wrapping ErrFieldType is returned if a value cannot be assigned to its field.
+func ToStructs[T any](frame *Frame) ([]T, error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	fields, err := structFields(t)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]T, frame.Len())
+	for r := range out {
+		row, values := newStruct(t)
+		for _, field := range fields {
+			if series := frame.Series(field.column); series != nil {
+				if err := setField(values.FieldByIndex(field.index), field.column, series.Value(r)); err != nil {
+					return nil, fmt.Errorf("row %d: %w", r, err)
+				}
+			}
+		}
+		out[r] = row.Interface().(T)
+	}
+	return out, nil
+}
+
+// IndexedFromStructs returns an IndexedFrame of rows like FromStructs, indexed by the field tagged with the index option like `frame:"Date,index"`. The type of the index field must be convertible to I, like an int64 field for a UnixTime index. The index field is not made into a column. Rows with the same index overwrite earlier rows, and nil pointers are skipped. An error wrapping ErrMissingIndex is returned if T has no index field.
+func IndexedFromStructs[I Index, T any](rows []T) (*IndexedFrame[I], error) {
+	fields, err := structFields(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return nil, err
+	}
+	idx := slices.IndexFunc(fields, func(f structField) bool { return f.isIdx })
+	if idx < 0 {
+		return nil, fmt.Errorf("%w: %s", ErrMissingIndex, reflect.TypeOf((*T)(nil)).Elem())
+	}
+	indexType := reflect.TypeOf((*I)(nil)).Elem()
+	if !fields[idx].typ.ConvertibleTo(indexType) {
+		return nil, fmt.Errorf("%w: %s to index of type %s", ErrFieldType, fields[idx].typ, indexType)
+	}
+	series := make([]*IndexedSeries[I], len(fields))
+	for i, field := range fields {
+		if i != idx {
+			series[i] = NewIndexedSeries[I, any](field.column, nil)
+		}
+	}
+	for r := range rows {
+		row, ok := structValue(reflect.ValueOf(&rows[r]).Elem())
+		if !ok {
+			continue
+		}
+		index := row.FieldByIndex(fields[idx].index).Convert(indexType).Interface().(I)
+		for i, field := range fields {
+			if i != idx {
+				series[i].Insert(index, row.FieldByIndex(field.index).Interface())
+			}
+		}
+	}
+	frame := NewIndexedFrame[I]()
+	for i, s := range series {
+		if i != idx {
+			if err := frame.PushSeries(s); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return frame, nil
+}
+
+// IndexedToStructs returns a T for each row of frame like ToStructs, with the field tagged with the index option set to the index of the row. The rows are in index order.
+func IndexedToStructs[I Index, T any](frame *IndexedFrame[I]) ([]T, error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	fields, err := structFields(t)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]T, frame.Len())
+	for r := range out {
+		row, values := newStruct(t)
+		for _, field := range fields {
+			var val any
+			if field.isIdx {
+				if index := frame.Index(r); index != nil {
+					val = *index
+				}
+			} else if series := frame.Series(field.column); series != nil {
+				val = series.Value(r)
+			}
+			if err := setField(values.FieldByIndex(field.index), field.column, val); err != nil {
+				return nil, fmt.Errorf("row %d: %w", r, err)
+			}
+		}
+		out[r] = row.Interface().(T)
+	}
+	return out, nil
+}
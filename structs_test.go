@@ -0,0 +1,76 @@
+package autotrader
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type fundamentals struct {
+	Date     time.Time
+	Earnings float64 `frame:"EPS"`
+	Shares   int64
+	Note     string `frame:"-"`
+	hidden   int
+}
+
+func TestStructs(t *testing.T) {
+	date := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := []fundamentals{
+		{Date: date, Earnings: 1.5, Shares: 100, Note: "skipped"},
+		{Date: date.AddDate(0, 3, 0), Earnings: 1.75, Shares: 120},
+	}
+	frame, err := FromStructs(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := frame.Names(); len(names) != 3 || !frame.Contains("Date", "EPS", "Shares") {
+		t.Fatalf("Expected columns Date, EPS, and Shares, got %v", names)
+	}
+	if frame.Len() != 2 || frame.Float("EPS", 1) != 1.75 || !frame.Date(1).Equal(rows[1].Date) {
+		t.Errorf("Expected 2 rows with the second EPS 1.75, got %d rows and %f", frame.Len(), frame.Float("EPS", 1))
+	}
+
+	frame.Series("Shares").SetValue(0, 150) // An int is converted to the int64 field.
+	frame.Series("EPS").SetValue(1, nil)
+	out, err := ToStructs[*fundamentals](frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 2 || out[0].Shares != 150 || out[0].Note != "" || out[1].Earnings != 0 || !out[1].Date.Equal(rows[1].Date) {
+		t.Errorf("Expected the rows to round trip with the changes, got %+v and %+v", *out[0], *out[1])
+	}
+
+	frame.Series("Shares").SetValue(0, "many")
+	if _, err := ToStructs[fundamentals](frame); !errors.Is(err, ErrFieldType) {
+		t.Errorf("Expected ErrFieldType, got %v", err)
+	}
+	if _, err := FromStructs([]int{1}); !errors.Is(err, ErrNotStruct) {
+		t.Errorf("Expected ErrNotStruct, got %v", err)
+	}
+}
+
+func TestIndexedStructs(t *testing.T) {
+	type sentiment struct {
+		Time  int64 `frame:",index"`
+		Score float64
+	}
+	rows := []*sentiment{{Time: 200, Score: -0.5}, nil, {Time: 100, Score: 0.25}}
+	frame, err := IndexedFromStructs[UnixTime](rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frame.Len() != 2 || *frame.Index(0) != 100 || frame.FloatIndex("Score", 200) != -0.5 {
+		t.Errorf("Expected 2 rows sorted by index, got %d rows starting at %v", frame.Len(), frame.Index(0))
+	}
+	out, err := IndexedToStructs[UnixTime, sentiment](frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 2 || out[0] != (sentiment{100, 0.25}) || out[1] != (sentiment{200, -0.5}) {
+		t.Errorf("Expected the rows in index order, got %v", out)
+	}
+	if _, err := IndexedFromStructs[UnixTime]([]fundamentals{}); !errors.Is(err, ErrMissingIndex) {
+		t.Errorf("Expected ErrMissingIndex, got %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package autotrader
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+)
+
+// SeedSweep is the result of running the same backtest with different slippage seeds. It shows how much the outcome of a strategy depends on lucky fills.
+type SeedSweep struct {
+	Seeds      []uint64          // Seeds are the slippage seeds of each run.
+	Summaries  []BacktestSummary // Summaries are the results of each run, in the same order as Seeds.
+	Mean       float64           // Mean is the average net profit of the runs.
+	Std        float64           // Std is the standard deviation of the net profit of the runs.
+	Worst      float64           // Worst is the lowest net profit of any run.
+	Best       float64           // Best is the highest net profit of any run.
+	Profitable int               // Profitable is the number of runs that made a profit.
+	// Locale formats the money printed by Print. DefaultLocale is used if nil.
+	Locale *Locale
+}
+
+// LuckDependent returns true if only some of the runs were profitable, which means the profitability of the strategy depends on the fills it happened to get.
+func (s *SeedSweep) LuckDependent() bool {
+	return s.Profitable > 0 && s.Profitable < len(s.Summaries)
+}
+
+// Print writes a table describing the distribution of outcomes to w.
+func (s *SeedSweep) Print(w io.Writer) error {
+	l := DefaultLocale
+	if s.Locale != nil {
+		l = *s.Locale
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	fmt.Fprintln(tw)
+	fmt.Fprintf(tw, "Runs:\t%d\t\n", len(s.Summaries))
+	fmt.Fprintf(tw, "Profitable:\t%d (%.0f%%)\t\n", s.Profitable, 100*float64(s.Profitable)/float64(len(s.Summaries)))
+	fmt.Fprintf(tw, "Mean Net Profit:\t%s\t\n", l.Money(s.Mean))
+	fmt.Fprintf(tw, "Std Net Profit:\t%s\t\n", l.Money(s.Std))
+	fmt.Fprintf(tw, "Worst Net Profit:\t%s\t\n", l.Money(s.Worst))
+	fmt.Fprintf(tw, "Best Net Profit:\t%s\t\n", l.Money(s.Best))
+	if s.LuckDependent() {
+		fmt.Fprintln(tw, "WARNING:\tprofitability depends on lucky fills\t")
+	}
+	fmt.Fprintln(tw)
+	return tw.Flush()
+}
+
+// RunSeedSweep runs a backtest n times with the slippage seeds 1 through n and returns the distribution of outcomes. newTrader must return a new Trader with a new *TestBroker and Strategy each time it is called, since a Trader cannot be run twice. The seed of the broker is overwritten.
+func RunSeedSweep(n int, newTrader func() *Trader) (*SeedSweep, error) {
+	sweep := &SeedSweep{
+		Seeds:     make([]uint64, 0, n),
+		Summaries: make([]BacktestSummary, 0, n),
+		Worst:     math.Inf(1),
+		Best:      math.Inf(-1),
+	}
+	for i := 1; i <= n; i++ {
+		trader := newTrader()
+		broker, ok := trader.Broker.(*TestBroker)
+		if !ok {
+			return nil, fmt.Errorf("%w: got %T", ErrNotTestBroker, trader.Broker)
+		}
+		broker.Seed = uint64(i)
+		summary, err := RunBacktest(trader)
+		if err != nil {
+			return nil, err
+		}
+		sweep.Seeds = append(sweep.Seeds, broker.Seed)
+		sweep.Summaries = append(sweep.Summaries, summary)
+	}
+
+	for _, summary := range sweep.Summaries {
+		sweep.Mean += summary.NetProfit
+		sweep.Worst = Min(sweep.Worst, summary.NetProfit)
+		sweep.Best = Max(sweep.Best, summary.NetProfit)
+		if summary.NetProfit > 0 {
+			sweep.Profitable++
+		}
+	}
+	if n > 0 {
+		sweep.Mean /= float64(n)
+		for _, summary := range sweep.Summaries {
+			sweep.Std += math.Pow(summary.NetProfit-sweep.Mean, 2)
+		}
+		sweep.Std = math.Sqrt(sweep.Std / float64(n))
+	} else {
+		sweep.Worst, sweep.Best = 0, 0
+	}
+	return sweep, nil
+}
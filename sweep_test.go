@@ -0,0 +1,66 @@
+package autotrader
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func newSweepTrader() *Trader {
+	broker := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	broker.Slippage = 0.05
+	trader := NewTrader(TraderConfig{
+		Broker:        broker,
+		Strategy:      &roundTripStrategy{},
+		Symbol:        "EUR_USD",
+		Frequency:     "D",
+		CandlesToKeep: 5,
+	})
+	trader.Log.SetOutput(io.Discard)
+	return trader
+}
+
+func TestRunSeedSweep(t *testing.T) {
+	sweep, err := RunSeedSweep(5, newSweepTrader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sweep.Summaries) != 5 || len(sweep.Seeds) != 5 {
+		t.Fatalf("Expected 5 runs, got %d", len(sweep.Summaries))
+	}
+	if sweep.Std <= 0 {
+		t.Errorf("Expected different seeds to produce different outcomes, got std %f", sweep.Std)
+	}
+	if sweep.Worst > sweep.Mean || sweep.Best < sweep.Mean {
+		t.Errorf("Expected worst <= mean <= best, got %f, %f, %f", sweep.Worst, sweep.Mean, sweep.Best)
+	}
+
+	again, err := RunSeedSweep(5, newSweepTrader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range sweep.Summaries {
+		if sweep.Summaries[i].NetProfit != again.Summaries[i].NetProfit {
+			t.Errorf("Expected seed %d to reproduce net profit %f, got %f", sweep.Seeds[i], sweep.Summaries[i].NetProfit, again.Summaries[i].NetProfit)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := sweep.Print(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Worst Net Profit:") {
+		t.Errorf("Expected the sweep table to be printed, got %q", out.String())
+	}
+}
+
+func TestRunSeedSweepRequiresTestBroker(t *testing.T) {
+	_, err := RunSeedSweep(1, func() *Trader {
+		return NewTrader(TraderConfig{Broker: &countingBroker{}, Strategy: &roundTripStrategy{}})
+	})
+	if !errors.Is(err, ErrNotTestBroker) {
+		t.Errorf("Expected ErrNotTestBroker, got %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+package autotrader
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"golang.org/x/exp/rand"
+)
+
+var ErrInvalidModel = errors.New("invalid synthetic market model")
+
+// SyntheticModel is the model of the prices generated by Synthetic.
+type SyntheticModel string
+
+const (
+	GBM             SyntheticModel = "gbm"    // GBM is a geometric Brownian motion, a random walk of the log price with a constant Drift and Volatility.
+	MeanReverting   SyntheticModel = "ou"     // MeanReverting is an Ornstein-Uhlenbeck process of the log price, which is pulled back toward the Mean at the speed of MeanReversion.
+	RegimeSwitching SyntheticModel = "regime" // RegimeSwitching is a geometric Brownian motion that switches between the drifts and volatilities of its Regimes, like a calm bull market and a volatile bear market.
+)
+
+// Regime is a state of a RegimeSwitching market.
+type Regime struct {
+	Drift      float64 // Drift is the mean log return of a candle, like 0.001 for a trend of about 0.1% per candle.
+	Volatility float64 // Volatility is the standard deviation of the log return of a candle.
+}
+
+// SyntheticConfig configures the candles generated by Synthetic. Drifts and volatilities are per candle of Frequency.
+type SyntheticConfig struct {
+	Model     SyntheticModel // Model is the model of the prices. The default is GBM.
+	Candles   int            // Candles is the number of candles to generate.
+	Start     time.Time      // Start is the date of the first candle. The default is 2000-01-01 UTC.
+	Frequency string         // Frequency is the frequency of the candles, like "H1". The default is "D".
+	Price     float64        // Price is the open of the first candle. The default is 100.
+	// Drift is the mean log return of a candle, like 0.001 for an uptrend of about 0.1% per candle or a negative number for a downtrend.
+	Drift float64
+	// Volatility is the standard deviation of the log return of a candle. The default is 0.01.
+	Volatility float64
+	// MeanReversion is the fraction of the distance of the log price from the log of Mean that a MeanReverting price moves back in each candle, between 0 and 1. The default is 0.1.
+	MeanReversion float64
+	Mean          float64 // Mean is the price a MeanReverting price reverts to. The default is Price.
+	// Regimes are the states of a RegimeSwitching market. The market starts in the first regime.
+	Regimes []Regime
+	// SwitchProbability is the probability that a RegimeSwitching market switches to another regime, chosen at random, on each candle. The default is 0.01.
+	SwitchProbability float64
+	// Volume is the mean volume of a candle. The volume is higher on candles with larger moves. The default is 1000.
+	Volume int64
+	// Seed seeds the random numbers, so the same config always generates the same candles.
+	Seed uint64
+}
+
+// syntheticSteps is the number of steps each candle is simulated in, which gives the candles realistic highs and lows.
+const syntheticSteps = 8
+
+// Synthetic generates candles of a modeled market, so strategies can be stress-tested against markets with known properties, like trends, mean reversion, or sudden changes of volatility, and tests can use realistic data without bundled CSV files. Each candle opens at the close of the previous candle and is simulated in several steps, and its high and low are the extremes of the steps. A RegimeSwitching market also has a Regime column of the index of the regime of each candle.
+func Synthetic(config SyntheticConfig) (*IndexedFrame[UnixTime], error) {
+	if config.Model == "" {
+		config.Model = GBM
+	}
+	if config.Candles <= 0 {
+		return nil, fmt.Errorf("%w: %d candles", ErrInvalidModel, config.Candles)
+	}
+	if config.Start.IsZero() {
+		config.Start = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	if config.Frequency == "" {
+		config.Frequency = "D"
+	}
+	if _, err := FrequencyDuration(config.Frequency); err != nil {
+		return nil, err
+	}
+	if config.Price <= 0 {
+		config.Price = 100
+	}
+	if config.Volatility <= 0 {
+		config.Volatility = 0.01
+	}
+	if config.MeanReversion <= 0 {
+		config.MeanReversion = 0.1
+	}
+	if config.Mean <= 0 {
+		config.Mean = config.Price
+	}
+	if config.SwitchProbability <= 0 {
+		config.SwitchProbability = 0.01
+	}
+	if config.Volume <= 0 {
+		config.Volume = 1000
+	}
+	switch config.Model {
+	case GBM, MeanReverting:
+	case RegimeSwitching:
+		if len(config.Regimes) == 0 {
+			return nil, fmt.Errorf("%w: a regime switching market needs regimes", ErrInvalidModel)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidModel, config.Model)
+	}
+
+	r := rand.New(rand.NewSource(config.Seed))
+	n := config.Candles
+	indexes := make([]UnixTime, n)
+	opens, highs, lows, closes, volumes := make([]any, n), make([]any, n), make([]any, n), make([]any, n), make([]any, n)
+	var regimes []any
+	if config.Model == RegimeSwitching {
+		regimes = make([]any, n)
+	}
+	date, price := config.Start, config.Price
+	logPrice, logMean := math.Log(price), math.Log(config.Mean)
+	regime := 0
+	for i := 0; i < n; i++ {
+		drift, volatility := config.Drift, config.Volatility
+		if regimes != nil {
+			if len(config.Regimes) > 1 && r.Float64() < config.SwitchProbability {
+				regime = (regime + 1 + r.Intn(len(config.Regimes)-1)) % len(config.Regimes) // Any regime but the current one.
+			}
+			drift, volatility = config.Regimes[regime].Drift, config.Regimes[regime].Volatility
+			regimes[i] = regime
+		}
+		logOpen := logPrice
+		high, low := logPrice, logPrice
+		for step := 0; step < syntheticSteps; step++ {
+			if config.Model == MeanReverting {
+				logPrice += config.MeanReversion / syntheticSteps * (logMean - logPrice)
+			}
+			logPrice += drift/syntheticSteps + volatility/math.Sqrt(syntheticSteps)*r.NormFloat64()
+			high, low = math.Max(high, logPrice), math.Min(low, logPrice)
+		}
+		closePrice := math.Exp(logPrice)
+		indexes[i] = UnixTime(date.Unix())
+		opens[i], closes[i] = price, closePrice
+		highs[i], lows[i] = math.Max(math.Exp(high), math.Max(price, closePrice)), math.Min(math.Exp(low), math.Min(price, closePrice))
+		price = closePrice
+		// Larger moves trade more volume.
+		move := math.Abs(logPrice-logOpen) / math.Max(volatility, 1e-12)
+		volumes[i] = int64(math.Round(float64(config.Volume) * (0.5 + 0.5*move) * math.Exp(0.25*r.NormFloat64())))
+		date = periodEnd(date, config.Frequency)
+	}
+
+	series := func(name string, vals []any) *IndexedSeries[UnixTime] {
+		return &IndexedSeries[UnixTime]{&SignalManager{}, NewSeries(name, vals...), append([]UnixTime(nil), indexes...)}
+	}
+	frame := NewIndexedFrame(series("Open", opens), series("High", highs), series("Low", lows), series("Close", closes), series("Volume", volumes))
+	if regimes != nil {
+		if err := frame.PushSeries(series("Regime", regimes)); err != nil {
+			return nil, err
+		}
+	}
+	return frame, nil
+}
@@ -0,0 +1,78 @@
+package autotrader
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSynthetic(t *testing.T) {
+	candles, err := Synthetic(SyntheticConfig{Candles: 1000, Frequency: "H1", Drift: 0.001, Volatility: 0.005, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if candles.Len() != 1000 {
+		t.Fatalf("Expected 1000 candles, got %d", candles.Len())
+	}
+	if start := candles.Date(0).Time().UTC(); !start.Equal(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)) || candles.Date(1).Time().Sub(start) != time.Hour {
+		t.Errorf("Expected hourly candles from 2000-01-01, got %v and %v", start, candles.Date(1).Time())
+	}
+	if candles.Open(0) != 100 {
+		t.Errorf("Expected the first open to be 100, got %v", candles.Open(0))
+	}
+	for i := 0; i < candles.Len(); i++ {
+		if high, low := candles.High(i), candles.Low(i); high < math.Max(candles.Open(i), candles.Close(i)) || low > math.Min(candles.Open(i), candles.Close(i)) {
+			t.Fatalf("Expected candle %d to contain its open and close", i)
+		}
+		if i > 0 && candles.Open(i) != candles.Close(i-1) {
+			t.Fatalf("Expected candle %d to open at the previous close", i)
+		}
+	}
+	// A drift of 0.1% over 1000 candles trends up by about e^1.
+	if trend := math.Log(candles.Close(-1) / candles.Open(0)); trend < 0.7 || trend > 1.3 {
+		t.Errorf("Expected a log trend of about 1, got %v", trend)
+	}
+	again, _ := Synthetic(SyntheticConfig{Candles: 1000, Frequency: "H1", Drift: 0.001, Volatility: 0.005, Seed: 1})
+	if again.Close(-1) != candles.Close(-1) {
+		t.Error("Expected the same seed to generate the same candles")
+	}
+}
+
+func TestSyntheticMeanReverting(t *testing.T) {
+	candles, err := Synthetic(SyntheticConfig{Model: MeanReverting, Candles: 2000, Price: 50, Mean: 100, MeanReversion: 0.2, Volatility: 0.01})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mean := candles.Closes().CopyRange(1000, -1).series.Mean(); math.Abs(mean-100) > 5 {
+		t.Errorf("Expected the price to revert to about 100, got a mean of %v", mean)
+	}
+}
+
+func TestSyntheticRegimeSwitching(t *testing.T) {
+	regimes := []Regime{{Drift: 0.001, Volatility: 0.002}, {Drift: -0.002, Volatility: 0.03}}
+	candles, err := Synthetic(SyntheticConfig{Model: RegimeSwitching, Candles: 5000, Regimes: regimes, SwitchProbability: 0.02, Seed: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sum [2]float64
+	var count [2]int
+	for i := 1; i < candles.Len(); i++ {
+		regime := candles.Value("Regime", i).(int)
+		sum[regime] += math.Abs(math.Log(candles.Close(i) / candles.Close(i-1)))
+		count[regime]++
+	}
+	if count[0] == 0 || count[1] == 0 {
+		t.Fatalf("Expected both regimes, got %v candles", count)
+	}
+	if calm, volatile := sum[0]/float64(count[0]), sum[1]/float64(count[1]); volatile < 5*calm {
+		t.Errorf("Expected the second regime to be much more volatile, got %v and %v", calm, volatile)
+	}
+
+	if _, err := Synthetic(SyntheticConfig{Model: RegimeSwitching, Candles: 10}); !errors.Is(err, ErrInvalidModel) {
+		t.Errorf("Expected ErrInvalidModel without regimes, got %v", err)
+	}
+	if _, err := Synthetic(SyntheticConfig{Model: "jump", Candles: 10}); !errors.Is(err, ErrInvalidModel) {
+		t.Errorf("Expected ErrInvalidModel for an unknown model, got %v", err)
+	}
+}
@@ -0,0 +1,324 @@
+package autotrader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TelegramBot supervises a live Trader from a Telegram chat. It reports every fill and closed position and the profit or loss of each day, and accepts the commands /status, /positions, /setups, /pause, /resume, and /flatten, which are mapped onto Trader.Pause, Trader.Resume, and Trader.Flatten. /setups lists the pending setups of the strategy. Only messages from ChatID are obeyed, so nobody else who finds the bot can control the Trader. Create a bot with @BotFather to get a token.
+//
+// Set the Telegram bot of a Trader and it is started by RunContext. A TelegramBot is also a Notifier, so it can receive the alerts of a Watchdog.
+type TelegramBot struct {
+	Token    string         // Token is the token of the bot given by @BotFather.
+	ChatID   int64          // ChatID is the chat that receives the reports and is allowed to send commands.
+	Location *time.Location // Location is where days start for the daily profit and loss. The default is UTC.
+	Client   *http.Client   // Client sends the requests. If nil, http.DefaultClient is used.
+	BaseURL  string         // BaseURL is the URL of the Bot API. The default is https://api.telegram.org.
+	// PollTimeout is how long a request for updates waits for a message before it is repeated. The default is 30 seconds.
+	PollTimeout time.Duration
+
+	mu       sync.Mutex
+	outbox   chan string
+	offset   int64
+	day      time.Time // day is the start of the day of the daily profit and loss.
+	dayStart float64   // dayStart is the NAV at the start of day.
+}
+
+// telegramUpdate is an update returned by the getUpdates method of the Bot API.
+type telegramUpdate struct {
+	UpdateID int64 `json:"update_id"`
+	Message  *struct {
+		Chat struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+		Text string `json:"text"`
+	} `json:"message"`
+}
+
+// Notify sends message to the chat. It is queued while the bot is running, so it never blocks the caller on the network.
+func (b *TelegramBot) Notify(message string) error {
+	b.mu.Lock()
+	outbox := b.outbox
+	b.mu.Unlock()
+	if outbox != nil {
+		select {
+		case outbox <- message:
+			return nil
+		default: // The queue is full, so send it directly.
+		}
+	}
+	return b.send(context.Background(), message)
+}
+
+// Run reports the fills and daily profit or loss of t and obeys commands until ctx is done.
+func (b *TelegramBot) Run(ctx context.Context, t *Trader) error {
+	defer b.connect(t)()
+	return b.run(ctx, t)
+}
+
+// connect starts the day and connects the reports of fills and closed positions to the Broker of t. It returns a function that disconnects them. The Trader connects the bot before running it in the background, so the handlers are connected before the Trader starts ticking.
+func (b *TelegramBot) connect(t *Trader) (disconnect func()) {
+	t.mu.Lock()
+	day, nav := b.startOfDay(t.Now()), t.Broker.NAV()
+	t.mu.Unlock()
+	b.mu.Lock()
+	b.outbox = make(chan string, 100)
+	b.day, b.dayStart = day, nav
+	b.mu.Unlock()
+
+	onFill := func(args ...any) {
+		b.Notify(fillMessage(args[0].(Order)))
+	}
+	onClose := func(args ...any) {
+		b.Notify(closeMessage(args[0].(Position)))
+	}
+	t.Broker.SignalConnect(OrderFulfilled, b, onFill)
+	t.Broker.SignalConnect(PositionClosed, b, onClose)
+	return func() {
+		t.Broker.SignalDisconnect(OrderFulfilled, b, onFill)
+		t.Broker.SignalDisconnect(PositionClosed, b, onClose)
+		b.mu.Lock()
+		b.outbox = nil
+		b.mu.Unlock()
+	}
+}
+
+// run sends the queued reports and the daily profit or loss of t and obeys commands until ctx is done. The bot must be connected.
+func (b *TelegramBot) run(ctx context.Context, t *Trader) error {
+	b.mu.Lock()
+	outbox := b.outbox
+	b.mu.Unlock()
+
+	commands := make(chan string)
+	go b.poll(ctx, t, commands)
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case message := <-outbox:
+			if err := b.send(ctx, message); err != nil && ctx.Err() == nil {
+				t.Log.Printf("error sending telegram message: %v", err)
+			}
+		case command := <-commands:
+			if err := b.send(ctx, b.Command(t, command)); err != nil && ctx.Err() == nil {
+				t.Log.Printf("error sending telegram reply: %v", err)
+			}
+		case <-ticker.C:
+			if report := b.dailyReport(t); report != "" {
+				if err := b.send(ctx, report); err != nil && ctx.Err() == nil {
+					t.Log.Printf("error sending telegram daily report: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// poll sends the commands of the chat to commands until ctx is done. Failed requests are retried after a few seconds.
+func (b *TelegramBot) poll(ctx context.Context, t *Trader, commands chan<- string) {
+	for ctx.Err() == nil {
+		updates, err := b.updates(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				t.Log.Printf("error getting telegram updates: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(5 * time.Second):
+			}
+			continue
+		}
+		for _, update := range updates {
+			b.offset = update.UpdateID + 1
+			if update.Message == nil || update.Message.Chat.ID != b.ChatID {
+				continue
+			}
+			select {
+			case commands <- update.Message.Text:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// Command executes a command like "/status" on t and returns the reply. Commands are case insensitive, may omit the slash, and may be addressed to the bot like "/status@mybot". It waits for a tick in progress to finish, so it must not be called by the strategy.
+func (b *TelegramBot) Command(t *Trader, command string) string {
+	name, _, _ := strings.Cut(strings.TrimSpace(command), " ")
+	name, _, _ = strings.Cut(strings.ToLower(strings.TrimPrefix(name, "/")), "@")
+	switch name {
+	case "status":
+		state := "running"
+		if t.Paused() {
+			state = "paused"
+		}
+		t.mu.Lock()
+		nav, pl, open := t.Broker.NAV(), t.Broker.PL(), len(t.Broker.OpenPositions())
+		t.mu.Unlock()
+		return fmt.Sprintf("%s on %s is %s\nNAV: $%.2f\nPL: $%.2f\nOpen positions: %d\nToday: $%.2f",
+			strategyName(t.Strategy), t.Symbol, state, nav, pl, open, b.dayPL(nav))
+	case "positions":
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		positions := t.Broker.OpenPositions()
+		if len(positions) == 0 {
+			return "No open positions"
+		}
+		lines := make([]string, len(positions))
+		for i, position := range positions {
+			lines[i] = fmt.Sprintf("%s: %v %s @ %.5f, PL $%.2f", position.Id(), position.Units(), position.Symbol(), position.EntryPrice(), position.PL())
+		}
+		return strings.Join(lines, "\n")
+	case "setups":
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if len(t.setups) == 0 {
+			return "No pending setups"
+		}
+		lines := make([]string, len(t.setups))
+		for i, setup := range t.setups {
+			lines[i] = fmt.Sprintf("%s: %v %s, added %s", setup.Name, setup.Units, setup.Symbol, setup.Created.Format(time.DateTime))
+			if setup.Expiry > 0 {
+				lines[i] += fmt.Sprintf(", expires in %d candles", setup.Expiry-setup.checked)
+			}
+		}
+		return strings.Join(lines, "\n")
+	case "pause":
+		t.Pause()
+		return "Paused. Open positions are still managed. Send /resume to continue."
+	case "resume":
+		t.Resume()
+		return "Resumed"
+	case "flatten":
+		t.Flatten()
+		return fmt.Sprintf("Closed the orders and positions of %s", t.Symbol)
+	default:
+		return "Commands: /status, /positions, /setups, /pause, /resume, /flatten"
+	}
+}
+
+// dailyReport returns the profit or loss of the day if the day of the Trader changed since it was last called and starts the new day, otherwise an empty string.
+func (b *TelegramBot) dailyReport(t *Trader) string {
+	t.mu.Lock()
+	day, nav := b.startOfDay(t.Now()), t.Broker.NAV()
+	t.mu.Unlock()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !day.After(b.day) {
+		return ""
+	}
+	report := fmt.Sprintf("Daily PL for %s: $%.2f (NAV $%.2f)", b.day.Format(time.DateOnly), nav-b.dayStart, nav)
+	b.day, b.dayStart = day, nav
+	return report
+}
+
+// dayPL returns the profit or loss of the current day at nav.
+func (b *TelegramBot) dayPL(nav float64) float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return nav - b.dayStart
+}
+
+func (b *TelegramBot) startOfDay(now time.Time) time.Time {
+	location := b.Location
+	if location == nil {
+		location = time.UTC
+	}
+	year, month, day := now.In(location).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, location)
+}
+
+func fillMessage(order Order) string {
+	side := "Bought"
+	if order.Units() < 0 {
+		side = "Sold"
+	}
+	price := order.Price()
+	if position := order.Position(); position != nil {
+		price = position.EntryPrice()
+	}
+	return fmt.Sprintf("%s %v %s @ %.5f", side, Abs(order.Units()), order.Symbol(), price)
+}
+
+func closeMessage(position Position) string {
+	msg := fmt.Sprintf("Closed %v %s @ %.5f, PL $%.2f", position.Units(), position.Symbol(), position.ClosePrice(), position.PL())
+	if position.CloseType() != "" {
+		msg += " (" + string(position.CloseType()) + ")"
+	}
+	return msg
+}
+
+// updates returns the new updates of the bot, waiting up to PollTimeout for one.
+func (b *TelegramBot) updates(ctx context.Context) ([]telegramUpdate, error) {
+	timeout := b.PollTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	query := url.Values{"offset": {strconv.FormatInt(b.offset, 10)}, "timeout": {strconv.Itoa(int(timeout.Seconds()))}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.methodURL("getUpdates")+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var updates []telegramUpdate
+	return updates, b.do(req, &updates)
+}
+
+// send sends text to the chat.
+func (b *TelegramBot) send(ctx context.Context, text string) error {
+	form := url.Values{"chat_id": {strconv.FormatInt(b.ChatID, 10)}, "text": {text}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.methodURL("sendMessage"), strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return b.do(req, nil)
+}
+
+// do sends a request to the Bot API and decodes its result into result if it is not nil.
+func (b *TelegramBot) do(req *http.Request, result any) error {
+	client := b.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) { // The URL contains the token, which must not end up in logs.
+			return fmt.Errorf("telegram request failed: %w", urlErr.Err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	var body struct {
+		OK          bool            `json:"ok"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("telegram responded with status %s", resp.Status)
+	}
+	if !body.OK {
+		return fmt.Errorf("telegram responded with status %s: %s", resp.Status, body.Description)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(body.Result, result)
+}
+
+func (b *TelegramBot) methodURL(method string) string {
+	base := b.BaseURL
+	if base == "" {
+		base = "https://api.telegram.org"
+	}
+	return strings.TrimSuffix(base, "/") + "/bot" + b.Token + "/" + method
+}
@@ -0,0 +1,103 @@
+package autotrader
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTelegramBot(t *testing.T) {
+	replies := make(chan string, 10)
+	updates := []map[string]any{
+		{"update_id": 1, "message": map[string]any{"chat": map[string]any{"id": 42}, "text": "/pause"}},
+		{"update_id": 2, "message": map[string]any{"chat": map[string]any{"id": 7}, "text": "/flatten"}}, // Another chat is ignored.
+		{"update_id": 3, "message": map[string]any{"chat": map[string]any{"id": 42}, "text": "/positions@testbot"}},
+		{"update_id": 4, "message": map[string]any{"chat": map[string]any{"id": 42}, "text": "/flatten"}},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/botTOKEN/getUpdates":
+			var result []map[string]any
+			if r.URL.Query().Get("offset") == "0" {
+				result = updates
+			} else {
+				<-r.Context().Done() // Wait like a long poll until the bot stops.
+			}
+			json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": result})
+		case "/botTOKEN/sendMessage":
+			r.ParseForm()
+			if chat := r.Form.Get("chat_id"); chat != "42" {
+				t.Errorf("Expected messages to be sent to chat 42, got %s", chat)
+			}
+			replies <- r.Form.Get("text")
+			json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": map[string]any{}})
+		default:
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	broker := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	broker.Slippage = 0
+	bot := &TelegramBot{Token: "TOKEN", ChatID: 42, BaseURL: server.URL}
+	trader := NewTrader(TraderConfig{Broker: broker, Strategy: &roundTripStrategy{}, Symbol: "EUR_USD", Frequency: "D", CandlesToKeep: 5, Telegram: bot})
+	trader.Log.SetOutput(io.Discard)
+	if _, err := broker.Order(context.Background(), Market, "EUR_USD", 1000, 0, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- bot.Run(ctx, trader) }()
+	var sent []string
+	timeout := time.After(5 * time.Second)
+	for len(sent) < 4 {
+		select {
+		case message := <-replies:
+			sent = append(sent, message)
+		case <-timeout:
+			t.Fatalf("Expected 4 messages, got %d: %q", len(sent), sent)
+		}
+	}
+	cancel()
+	<-done
+	if !strings.HasPrefix(sent[0], "Paused") || !trader.Paused() {
+		t.Errorf("Expected the trader to be paused, got %q", sent[0])
+	}
+	if !strings.Contains(sent[1], "1000 EUR_USD @ 1.15000") {
+		t.Errorf("Expected the open position to be listed, got %q", sent[1])
+	}
+	joined := strings.Join(sent[2:], "\n")
+	if !strings.Contains(joined, "Closed the orders and positions of EUR_USD") || !strings.Contains(joined, "Closed 1000 EUR_USD @ 1.15000") {
+		t.Errorf("Expected the flatten reply and the closed position, got %q", sent[2:])
+	}
+	if len(broker.OpenPositions()) != 0 {
+		t.Errorf("Expected no open positions after flatten, got %d", len(broker.OpenPositions()))
+	}
+
+	broker.Advance()
+	if report := bot.dailyReport(trader); report != "Daily PL for 2022-01-01: $0.00 (NAV $100000.00)" {
+		t.Errorf("Expected the daily report of the first day, got %q", report)
+	}
+	if report := bot.dailyReport(trader); report != "" {
+		t.Errorf("Expected one daily report per day, got %q", report)
+	}
+}
+
+func TestTelegramBotErrorHidesToken(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close() // Nothing listens at the URL anymore.
+	bot := &TelegramBot{Token: "123:SECRET", ChatID: 42, BaseURL: server.URL}
+	err := bot.Notify("hello")
+	if err == nil {
+		t.Fatal("Expected an error from an unreachable Bot API")
+	}
+	if strings.Contains(err.Error(), "SECRET") {
+		t.Errorf("Expected the error not to contain the token, got %q", err)
+	}
+}
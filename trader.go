@@ -1,48 +1,273 @@
 package autotrader
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-co-op/gocron"
+	"golang.org/x/exp/slices"
 )
 
 // Trader acts as the primary interface to the broker and strategy. To the strategy, it provides all the information
 // about the current state of the market and the portfolio. To the broker, it provides the orders to be executed and
 // requests for the current state of the portfolio.
 type Trader struct {
-	Broker        Broker
-	Strategy      Strategy
-	Symbol        string
+	Broker   Broker
+	Strategy Strategy
+	Symbol   string
+	// Symbols are the symbols of a basket the strategy trades besides Symbol. Their candles are fetched at the frequency of the Trader on every candle and returned by SymbolData, and orders are placed for them with OrderSymbol. Symbol is still the symbol that drives the candles of the Trader.
+	Symbols       []string
 	Frequency     string
 	CandlesToKeep int
 	Log           *log.Logger
 	EOF           bool
+	Sizer         PositionSizer // Sizer decides the number of units returned by Size. It is optional.
+	Risk          *RiskManager  // Risk checks every order before it is placed. It is optional.
+	Tags          Tags          // Tags are attached to every order placed by the Trader, in addition to any tags given to the order.
+	Journal       Journal       // Journal records every order and position event of the broker. It is optional.
+	// SignalsOnly makes the Trader publish the orders of the strategy to the Publishers instead of sending them to the broker. The broker is still used for data and prices. This is useful for running a signal service or shadow testing a strategy.
+	SignalsOnly bool
+	Publishers  []SignalPublisher // Publishers receive the trade signals in signals-only mode.
+	Timeout     time.Duration     // Timeout bounds each request to the broker. Zero means requests are only bounded by the context of the Trader.
+	Watchdog    *Watchdog         // Watchdog alerts when the Trader stops ticking while running live. It is optional.
+	Clock       Clock             // Clock tells the time returned by Now. If nil, the broker is used if it is a Clock, like the TestBroker of a backtest, and the wall clock otherwise.
+	// CandleDriven makes RunContext tick on the CandleClosed signals of the broker for the symbol and frequency of the Trader instead of polling on a schedule. If the Capabilities of the broker do not include Streaming, the Trader polls on a schedule anyway.
+	CandleDriven bool
+	// ProfileAddr is the address, like "localhost:6060", of an HTTP server of the net/http/pprof profiles that runs while the Trader runs live or in a backtest, for finding the hot spots of a strategy with go tool pprof. If empty, no server is started.
+	ProfileAddr string
+	// SignalMetrics records the signals of the broker and the time taken by each of their handlers, if the broker embeds a SignalManager, so slow event handlers can be found while trading live. They are served as JSON under /debug/signals of ProfileAddr. It is optional.
+	SignalMetrics *SignalMetrics
+	Telegram      *TelegramBot // Telegram reports to and takes commands from a Telegram chat while the Trader runs live. It is optional.
+	// SampleEquity is a frequency finer than Frequency, like "M1" while trading "H1", at which the equity, drawdown, and exposure are also recorded in TraderStats.Samples, so swings within the candles of the strategy are not hidden. "tick" records a sample on every candle of the broker. In a backtest the samples are taken on the candles of the TestBroker, so its Data must be at the finer frequency and its Frequency set to it. Live, samples are taken on a timer. If empty, no samples are recorded.
+	SampleEquity string
+	// StateFile is the file the state of a StatefulStrategy is saved to and loaded from. RunContext loads it after Init, if it exists, and saves it when it stops. If empty, the state is not saved.
+	StateFile string
+	// CheckpointEvery also saves the state to StateFile every that many candles, in live trading and backtests, so a crash loses little state. If zero, the state is only saved when RunContext stops.
+	CheckpointEvery int
+	// Schedule limits when the strategy may place orders, like only during the London session. Orders outside it fail with ErrOutsideSchedule and are counted in TraderStats.Skipped. If nil, the strategy may trade at any time.
+	Schedule *TradingSchedule
+	// Reoptimizer periodically re-optimizes the parameters of the strategy on its trailing candles. It is optional.
+	Reoptimizer *Reoptimizer
+	// Indicators caches the indicators the strategy computes with CachedIndicator. The backtests of an Optimizer share its cache. It is optional.
+	Indicators *IndicatorCache
 
-	data  *IndexedFrame[UnixTime]
-	sched *gocron.Scheduler
-	stats *TraderStats
+	ctx     context.Context // ctx is the context given to RunContext.
+	parents []*ParentOrder  // parents are the orders with an execution algorithm that are still placing child orders.
+	// emulated are the exits of orders that the broker does not support, which the Trader enforces itself.
+	emulated       []*emulatedExit
+	emulatedCloses map[string]OrderCloseType // emulatedCloses are the close types of positions closed by emulated exits by their IDs.
+	emulatedMu     sync.Mutex                // emulatedMu guards emulatedCloses, which is read by the PositionClosed signal of the broker.
+	oco            [][]Order                 // oco are the groups of orders linked by OCO.
+	filled         []Order                   // filled are the orders filled since the last tick, which may be linked by OCO.
+	filledMu       sync.Mutex                // filledMu guards filled, which is appended to by the signals of the broker.
+	setups         []*Setup                  // setups are the pending setups of the strategy.
+	mu             sync.Mutex                // mu is held while ticking, so controls called from other goroutines do not interleave with the strategy.
+	paused         atomic.Bool
+	data           *IndexedFrame[UnixTime]
+	symbolData     map[string]*IndexedFrame[UnixTime] // Candles of the other symbols of the basket.
+	frames         map[string]*IndexedFrame[UnixTime] // Candles of the other frequencies of a MultiFrequencyStrategy.
+	sched          *gocron.Scheduler
+	stats          *TraderStats
 }
 
+// Data returns the last CandlesToKeep candles of the Trader. The same frame is kept and updated on every candle, so a strategy may keep a reference to it between candles.
 func (t *Trader) Data() *IndexedFrame[UnixTime] {
 	return t.data
 }
 
+// DataOf returns the latest closed candles of frequency, which must be the frequency of the Trader or one of the frequencies of a MultiFrequencyStrategy. Nil is returned for any other frequency.
+func (t *Trader) DataOf(frequency string) *IndexedFrame[UnixTime] {
+	if frequency == t.Frequency {
+		return t.data
+	}
+	return t.frames[frequency]
+}
+
+// SymbolData returns the last CandlesToKeep candles of symbol, which must be the Symbol of the Trader or one of its Symbols. Nil is returned for any other symbol. Unlike Data, the candles of other symbols are a new frame on every candle.
+func (t *Trader) SymbolData(symbol string) *IndexedFrame[UnixTime] {
+	if symbol == t.Symbol {
+		return t.data
+	}
+	return t.symbolData[symbol]
+}
+
+// TradesSymbol returns true if symbol is the Symbol of the Trader or one of its Symbols.
+func (t *Trader) TradesSymbol(symbol string) bool {
+	return symbol == t.Symbol || slices.Contains(t.Symbols, symbol)
+}
+
 type TradeStat struct {
-	Price float64 // Price is the price at which the trade was executed. If Exit is true, this is the exit price. Otherwise, this is the entry price.
-	Units float64 // Units is the signed number of units bought or sold.
-	Exit  bool    // Exit is true if the trade was to exit a previous position.
+	Price      float64        // Price is the price at which the trade was executed. If Exit is true, this is the exit price. Otherwise, this is the entry price.
+	Units      float64        // Units is the signed number of units bought or sold.
+	Exit       bool           // Exit is true if the trade was to exit a previous position.
+	Spread     float64        // Spread is the cost of crossing the bid/ask spread on this trade.
+	SpreadPips float64        // SpreadPips is the spread paid on this trade in pips.
+	Commission float64        // Commission is the fee the broker charged for this trade.
+	Slippage   float64        // Slippage is the cost of the trade filling at a worse price than requested. It is negative if the fill was better than requested.
+	Financing  float64        // Financing is the swap or funding paid for holding the position. It is only set on exit trades.
+	PositionID string         // PositionID is the broker's identifier of the position that was opened or closed by the trade.
+	OpenTime   time.Time      // OpenTime is the date of the candle the position was opened on.
+	CloseTime  time.Time      // CloseTime is the date of the candle the position was closed on. It is zero for entry trades.
+	Tags       Tags           // Tags are the tags of the order that opened the position.
+	CloseType  OrderCloseType // CloseType is how the position was closed, like CloseStopLoss or CloseTimeExit. It is empty for entry trades.
+	Gap        bool           // Gap is true if the trade was filled at a price that gapped past the requested price while the market was closed, such as over a weekend.
+	Symbol     string         // Symbol is the symbol that was traded.
+	PL         float64        // PL is the profit or loss of the position in the account currency as reported by the broker. It is only set on exit trades.
+	Entry      *TradeStat     // Entry links an exit trade to the trade that opened its position. It is nil for entry trades and for positions opened before the trader started.
+
+	open float64 // open is the units of the position of an entry that have not been closed yet.
+}
+
+// Duration returns how long the position was held if this is an exit trade, otherwise zero.
+func (s TradeStat) Duration() time.Duration {
+	if !s.Exit || s.OpenTime.IsZero() {
+		return 0
+	}
+	return s.CloseTime.Sub(s.OpenTime)
+}
+
+// Cost returns the total cost of executing the trade, which is the sum of the spread, commission, slippage, and financing.
+func (s TradeStat) Cost() float64 {
+	return s.Spread + s.Commission + s.Slippage + s.Financing
+}
+
+func newTradeStat(price, units float64, exit bool, costs TradeCosts, positionID string, tags Tags) TradeStat {
+	return TradeStat{
+		Price:      price,
+		Units:      units,
+		Exit:       exit,
+		Spread:     costs.Spread,
+		SpreadPips: costs.SpreadPips,
+		Commission: costs.Commission,
+		Slippage:   costs.Slippage,
+		Financing:  costs.Financing,
+		PositionID: positionID,
+		Tags:       tags,
+	}
+}
+
+// gapFilled returns true if the order or position implements GapFill and was filled across a gap.
+func gapFilled(v any) bool {
+	gap, ok := v.(GapFill)
+	return ok && gap.Gapped()
 }
 
 // Financial performance reporting and statistics.
 type TraderStats struct {
-	Dated             *Frame
-	returnsThisCandle float64
-	tradesThisCandle  []TradeStat
+	Dated              *Frame
+	Samples            *Frame // Samples are the Date, Equity, Drawdown, and Exposure sampled at the SampleEquity frequency of the Trader. It is nil if the Trader does not sample.
+	returnsThisCandle  float64
+	tradesThisCandle   []TradeStat
+	openTrades         map[string]*TradeStat // Entry trades of open positions by position ID.
+	recorded           []string              // Names of the columns added by Trader.Record in the order they were first recorded.
+	recordedThisCandle map[string]any
+	annotations        []Annotation
+	skipped            []SkippedSignal
+	setups             []*Setup // Every setup added by the strategy.
+}
+
+// Annotation is a named marker that a strategy placed on a candle, like "regime change" or "news skip". The report draws annotations on the kline and equity charts.
+type Annotation struct {
+	Time time.Time // Time is the date of the annotated candle.
+	Name string
+}
+
+// Annotations returns the annotations placed by the strategy in the order they were made.
+func (s *TraderStats) Annotations() []Annotation {
+	return s.annotations
+}
+
+// statsColumns are the columns of TraderStats.Dated that are always recorded by the Trader.
+var statsColumns = []string{"Date", "Equity", "Profit", "Drawdown", "Returns", "Trades", "Levels", "Currencies"}
+
+// PositionLevels are the exit levels of an open position at the end of a candle. Levels that are not set are zero.
+type PositionLevels struct {
+	PositionID   string
+	StopLoss     float64
+	TakeProfit   float64
+	TrailingStop float64
+}
+
+// Recorded returns the names of the columns that were added to Dated by Trader.Record, in the order they were first recorded.
+func (s *TraderStats) Recorded() []string {
+	return s.recorded
+}
+
+// Trades returns every trade recorded in the Dated Trades column in the order they happened.
+func (s *TraderStats) Trades() []TradeStat {
+	var trades []TradeStat
+	if s.Dated == nil || s.Dated.Series("Trades") == nil {
+		return trades
+	}
+	s.Dated.Series("Trades").ForEach(func(_ int, val any) {
+		if candleTrades, ok := val.([]TradeStat); ok {
+			trades = append(trades, candleTrades...)
+		}
+	})
+	return trades
+}
+
+// SymbolResult is the performance of the trades of one symbol.
+type SymbolResult struct {
+	Trades int     `json:"trades"` // Trades is the number of positions that were opened.
+	Wins   int     `json:"wins"`   // Wins is the number of positions that were closed with a profit.
+	Profit float64 `json:"profit"` // Profit is the sum of the PL of the closed positions.
+	Costs  float64 `json:"costs"`  // Costs are the execution costs of every trade.
+}
+
+// SymbolResults returns the results of the trades of each symbol, so the symbols of a basket can be compared.
+func (s *TraderStats) SymbolResults() map[string]SymbolResult {
+	results := make(map[string]SymbolResult)
+	for _, trade := range s.Trades() {
+		result := results[trade.Symbol]
+		if !trade.Exit {
+			result.Trades++
+		} else {
+			result.Profit += trade.PL
+			if trade.PL > 0 {
+				result.Wins++
+			}
+		}
+		result.Costs += trade.Cost()
+		results[trade.Symbol] = result
+	}
+	return results
+}
+
+// stampTrades sets the open and close times of trades made on the candle at date and links exit trades to their entries.
+func (s *TraderStats) stampTrades(trades []TradeStat, date time.Time) {
+	for i := range trades {
+		trade := &trades[i]
+		if !trade.Exit {
+			trade.OpenTime = date
+			if entry, ok := s.openTrades[trade.PositionID]; ok {
+				entry.open += trade.Units // The order added to a netted position, whose exits stay linked to its first entry.
+				continue
+			}
+			entry := *trade
+			entry.open = trade.Units
+			s.openTrades[trade.PositionID] = &entry
+			continue
+		}
+		trade.CloseTime = date
+		if entry, ok := s.openTrades[trade.PositionID]; ok {
+			trade.OpenTime = entry.OpenTime
+			trade.Entry = entry
+			// A position that was reduced stays linked until all of its units are closed.
+			if entry.open -= trade.Units; EqualApprox(entry.open, 0) {
+				delete(s.openTrades, trade.PositionID)
+			}
+		}
+	}
 }
 
 func (t *Trader) Stats() *TraderStats {
@@ -51,9 +276,28 @@ func (t *Trader) Stats() *TraderStats {
 
 // Run starts the trader. This is a blocking call.
 func (t *Trader) Run() {
+	t.RunContext(context.Background())
+}
+
+// RunContext starts the trader and blocks until ctx is done. Requests to the broker are made with ctx, so a cancelled ctx also abandons any request in flight, and no more candles are processed after ctx is done.
+func (t *Trader) RunContext(ctx context.Context) {
+	t.ctx = ctx
+	if t.CandleDriven && t.Broker.Capabilities().Streaming {
+		t.runCandleDriven(ctx)
+		return
+	} else if t.CandleDriven {
+		t.Log.Printf("The broker does not stream candles, so they are polled every %s instead", t.Frequency)
+	}
 	t.sched = gocron.NewScheduler(time.UTC)
+	t.sched.SingletonModeAll() // A tick that takes longer than the frequency must not overlap with the next one.
 	capitalizedFreq := strings.ToUpper(t.Frequency)
-	if strings.HasPrefix(capitalizedFreq, "S") {
+	if strings.HasPrefix(capitalizedFreq, "MS") {
+		milliseconds, err := strconv.Atoi(t.Frequency[2:])
+		if err != nil {
+			panic(err)
+		}
+		t.sched.Every(milliseconds).Milliseconds()
+	} else if strings.HasPrefix(capitalizedFreq, "S") {
 		seconds, err := strconv.Atoi(t.Frequency[1:])
 		if err != nil {
 			panic(err)
@@ -86,7 +330,135 @@ func (t *Trader) Run() {
 	t.sched.Do(t.Tick) // Set the function to be run when the interval repeats.
 
 	t.Init()
-	t.sched.StartBlocking()
+	if err := t.LoadState(); err != nil {
+		t.Log.Printf("Not starting: %v", err)
+		return
+	}
+	t.startTelegram(ctx)
+	t.sched.StartAsync()
+	t.startWatchdog(ctx)
+	t.startSampler(ctx)
+	t.startProfiler(ctx)
+	<-ctx.Done()
+	t.sched.Stop()
+	t.stopped(ctx)
+}
+
+// runCandleDriven ticks on every CandleClosed signal of the broker for the symbol and frequency of the Trader until ctx is done.
+func (t *Trader) runCandleDriven(ctx context.Context) {
+	var mu sync.Mutex
+	onClose := func(args ...any) {
+		event := args[0].(CandleEvent)
+		if (event.Symbol != "" && event.Symbol != t.Symbol) || (event.Frequency != "" && event.Frequency != t.Frequency) || ctx.Err() != nil {
+			return
+		}
+		if !mu.TryLock() {
+			return // A tick that takes longer than the candle must not overlap with the next one.
+		}
+		defer mu.Unlock()
+		t.Tick()
+	}
+	t.Init()
+	if err := t.LoadState(); err != nil {
+		t.Log.Printf("Not starting: %v", err)
+		return
+	}
+	t.startTelegram(ctx)
+	t.Broker.SignalConnect(CandleClosed, t, onClose)
+	t.startWatchdog(ctx)
+	t.startSampler(ctx)
+	t.startProfiler(ctx)
+	<-ctx.Done()
+	t.Broker.SignalDisconnect(CandleClosed, t, onClose)
+	t.stopped(ctx)
+}
+
+// stopped saves the state of the strategy and logs that the Trader stopped because ctx is done.
+func (t *Trader) stopped(ctx context.Context) {
+	t.mu.Lock()
+	err := t.SaveState()
+	t.mu.Unlock()
+	if err != nil {
+		t.Log.Printf("error saving strategy state: %v", err)
+	}
+	t.Log.Printf("Stopped: %v", ctx.Err())
+}
+
+// startWatchdog runs the Watchdog in the background if there is one.
+func (t *Trader) startWatchdog(ctx context.Context) {
+	if t.Watchdog != nil {
+		go func() {
+			if err := t.Watchdog.Watch(ctx, t); err != nil && ctx.Err() == nil {
+				t.Log.Printf("error running watchdog: %v", err)
+			}
+		}()
+	}
+}
+
+// startTelegram runs the TelegramBot in the background if there is one. Its handlers are connected before it starts.
+func (t *Trader) startTelegram(ctx context.Context) {
+	if t.Telegram != nil {
+		disconnect := t.Telegram.connect(t)
+		go func() {
+			defer disconnect()
+			if err := t.Telegram.run(ctx, t); err != nil && ctx.Err() == nil {
+				t.Log.Printf("error running telegram bot: %v", err)
+			}
+		}()
+	}
+}
+
+// Pause stops running the strategy until Resume is called. The Trader keeps fetching candles and recording stats, and the RiskManager keeps managing exits, so open positions are still protected. It is safe to call from any goroutine.
+func (t *Trader) Pause() {
+	if !t.paused.Swap(true) {
+		t.Log.Println("Paused")
+	}
+}
+
+// Resume runs the strategy again from the next tick after Pause.
+func (t *Trader) Resume() {
+	if t.paused.Swap(false) {
+		t.Log.Println("Resumed")
+	}
+}
+
+// Paused returns true if the Trader was paused by Pause.
+func (t *Trader) Paused() bool {
+	return t.paused.Load()
+}
+
+// Flatten cancels the orders and closes the positions of the symbol like CloseOrdersAndPositions, but is safe to call from any goroutine, like an operator's command, because it waits for a tick in progress to finish. It must not be called by the strategy, which should call CloseOrdersAndPositions instead.
+func (t *Trader) Flatten() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.SignalsOnly && t.data == nil {
+		return // There is no candle to date the signal with.
+	}
+	t.CloseOrdersAndPositions()
+}
+
+// Now returns the current time of the Trader, which is the simulated time of the current candle in a backtest. Use it instead of time.Now for time-based strategy logic, like closing positions before the weekend.
+func (t *Trader) Now() time.Time {
+	if t.Clock != nil {
+		return t.Clock.Now()
+	}
+	return brokerNow(t.Broker)
+}
+
+// Context returns the context the Trader was started with by RunContext, or context.Background if it was not.
+func (t *Trader) Context() context.Context {
+	if t.ctx == nil {
+		return context.Background()
+	}
+	return t.ctx
+}
+
+// brokerContext returns the context for a single request to the broker, which is bounded by Timeout if it is set. The returned cancel function must be called when the request is done.
+func (t *Trader) brokerContext() (context.Context, context.CancelFunc) {
+	if t.Timeout > 0 {
+		return context.WithTimeout(t.Context(), t.Timeout)
+	}
+	return context.WithCancel(t.Context())
 }
 
 func (t *Trader) Init() {
@@ -97,26 +469,110 @@ func (t *Trader) Init() {
 		NewSeries("Profit"),
 		NewSeries("Drawdown"),
 		NewSeries("Returns"),
-		NewSeries("Trades"), // []float64 representing the number of units traded positive for buy, negative for sell.
+		NewSeries("Trades"),     // []float64 representing the number of units traded positive for buy, negative for sell.
+		NewSeries("Levels"),     // []PositionLevels of the open positions of the symbol, or nil.
+		NewSeries("Currencies"), // map[string]float64 of the net exposure to each currency of every open position, or nil.
 	)
 	t.stats.tradesThisCandle = make([]TradeStat, 0, 2)
+	t.stats.openTrades = make(map[string]*TradeStat)
+	t.stats.recorded = nil
+	t.stats.recordedThisCandle = make(map[string]any)
+	t.stats.annotations = nil
+	t.stats.skipped = nil
+	t.stats.setups = nil
+	t.emulated, t.emulatedCloses, t.oco, t.filled, t.setups = nil, make(map[string]OrderCloseType), nil, nil, nil
+	t.stats.Samples = nil
+	if t.SampleEquity != "" {
+		t.stats.Samples = NewFrame(NewSeries("Date"), NewSeries("Equity"), NewSeries("Drawdown"), NewSeries("Exposure"))
+	}
+	if instrumented, ok := t.Broker.(interface{ SignalInstrument(*SignalMetrics) }); ok && t.SignalMetrics != nil {
+		instrumented.SignalInstrument(t.SignalMetrics)
+	}
 	t.Broker.SignalConnect(OrderFulfilled, t, func(a ...any) {
 		order := a[0].(Order)
-		tradeStat := TradeStat{order.Position().EntryPrice(), order.Units(), false}
-		t.stats.tradesThisCandle = append(t.stats.tradesThisCandle, tradeStat)
+		// An order that only closed positions of a broker that nets them is not an entry. Their exits are recorded on PositionClosed.
+		if position := order.Position(); !position.Closed() {
+			units := order.Units()
+			if math.Abs(position.Units()) < math.Abs(units) {
+				units = position.Units() // The rest of the units closed opposite positions.
+			}
+			tradeStat := newTradeStat(position.EntryPrice(), units, false, order.Costs(), position.Id(), order.Tags())
+			tradeStat.Gap = gapFilled(order)
+			tradeStat.Symbol = order.Symbol()
+			t.stats.tradesThisCandle = append(t.stats.tradesThisCandle, tradeStat)
+		}
+		t.orderFilled(order)
+	})
+	t.Broker.SignalConnect(MarginCall, t, func(args ...any) {
+		event := args[0].(MarginEvent)
+		t.Log.Printf("Margin call: NAV of $%.2f is %.0f%% of the $%.2f of margin used", event.NAV, 100*event.Level, event.MarginUsed)
 	})
 	t.Broker.SignalConnect("PositionClosed", t, func(args ...any) {
 		position := args[0].(Position)
-		tradeStat := TradeStat{position.ClosePrice(), position.Units(), true}
+		tradeStat := newTradeStat(position.ClosePrice(), position.Units(), true, position.CloseCosts(), position.Id(), position.Tags())
+		tradeStat.Gap = gapFilled(position)
+		tradeStat.Symbol, tradeStat.PL = position.Symbol(), position.PL()
+		tradeStat.CloseType = position.CloseType()
+		if t.Risk != nil && t.Risk.timeExited(position.Id()) {
+			tradeStat.CloseType = CloseTimeExit
+		}
+		if closeType, ok := t.emulatedClose(position.Id()); ok {
+			tradeStat.CloseType = closeType
+		}
 		t.stats.tradesThisCandle = append(t.stats.tradesThisCandle, tradeStat)
 		t.stats.returnsThisCandle += position.PL()
+		if t.Risk != nil {
+			t.Risk.PositionClosed(t, position)
+		}
 	})
+	if t.Journal != nil {
+		for _, event := range []string{OrderPlaced, OrderFulfilled, OrderCancelled, OrderExpired, PositionClosed, PositionModified} {
+			event := event
+			t.Broker.SignalConnect(event, t.Journal, func(args ...any) {
+				if err := t.Journal.Record(journalEntry(event, args[0], t.Broker, t.Now())); err != nil {
+					t.Log.Printf("error recording %s in the journal: %v", event, err)
+				}
+			})
+		}
+	}
 }
 
 // Tick updates the current state of the market and runs the strategy.
 func (t *Trader) Tick() {
-	t.fetchData()      // Fetch the latest candlesticks from the broker.
-	t.Strategy.Next(t) // Run the strategy.
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.Watchdog != nil {
+		t.Watchdog.Heartbeat()
+	}
+	if err := t.fetchData(); err != nil { // Fetch the latest candlesticks from the broker.
+		t.Log.Printf("Skipping tick: %v", err)
+		return
+	}
+	t.cancelOCO()
+	t.checkEmulatedExits()
+	if t.Risk != nil {
+		t.Risk.ManageExits(t)
+	}
+	if t.Reoptimizer != nil && !t.Paused() {
+		t.Reoptimizer.check(t)
+	}
+	if !t.Paused() && (t.Schedule == nil || t.Schedule.Allowed(t.Now())) {
+		t.executeParents() // Child orders wait for the schedule to allow trading again.
+		t.checkSetups()
+	}
+	if strategy, ok := t.Strategy.(MultiFrequencyStrategy); ok && !t.Paused() {
+		for _, frequency := range t.fetchFrequencies(strategy.Frequencies()) {
+			strategy.OnClose(t, frequency)
+		}
+	}
+	if !t.Paused() {
+		t.Strategy.Next(t) // Run the strategy.
+	}
+	if t.EOF {
+		if ender, ok := t.Strategy.(StrategyEnder); ok {
+			ender.End(t) // Let the strategy clean up before the final stats are recorded.
+		}
+	}
 
 	// Update the stats.
 	err := t.stats.Dated.PushValues(map[string]any{
@@ -146,18 +602,135 @@ func (t *Trader) Tick() {
 			trades := make([]TradeStat, len(t.stats.tradesThisCandle))
 			copy(trades, t.stats.tradesThisCandle)
 			t.stats.tradesThisCandle = t.stats.tradesThisCandle[:0]
+			t.stats.stampTrades(trades, t.data.Date(-1).Time())
 			return trades
 		}(),
+		"Levels": func() any {
+			var levels []PositionLevels
+			for _, position := range t.Broker.OpenPositions() {
+				if position.Symbol() != t.Symbol || position.Closed() {
+					continue
+				}
+				if position.StopLoss() == 0 && position.TakeProfit() == 0 && position.TrailingStop() == 0 {
+					continue
+				}
+				levels = append(levels, PositionLevels{position.Id(), position.StopLoss(), position.TakeProfit(), position.TrailingStop()})
+			}
+			if levels == nil {
+				return nil
+			}
+			return levels
+		}(),
+		"Currencies": func() any {
+			exposure := CurrencyExposure(t.Broker.OpenPositions())
+			if len(exposure) == 0 {
+				return nil
+			}
+			return exposure
+		}(),
 	})
+	if err == nil {
+		err = t.pushRecorded()
+	}
 	if err != nil {
 		log.Printf("error pushing values to stats dataframe: %v\n", err.Error())
 	}
 	t.stats.returnsThisCandle = 0
+	if t.CheckpointEvery > 0 && t.stats.Dated.Len()%t.CheckpointEvery == 0 {
+		if err := t.SaveState(); err != nil {
+			t.Log.Printf("error saving strategy state: %v", err)
+		}
+	}
+}
+
+// Record stores value under name for the current candle, like an indicator reading or a regime flag. When the candle ends, the value is added to a column of TraderStats.Dated, where candles without a value hold nil. Numeric columns are plotted by the RecordedSection of the report. Call Record from the Next method of a strategy.
+func (t *Trader) Record(name string, value any) {
+	if slices.Contains(statsColumns, name) {
+		t.Log.Printf("Cannot record %q because it is a column of the stats", name)
+		return
+	}
+	if !slices.Contains(t.stats.recorded, name) {
+		t.stats.recorded = append(t.stats.recorded, name)
+	}
+	t.stats.recordedThisCandle[name] = value
+}
+
+// Annotate places a marker named name on the latest candle, which the report draws on the kline and equity charts. Call Annotate from the Next method of a strategy to flag events for later analysis.
+func (t *Trader) Annotate(name string) {
+	t.AnnotateAt(t.data.Date(-1).Time(), name)
+}
+
+// AnnotateAt places a marker named name on the candle at date.
+func (t *Trader) AnnotateAt(date time.Time, name string) {
+	t.stats.annotations = append(t.stats.annotations, Annotation{Time: date, Name: name})
 }
 
-func (t *Trader) fetchData() {
-	var err error
-	t.data, err = t.Broker.Candles(t.Symbol, t.Frequency, t.CandlesToKeep)
+// pushRecorded adds the values recorded on the current candle to the stats, creating the columns of new names.
+func (t *Trader) pushRecorded() error {
+	values := make(map[string]any, len(t.stats.recorded))
+	for _, name := range t.stats.recorded {
+		if t.stats.Dated.Series(name) == nil {
+			// Previous candles have no value for a new column.
+			if err := t.stats.Dated.PushSeries(NewSeries(name, make([]any, t.stats.Dated.Len()-1)...)); err != nil {
+				return err
+			}
+		}
+		values[name] = t.stats.recordedThisCandle[name]
+	}
+	for name := range t.stats.recordedThisCandle {
+		delete(t.stats.recordedThisCandle, name)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return t.stats.Dated.PushValues(values)
+}
+
+// fetchFrequencies fetches the latest candles of each of the frequencies and returns the frequencies that have a new closed candle.
+func (t *Trader) fetchFrequencies(frequencies []string) []string {
+	if t.frames == nil {
+		t.frames = make(map[string]*IndexedFrame[UnixTime], len(frequencies))
+	}
+	var closed []string
+	for _, frequency := range frequencies {
+		if frequency == t.Frequency {
+			continue
+		}
+		ctx, cancel := t.brokerContext()
+		candles, err := t.Broker.Candles(ctx, t.Symbol, frequency, t.CandlesToKeep)
+		cancel()
+		if err != nil && err != ErrEOF {
+			t.Log.Printf("error fetching %s candles: %v", frequency, err)
+			continue // The candles of the last tick are kept.
+		}
+		prev := t.frames[frequency]
+		if candles.Len() > 0 && (prev == nil || prev.Len() == 0 || *prev.Date(-1) != *candles.Date(-1)) {
+			closed = append(closed, frequency)
+		}
+		t.frames[frequency] = candles
+	}
+	return closed
+}
+
+// fetchData fetches the latest candles of the Trader. Once the Trader has candles, only the candles since the last one are fetched and merged into the same frame, which keeps the last CandlesToKeep candles. An error is only returned when the context of the Trader is done or the request took longer than Timeout, in which case the data is left unchanged.
+func (t *Trader) fetchData() error {
+	ctx, cancel := t.brokerContext()
+	defer cancel()
+	count := t.fetchCount()
+	data, err := t.Broker.Candles(ctx, t.Symbol, t.Frequency, count)
+	if err == nil || err == ErrEOF {
+		if count < t.CandlesToKeep && !t.mergeData(data) {
+			// The candles do not overlap the data, so candles may have been missed. Start over with a full window.
+			data, err = t.Broker.Candles(ctx, t.Symbol, t.Frequency, t.CandlesToKeep)
+			count = t.CandlesToKeep
+		}
+	}
+	if err != nil && err != ErrEOF && (t.Context().Err() != nil || errors.Is(err, context.DeadlineExceeded) && ctx.Err() != nil) {
+		return err
+	}
+	if count >= t.CandlesToKeep {
+		t.data = data
+	}
 	if err == ErrEOF {
 		t.EOF = true
 		t.Log.Println("End of data")
@@ -167,95 +740,306 @@ func (t *Trader) fetchData() {
 	} else if err != nil {
 		panic(err) // TODO: implement safe shutdown procedure
 	}
+	t.fetchSymbols()
+	return nil
 }
 
-func (t *Trader) Order(orderType OrderType, units, price, stopLoss, takeProfit float64) (Order, error) {
+// fetchSymbols fetches the latest candles of the other symbols of the basket. A symbol whose candles cannot be fetched keeps its previous candles.
+func (t *Trader) fetchSymbols() {
+	if len(t.Symbols) == 0 {
+		return
+	}
+	if t.symbolData == nil {
+		t.symbolData = make(map[string]*IndexedFrame[UnixTime], len(t.Symbols))
+	}
+	for _, symbol := range t.Symbols {
+		if symbol == t.Symbol {
+			continue
+		}
+		ctx, cancel := t.brokerContext()
+		candles, err := t.Broker.Candles(ctx, symbol, t.Frequency, t.CandlesToKeep)
+		cancel()
+		if err != nil && err != ErrEOF {
+			t.Log.Printf("error fetching candles of %s: %v", symbol, err)
+			continue
+		}
+		t.symbolData[symbol] = candles
+	}
+}
+
+// fetchCount returns the number of candles to fetch to catch up with the broker, which is every candle since the last one the Trader has plus the last one again to check that none were missed. It is CandlesToKeep until the Trader has candles.
+func (t *Trader) fetchCount() int {
+	if t.data == nil || t.data.Len() == 0 || t.CandlesToKeep <= 0 {
+		return t.CandlesToKeep
+	}
+	duration, err := FrequencyDuration(t.Frequency)
+	if err != nil || duration <= 0 {
+		return t.CandlesToKeep
+	}
+	elapsed := t.Now().Sub(t.data.Date(-1).Time())
+	return Min(Max(int(elapsed/duration)+2, 2), t.CandlesToKeep) // One more than the elapsed candles rounds up a partial candle.
+}
+
+// mergeData inserts candles into the data of the Trader, replacing candles with the same date, and drops the oldest candles beyond CandlesToKeep. A CandleRevised signal is emitted on the data for each replaced candle whose values changed, once the data is updated. It returns false without changing the data if the candles start after the last candle of the data or have different columns, in which case the whole window must be fetched again.
+func (t *Trader) mergeData(candles *IndexedFrame[UnixTime]) bool {
+	if candles == nil || candles.Len() == 0 {
+		return true
+	} else if *candles.Date(0) > *t.data.Date(-1) || len(candles.Names()) != len(t.data.Names()) || !t.data.Contains(candles.Names()...) {
+		return false
+	}
+	first := *t.data.Date(0)
+	var revised []CandleRevision
+	for row := 0; row < candles.Len(); row++ {
+		index := *candles.Date(row)
+		if index < first {
+			continue
+		}
+		if existing := t.data.Closes().Row(index); existing >= 0 {
+			if previous, candle := frameCandle(t.data, existing), frameCandle(candles, row); candle != previous {
+				revised = append(revised, CandleRevision{Previous: previous, Candle: candle})
+			}
+		}
+		candles.ForEachSeries(func(s *IndexedSeries[UnixTime]) {
+			t.data.Series(s.Name()).Insert(index, s.Value(row))
+		})
+	}
+	if excess := t.data.Len() - t.CandlesToKeep; excess > 0 {
+		t.data.dropFront(excess)
+	}
+	for _, revision := range revised {
+		if revision.Row = t.data.Closes().Row(UnixTime(revision.Candle.Date.Unix())); revision.Row < 0 {
+			continue // Dropped beyond CandlesToKeep.
+		}
+		t.Log.Printf("Candle of %s revised: close %v -> %v", revision.Candle.Date.Format(time.DateTime), revision.Previous.Close, revision.Candle.Close)
+		t.data.SignalEmit(CandleRevised, revision)
+	}
+	return true
+}
+
+// Order places an order for the symbol of the Trader. See OrderSymbol.
+func (t *Trader) Order(orderType OrderType, units, price, stopLoss, takeProfit float64, options ...OrderOption) (Order, error) {
+	return t.OrderSymbol(t.Symbol, orderType, units, price, stopLoss, takeProfit, options...)
+}
+
+// OrderSymbol places an order for symbol, which is usually the Symbol of the Trader or one of its Symbols. See Broker.Order for the meaning of the arguments. The tags of the Trader are attached to the order before any tags given as options. An order with an execution algorithm, like WithTWAP or WithIceberg, is checked by the RiskManager as a whole and then placed as child orders over the following candles, and its first child order is returned. See ParentOrders.
+func (t *Trader) OrderSymbol(symbol string, orderType OrderType, units, price, stopLoss, takeProfit float64, options ...OrderOption) (Order, error) {
 	var priceStr string
 	if orderType != Market { // Price is ignored on market orders.
 		priceStr = fmt.Sprintf(" @ $%.2f", price)
 	} else {
-		priceStr = fmt.Sprintf(" @ ~$%.2f", t.Broker.Price(t.Symbol, units > 0))
+		priceStr = fmt.Sprintf(" @ ~$%.2f", t.Broker.Price(symbol, units > 0))
 	}
 	t.Log.Printf("%v %v units%v, stopLoss: %v, takeProfit: %v", orderType, units, priceStr, stopLoss, takeProfit)
 
-	order, err := t.Broker.Order(orderType, t.Symbol, units, price, stopLoss, takeProfit)
+	if t.Schedule != nil && !t.Schedule.Allowed(t.Now()) {
+		skipped := SkippedSignal{Time: t.Now(), Symbol: symbol, OrderType: orderType, Units: units, Price: price}
+		if orderType == Market {
+			skipped.Price = t.Broker.Price(symbol, units > 0)
+		}
+		t.stats.skipped = append(t.stats.skipped, skipped)
+		t.Log.Printf("Order skipped: %v", ErrOutsideSchedule)
+		return nil, ErrOutsideSchedule
+	}
+	if t.Risk != nil {
+		checkPrice := price
+		if orderType == Market {
+			checkPrice = t.Broker.Price(symbol, units > 0)
+		}
+		if err := t.Risk.Check(t, symbol, units, checkPrice); err != nil {
+			t.Log.Printf("Order rejected: %v", err)
+			return nil, err
+		}
+		if err := t.Risk.CheckStop(t, symbol, checkPrice, stopLoss); err != nil {
+			t.Log.Printf("Order rejected: %v", err)
+			return nil, err
+		}
+	}
+
+	if len(t.Tags) > 0 {
+		options = append([]OrderOption{WithTags(t.Tags)}, options...)
+	}
+	if t.SignalsOnly {
+		signalPrice := price
+		if orderType == Market {
+			signalPrice = t.Broker.Price(symbol, units > 0)
+		}
+		t.publish(TradeSignal{
+			Time:       t.data.Date(-1).Time(),
+			Symbol:     symbol,
+			OrderType:  orderType,
+			Units:      units,
+			Price:      signalPrice,
+			StopLoss:   stopLoss,
+			TakeProfit: takeProfit,
+			Tags:       NewOrderOptions(options...).Tags,
+		})
+		return nil, ErrSignalsOnly
+	}
+	if NewOrderOptions(options...).Execution.Algo != "" {
+		return t.execute(symbol, orderType, units, price, stopLoss, takeProfit, options)
+	}
+	return t.placeOrder(orderType, symbol, units, price, stopLoss, takeProfit, options)
+}
+
+// placeOrder sends an order to the broker, emulating the exits it does not support, and counts it toward the order limits of the RiskManager.
+func (t *Trader) placeOrder(orderType OrderType, symbol string, units, price, stopLoss, takeProfit float64, options []OrderOption) (Order, error) {
+	stopLoss, takeProfit, options, exit := t.emulateExits(units, stopLoss, takeProfit, options)
+	if exit != nil {
+		// The broker cannot check the sides of exits it never sees.
+		if err := ValidateOrder(Instrument{Symbol: symbol}, orderType, units, price, exit.stopLoss, exit.takeProfit, t.Broker.Price(symbol, units > 0)); err != nil {
+			return nil, err
+		}
+	}
+	ctx, cancel := t.brokerContext()
+	defer cancel()
+	order, err := t.Broker.Order(ctx, orderType, symbol, units, price, stopLoss, takeProfit, options...)
 	if err != nil {
 		return order, err
 	}
+	if t.Risk != nil {
+		t.Risk.OrderPlaced(t, symbol)
+	}
+	if exit != nil {
+		exit.order = order
+		t.emulated = append(t.emulated, exit)
+	}
 
 	// NOTE: Trade stats get added by handling an event by the broker
 	return order, nil
 }
 
 // Buy creates a buy market order. Units must be greater than zero or ErrInvalidUnits is returned.
-func (t *Trader) Buy(units, stopLoss, takeProfit float64) (Order, error) {
+func (t *Trader) Buy(units, stopLoss, takeProfit float64, options ...OrderOption) (Order, error) {
 	if units <= 0 {
 		return nil, ErrInvalidUnits
 	}
-	return t.Order(Market, units, 0, stopLoss, takeProfit)
+	return t.Order(Market, units, 0, stopLoss, takeProfit, options...)
 }
 
 // Sell creates a sell market order. Units must be greater than zero or ErrInvalidUnits is returned.
-func (t *Trader) Sell(units, stopLoss, takeProfit float64) (Order, error) {
+func (t *Trader) Sell(units, stopLoss, takeProfit float64, options ...OrderOption) (Order, error) {
 	if units <= 0 {
 		return nil, ErrInvalidUnits
 	}
-	return t.Order(Market, -units, 0, stopLoss, takeProfit)
+	return t.Order(Market, -units, 0, stopLoss, takeProfit, options...)
+}
+
+// Size returns the number of units the Sizer recommends trading at the latest candle, or zero if the Trader has no Sizer.
+func (t *Trader) Size() float64 {
+	if t.Sizer == nil {
+		return 0
+	}
+	return t.Sizer.Units(t)
 }
 
 func (t *Trader) CloseOrdersAndPositions() {
+	t.CloseSymbol(t.Symbol)
+}
+
+// CloseSymbol cancels the orders and closes the positions of symbol, like CloseOrdersAndPositions does for the symbol of the Trader.
+func (t *Trader) CloseSymbol(symbol string) {
+	if t.SignalsOnly {
+		t.publish(TradeSignal{Time: t.data.Date(-1).Time(), Symbol: symbol, Close: true, Tags: t.Tags})
+		return
+	}
+	for _, parent := range t.parents {
+		if parent.Symbol == symbol {
+			parent.Cancel()
+		}
+	}
 	for _, order := range t.Broker.OpenOrders() {
-		if order.Symbol() == t.Symbol {
+		if order.Symbol() == symbol {
 			t.Log.Printf("Cancelling order: %v units", order.Units())
 			order.Cancel()
 		}
 	}
 	for _, position := range t.Broker.OpenPositions() {
-		if position.Symbol() == t.Symbol {
+		if position.Symbol() == symbol {
 			t.Log.Printf("Closing position: %v units, $%.2f PL, ($%.2f -> $%.2f)", position.Units(), position.PL(), position.EntryPrice(), position.ClosePrice())
 			position.Close() // Event gets handled in the Init function
 		}
 	}
 }
 
+// IsLong returns true if the net units of the open positions in the Symbol of the Trader are positive, which is also the case for a broker that holds long and short positions at once when the longs are larger.
 func (t *Trader) IsLong() bool {
-	positions := t.Broker.OpenPositions()
-	if len(positions) < 1 {
-		return false
-	} else if len(positions) > 1 {
-		panic("cannot call IsLong with hedging enabled")
-	}
-	return positions[0].Units() > 0
+	return t.netUnits() > 0
 }
 
+// IsShort returns true if the net units of the open positions in the Symbol of the Trader are negative.
 func (t *Trader) IsShort() bool {
-	positions := t.Broker.OpenPositions()
-	if len(positions) < 1 {
-		return false
-	} else if len(positions) > 1 {
-		panic("cannot call IsShort with hedging enabled")
+	return t.netUnits() < 0
+}
+
+// netUnits returns the sum of the units of the open positions in the Symbol of the Trader.
+func (t *Trader) netUnits() float64 {
+	var units float64
+	for _, position := range t.Broker.OpenPositions() {
+		if position.Symbol() == t.Symbol {
+			units += position.Units()
+		}
 	}
-	return positions[0].Units() < 0
+	return units
 }
 
 type TraderConfig struct {
-	Broker        Broker
-	Strategy      Strategy
-	Symbol        string
-	Frequency     string
-	CandlesToKeep int
+	Broker          Broker
+	Strategy        Strategy
+	Symbol          string
+	Symbols         []string
+	Frequency       string
+	CandlesToKeep   int
+	Sizer           PositionSizer
+	Risk            *RiskManager
+	Tags            Tags
+	Journal         Journal
+	SignalsOnly     bool
+	Publishers      []SignalPublisher
+	Timeout         time.Duration
+	Watchdog        *Watchdog
+	Clock           Clock
+	CandleDriven    bool
+	ProfileAddr     string
+	SignalMetrics   *SignalMetrics
+	Telegram        *TelegramBot
+	SampleEquity    string
+	StateFile       string
+	CheckpointEvery int
+	Schedule        *TradingSchedule
+	Reoptimizer     *Reoptimizer
+	Indicators      *IndicatorCache
 }
 
 // NewTrader initializes a new Trader which can be used for live trading or backtesting.
 func NewTrader(config TraderConfig) *Trader {
 	logger := log.New(os.Stdout, "autotrader: ", log.LstdFlags|log.Lshortfile)
 	return &Trader{
-		Broker:        config.Broker,
-		Strategy:      config.Strategy,
-		Symbol:        config.Symbol,
-		Frequency:     config.Frequency,
-		CandlesToKeep: config.CandlesToKeep,
-		Log:           logger,
-		stats:         &TraderStats{},
+		Broker:          config.Broker,
+		Strategy:        config.Strategy,
+		Symbol:          config.Symbol,
+		Symbols:         config.Symbols,
+		Frequency:       config.Frequency,
+		CandlesToKeep:   config.CandlesToKeep,
+		Sizer:           config.Sizer,
+		Risk:            config.Risk,
+		Tags:            config.Tags,
+		Journal:         config.Journal,
+		SignalsOnly:     config.SignalsOnly,
+		Publishers:      config.Publishers,
+		Timeout:         config.Timeout,
+		Watchdog:        config.Watchdog,
+		Clock:           config.Clock,
+		CandleDriven:    config.CandleDriven,
+		SampleEquity:    config.SampleEquity,
+		StateFile:       config.StateFile,
+		CheckpointEvery: config.CheckpointEvery,
+		Schedule:        config.Schedule,
+		Reoptimizer:     config.Reoptimizer,
+		Indicators:      config.Indicators,
+		ProfileAddr:     config.ProfileAddr,
+		SignalMetrics:   config.SignalMetrics,
+		Telegram:        config.Telegram,
+		Log:             logger,
+		stats:           &TraderStats{},
 	}
 }
@@ -0,0 +1,191 @@
+package autotrader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestTraderIncrementalData(t *testing.T) {
+	broker := &countingBroker{TestBroker: NewTestBroker(nil, testData, 100_000, 50, 0, 0)}
+	trader := NewTrader(TraderConfig{Broker: broker, Strategy: &scriptedStrategy{}, Symbol: "EUR_USD", Frequency: "D", CandlesToKeep: 5})
+	trader.Log.SetOutput(io.Discard)
+	trader.Init()
+
+	trader.Tick()
+	data := trader.Data()
+	for i := 1; i < testData.Len()-1; i++ {
+		broker.Advance()
+		trader.Tick()
+		if trader.Data() != data {
+			t.Fatal("Expected the Trader to keep the same frame")
+		}
+		start := Max(i-4, 0)
+		expected := testData.CopyRange(start, i-start+1)
+		if data.Len() != expected.Len() || *data.Date(0) != *expected.Date(0) || *data.Date(-1) != *expected.Date(-1) || data.Close(-1) != expected.Close(-1) {
+			t.Errorf("Expected the data on candle %d to be:\n%v\ngot:\n%v", i, expected, data)
+		}
+	}
+	// The first fetch fills the window and the rest fetch the new candle plus the last candle and a spare.
+	if broker.requested[0] != 5 || broker.requested[len(broker.requested)-1] != 3 {
+		t.Errorf("Expected 5 candles and then 3 candles per fetch, got %v", broker.requested)
+	}
+}
+
+func TestTraderCandleRevised(t *testing.T) {
+	broker := NewTestBroker(nil, testData.Copy(), 100_000, 50, 0, 3)
+	trader := NewTrader(TraderConfig{Broker: broker, Strategy: &scriptedStrategy{}, Symbol: "EUR_USD", Frequency: "D", CandlesToKeep: 5})
+	trader.Log.SetOutput(io.Discard)
+	trader.Init()
+	trader.Tick()
+	var revisions []CandleRevision
+	trader.Data().SignalConnect(CandleRevised, t, func(args ...any) {
+		revisions = append(revisions, args[0].(CandleRevision))
+	})
+
+	broker.Advance()
+	trader.Tick()
+	if len(revisions) != 0 {
+		t.Fatalf("Expected no revisions of unchanged candles, got %+v", revisions)
+	}
+	broker.Data.Closes().SetValue(3, 1.12) // The broker corrects the close of the last candle the Trader had.
+	broker.Advance()
+	trader.Tick()
+	if len(revisions) != 1 {
+		t.Fatalf("Expected 1 revision, got %+v", revisions)
+	}
+	revision := revisions[0]
+	if revision.Previous.Close != 1.1 || revision.Candle.Close != 1.12 || !revision.Candle.Date.Equal(testData.Date(3).Time()) {
+		t.Errorf("Expected the close of the fourth candle to be revised from 1.1 to 1.12, got %+v", revision)
+	}
+	if data := trader.Data(); data.Close(revision.Row) != 1.12 || *data.Date(revision.Row) != *testData.Date(3) {
+		t.Errorf("Expected the revised candle to be stored at row %d, got:\n%v", revision.Row, data)
+	}
+}
+
+// connectBroker is a TestBroker that closes connected once a handler is connected to the signal.
+type connectBroker struct {
+	*TestBroker
+	signal    string
+	connected chan struct{}
+}
+
+func newConnectBroker(broker *TestBroker, signal string) *connectBroker {
+	return &connectBroker{TestBroker: broker, signal: signal, connected: make(chan struct{})}
+}
+
+func (b *connectBroker) SignalConnect(signal string, identity any, callback func(...any), bindings ...any) error {
+	err := b.TestBroker.SignalConnect(signal, identity, callback, bindings...)
+	if signal == b.signal {
+		close(b.connected)
+	}
+	return err
+}
+
+func TestTraderCandleDriven(t *testing.T) {
+	strategy := &scriptedStrategy{}
+	broker := newConnectBroker(NewTestBroker(nil, testData, 100_000, 50, 0, 0), CandleClosed)
+	trader := NewTrader(TraderConfig{Broker: broker, Strategy: strategy, Symbol: "EUR_USD", Frequency: "D", CandlesToKeep: 5, CandleDriven: true})
+	trader.Log.SetOutput(io.Discard)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		trader.RunContext(ctx)
+		close(done)
+	}()
+	<-broker.connected
+
+	broker.Advance()
+	broker.Advance()
+	cancel()
+	<-done
+	if strategy.candle != 2 {
+		t.Errorf("Expected the strategy to run on each of the 2 closed candles, got %d", strategy.candle)
+	}
+	if trader.Data().Len() != 3 {
+		t.Errorf("Expected 3 candles, got %d", trader.Data().Len())
+	}
+}
+
+func TestTraderPause(t *testing.T) {
+	broker := NewTestBroker(nil, testData, 100_000, 50, 0, 0)
+	strategy := &roundTripStrategy{}
+	trader := NewTrader(TraderConfig{Broker: broker, Strategy: strategy, Symbol: "EUR_USD", Frequency: "D", CandlesToKeep: 5})
+	trader.Log.SetOutput(io.Discard)
+	trader.Init()
+	trader.Tick()
+	trader.Pause()
+	broker.Advance()
+	trader.Tick()
+	if strategy.candle != 1 {
+		t.Errorf("Expected the strategy not to run while paused, got %d candles", strategy.candle)
+	}
+	if trader.Stats().Dated.Len() != 2 {
+		t.Errorf("Expected stats to be recorded while paused, got %d rows", trader.Stats().Dated.Len())
+	}
+	trader.Resume()
+	broker.Advance()
+	trader.Tick()
+	if strategy.candle != 2 || len(broker.OpenPositions()) != 1 {
+		t.Fatalf("Expected the strategy to buy after resuming, got %d candles and %d positions", strategy.candle, len(broker.OpenPositions()))
+	}
+	trader.Flatten()
+	if len(broker.OpenPositions()) != 0 {
+		t.Errorf("Expected Flatten to close the position, got %d open", len(broker.OpenPositions()))
+	}
+}
+
+func TestTraderSymbols(t *testing.T) {
+	// GBP_USD starts a day after EUR_USD at twice its prices.
+	gbp := NewDOHLCVIndexedFrame[UnixTime]()
+	for i := 1; i < testData.Len(); i++ {
+		gbp.PushCandle(*testData.Date(i), 2*testData.Open(i), 2*testData.High(i), 2*testData.Low(i), 2*testData.Close(i), int64(testData.Volume(i)))
+	}
+	broker := NewTestBroker(nil, testData, 10_000, 50, 0, 0)
+	broker.Slippage = 0
+	broker.SymbolData = map[string]*IndexedFrame[UnixTime]{"GBP_USD": gbp}
+
+	var early error
+	var candles []int
+	strategy := &scriptedStrategy{actions: map[int]func(*Trader){
+		1: func(t *Trader) {
+			_, early = t.OrderSymbol("GBP_USD", Market, 1000, 0, 0, 0)
+		},
+		2: func(t *Trader) {
+			t.Buy(1000, 0, 0)
+			t.OrderSymbol("GBP_USD", Market, 1000, 0, 0, 2.55) // Takes profit on the third candle.
+		},
+		4: func(t *Trader) {
+			t.CloseOrdersAndPositions()
+		},
+	}}
+	trader := NewTrader(TraderConfig{Broker: broker, Strategy: strategy, Symbol: "EUR_USD", Symbols: []string{"EUR_USD", "GBP_USD"}, Frequency: "D", CandlesToKeep: 5})
+	trader.Log.SetOutput(io.Discard)
+	trader.Init()
+	for i := 0; i < 5; i++ {
+		trader.Tick()
+		candles = append(candles, trader.SymbolData("GBP_USD").Len())
+		broker.Advance()
+	}
+
+	if !errors.Is(early, ErrNoData) {
+		t.Errorf("Expected an order before the first candle of GBP_USD to fail with ErrNoData, got %v", early)
+	}
+	if !slices.Equal(candles, []int{0, 1, 2, 3, 4}) {
+		t.Errorf("Expected the candles of GBP_USD to lag a day behind, got %v", candles)
+	}
+	if trader.SymbolData("EUR_USD") != trader.Data() || trader.SymbolData("USD_JPY") != nil {
+		t.Error("Expected the data of the Symbol to be Data and unknown symbols to have no data")
+	}
+	summary := Summarize(trader.Stats(), broker)
+	expected := map[string]SymbolResult{"EUR_USD": {Trades: 1, Profit: -100}, "GBP_USD": {Trades: 1, Wins: 1, Profit: 150}}
+	for symbol, result := range expected {
+		got := summary.Symbols[symbol]
+		if got.Trades != result.Trades || got.Wins != result.Wins || !EqualApprox(got.Profit, result.Profit) {
+			t.Errorf("Expected %s to have results %+v, got %+v", symbol, result, got)
+		}
+	}
+}
@@ -2,9 +2,13 @@ package autotrader
 
 import (
 	"errors"
+	"fmt"
 	"math"
 	"os/exec"
 	"runtime"
+	"strconv"
+	"strings"
+	"time"
 
 	"golang.org/x/exp/constraints"
 )
@@ -80,6 +84,51 @@ func Max[T constraints.Ordered](a, b T) T {
 	return b
 }
 
+// FrequencyDuration returns the duration of a single candle of the given frequency, such as "MS100", "S5", "M15", "H1", "D", "W", or "M". "MS" is milliseconds for sub-second bars. The frequency is not case sensitive, except for a lone "M" which means one month and is approximated as 30 days.
+func FrequencyDuration(frequency string) (time.Duration, error) {
+	if frequency == "M" {
+		return 30 * 24 * time.Hour, nil
+	}
+	capitalizedFreq := strings.ToUpper(frequency)
+	switch capitalizedFreq {
+	case "D":
+		return 24 * time.Hour, nil
+	case "W":
+		return 7 * 24 * time.Hour, nil
+	}
+	if len(capitalizedFreq) < 2 {
+		return 0, fmt.Errorf("invalid frequency: %s", frequency)
+	}
+	if strings.HasPrefix(capitalizedFreq, "MS") {
+		n, err := strconv.Atoi(capitalizedFreq[2:])
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid frequency: %s", frequency)
+		}
+		return time.Duration(n) * time.Millisecond, nil
+	}
+	n, err := strconv.Atoi(capitalizedFreq[1:])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid frequency: %s", frequency)
+	}
+	switch capitalizedFreq[0] {
+	case 'S':
+		return time.Duration(n) * time.Second, nil
+	case 'M':
+		return time.Duration(n) * time.Minute, nil
+	case 'H':
+		return time.Duration(n) * time.Hour, nil
+	}
+	return 0, fmt.Errorf("invalid frequency: %s", frequency)
+}
+
+// PipSize returns the size of a pip of a forex symbol like "EUR_USD" or "USD/JPY", which is 0.01 for pairs quoted in JPY and 0.0001 for every other pair.
+func PipSize(symbol string) float64 {
+	if strings.HasSuffix(strings.ToUpper(symbol), "JPY") {
+		return 0.01
+	}
+	return 0.0001
+}
+
 func LeverageToMargin(leverage float64) float64 {
 	return 1 / leverage
 }
@@ -3,6 +3,7 @@ package autotrader
 import (
 	"math"
 	"testing"
+	"time"
 )
 
 func TestEqualApprox(t *testing.T) {
@@ -49,3 +50,32 @@ func TestRound(t *testing.T) {
 		t.Error("Expected 12.34 to round to 10")
 	}
 }
+
+func TestFrequencyDuration(t *testing.T) {
+	for freq, expected := range map[string]time.Duration{
+		"MS250": 250 * time.Millisecond,
+		"S5":    5 * time.Second,
+		"M15":   15 * time.Minute,
+		"h4":    4 * time.Hour,
+		"D":     24 * time.Hour,
+		"W":     7 * 24 * time.Hour,
+		"M":     30 * 24 * time.Hour,
+	} {
+		if d, err := FrequencyDuration(freq); err != nil || d != expected {
+			t.Errorf("Expected %q to be %v, got %v (%v)", freq, expected, d, err)
+		}
+	}
+	for _, freq := range []string{"", "X1", "M0", "Hx", "MS0", "MS"} {
+		if _, err := FrequencyDuration(freq); err == nil {
+			t.Errorf("Expected an error for frequency %q", freq)
+		}
+	}
+}
+
+func TestPipSize(t *testing.T) {
+	for symbol, want := range map[string]float64{"EUR_USD": 0.0001, "USD_JPY": 0.01, "eur/jpy": 0.01, "GBPUSD": 0.0001} {
+		if got := PipSize(symbol); got != want {
+			t.Errorf("Expected the pip size of %s to be %v, got %v", symbol, want, got)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package autotrader
+
+import (
+	"fmt"
+	"math"
+)
+
+// ValidateOrder returns an *OrderError if an order for instrument breaks its constraints or would be rejected by any broker, so every broker rejects the same orders with the same errors before they are submitted. It checks that the units are not zero and within the MinUnits and MaxUnits of the instrument, that the price of a limit or stop order is within MaxPriceDistance of marketPrice, and that the stop loss and take profit are on the losing and winning side of the entry, which is the market price of a market order and the price of any other order. Negative stop losses are trailing distances and are not checked. If marketPrice is zero, like before the first quote of a live broker, the checks that need it are skipped.
+func ValidateOrder(instrument Instrument, orderType OrderType, units, price, stopLoss, takeProfit, marketPrice float64) error {
+	entry := price
+	if orderType == Market {
+		entry = marketPrice
+	}
+	reject := func(err error, reason string) error {
+		return &OrderError{Err: err, OrderType: orderType, Symbol: instrument.Symbol, Units: units, Price: entry, Reason: reason}
+	}
+	switch size := math.Abs(units); {
+	case size == 0:
+		return reject(ErrInvalidUnits, "units must not be zero")
+	case size < instrument.MinUnits:
+		return reject(ErrUnitsBelowMinimum, fmt.Sprintf("minimum is %v units", instrument.MinUnits))
+	case instrument.MaxUnits > 0 && size > instrument.MaxUnits:
+		return reject(ErrUnitsAboveMaximum, fmt.Sprintf("maximum is %v units", instrument.MaxUnits))
+	}
+	if orderType != Market && instrument.MaxPriceDistance > 0 && marketPrice > 0 {
+		if distance := math.Abs(price-marketPrice) / marketPrice; distance > instrument.MaxPriceDistance {
+			return reject(ErrPriceTooFar, fmt.Sprintf("%.2f%% from the market price of %v but the maximum is %.2f%%", distance*100, marketPrice, instrument.MaxPriceDistance*100))
+		}
+	}
+	if entry <= 0 {
+		return nil
+	}
+	// A stop loss must be on the losing side of the entry and a take profit on the winning side.
+	if stopLoss > 0 && (units > 0 && stopLoss >= entry || units < 0 && stopLoss <= entry) {
+		return reject(ErrInvalidStopLoss, fmt.Sprintf("stop loss %v is on the wrong side of the price", stopLoss))
+	}
+	if takeProfit > 0 && (units > 0 && takeProfit <= entry || units < 0 && takeProfit >= entry) {
+		return reject(ErrInvalidTakeProfit, fmt.Sprintf("take profit %v is on the wrong side of the price", takeProfit))
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package autotrader
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestValidateOrder(t *testing.T) {
+	instrument := Instrument{Symbol: "EUR_USD", MinUnits: 10, MaxUnits: 1000, MaxPriceDistance: 0.1}
+	tests := []struct {
+		name                               string
+		orderType                          OrderType
+		units, price, stopLoss, takeProfit float64
+		expected                           error
+	}{
+		{"valid market", Market, 100, 0, 1.1, 1.3, nil},
+		{"zero units", Market, 0, 0, 0, 0, ErrInvalidUnits},
+		{"below minimum", Market, -5, 0, 0, 0, ErrUnitsBelowMinimum},
+		{"above maximum", Market, 1001, 0, 0, 0, ErrUnitsAboveMaximum},
+		{"limit too far", Limit, 100, 1.5, 0, 0, ErrPriceTooFar},
+		{"valid limit", Limit, 100, 1.1, 1.05, 1.3, nil},
+		{"long stop loss above", Market, 100, 0, 1.25, 0, ErrInvalidStopLoss},
+		{"short stop loss below", Market, -100, 0, 1.1, 0, ErrInvalidStopLoss},
+		{"trailing stop loss", Market, 100, 0, -0.01, 0, nil},
+		{"long take profit below", Market, 100, 0, 0, 1.1, ErrInvalidTakeProfit},
+		{"limit take profit from its price", Limit, 100, 1.1, 0, 1.15, nil},
+	}
+	for _, test := range tests {
+		err := ValidateOrder(instrument, test.orderType, test.units, test.price, test.stopLoss, test.takeProfit, 1.2)
+		if !errors.Is(err, test.expected) || (err == nil) != (test.expected == nil) {
+			t.Errorf("%s: Expected %v, got %v", test.name, test.expected, err)
+		}
+	}
+	if err := ValidateOrder(instrument, Market, 1, 0, 0, 0, 1.2); !errors.Is(err, ErrInvalidUnits) {
+		t.Errorf("Expected ErrUnitsBelowMinimum to wrap ErrInvalidUnits, got %v", err)
+	}
+	var orderErr *OrderError
+	if err := ValidateOrder(instrument, Market, 0, 0, 0, 0, 1.2); !errors.As(err, &orderErr) || orderErr.Symbol != "EUR_USD" {
+		t.Errorf("Expected an *OrderError for EUR_USD, got %v", err)
+	}
+	if err := ValidateOrder(Instrument{MaxPriceDistance: 0.1}, Limit, 100, 1.5, 0, 0, 0); err != nil {
+		t.Errorf("Expected no price distance check without a market price, got %v", err)
+	}
+}
+
+func TestBacktestingBrokerValidation(t *testing.T) {
+	broker := NewTestBroker(nil, testData, 100_000, 1, 0, 0)
+	broker.MinUnits = 10
+	broker.Instruments = map[string]Instrument{"EUR_USD": {Symbol: "EUR_USD", MaxUnits: 500}}
+	if _, err := broker.Order(context.Background(), Market, "EUR_USD", 0, 0, 0, 0); !errors.Is(err, ErrInvalidUnits) {
+		t.Errorf("Expected ErrInvalidUnits, got %v", err)
+	}
+	if _, err := broker.Order(context.Background(), Market, "EUR_USD", 5, 0, 0, 0); !errors.Is(err, ErrUnitsBelowMinimum) {
+		t.Errorf("Expected the broker MinUnits to apply to the instrument, got %v", err)
+	}
+	if _, err := broker.Order(context.Background(), Market, "EUR_USD", 600, 0, 0, 0); !errors.Is(err, ErrUnitsAboveMaximum) {
+		t.Errorf("Expected ErrUnitsAboveMaximum, got %v", err)
+	}
+	if _, err := broker.Order(context.Background(), Market, "EUR_USD", 500, 0, 0, 0); err != nil {
+		t.Errorf("Expected the maximum units to be allowed, got %v", err)
+	}
+}
@@ -0,0 +1,234 @@
+package autotrader
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/components"
+	"github.com/go-echarts/go-echarts/v2/opts"
+)
+
+// SessionFunc returns the start of the trading session that t is in. Candles with the same session start are in the same session.
+type SessionFunc func(t time.Time) time.Time
+
+// DailySession returns a SessionFunc of sessions that start every day at open in UTC, like 22 hours for the forex day or 13 hours 30 minutes for the New York stock market.
+func DailySession(open time.Duration) SessionFunc {
+	return func(t time.Time) time.Time {
+		return dayStart(t.UTC().Add(-open)).Add(open)
+	}
+}
+
+// VWAP calculates the Volume Weighted Average Price of the candles in dohlcv, which is the average of the typical price (high + low + close) / 3 of each candle weighted by its volume. The average restarts with the first candle of every session, or runs over every candle if session is nil. Where a session has had no volume yet, the VWAP is the typical price. Returns a Series of VWAP values of the same length as the input.
+func VWAP(dohlcv *IndexedFrame[UnixTime], session SessionFunc) *FloatSeries {
+	vwap := make([]float64, dohlcv.Len())
+	var start time.Time
+	var pv, volume float64
+	for i := range vwap {
+		typical := (dohlcv.High(i) + dohlcv.Low(i) + dohlcv.Close(i)) / 3
+		if session != nil {
+			if s := session(dohlcv.Date(i).Time()); i == 0 || !s.Equal(start) {
+				start, pv, volume = s, 0, 0
+			}
+		}
+		pv += typical * dohlcv.Float("Volume", i)
+		volume += dohlcv.Float("Volume", i)
+		vwap[i] = typical
+		if volume > 0 {
+			vwap[i] = pv / volume
+		}
+	}
+	return NewFloatSeries("VWAP", vwap...)
+}
+
+// VolumeProfile is a histogram of the volume traded at each price, which shows the high volume nodes that price tends to react to. The prices from Low to High are split into bins of equal size.
+type VolumeProfile struct {
+	Low     float64
+	High    float64
+	Volumes []float64 // Volumes are the volume of each bin, from the lowest price.
+}
+
+// NewVolumeProfile returns an empty VolumeProfile of the prices from low to high split into bins.
+func NewVolumeProfile(low, high float64, bins int) *VolumeProfile {
+	return &VolumeProfile{Low: low, High: high, Volumes: make([]float64, Max(bins, 1))}
+}
+
+// CandleVolumeProfile returns the VolumeProfile of the candles of dohlcv from start up to but not including end, with bins from the lowest low to the highest high of those candles.
+func CandleVolumeProfile(dohlcv *IndexedFrame[UnixTime], start, end, bins int) *VolumeProfile {
+	low, high := math.Inf(1), math.Inf(-1)
+	for i := start; i < end; i++ {
+		low, high = math.Min(low, dohlcv.Low(i)), math.Max(high, dohlcv.High(i))
+	}
+	if start >= end {
+		low, high = 0, 0
+	}
+	return NewVolumeProfile(low, high, bins).AddCandles(dohlcv, start, end)
+}
+
+// BinSize returns the range of prices of each bin.
+func (p *VolumeProfile) BinSize() float64 {
+	return (p.High - p.Low) / float64(len(p.Volumes))
+}
+
+// Bin returns the index of the bin that price is in, or -1 if price is outside of the profile. The High price is in the last bin.
+func (p *VolumeProfile) Bin(price float64) int {
+	if price < p.Low || price > p.High {
+		return -1
+	}
+	if p.BinSize() == 0 {
+		return 0
+	}
+	return Min(int((price-p.Low)/p.BinSize()), len(p.Volumes)-1)
+}
+
+// Price returns the middle price of the bin.
+func (p *VolumeProfile) Price(bin int) float64 {
+	return p.Low + (float64(bin)+0.5)*p.BinSize()
+}
+
+// Add spreads volume evenly over the prices from low to high, like the range of a candle. The volume traded at prices outside of the profile is left out.
+func (p *VolumeProfile) Add(low, high, volume float64) {
+	if high-low <= 0 || p.BinSize() == 0 {
+		if bin := p.Bin(low); bin >= 0 {
+			p.Volumes[bin] += volume
+		}
+		return
+	}
+	from, to := math.Max(low, p.Low), math.Min(high, p.High)
+	if from > to {
+		return
+	}
+	for bin := p.Bin(from); bin <= p.Bin(to); bin++ {
+		binLow := p.Low + float64(bin)*p.BinSize()
+		overlap := math.Min(to, binLow+p.BinSize()) - math.Max(from, binLow)
+		p.Volumes[bin] += volume * overlap / (high - low)
+	}
+}
+
+// AddCandles adds the volume of the candles of dohlcv from start up to but not including end over the range of each candle. It returns p for chaining.
+func (p *VolumeProfile) AddCandles(dohlcv *IndexedFrame[UnixTime], start, end int) *VolumeProfile {
+	for i := start; i < end; i++ {
+		p.Add(dohlcv.Low(i), dohlcv.High(i), dohlcv.Float("Volume", i))
+	}
+	return p
+}
+
+// Total returns the volume of every bin.
+func (p *VolumeProfile) Total() float64 {
+	var total float64
+	for _, v := range p.Volumes {
+		total += v
+	}
+	return total
+}
+
+// PointOfControl returns the middle price of the bin with the most volume, which is the lowest one of equal bins.
+func (p *VolumeProfile) PointOfControl() float64 {
+	return p.Price(p.pointOfControl())
+}
+
+func (p *VolumeProfile) pointOfControl() int {
+	poc := 0
+	for bin, v := range p.Volumes {
+		if v > p.Volumes[poc] {
+			poc = bin
+		}
+	}
+	return poc
+}
+
+// ValueArea returns the range of prices around the point of control that holds pct of the volume, like 0.7 for the traditional 70% value area. The range grows from the point of control one bin at a time toward the side with more volume.
+func (p *VolumeProfile) ValueArea(pct float64) (low, high float64) {
+	lo, hi := p.pointOfControl(), p.pointOfControl()
+	volume, target := p.Volumes[lo], pct*p.Total()
+	for volume < target && (lo > 0 || hi < len(p.Volumes)-1) {
+		if hi == len(p.Volumes)-1 || lo > 0 && p.Volumes[lo-1] > p.Volumes[hi+1] {
+			lo--
+			volume += p.Volumes[lo]
+		} else {
+			hi++
+			volume += p.Volumes[hi]
+		}
+	}
+	return p.Low + float64(lo)*p.BinSize(), p.Low + float64(hi+1)*p.BinSize()
+}
+
+// HighVolumeNodes returns the middle prices of the bins that are peaks of the profile, which hold more volume than the bins below them, at least as much as the bins above them, and more than the average bin. They are returned from the lowest price.
+func (p *VolumeProfile) HighVolumeNodes() []float64 {
+	mean := p.Total() / float64(len(p.Volumes))
+	var nodes []float64
+	for bin, v := range p.Volumes {
+		if v > mean && (bin == 0 || v > p.Volumes[bin-1]) && (bin == len(p.Volumes)-1 || v >= p.Volumes[bin+1]) {
+			nodes = append(nodes, p.Price(bin))
+		}
+	}
+	return nodes
+}
+
+// VolumeProfileSection charts the candles with the session VWAP and a side pane of the volume profile of the last candles in bins, with the point of control and the 70% value area drawn over those candles. The profile covers every candle if candles is 0, and the VWAP runs over every candle if session is nil. Nothing is added if the candles have no volume.
+func VolumeProfileSection(candles, bins int, session SessionFunc) ReportSection {
+	return ChartSection(func(ctx *ReportContext) components.Charter {
+		return newVolumeProfileChart(ctx, candles, bins, session)
+	})
+}
+
+func newVolumeProfileChart(ctx *ReportContext, candles, bins int, session SessionFunc) components.Charter {
+	dohlcv := ctx.Trader.data
+	if dohlcv.Len() == 0 {
+		return nil
+	}
+	start := 0
+	if candles > 0 {
+		start = Max(dohlcv.Len()-candles, 0)
+	}
+	// The bins span the prices of every candle so the pane lines up with the price axis of the kline.
+	low, high := math.Inf(1), math.Inf(-1)
+	for i := 0; i < dohlcv.Len(); i++ {
+		low, high = math.Min(low, dohlcv.Low(i)), math.Max(high, dohlcv.High(i))
+	}
+	profile := NewVolumeProfile(low, high, bins).AddCandles(dohlcv, start, dohlcv.Len())
+	if profile.Total() == 0 {
+		return nil
+	}
+
+	kline := newKline(dohlcv, ctx.Stats.Dated, ctx.DateLayout)
+	kline.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{
+			Title:    "Volume Profile",
+			Subtitle: fmt.Sprintf("Point of control %.5g over the last %d candles", profile.PointOfControl(), dohlcv.Len()-start),
+		}),
+		charts.WithGridOpts(opts.Grid{Right: "22%"}, opts.Grid{Left: "80%", Right: "4%"}),
+		charts.WithYAxisOpts(opts.YAxis{Min: profile.Low, Max: profile.High}),
+	)
+	kline.ExtendXAxis(opts.XAxis{Type: "value", GridIndex: 1})
+	prices := make([]string, len(profile.Volumes))
+	volumes := make([]opts.BarData, len(profile.Volumes))
+	for bin, v := range profile.Volumes {
+		prices[bin] = fmt.Sprintf("%.5g", profile.Price(bin))
+		volumes[bin] = opts.BarData{Value: Round(v, 2)}
+	}
+	kline.ExtendYAxis(opts.YAxis{Type: "category", GridIndex: 1, Data: prices})
+
+	vwapData := make([]opts.LineData, dohlcv.Len())
+	for i, v := range VWAP(dohlcv, session).Values() {
+		vwapData[i] = opts.LineData{Value: Round(v, 5)}
+	}
+	vwap := charts.NewLine()
+	vwap.AddSeries("VWAP", vwapData, charts.WithLineChartOpts(opts.LineChart{ShowSymbol: false}))
+	pane := charts.NewBar()
+	pane.AddSeries("Volume", volumes, func(s *charts.SingleSeries) {
+		s.XAxisIndex, s.YAxisIndex = 1, 1
+		s.BarCategoryGap = "10%"
+	})
+	kline.Overlap(vwap, pane)
+
+	from, to := dohlcv.Date(start).Time().Format(ctx.DateLayout), dohlcv.Date(-1).Time().Format(ctx.DateLayout)
+	areaLow, areaHigh := profile.ValueArea(0.7)
+	kline.AddSeries("Value Area", nil, withLineSegments([]lineSegment{
+		{From: []any{from, profile.PointOfControl()}, To: []any{to, profile.PointOfControl()}, Color: "#4575b4", Type: "solid"},
+		{From: []any{from, areaLow}, To: []any{to, areaLow}, Color: "#91bfdb", Type: "dashed"},
+		{From: []any{from, areaHigh}, To: []any{to, areaHigh}, Color: "#91bfdb", Type: "dashed"},
+	}))
+	return kline
+}
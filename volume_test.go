@@ -0,0 +1,87 @@
+package autotrader
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVWAP(t *testing.T) {
+	typical := func(i int) float64 { return (testData.High(i) + testData.Low(i) + testData.Close(i)) / 3 }
+	anchored := VWAP(testData, nil)
+	if anchored.Len() != testData.Len() || !EqualApprox(anchored.Value(0), typical(0)) {
+		t.Fatalf("Expected the first VWAP to be the typical price %f, got %v", typical(0), anchored.Values())
+	}
+	expected := (typical(0)*100 + typical(1)*110) / 210
+	if !EqualApprox(anchored.Value(1), expected) {
+		t.Errorf("Expected the VWAP of the first two candles to be %f, got %f", expected, anchored.Value(1))
+	}
+
+	// Sessions of two days restart the average on every odd day of January.
+	twoDays := func(t time.Time) time.Time { return t.AddDate(0, 0, -(t.Day()-1)%2) }
+	sessions := VWAP(testData, twoDays)
+	if !EqualApprox(sessions.Value(1), expected) || !EqualApprox(sessions.Value(2), typical(2)) {
+		t.Errorf("Expected the VWAP to restart on the third candle, got %v", sessions.Values())
+	}
+	if daily := VWAP(testData, DailySession(0)); !EqualApprox(daily.Value(5), typical(5)) {
+		t.Errorf("Expected a daily VWAP of daily candles to be the typical price, got %f", daily.Value(5))
+	}
+	if start := DailySession(22 * time.Hour)(time.Date(2022, 1, 3, 10, 0, 0, 0, time.UTC)); !start.Equal(time.Date(2022, 1, 2, 22, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the forex day to start at 22:00 the day before, got %s", start)
+	}
+}
+
+func TestVolumeProfile(t *testing.T) {
+	profile := NewVolumeProfile(1, 2, 4)
+	profile.Add(1, 2, 100)     // 25 in each bin.
+	profile.Add(1.5, 1.75, 50) // All in the third bin.
+	profile.Add(1.25, 1.25, 10)
+	profile.Add(2.5, 3, 1000) // Outside of the profile.
+	expected := []float64{25, 35, 75, 25}
+	for bin, v := range profile.Volumes {
+		if !EqualApprox(v, expected[bin]) {
+			t.Errorf("Expected bin %d to have %f volume, got %f", bin, expected[bin], v)
+		}
+	}
+	if poc := profile.PointOfControl(); !EqualApprox(poc, 1.625) {
+		t.Errorf("Expected the point of control at 1.625, got %f", poc)
+	}
+	if low, high := profile.ValueArea(0.6); !EqualApprox(low, 1.25) || !EqualApprox(high, 1.75) {
+		t.Errorf("Expected the value area from 1.25 to 1.75, got %f to %f", low, high)
+	}
+	if nodes := profile.HighVolumeNodes(); len(nodes) != 1 || !EqualApprox(nodes[0], 1.625) {
+		t.Errorf("Expected a single high volume node at 1.625, got %v", nodes)
+	}
+	if profile.Bin(2) != 3 || profile.Bin(0.9) != -1 {
+		t.Errorf("Expected the high in the last bin and prices below the low outside, got %d and %d", profile.Bin(2), profile.Bin(0.9))
+	}
+
+	candles := CandleVolumeProfile(testData, 0, testData.Len(), 8)
+	if candles.Low != 1 || candles.High != 1.4 || !EqualApprox(candles.Total(), 1140) {
+		t.Errorf("Expected the profile of every candle from 1 to 1.4 with all of their volume, got %+v", candles)
+	}
+}
+
+func TestVolumeProfileSection(t *testing.T) {
+	trader, broker := runTestBacktest(t, &roundTripStrategy{})
+	report := &Report{
+		Filename: filepath.Join(t.TempDir(), "report.html"),
+		Out:      io.Discard,
+		Sections: []ReportSection{VolumeProfileSection(5, 10, DailySession(0))},
+	}
+	if err := report.Generate(trader, broker, 0); err != nil {
+		t.Fatal(err)
+	}
+	page, err := os.ReadFile(report.Filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Volume Profile", "VWAP", "Value Area", "over the last 5 candles"} {
+		if !strings.Contains(string(page), name) {
+			t.Errorf("Expected the chart to contain %q", name)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package autotrader
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Watchdog raises an alert when a live Trader stops ticking, like when its scheduler stalls or a request to the broker hangs. Every Tick is a heartbeat, and a tick is late when no heartbeat arrives within the frequency of the Trader plus Tolerance. Once a stall is detected, the Notifier is alerted and Recover is called, which is repeated each time the deadline passes again until the ticks resume.
+//
+// Set the Watchdog of a Trader and it is started by RunContext.
+type Watchdog struct {
+	Tolerance time.Duration // Tolerance is how late a tick may be before the Trader is considered stalled. The default is one period of the frequency.
+	Interval  time.Duration // Interval is how often the heartbeat is checked. The default is a quarter of the deadline.
+	Notifier  Notifier      // Notifier receives an alert when the Trader stalls and when it resumes. If nil, alerts are written to the log of the Trader.
+	// Recover attempts to get a stalled Trader ticking again, like reconnecting to the broker or resubscribing to a stream. It is optional, and a returned error is sent to the Notifier.
+	Recover func(ctx context.Context, t *Trader) error
+
+	mu       sync.Mutex
+	lastTick time.Time
+	stalled  bool
+	now      func() time.Time
+}
+
+// Heartbeat records that the Trader ticked. It is called by Tick.
+func (w *Watchdog) Heartbeat() {
+	w.mu.Lock()
+	w.lastTick = w.clock()
+	w.mu.Unlock()
+}
+
+// LastTick returns the time of the last heartbeat.
+func (w *Watchdog) LastTick() time.Time {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lastTick
+}
+
+// Stalled returns true if the Trader is stalled and has not ticked since.
+func (w *Watchdog) Stalled() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stalled
+}
+
+// Watch checks the heartbeat of t every Interval until ctx is done. The time Watch is called counts as the first heartbeat, so a Trader that never ticks is also caught.
+func (w *Watchdog) Watch(ctx context.Context, t *Trader) error {
+	deadline, err := w.deadline(t.Frequency)
+	if err != nil {
+		return err
+	}
+	interval := w.Interval
+	if interval <= 0 {
+		interval = deadline / 4
+	}
+	w.Heartbeat()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var lastAlert time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			lastAlert = w.check(ctx, t, deadline, lastAlert)
+		}
+	}
+}
+
+// check alerts and attempts recovery if the last heartbeat is older than deadline and no alert was raised within deadline. It returns the time of the last alert.
+func (w *Watchdog) check(ctx context.Context, t *Trader, deadline time.Duration, lastAlert time.Time) time.Time {
+	w.mu.Lock()
+	now := w.clock()
+	since := now.Sub(w.lastTick)
+	late := since > deadline
+	resumed := w.stalled && !late
+	w.stalled = late
+	w.mu.Unlock()
+
+	if resumed {
+		w.notify(t, fmt.Sprintf("%s on %s resumed ticking", strategyName(t.Strategy), t.Symbol))
+		return time.Time{}
+	}
+	if !late || now.Sub(lastAlert) <= deadline {
+		return lastAlert
+	}
+	w.notify(t, fmt.Sprintf("%s on %s stalled: no tick for %v, expected one every %s", strategyName(t.Strategy), t.Symbol, since.Round(time.Millisecond), t.Frequency))
+	if w.Recover != nil {
+		if err := w.Recover(ctx, t); err != nil {
+			w.notify(t, fmt.Sprintf("%s on %s failed to recover: %v", strategyName(t.Strategy), t.Symbol, err))
+		}
+	}
+	return now
+}
+
+// deadline returns the longest time allowed between two ticks at frequency.
+func (w *Watchdog) deadline(frequency string) (time.Duration, error) {
+	period, err := FrequencyDuration(frequency)
+	if err != nil {
+		return 0, err
+	}
+	if w.Tolerance > 0 {
+		return period + w.Tolerance, nil
+	}
+	return 2 * period, nil
+}
+
+func (w *Watchdog) notify(t *Trader, message string) {
+	if w.Notifier == nil {
+		t.Log.Printf("Watchdog: %s", message)
+		return
+	}
+	if err := w.Notifier.Notify(message); err != nil {
+		t.Log.Printf("error sending watchdog alert %q: %v", message, err)
+	}
+}
+
+func (w *Watchdog) clock() time.Time {
+	if w.now != nil {
+		return w.now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,72 @@
+package autotrader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchdog(t *testing.T) {
+	now := time.Date(2023, 1, 2, 12, 0, 0, 0, time.UTC)
+	var alerts []string
+	var recoveries int
+	watchdog := &Watchdog{
+		Tolerance: 30 * time.Second,
+		Notifier: NotifierFunc(func(message string) error {
+			alerts = append(alerts, message)
+			return nil
+		}),
+		Recover: func(ctx context.Context, t *Trader) error {
+			recoveries++
+			return errors.New("broker unreachable")
+		},
+		now: func() time.Time { return now },
+	}
+	trader := NewTrader(TraderConfig{Strategy: &roundTripStrategy{}, Symbol: "EUR_USD", Frequency: "M1", Watchdog: watchdog})
+	trader.Log.SetOutput(io.Discard)
+	deadline, err := watchdog.deadline(trader.Frequency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deadline != 90*time.Second {
+		t.Fatalf("Expected a deadline of 90s, got %v", deadline)
+	}
+
+	ctx := context.Background()
+	check := func(after time.Duration, lastAlert time.Time) time.Time {
+		now = now.Add(after)
+		return watchdog.check(ctx, trader, deadline, lastAlert)
+	}
+	watchdog.Heartbeat()
+	lastAlert := check(time.Minute, time.Time{})
+	if len(alerts) != 0 || watchdog.Stalled() {
+		t.Fatalf("Expected no alert for a tick on time, got %q", alerts)
+	}
+	lastAlert = check(time.Minute, lastAlert)
+	if len(alerts) != 2 || !strings.Contains(alerts[0], "stalled") || !strings.Contains(alerts[1], "broker unreachable") {
+		t.Fatalf("Expected a stall alert and a recovery failure, got %q", alerts)
+	}
+	if recoveries != 1 || !watchdog.Stalled() {
+		t.Errorf("Expected 1 recovery attempt while stalled, got %d", recoveries)
+	}
+	lastAlert = check(30*time.Second, lastAlert)
+	if len(alerts) != 2 {
+		t.Errorf("Expected no repeated alert within the deadline, got %q", alerts)
+	}
+	lastAlert = check(2*time.Minute, lastAlert)
+	if len(alerts) != 4 || recoveries != 2 {
+		t.Errorf("Expected the alert and recovery to repeat after the deadline, got %q", alerts)
+	}
+
+	watchdog.Heartbeat()
+	check(time.Second, lastAlert)
+	if len(alerts) != 5 || !strings.Contains(alerts[4], "resumed") {
+		t.Errorf("Expected a resumed alert, got %q", alerts)
+	}
+	if watchdog.Stalled() {
+		t.Error("Expected the watchdog to no longer be stalled")
+	}
+}